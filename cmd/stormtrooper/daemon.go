@@ -0,0 +1,201 @@
+package main
+
+import (
+	gocontext "context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/gavinyap/stormtrooper/internal/agent"
+	"github.com/gavinyap/stormtrooper/internal/daemon"
+)
+
+const detachUsage = `Usage: stormtrooper detach [flags] <prompt>
+
+Starts a background session that runs <prompt> and keeps going after
+this terminal closes. Follow it, answer its permission prompts, and send
+it more prompts with "stormtrooper attach <id>". The session ends once
+its prompts are done and nobody is attached. The workspace must already
+be trusted.
+
+Flags:
+`
+
+const attachUsage = `Usage: stormtrooper attach [--all] [<id>]
+
+Without <id>, lists the detached sessions. With it, prints what the
+session did since you last attached and follows it live. Type y or n to
+answer a permission prompt, or a line to send it as the next prompt.
+"/detach" or Ctrl+C leaves the session running; "/stop" ends it.
+
+Flags:
+`
+
+// runDetach implements the "detach" subcommand and returns the exit
+// code.
+func runDetach(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("detach", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, detachUsage)
+		fs.PrintDefaults()
+	}
+	model := fs.String("model", "", "LLM model to use (overrides config)")
+	profile := fs.String("tool-profile", "", "Tool profile: all, plan, or review")
+	yes := fs.Bool("yes", false, "Approve every tool call without asking (for disposable environments only)")
+	if err := fs.Parse(args); err != nil || fs.NArg() == 0 {
+		if err == nil {
+			fs.Usage()
+		}
+		return 2
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: could not determine working directory: %v\n", err)
+		return 1
+	}
+	if !resolveTrust(cwd) {
+		fmt.Fprintln(stderr, "Error: detach runs project commands and needs a trusted workspace")
+		return 1
+	}
+	root, err := daemon.DefaultRoot()
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	info := daemon.Info{ID: daemon.NewID(), Dir: cwd, Prompt: strings.Join(fs.Args(), " "), Started: time.Now().UTC()}
+	dir, err := daemon.Create(root, info)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	childArgs := []string{"-daemon", info.ID}
+	if *model != "" {
+		childArgs = append(childArgs, "-model", *model)
+	}
+	if *profile != "" {
+		childArgs = append(childArgs, "-tool-profile", *profile)
+	}
+	if *yes {
+		childArgs = append(childArgs, "-yes")
+	}
+	logFile, err := os.OpenFile(filepath.Join(dir, daemon.LogFile), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	defer logFile.Close()
+	cmd := exec.Command(self, childArgs...)
+	cmd.Dir = cwd
+	cmd.Stdout, cmd.Stderr = logFile, logFile
+	if err := cmd.Start(); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	info.PID = cmd.Process.Pid
+	daemon.WriteInfo(dir, info)
+	cmd.Process.Release()
+
+	fmt.Fprintf(stdout, "Started session %s. Follow it with: stormtrooper attach %s\n", info.ID, info.ID)
+	return 0
+}
+
+// runAttach implements the "attach" subcommand and returns the exit
+// code.
+func runAttach(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("attach", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, attachUsage)
+		fs.PrintDefaults()
+	}
+	all := fs.Bool("all", false, "Replay the whole session, not just what you missed")
+	if err := fs.Parse(args); err != nil || fs.NArg() > 1 {
+		if err == nil {
+			fs.Usage()
+		}
+		return 2
+	}
+	root, err := daemon.DefaultRoot()
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	if fs.NArg() == 0 {
+		infos, err := daemon.List(root)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+		if len(infos) == 0 {
+			fmt.Fprintln(stdout, "No detached sessions.")
+			return 0
+		}
+		for _, info := range infos {
+			state := "ended"
+			if daemon.Running(filepath.Join(root, info.ID)) {
+				state = "running"
+			}
+			fmt.Fprintf(stdout, "%s  %-7s  %s  %s\n", info.ID, state, info.Started.Local().Format("2006-01-02 15:04"), info.Dir)
+			fmt.Fprintf(stdout, "%-8s  %s\n", "", oneLinePrompt(info.Prompt))
+		}
+		return 0
+	}
+
+	dir := filepath.Join(root, fs.Arg(0))
+	if _, err := daemon.ReadInfo(dir); err != nil {
+		fmt.Fprintf(stderr, "Error: no detached session %q\n", fs.Arg(0))
+		return 1
+	}
+	ctx, stop := signal.NotifyContext(gocontext.Background(), syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	defer stop()
+	if err := daemon.Attach(ctx, dir, daemon.AttachOptions{All: *all, In: os.Stdin, Out: stdout}); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// listenDaemon starts serving the detached session id, for the process
+// "stormtrooper detach" started.
+func listenDaemon(id string) (*daemon.Server, daemon.Info, error) {
+	root, err := daemon.DefaultRoot()
+	if err != nil {
+		return nil, daemon.Info{}, err
+	}
+	dir := filepath.Join(root, id)
+	info, err := daemon.ReadInfo(dir)
+	if err != nil {
+		return nil, info, err
+	}
+	srv, err := daemon.Listen(dir)
+	return srv, info, err
+}
+
+// runDaemon runs the detached session's prompts until it ends and
+// returns the exit code.
+func runDaemon(ctx gocontext.Context, srv *daemon.Server, info daemon.Info, ag *agent.Agent) int {
+	defer srv.Close()
+	ag.SetOutput(srv.Stdout(), srv.Stderr())
+	srv.Submit(info.Prompt)
+	if err := srv.Run(ctx, ag.Send); err != nil && ctx.Err() == nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gavinyap/stormtrooper/internal/trust"
+)
+
+// projectFlagsFile holds a project's default flags, relative to its root.
+var projectFlagsFile = filepath.Join(".stormtrooper", "flags")
+
+// unsafeProjectFlags cannot be set by a project: they approve tool calls
+// without asking, or decide what the run is rather than how it behaves.
+var unsafeProjectFlags = map[string]bool{
+	"yes":           true,
+	"p":             true,
+	"resume":        true,
+	"daemon":        true,
+	"stress-tokens": true,
+}
+
+// applyProjectFlags sets the flags listed in dir's .stormtrooper/flags
+// that were not given on the command line, so a team can encode how
+// stormtrooper behaves in its repository. The file holds one flag per
+// line, as on the command line ("-tool-profile review"), and lines
+// starting with # are comments. It is read only in a workspace already
+// trusted, since a cloned repository could ship one.
+func applyProjectFlags(set *flag.FlagSet, dir string) error {
+	if !knownTrusted(dir) {
+		return nil
+	}
+	path := filepath.Join(dir, projectFlagsFile)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var args []string
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if !strings.HasPrefix(line, "-") {
+			return fmt.Errorf("%s:%d: %q is not a flag", path, i+1, line)
+		}
+		if name, value, ok := strings.Cut(line, " "); ok {
+			line = name + "=" + strings.TrimSpace(value)
+		}
+		if name, _, _ := strings.Cut(strings.TrimLeft(line, "-"), "="); unsafeProjectFlags[name] {
+			return fmt.Errorf("%s:%d: -%s cannot be set per project", path, i+1, name)
+		}
+		args = append(args, line)
+	}
+
+	// Parse into stand-ins for the real flags that pass on only the
+	// values the command line left unset.
+	onCommandLine := map[string]bool{}
+	set.Visit(func(f *flag.Flag) { onCommandLine[f.Name] = true })
+	project := flag.NewFlagSet(path, flag.ContinueOnError)
+	project.SetOutput(io.Discard)
+	set.VisitAll(func(f *flag.Flag) {
+		project.Var(&projectFlag{target: f, skip: onCommandLine[f.Name]}, f.Name, f.Usage)
+	})
+	if err := project.Parse(args); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// projectFlag stands in for target while a project's flags are parsed.
+type projectFlag struct {
+	target *flag.Flag
+	skip   bool // set on the command line, which wins
+}
+
+func (p *projectFlag) String() string { return "" }
+
+func (p *projectFlag) Set(value string) error {
+	if p.skip {
+		return nil
+	}
+	return p.target.Value.Set(value)
+}
+
+func (p *projectFlag) IsBoolFlag() bool {
+	b, ok := p.target.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// optionalFloat is a float flag that tells a value of 0 from no value,
+// for settings such as the temperature whose zero means something.
+type optionalFloat struct {
+	value *float64
+}
+
+func (f *optionalFloat) String() string {
+	if f.value == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*f.value, 'g', -1, 64)
+}
+
+func (f *optionalFloat) Set(s string) error {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return err
+	}
+	f.value = &v
+	return nil
+}
+
+// listFlag collects the values of a flag given more than once.
+type listFlag []string
+
+func (l *listFlag) String() string { return strings.Join(*l, ",") }
+
+func (l *listFlag) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
+
+// knownTrusted reports whether dir was trusted before, without asking.
+func knownTrusted(dir string) bool {
+	path, err := trust.DefaultPath()
+	if err != nil {
+		return false
+	}
+	store, err := trust.Load(path)
+	if err != nil {
+		return false
+	}
+	trusted, _ := store.Lookup(dir)
+	return trusted
+}
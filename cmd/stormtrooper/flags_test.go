@@ -0,0 +1,144 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gavinyap/stormtrooper/internal/trust"
+)
+
+// projectDir returns a workspace with the given .stormtrooper/flags,
+// trusted or not, under a fresh home directory.
+func projectDir(t *testing.T, flags string, trusted bool) string {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, ".stormtrooper"), 0755)
+	if err := os.WriteFile(filepath.Join(dir, projectFlagsFile), []byte(flags), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if trusted {
+		path, err := trust.DefaultPath()
+		if err != nil {
+			t.Fatal(err)
+		}
+		store, _ := trust.Load(path)
+		store.Set(dir, true)
+		if err := store.Save(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// testFlags is a flag set like the command line's, parsed from args.
+type testFlags struct {
+	set      *flag.FlagSet
+	model    *string
+	profile  *string
+	maxTurns *int
+	verbose  *bool
+	yes      *bool
+	prompt   *string
+}
+
+func parseTestFlags(t *testing.T, args ...string) testFlags {
+	t.Helper()
+	set := flag.NewFlagSet("stormtrooper", flag.ContinueOnError)
+	set.SetOutput(io.Discard)
+	f := testFlags{
+		set:      set,
+		model:    set.String("model", "default-model", ""),
+		profile:  set.String("tool-profile", "", ""),
+		maxTurns: set.Int("max-turns", 10, ""),
+		verbose:  set.Bool("verbose", false, ""),
+		yes:      set.Bool("yes", false, ""),
+		prompt:   set.String("p", "", ""),
+	}
+	set.String("resume", "", "")
+	set.String("daemon", "", "")
+	set.Int("stress-tokens", 0, "")
+	if err := set.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestApplyProjectFlags(t *testing.T) {
+	dir := projectDir(t, "# review defaults\n-tool-profile review\n\n--max-turns=7\n-verbose\n-model project-model\n", true)
+	f := parseTestFlags(t, "-model", "cli-model")
+
+	if err := applyProjectFlags(f.set, dir); err != nil {
+		t.Fatal(err)
+	}
+	if *f.profile != "review" {
+		t.Errorf("-flag value: tool-profile = %q", *f.profile)
+	}
+	if *f.maxTurns != 7 {
+		t.Errorf("-flag=value: max-turns = %d", *f.maxTurns)
+	}
+	if !*f.verbose {
+		t.Error("a bare bool flag should set it")
+	}
+	if *f.model != "cli-model" {
+		t.Errorf("the command line should win, got model %q", *f.model)
+	}
+}
+
+func TestApplyProjectFlags_BoolValue(t *testing.T) {
+	dir := projectDir(t, "-verbose false\n", true)
+	f := parseTestFlags(t)
+	*f.verbose = true
+
+	if err := applyProjectFlags(f.set, dir); err != nil {
+		t.Fatal(err)
+	}
+	if *f.verbose {
+		t.Error("-verbose false should clear the flag")
+	}
+}
+
+func TestApplyProjectFlags_Untrusted(t *testing.T) {
+	dir := projectDir(t, "-model project-model\n-yes\n", false)
+	f := parseTestFlags(t)
+
+	if err := applyProjectFlags(f.set, dir); err != nil {
+		t.Fatalf("an untrusted workspace's flags should be ignored, got %v", err)
+	}
+	if *f.model != "default-model" || *f.yes {
+		t.Errorf("flags changed in an untrusted workspace: model %q, yes %v", *f.model, *f.yes)
+	}
+}
+
+func TestApplyProjectFlags_Unsafe(t *testing.T) {
+	for _, line := range []string{"-yes", "-p fix the build", "--resume=last", "-daemon abc", "-stress-tokens 5000"} {
+		dir := projectDir(t, "-model project-model\n"+line+"\n", true)
+		f := parseTestFlags(t)
+
+		err := applyProjectFlags(f.set, dir)
+		if err == nil || !strings.Contains(err.Error(), "cannot be set per project") {
+			t.Errorf("%s: err = %v", line, err)
+		}
+		if *f.yes || *f.prompt != "" || *f.model != "default-model" {
+			t.Errorf("%s: no flag should be set when the file is refused", line)
+		}
+	}
+}
+
+func TestApplyProjectFlags_Errors(t *testing.T) {
+	for content, want := range map[string]string{
+		"model gpt\n":   `"model gpt" is not a flag`,
+		"-no-such-flag": "not defined",
+		"-max-turns x":  "invalid value",
+	} {
+		dir := projectDir(t, content, true)
+		err := applyProjectFlags(parseTestFlags(t).set, dir)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: err = %v, want %q", content, err, want)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/gavinyap/stormtrooper/internal/agent"
+	"github.com/gavinyap/stormtrooper/internal/inject"
+)
+
+const injectUsage = `Usage: stormtrooper inject [flags] [text]
+
+Hands text, or a tool result with --tool, to the interactive session
+running in this directory (the most recently started one, unless
+--socket names another). The text comes from the arguments, or from
+stdin when there are none. The model sees it with its next request; it
+does not start a turn by itself.
+
+Flags:
+`
+
+// runInject implements the "inject" subcommand and returns the exit code.
+func runInject(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("inject", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, injectUsage)
+		fs.PrintDefaults()
+	}
+	socket := fs.String("socket", "", "Socket of the session to send to (default: the newest in .stormtrooper/run/)")
+	source := fs.String("source", "", "Who is sending, shown to the user and the model (e.g. vim)")
+	toolName := fs.String("tool", "", "Send a tool result from the named tool instead of a message")
+	toolArgs := fs.String("args", "", "JSON object of arguments for --tool")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	text := strings.Join(fs.Args(), " ")
+	if fs.NArg() == 0 {
+		data, err := io.ReadAll(stdin)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: reading stdin: %v\n", err)
+			return 1
+		}
+		text = string(data)
+	}
+	req := inject.Request{Type: inject.KindMessage, Source: *source, Text: text}
+	if *toolName != "" {
+		req = inject.Request{Type: inject.KindToolResult, Source: *source, Name: *toolName, Result: text}
+		if *toolArgs != "" {
+			req.Args = json.RawMessage(*toolArgs)
+		}
+	}
+
+	path := *socket
+	if path == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: could not determine working directory: %v\n", err)
+			return 1
+		}
+		socks, err := inject.Sockets(cwd)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+		if len(socks) == 0 {
+			fmt.Fprintf(stderr, "Error: no interactive session is running in %s\n", cwd)
+			return 1
+		}
+		path = socks[0]
+	}
+	if err := inject.Send(path, req); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// listenForInjections lets other programs hand ag messages and tool
+// results over a socket in dir, calling notice to tell the user about
+// each. It returns nil, after a warning, when the socket cannot be made.
+func listenForInjections(dir string, ag *agent.Agent, notice func(string)) *inject.Server {
+	srv, err := inject.Listen(dir, func(req inject.Request) {
+		switch req.Type {
+		case inject.KindMessage:
+			ag.InjectMessage(req.Source, req.Text)
+			notice(fmt.Sprintf("Received a message from %s; the model sees it with the next request.", req.Source))
+		case inject.KindToolResult:
+			ag.InjectToolResult(req.Name, req.Args, req.Result)
+			notice(fmt.Sprintf("Received a %s result from %s; the model sees it with the next request.", req.Name, req.Source))
+		}
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: other programs cannot send to this session: %v\n", err)
+		return nil
+	}
+	return srv
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	gocontext "context"
+	"crypto/rand"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gavinyap/stormtrooper/internal/kube"
+)
+
+const jobsUsage = `Usage:
+  stormtrooper jobs run --image <image> (--repo <url[@ref]>... | --repos <file>) [flags] <prompt>
+  stormtrooper jobs collect [flags] <batch>
+
+"run" creates one Kubernetes Job per repository. Each job clones the
+repository, runs <prompt> headlessly with every tool approved, and records
+the resulting diff. Unless --detach is given, run waits for the jobs and
+collects their output like "collect" does.
+
+Flags:
+`
+
+// stringList is a repeatable string flag.
+type stringList []string
+
+func (l *stringList) String() string     { return strings.Join(*l, ",") }
+func (l *stringList) Set(v string) error { *l = append(*l, v); return nil }
+
+// runJobs implements the "jobs" subcommand and returns the exit code.
+func runJobs(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || (args[0] != "run" && args[0] != "collect") {
+		fmt.Fprint(stderr, jobsUsage)
+		return 2
+	}
+
+	fs := flag.NewFlagSet("jobs "+args[0], flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, jobsUsage)
+		fs.PrintDefaults()
+	}
+	namespace := fs.String("namespace", "", "Kubernetes namespace (default: the kubeconfig context's)")
+	out := fs.String("out", "", "Directory for collected results (default: stormtrooper-jobs/<batch>)")
+	timeout := fs.Duration("timeout", time.Hour, "Per-job deadline, also the longest time to wait for results")
+	var repos stringList
+	fs.Var(&repos, "repo", "Repository to run against, as URL or URL@ref (repeatable)")
+	reposFile := fs.String("repos", "", "File listing repositories, one URL[@ref] per line")
+	image := fs.String("image", "", "Container image with stormtrooper and git installed")
+	secret := fs.String("secret", "", "Secret whose keys are set as environment variables (e.g. OPENROUTER_API_KEY)")
+	model := fs.String("model", "", "LLM model to use in the jobs")
+	configFile := fs.String("config", "", "config.yaml to install in the jobs")
+	dryRun := fs.Bool("dry-run", false, "Print the Job manifests instead of creating them")
+	detach := fs.Bool("detach", false, "Create the jobs and exit without waiting")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	runner := kube.NewRunner(*namespace)
+	ctx, cancel := gocontext.WithTimeout(gocontext.Background(), *timeout+5*time.Minute)
+	defer cancel()
+
+	var batch string
+	switch args[0] {
+	case "collect":
+		if fs.NArg() != 1 {
+			fs.Usage()
+			return 2
+		}
+		batch = fs.Arg(0)
+
+	case "run":
+		if fs.NArg() == 0 {
+			fs.Usage()
+			return 2
+		}
+		spec := &kube.Spec{
+			Batch:     newBatchID(),
+			Namespace: *namespace,
+			Image:     *image,
+			Prompt:    strings.Join(fs.Args(), " "),
+			Model:     *model,
+			Secret:    *secret,
+			Deadline:  int64(timeout.Seconds()),
+		}
+		for _, r := range repos {
+			spec.Targets = append(spec.Targets, kube.ParseTarget(r))
+		}
+		if *reposFile != "" {
+			data, err := os.ReadFile(*reposFile)
+			if err != nil {
+				fmt.Fprintf(stderr, "Error: %v\n", err)
+				return 1
+			}
+			for _, line := range strings.Split(string(data), "\n") {
+				if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+					spec.Targets = append(spec.Targets, kube.ParseTarget(line))
+				}
+			}
+		}
+		if *configFile != "" {
+			data, err := os.ReadFile(*configFile)
+			if err != nil {
+				fmt.Fprintf(stderr, "Error: %v\n", err)
+				return 1
+			}
+			spec.Config = string(data)
+		}
+
+		if *dryRun {
+			manifests, err := spec.Manifests()
+			if err != nil {
+				fmt.Fprintf(stderr, "Error: %v\n", err)
+				return 1
+			}
+			stdout.Write(manifests)
+			return 0
+		}
+		if err := runner.Submit(ctx, spec); err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(stdout, "Created %d jobs in batch %s\n", len(spec.Targets), spec.Batch)
+		if *detach {
+			fmt.Fprintf(stdout, "Collect results with: stormtrooper jobs collect %s\n", spec.Batch)
+			return 0
+		}
+		batch = spec.Batch
+	}
+
+	results, err := runner.Wait(ctx, batch, stderr)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	dir := *out
+	if dir == "" {
+		dir = filepath.Join("stormtrooper-jobs", batch)
+	}
+	if err := runner.Collect(ctx, results, dir); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	kube.WriteSummary(stdout, results)
+	fmt.Fprintf(stdout, "Results written to %s\n", dir)
+	for _, r := range results {
+		if !r.Succeeded {
+			return 1
+		}
+	}
+	return 0
+}
+
+// newBatchID returns a short random ID usable in Kubernetes names.
+func newBatchID() string {
+	b := make([]byte, 3)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -2,23 +2,53 @@
 package main
 
 import (
+	"bytes"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/gavinyap/stormtrooper/internal/activity"
 	"github.com/gavinyap/stormtrooper/internal/agent"
+	"github.com/gavinyap/stormtrooper/internal/cassette"
+	"github.com/gavinyap/stormtrooper/internal/checkpoint"
+	"github.com/gavinyap/stormtrooper/internal/command"
 	"github.com/gavinyap/stormtrooper/internal/config"
 	projectctx "github.com/gavinyap/stormtrooper/internal/context"
+	"github.com/gavinyap/stormtrooper/internal/daemon"
+	"github.com/gavinyap/stormtrooper/internal/devcontainer"
+	"github.com/gavinyap/stormtrooper/internal/editor"
+	"github.com/gavinyap/stormtrooper/internal/forge"
+	"github.com/gavinyap/stormtrooper/internal/i18n"
+	"github.com/gavinyap/stormtrooper/internal/inject"
 	"github.com/gavinyap/stormtrooper/internal/llm"
+	"github.com/gavinyap/stormtrooper/internal/llm/mock"
+	"github.com/gavinyap/stormtrooper/internal/llmcache"
 	"github.com/gavinyap/stormtrooper/internal/memory"
+	"github.com/gavinyap/stormtrooper/internal/metrics"
+	"github.com/gavinyap/stormtrooper/internal/notify"
 	"github.com/gavinyap/stormtrooper/internal/permission"
+	"github.com/gavinyap/stormtrooper/internal/policy"
+	"github.com/gavinyap/stormtrooper/internal/remote"
 	"github.com/gavinyap/stormtrooper/internal/repl"
+	"github.com/gavinyap/stormtrooper/internal/sandbox"
+	"github.com/gavinyap/stormtrooper/internal/session"
+	"github.com/gavinyap/stormtrooper/internal/staging"
+	"github.com/gavinyap/stormtrooper/internal/termcap"
 	"github.com/gavinyap/stormtrooper/internal/tool"
+	"github.com/gavinyap/stormtrooper/internal/tracker"
+	"github.com/gavinyap/stormtrooper/internal/trust"
 	"github.com/gavinyap/stormtrooper/internal/tui"
+	"github.com/gavinyap/stormtrooper/internal/undo"
+	"github.com/gavinyap/stormtrooper/internal/workflow"
 	"github.com/muesli/termenv"
 
 	gocontext "context"
@@ -30,38 +60,421 @@ func main() {
 	lipgloss.SetColorProfile(termenv.ANSI256)
 	lipgloss.SetHasDarkBackground(true)
 
+	if len(os.Args) > 1 && os.Args[1] == "sessions" {
+		os.Exit(runSessions(os.Args[2:], os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "jobs" {
+		os.Exit(runJobs(os.Args[2:], os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "share" {
+		os.Exit(runShare(os.Args[2:], os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "schedule" {
+		os.Exit(runSchedule(os.Args[2:], os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "detach" {
+		os.Exit(runDetach(os.Args[2:], os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "attach" {
+		os.Exit(runAttach(os.Args[2:], os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "policy" {
+		os.Exit(runPolicy(os.Args[2:], os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "inject" {
+		os.Exit(runInject(os.Args[2:], os.Stdin, os.Stdout, os.Stderr))
+	}
+
 	model := flag.String("model", "", "LLM model to use (overrides config)")
 	noTUI := flag.Bool("no-tui", false, "Use plain REPL instead of TUI")
+	accessible := flag.Bool("accessible", false, "Screen-reader-friendly linear output (implies --no-tui)")
+	record := flag.String("record", "", "Record LLM responses and tool results to a cassette file")
+	replay := flag.String("replay", "", "Replay a cassette file instead of calling the LLM or running tools")
+	metricsAddr := flag.String("metrics-addr", "", "Serve Prometheus metrics at http://<addr>/metrics (e.g. localhost:9090)")
+	stressTokens := flag.Int("stress-tokens", 0, "Measure TUI rendering throughput with N synthetic tokens, then exit")
+	prompt := flag.String("p", "", "Run a single prompt without the UI, print the response, and exit")
+	output := flag.String("output", "text", "What -p prints: text (the response as it streams) or json (the final response, the transcript, and usage)")
+	yes := flag.Bool("yes", false, "Approve every tool call without asking (for disposable environments only)")
+	verbosity := flag.String("verbosity", "", "Answer length: concise, normal, or detailed (overrides config)")
+	maxTokens := flag.Int("max-tokens", 0, "Cap each model response at this many tokens (overrides config)")
+	var temperature, topP optionalFloat
+	var stop listFlag
+	flag.Var(&temperature, "temperature", "Sampling temperature from 0 to 2, such as 0.2 for focused answers (overrides config)")
+	flag.Var(&topP, "top-p", "Sample only from the most likely tokens up to this total probability, above 0 and at most 1 (overrides config)")
+	flag.Var(&stop, "stop", "End each response where this sequence appears; repeat for more (overrides config)")
+	toolProfile := flag.String("tool-profile", "", "Offer the model only the tools in this profile: all, plan, or review (overrides config)")
+	review := flag.Bool("review", false, "Stage file edits for review instead of writing them; apply them with /apply or Ctrl+R")
+	profile := flag.String("profile", "", "Use a provider profile from the config's profiles, such as a local Ollama server")
+	offline := flag.Bool("offline", false, "Use only a local model provider and block tools from reaching the network (for air-gapped environments)")
+	cacheResponses := flag.Bool("cache", false, "Answer repeated model requests from a local cache, so re-running a -p prompt or workflow command skips the requests that already succeeded")
+	colorFlag := flag.String("color", "auto", "Color output: auto, always, or never (auto follows the terminal and NO_COLOR)")
+	resumeRef := flag.String("resume", "", "Continue a saved session: its ID, or \"last\" for the most recent one")
+	daemonID := flag.String("daemon", "", "Serve the detached session with this ID (started by \"stormtrooper detach\")")
 	flag.Parse()
+	if dir, err := os.Getwd(); err == nil {
+		if err := applyProjectFlags(flag.CommandLine, dir); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(2)
+		}
+	}
+
+	// Anything after the flags names a workflow, or "slack" for the bot.
+	var runWorkflow workflowFunc
+	slackMode := flag.Arg(0) == "slack"
+	if flag.NArg() > 0 && !slackMode {
+		run, ok := workflows[flag.Arg(0)]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Error: unknown command %q\n", flag.Arg(0))
+			os.Exit(2)
+		}
+		runWorkflow = run
+	}
+
+	if *stressTokens > 0 {
+		// Offline benchmark: needs no config, API key, or terminal.
+		fmt.Println(tui.Stress(*stressTokens, 120, 40))
+		return
+	}
+
+	if *record != "" && *replay != "" {
+		fmt.Fprintln(os.Stderr, "Error: --record and --replay cannot be used together")
+		os.Exit(1)
+	}
+	if *review && (*prompt != "" || runWorkflow != nil || slackMode || *daemonID != "") {
+		fmt.Fprintln(os.Stderr, "Error: --review needs the TUI or the REPL to apply the staged changes")
+		os.Exit(1)
+	}
+
+	if *resumeRef != "" && (runWorkflow != nil || slackMode || *daemonID != "") {
+		fmt.Fprintln(os.Stderr, "Error: --resume works only with the TUI, the REPL, and -p")
+		os.Exit(1)
+	}
+
+	if *cacheResponses && *prompt == "" && runWorkflow == nil {
+		fmt.Fprintln(os.Stderr, "Error: --cache works only with -p and workflow commands")
+		os.Exit(1)
+	}
+
+	if *output != "text" && *output != "json" {
+		fmt.Fprintf(os.Stderr, "Error: --output must be text or json, got %q\n", *output)
+		os.Exit(2)
+	}
+	if *output == "json" && *prompt == "" {
+		fmt.Fprintln(os.Stderr, "Error: --output json works only with -p")
+		os.Exit(1)
+	}
+
+	colorMode, err := termcap.ParseColorMode(*colorFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	terminal := termcap.Detect(termcap.IsTerminal(os.Stdin), termcap.IsTerminal(os.Stdout), os.Getenv)
+	if !terminal.Color(colorMode) {
+		lipgloss.SetColorProfile(termenv.Ascii)
+	}
+	interactive := *prompt == "" && runWorkflow == nil && !slackMode && *daemonID == ""
+	if interactive && !*noTUI && !*accessible && !terminal.FullScreen() {
+		fmt.Fprintln(os.Stderr, "This terminal cannot show the full-screen UI; using the plain REPL.")
+		*noTUI = true
+	}
+
+	if *accessible {
+		// Linear, labeled output with no styling escape codes.
+		*noTUI = true
+		lipgloss.SetColorProfile(termenv.Ascii)
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: could not determine working directory: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *metricsAddr != "" {
+		go serveMetrics(*metricsAddr)
+	}
+
+	// Decide whether this workspace is trusted before reading anything
+	// from it that could influence the agent.
+	trusted := resolveTrust(cwd)
+	if (runWorkflow != nil || slackMode) && !trusted {
+		fmt.Fprintf(os.Stderr, "Error: %s runs project commands and needs a trusted workspace\n", flag.Arg(0))
+		os.Exit(1)
+	}
+	if *daemonID != "" && !trusted {
+		fmt.Fprintln(os.Stderr, "Error: a detached session needs a trusted workspace")
+		os.Exit(1)
+	}
+	// Sessions are only saved in trusted workspaces, so only they can
+	// be resumed.
+	var resumed *session.Session
+	if *resumeRef != "" {
+		if !trusted {
+			fmt.Fprintln(os.Stderr, "Error: --resume needs a trusted workspace")
+			os.Exit(1)
+		}
+		if resumed, err = session.Resolve(session.Dir(cwd), *resumeRef); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
 
 	// Load config.
-	cfg, err := config.Load(*model)
+	loadOpts := config.LoadOptions{
+		CLIModel:       *model,
+		CLIVerbosity:   *verbosity,
+		CLIMaxTokens:   *maxTokens,
+		CLITemperature: temperature.value,
+		CLITopP:        topP.value,
+		CLIStop:        stop,
+		CLIToolProfile: *toolProfile,
+		CLIOffline:     *offline,
+		CLIProfile:     *profile,
+		SkipProject:    !trusted,
+		// Replay never contacts the provider, so no key is needed.
+		AllowMissingKey: *replay != "",
+	}
+	cfg, err := config.LoadWithOptions(loadOpts)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
+	i18n.SetLanguage(i18n.Detect(cfg.Language))
+
+	if cfg.Offline {
+		if slackMode {
+			fmt.Fprintln(os.Stderr, "Error: the Slack bot needs the network and cannot run offline")
+			os.Exit(1)
+		}
+		// Fail closed: requests from this process to anything but this
+		// machine error out, and the sessions it starts stay offline.
+		http.DefaultTransport = &tool.OfflineTransport{Base: http.DefaultTransport}
+		os.Setenv(config.OfflineEnv, "1")
+	}
+
+	// An organization policy, once installed, has the last word over
+	// config and flags.
+	orgPolicy, err := policy.Load(gocontext.Background())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	var enforcer *policy.Enforcer
+	if orgPolicy != nil {
+		for _, m := range []string{cfg.Model, cfg.Summarize.Model, cfg.Compact.Model, cfg.Route.Model, cfg.SubagentSummary.Model} {
+			if m != "" && !orgPolicy.AllowsModel(m) {
+				fmt.Fprintf(os.Stderr, "Error: the organization policy does not allow the model %s (allowed: %s)\n", m, strings.Join(orgPolicy.AllowedModels, ", "))
+				os.Exit(1)
+			}
+		}
+		if enforcer, err = orgPolicy.Enforce(cwd); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: organization policy: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
 	// Create LLM client.
 	client := llm.NewClient(cfg.APIKey)
 	if cfg.BaseURL != "" {
 		client.SetBaseURL(cfg.BaseURL)
 	}
+	client.SetConcurrency(cfg.Concurrency)
+	client.SetMaxLineSize(cfg.MaxStreamLineMB << 20)
+	client.SetRetry(cfg.Retry.MaxRetries, cfg.Retry.BaseDelay)
+	client.SetToolCalling(cfg.ToolCalling)
+	if enforcer != nil {
+		client.SetGuard(enforcer)
+	}
+	if cfg.Provider == config.ProviderMock {
+		script, err := mock.Load(cfg.MockScript)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: mock provider: %v\n", err)
+			os.Exit(1)
+		}
+		client.SetTransport(script.Transport())
+	}
 
-	// Create tool registry and register all tools.
-	cwd, err := os.Getwd()
+	// Headless runs may reuse responses from earlier runs. The cassette
+	// goes in front, so a recording holds cached responses too.
+	var respCache *llmcache.Cache
+	if *cacheResponses {
+		dir, err := llmcache.DefaultDir()
+		if err == nil {
+			respCache, err = llmcache.Open(dir)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		client.SetTransport(respCache.Transport(client.Transport()))
+	}
+
+	// Open the cassette, if any, and route LLM traffic through it.
+	var tape *cassette.Cassette
+	switch {
+	case *record != "":
+		tape, err = cassette.Record(*record)
+	case *replay != "":
+		tape, err = cassette.Load(*replay)
+	}
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: could not determine working directory: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Error: cassette: %v\n", err)
 		os.Exit(1)
 	}
+	if tape != nil {
+		client.SetTransport(tape.Transport(client.Transport()))
+	}
+
+	// Route shell_exec into a sandbox container when one is configured,
+	// or into the project's dev container if the user agrees.
+	sandboxCfg := cfg.Sandbox
+	if trusted && sandboxCfg.Image == "" && cfg.Remote.Host == "" && cfg.Devcontainer != "never" {
+		sandboxCfg = resolveDevcontainer(cwd, cfg)
+	}
+	var executor tool.Executor
+	var box *sandbox.Container
+	if trusted && sandboxCfg.Image != "" {
+		box = sandbox.New(sandboxCfg, cwd)
+		fmt.Fprintf(os.Stderr, "Starting sandbox container from %s...\n", sandboxCfg.Image)
+		if err := box.Start(gocontext.Background()); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		executor = box
+	}
 
+	// Or run shell_exec and the file tools on a remote host over SSH.
+	var files tool.FileSystem
+	var rem *remote.SSH
+	if trusted && cfg.Remote.Host != "" {
+		rem = remote.New(cfg.Remote)
+		executor, files = rem, rem
+	}
+	// Otherwise commands run on the host, without the secrets in our
+	// own environment.
+	if executor == nil {
+		if cfg.Offline {
+			if err := tool.NetworkIsolation(gocontext.Background()); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: offline mode cannot cut commands on this host off from the network (%v); configure a sandbox container instead\n", err)
+				os.Exit(1)
+			}
+		}
+		executor = tool.LocalExecutor{Env: &tool.EnvFilter{Allow: cfg.ShellEnv.Allow, Strip: cfg.ShellEnv.Strip}, NoNetwork: cfg.Offline}
+	}
+	shellRoot := cwd
+	if rem != nil {
+		shellRoot = cfg.Remote.WorkDir
+	}
+
+	// In review mode, hold the agent's writes in memory until the user
+	// applies them.
+	var overlay *staging.Overlay
+	if *review {
+		overlay = staging.New(files)
+		files = overlay
+	}
+
+	// Snapshot the files the agent writes so /rewind can restore them.
+	checkpoints := checkpoint.New(files)
+	files = checkpoints.FS()
+
+	// And log each write, with a copy of the file before it, so a single
+	// change can be undone with /undo or undo_last_edit, even in a later
+	// session. Untrusted workspaces are never written.
+	var undos *undo.Log
+	if trusted {
+		if undos, err = undo.Open(undo.Dir(cwd), files); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: undo log disabled: %v\n", err)
+		} else {
+			files = undos.FS()
+		}
+	}
+
+	// Create tool registry and register all tools. File-modifying and
+	// command tools are only available in trusted workspaces.
 	registry := tool.NewRegistry()
-	registry.Register(&tool.ReadFileTool{})
-	registry.Register(&tool.WriteFileTool{})
-	registry.Register(&tool.EditFileTool{})
-	registry.Register(&tool.ShellExecTool{})
-	registry.Register(&tool.GlobTool{})
-	registry.Register(&tool.GrepTool{})
-	registry.Register(&tool.MemoryWriteTool{MemoryDir: memory.Dir(cwd)})
+	if enforcer != nil {
+		registry.SetGuard(enforcer)
+	}
+	registry.Register(&tool.ReadFileTool{FS: files})
+	if !cfg.Offline {
+		registry.Register(&tool.PackageInfoTool{})
+	}
+	registry.Register(&tool.ReadClipboardTool{})
+	// The scratchpad lives in a temporary directory outside the project,
+	// so it is safe in untrusted workspaces too.
+	scratchpad := &tool.Scratchpad{}
+	for _, t := range scratchpad.Tools() {
+		registry.Register(t)
+	}
+	capture := &tool.CaptureTerminalTool{Pad: scratchpad}
+	registry.Register(capture)
+	if trusted {
+		registry.Register(&tool.WriteFileTool{FS: files})
+		registry.Register(&tool.WriteFilesTool{FS: files})
+		registry.Register(&tool.EditFileTool{FS: files})
+		if undos != nil {
+			registry.Register(&undo.LastEditTool{Log: undos})
+		}
+		registry.Register(&tool.ShellExecTool{Executor: executor, Root: shellRoot})
+		// git runs the repository's hooks and config, so only here. In
+		// review mode the agent's changes are not on disk to commit.
+		git := &tool.Git{Executor: executor, Root: shellRoot}
+		registry.Register(&tool.GitStatusTool{Git: git})
+		registry.Register(&tool.GitDiffTool{Git: git})
+		if overlay == nil {
+			registry.Register(&tool.GitCommitTool{Git: git})
+		}
+		registry.Register(&tool.HTTPRequestTool{})
+	}
+	if rem == nil {
+		// glob and grep search the local tree; remotely the agent uses
+		// shell_exec with find and grep instead.
+		registry.Register(&tool.GlobTool{})
+		registry.Register(&tool.GrepTool{})
+	}
+	if trusted {
+		registry.Register(&tool.MemoryWriteTool{MemoryDir: memory.Dir(cwd)})
+		// Issue and pull request tools for the forge the origin remote
+		// points at; silently absent outside a git checkout.
+		if origin, err := forge.OriginRemote(cwd); err == nil && !cfg.Offline {
+			f, err := forge.New(cfg.Forge, origin)
+			switch {
+			case err == nil:
+				for _, t := range forge.Tools(f) {
+					registry.Register(t)
+				}
+			case !errors.Is(err, forge.ErrNoForge):
+				fmt.Fprintf(os.Stderr, "Warning: forge tools disabled: %v\n", err)
+			}
+		}
+		if len(cfg.Databases) > 0 {
+			dbs := make(map[string]tool.Database)
+			for name, db := range cfg.Databases {
+				d := tool.Database{Driver: db.Driver, DSN: db.DSN, AllowWrites: db.AllowWrites}
+				if cfg.Offline && !d.Local() {
+					fmt.Fprintf(os.Stderr, "Warning: database %s is not on this machine; skipped in offline mode\n", name)
+					continue
+				}
+				dbs[name] = d
+			}
+			if len(dbs) > 0 {
+				registry.Register(&tool.DBQueryTool{Databases: dbs, Env: &tool.EnvFilter{Allow: cfg.ShellEnv.Allow, Strip: cfg.ShellEnv.Strip}})
+			}
+		}
+		if cfg.Tracker.Type != "" && !cfg.Offline {
+			tr, err := tracker.New(cfg.Tracker)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: issue tracker tools disabled: %v\n", err)
+			} else {
+				for _, t := range tracker.Tools(tr) {
+					registry.Register(t)
+				}
+			}
+		}
+	}
 
 	// Load project context and build system prompt.
 	projCtx, err := projectctx.Load(cwd)
@@ -69,22 +482,294 @@ func main() {
 		fmt.Fprintf(os.Stderr, "Warning: could not load project context: %v\n", err)
 		projCtx = &projectctx.ProjectContext{WorkingDir: cwd}
 	}
+	if !trusted {
+		// Project instructions and memory are attacker-controlled in an
+		// unfamiliar checkout; keep them out of the system prompt.
+		projCtx.Instructions = ""
+		projCtx.Memory = ""
+		fmt.Fprintln(os.Stderr, "Workspace not trusted: project instructions, memory and config were not loaded; file-modifying tools are disabled.")
+	}
+	projCtx.Verbosity = cfg.Verbosity
 	systemPrompt := projCtx.BuildSystemPrompt()
+	if rem != nil {
+		systemPrompt += "\n\nTools run on the remote host " + rem.Describe() +
+			" over SSH. Relative paths resolve against the remote working directory. Use shell_exec with find or grep to search files."
+	}
+	if cfg.Offline {
+		systemPrompt += "\n\nThis is an air-gapped environment: commands and tools cannot reach the network, so work with what is installed and do not try to download anything."
+	}
 
 	// Create permission checker.
-	perm := permission.NewChecker()
+	checker := permission.NewChecker()
+	checker.SetAccessible(*accessible)
+	checker.SetColor(terminal.Color(colorMode))
+	var perm permission.Handler = checker
+	if *yes {
+		perm = permission.AllowAll{}
+	}
+	// A detached session serves its clients from here on, and asks them,
+	// sub-agents included, instead of the terminal nobody is watching.
+	var daemonSrv *daemon.Server
+	var daemonInfo daemon.Info
+	if *daemonID != "" {
+		if daemonSrv, daemonInfo, err = listenDaemon(*daemonID); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if !*yes {
+			perm = daemonSrv
+		}
+	}
+
+	// Approvals remembered with "always allow" are kept per project. A
+	// cloned repository could ship its own, so only trusted workspaces
+	// read them.
+	var rules *permission.Rules
+	if trusted {
+		if rules, err = permission.LoadRules(cwd); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: remembered approvals not loaded: %v\n", err)
+		}
+	}
+
+	// Tool results too long for one message are kept whole for the
+	// session, and the model reads them a page at a time with read_more.
+	pages := agent.NewPages(0)
+	registry.Register(&agent.ReadMoreTool{Pages: pages})
+
+	// Register spawn_agent and the tools for steering background
+	// sub-agents (needs client, registry, and permission checker).
+	spawner := agent.NewSpawnAgentTool(client, registry, perm, cfg.Model)
+	spawner.Rules = rules
+	spawner.Pages = pages
+	spawner.Summary = agent.SubagentSummaryOptions{Threshold: cfg.SubagentSummary.Threshold, Model: cfg.SubagentSummary.Model, Pad: scratchpad}
+	registry.Register(spawner)
+	for _, t := range spawner.Tools() {
+		registry.Register(t)
+	}
+	registry.Register(&agent.SpawnParallelTool{Spawner: spawner})
+	registry.Register(&agent.HandoffTool{Spawner: spawner, SystemPrompt: systemPrompt, FS: files})
 
-	// Register spawn_agent tool (needs client, registry, and permission checker).
-	registry.Register(agent.NewSpawnAgentTool(client, registry, perm, cfg.Model))
+	// Record tool results, or serve them from the cassette without
+	// touching the filesystem.
+	if tape != nil {
+		registry = tape.WrapRegistry(registry)
+	}
 
 	// Create root agent.
-	rootAgent := agent.New(agent.Options{
-		Client:       client,
-		Registry:     registry,
-		Permission:   perm,
-		Model:        cfg.Model,
-		SystemPrompt: systemPrompt,
-	})
+	pins := agent.NewPins(files)
+	agentOpts := agent.Options{
+		Client:           client,
+		Registry:         registry,
+		Permission:       perm,
+		Model:            cfg.Model,
+		SystemPrompt:     systemPrompt,
+		Checkpoints:      checkpoints,
+		Pins:             pins,
+		MaxTokens:        cfg.ResponseMaxTokens(),
+		MaxContinuations: cfg.MaxContinuations,
+		ToolProfile:      tool.Profiles[cfg.ToolProfile],
+		Prune:            agent.PruneOptions{AfterTurns: max(cfg.Prune.AfterTurns, 0), MinBytes: cfg.Prune.MinBytes},
+		Summarize:        agent.SummarizeOptions{Model: cfg.Summarize.Model, MinTokens: cfg.Summarize.MinTokens, Pad: scratchpad},
+		Compact:          agent.CompactOptions{Threshold: max(cfg.Compact.Threshold, 0), KeepTurns: cfg.Compact.KeepTurns, Model: cfg.Compact.Model},
+		Route:            agent.RouteOptions{Model: cfg.Route.Model, MaxChars: cfg.Route.MaxChars},
+		Stop:             cfg.Stop,
+		Temperature:      cfg.Temperature,
+		TopP:             cfg.TopP,
+		ModelSampling:    agent.ConfigSampling(cfg),
+		Rules:            rules,
+		RepeatGuard:      agent.RepeatGuardOptions{MinRepeats: cfg.RepeatGuard.MinRepeats, Penalty: cfg.RepeatGuard.Penalty},
+		Prices:           agent.ConfigPrices(cfg),
+		Pages:            pages,
+	}
+	rootAgent := agent.New(agentOpts)
+	capture.Outputs = rootAgent.ToolOutputs
+	// Trusted runs keep a line per change in .stormtrooper/activity.md
+	// for reviewers to skim afterwards.
+	var activityLog *activity.Log
+	if trusted {
+		activityLog = activity.New(cwd, runTitle(flag.Args(), *prompt, *daemonID))
+		rootAgent.OnToolResult(activityLog.ToolResult)
+	}
+	// The conversation is saved after every turn, so a crash or a closed
+	// terminal loses at most the turn in progress. A resumed session
+	// keeps its ID and is saved back to its own file.
+	sess := session.New(cwd, cfg.Model)
+	if resumed != nil {
+		rootAgent.Restore(resumed.Messages)
+		sess = resumed
+	}
+	if trusted {
+		rootAgent.OnTurn(func() { saveSession(sess, rootAgent) })
+	}
+	commands := command.NewDispatcher()
+	commands.Register(command.Rewind(rootAgent))
+	commands.Register(command.Pin(pins))
+	commands.Register(command.Unpin(pins))
+	commands.Register(command.Stats(client.Stats()))
+	commands.Register(command.Context(rootAgent))
+	commands.Register(command.Usage(rootAgent))
+	commands.Register(command.Compact(rootAgent))
+	commands.Register(command.Clear(rootAgent))
+	var allowedModel func(string) bool
+	if orgPolicy != nil {
+		allowedModel = orgPolicy.AllowsModel
+	}
+	commands.Register(command.Model(rootAgent, allowedModel, client.ListModels))
+	commands.Register(command.Tools(rootAgent, registry))
+	commands.Register(command.RunTool(rootAgent, registry))
+	// Files are only opened in an editor when they are on this machine.
+	var ed *editor.Editor
+	if rem == nil {
+		ed = editor.New(cfg.Editor, cwd, os.Getenv)
+		commands.Register(command.Open(ed))
+	}
+	if overlay != nil {
+		commands.Register(command.Changes(overlay))
+		commands.Register(command.Apply(overlay))
+		commands.Register(command.Discard(overlay))
+	}
+	if trusted {
+		commands.Register(command.Memory(cwd))
+		if undos != nil {
+			commands.Register(command.Undo(undos))
+		}
+		commands.Register(command.Resume(session.Dir(cwd), func(s *session.Session) (string, error) {
+			if s.ID == sess.ID {
+				return "", fmt.Errorf("session %s is already open", s.ID)
+			}
+			saveSession(sess, rootAgent)
+			rootAgent.Restore(s.Messages)
+			sess = s
+			return fmt.Sprintf("Resumed session %s (%d prompt(s)).", s.ID, len(s.Prompts())), nil
+		}))
+	}
+
+	// On exit, save the conversation so it can be compared later with
+	// "stormtrooper sessions diff" or resumed (untrusted workspaces are
+	// never written), stop background sub-agents, and remove the sandbox
+	// container and the scratchpad. Interactive sessions also close the
+	// socket other programs inject messages through.
+	var injectSrv *inject.Server
+	cleanup := func() {
+		if trusted {
+			saveSession(sess, rootAgent)
+		}
+		if injectSrv != nil {
+			injectSrv.Close()
+		}
+		if box != nil {
+			box.Stop(gocontext.Background())
+		}
+		spawner.Close()
+		scratchpad.Close()
+		if overlay != nil && overlay.Len() > 0 {
+			fmt.Fprintf(os.Stderr, "Discarded %d staged change(s) that were never applied.\n", overlay.Len())
+		}
+		if respCache != nil {
+			if hits, misses := respCache.Stats(); hits > 0 {
+				fmt.Fprintf(os.Stderr, "Answered %d of %d model requests from the response cache.\n", hits, hits+misses)
+			}
+		}
+		if stats := client.Stats().String(); stats != "" {
+			fmt.Fprintf(os.Stderr, "Model latency this session:\n%s\n", stats)
+		}
+		if activityLog != nil {
+			activityLog.Close()
+		}
+		if enforcer != nil {
+			enforcer.Close()
+		}
+	}
+	defer cleanup()
+
+	// Headless runs report how they ended to the configured webhooks
+	// and email addresses.
+	var notifier *notify.Notifier
+	if !cfg.Offline {
+		notifier = notify.New(cfg.Notify)
+	}
+	started := time.Now()
+
+	if runWorkflow != nil {
+		// The agent's narration goes to stderr so stdout carries only
+		// the workflow's report, which is also kept for notifications.
+		rootAgent.SetOutput(os.Stderr, os.Stderr)
+		ctx, stop := signal.NotifyContext(gocontext.Background(), syscall.SIGINT, syscall.SIGTERM)
+		env := workflow.Env{Agent: rootAgent, Executor: executor, Dir: cwd, Log: os.Stderr, Permission: perm}
+		var output bytes.Buffer
+		code := runWorkflow(ctx, flag.Args()[1:], env, io.MultiWriter(os.Stdout, &output), os.Stderr)
+		stop()
+		cleanup()
+		var runErr error
+		if code != 0 {
+			runErr = fmt.Errorf("exited with status %d", code)
+		}
+		notifyRun(notifier, notify.Report{Command: flag.Arg(0), Dir: cwd, Err: runErr, Duration: time.Since(started), Output: output.String()}, sess, rootAgent)
+		os.Exit(code)
+	}
+
+	if slackMode {
+		// One session per Slack thread. Permission prompts become buttons
+		// in the thread unless --yes approves everything; /rewind and
+		// /pin are TUI features, so the sessions go without them.
+		newAgent := func(p permission.Handler) *agent.Agent {
+			opts := agentOpts
+			opts.Checkpoints, opts.Pins = nil, nil
+			if !*yes {
+				opts.Permission = p
+			}
+			ag := agent.New(opts)
+			ag.OnToolResult(activityLog.ToolResult)
+			return ag
+		}
+		ctx, stop := signal.NotifyContext(gocontext.Background(), syscall.SIGINT, syscall.SIGTERM)
+		code := runSlack(ctx, cfg.Slack, newAgent, os.Stderr)
+		stop()
+		cleanup()
+		os.Exit(code)
+	}
+
+	if daemonSrv != nil {
+		// Keep going when the terminal that started us hangs up.
+		signal.Ignore(syscall.SIGHUP)
+		ctx, stop := signal.NotifyContext(gocontext.Background(), syscall.SIGINT, syscall.SIGTERM)
+		code := runDaemon(ctx, daemonSrv, daemonInfo, rootAgent)
+		stop()
+		cleanup()
+		os.Exit(code)
+	}
+
+	if *prompt != "" {
+		// Headless: one prompt, response streamed to stdout, tool status
+		// on stderr, non-zero exit on failure. With --output json, stdout
+		// gets only the JSON result, written after the run.
+		report := notify.Report{Command: promptCommand(*prompt), Dir: cwd}
+		if *output == "json" {
+			rootAgent.SetOutput(io.Discard, os.Stderr)
+		}
+		err := rootAgent.Send(gocontext.Background(), *prompt)
+		if *output == "json" {
+			if werr := writePromptJSON(os.Stdout, *prompt, rootAgent, err, time.Since(started)); werr != nil && err == nil {
+				err = werr
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			cleanup()
+			if trusted {
+				writePostMortem(sess, err)
+			}
+			report.Err, report.Duration = err, time.Since(started)
+			notifyRun(notifier, report, sess, rootAgent)
+			os.Exit(1)
+		}
+		if *output == "text" {
+			fmt.Println()
+		}
+		report.Duration, report.Output = time.Since(started), lastResponse(rootAgent)
+		notifyRun(notifier, report, sess, rootAgent)
+		return
+	}
 
 	if *noTUI {
 		// REPL mode — existing behavior unchanged.
@@ -100,23 +785,185 @@ func main() {
 			os.Exit(1)
 		}()
 
+		// Hot-reload config; report changes on stderr like other status lines.
+		current := cfg
+		go config.NewWatcher(loadOpts, config.DefaultWatchInterval).Run(ctx, func(newCfg *config.Config, err error) {
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "\n[config] reload failed: %v\n", err)
+				return
+			}
+			for _, change := range config.Changes(current, newCfg) {
+				fmt.Fprintf(os.Stderr, "\n[config] %s\n", change)
+			}
+			// Keep a model picked with /model unless the config's changed.
+			if newCfg.Model != current.Model {
+				rootAgent.SetModel(newCfg.Model)
+			}
+			rootAgent.SetMaxTokens(newCfg.ResponseMaxTokens())
+			rootAgent.SetPrices(agent.ConfigPrices(newCfg))
+			rootAgent.SetSampling(newCfg.Temperature, newCfg.TopP, agent.ConfigSampling(newCfg))
+			current = newCfg
+		})
+
+		if trusted {
+			injectSrv = listenForInjections(cwd, rootAgent, func(text string) {
+				fmt.Fprintf(os.Stderr, "\n[inject] %s\n", text)
+			})
+		}
+
+		if *accessible {
+			rootAgent.SetOutput(os.Stdout, repl.NewAccessibleWriter(os.Stderr))
+		}
+
 		r := repl.New(rootAgent, "0.2.5")
+		r.SetAccessible(*accessible)
+		r.SetCommands(commands)
 		if err := r.Run(ctx); err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			cleanup()
 			os.Exit(1)
 		}
 	} else {
-		// TUI mode — Bubble Tea handles signals via tea.KeyMsg.
+		// TUI mode — Bubble Tea handles signals via tea.KeyMsg. File
+		// paths are only hyperlinked when they are on this machine.
+		var links *tui.Linker
+		if rem == nil {
+			links = tui.NewLinker(cfg.Hyperlinks, cwd, os.Getenv)
+		}
 		app := tui.New(tui.Options{
 			Agent:      rootAgent,
 			Config:     cfg,
 			ProjectCtx: projCtx,
 			Version:    "0.2.5",
+			Restricted: !trusted,
+			Commands:   commands,
+			Links:      links,
+			Editor:     ed,
+			Staging:    overlay,
+			Pins:       pins,
 		})
 		p := tea.NewProgram(app, tea.WithAltScreen())
+
+		ctx, cancel := gocontext.WithCancel(gocontext.Background())
+		defer cancel()
+		go config.NewWatcher(loadOpts, config.DefaultWatchInterval).Run(ctx, func(newCfg *config.Config, err error) {
+			p.Send(tui.ConfigReloadMsg{Config: newCfg, Err: err})
+		})
+		if trusted {
+			injectSrv = listenForInjections(cwd, rootAgent, func(text string) {
+				p.Send(tui.NoticeMsg{Text: text})
+			})
+		}
+
 		if _, err := p.Run(); err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			cleanup()
 			os.Exit(1)
 		}
 	}
 }
+
+// serveMetrics exposes the metrics endpoint until the process exits.
+func serveMetrics(addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: metrics server: %v\n", err)
+	}
+}
+
+// saveSession writes the agent's conversation to the project's sessions
+// directory. Conversations without a user message are not saved.
+func saveSession(sess *session.Session, ag *agent.Agent) {
+	sess.Messages = ag.History()
+	sess.Model = ag.Model()
+	if len(sess.Prompts()) == 0 {
+		return
+	}
+	if err := sess.Save(session.Dir(sess.WorkingDir)); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not save session: %v\n", err)
+	}
+}
+
+// writePostMortem saves a triage report for a failed headless run next
+// to the session file. saveSession must have run first.
+func writePostMortem(sess *session.Session, runErr error) {
+	path, err := sess.SavePostMortem(session.Dir(sess.WorkingDir), runErr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not write post-mortem: %v\n", err)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Post-mortem written to %s\n", path)
+}
+
+// resolveDevcontainer returns a sandbox configuration for the project's
+// devcontainer.json, asking first unless config says "always". It returns
+// the unchanged sandbox config when there is no dev container or the user
+// declines.
+func resolveDevcontainer(dir string, cfg *config.Config) config.SandboxConfig {
+	dc, err := devcontainer.Find(dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		return cfg.Sandbox
+	}
+	if dc == nil {
+		return cfg.Sandbox
+	}
+	if cfg.Devcontainer != "always" {
+		if use, err := devcontainer.Ask(os.Stdin, os.Stderr, dc.Path); err != nil || !use {
+			return cfg.Sandbox
+		}
+	}
+	fmt.Fprintln(os.Stderr, "Preparing dev container image...")
+	sb, err := dc.Sandbox(gocontext.Background(), cfg.Sandbox.Engine, cfg.Offline)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	return sb
+}
+
+// resolveTrust reports whether dir is trusted, asking the user the first
+// time stormtrooper runs there and remembering the answer in
+// ~/.stormtrooper/trusted.json.
+func resolveTrust(dir string) bool {
+	path, err := trust.DefaultPath()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not locate trust store: %v\n", err)
+		return false
+	}
+	store, err := trust.Load(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		return false
+	}
+	if trusted, known := store.Lookup(dir); known {
+		return trusted
+	}
+
+	trusted, err := trust.Ask(os.Stdin, os.Stderr, dir)
+	if err != nil {
+		// No answer (e.g. stdin closed): stay restricted but ask again next time.
+		return false
+	}
+	store.Set(dir, trusted)
+	if err := store.Save(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not save trust decision: %v\n", err)
+	}
+	return trusted
+}
+
+// runTitle names this run in the activity log.
+func runTitle(args []string, prompt, daemonID string) string {
+	switch {
+	case len(args) > 0 && args[0] == "slack":
+		return "Slack bot"
+	case len(args) > 0:
+		return "stormtrooper " + strings.Join(args, " ")
+	case daemonID != "":
+		return "detached session " + daemonID
+	case prompt != "":
+		return promptCommand(prompt)
+	}
+	return "interactive session"
+}
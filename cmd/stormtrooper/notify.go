@@ -0,0 +1,49 @@
+package main
+
+import (
+	gocontext "context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/gavinyap/stormtrooper/internal/agent"
+	"github.com/gavinyap/stormtrooper/internal/notify"
+	"github.com/gavinyap/stormtrooper/internal/session"
+)
+
+// notifyRun sends the report of a headless run, adding a post-mortem if
+// it failed. Delivery problems are warnings; they never change the exit
+// status.
+func notifyRun(n *notify.Notifier, r notify.Report, sess *session.Session, ag *agent.Agent) {
+	if n == nil {
+		return
+	}
+	if r.Err != nil {
+		sess.Messages = ag.History()
+		r.PostMortem = sess.PostMortem(r.Err).Markdown()
+	}
+	ctx, cancel := gocontext.WithTimeout(gocontext.Background(), time.Minute)
+	defer cancel()
+	if err := n.Notify(ctx, r); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not send notification: %v\n", err)
+	}
+}
+
+// lastResponse returns the agent's final answer.
+func lastResponse(ag *agent.Agent) string {
+	history := ag.History()
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].Role == "assistant" && history[i].Content != "" {
+			return history[i].Content
+		}
+	}
+	return ""
+}
+
+// promptCommand describes a -p run for notifications.
+func promptCommand(prompt string) string {
+	if len(prompt) > 60 {
+		prompt = prompt[:57] + "..."
+	}
+	return fmt.Sprintf("-p %q", prompt)
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	gocontext "context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gavinyap/stormtrooper/internal/policy"
+)
+
+const policyUsage = `Usage:
+  stormtrooper policy [show]
+  stormtrooper policy keygen <dir>
+  stormtrooper policy sign --key <private key> <policy.yaml>
+
+Manages the organization policy. "show" prints the policy in force.
+"keygen" writes a signing key pair into <dir>: install policy.pub in the
+system policy directory and keep policy.key secret. "sign" writes
+<policy.yaml>.sig next to the policy; install both, or serve both and
+put the policy's URL in policy.url.
+
+Flags:
+`
+
+// runPolicy implements the "policy" subcommand and returns the exit
+// code.
+func runPolicy(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		args = []string{"show"}
+	}
+	fs := flag.NewFlagSet("policy "+args[0], flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, policyUsage)
+		fs.PrintDefaults()
+	}
+
+	switch args[0] {
+	case "show":
+		if err := fs.Parse(args[1:]); err != nil || fs.NArg() > 0 {
+			if err == nil {
+				fs.Usage()
+			}
+			return 2
+		}
+		p, err := policy.Load(gocontext.Background())
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+		if p == nil {
+			fmt.Fprintf(stdout, "No organization policy: there is no key at %s.\n", filepath.Join(policy.SystemDir, policy.KeyFile))
+			return 0
+		}
+		printPolicy(stdout, p)
+		return 0
+
+	case "keygen":
+		if err := fs.Parse(args[1:]); err != nil || fs.NArg() != 1 {
+			if err == nil {
+				fs.Usage()
+			}
+			return 2
+		}
+		dir := fs.Arg(0)
+		pubPath, keyPath := filepath.Join(dir, policy.KeyFile), filepath.Join(dir, "policy.key")
+		for _, name := range []string{pubPath, keyPath} {
+			if _, err := os.Stat(name); err == nil {
+				fmt.Fprintf(stderr, "Error: %s already exists\n", name)
+				return 1
+			}
+		}
+		pub, priv, err := policy.GenerateKey()
+		if err == nil {
+			err = os.MkdirAll(dir, 0700)
+		}
+		if err == nil {
+			err = os.WriteFile(keyPath, priv, 0600)
+		}
+		if err == nil {
+			err = os.WriteFile(pubPath, pub, 0644)
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(stdout, "Wrote %s (install it in %s) and %s (keep it secret).\n", pubPath, policy.SystemDir, keyPath)
+		return 0
+
+	case "sign":
+		key := fs.String("key", "", "Private key written by \"policy keygen\" (required)")
+		if err := fs.Parse(args[1:]); err != nil || fs.NArg() != 1 || *key == "" {
+			if err == nil {
+				fs.Usage()
+			}
+			return 2
+		}
+		name := fs.Arg(0)
+		if err := signPolicy(*key, name); err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(stdout, "Wrote %s.\n", name+policy.SigSuffix)
+		return 0
+	}
+	fmt.Fprint(stderr, policyUsage)
+	return 2
+}
+
+// signPolicy checks the policy at name and writes its signature.
+func signPolicy(keyPath, name string) error {
+	priv, err := os.ReadFile(keyPath)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	if _, err := policy.Parse(data); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	sig, err := policy.Sign(priv, data)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(name+policy.SigSuffix, sig, 0644)
+}
+
+func printPolicy(w io.Writer, p *policy.Policy) {
+	fmt.Fprintf(w, "Organization policy from %s\n", p.Source)
+	if len(p.AllowedModels) > 0 {
+		fmt.Fprintf(w, "  Allowed models: %s\n", strings.Join(p.AllowedModels, ", "))
+	} else {
+		fmt.Fprintln(w, "  Allowed models: any")
+	}
+	for _, rule := range p.Deny {
+		tool := rule.Tool
+		if tool == "" {
+			tool = "*"
+		}
+		if rule.Match != "" {
+			tool += " matching " + rule.Match
+		}
+		fmt.Fprintf(w, "  Denied: %s", tool)
+		if rule.Reason != "" {
+			fmt.Fprintf(w, " (%s)", rule.Reason)
+		}
+		fmt.Fprintln(w)
+	}
+	if p.Budget.MaxTokens > 0 {
+		fmt.Fprintf(w, "  Budget: %d tokens per run\n", p.Budget.MaxTokens)
+	}
+	if p.Budget.MaxRequests > 0 {
+		fmt.Fprintf(w, "  Budget: %d model requests per run\n", p.Budget.MaxRequests)
+	}
+	if p.AuditLog != "" {
+		fmt.Fprintf(w, "  Audit log: %s\n", p.AuditLog)
+	}
+}
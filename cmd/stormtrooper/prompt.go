@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/gavinyap/stormtrooper/internal/agent"
+	"github.com/gavinyap/stormtrooper/internal/llm"
+)
+
+// promptResult is what a -p run prints with --output json.
+type promptResult struct {
+	Prompt     string        `json:"prompt"`
+	Model      string        `json:"model"`
+	Response   string        `json:"response"`
+	Error      string        `json:"error,omitempty"`
+	DurationMS int64         `json:"duration_ms"`
+	Usage      promptUsage   `json:"usage"`
+	Messages   []llm.Message `json:"messages"`
+}
+
+type promptUsage struct {
+	Requests         int     `json:"requests"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	Cost             float64 `json:"cost_usd"`
+}
+
+// writePromptJSON writes the outcome of a -p run as JSON: the final
+// answer, the error if the run failed, and the conversation without the
+// system prompt.
+func writePromptJSON(w io.Writer, prompt string, ag *agent.Agent, runErr error, elapsed time.Duration) error {
+	messages := ag.History()
+	if len(messages) > 0 && messages[0].Role == "system" {
+		messages = messages[1:]
+	}
+	u := ag.Usage()
+	res := promptResult{
+		Prompt:     prompt,
+		Model:      ag.Model(),
+		Response:   lastResponse(ag),
+		DurationMS: elapsed.Milliseconds(),
+		Usage:      promptUsage{Requests: u.Requests, PromptTokens: u.PromptTokens, CompletionTokens: u.CompletionTokens, Cost: u.Cost},
+		Messages:   messages,
+	}
+	if runErr != nil {
+		res.Error = runErr.Error()
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(res)
+}
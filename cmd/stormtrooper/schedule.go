@@ -0,0 +1,272 @@
+package main
+
+import (
+	gocontext "context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/gavinyap/stormtrooper/internal/schedule"
+)
+
+const scheduleUsage = `Usage:
+  stormtrooper schedule add --cron <expr> [flags] <prompt>
+  stormtrooper schedule list
+  stormtrooper schedule remove <name>
+  stormtrooper schedule run <name>
+  stormtrooper schedule history [-n N] [name]
+  stormtrooper schedule daemon
+
+Manages recurring headless tasks. "daemon" runs in the foreground and
+starts each task when its cron expression is due, running
+"stormtrooper -p <prompt>" in the task's directory. A task is skipped,
+not queued, while its previous run is still going. Runs are recorded in
+~/.stormtrooper/schedule/history.jsonl with their output in
+~/.stormtrooper/schedule/logs/. "run" starts a task immediately.
+
+Flags:
+`
+
+// runSchedule implements the "schedule" subcommand and returns the exit
+// code.
+func runSchedule(args []string, stdout, stderr io.Writer) int {
+	usage := func() int {
+		fmt.Fprint(stderr, scheduleUsage)
+		return 2
+	}
+	if len(args) == 0 {
+		return usage()
+	}
+	fs := flag.NewFlagSet("schedule "+args[0], flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, scheduleUsage)
+		fs.PrintDefaults()
+	}
+
+	path, err := schedule.DefaultPath()
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	store, err := schedule.Load(path)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	daemon := schedule.NewDaemon(path, filepath.Join(filepath.Dir(path), "schedule"), runScheduledTask, stderr)
+
+	switch args[0] {
+	case "add":
+		cron := fs.String("cron", "", `When to run, e.g. "0 3 * * 1" or @daily (required)`)
+		name := fs.String("name", "", "Task name (default: derived from the prompt)")
+		dir := fs.String("dir", "", "Repository to run in (default: the current directory)")
+		profile := fs.String("profile", "", "Tool profile: all, plan, or review")
+		yes := fs.Bool("yes", false, "Approve every tool call without asking (for disposable environments only)")
+		if err := fs.Parse(args[1:]); err != nil || fs.NArg() == 0 || *cron == "" {
+			if err == nil {
+				fs.Usage()
+			}
+			return 2
+		}
+		task := schedule.Task{
+			Name:    *name,
+			Cron:    *cron,
+			Prompt:  strings.Join(fs.Args(), " "),
+			Dir:     *dir,
+			Profile: *profile,
+			Yes:     *yes,
+			Created: time.Now().UTC(),
+		}
+		if task.Name == "" {
+			task.Name = taskName(task.Prompt)
+		}
+		if task.Dir == "" {
+			task.Dir, _ = os.Getwd()
+		}
+		if task.Dir, err = filepath.Abs(task.Dir); err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+		if err := store.Add(task); err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+		if err := store.Save(); err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+		c, _ := schedule.ParseCron(task.Cron)
+		fmt.Fprintf(stdout, "Added %s; next run %s\n", task.Name, formatNext(c.Next(time.Now())))
+		fmt.Fprintln(stdout, `Tasks run while "stormtrooper schedule daemon" is running.`)
+		return 0
+
+	case "list":
+		if len(store.Tasks) == 0 {
+			fmt.Fprintln(stdout, "No scheduled tasks.")
+			return 0
+		}
+		for _, t := range store.Tasks {
+			next := "invalid cron"
+			if c, err := schedule.ParseCron(t.Cron); err == nil {
+				next = formatNext(c.Next(time.Now()))
+			}
+			fmt.Fprintf(stdout, "%-20s  %-16s  next %-16s  %s\n", t.Name, t.Cron, next, t.Dir)
+			fmt.Fprintf(stdout, "%-20s  %s\n", "", oneLinePrompt(t.Prompt))
+		}
+		return 0
+
+	case "remove":
+		if len(args) != 2 {
+			return usage()
+		}
+		if !store.Remove(args[1]) {
+			fmt.Fprintf(stderr, "Error: no task named %q\n", args[1])
+			return 1
+		}
+		if err := store.Save(); err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(stdout, "Removed %s\n", args[1])
+		return 0
+
+	case "run":
+		if len(args) != 2 {
+			return usage()
+		}
+		task := store.Get(args[1])
+		if task == nil {
+			fmt.Fprintf(stderr, "Error: no task named %q\n", args[1])
+			return 1
+		}
+		ctx, stop := signal.NotifyContext(gocontext.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+		run, err := daemon.RunNow(ctx, *task)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(stdout, "%s: %s; output in %s\n", run.Task, run.Status, run.Log)
+		if run.Status != schedule.StatusOK {
+			return 1
+		}
+		return 0
+
+	case "history":
+		n := fs.Int("n", 20, "Show the last N runs")
+		if err := fs.Parse(args[1:]); err != nil || fs.NArg() > 1 {
+			if err == nil {
+				fs.Usage()
+			}
+			return 2
+		}
+		runs, err := daemon.History().Runs(fs.Arg(0), *n)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+		if len(runs) == 0 {
+			fmt.Fprintln(stdout, "No runs yet.")
+		}
+		for _, r := range runs {
+			status := r.Status
+			if r.ExitCode != 0 {
+				status += fmt.Sprintf(" (exit %d)", r.ExitCode)
+			}
+			if r.Error != "" {
+				status += ": " + r.Error
+			}
+			fmt.Fprintf(stdout, "%s  %-20s  %-8s  %s\n", r.Start.Local().Format("2006-01-02 15:04"), r.Task, r.End.Sub(r.Start).Round(time.Second), status)
+		}
+		return 0
+
+	case "daemon":
+		ctx, stop := signal.NotifyContext(gocontext.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+		fmt.Fprintf(stderr, "Running %d scheduled task(s) from %s. Press Ctrl+C to stop.\n", len(store.Tasks), path)
+		if err := daemon.Serve(ctx); err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+	return usage()
+}
+
+// runScheduledTask runs a task as a headless stormtrooper process in the
+// task's directory. The directory must already be trusted, since nobody
+// is there to answer the trust prompt.
+func runScheduledTask(ctx gocontext.Context, t schedule.Task, log io.Writer) (int, error) {
+	self, err := os.Executable()
+	if err != nil {
+		return -1, err
+	}
+	args := []string{"-p", t.Prompt}
+	if t.Profile != "" {
+		args = append(args, "--tool-profile", t.Profile)
+	}
+	if t.Yes {
+		args = append(args, "--yes")
+	}
+	cmd := exec.CommandContext(ctx, self, args...)
+	cmd.Dir = t.Dir
+	cmd.Stdout, cmd.Stderr = log, log
+	// Interrupt rather than kill on shutdown, so the run saves its
+	// session and reports how it ended.
+	cmd.Cancel = func() error { return cmd.Process.Signal(os.Interrupt) }
+	cmd.WaitDelay = time.Minute
+	err = cmd.Run()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return exitErr.ExitCode(), nil
+	}
+	if err != nil {
+		return -1, err
+	}
+	return 0, nil
+}
+
+// taskName derives a task name from the first words of a prompt.
+func taskName(prompt string) string {
+	var words []string
+	for _, w := range strings.Fields(strings.ToLower(prompt)) {
+		w = strings.Map(func(r rune) rune {
+			if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+				return r
+			}
+			return -1
+		}, w)
+		if w != "" {
+			words = append(words, w)
+		}
+		if len(words) == 3 {
+			break
+		}
+	}
+	if len(words) == 0 {
+		return "task"
+	}
+	return strings.Join(words, "-")
+}
+
+func formatNext(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	return t.Format("2006-01-02 15:04")
+}
+
+func oneLinePrompt(prompt string) string {
+	prompt = strings.Join(strings.Fields(prompt), " ")
+	if len(prompt) > 72 {
+		prompt = prompt[:69] + "..."
+	}
+	return prompt
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/gavinyap/stormtrooper/internal/session"
+)
+
+const sessionsUsage = `Usage:
+  stormtrooper sessions list
+  stormtrooper sessions diff <a> <b>
+
+<a> and <b> are session IDs from .stormtrooper/sessions/ or paths to session files.
+Continue a session with "stormtrooper --resume <id>" or "--resume last".
+`
+
+// runSessions implements the "sessions" subcommand and returns the exit code.
+func runSessions(args []string, stdout, stderr io.Writer) int {
+	cwd, err := os.Getwd()
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: could not determine working directory: %v\n", err)
+		return 1
+	}
+	dir := session.Dir(cwd)
+
+	if len(args) == 0 {
+		fmt.Fprint(stderr, sessionsUsage)
+		return 2
+	}
+
+	switch args[0] {
+	case "list":
+		sessions, err := session.List(dir)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+		if len(sessions) == 0 {
+			fmt.Fprintln(stdout, "No saved sessions.")
+			return 0
+		}
+		for _, s := range sessions {
+			fmt.Fprintf(stdout, "%s  %-24s  %s\n", s.ID, s.Model, s.Title())
+		}
+		return 0
+
+	case "diff":
+		if len(args) != 3 {
+			fmt.Fprint(stderr, sessionsUsage)
+			return 2
+		}
+		a, err := session.Resolve(dir, args[1])
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+		b, err := session.Resolve(dir, args[2])
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Fprint(stdout, session.Diff(a, b))
+		return 0
+	}
+
+	fmt.Fprint(stderr, sessionsUsage)
+	return 2
+}
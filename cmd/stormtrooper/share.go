@@ -0,0 +1,83 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/gavinyap/stormtrooper/internal/session"
+)
+
+const shareUsage = `Usage:
+  stormtrooper share [flags] [session]
+
+Exports a saved session (default: the most recent) as a self-contained
+markdown or HTML document for bug reports. Secrets such as API keys and
+tokens are redacted and tool calls are collapsed. [session] is a session ID
+from .stormtrooper/sessions/ or a path to a session file.
+
+Flags:
+`
+
+// runShare implements the "share" subcommand and returns the exit code.
+func runShare(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("share", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, shareUsage)
+		fs.PrintDefaults()
+	}
+	format := fs.String("format", session.FormatMarkdown, "Output format: markdown or html")
+	stripFiles := fs.Bool("strip-files", false, "Omit file contents read or written by the file tools")
+	out := fs.String("o", "", "Write to this file instead of stdout")
+	if err := fs.Parse(args); err != nil || fs.NArg() > 1 {
+		if err == nil {
+			fs.Usage()
+		}
+		return 2
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: could not determine working directory: %v\n", err)
+		return 1
+	}
+	dir := session.Dir(cwd)
+
+	var s *session.Session
+	if fs.NArg() == 1 {
+		s, err = session.Resolve(dir, fs.Arg(0))
+	} else {
+		var sessions []*session.Session
+		sessions, err = session.List(dir)
+		if err == nil && len(sessions) == 0 {
+			err = fmt.Errorf("no saved sessions in %s", dir)
+		}
+		if err == nil {
+			s = sessions[len(sessions)-1]
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	doc, redacted, err := s.Share(session.ShareOptions{Format: *format, StripFiles: *stripFiles})
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 2
+	}
+	if *out == "" {
+		fmt.Fprint(stdout, doc)
+	} else if err := os.WriteFile(*out, []byte(doc), 0644); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	} else {
+		fmt.Fprintf(stderr, "Wrote session %s to %s\n", s.ID, *out)
+	}
+	if redacted > 0 {
+		fmt.Fprintf(stderr, "Redacted %d secret(s). Review the export before sharing it.\n", redacted)
+	}
+	return 0
+}
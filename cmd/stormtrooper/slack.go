@@ -0,0 +1,35 @@
+package main
+
+import (
+	gocontext "context"
+	"fmt"
+	"io"
+
+	"github.com/gavinyap/stormtrooper/internal/agent"
+	"github.com/gavinyap/stormtrooper/internal/config"
+	"github.com/gavinyap/stormtrooper/internal/permission"
+	"github.com/gavinyap/stormtrooper/internal/slack"
+)
+
+// runSlack implements "stormtrooper slack": it answers Slack threads
+// until ctx is cancelled and returns the exit code. newAgent creates the
+// session for each thread.
+func runSlack(ctx gocontext.Context, cfg config.SlackConfig, newAgent func(permission.Handler) *agent.Agent, stderr io.Writer) int {
+	api, err := slack.NewAPI(cfg)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	bot := slack.New(slack.Options{
+		API:      api,
+		NewAgent: newAgent,
+		Channels: cfg.Channels,
+		Log:      stderr,
+	})
+	fmt.Fprintln(stderr, "Connecting to Slack; mention the bot in a channel to start a session. Press Ctrl+C to stop.")
+	if err := bot.Run(ctx); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,558 @@
+package main
+
+import (
+	gocontext "context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/gavinyap/stormtrooper/internal/trust"
+	"github.com/gavinyap/stormtrooper/internal/workflow"
+)
+
+// workflowFunc implements a workflow subcommand. args are the arguments
+// after the subcommand name; the return value is the exit code.
+type workflowFunc func(ctx gocontext.Context, args []string, env workflow.Env, stdout, stderr io.Writer) int
+
+// workflows are the subcommands that drive the agent through a scripted
+// task. Unlike "sessions" and "jobs" they need a configured agent, so
+// they are dispatched after the global flags are parsed:
+//
+//	stormtrooper [flags] <workflow> [workflow flags]
+var workflows = map[string]workflowFunc{
+	"audit-deps":        runAuditDeps,
+	"test":              runTest,
+	"gen-tests":         runGenTests,
+	"gen-docs":          runGenDocs,
+	"release-notes":     runReleaseNotes,
+	"refactor":          runRefactor,
+	"resolve-conflicts": runResolveConflicts,
+	"new":               runNew,
+	"worktree":          runWorktree,
+}
+
+const auditDepsUsage = `Usage:
+  stormtrooper [flags] audit-deps [--json] [--out <file>]
+
+Runs the project's dependency vulnerability scanners (govulncheck,
+npm audit, pip-audit), has the agent triage the findings and propose
+minimal upgrades as a patch, and prints a report. No files are modified.
+
+Exit status is 0 when nothing needs attention, 3 when findings need an
+upgrade or investigation, and 1 on error.
+
+Flags:
+`
+
+// runAuditDeps implements the "audit-deps" workflow.
+func runAuditDeps(ctx gocontext.Context, args []string, env workflow.Env, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("audit-deps", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, auditDepsUsage)
+		fs.PrintDefaults()
+	}
+	asJSON := fs.Bool("json", false, "Print the report as JSON instead of Markdown")
+	out := fs.String("out", "", "Also write the report to this file")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return 2
+	}
+
+	report, err := workflow.AuditDeps(ctx, env, workflow.DetectAudits(env.Dir))
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	text := report.Markdown()
+	if *asJSON {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+		text = string(data) + "\n"
+	}
+	fmt.Fprint(stdout, text)
+	if *out != "" {
+		if err := os.WriteFile(*out, []byte(text), 0644); err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+	}
+	if report.Actionable() {
+		return 3
+	}
+	return 0
+}
+
+const testUsage = `Usage:
+  stormtrooper [flags] test [--watch] [--cmd <command>] [--attempts <n>]
+
+Runs the project's tests and, while they fail, gives the failures and the
+source they point at to the agent to fix, until the tests pass or the
+attempts run out. Prints a summary of each fix. Go projects run
+"go test -json ./..." so failures are reported test by test.
+
+With --watch, the tests run again whenever a file changes, until
+interrupted.
+
+Flags:
+`
+
+// runTest implements the "test" workflow.
+func runTest(ctx gocontext.Context, args []string, env workflow.Env, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, testUsage)
+		fs.PrintDefaults()
+	}
+	watch := fs.Bool("watch", false, "Re-run whenever files change, until interrupted")
+	command := fs.String("cmd", "", "Test command (default: detected from the project)")
+	attempts := fs.Int("attempts", workflow.DefaultTestAttempts, "Fix attempts before giving up")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return 2
+	}
+	opts := workflow.TestOptions{Command: *command, MaxAttempts: *attempts}
+	if opts.Command == "" {
+		opts.Command = workflow.DetectTestCommand(env.Dir)
+	}
+	if opts.Command == "" {
+		fmt.Fprintln(stderr, "Error: could not detect a test command; pass --cmd")
+		return 2
+	}
+
+	for {
+		result, err := workflow.FixTests(ctx, env, opts)
+		if err != nil {
+			if *watch && ctx.Err() != nil {
+				// Interrupted: the normal way to leave watch mode.
+				return 0
+			}
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Fprint(stdout, result.Summary())
+		if !*watch {
+			if result.Passed {
+				return 0
+			}
+			return 1
+		}
+		fmt.Fprintln(stderr, "[test] watching for changes (Ctrl-C to stop)")
+		if err := workflow.WaitForChange(ctx, env.Dir, workflow.DefaultWatchInterval); err != nil {
+			return 0
+		}
+	}
+}
+
+const genTestsUsage = `Usage:
+  stormtrooper [flags] gen-tests [--max <n>] [--attempts <n>] <path>
+
+Measures test coverage of the Go package at <path>, asks the agent to
+write table-driven tests for the least covered functions, checks that
+the tests compile and pass, and prints the coverage change and the diff
+of the test files.
+
+Flags:
+`
+
+// runGenTests implements the "gen-tests" workflow.
+func runGenTests(ctx gocontext.Context, args []string, env workflow.Env, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("gen-tests", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, genTestsUsage)
+		fs.PrintDefaults()
+	}
+	maxTargets := fs.Int("max", workflow.DefaultGenTestsTargets, "Most functions to write tests for")
+	attempts := fs.Int("attempts", workflow.DefaultTestAttempts, "Attempts to fix failing generated tests")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	result, err := workflow.GenTests(ctx, env, workflow.GenTestsOptions{
+		Package:     fs.Arg(0),
+		MaxTargets:  *maxTargets,
+		MaxAttempts: *attempts,
+	})
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	fmt.Fprint(stdout, result.Summary())
+	if !result.Passed {
+		return 1
+	}
+	return 0
+}
+
+const genDocsUsage = `Usage:
+  stormtrooper [flags] gen-docs [--readme] [<path>]
+
+Finds exported symbols without doc comments in the Go package at <path>
+(default: the current directory) and asks the agent to document them,
+one file at a time. Each file's changes are shown as a diff and written
+only if you approve. Packages without a package comment get one, and
+--readme also adds an overview section to the package's README.md.
+
+Flags:
+`
+
+// runGenDocs implements the "gen-docs" workflow.
+func runGenDocs(ctx gocontext.Context, args []string, env workflow.Env, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("gen-docs", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, genDocsUsage)
+		fs.PrintDefaults()
+	}
+	readme := fs.Bool("readme", false, "Also write an overview section in the package's README.md")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return 2
+	}
+
+	result, err := workflow.GenDocs(ctx, env, workflow.GenDocsOptions{Package: fs.Arg(0), Readme: *readme})
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	fmt.Fprint(stdout, result.Summary())
+	return 0
+}
+
+const releaseNotesUsage = `Usage:
+  stormtrooper [flags] release-notes [--version <v>] [--write] <from>..<to>
+
+Reads the commits and merged pull request titles in the git range
+(<from> alone means <from>..HEAD), has the agent group them into Added,
+Changed, Fixed and the other Keep a Changelog sections, and prints the
+entry. The agent may read diffs of commits whose titles are unclear.
+With --write the entry is merged into CHANGELOG.md after you approve
+the diff.
+
+Flags:
+`
+
+// runReleaseNotes implements the "release-notes" workflow.
+func runReleaseNotes(ctx gocontext.Context, args []string, env workflow.Env, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("release-notes", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, releaseNotesUsage)
+		fs.PrintDefaults()
+	}
+	version := fs.String("version", "", "Head the entry with this version and today's date instead of Unreleased")
+	write := fs.Bool("write", false, "Merge the entry into CHANGELOG.md")
+	changelog := fs.String("changelog", "CHANGELOG.md", "Changelog file used by --write")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	opts := workflow.ReleaseOptions{
+		Range:   fs.Arg(0),
+		Version: *version,
+		Date:    time.Now().Format("2006-01-02"),
+	}
+	if *write {
+		opts.Changelog = *changelog
+	}
+	notes, err := workflow.DraftReleaseNotes(ctx, env, opts)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	fmt.Fprint(stdout, notes.Markdown())
+	if *write && !notes.Written {
+		fmt.Fprintf(stderr, "%s not updated.\n", *changelog)
+	}
+	return 0
+}
+
+const refactorUsage = `Usage:
+  stormtrooper [flags] refactor [--dry-run] --rename <name> <symbol>
+  stormtrooper [flags] refactor [--dry-run] --change <description> <symbol>
+
+Finds every reference to a Go symbol, such as internal/tool.NewRegistry
+or tool.Registry.Register, and prints the impact by file. --rename
+renames it everywhere; --change has the agent make the described change
+(a new parameter, say) at the declaration and each reference, one file
+at a time. All edits are then shown as one diff to approve, and after
+writing them go vet checks the result.
+
+Exit status is 0 on success and 1 on error or when go vet fails.
+
+Flags:
+`
+
+// runRefactor implements the "refactor" workflow.
+func runRefactor(ctx gocontext.Context, args []string, env workflow.Env, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("refactor", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, refactorUsage)
+		fs.PrintDefaults()
+	}
+	rename := fs.String("rename", "", "Rename the symbol")
+	change := fs.String("change", "", "Describe a change for the agent to make at every reference")
+	dryRun := fs.Bool("dry-run", false, "Only print the impact analysis")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 || (*rename == "") == (*change == "") {
+		fs.Usage()
+		return 2
+	}
+
+	result, err := workflow.Refactor(ctx, env, workflow.RefactorOptions{
+		Symbol: fs.Arg(0),
+		Rename: *rename,
+		Change: *change,
+		DryRun: *dryRun,
+	})
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	if *dryRun {
+		fmt.Fprint(stdout, result.Impact())
+		return 0
+	}
+	fmt.Fprint(stdout, result.Summary())
+	if result.CheckFailed() {
+		return 1
+	}
+	return 0
+}
+
+const resolveConflictsUsage = `Usage:
+  stormtrooper [flags] resolve-conflicts [<file>...]
+
+Finds the files git reports as unmerged (all of them, or just the ones
+named), gives each conflict hunk with its surroundings and the names of
+both sides to the agent, and shows the proposed resolution as a diff.
+Approved files are written and marked resolved with git add; you still
+commit or continue the merge or rebase yourself.
+
+Exit status is 0 when every conflicted file was resolved, 1 otherwise.
+
+Flags:
+`
+
+// runResolveConflicts implements the "resolve-conflicts" workflow.
+func runResolveConflicts(ctx gocontext.Context, args []string, env workflow.Env, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("resolve-conflicts", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, resolveConflictsUsage)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	result, err := workflow.ResolveConflicts(ctx, env, fs.Args())
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	fmt.Fprint(stdout, result.Summary())
+	if !result.Resolved() {
+		return 1
+	}
+	return 0
+}
+
+const newUsage = `Usage:
+  stormtrooper [flags] new <template> [--name <name>] [flags] <spec>
+
+Has the agent generate a project from <template> and a short spec in a
+new directory (default: the project name), writing each file with
+write_file. The template's build and tests are then run to verify the
+project, giving the agent a chance to fix failures, and a summary of
+the result is printed.
+
+Exit status is 0 when the project verifies, 1 otherwise.
+
+Templates:
+%s
+Flags:
+`
+
+// runNew implements the "new" workflow.
+func runNew(ctx gocontext.Context, args []string, env workflow.Env, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("new", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		var templates strings.Builder
+		for _, name := range workflow.TemplateNames() {
+			t := workflow.Templates[name]
+			fmt.Fprintf(&templates, "  %-16s %s (%s)\n", name, t.Description, t.Framework)
+		}
+		fmt.Fprintf(stderr, newUsage, templates.String())
+		fs.PrintDefaults()
+	}
+	name := fs.String("name", "", "Project name (default: the template name)")
+	dir := fs.String("dir", "", "Directory for the project, which must not exist or be empty (default: the name)")
+	framework := fs.String("framework", "", "Framework to use instead of the template's")
+	ci := fs.String("ci", "github", "CI configuration: github, gitlab, or none")
+	// The template comes first, so parse the flags after it.
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		fs.Usage()
+		return 2
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+	opts := workflow.ScaffoldOptions{
+		Template:  args[0],
+		Name:      *name,
+		Dir:       *dir,
+		Framework: *framework,
+		CI:        *ci,
+		Spec:      strings.Join(fs.Args(), " "),
+	}
+	if opts.Name == "" {
+		opts.Name = opts.Template
+	}
+
+	result, err := workflow.Scaffold(ctx, env, opts)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	fmt.Fprint(stdout, result.Report())
+	if !result.Verified {
+		return 1
+	}
+	return 0
+}
+
+const worktreeUsage = `Usage:
+  stormtrooper [flags] worktree [--branch <name>] [flags] <task>
+
+Creates a branch and a git worktree for it next to the repository, runs
+an agent session on <task> confined to the worktree, and commits what it
+changed to the branch, ready to push for a pull request. Run several at
+once to have agents work on the same repository without touching each
+other's files. Permission prompts are asked on this terminal unless
+--yes is given.
+
+Exit status is 0 when the session succeeds, 1 otherwise.
+
+Flags:
+`
+
+// runWorktree implements the "worktree" workflow.
+func runWorktree(ctx gocontext.Context, args []string, env workflow.Env, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("worktree", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, worktreeUsage)
+		fs.PrintDefaults()
+	}
+	branch := fs.String("branch", "", "Branch to create (default: stormtrooper/<task words>)")
+	base := fs.String("base", "HEAD", "Revision the branch starts from")
+	dir := fs.String("dir", "", "Where to put the worktree (default: ../<repo>-<task words>)")
+	remove := fs.Bool("remove", false, "Remove the worktree afterwards, keeping the branch")
+	model := fs.String("model", "", "LLM model for the session (overrides config)")
+	profile := fs.String("tool-profile", "", "Tool profile for the session: all, plan, or review")
+	yes := fs.Bool("yes", false, "Approve every tool call in the session without asking")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	var sessionArgs []string
+	if *model != "" {
+		sessionArgs = append(sessionArgs, "-model", *model)
+	}
+	if *profile != "" {
+		sessionArgs = append(sessionArgs, "-tool-profile", *profile)
+	}
+	if *yes {
+		sessionArgs = append(sessionArgs, "-yes")
+	}
+	opts := workflow.WorktreeOptions{
+		Task:   strings.Join(fs.Args(), " "),
+		Branch: *branch,
+		Base:   *base,
+		Dir:    *dir,
+		Remove: *remove,
+		Run: func(ctx gocontext.Context, dir, task string) error {
+			return runSessionIn(ctx, dir, task, sessionArgs, stderr)
+		},
+	}
+	result, err := workflow.Worktree(ctx, env, opts)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	fmt.Fprint(stdout, result.Report())
+	if result.RunErr != nil {
+		return 1
+	}
+	return 0
+}
+
+// runSessionIn runs "stormtrooper -p task" in dir, which it trusts first:
+// the worktree is a checkout of the repository already trusted here.
+func runSessionIn(ctx gocontext.Context, dir, task string, args []string, output io.Writer) error {
+	path, err := trust.DefaultPath()
+	if err != nil {
+		return err
+	}
+	store, err := trust.Load(path)
+	if err != nil {
+		return err
+	}
+	store.Set(dir, true)
+	if err := store.Save(); err != nil {
+		return err
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	cmd := exec.CommandContext(ctx, self, append(args, "-p", task)...)
+	cmd.Dir = dir
+	cmd.Stdin = os.Stdin
+	cmd.Stdout, cmd.Stderr = output, output
+	cmd.Cancel = func() error { return cmd.Process.Signal(os.Interrupt) }
+	cmd.WaitDelay = time.Minute
+	return cmd.Run()
+}
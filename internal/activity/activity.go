@@ -0,0 +1,206 @@
+// Package activity writes a short, human-readable account of what the
+// agent did to .stormtrooper/activity.md, one line per action, so that
+// reviewers can skim a long unattended run. It complements the saved
+// sessions, which hold everything, and the audit log of an
+// organization policy, which is for machines.
+package activity
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// File is the log, relative to the workspace.
+var File = filepath.Join(".stormtrooper", "activity.md")
+
+// quiet tools only look things up; logging them would bury the changes.
+var quiet = map[string]bool{
+	"read_file": true, "glob": true, "grep": true, "echo": true,
+	"scratchpad_read": true, "scratchpad_write": true, "capture_terminal": true,
+	"read_clipboard": true, "package_info": true, "agent_status": true,
+	"forge_issue_read": true, "issue_read": true,
+	"git_status": true, "git_diff": true,
+}
+
+// Log appends to the activity log. It writes nothing, not even the run's
+// heading, until the first action, and it is safe for concurrent use.
+type Log struct {
+	path  string
+	title string
+	now   func() time.Time
+
+	mu sync.Mutex
+	w  io.WriteCloser
+}
+
+// New returns the log of workspace dir for a run described by title,
+// such as the command that started it.
+func New(dir, title string) *Log {
+	return &Log{path: filepath.Join(dir, File), title: title, now: time.Now}
+}
+
+// ToolResult records a finished tool call. Its signature matches
+// agent.OnToolResult.
+func (l *Log) ToolResult(name string, args json.RawMessage, result string) {
+	line := describe(name, args, result)
+	if line == "" {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.w == nil {
+		if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+			return
+		}
+		f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			return
+		}
+		l.w = f
+		fmt.Fprintf(l.w, "\n## %s %s\n\n", l.now().Format("2006-01-02 15:04"), l.title)
+	}
+	fmt.Fprintf(l.w, "- %s %s\n", l.now().Format("15:04"), line)
+}
+
+// Close closes the log file, if anything was written.
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.w == nil {
+		return nil
+	}
+	err := l.w.Close()
+	l.w = nil
+	return err
+}
+
+// describe turns a tool call into a line of the log, or "" for calls
+// not worth one.
+func describe(name string, args json.RawMessage, result string) string {
+	if quiet[name] {
+		return ""
+	}
+	var p struct {
+		FilePath  string   `json:"file_path"`
+		Content   string   `json:"content"`
+		OldString string   `json:"old_string"`
+		NewString string   `json:"new_string"`
+		Command   string   `json:"command"`
+		Argv      []string `json:"argv"`
+		Method    string   `json:"method"`
+		URL       string   `json:"url"`
+		Message   string   `json:"message"`
+		Task      string   `json:"task"`
+		Tasks     []string `json:"tasks"`
+		Files     []struct {
+			FilePath string `json:"file_path"`
+		} `json:"files"`
+	}
+	json.Unmarshal(args, &p)
+
+	var action string
+	switch name {
+	case "edit_file":
+		action = "edited " + p.FilePath
+		if !failed(result) {
+			added, removed := lineChanges(p.OldString, p.NewString)
+			action += fmt.Sprintf(" (+%d/-%d)", added, removed)
+		}
+	case "write_file":
+		action = "wrote " + p.FilePath
+		if !failed(result) {
+			n := countLines(p.Content)
+			unit := "lines"
+			if n == 1 {
+				unit = "line"
+			}
+			action += fmt.Sprintf(" (%d %s)", n, unit)
+		}
+	case "write_files":
+		paths := make([]string, len(p.Files))
+		for i, f := range p.Files {
+			paths[i] = f.FilePath
+		}
+		action = fmt.Sprintf("wrote %d files: %s", len(paths), strings.Join(paths, ", "))
+	case "shell_exec":
+		command := p.Command
+		if command == "" {
+			command = strings.Join(p.Argv, " ")
+		}
+		return fmt.Sprintf("ran `%s` — %s", shorten(command), commandOutcome(result))
+	case "http_request":
+		method := p.Method
+		if method == "" {
+			method = "GET"
+		}
+		action = "sent " + strings.ToUpper(method) + " " + p.URL
+	case "git_commit":
+		action = "committed: " + shorten(p.Message)
+	case "spawn_agent":
+		action = "started a sub-agent: " + shorten(p.Task)
+	case "spawn_agents_parallel":
+		action = fmt.Sprintf("started %d sub-agents in parallel", len(p.Tasks))
+	default:
+		action = "used " + name
+	}
+	if failed(result) {
+		return action + " — failed: " + shorten(strings.TrimPrefix(result, "Error: "))
+	}
+	return action
+}
+
+func failed(result string) bool {
+	return strings.HasPrefix(result, "Error:")
+}
+
+// commandOutcome reads shell_exec's result.
+func commandOutcome(result string) string {
+	first, _, _ := strings.Cut(result, "\n")
+	switch {
+	case strings.HasPrefix(first, "Exit code: "):
+		return "exit " + strings.TrimPrefix(first, "Exit code: ")
+	case strings.HasPrefix(first, "Command timed out"):
+		return "timed out"
+	case failed(first):
+		return "failed: " + shorten(strings.TrimPrefix(first, "Error: "))
+	}
+	return "pass"
+}
+
+// lineChanges counts the lines added and removed by replacing old with
+// new, ignoring the lines they share at either end.
+func lineChanges(old, new string) (added, removed int) {
+	a, b := strings.Split(old, "\n"), strings.Split(new, "\n")
+	for len(a) > 0 && len(b) > 0 && a[0] == b[0] {
+		a, b = a[1:], b[1:]
+	}
+	for len(a) > 0 && len(b) > 0 && a[len(a)-1] == b[len(b)-1] {
+		a, b = a[:len(a)-1], b[:len(b)-1]
+	}
+	return len(b), len(a)
+}
+
+func countLines(s string) int {
+	if s == "" {
+		return 0
+	}
+	return strings.Count(strings.TrimSuffix(s, "\n"), "\n") + 1
+}
+
+// shorten keeps the first line of s, up to 80 characters.
+func shorten(s string) string {
+	s, _, cut := strings.Cut(strings.TrimSpace(s), "\n")
+	if len(s) > 80 {
+		s, cut = s[:77], true
+	}
+	if cut {
+		s += "..."
+	}
+	return s
+}
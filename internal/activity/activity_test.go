@@ -0,0 +1,82 @@
+package activity
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDescribe(t *testing.T) {
+	tests := []struct {
+		name, args, result, want string
+	}{
+		{"edit_file", `{"file_path":"internal/llm/client.go","old_string":"a\nb\nc","new_string":"a\nB\nB2\nc"}`, "File edited: internal/llm/client.go",
+			"edited internal/llm/client.go (+2/-1)"},
+		{"edit_file", `{"file_path":"x.go","old_string":"zzz","new_string":"y"}`, "Error: old_string not found in x.go",
+			"edited x.go — failed: old_string not found in x.go"},
+		{"write_file", `{"file_path":"README.md","content":"one\ntwo\n"}`, "File written: README.md",
+			"wrote README.md (2 lines)"},
+		{"write_files", `{"files":[{"file_path":"a.go","content":"x"},{"file_path":"b.go","content":"y"}]}`, "Files written: 2",
+			"wrote 2 files: a.go, b.go"},
+		{"shell_exec", `{"command":"go test ./..."}`, "ok  \tpkg\t0.1s\n",
+			"ran `go test ./...` — pass"},
+		{"shell_exec", `{"argv":["go","vet","./..."]}`, "Exit code: 1\nvet: bad\n",
+			"ran `go vet ./...` — exit 1"},
+		{"shell_exec", `{"command":"sleep 100"}`, "Command timed out after 30s\n",
+			"ran `sleep 100` — timed out"},
+		{"http_request", `{"url":"http://localhost:8080/health"}`, "Status: 200 OK",
+			"sent GET http://localhost:8080/health"},
+		{"spawn_agent", `{"task":"Write the tests\nfor the parser"}`, "done",
+			"started a sub-agent: Write the tests..."},
+		{"spawn_agents_parallel", `{"tasks":["a","b","c"]}`, "Ran 3 sub-agents, 3 at a time.",
+			"started 3 sub-agents in parallel"},
+		{"git_commit", `{"message":"Fix the parser\n\nIt dropped the last token."}`, "[main 1a2b3c4] Fix the parser",
+			"committed: Fix the parser..."},
+		{"db_query", `{}`, "rows", "used db_query"},
+		{"read_file", `{"file_path":"a.go"}`, "package a", ""},
+	}
+	for _, tt := range tests {
+		if got := describe(tt.name, json.RawMessage(tt.args), tt.result); got != tt.want {
+			t.Errorf("describe(%s, %s) = %q, want %q", tt.name, tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestLog(t *testing.T) {
+	dir := t.TempDir()
+	clock := time.Date(2026, 10, 15, 14, 2, 0, 0, time.Local)
+	l := New(dir, `-p "fix the tests"`)
+	l.now = func() time.Time { return clock }
+
+	// Lookups alone write nothing.
+	l.ToolResult("read_file", json.RawMessage(`{"file_path":"a.go"}`), "package a")
+	if _, err := os.Stat(filepath.Join(dir, File)); !os.IsNotExist(err) {
+		t.Fatal("expected no log before the first action")
+	}
+
+	l.ToolResult("edit_file", json.RawMessage(`{"file_path":"a.go","old_string":"x","new_string":"y"}`), "File edited: a.go")
+	clock = clock.Add(time.Minute)
+	l.ToolResult("shell_exec", json.RawMessage(`{"command":"go test ./..."}`), "ok\n")
+	l.Close()
+
+	// A second run appends under its own heading.
+	l = New(dir, "interactive session")
+	l.now = func() time.Time { return clock }
+	l.ToolResult("write_file", json.RawMessage(`{"file_path":"b.go","content":"package b\n"}`), "File written: b.go")
+	l.Close()
+
+	data, err := os.ReadFile(filepath.Join(dir, File))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "\n## 2026-10-15 14:02 -p \"fix the tests\"\n\n" +
+		"- 14:02 edited a.go (+1/-1)\n" +
+		"- 14:03 ran `go test ./...` — pass\n" +
+		"\n## 2026-10-15 14:03 interactive session\n\n" +
+		"- 14:03 wrote b.go (1 line)\n"
+	if string(data) != want {
+		t.Errorf("log =\n%s\nwant\n%s", data, want)
+	}
+}
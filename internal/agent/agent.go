@@ -9,8 +9,13 @@ import (
 	"io"
 	"os"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/gavinyap/stormtrooper/internal/checkpoint"
+	"github.com/gavinyap/stormtrooper/internal/hunk"
 	"github.com/gavinyap/stormtrooper/internal/llm"
+	"github.com/gavinyap/stormtrooper/internal/metrics"
 	"github.com/gavinyap/stormtrooper/internal/permission"
 	"github.com/gavinyap/stormtrooper/internal/tool"
 )
@@ -28,13 +33,40 @@ var specialTokens = []string{
 // Agent orchestrates a conversation with an LLM, dispatching tool calls
 // and maintaining history.
 type Agent struct {
-	client     *llm.Client
-	registry   *tool.Registry
-	permission permission.Handler
-	model      string
-	history    []llm.Message
-	stdout     io.Writer
-	stderr     io.Writer
+	client      *llm.Client
+	registry    *tool.Registry
+	permission  permission.Handler
+	history     []llm.Message
+	stdout      io.Writer
+	stderr      io.Writer
+	mailbox     *Mailbox
+	checkpoints *checkpoint.Tracker
+	pins        *Pins
+	prune       PruneOptions
+	summarizer  SummarizeOptions
+	pages       *Pages
+	compaction  CompactOptions
+	route       RouteOptions
+	repeat      RepeatGuardOptions
+	rules       *permission.Rules
+	stop        []string
+	routed      string // model for the first step of the current turn, if routed
+	continues   int
+	toolHooks   []func(name string, args json.RawMessage, result string)
+	turnHooks   []func()
+	eventHooks  []func(Event)
+
+	mu          sync.Mutex // guards model, sampling, profile, injected, prices, and usage, which may change between turns
+	model       string
+	maxTokens   int
+	temperature *float64
+	topP        *float64
+	models      map[string]Sampling // sampling overrides by model
+	profile     tool.Profile
+	injected    []llm.Message
+	injects     int
+	prices      map[string]Price
+	usage       Usage
 }
 
 // Options configures a new Agent.
@@ -44,18 +76,84 @@ type Options struct {
 	Permission   permission.Handler
 	Model        string
 	SystemPrompt string
+	// Mailbox, if set, delivers messages posted by a parent agent
+	// before each model request.
+	Mailbox *Mailbox
+	// Checkpoints, if set, is given a snapshot at the end of every turn
+	// so that Rewind can undo turns.
+	Checkpoints *checkpoint.Tracker
+	// Pins, if set, are files whose current contents are sent with every
+	// request.
+	Pins *Pins
+	// Prune shortens old tool results in each request.
+	Prune PruneOptions
+	// Summarize condenses long tool results before they are added to
+	// the history.
+	Summarize SummarizeOptions
+	// MaxTokens caps each response; 0 leaves it to the provider.
+	MaxTokens int
+	// ToolProfile limits the tools offered to the model; the zero value
+	// offers all of them.
+	ToolProfile tool.Profile
+	// MaxContinuations caps the follow-up requests made when a response
+	// is cut off by the length limit; 0 means DefaultMaxContinuations and
+	// negative turns continuation off.
+	MaxContinuations int
+	// Compact replaces older turns with a summary when a request grows
+	// past a threshold.
+	Compact CompactOptions
+	// Route sends simple turns to a cheaper model.
+	Route RouteOptions
+	// Stop sequences end a response where they appear.
+	Stop []string
+	// Temperature and TopP, if set, are sent with every request.
+	Temperature *float64
+	TopP        *float64
+	// ModelSampling overrides the sampling parameters above for requests
+	// to the models it names.
+	ModelSampling map[string]Sampling
+	// RepeatGuard stops responses that repeat themselves.
+	RepeatGuard RepeatGuardOptions
+	// Rules, if set, are the project's remembered approvals: calls they
+	// allow are not asked about, and a handler that implements
+	// permission.Decider may add to them.
+	Rules *permission.Rules
+	// Prices, keyed by model, estimate the cost of requests whose
+	// provider does not report it.
+	Prices map[string]Price
+	// Pages, if set, keeps tool results too long for one message, which
+	// the model then reads a page at a time with read_more.
+	Pages *Pages
 }
 
 // New creates an Agent with the given options.
 // If SystemPrompt is non-empty, it is prepended to the conversation history.
 func New(opts Options) *Agent {
 	a := &Agent{
-		client:     opts.Client,
-		registry:   opts.Registry,
-		permission: opts.Permission,
-		model:      opts.Model,
-		stdout:     os.Stdout,
-		stderr:     os.Stderr,
+		client:      opts.Client,
+		registry:    opts.Registry,
+		permission:  opts.Permission,
+		model:       opts.Model,
+		maxTokens:   opts.MaxTokens,
+		profile:     opts.ToolProfile,
+		mailbox:     opts.Mailbox,
+		checkpoints: opts.Checkpoints,
+		pins:        opts.Pins,
+		prune:       opts.Prune,
+		summarizer:  opts.Summarize,
+		compaction:  opts.Compact,
+		route:       opts.Route,
+		repeat:      opts.RepeatGuard,
+		rules:       opts.Rules,
+		stop:        opts.Stop,
+		temperature: opts.Temperature,
+		topP:        opts.TopP,
+		continues:   opts.MaxContinuations,
+		prices:      opts.Prices,
+		models:      opts.ModelSampling,
+		pages:       opts.Pages,
+		stdout:      os.Stdout,
+		stderr:      os.Stderr,
 	}
 
 	if opts.SystemPrompt != "" {
@@ -64,11 +162,15 @@ func New(opts Options) *Agent {
 			Content: opts.SystemPrompt,
 		})
 	}
+	if a.checkpoints != nil {
+		a.checkpoints.Start(a.history)
+	}
 
 	return a
 }
 
-// SetOutput overrides stdout and stderr writers (for testing or TUI mode).
+// SetOutput overrides the stdout and stderr writers events are written
+// to as text (for testing or TUI mode).
 func (a *Agent) SetOutput(stdout, stderr io.Writer) {
 	a.stdout = stdout
 	a.stderr = stderr
@@ -79,73 +181,303 @@ func (a *Agent) SetPermission(h permission.Handler) {
 	a.permission = h
 }
 
+// OnToolResult registers a function that is called with each tool's
+// arguments and result after the tool runs (for TUI mode and the
+// activity log). Functions are called in the order they were added.
+func (a *Agent) OnToolResult(fn func(name string, args json.RawMessage, result string)) {
+	a.toolHooks = append(a.toolHooks, fn)
+}
+
+// OnTurn registers a function that is called at the end of every turn,
+// once the conversation includes the model's reply (for saving the
+// session as it goes).
+func (a *Agent) OnTurn(fn func()) {
+	a.turnHooks = append(a.turnHooks, fn)
+}
+
+// SetModel changes the model used for subsequent LLM requests. It is safe
+// to call while a turn is running; the change applies to the next request.
+func (a *Agent) SetModel(model string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.model = model
+}
+
+// SetMaxTokens changes the response cap for subsequent LLM requests; 0
+// removes it.
+func (a *Agent) SetMaxTokens(n int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.maxTokens = n
+}
+
+// SetToolProfile changes the tools offered to the model from the next
+// request on.
+func (a *Agent) SetToolProfile(p tool.Profile) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.profile = p
+}
+
+// ToolProfile returns the profile that selects the tools offered to the
+// model.
+func (a *Agent) ToolProfile() tool.Profile {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.profile
+}
+
+// Model returns the model used for LLM requests.
+func (a *Agent) Model() string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.model
+}
+
+// History returns a copy of the conversation so far, including the
+// system prompt.
+func (a *Agent) History() []llm.Message {
+	return append([]llm.Message(nil), a.history...)
+}
+
+// Restore replaces the conversation with history, such as one saved by
+// an earlier run. The saved system prompt is replaced by the current
+// one, so tools and instructions added since then apply; a summary of
+// compacted turns is kept. With
+// checkpoints enabled the restore is a turn of its own, which Rewind can
+// undo. It must not be called during Send.
+func (a *Agent) Restore(history []llm.Message) {
+	var restored []llm.Message
+	if len(a.history) > 0 && a.history[0].Role == "system" {
+		restored = append(restored, a.history[0])
+	}
+	for _, m := range history {
+		if m.Role != "system" || isCompacted(m) {
+			restored = append(restored, m)
+		}
+	}
+	a.history = restored
+	if a.checkpoints != nil {
+		a.checkpoints.Commit(a.history)
+	}
+}
+
+// ToolOutputs returns the results of the tool calls in the conversation,
+// oldest first, in full even where pruning shortens them for the model.
+func (a *Agent) ToolOutputs() []tool.CallOutput {
+	calls := map[string]llm.FunctionCall{}
+	var outputs []tool.CallOutput
+	for _, m := range a.history {
+		for _, tc := range m.ToolCalls {
+			calls[tc.ID] = tc.Function
+		}
+		if m.Role == "tool" {
+			call := calls[m.ToolCallID]
+			outputs = append(outputs, tool.CallOutput{Name: call.Name, Args: call.Arguments, Result: m.Content})
+		}
+	}
+	return outputs
+}
+
+// AddToolResult records a tool run outside the model's control, such as
+// one the user started by hand, as a tool call and its result, so the
+// model sees it on the next turn. It must not be called during Send.
+func (a *Agent) AddToolResult(name string, args json.RawMessage, result string) {
+	a.history = append(a.history, toolCallMessages(fmt.Sprintf("manual_%d", len(a.history)), name, args, a.paginate(name, result))...)
+}
+
+// InjectMessage queues text sent by another program, such as an editor,
+// to be added to the conversation as a user message before the next
+// model request. Unlike AddToolResult it may be called during Send, in
+// which case the model sees the text before its next step.
+func (a *Agent) InjectMessage(source, text string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.injected = append(a.injected, llm.Message{Role: "user", Content: fmt.Sprintf("Message from %s: %s", source, text)})
+}
+
+// InjectToolResult queues a tool call and its result, made by another
+// program, to be added before the next model request, like
+// InjectMessage.
+func (a *Agent) InjectToolResult(name string, args json.RawMessage, result string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.injects++
+	a.injected = append(a.injected, toolCallMessages(fmt.Sprintf("injected_%d", a.injects), name, args, result)...)
+}
+
+// takeInjected moves queued injected messages into the history.
+func (a *Agent) takeInjected() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.history = append(a.history, a.injected...)
+	a.injected = nil
+}
+
+// toolCallMessages returns an assistant tool call and its result.
+func toolCallMessages(id, name string, args json.RawMessage, result string) []llm.Message {
+	if len(args) == 0 {
+		args = json.RawMessage("{}")
+	}
+	return []llm.Message{
+		{
+			Role: "assistant",
+			ToolCalls: []llm.ToolCall{{
+				ID:       id,
+				Type:     "function",
+				Function: llm.FunctionCall{Name: name, Arguments: string(args)},
+			}},
+		},
+		{Role: "tool", ToolCallID: id, Name: name, Content: result},
+	}
+}
+
 // Send processes a user message through the conversation loop.
 // It streams the response, handles tool calls, and loops until
 // the model produces a text-only response.
 func (a *Agent) Send(ctx context.Context, userMessage string) error {
+	// Anything injected while idle comes before the prompt it prepared.
+	a.takeInjected()
 	a.history = append(a.history, llm.Message{
 		Role:    "user",
 		Content: userMessage,
 	})
+	a.routed = a.route.routeModel(userMessage)
 
-	return a.loop(ctx)
+	err := a.loop(ctx)
+	if a.checkpoints != nil {
+		a.checkpoints.Commit(a.history)
+	}
+	for _, hook := range a.turnHooks {
+		hook()
+	}
+	a.emit(TurnDone{Err: err})
+	return err
+}
+
+// Rewind undoes the last n turns, restoring both the conversation and the
+// files changed through the checkpoint tracker. It returns the restored
+// files.
+func (a *Agent) Rewind(n int) ([]string, error) {
+	if a.checkpoints == nil {
+		return nil, fmt.Errorf("checkpoints are not enabled")
+	}
+	history, files, err := a.checkpoints.Rewind(n)
+	if err != nil {
+		return nil, err
+	}
+	a.history = history
+	return files, nil
 }
 
 // loop runs the core agent loop: send to LLM, handle tool calls, repeat.
 func (a *Agent) loop(ctx context.Context) error {
+	retries := 0
+	compacted := false
 	for {
 		// Check for context cancellation before each iteration.
 		if err := ctx.Err(); err != nil {
 			return fmt.Errorf("agent cancelled: %w", err)
 		}
 
+		// Pick up instructions the parent sent while we were working.
+		if a.mailbox != nil {
+			for _, msg := range a.mailbox.take() {
+				a.history = append(a.history, llm.Message{
+					Role:    "user",
+					Content: "Message from the agent that started you: " + msg,
+				})
+			}
+		}
+		a.takeInjected()
+
+		// Compact at most once a turn; a turn that is itself too big
+		// would otherwise summarize on every step.
+		if !compacted {
+			compacted = a.autoCompact(ctx)
+		}
+
+		a.mu.Lock()
+		model, profile := a.model, a.profile
+		a.mu.Unlock()
+
+		// A routed turn starts on the route model; if it reaches for
+		// tools, the agent's own model takes over from the next step.
+		if a.routed != "" {
+			model, a.routed = a.routed, ""
+		}
+		if a.route.Model != "" {
+			a.emit(ModelUsed{Model: model})
+		}
+
 		// Build tool definitions from registry.
-		toolDefs := a.convertToolDefs()
+		toolDefs := a.convertToolDefs(profile)
 
+		sampling := a.sampling(model)
 		req := llm.ChatCompletionRequest{
-			Model:    a.model,
-			Messages: a.history,
-			Tools:    toolDefs,
-		}
-
-		// Stream the response, filtering out tool-call content and special tokens.
-		msg, err := a.client.ChatCompletionStream(ctx, req, func(chunk llm.ChatCompletionChunk) {
-			for _, choice := range chunk.Choices {
-				// Skip content when the chunk also carries tool call deltas —
-				// some open-source models send tool call arguments as content.
-				if len(choice.Delta.ToolCalls) > 0 {
-					continue
-				}
-
-				content := choice.Delta.Content
-				if content == "" {
-					continue
-				}
-
-				// Strip special tokens that open-source models emit.
-				content = stripSpecialTokens(content)
-
-				if content != "" {
-					fmt.Fprint(a.stdout, content)
-				}
-			}
-		})
+			Model:       model,
+			Messages:    a.pins.withPins(prune(a.history, a.prune)),
+			Tools:       toolDefs,
+			MaxTokens:   sampling.MaxTokens,
+			Stop:        sampling.Stop,
+			Temperature: sampling.Temperature,
+			TopP:        sampling.TopP,
+		}
+
+		msg, finish, err := a.stream(ctx, req)
 		if err != nil {
-			return fmt.Errorf("LLM request failed: %w", err)
+			return err
 		}
+		msg, finish, err = a.stitch(ctx, req, msg, finish)
+		if err != nil {
+			return err
+		}
+		if a.route.Model != "" {
+			msg.Model = model
+		}
+
+		switch finish {
+		case "length":
+			// Text was stitched above, so only tool calls whose arguments
+			// were cut off remain. Ask for those again rather than run them.
+			if len(msg.ToolCalls) > 0 && retries < a.continueLimit() {
+				retries++
+				msg.ToolCalls = nil
+				a.warn("Tool calls cut off by the length limit; asking again (%d/%d)", retries, a.continueLimit())
+				a.history = append(a.history, *msg, llm.Message{Role: "user", Content: retryPrompt})
+				continue
+			}
+			msg.ToolCalls = nil
+			a.history = append(a.history, *msg)
+			a.emit(TokenDelta{Content: "\n"})
+			a.warnTruncated()
+			return nil
+		case "content_filter":
+			msg.ToolCalls = nil
+			a.history = append(a.history, *msg)
+			a.emit(TokenDelta{Content: "\n"})
+			a.warn("Response stopped by the provider's content filter")
+			return nil
+		}
+		retries = 0
 
 		// Append assistant message to history.
 		a.history = append(a.history, *msg)
 
 		// If no tool calls, we're done.
 		if len(msg.ToolCalls) == 0 {
-			fmt.Fprintln(a.stdout)
+			a.emit(TokenDelta{Content: "\n"})
 			return nil
 		}
 
-		// Process each tool call.
+		// Process each tool call. Once the turn is cancelled, the rest are
+		// answered without running, so no more prompts appear and every
+		// call still has a result in the history.
 		for _, tc := range msg.ToolCalls {
-			result := a.executeTool(ctx, tc)
+			result := "Error: cancelled before running"
+			if ctx.Err() == nil {
+				result = a.paginate(tc.Function.Name, a.summarize(ctx, tc.Function.Name, a.executeTool(ctx, tc, profile)))
+			}
 			a.history = append(a.history, llm.Message{
 				Role:       "tool",
 				ToolCallID: tc.ID,
@@ -158,43 +490,182 @@ func (a *Agent) loop(ctx context.Context) error {
 	}
 }
 
+// streamOnce sends req and streams the response to stdout, filtering out
+// tool-call content and special tokens. It returns the assembled message
+// and the finish reason of its last choice, or errRepeating if it stopped
+// a response that was repeating itself.
+func (a *Agent) streamOnce(ctx context.Context, req llm.ChatCompletionRequest) (*llm.Message, string, error) {
+	model := req.Model
+	metrics.LLMRequests.Inc(model)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var guard *repeatDetector
+	if n := a.repeat.minRepeats(); n > 0 {
+		guard = &repeatDetector{minRepeats: n}
+	}
+	repeated := false
+
+	var finish string
+	var usage *llm.Usage
+	promptTools := a.client.PromptTools()
+	msg, err := a.client.ChatCompletionStream(ctx, req, func(chunk llm.ChatCompletionChunk) {
+		if repeated {
+			return
+		}
+		if chunk.Usage != nil {
+			metrics.LLMTokens.Add(float64(chunk.Usage.PromptTokens), model, "prompt")
+			metrics.LLMTokens.Add(float64(chunk.Usage.CompletionTokens), model, "completion")
+			usage = chunk.Usage
+		}
+		for _, choice := range chunk.Choices {
+			if choice.FinishReason != nil && *choice.FinishReason != "" {
+				finish = *choice.FinishReason
+			}
+			// Skip content when the chunk also carries tool call deltas —
+			// some open-source models send tool call arguments as content.
+			if len(choice.Delta.ToolCalls) > 0 {
+				continue
+			}
+
+			content := choice.Delta.Content
+			if content == "" {
+				continue
+			}
+
+			// Strip special tokens that open-source models emit.
+			content = stripSpecialTokens(content)
+
+			if content != "" {
+				a.emit(TokenDelta{Content: content})
+			}
+			if guard != nil && guard.add(content) {
+				repeated = true
+				cancel()
+				return
+			}
+		}
+	})
+	a.recordUsage(model, usage)
+	if !promptTools && a.client.PromptTools() {
+		a.warn("The server rejected native tool calls; describing the tools in the prompt for the rest of the session (tool_calling: native turns this off)")
+	}
+	if repeated {
+		return nil, "", errRepeating
+	}
+	if err != nil {
+		metrics.LLMErrors.Inc(model)
+		return nil, "", fmt.Errorf("LLM request failed: %w", err)
+	}
+	return msg, finish, nil
+}
+
 // executeTool handles a single tool call: lookup, permission check, execution.
-func (a *Agent) executeTool(ctx context.Context, tc llm.ToolCall) string {
+func (a *Agent) executeTool(ctx context.Context, tc llm.ToolCall, profile tool.Profile) string {
 	t := a.registry.Get(tc.Function.Name)
 	if t == nil {
 		fmt.Fprintf(a.stderr, "[tool] Unknown tool: %s\n", tc.Function.Name)
-		return fmt.Sprintf("Unknown tool: %s", tc.Function.Name)
+		// Model-invented names would explode label cardinality.
+		metrics.ToolCalls.Inc("(unknown)", "unknown")
+		// Tools can be unregistered between turns, so the model may ask
+		// for one it saw earlier.
+		return fmt.Sprintf("Unknown tool: %s. It may have been removed; use only the tools in the current request.", tc.Function.Name)
 	}
 
-	// Permission check.
-	if t.Permission() == tool.PermissionPrompt {
-		var preview string
-		if p, ok := t.(tool.Previewer); ok {
-			preview = p.Preview(json.RawMessage(tc.Function.Arguments))
-		} else {
-			preview = fmt.Sprintf("%s(%s)", tc.Function.Name, truncateArgs(tc.Function.Arguments, 200))
+	// Tools that start sub-agents pass the sub-agents' events up.
+	ctx = withEmitter(ctx, a.emit)
+
+	// The model may still ask for a tool it was not offered.
+	if !profile.Exposes(t) {
+		metrics.ToolCalls.Inc(tc.Function.Name, "denied")
+		return fmt.Sprintf("Error: %s is not available with the %s tool profile", tc.Function.Name, profile.Name)
+	}
+
+	// An organization policy overrides the profile and the user alike.
+	args := json.RawMessage(tc.Function.Arguments)
+	guard := a.registry.Guard()
+	record := func(outcome string) {
+		if guard != nil {
+			guard.Record(tc.Function.Name, args, outcome)
+		}
+	}
+	if guard != nil {
+		if reason := guard.Check(tc.Function.Name, args); reason != "" {
+			a.emit(AutoDecision{Tool: tc.Function.Name, Reason: reason})
+			metrics.ToolCalls.Inc(tc.Function.Name, "denied")
+			record("blocked")
+			return "Error: blocked by the organization policy: " + reason
 		}
-		if !a.permission.Check(tc.Function.Name, preview) {
+	}
+
+	// Permission check. A change to one file with several hunks may be
+	// accepted in part, in which case run writes just those hunks.
+	// Calls the project allowed for good are not asked about; the rule
+	// that allowed them is reported instead.
+	var run func() (string, error)
+	command := tool.CommandFor(t, args)
+	ask := tool.PermissionFor(t, args) == tool.PermissionPrompt
+	if rule := a.rules.Match(tc.Function.Name, command); ask && rule != "" {
+		a.emit(AutoDecision{Tool: tc.Function.Name, Approved: true, Rule: rule})
+		ask = false
+	}
+	if ask {
+		var approved, reviewed bool
+		run, approved, reviewed = a.reviewHunks(ctx, t, tc.Function.Name, args)
+		if !reviewed {
+			var preview string
+			if p, ok := t.(tool.Previewer); ok {
+				preview = p.Preview(json.RawMessage(tc.Function.Arguments))
+			} else {
+				preview = fmt.Sprintf("%s(%s)", tc.Function.Name, truncateArgs(tc.Function.Arguments, 200))
+			}
+			approved = a.ask(ctx, tc.Function.Name, preview, command)
+		}
+		if !approved {
 			fmt.Fprintf(a.stderr, "[tool] %s: permission denied\n", tc.Function.Name)
+			metrics.PermissionDenials.Inc(tc.Function.Name)
+			metrics.ToolCalls.Inc(tc.Function.Name, "denied")
+			record("denied")
 			return "Permission denied by user"
 		}
 	}
 
-	fmt.Fprintf(a.stderr, "[tool] %s\n", tc.Function.Name)
+	a.emit(ToolStart{Name: tc.Function.Name, Args: args})
 
-	result, err := t.Execute(ctx, json.RawMessage(tc.Function.Arguments))
+	start := time.Now()
+	if run == nil {
+		run = func() (string, error) { return t.Execute(ctx, args) }
+	}
+	result, err := recoverRun(run)
+	metrics.ToolDuration.ObserveSince(start, tc.Function.Name)
 	if err != nil {
-		fmt.Fprintf(a.stderr, "[tool:error] %s\n", tc.Function.Name)
-		return fmt.Sprintf("Tool error: %v", err)
+		te := NewToolError(tc.Function.Name, args, err)
+		summary, _, _ := strings.Cut(te.Error(), "\n")
+		a.emit(ToolResult{Name: tc.Function.Name, Args: args, Result: te.String(), Error: summary})
+		metrics.ToolCalls.Inc(tc.Function.Name, "error")
+		record("error")
+		return te.String()
+	}
+
+	// Tools report user-facing failures as "Error: ..." results.
+	outcome := "ok"
+	if strings.HasPrefix(result, "Error:") {
+		outcome = "error"
 	}
+	metrics.ToolCalls.Inc(tc.Function.Name, outcome)
+	record(outcome)
 
-	fmt.Fprintf(a.stderr, "[tool:done] %s\n", tc.Function.Name)
+	a.emit(ToolResult{Name: tc.Function.Name, Args: args, Result: result})
+	for _, hook := range a.toolHooks {
+		hook(tc.Function.Name, json.RawMessage(tc.Function.Arguments), result)
+	}
 	return result
 }
 
-// convertToolDefs converts tool.ToolDef to llm.ToolDef.
-func (a *Agent) convertToolDefs() []llm.ToolDef {
-	defs := a.registry.Definitions()
+// convertToolDefs converts the tool.ToolDef of each tool profile exposes to
+// llm.ToolDef.
+func (a *Agent) convertToolDefs(profile tool.Profile) []llm.ToolDef {
+	defs := a.registry.DefinitionsFor(profile)
 	llmDefs := make([]llm.ToolDef, len(defs))
 	for i, d := range defs {
 		llmDefs[i] = llm.ToolDef{
@@ -209,6 +680,69 @@ func (a *Agent) convertToolDefs() []llm.ToolDef {
 	return llmDefs
 }
 
+// reviewHunks asks the user about each hunk of a call that changes one
+// file in several places, if the permission handler can. reviewed is
+// false when it did not ask. When only some hunks were accepted, run
+// writes those and tells the model which were rejected.
+func (a *Agent) reviewHunks(ctx context.Context, t tool.Tool, name string, args json.RawMessage) (run func() (string, error), approved, reviewed bool) {
+	reviewer, ok := a.permission.(permission.HunkReviewer)
+	if !ok {
+		return nil, false, false
+	}
+	proposer, ok := t.(tool.Proposer)
+	if !ok {
+		return nil, false, false
+	}
+	path, before, after, err := proposer.Propose(args)
+	if err != nil {
+		return nil, false, false
+	}
+	hunks := hunk.Split(before, after)
+	if len(hunks) < 2 {
+		return nil, false, false
+	}
+
+	diffs := make([]string, len(hunks))
+	for i, h := range hunks {
+		diffs[i] = h.Diff
+	}
+	accepted := reviewer.ReviewHunks(ctx, name, path, diffs)
+	var rejected []string
+	for i, ok := range accepted {
+		if !ok {
+			rejected = append(rejected, fmt.Sprintf("Hunk %d:\n%s", i+1, diffs[i]))
+		}
+	}
+	switch len(rejected) {
+	case 0:
+		return nil, true, true
+	case len(hunks):
+		return nil, false, true
+	}
+	return func() (string, error) {
+		if err := proposer.WriteProposed(path, hunk.Apply(before, hunks, accepted)); err != nil {
+			return fmt.Sprintf("Error: %v", err), nil
+		}
+		return fmt.Sprintf("Applied %d of %d hunks to %s. The user rejected these hunks, which were not applied:\n%s",
+			len(hunks)-len(rejected), len(hunks), path, strings.Join(rejected, "")), nil
+	}, true, true
+}
+
+// ask asks the permission handler about a call. If the project keeps
+// rules and the handler can offer it, the user may allow the tool, or
+// the exact command, for good.
+func (a *Agent) ask(ctx context.Context, name, preview, command string) bool {
+	d, ok := a.permission.(permission.Decider)
+	if !ok || a.rules == nil {
+		return a.permission.Check(ctx, name, preview)
+	}
+	decision := d.Decide(ctx, name, preview, command)
+	if err := a.rules.Remember(decision, name, command); err != nil {
+		a.warn("Could not save the approval: %v", err)
+	}
+	return decision != permission.Deny
+}
+
 // truncateArgs shortens a JSON arguments string for display.
 func truncateArgs(s string, maxLen int) string {
 	if len(s) <= maxLen {
@@ -4,13 +4,18 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/gavinyap/stormtrooper/internal/llm"
+	"github.com/gavinyap/stormtrooper/internal/metrics"
 	"github.com/gavinyap/stormtrooper/internal/permission"
 	"github.com/gavinyap/stormtrooper/internal/tool"
 )
@@ -128,6 +133,10 @@ func TestAgent_ToolCallAndResponse(t *testing.T) {
 
 	var stdout, stderr bytes.Buffer
 	ag.SetOutput(&stdout, &stderr)
+	var hooked []string
+	ag.OnToolResult(func(name string, args json.RawMessage, result string) {
+		hooked = append(hooked, name+" "+string(args)+" "+result)
+	})
 
 	err := ag.Send(context.Background(), "Use the tool")
 	if err != nil {
@@ -138,6 +147,9 @@ func TestAgent_ToolCallAndResponse(t *testing.T) {
 	if mt.lastParams != `{"input":"hello"}` {
 		t.Errorf("expected tool params, got %q", mt.lastParams)
 	}
+	if len(hooked) != 1 || hooked[0] != `test_tool {"input":"hello"} mock-result` {
+		t.Errorf("tool result hook calls = %q", hooked)
+	}
 
 	// Verify tool activity logged to stderr
 	if !strings.Contains(stderr.String(), "[tool] test_tool") {
@@ -196,6 +208,164 @@ func TestAgent_UnknownTool(t *testing.T) {
 	}
 }
 
+func TestAgent_SeesRegistryChangesBetweenTurns(t *testing.T) {
+	var tools [][]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req llm.ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		var names []string
+		for _, d := range req.Tools {
+			names = append(names, d.Function.Name)
+		}
+		tools = append(tools, names)
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(sseTextResponse("ok")))
+	}))
+	defer server.Close()
+
+	client := llm.NewClient("test-key")
+	client.SetBaseURL(server.URL)
+
+	reg := tool.NewRegistry()
+	reg.Register(&mockTool{name: "builtin", perm: tool.PermissionAuto})
+	ag := New(Options{
+		Client:     client,
+		Registry:   reg,
+		Permission: permission.NewCheckerWithIO(strings.NewReader(""), &bytes.Buffer{}),
+		Model:      "test-model",
+	})
+	ag.SetOutput(&bytes.Buffer{}, &bytes.Buffer{})
+
+	ag.Send(context.Background(), "first")
+	reg.RegisterNamespace("mcp_github", &mockTool{name: "create_issue", perm: tool.PermissionAuto})
+	ag.Send(context.Background(), "second")
+	reg.UnregisterNamespace("mcp_github")
+	ag.Send(context.Background(), "third")
+
+	want := []string{"builtin", "builtin,mcp_github.create_issue", "builtin"}
+	for i, names := range tools {
+		if got := strings.Join(names, ","); got != want[i] {
+			t.Errorf("request %d tools = %s, want %s", i+1, got, want[i])
+		}
+	}
+}
+
+func TestAgent_ToolProfile(t *testing.T) {
+	shell := &mockTool{name: "shell", result: "ran", perm: tool.PermissionAuto}
+	var offered [][]llm.ToolDef
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req llm.ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		offered = append(offered, req.Tools)
+		w.Header().Set("Content-Type", "text/event-stream")
+		if len(offered) == 1 {
+			// Ask for a tool the profile hides.
+			w.Write([]byte(sseToolCallResponse("call_1", "shell", `{}`)))
+			return
+		}
+		w.Write([]byte(sseTextResponse("ok")))
+	}))
+	defer server.Close()
+
+	client := llm.NewClient("test-key")
+	client.SetBaseURL(server.URL)
+
+	reg := tool.NewRegistry()
+	reg.Register(shell)
+	reg.Register(&tool.GrepTool{})
+	ag := New(Options{
+		Client:      client,
+		Registry:    reg,
+		Permission:  permission.NewCheckerWithIO(strings.NewReader(""), &bytes.Buffer{}),
+		Model:       "test-model",
+		ToolProfile: tool.Profiles["plan"],
+	})
+	ag.SetOutput(&bytes.Buffer{}, &bytes.Buffer{})
+
+	if err := ag.Send(context.Background(), "Hi"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(offered[0]) != 1 || offered[0][0].Function.Name != "grep" {
+		t.Errorf("plan profile offered %+v, want only grep", offered[0])
+	}
+	if shell.lastParams != "" {
+		t.Error("hidden tool should not run")
+	}
+	if result := ag.History()[2].Content; !strings.Contains(result, "not available with the plan tool profile") {
+		t.Errorf("tool result = %q", result)
+	}
+
+	ag.SetToolProfile(tool.Profile{})
+	ag.Send(context.Background(), "again")
+	if len(offered[2]) != 2 {
+		t.Errorf("expected every tool after clearing the profile, got %+v", offered[2])
+	}
+}
+
+// blockGuard forbids calls whose arguments mention a word and records
+// every outcome.
+type blockGuard struct {
+	word     string
+	outcomes []string
+}
+
+func (g *blockGuard) Check(name string, args json.RawMessage) string {
+	if strings.Contains(string(args), g.word) {
+		return "no " + g.word
+	}
+	return ""
+}
+
+func (g *blockGuard) Record(name string, args json.RawMessage, outcome string) {
+	g.outcomes = append(g.outcomes, name+":"+outcome)
+}
+
+func TestAgent_RegistryGuard(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "text/event-stream")
+		switch calls {
+		case 1:
+			w.Write([]byte(sseToolCallResponse("call_1", "shell", `{"cmd":"curl evil.example"}`)))
+		case 2:
+			w.Write([]byte(sseToolCallResponse("call_2", "shell", `{"cmd":"go test"}`)))
+		default:
+			w.Write([]byte(sseTextResponse("done")))
+		}
+	}))
+	defer server.Close()
+
+	client := llm.NewClient("test-key")
+	client.SetBaseURL(server.URL)
+
+	shell := &mockTool{name: "shell", result: "ran", perm: tool.PermissionPrompt}
+	reg := tool.NewRegistry()
+	reg.Register(shell)
+	guard := &blockGuard{word: "curl"}
+	reg.SetGuard(guard)
+	// Everything is approved, yet the policy still applies.
+	ag := New(Options{Client: client, Registry: reg, Permission: permission.AllowAll{}, Model: "test-model"})
+	var stderr bytes.Buffer
+	ag.SetOutput(&bytes.Buffer{}, &stderr)
+
+	if err := ag.Send(context.Background(), "Hi"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result := ag.History()[2].Content; result != "Error: blocked by the organization policy: no curl" {
+		t.Errorf("tool result = %q", result)
+	}
+	if shell.lastParams != `{"cmd":"go test"}` {
+		t.Errorf("expected only the allowed call to run, got %q", shell.lastParams)
+	}
+	if got := strings.Join(guard.outcomes, ","); got != "shell:blocked,shell:ok" {
+		t.Errorf("recorded outcomes = %s", got)
+	}
+	if !strings.Contains(stderr.String(), "[auto] shell: denied by policy: no curl\n") {
+		t.Errorf("expected the policy decision on stderr, got:\n%s", stderr.String())
+	}
+}
+
 func TestAgent_PermissionDenied(t *testing.T) {
 	callCount := 0
 
@@ -246,6 +416,58 @@ func TestAgent_PermissionDenied(t *testing.T) {
 	}
 }
 
+// paramPermTool asks for permission only when its params say so.
+type paramPermTool struct {
+	mockTool
+}
+
+func (p *paramPermTool) PermissionFor(params json.RawMessage) tool.PermissionLevel {
+	if strings.Contains(string(params), "write") {
+		return tool.PermissionPrompt
+	}
+	return tool.PermissionAuto
+}
+
+func TestAgent_PermissionForParams(t *testing.T) {
+	callCount := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		callCount++
+		w.Header().Set("Content-Type", "text/event-stream")
+		switch callCount {
+		case 1:
+			w.Write([]byte(sseToolCallResponse("call_1", "query", `{"mode":"read"}`)))
+		case 3:
+			w.Write([]byte(sseToolCallResponse("call_2", "query", `{"mode":"write"}`)))
+		default:
+			w.Write([]byte(sseTextResponse("ok")))
+		}
+	}))
+	defer server.Close()
+
+	client := llm.NewClient("test-key")
+	client.SetBaseURL(server.URL)
+
+	reg := tool.NewRegistry()
+	pt := &paramPermTool{mockTool{name: "query", perm: tool.PermissionPrompt, result: "rows"}}
+	reg.Register(pt)
+
+	// Only one answer is available: the read must not consume it.
+	var prompts bytes.Buffer
+	perm := permission.NewCheckerWithIO(strings.NewReader("n\n"), &prompts)
+	ag := New(Options{Client: client, Registry: reg, Permission: perm, Model: "test-model"})
+	ag.SetOutput(&bytes.Buffer{}, &bytes.Buffer{})
+
+	ag.Send(context.Background(), "read")
+	if pt.lastParams != `{"mode":"read"}` || prompts.Len() != 0 {
+		t.Fatalf("read should run without a prompt (params %q, prompt %q)", pt.lastParams, prompts.String())
+	}
+
+	ag.Send(context.Background(), "write")
+	if pt.lastParams != `{"mode":"read"}` || prompts.Len() == 0 {
+		t.Errorf("write should prompt and be denied (params %q)", pt.lastParams)
+	}
+}
+
 func TestAgent_PermissionApproved(t *testing.T) {
 	callCount := 0
 
@@ -291,6 +513,110 @@ func TestAgent_PermissionApproved(t *testing.T) {
 	}
 }
 
+// decider answers permission prompts with its decisions in turn,
+// recording what it was asked.
+type decider struct {
+	decisions []permission.Decision
+	asked     []string
+}
+
+func (d *decider) Check(ctx context.Context, toolName, preview string) bool {
+	return d.Decide(ctx, toolName, preview, "") != permission.Deny
+}
+
+func (d *decider) Decide(_ context.Context, toolName, preview, command string) permission.Decision {
+	d.asked = append(d.asked, command)
+	if len(d.asked) > len(d.decisions) {
+		return permission.Deny
+	}
+	return d.decisions[len(d.asked)-1]
+}
+
+// commandTool is a mockTool that runs its "command" argument.
+type commandTool struct{ mockTool }
+
+func (c *commandTool) Command(params json.RawMessage) string {
+	var p struct {
+		Command string `json:"command"`
+	}
+	json.Unmarshal(params, &p)
+	return p.Command
+}
+
+func TestAgent_RememberedApprovals(t *testing.T) {
+	// Each turn runs one command: the prompt names it.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req llm.ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		w.Header().Set("Content-Type", "text/event-stream")
+		last := req.Messages[len(req.Messages)-1]
+		if last.Role == "user" {
+			args, _ := json.Marshal(map[string]string{"command": last.Content})
+			w.Write([]byte(sseToolCallResponse("call_1", "shell_tool", string(args))))
+			return
+		}
+		w.Write([]byte(sseTextResponse("done")))
+	}))
+	defer server.Close()
+	client := llm.NewClient("test-key")
+	client.SetBaseURL(server.URL)
+
+	reg := tool.NewRegistry()
+	reg.Register(&commandTool{mockTool{name: "shell_tool", perm: tool.PermissionPrompt, result: "ok"}})
+	rules, err := permission.LoadRules(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	perm := &decider{decisions: []permission.Decision{permission.AllowCommand, permission.AllowOnce}}
+	ag := New(Options{Client: client, Registry: reg, Permission: perm, Model: "test-model", Rules: rules})
+	var stderr bytes.Buffer
+	ag.SetOutput(&bytes.Buffer{}, &stderr)
+
+	for _, prompt := range []string{"go test ./...", "go test ./...", "go vet ./..."} {
+		if err := ag.Send(context.Background(), prompt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The repeated command was not asked about again; the new one was.
+	if strings.Join(perm.asked, "|") != "go test ./...|go vet ./..." {
+		t.Errorf("asked about %q", perm.asked)
+	}
+	if !rules.Allows("shell_tool", "go test ./...") || rules.Allows("shell_tool", "go vet ./...") {
+		t.Errorf("rules = %+v", rules.Commands)
+	}
+	// The call the rule allowed is reported, with the rule.
+	if n := strings.Count(stderr.String(), `[auto] shell_tool: approved by rule "go test ./..."`); n != 1 {
+		t.Errorf("expected one automatic approval, got %d in:\n%s", n, stderr.String())
+	}
+}
+
+func TestAgent_DeciderWithoutRules(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req llm.ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		w.Header().Set("Content-Type", "text/event-stream")
+		if req.Messages[len(req.Messages)-1].Role == "tool" {
+			w.Write([]byte(sseTextResponse("done")))
+			return
+		}
+		w.Write([]byte(sseToolCallResponse("call_1", "shell_tool", `{"command":"ls"}`)))
+	}))
+	defer server.Close()
+	client := llm.NewClient("test-key")
+	client.SetBaseURL(server.URL)
+	reg := tool.NewRegistry()
+	reg.Register(&mockTool{name: "shell_tool", perm: tool.PermissionPrompt, result: "ok"})
+
+	// Without rules to keep the answer, the handler is only asked yes or no.
+	perm := &decider{}
+	ag := New(Options{Client: client, Registry: reg, Permission: perm, Model: "test-model"})
+	ag.SetOutput(&bytes.Buffer{}, &bytes.Buffer{})
+	ag.Send(context.Background(), "list")
+	if len(perm.asked) == 0 || perm.asked[0] != "" {
+		t.Errorf("asked = %q, want a plain Check", perm.asked)
+	}
+}
+
 func TestAgent_SystemPromptInHistory(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Verify the request includes system prompt.
@@ -367,6 +693,54 @@ func TestAgent_CancelledContextReturnsError(t *testing.T) {
 	}
 }
 
+// cancelTool cancels the turn that runs it.
+type cancelTool struct {
+	mockTool
+	cancel context.CancelFunc
+}
+
+func (m *cancelTool) Execute(ctx context.Context, params json.RawMessage) (string, error) {
+	m.cancel()
+	return m.mockTool.Execute(ctx, params)
+}
+
+func TestAgent_CancelSkipsRemainingToolCalls(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: {\"id\":\"1\",\"choices\":[{\"index\":0,\"delta\":{\"role\":\"assistant\",\"tool_calls\":[" +
+			"{\"index\":0,\"id\":\"call_1\",\"type\":\"function\",\"function\":{\"name\":\"stop\",\"arguments\":\"{}\"}}," +
+			"{\"index\":1,\"id\":\"call_2\",\"type\":\"function\",\"function\":{\"name\":\"other\",\"arguments\":\"{}\"}}" +
+			"]},\"finish_reason\":null}]}\n\n"))
+		w.Write([]byte("data: {\"id\":\"1\",\"choices\":[{\"index\":0,\"delta\":{},\"finish_reason\":\"tool_calls\"}]}\n\ndata: [DONE]\n"))
+	}))
+	defer server.Close()
+
+	client := llm.NewClient("test-key")
+	client.SetBaseURL(server.URL)
+	reg := tool.NewRegistry()
+	reg.Register(&cancelTool{mockTool: mockTool{name: "stop", perm: tool.PermissionAuto, result: "stopped"}, cancel: cancel})
+	other := &mockTool{name: "other", perm: tool.PermissionAuto, result: "ran"}
+	reg.Register(other)
+
+	ag := New(Options{Client: client, Registry: reg, Model: "test-model"})
+	ag.SetOutput(&bytes.Buffer{}, &bytes.Buffer{})
+
+	err := ag.Send(ctx, "Go")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected a cancellation error, got %v", err)
+	}
+	if other.lastParams != "" {
+		t.Error("a tool call after the cancellation should not run")
+	}
+	h := ag.History()
+	if len(h) < 2 || h[len(h)-1].Content != "Error: cancelled before running" || h[len(h)-2].Content != "stopped" {
+		t.Errorf("every tool call should have a result, history ends %+v", h[max(0, len(h)-2):])
+	}
+}
+
 // sseToolCallWithContentResponse simulates an open-source model that sends
 // tool call arguments as both Delta.Content and Delta.ToolCalls simultaneously.
 func sseToolCallWithContentResponse(callID, toolName, args, leakedContent string) string {
@@ -516,3 +890,403 @@ func TestTruncateArgs(t *testing.T) {
 		t.Error("expected ... suffix")
 	}
 }
+
+func TestAgent_SetModelAppliesToNextRequest(t *testing.T) {
+	var gotModel string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req llm.ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		gotModel = req.Model
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(sseTextResponse("ok")))
+	}))
+	defer server.Close()
+
+	client := llm.NewClient("test-key")
+	client.SetBaseURL(server.URL)
+
+	ag := New(Options{
+		Client:     client,
+		Registry:   tool.NewRegistry(),
+		Permission: permission.NewCheckerWithIO(strings.NewReader(""), &bytes.Buffer{}),
+		Model:      "first-model",
+	})
+	ag.SetOutput(&bytes.Buffer{}, &bytes.Buffer{})
+
+	ag.SetModel("second-model")
+	if ag.Model() != "second-model" {
+		t.Fatalf("expected Model() to return 'second-model', got %q", ag.Model())
+	}
+
+	if err := ag.Send(context.Background(), "Hi"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gotModel != "second-model" {
+		t.Errorf("expected request to use 'second-model', got %q", gotModel)
+	}
+}
+
+func TestAgent_MaxTokens(t *testing.T) {
+	var got []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req llm.ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		got = append(got, req.MaxTokens)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(sseTextResponse("ok")))
+	}))
+	defer server.Close()
+
+	client := llm.NewClient("test-key")
+	client.SetBaseURL(server.URL)
+
+	ag := New(Options{
+		Client:     client,
+		Registry:   tool.NewRegistry(),
+		Permission: permission.NewCheckerWithIO(strings.NewReader(""), &bytes.Buffer{}),
+		Model:      "test-model",
+		MaxTokens:  512,
+	})
+	ag.SetOutput(&bytes.Buffer{}, &bytes.Buffer{})
+
+	ag.Send(context.Background(), "Hi")
+	ag.SetMaxTokens(0)
+	ag.Send(context.Background(), "Hi again")
+	if len(got) != 2 || got[0] != 512 || got[1] != 0 {
+		t.Errorf("max_tokens per request = %v, want [512 0]", got)
+	}
+}
+
+func TestAgent_HistoryIsACopy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(sseTextResponse("Hello!")))
+	}))
+	defer server.Close()
+
+	client := llm.NewClient("test-key")
+	client.SetBaseURL(server.URL)
+
+	ag := New(Options{
+		Client:       client,
+		Registry:     tool.NewRegistry(),
+		Permission:   permission.NewCheckerWithIO(strings.NewReader(""), &bytes.Buffer{}),
+		Model:        "test-model",
+		SystemPrompt: "sys",
+	})
+	ag.SetOutput(&bytes.Buffer{}, &bytes.Buffer{})
+
+	if err := ag.Send(context.Background(), "Hi"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	history := ag.History()
+	if len(history) != 3 {
+		t.Fatalf("expected system, user, assistant messages, got %d", len(history))
+	}
+	if history[2].Content != "Hello!" {
+		t.Errorf("expected assistant reply, got %q", history[2].Content)
+	}
+
+	history[0].Content = "changed"
+	if ag.History()[0].Content != "sys" {
+		t.Error("History should return a copy")
+	}
+}
+
+func TestAgent_Restore(t *testing.T) {
+	var req llm.ChatCompletionRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&req)
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(sseTextResponse("Welcome back")))
+	}))
+	defer server.Close()
+
+	client := llm.NewClient("test-key")
+	client.SetBaseURL(server.URL)
+
+	ag := New(Options{
+		Client:       client,
+		Registry:     tool.NewRegistry(),
+		Permission:   permission.NewCheckerWithIO(strings.NewReader(""), &bytes.Buffer{}),
+		Model:        "test-model",
+		SystemPrompt: "new sys",
+	})
+	ag.SetOutput(&bytes.Buffer{}, &bytes.Buffer{})
+	turns := 0
+	ag.OnTurn(func() { turns++ })
+
+	ag.Restore([]llm.Message{
+		{Role: "system", Content: "old sys"},
+		{Role: "user", Content: "first"},
+		{Role: "assistant", Content: "done"},
+	})
+	if err := ag.Send(context.Background(), "second"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(req.Messages) != 4 || req.Messages[0].Content != "new sys" || req.Messages[1].Content != "first" || req.Messages[3].Content != "second" {
+		t.Errorf("expected the restored conversation with the current system prompt, got %+v", req.Messages)
+	}
+	if got := ag.History(); len(got) != 5 || got[4].Content != "Welcome back" {
+		t.Errorf("unexpected history %+v", got)
+	}
+	if turns != 1 {
+		t.Errorf("expected OnTurn to be called once, got %d", turns)
+	}
+}
+
+func TestAgent_Inject(t *testing.T) {
+	var requests []llm.ChatCompletionRequest
+	var ag *Agent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req llm.ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		requests = append(requests, req)
+		w.Header().Set("Content-Type", "text/event-stream")
+		if len(requests) == 2 {
+			// Arrives while the turn is running.
+			ag.InjectMessage("vim", "also check the tests")
+			w.Write([]byte(sseToolCallResponse("call_1", "test_tool", `{}`)))
+		} else {
+			w.Write([]byte(sseTextResponse("ok")))
+		}
+	}))
+	defer server.Close()
+
+	client := llm.NewClient("test-key")
+	client.SetBaseURL(server.URL)
+	reg := tool.NewRegistry()
+	reg.Register(&mockTool{name: "test_tool", perm: tool.PermissionAuto, result: "ran"})
+	ag = New(Options{
+		Client:     client,
+		Registry:   reg,
+		Permission: permission.NewCheckerWithIO(strings.NewReader(""), &bytes.Buffer{}),
+		Model:      "test-model",
+	})
+	ag.SetOutput(&bytes.Buffer{}, &bytes.Buffer{})
+
+	ag.InjectToolResult("editor_selection", json.RawMessage(`{"file":"main.go"}`), "func main() {}")
+	if err := ag.Send(context.Background(), "explain this"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ag.Send(context.Background(), "go on"); err != nil {
+		t.Fatal(err)
+	}
+
+	roles := func(msgs []llm.Message) string {
+		var out []string
+		for _, m := range msgs {
+			out = append(out, m.Role)
+		}
+		return strings.Join(out, ",")
+	}
+	first := requests[0].Messages
+	if roles(first) != "assistant,tool,user" || first[0].ToolCalls[0].Function.Name != "editor_selection" || first[1].Content != "func main() {}" {
+		t.Errorf("expected the injected tool result before the prompt, got %+v", first)
+	}
+	last := requests[2].Messages
+	if roles(last) != "assistant,tool,user,assistant,user,assistant,tool,user" || last[len(last)-1].Content != "Message from vim: also check the tests" {
+		t.Errorf("expected the message injected mid-turn after the tool result, got %+v", last)
+	}
+}
+
+func TestAgent_RecordsMetrics(t *testing.T) {
+	callCount := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		callCount++
+		w.Header().Set("Content-Type", "text/event-stream")
+		if callCount == 1 {
+			w.Write([]byte(sseToolCallResponse("call_1", "metrics_tool", `{}`)))
+			return
+		}
+		w.Write([]byte("data: {\"id\":\"1\",\"choices\":[],\"usage\":{\"prompt_tokens\":12,\"completion_tokens\":3,\"total_tokens\":15}}\n\n"))
+		w.Write([]byte(sseTextResponse("done")))
+	}))
+	defer server.Close()
+
+	client := llm.NewClient("test-key")
+	client.SetBaseURL(server.URL)
+	reg := tool.NewRegistry()
+	reg.Register(&mockTool{name: "metrics_tool", perm: tool.PermissionAuto, result: "ok"})
+
+	ag := New(Options{
+		Client:     client,
+		Registry:   reg,
+		Permission: permission.NewCheckerWithIO(strings.NewReader(""), &bytes.Buffer{}),
+		Model:      "metrics-model",
+	})
+	ag.SetOutput(&bytes.Buffer{}, &bytes.Buffer{})
+
+	if err := ag.Send(context.Background(), "go"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := metrics.LLMRequests.Value("metrics-model"); got != 2 {
+		t.Errorf("requests = %v, want 2", got)
+	}
+	if got := metrics.LLMTokens.Value("metrics-model", "prompt"); got != 12 {
+		t.Errorf("prompt tokens = %v, want 12", got)
+	}
+	if got := metrics.LLMTokens.Value("metrics-model", "completion"); got != 3 {
+		t.Errorf("completion tokens = %v, want 3", got)
+	}
+	if got := metrics.ToolCalls.Value("metrics_tool", "ok"); got != 1 {
+		t.Errorf("tool calls = %v, want 1", got)
+	}
+	if got := metrics.ToolDuration.Count("metrics_tool"); got != 1 {
+		t.Errorf("tool duration observations = %d, want 1", got)
+	}
+}
+
+func sseFinishResponse(content, reason string) string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("data: {\"id\":\"1\",\"choices\":[{\"index\":0,\"delta\":{\"role\":\"assistant\",\"content\":%s},\"finish_reason\":null}]}\n\n", jsonStr(content)))
+	b.WriteString(fmt.Sprintf("data: {\"id\":\"1\",\"choices\":[{\"index\":0,\"delta\":{},\"finish_reason\":%s}]}\n\n", jsonStr(reason)))
+	b.WriteString("data: [DONE]\n")
+	return b.String()
+}
+
+func TestAgent_WarnsOnContentFilter(t *testing.T) {
+	callCount := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		callCount++
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(sseFinishResponse("Partial", "content_filter")))
+	}))
+	defer server.Close()
+
+	client := llm.NewClient("test-key")
+	client.SetBaseURL(server.URL)
+
+	ag := New(Options{
+		Client:     client,
+		Registry:   tool.NewRegistry(),
+		Permission: permission.NewCheckerWithIO(strings.NewReader(""), &bytes.Buffer{}),
+		Model:      "test-model",
+	})
+	var stderr bytes.Buffer
+	ag.SetOutput(&bytes.Buffer{}, &stderr)
+
+	if err := ag.Send(context.Background(), "Hi"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if callCount != 1 {
+		t.Errorf("expected 1 request, got %d", callCount)
+	}
+	if !strings.Contains(stderr.String(), "[warning] Response stopped by the provider's content filter") {
+		t.Errorf("expected a content filter warning, got %q", stderr.String())
+	}
+}
+
+func TestAgent_ToolOutputs(t *testing.T) {
+	ag := New(Options{
+		Client:       llm.NewClient("test-key"),
+		Registry:     tool.NewRegistry(),
+		Permission:   permission.NewCheckerWithIO(strings.NewReader(""), &bytes.Buffer{}),
+		Model:        "test-model",
+		SystemPrompt: "sys",
+	})
+	ag.AddToolResult("shell_exec", json.RawMessage(`{"command":"go test"}`), "FAIL TestX")
+	ag.AddToolResult("read_file", json.RawMessage(`{"file_path":"a.go"}`), "package a")
+
+	outputs := ag.ToolOutputs()
+	if len(outputs) != 2 {
+		t.Fatalf("expected 2 outputs, got %+v", outputs)
+	}
+	if outputs[0] != (tool.CallOutput{Name: "shell_exec", Args: `{"command":"go test"}`, Result: "FAIL TestX"}) {
+		t.Errorf("unexpected first output %+v", outputs[0])
+	}
+	if outputs[1].Name != "read_file" || outputs[1].Result != "package a" {
+		t.Errorf("unexpected second output %+v", outputs[1])
+	}
+}
+
+func TestAgent_PartiallyAcceptedHunks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "numbers.txt")
+	var lines []string
+	for i := 1; i <= 30; i++ {
+		lines = append(lines, fmt.Sprintf("%d", i))
+	}
+	before := strings.Join(lines, "\n") + "\n"
+	os.WriteFile(path, []byte(before), 0644)
+	lines[1], lines[25] = "two", "twenty-six"
+	args, _ := json.Marshal(map[string]string{"file_path": path, "content": strings.Join(lines, "\n") + "\n"})
+
+	var followUp string
+	callCount := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		callCount++
+		w.Header().Set("Content-Type", "text/event-stream")
+		if callCount == 1 {
+			w.Write([]byte(sseToolCallResponse("call_1", "write_file", string(args))))
+		} else {
+			body, _ := io.ReadAll(r.Body)
+			followUp = string(body)
+			w.Write([]byte(sseTextResponse("Noted.")))
+		}
+	}))
+	defer server.Close()
+
+	client := llm.NewClient("test-key")
+	client.SetBaseURL(server.URL)
+	reg := tool.NewRegistry()
+	reg.Register(&tool.WriteFileTool{})
+
+	// Accept the first hunk and reject the second.
+	perm := permission.NewCheckerWithIO(strings.NewReader("y\nn\n"), &bytes.Buffer{})
+	ag := New(Options{Client: client, Registry: reg, Permission: perm, Model: "test-model"})
+	ag.SetOutput(&bytes.Buffer{}, &bytes.Buffer{})
+
+	if err := ag.Send(context.Background(), "Spell out two numbers"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	if want := strings.Replace(before, "\n2\n", "\ntwo\n", 1); string(data) != want {
+		t.Errorf("expected only the first hunk applied, got:\n%s", data)
+	}
+	if !strings.Contains(followUp, "Applied 1 of 2 hunks") || !strings.Contains(followUp, `+twenty-six`) {
+		t.Errorf("expected the rejected hunk reported to the model, got %s", followUp)
+	}
+}
+
+func TestAgent_WarnsOnPromptToolsFallback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]any
+		json.NewDecoder(r.Body).Decode(&req)
+		if req["tools"] != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":{"message":"registry.ollama.ai/library/gemma:latest does not support tools"}}`))
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(sseTextResponse("ok")))
+	}))
+	defer server.Close()
+	client := llm.NewClient("")
+	client.SetBaseURL(server.URL)
+	reg := tool.NewRegistry()
+	reg.Register(&mockTool{name: "test_tool", perm: tool.PermissionAuto})
+
+	ag := New(Options{Client: client, Registry: reg, Model: "gemma"})
+	ag.SetOutput(&bytes.Buffer{}, &bytes.Buffer{})
+	var warnings []string
+	ag.OnEvent(func(e Event) {
+		if w, ok := e.(Warning); ok {
+			warnings = append(warnings, w.Text)
+		}
+	})
+
+	for _, msg := range []string{"Hi", "Again"} {
+		if err := ag.Send(context.Background(), msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "describing the tools in the prompt") {
+		t.Errorf("expected one warning about the switch, got %q", warnings)
+	}
+}
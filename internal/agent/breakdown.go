@@ -0,0 +1,102 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/gavinyap/stormtrooper/internal/llm"
+)
+
+// ContextPart is one piece of the prompt sent with a request.
+type ContextPart struct {
+	// Kind groups parts: "system", "pins", "summary" for compacted
+	// turns, "user", "assistant", "tool", or "tools" for the tool
+	// definitions.
+	Kind string
+	// Label says what the part is, e.g. "Tool result: read_file".
+	Label string
+	// Tokens is an estimate; see llm.EstimateTokens.
+	Tokens int
+}
+
+// ContextBreakdown splits the prompt the next request would send into
+// parts, in the order they are sent: the system prompt by section, the
+// pinned files, every message after pruning, and the tool definitions.
+// It must not be called during Send.
+func (a *Agent) ContextBreakdown() []ContextPart {
+	var parts []ContextPart
+	for _, m := range a.pins.withPins(prune(a.history, a.prune)) {
+		switch {
+		case m.Role == "system" && strings.HasPrefix(m.Content, pinsPreamble):
+			parts = append(parts, pinParts(m.Content)...)
+		case isCompacted(m):
+			parts = append(parts, ContextPart{Kind: "summary", Label: "Summary of compacted turns", Tokens: llm.EstimateTokens(m)})
+		case m.Role == "system":
+			parts = append(parts, systemParts(m.Content)...)
+		default:
+			parts = append(parts, ContextPart{Kind: m.Role, Label: messageLabel(m), Tokens: llm.EstimateTokens(m)})
+		}
+	}
+
+	a.mu.Lock()
+	profile := a.profile
+	a.mu.Unlock()
+	if defs := a.convertToolDefs(profile); len(defs) > 0 {
+		data, _ := json.Marshal(defs)
+		parts = append(parts, ContextPart{Kind: "tools", Label: fmt.Sprintf("Tool definitions (%d)", len(defs)), Tokens: len(data) / 4})
+	}
+	return parts
+}
+
+// systemParts splits a system prompt at its "# Heading" sections.
+func systemParts(prompt string) []ContextPart {
+	sections := strings.Split(prompt, "\n\n# ")
+	parts := []ContextPart{{Kind: "system", Label: "System prompt", Tokens: len(sections[0]) / 4}}
+	for _, s := range sections[1:] {
+		heading, _, _ := strings.Cut(s, "\n")
+		parts = append(parts, ContextPart{Kind: "system", Label: "System prompt: " + heading, Tokens: (len(s) + 4) / 4})
+	}
+	return parts
+}
+
+// pinParts splits the pinned files message into one part per file.
+func pinParts(content string) []ContextPart {
+	files := strings.Split(content, "\n## ")
+	var parts []ContextPart
+	for _, f := range files[1:] {
+		path, _, _ := strings.Cut(f, "\n")
+		parts = append(parts, ContextPart{Kind: "pins", Label: "Pinned: " + path, Tokens: (len(f) + 4) / 4})
+	}
+	if len(parts) > 0 {
+		parts[0].Tokens += len(files[0]) / 4
+	}
+	return parts
+}
+
+// messageLabel describes a conversation message in a line.
+func messageLabel(m llm.Message) string {
+	switch m.Role {
+	case "user":
+		return "User: " + firstLine(m.Content, 50)
+	case "tool":
+		return "Tool result: " + m.Name
+	}
+	if len(m.ToolCalls) > 0 {
+		names := make([]string, len(m.ToolCalls))
+		for i, tc := range m.ToolCalls {
+			names[i] = tc.Function.Name
+		}
+		return "Assistant: calls " + strings.Join(names, ", ")
+	}
+	return "Assistant: " + firstLine(m.Content, 50)
+}
+
+// firstLine returns the first line of s, cut to n bytes.
+func firstLine(s string, n int) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	if len(line) > n {
+		line = line[:n] + "..."
+	}
+	return line
+}
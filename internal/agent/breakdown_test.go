@@ -0,0 +1,64 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gavinyap/stormtrooper/internal/llm"
+	"github.com/gavinyap/stormtrooper/internal/tool"
+)
+
+func TestContextBreakdown(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.md")
+	os.WriteFile(path, []byte(strings.Repeat("n", 400)), 0644)
+	pins := NewPins(nil)
+	pins.Pin(path)
+
+	reg := tool.NewRegistry()
+	reg.Register(&mockTool{name: "read_file", perm: tool.PermissionAuto})
+	ag := New(Options{
+		Registry:     reg,
+		Model:        "test-model",
+		SystemPrompt: "You are Stormtrooper.\n\n# Memory\n\n" + strings.Repeat("m", 800) + "\n\n# Environment\n- Date: today",
+		Pins:         pins,
+	})
+	ag.Restore([]llm.Message{
+		{Role: "user", Content: "Read the notes\nand summarize them"},
+		{Role: "assistant", ToolCalls: []llm.ToolCall{{ID: "1", Function: llm.FunctionCall{Name: "read_file", Arguments: `{"file_path":"notes.md"}`}}}},
+		{Role: "tool", ToolCallID: "1", Name: "read_file", Content: strings.Repeat("x", 2000)},
+		{Role: "assistant", Content: "They are about n."},
+	})
+
+	parts := ag.ContextBreakdown()
+	var labels []string
+	tokens := map[string]int{}
+	for _, p := range parts {
+		labels = append(labels, p.Kind+"|"+p.Label)
+		tokens[p.Label] = p.Tokens
+	}
+	want := []string{
+		"system|System prompt",
+		"system|System prompt: Memory",
+		"system|System prompt: Environment",
+		"pins|Pinned: " + path,
+		"user|User: Read the notes",
+		"assistant|Assistant: calls read_file",
+		"tool|Tool result: read_file",
+		"assistant|Assistant: They are about n.",
+		"tools|Tool definitions (1)",
+	}
+	if strings.Join(labels, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("parts =\n%s\nwant\n%s", strings.Join(labels, "\n"), strings.Join(want, "\n"))
+	}
+	if tokens["Tool result: read_file"] != 500 {
+		t.Errorf("tool result tokens = %d, want 500", tokens["Tool result: read_file"])
+	}
+	if n := tokens["System prompt: Memory"]; n < 200 || n > 210 {
+		t.Errorf("memory tokens = %d, want about 200", n)
+	}
+	if n := tokens["Pinned: "+path]; n < 100 {
+		t.Errorf("pinned file tokens = %d, want at least 100", n)
+	}
+}
@@ -0,0 +1,206 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gavinyap/stormtrooper/internal/llm"
+	"github.com/gavinyap/stormtrooper/internal/metrics"
+)
+
+// CompactOptions controls when older turns are replaced by a summary, so
+// a long session stays inside the model's context window.
+type CompactOptions struct {
+	// Threshold is the estimated size of a request, in tokens, above
+	// which older turns are compacted before it is sent; 0 leaves
+	// compaction to Compact.
+	Threshold int
+	// KeepTurns is how many of the most recent turns are kept as they
+	// are; 0 means DefaultKeepTurns.
+	KeepTurns int
+	// Model writes the summary; empty means the agent's model.
+	Model string
+}
+
+// DefaultKeepTurns is how many recent turns compaction keeps by default.
+const DefaultKeepTurns = 2
+
+// compactedPreamble starts the system message that holds the summary.
+const compactedPreamble = "Summary of the earlier conversation, which was compacted to save context:\n\n"
+
+// Limits on a compaction request: each tool result and tool call in the
+// transcript, the whole transcript, and the summary.
+const (
+	maxCompactResult   = 4 * 1024
+	maxCompactArgs     = 512
+	maxCompactInput    = 512 * 1024
+	maxCompactResponse = 2048
+)
+
+const compactPrompt = "You compact the history of a coding session so the agent can carry on with less context. The agent will see only your summary and the latest turns. Keep the user's goals and requests, decisions and their reasons, constraints discovered, approaches ruled out, files read or changed and how, commands run and results that still matter, unresolved errors verbatim, and what was about to happen next. Be specific: paths, names, and numbers. Answer with the summary only."
+
+// ErrNothingToCompact is returned by Compact when every turn is recent
+// enough to keep.
+var ErrNothingToCompact = errors.New("nothing to compact: the conversation is no longer than the turns kept")
+
+// CompactResult describes a compaction.
+type CompactResult struct {
+	// Messages is how many messages the summary replaced.
+	Messages int
+	// Before and After estimate the tokens of the next request.
+	Before, After int
+}
+
+// Compact replaces all but the most recent turns with a summary written
+// by the model, following focus if it is not empty. The replaced turns
+// can be brought back with Rewind. It must not be called during Send.
+func (a *Agent) Compact(ctx context.Context, focus string) (CompactResult, error) {
+	res, err := a.compact(ctx, focus)
+	if err == nil && a.checkpoints != nil {
+		a.checkpoints.Commit(a.history)
+	}
+	return res, err
+}
+
+// autoCompact compacts the history when the next request would be over
+// the threshold. It reports whether it tried, successfully or not.
+func (a *Agent) autoCompact(ctx context.Context) bool {
+	if a.compaction.Threshold <= 0 {
+		return false
+	}
+	if tokens := a.estimateRequest(); tokens <= a.compaction.Threshold {
+		return false
+	}
+	res, err := a.compact(ctx, "")
+	switch {
+	case errors.Is(err, ErrNothingToCompact):
+	case err != nil:
+		a.warn("Could not compact the conversation: %v", err)
+	default:
+		a.emit(Compacted{Text: fmt.Sprintf("Summarized %d earlier messages to stay under %d tokens (about %d -> %d)",
+			res.Messages, a.compaction.Threshold, res.Before, res.After)})
+	}
+	return true
+}
+
+// estimateRequest returns the estimated tokens of the next request.
+func (a *Agent) estimateRequest() int {
+	total := 0
+	for _, p := range a.ContextBreakdown() {
+		total += p.Tokens
+	}
+	return total
+}
+
+// compact summarizes the messages before the kept turns and puts the
+// summary in their place, after the system prompt.
+func (a *Agent) compact(ctx context.Context, focus string) (CompactResult, error) {
+	start := 0
+	if len(a.history) > 0 && a.history[0].Role == "system" && !isCompacted(a.history[0]) {
+		start = 1
+	}
+	cut := a.keptTurnsStart()
+	if cut <= start || (cut == start+1 && isCompacted(a.history[start])) {
+		return CompactResult{}, ErrNothingToCompact
+	}
+	old := a.history[start:cut]
+
+	model := a.compaction.Model
+	if model == "" {
+		model = a.Model()
+	}
+	prompt := compactPrompt
+	if focus != "" {
+		prompt += " Pay most attention to: " + focus
+	}
+	metrics.LLMRequests.Inc(model)
+	resp, err := a.client.ChatCompletion(ctx, llm.ChatCompletionRequest{
+		Model: model,
+		Messages: []llm.Message{
+			{Role: "system", Content: prompt},
+			{Role: "user", Content: transcript(old)},
+		},
+		MaxTokens: maxCompactResponse,
+	})
+	if err == nil {
+		a.recordUsage(model, resp.Usage)
+		if len(resp.Choices) == 0 || strings.TrimSpace(resp.Choices[0].Message.Content) == "" {
+			err = fmt.Errorf("empty response")
+		}
+	}
+	if err != nil {
+		metrics.LLMErrors.Inc(model)
+		return CompactResult{}, err
+	}
+
+	res := CompactResult{Messages: len(old), Before: a.estimateRequest()}
+	summary := llm.Message{Role: "system", Content: compactedPreamble + strings.TrimSpace(resp.Choices[0].Message.Content)}
+	history := append([]llm.Message(nil), a.history[:start]...)
+	history = append(history, summary)
+	a.history = append(history, a.history[cut:]...)
+	res.After = a.estimateRequest()
+	return res, nil
+}
+
+// keptTurnsStart returns the index of the first message of the turns
+// compaction keeps, or 0 if there are no more turns than that.
+func (a *Agent) keptTurnsStart() int {
+	keep := a.compaction.KeepTurns
+	if keep <= 0 {
+		keep = DefaultKeepTurns
+	}
+	turns := 0
+	for i := len(a.history) - 1; i >= 0; i-- {
+		if a.history[i].Role == "user" {
+			turns++
+			if turns == keep {
+				return i
+			}
+		}
+	}
+	return 0
+}
+
+// isCompacted reports whether m holds the summary of compacted turns.
+func isCompacted(m llm.Message) bool {
+	return m.Role == "system" && strings.HasPrefix(m.Content, compactedPreamble)
+}
+
+// transcript renders messages as plain text for the summarizer, with
+// long tool calls and results cut. When it is still too long, the
+// oldest part is dropped.
+func transcript(messages []llm.Message) string {
+	var b strings.Builder
+	for _, m := range messages {
+		switch m.Role {
+		case "system":
+			fmt.Fprintf(&b, "Earlier summary:\n%s\n\n", strings.TrimPrefix(m.Content, compactedPreamble))
+		case "user":
+			fmt.Fprintf(&b, "User: %s\n\n", m.Content)
+		case "assistant":
+			if m.Content != "" {
+				fmt.Fprintf(&b, "Assistant: %s\n\n", m.Content)
+			}
+			for _, tc := range m.ToolCalls {
+				fmt.Fprintf(&b, "Assistant called %s(%s)\n\n", tc.Function.Name, clip(tc.Function.Arguments, maxCompactArgs))
+			}
+		case "tool":
+			fmt.Fprintf(&b, "Result of %s:\n%s\n\n", m.Name, clip(m.Content, maxCompactResult))
+		}
+	}
+	out := b.String()
+	if len(out) > maxCompactInput {
+		out = "[earlier messages omitted]\n\n" + out[len(out)-maxCompactInput:]
+	}
+	return out
+}
+
+// clip shortens s to n bytes, noting how much was left out.
+func clip(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return fmt.Sprintf("%s\n[... %d more bytes]", s[:n], len(s)-n)
+}
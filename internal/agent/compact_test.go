@@ -0,0 +1,133 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gavinyap/stormtrooper/internal/checkpoint"
+	"github.com/gavinyap/stormtrooper/internal/llm"
+	"github.com/gavinyap/stormtrooper/internal/tool"
+)
+
+// compactServer answers non-streaming requests with summary and
+// streaming ones with "ok", recording the non-streaming ones.
+func compactServer(t *testing.T, summary string, requests *[]llm.ChatCompletionRequest) *llm.Client {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req llm.ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Stream {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Write([]byte(sseTextResponse("ok")))
+			return
+		}
+		*requests = append(*requests, req)
+		json.NewEncoder(w).Encode(llm.ChatCompletionResponse{
+			Choices: []llm.Choice{{Message: llm.Message{Role: "assistant", Content: summary}}},
+		})
+	}))
+	t.Cleanup(server.Close)
+	client := llm.NewClient("test-key")
+	client.SetBaseURL(server.URL)
+	return client
+}
+
+func TestAgent_Compact(t *testing.T) {
+	var requests []llm.ChatCompletionRequest
+	ag := New(Options{
+		Client:       compactServer(t, "The user is renaming Foo to Bar.", &requests),
+		Registry:     tool.NewRegistry(),
+		Model:        "main",
+		SystemPrompt: "You are Stormtrooper.",
+		Checkpoints:  checkpoint.New(nil),
+		Compact:      CompactOptions{KeepTurns: 1, Model: "cheap"},
+	})
+	ag.Restore([]llm.Message{
+		{Role: "user", Content: "Rename Foo to Bar"},
+		{Role: "assistant", ToolCalls: []llm.ToolCall{{ID: "1", Function: llm.FunctionCall{Name: "grep", Arguments: `{"pattern":"Foo"}`}}}},
+		{Role: "tool", ToolCallID: "1", Name: "grep", Content: strings.Repeat("a.go: Foo\n", 2000)},
+		{Role: "assistant", Content: "Found it in a.go."},
+		{Role: "user", Content: "Now update the docs"},
+		{Role: "assistant", Content: "Done."},
+	})
+	before := ag.History()
+
+	res, err := ag.Compact(context.Background(), "the docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 1 || requests[0].Model != "cheap" {
+		t.Fatalf("summary requests = %+v", requests)
+	}
+	sent := requests[0].Messages
+	if !strings.Contains(sent[0].Content, "Pay most attention to: the docs") {
+		t.Errorf("the focus should be passed on, got %q", sent[0].Content)
+	}
+	if !strings.Contains(sent[1].Content, "User: Rename Foo to Bar") || !strings.Contains(sent[1].Content, `Assistant called grep({"pattern":"Foo"})`) ||
+		!strings.Contains(sent[1].Content, "more bytes]") || strings.Contains(sent[1].Content, "update the docs") {
+		t.Errorf("transcript = %q", sent[1].Content)
+	}
+
+	history := ag.History()
+	if len(history) != 4 || history[0].Content != "You are Stormtrooper." || !isCompacted(history[1]) ||
+		!strings.HasSuffix(history[1].Content, "renaming Foo to Bar.") || history[2].Content != "Now update the docs" {
+		t.Fatalf("history after compaction = %+v", history)
+	}
+	if res.Messages != 4 || res.After >= res.Before {
+		t.Errorf("result = %+v", res)
+	}
+	if parts := ag.ContextBreakdown(); parts[1].Kind != "summary" {
+		t.Errorf("expected the summary as a part, got %+v", parts[1])
+	}
+
+	// A resumed session keeps its summary, and compacting again folds
+	// the old summary into the new one.
+	ag.Restore(history)
+	if h := ag.History(); len(h) != 4 || !isCompacted(h[1]) {
+		t.Errorf("Restore should keep the summary, got %+v", h)
+	}
+	if _, err := ag.Compact(context.Background(), ""); !errors.Is(err, ErrNothingToCompact) {
+		t.Errorf("expected ErrNothingToCompact with one turn, got %v", err)
+	}
+
+	if _, err := ag.Rewind(2); err != nil {
+		t.Fatal(err)
+	}
+	if h := ag.History(); len(h) != len(before) {
+		t.Errorf("Rewind should undo the compaction, got %d messages, want %d", len(h), len(before))
+	}
+}
+
+func TestAgent_AutoCompact(t *testing.T) {
+	var requests []llm.ChatCompletionRequest
+	var stderr bytes.Buffer
+	ag := New(Options{
+		Client:   compactServer(t, "Earlier: setup.", &requests),
+		Registry: tool.NewRegistry(),
+		Model:    "main",
+		Compact:  CompactOptions{Threshold: 500},
+	})
+	ag.SetOutput(&bytes.Buffer{}, &stderr)
+
+	for _, prompt := range []string{strings.Repeat("x", 2400), "second", "third"} {
+		if err := ag.Send(context.Background(), prompt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(requests) != 1 {
+		t.Fatalf("expected one compaction, got %d", len(requests))
+	}
+	if !strings.Contains(stderr.String(), "[compact] Summarized 2 earlier messages to stay under 500 tokens") {
+		t.Errorf("stderr = %q", stderr.String())
+	}
+	history := ag.History()
+	if !isCompacted(history[0]) || history[1].Content != "second" {
+		t.Errorf("history = %+v", history)
+	}
+}
@@ -0,0 +1,61 @@
+package agent
+
+import (
+	"context"
+
+	"github.com/gavinyap/stormtrooper/internal/llm"
+)
+
+// DefaultMaxContinuations is how many follow-up requests a response cut
+// off by the length limit may use when Options.MaxContinuations is 0.
+const DefaultMaxContinuations = 3
+
+const (
+	// continuePrompt asks for the rest of a truncated response. It is only
+	// sent with continuation requests and never kept in the history.
+	continuePrompt = "Your previous response was cut off by the length limit. Continue exactly where it stopped, without repeating any of it."
+
+	// retryPrompt asks for tool calls whose arguments were cut off.
+	retryPrompt = "Your previous response was cut off by the length limit before the tool calls were complete, so they were not run. Make them again, splitting large content across several smaller calls."
+)
+
+// continueLimit returns how many continuation requests a response may use.
+func (a *Agent) continueLimit() int {
+	switch {
+	case a.continues == 0:
+		return DefaultMaxContinuations
+	case a.continues < 0:
+		return 0
+	}
+	return a.continues
+}
+
+// stitch continues a text response that was cut off by the length limit,
+// appending each continuation to msg so the history holds one assistant
+// message. It stops when a response finishes for any other reason, ends in
+// tool calls, or the continuation limit is reached, and returns the
+// stitched message with the last finish reason.
+func (a *Agent) stitch(ctx context.Context, req llm.ChatCompletionRequest, msg *llm.Message, finish string) (*llm.Message, string, error) {
+	base := req.Messages
+	for n := 0; finish == "length" && len(msg.ToolCalls) == 0 && n < a.continueLimit(); n++ {
+		req.Messages = append(base[:len(base):len(base)], *msg, llm.Message{Role: "user", Content: continuePrompt})
+		next, reason, err := a.stream(ctx, req)
+		if err != nil {
+			return nil, "", err
+		}
+		msg.Content += next.Content
+		msg.ToolCalls = next.ToolCalls
+		finish = reason
+	}
+	return msg, finish, nil
+}
+
+// warnTruncated reports a response that is still cut off by the length
+// limit after any continuations.
+func (a *Agent) warnTruncated() {
+	if limit := a.continueLimit(); limit > 0 {
+		a.warn("Response still cut off by the length limit after %d continuations", limit)
+		return
+	}
+	a.warn("Response cut off by the length limit")
+}
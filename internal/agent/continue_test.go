@@ -0,0 +1,161 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gavinyap/stormtrooper/internal/llm"
+	"github.com/gavinyap/stormtrooper/internal/permission"
+	"github.com/gavinyap/stormtrooper/internal/tool"
+)
+
+func newContinueAgent(t *testing.T, url string, limit int, reg *tool.Registry) *Agent {
+	t.Helper()
+	client := llm.NewClient("test-key")
+	client.SetBaseURL(url)
+	if reg == nil {
+		reg = tool.NewRegistry()
+	}
+	return New(Options{
+		Client:           client,
+		Registry:         reg,
+		Permission:       permission.NewCheckerWithIO(strings.NewReader(""), &bytes.Buffer{}),
+		Model:            "test-model",
+		MaxContinuations: limit,
+	})
+}
+
+func TestAgent_StitchesTruncatedResponse(t *testing.T) {
+	var requests []llm.ChatCompletionRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req llm.ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		requests = append(requests, req)
+		w.Header().Set("Content-Type", "text/event-stream")
+		if len(requests) == 1 {
+			w.Write([]byte(sseFinishResponse("Hello, ", "length")))
+			return
+		}
+		w.Write([]byte(sseFinishResponse("world!", "stop")))
+	}))
+	defer server.Close()
+
+	ag := newContinueAgent(t, server.URL, 0, nil)
+	var stdout, stderr bytes.Buffer
+	ag.SetOutput(&stdout, &stderr)
+
+	if err := ag.Send(context.Background(), "Hi"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(requests) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(requests))
+	}
+	msgs := requests[1].Messages
+	if partial := msgs[len(msgs)-2]; partial.Role != "assistant" || partial.Content != "Hello, " {
+		t.Errorf("expected the partial reply before the prompt, got %+v", partial)
+	}
+	if last := msgs[len(msgs)-1]; last.Role != "user" || last.Content != continuePrompt {
+		t.Errorf("expected the continuation prompt, got %+v", last)
+	}
+
+	history := ag.History()
+	if len(history) != 2 {
+		t.Fatalf("expected user and assistant messages, got %+v", history)
+	}
+	if history[1].Content != "Hello, world!" {
+		t.Errorf("stitched reply = %q", history[1].Content)
+	}
+	if stdout.String() != "Hello, world!\n" {
+		t.Errorf("stdout = %q", stdout.String())
+	}
+	if stderr.Len() != 0 {
+		t.Errorf("expected no warning, got %q", stderr.String())
+	}
+}
+
+func TestAgent_StopsAfterContinuationLimit(t *testing.T) {
+	tests := []struct {
+		limit    int
+		requests int
+		warning  string
+	}{
+		{0, DefaultMaxContinuations + 1, "still cut off"},
+		{1, 2, "after 1 continuations"},
+		{-1, 1, "[warning] Response cut off by the length limit"},
+	}
+	for _, tt := range tests {
+		callCount := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			callCount++
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Write([]byte(sseFinishResponse("more", "length")))
+		}))
+
+		ag := newContinueAgent(t, server.URL, tt.limit, nil)
+		var stderr bytes.Buffer
+		ag.SetOutput(&bytes.Buffer{}, &stderr)
+
+		if err := ag.Send(context.Background(), "Hi"); err != nil {
+			t.Fatalf("limit %d: unexpected error: %v", tt.limit, err)
+		}
+		server.Close()
+
+		if callCount != tt.requests {
+			t.Errorf("limit %d: expected %d requests, got %d", tt.limit, tt.requests, callCount)
+		}
+		if !strings.Contains(stderr.String(), tt.warning) {
+			t.Errorf("limit %d: expected %q in warnings, got %q", tt.limit, tt.warning, stderr.String())
+		}
+		if got := ag.History()[1].Content; got != strings.Repeat("more", tt.requests) {
+			t.Errorf("limit %d: stitched reply = %q", tt.limit, got)
+		}
+	}
+}
+
+func TestAgent_DropsTruncatedToolCalls(t *testing.T) {
+	mt := &mockTool{name: "write", result: "ok", perm: tool.PermissionAuto}
+	var requests []llm.ChatCompletionRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req llm.ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		requests = append(requests, req)
+		w.Header().Set("Content-Type", "text/event-stream")
+		if len(requests) == 1 {
+			w.Write([]byte("data: {\"id\":\"1\",\"choices\":[{\"index\":0,\"delta\":{\"role\":\"assistant\",\"tool_calls\":[{\"index\":0,\"id\":\"call_1\",\"type\":\"function\",\"function\":{\"name\":\"write\",\"arguments\":\"{\\\"content\\\":\\\"abc\"}}]},\"finish_reason\":null}]}\n\n"))
+			w.Write([]byte("data: {\"id\":\"1\",\"choices\":[{\"index\":0,\"delta\":{},\"finish_reason\":\"length\"}]}\n\n"))
+			w.Write([]byte("data: [DONE]\n"))
+			return
+		}
+		w.Write([]byte(sseTextResponse("done")))
+	}))
+	defer server.Close()
+
+	reg := tool.NewRegistry()
+	reg.Register(mt)
+	ag := newContinueAgent(t, server.URL, 0, reg)
+	ag.SetOutput(&bytes.Buffer{}, &bytes.Buffer{})
+
+	if err := ag.Send(context.Background(), "Hi"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if mt.lastParams != "" {
+		t.Error("truncated tool call should not run")
+	}
+	if len(requests) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(requests))
+	}
+	msgs := requests[1].Messages
+	if last := msgs[len(msgs)-1]; last.Content != retryPrompt {
+		t.Errorf("expected the retry prompt, got %+v", last)
+	}
+	for _, m := range ag.History() {
+		if len(m.ToolCalls) > 0 {
+			t.Errorf("truncated tool calls kept in history: %+v", m)
+		}
+	}
+}
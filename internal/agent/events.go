@@ -0,0 +1,188 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Event is something the agent did that a front end may show: a piece
+// of the reply, a tool call, a sub-agent starting, and so on. Events go
+// to the functions registered with OnEvent. Each is also written to the
+// agent's stdout and stderr as text, reply text to stdout and the rest
+// as lines such as "[tool] read_file" to stderr, for the REPL and
+// headless runs.
+type Event interface {
+	event()
+}
+
+// TokenDelta is a piece of the reply as it streams in.
+type TokenDelta struct {
+	Content string
+}
+
+// ToolStart is a tool call that passed the permission check and is
+// about to run.
+type ToolStart struct {
+	Name string
+	Args json.RawMessage
+}
+
+// ToolResult is a tool call that has finished. Error is the error's
+// class and message if the tool failed to run; a tool that reports a
+// failure in its result, as "Error: ...", has finished normally.
+type ToolResult struct {
+	Name   string
+	Args   json.RawMessage
+	Result string
+	Error  string
+}
+
+// SubAgentSpawn is a sub-agent starting on a task.
+type SubAgentSpawn struct {
+	Task string // shortened for display
+}
+
+// SubAgentDone is a sub-agent that has finished.
+type SubAgentDone struct{}
+
+// SubAgentEvent is an event from a sub-agent. Agent numbers sub-agents
+// running in parallel from 1, is N for the background sub-agent agent-N,
+// and is 0 for a sub-agent on its own.
+type SubAgentEvent struct {
+	Agent int
+	Event Event
+}
+
+// AutoDecision is a permission decision made without asking: a call
+// allowed by a remembered rule, or forbidden by the organization policy.
+type AutoDecision struct {
+	Tool     string
+	Approved bool
+	Rule     string // the remembered approval that allowed the call
+	Reason   string // why the policy forbade the call
+}
+
+// Warning is a problem worth telling the user about, such as a response
+// cut off by the length limit.
+type Warning struct {
+	Text string
+}
+
+// Compacted reports that older turns were summarized to keep the
+// conversation inside the context window.
+type Compacted struct {
+	Text string
+}
+
+// ModelUsed is the model that writes the next part of the response,
+// when turns are routed between models.
+type ModelUsed struct {
+	Model string
+}
+
+// TurnDone is the end of a turn, once Send is about to return its error.
+type TurnDone struct {
+	Err error
+}
+
+func (TokenDelta) event()    {}
+func (ToolStart) event()     {}
+func (ToolResult) event()    {}
+func (SubAgentSpawn) event() {}
+func (SubAgentDone) event()  {}
+func (SubAgentEvent) event() {}
+func (AutoDecision) event()  {}
+func (Warning) event()       {}
+func (Compacted) event()     {}
+func (ModelUsed) event()     {}
+func (TurnDone) event()      {}
+
+// OnEvent registers a function that is called with each event, on the
+// goroutine that caused it (for TUI mode). Events from sub-agents
+// running in parallel arrive concurrently. Functions are called in the
+// order they were added.
+func (a *Agent) OnEvent(fn func(Event)) {
+	a.eventHooks = append(a.eventHooks, fn)
+}
+
+// emit delivers e to the registered functions and writes it as text.
+func (a *Agent) emit(e Event) {
+	for _, hook := range a.eventHooks {
+		hook(e)
+	}
+	writeEvent(a.stdout, a.stderr, e)
+}
+
+// warn emits a Warning.
+func (a *Agent) warn(format string, args ...any) {
+	a.emit(Warning{Text: fmt.Sprintf(format, args...)})
+}
+
+// writeEvent writes e as text: reply text to stdout and one line for
+// other events to stderr, in a single write so lines from sub-agents
+// running in parallel stay whole.
+func writeEvent(stdout, stderr io.Writer, e Event) {
+	if delta, ok := e.(TokenDelta); ok {
+		fmt.Fprint(stdout, delta.Content)
+		return
+	}
+	if line := eventLine(e); line != "" {
+		fmt.Fprintln(stderr, line)
+	}
+}
+
+// eventLine returns the stderr line for e, or "" for none.
+func eventLine(e Event) string {
+	switch e := e.(type) {
+	case ToolStart:
+		return "[tool] " + e.Name
+	case ToolResult:
+		if e.Error != "" {
+			return fmt.Sprintf("[tool:error] %s: %s", e.Name, e.Error)
+		}
+		return "[tool:done] " + e.Name
+	case SubAgentSpawn:
+		return "[agent] Spawning sub-agent: " + e.Task
+	case SubAgentDone:
+		return "[agent] Sub-agent completed"
+	case SubAgentEvent:
+		line := eventLine(e.Event)
+		if line == "" || e.Agent == 0 {
+			return line
+		}
+		return fmt.Sprintf("[agent:%d] %s", e.Agent, line)
+	case AutoDecision:
+		if e.Approved {
+			return fmt.Sprintf("[auto] %s: approved by rule %q", e.Tool, e.Rule)
+		}
+		return fmt.Sprintf("[auto] %s: denied by policy: %s", e.Tool, e.Reason)
+	case Warning:
+		return "[warning] " + e.Text
+	case Compacted:
+		return "[compact] " + e.Text
+	case ModelUsed:
+		return "[model] " + e.Model
+	}
+	return ""
+}
+
+type emitterKey struct{}
+
+// withEmitter returns ctx carrying the emit function of the agent
+// running a tool call, so tools that start sub-agents can pass their
+// events up.
+func withEmitter(ctx context.Context, emit func(Event)) context.Context {
+	return context.WithValue(ctx, emitterKey{}, emit)
+}
+
+// emitterFrom returns the emit function in ctx. A tool run outside an
+// agent writes its events to os.Stderr instead.
+func emitterFrom(ctx context.Context) func(Event) {
+	if emit, ok := ctx.Value(emitterKey{}).(func(Event)); ok {
+		return emit
+	}
+	return func(e Event) { writeEvent(io.Discard, os.Stderr, e) }
+}
@@ -0,0 +1,181 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gavinyap/stormtrooper/internal/llm"
+	"github.com/gavinyap/stormtrooper/internal/permission"
+	"github.com/gavinyap/stormtrooper/internal/tool"
+)
+
+// eventNames describes events without the reply text, which streams in
+// pieces.
+func eventNames(events []Event) []string {
+	var names []string
+	for _, e := range events {
+		switch e := e.(type) {
+		case TokenDelta:
+		case SubAgentEvent:
+			names = append(names, fmt.Sprintf("sub%d:%s", e.Agent, eventNames([]Event{e.Event})[0]))
+		case ToolStart:
+			names = append(names, "start "+e.Name)
+		case ToolResult:
+			names = append(names, "result "+e.Name+" "+e.Result)
+		case TurnDone:
+			names = append(names, fmt.Sprintf("done %v", e.Err))
+		default:
+			names = append(names, eventLine(e))
+		}
+	}
+	return names
+}
+
+func TestAgent_Events(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "text/event-stream")
+		if calls == 1 {
+			w.Write([]byte(sseToolCallResponse("call_1", "test_tool", `{"input":"hello"}`)))
+		} else {
+			w.Write([]byte(sseTextResponse("All done")))
+		}
+	}))
+	defer server.Close()
+	client := llm.NewClient("test-key")
+	client.SetBaseURL(server.URL)
+	reg := tool.NewRegistry()
+	reg.Register(&mockTool{name: "test_tool", perm: tool.PermissionAuto, result: "mock-result"})
+
+	ag := New(Options{Client: client, Registry: reg, Model: "test-model"})
+	var stdout, stderr bytes.Buffer
+	ag.SetOutput(&stdout, &stderr)
+	var events []Event
+	ag.OnEvent(func(e Event) { events = append(events, e) })
+
+	if err := ag.Send(context.Background(), "Use the tool"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"start test_tool", "result test_tool mock-result", "done <nil>"}
+	if got := eventNames(events); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("events = %q, want %q", got, want)
+	}
+	var reply strings.Builder
+	for _, e := range events {
+		if d, ok := e.(TokenDelta); ok {
+			reply.WriteString(d.Content)
+		}
+	}
+	if reply.String() != "All done\n" {
+		t.Errorf("reply from events = %q", reply.String())
+	}
+
+	// The same events are still written as text.
+	if stdout.String() != "All done\n" || stderr.String() != "[tool] test_tool\n[tool:done] test_tool\n" {
+		t.Errorf("stdout %q, stderr %q", stdout.String(), stderr.String())
+	}
+}
+
+func TestAgent_SubAgentEvents(t *testing.T) {
+	var mu sync.Mutex
+	parentCalls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req llm.ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		w.Header().Set("Content-Type", "text/event-stream")
+		if strings.HasPrefix(req.Messages[0].Content, "You are a sub-agent") {
+			if req.Messages[len(req.Messages)-1].Role == "tool" {
+				w.Write([]byte(sseTextResponse("Sub-agent finished")))
+			} else {
+				w.Write([]byte(sseToolCallResponse("call_c", "test_tool", `{}`)))
+			}
+			return
+		}
+		mu.Lock()
+		parentCalls++
+		n := parentCalls
+		mu.Unlock()
+		if n == 1 {
+			w.Write([]byte(sseToolCallResponse("call_p", "spawn_agent", `{"task":"Check the tests"}`)))
+		} else {
+			w.Write([]byte(sseTextResponse("Done")))
+		}
+	}))
+	defer server.Close()
+	client := llm.NewClient("test-key")
+	client.SetBaseURL(server.URL)
+	reg := tool.NewRegistry()
+	reg.Register(&mockTool{name: "test_tool", perm: tool.PermissionAuto, result: "ok"})
+	reg.Register(NewSpawnAgentTool(client, reg, permission.AllowAll{}, "test-model"))
+
+	ag := New(Options{Client: client, Registry: reg, Permission: permission.AllowAll{}, Model: "test-model"})
+	var stderr bytes.Buffer
+	ag.SetOutput(&bytes.Buffer{}, &stderr)
+	var events []Event
+	ag.OnEvent(func(e Event) { events = append(events, e) })
+
+	if err := ag.Send(context.Background(), "Delegate"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"start spawn_agent",
+		"[agent] Spawning sub-agent: Check the tests",
+		"sub0:start test_tool",
+		"sub0:result test_tool ok",
+		"[agent] Sub-agent completed",
+		"result spawn_agent Sub-agent finished\n",
+		"done <nil>",
+	}
+	if got := eventNames(events); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("events = %q, want %q", got, want)
+	}
+	// The sub-agent's reply is the tool result, not part of ours.
+	for _, e := range events {
+		if sub, ok := e.(SubAgentEvent); ok {
+			if _, ok := sub.Event.(TokenDelta); ok {
+				t.Errorf("sub-agent reply text leaked as an event: %+v", sub)
+			}
+		}
+	}
+	if !strings.Contains(stderr.String(), "[agent] Spawning sub-agent: Check the tests\n[tool] test_tool\n[tool:done] test_tool\n[agent] Sub-agent completed\n") {
+		t.Errorf("stderr = %q", stderr.String())
+	}
+}
+
+func TestEventLine(t *testing.T) {
+	tests := []struct {
+		event Event
+		want  string
+	}{
+		{ToolStart{Name: "grep"}, "[tool] grep"},
+		{ToolResult{Name: "grep"}, "[tool:done] grep"},
+		{ToolResult{Name: "grep", Error: "timeout: took too long"}, "[tool:error] grep: timeout: took too long"},
+		{SubAgentSpawn{Task: "lint"}, "[agent] Spawning sub-agent: lint"},
+		{SubAgentDone{}, "[agent] Sub-agent completed"},
+		{SubAgentEvent{Event: ToolStart{Name: "grep"}}, "[tool] grep"},
+		{SubAgentEvent{Agent: 2, Event: SubAgentEvent{Agent: 1, Event: ToolStart{Name: "grep"}}}, "[agent:2] [agent:1] [tool] grep"},
+		{SubAgentEvent{Agent: 2, Event: TurnDone{}}, ""},
+		{AutoDecision{Tool: "shell_exec", Approved: true, Rule: "go test ./..."}, `[auto] shell_exec: approved by rule "go test ./..."`},
+		{AutoDecision{Tool: "shell_exec", Reason: "no curl"}, "[auto] shell_exec: denied by policy: no curl"},
+		{Warning{Text: "cut off"}, "[warning] cut off"},
+		{Compacted{Text: "Summarized 4 earlier messages"}, "[compact] Summarized 4 earlier messages"},
+		{ModelUsed{Model: "cheap"}, "[model] cheap"},
+		{TurnDone{Err: errors.New("boom")}, ""},
+	}
+	for _, tt := range tests {
+		if got := eventLine(tt.event); got != tt.want {
+			t.Errorf("eventLine(%#v) = %q, want %q", tt.event, got, tt.want)
+		}
+	}
+}
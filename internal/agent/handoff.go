@@ -0,0 +1,152 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gavinyap/stormtrooper/internal/tool"
+)
+
+// Limits on the files a handoff carries, so the brief leaves the fresh
+// agent most of its context window.
+const (
+	maxHandoffFile  = 32 * 1024
+	maxHandoffFiles = 128 * 1024
+)
+
+// HandoffTool hands the rest of a long task to a fresh agent. Instead of
+// the whole conversation, the new agent starts from the project's system
+// prompt and a brief the current agent curates: the open task, what has
+// been decided so far, and the files that matter.
+type HandoffTool struct {
+	// Spawner supplies the client, tools, permission handler, and
+	// default model.
+	Spawner *SpawnAgentTool
+	// SystemPrompt is the project context the fresh agent starts from.
+	SystemPrompt string
+	// FS is where the selected files are read; nil means the host.
+	FS tool.FileSystem
+}
+
+type handoffParams struct {
+	Task    string   `json:"task"`
+	Summary string   `json:"summary"`
+	Files   []string `json:"files"`
+	Model   string   `json:"model"`
+}
+
+func (t *HandoffTool) Name() string { return "handoff" }
+func (t *HandoffTool) Description() string {
+	return "Hand the rest of a long task to a fresh agent with a clean context window. Give it the open task, a summary of the decisions and state it must not lose, and the files it needs; it returns its result when done."
+}
+func (t *HandoffTool) Permission() tool.PermissionLevel { return tool.PermissionPrompt }
+
+func (t *HandoffTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+	"type": "object",
+	"properties": {
+		"task": {
+			"type": "string",
+			"description": "What is left to do, stated so someone new could pick it up"
+		},
+		"summary": {
+			"type": "string",
+			"description": "Decisions made, constraints discovered, approaches ruled out, and the current state of the work"
+		},
+		"files": {
+			"type": "array",
+			"items": {"type": "string"},
+			"description": "Paths of files whose current contents the new agent should start with"
+		},
+		"model": {
+			"type": "string",
+			"description": "Model for the new agent (optional, defaults to the current one)"
+		}
+	},
+	"required": ["task", "summary"]
+}`)
+}
+
+// Preview returns a description for the permission prompt.
+func (t *HandoffTool) Preview(params json.RawMessage) string {
+	var p handoffParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return "Hand off to a fresh agent (invalid params)"
+	}
+	task := p.Task
+	if len(task) > 80 {
+		task = task[:80] + "..."
+	}
+	return fmt.Sprintf("Hand off to a fresh agent with %d file(s): %s", len(p.Files), task)
+}
+
+func (t *HandoffTool) Execute(ctx context.Context, params json.RawMessage) (string, error) {
+	var p handoffParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return fmt.Sprintf("Error: invalid parameters: %v", err), nil
+	}
+	if p.Task == "" {
+		return "Error: task is required", nil
+	}
+	if p.Summary == "" {
+		return "Error: summary is required; the new agent sees nothing of this conversation", nil
+	}
+
+	model := t.Spawner.Model
+	if p.Model != "" {
+		model = p.Model
+	}
+	fmt.Fprintf(os.Stderr, "[agent] Handing off to a fresh agent with %d file(s)\n", len(p.Files))
+
+	systemPrompt := t.SystemPrompt
+	if systemPrompt != "" {
+		systemPrompt += "\n\n"
+	}
+	systemPrompt += "You are taking over a task from another agent whose context window filled up. Its brief follows in the first message; trust its decisions rather than re-deriving them. When done, provide a concise summary of what you did and the results."
+
+	child := New(Options{
+		Client:       t.Spawner.Client,
+		Registry:     t.Spawner.Registry,
+		Permission:   t.Spawner.Perm,
+		Model:        model,
+		SystemPrompt: systemPrompt,
+	})
+	return runToCompletion(ctx, child, t.brief(p), t.Spawner.Summary, emitterFrom(ctx), 0), nil
+}
+
+// brief renders the handoff as the fresh agent's first message.
+func (t *HandoffTool) brief(p handoffParams) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## Task\n%s\n\n## Decisions and state so far\n%s\n", strings.TrimSpace(p.Task), strings.TrimSpace(p.Summary))
+	if len(p.Files) == 0 {
+		return b.String()
+	}
+
+	b.WriteString("\n## Files\n")
+	fsys := t.FS
+	if fsys == nil {
+		fsys = tool.LocalFS{}
+	}
+	budget := maxHandoffFiles
+	for _, path := range p.Files {
+		data, err := fsys.ReadFile(path)
+		switch {
+		case err != nil:
+			fmt.Fprintf(&b, "\n### %s\n(could not read: %v)\n", path, err)
+			continue
+		case budget <= 0:
+			fmt.Fprintf(&b, "\n### %s\n(not included: the brief is full; use read_file)\n", path)
+			continue
+		}
+		content, note := string(data), ""
+		if limit := min(maxHandoffFile, budget); len(content) > limit {
+			content, note = content[:limit], "\n[truncated; use read_file for the rest]"
+		}
+		budget -= len(content)
+		fmt.Fprintf(&b, "\n### %s\n```\n%s\n```%s\n", path, strings.TrimRight(content, "\n"), note)
+	}
+	return b.String()
+}
@@ -0,0 +1,108 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gavinyap/stormtrooper/internal/llm"
+	"github.com/gavinyap/stormtrooper/internal/permission"
+	"github.com/gavinyap/stormtrooper/internal/tool"
+)
+
+func TestHandoffToolInterface(t *testing.T) {
+	var _ tool.Tool = &HandoffTool{}
+
+	ht := &HandoffTool{}
+	if ht.Name() != "handoff" {
+		t.Fatalf("expected name handoff, got %s", ht.Name())
+	}
+	if ht.Permission() != tool.PermissionPrompt {
+		t.Fatalf("expected PermissionPrompt, got %d", ht.Permission())
+	}
+	var schema interface{}
+	if err := json.Unmarshal(ht.Schema(), &schema); err != nil {
+		t.Fatalf("schema is not valid JSON: %v", err)
+	}
+	params, _ := json.Marshal(handoffParams{Task: "finish the migration", Files: []string{"a", "b"}})
+	if got := ht.Preview(params); got != "Hand off to a fresh agent with 2 file(s): finish the migration" {
+		t.Fatalf("preview = %q", got)
+	}
+}
+
+func TestHandoffRequiresTaskAndSummary(t *testing.T) {
+	ht := &HandoffTool{Spawner: &SpawnAgentTool{}}
+	for _, p := range []handoffParams{{Summary: "s"}, {Task: "t"}} {
+		params, _ := json.Marshal(p)
+		if got, _ := ht.Execute(context.Background(), params); !strings.HasPrefix(got, "Error:") {
+			t.Errorf("Execute(%+v) = %q, want an error", p, got)
+		}
+	}
+}
+
+func TestHandoffBrief(t *testing.T) {
+	dir := t.TempDir()
+	small := filepath.Join(dir, "small.go")
+	big := filepath.Join(dir, "big.txt")
+	os.WriteFile(small, []byte("package small\n"), 0644)
+	os.WriteFile(big, []byte(strings.Repeat("x", maxHandoffFile+10)), 0644)
+
+	ht := &HandoffTool{}
+	got := ht.brief(handoffParams{
+		Task:    "Port the remaining handlers.",
+		Summary: "Handlers use the new router.",
+		Files:   []string{small, big, filepath.Join(dir, "missing")},
+	})
+	for _, want := range []string{
+		"## Task\nPort the remaining handlers.\n",
+		"## Decisions and state so far\nHandlers use the new router.\n",
+		"### " + small + "\n```\npackage small\n```\n",
+		"[truncated; use read_file for the rest]",
+		"missing\n(could not read:",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("brief missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestHandoffStartsFresh(t *testing.T) {
+	var got []llm.Message
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req llm.ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		got = req.Messages
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(sseTextResponse("All handlers ported.")))
+	}))
+	defer server.Close()
+
+	client := llm.NewClient("test-key")
+	client.SetBaseURL(server.URL)
+	ht := &HandoffTool{
+		Spawner:      NewSpawnAgentTool(client, tool.NewRegistry(), permission.AllowAll{}, "test-model"),
+		SystemPrompt: "Project: example",
+	}
+	params, _ := json.Marshal(handoffParams{Task: "Port the handlers.", Summary: "Use the new router."})
+	result, err := ht.Execute(context.Background(), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result, "All handlers ported.") {
+		t.Errorf("result = %q", result)
+	}
+	if len(got) != 2 {
+		t.Fatalf("the fresh agent should see only its system prompt and the brief, got %d messages", len(got))
+	}
+	if !strings.HasPrefix(got[0].Content, "Project: example\n\nYou are taking over") {
+		t.Errorf("system prompt = %q", got[0].Content)
+	}
+	if !strings.HasPrefix(got[1].Content, "## Task\nPort the handlers.") {
+		t.Errorf("brief = %q", got[1].Content)
+	}
+}
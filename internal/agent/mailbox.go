@@ -0,0 +1,58 @@
+package agent
+
+import (
+	"bytes"
+	"sync"
+)
+
+// Mailbox connects a parent to a sub-agent running in the background.
+// The parent posts follow-up instructions, which the sub-agent picks up
+// before its next model request, and reads back the sub-agent's output
+// so far. A Mailbox is safe for concurrent use.
+type Mailbox struct {
+	mu     sync.Mutex
+	inbox  []string
+	output bytes.Buffer
+}
+
+// NewMailbox returns an empty mailbox.
+func NewMailbox() *Mailbox {
+	return &Mailbox{}
+}
+
+// Post queues a message for the sub-agent.
+func (m *Mailbox) Post(msg string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.inbox = append(m.inbox, msg)
+}
+
+// Pending returns how many posted messages the sub-agent has not read.
+func (m *Mailbox) Pending() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.inbox)
+}
+
+// take removes and returns the queued messages.
+func (m *Mailbox) take() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	msgs := m.inbox
+	m.inbox = nil
+	return msgs
+}
+
+// Write records sub-agent output; the sub-agent's stdout is pointed here.
+func (m *Mailbox) Write(p []byte) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.output.Write(p)
+}
+
+// Output returns everything the sub-agent has written so far.
+func (m *Mailbox) Output() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.output.String()
+}
@@ -0,0 +1,70 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gavinyap/stormtrooper/internal/llm"
+	"github.com/gavinyap/stormtrooper/internal/permission"
+	"github.com/gavinyap/stormtrooper/internal/tool"
+)
+
+func TestMailbox(t *testing.T) {
+	mb := NewMailbox()
+	mb.Post("one")
+	mb.Post("two")
+	if mb.Pending() != 2 {
+		t.Fatalf("Pending() = %d, want 2", mb.Pending())
+	}
+	if got := mb.take(); len(got) != 2 || got[0] != "one" || got[1] != "two" {
+		t.Fatalf("take() = %q", got)
+	}
+	if mb.Pending() != 0 || mb.take() != nil {
+		t.Fatal("take() should empty the inbox")
+	}
+	mb.Write([]byte("partial "))
+	mb.Write([]byte("output"))
+	if mb.Output() != "partial output" {
+		t.Fatalf("Output() = %q", mb.Output())
+	}
+}
+
+func TestAgent_DeliversMailbox(t *testing.T) {
+	var got []llm.Message
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req llm.ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		got = req.Messages
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(sseTextResponse("ok")))
+	}))
+	defer server.Close()
+
+	client := llm.NewClient("test-key")
+	client.SetBaseURL(server.URL)
+	mb := NewMailbox()
+	ag := New(Options{
+		Client:     client,
+		Registry:   tool.NewRegistry(),
+		Permission: permission.NewCheckerWithIO(strings.NewReader(""), &bytes.Buffer{}),
+		Model:      "test-model",
+		Mailbox:    mb,
+	})
+	ag.SetOutput(&bytes.Buffer{}, &bytes.Buffer{})
+
+	mb.Post("only look at the tests")
+	if err := ag.Send(context.Background(), "review the code"); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[1].Role != "user" || got[1].Content != "Message from the agent that started you: only look at the tests" {
+		t.Fatalf("messages = %+v", got)
+	}
+	if mb.Pending() != 0 {
+		t.Error("the message should have been consumed")
+	}
+}
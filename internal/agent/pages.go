@@ -0,0 +1,158 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gavinyap/stormtrooper/internal/tool"
+)
+
+// DefaultPageSize is how much of a long tool result the model sees at
+// once, in bytes.
+const DefaultPageSize = 32 * 1024
+
+// maxPagedResults is how many long results are kept for read_more; the
+// oldest are dropped first.
+const maxPagedResults = 64
+
+// Pages keeps the whole of each tool result too long for one message,
+// so the model sees the first page and fetches the rest with read_more
+// instead of losing it. One Pages is shared by an agent and its
+// sub-agents for the session. Pages are safe for concurrent use.
+type Pages struct {
+	mu      sync.Mutex
+	size    int
+	next    int
+	order   []string
+	results map[string]string
+}
+
+// NewPages returns an empty cache that splits results into pages of
+// size bytes; size <= 0 means DefaultPageSize.
+func NewPages(size int) *Pages {
+	if size <= 0 {
+		size = DefaultPageSize
+	}
+	return &Pages{size: size, results: map[string]string{}}
+}
+
+// Paginate returns result if it fits on a page. Otherwise it keeps the
+// whole result and returns its first page, ending in a note that tells
+// the model how to read on.
+func (p *Pages) Paginate(result string) string {
+	if p == nil || len(result) <= p.size {
+		return result
+	}
+	p.mu.Lock()
+	p.next++
+	id := "r" + strconv.Itoa(p.next)
+	p.results[id] = result
+	p.order = append(p.order, id)
+	if len(p.order) > maxPagedResults {
+		delete(p.results, p.order[0])
+		p.order = p.order[1:]
+	}
+	p.mu.Unlock()
+	page, _ := p.page(id, 1)
+	return page
+}
+
+// page returns page n, counting from 1, of the result with the given ID,
+// and a note on what follows.
+func (p *Pages) page(id string, n int) (string, error) {
+	p.mu.Lock()
+	result, ok := p.results[id]
+	p.mu.Unlock()
+	if !ok {
+		return "", fmt.Errorf("no stored result %s; it may be too old, so run the tool again", id)
+	}
+
+	// Pages end at a line break where there is one in their second half,
+	// so lines are not split between them.
+	start := 0
+	for i := 1; ; i++ {
+		end := min(start+p.size, len(result))
+		if end < len(result) {
+			if nl := strings.LastIndexByte(result[start:end], '\n'); nl >= p.size/2 {
+				end = start + nl + 1
+			}
+		}
+		if i == n {
+			page := result[start:end]
+			if end == len(result) {
+				return page + fmt.Sprintf("\n\n[end of result %s: page %d, bytes %d-%d of %d]", id, n, start+1, end, len(result)), nil
+			}
+			return page + fmt.Sprintf("\n\n[truncated, call read_more with id=%s.%d for the next page (showing bytes %d-%d of %d)]", id, n+1, start+1, end, len(result)), nil
+		}
+		if end == len(result) {
+			return "", fmt.Errorf("result %s has only %d page(s)", id, i)
+		}
+		start = end
+	}
+}
+
+// paginate cuts a long tool result down to its first page. Pages from
+// read_more are never cut again.
+func (a *Agent) paginate(name, result string) string {
+	if name == "read_more" {
+		return result
+	}
+	return a.pages.Paginate(result)
+}
+
+// ReadMoreTool returns the next page of a tool result cut short by
+// Pages.
+type ReadMoreTool struct {
+	Pages *Pages
+}
+
+type readMoreParams struct {
+	ID string `json:"id"`
+}
+
+func (t *ReadMoreTool) Name() string { return "read_more" }
+func (t *ReadMoreTool) Description() string {
+	return "Read the next page of a tool result that was truncated, using the id from its \"[truncated, call read_more with id=...]\" note"
+}
+func (t *ReadMoreTool) Permission() tool.PermissionLevel { return tool.PermissionAuto }
+func (t *ReadMoreTool) Capabilities() []tool.Capability  { return []tool.Capability{tool.CapRead} }
+
+func (t *ReadMoreTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+	"type": "object",
+	"properties": {
+		"id": {
+			"type": "string",
+			"description": "The id from the truncation note, e.g. r3.2 for page 2 of result r3"
+		}
+	},
+	"required": ["id"]
+}`)
+}
+
+func (t *ReadMoreTool) Execute(_ context.Context, params json.RawMessage) (string, error) {
+	var p readMoreParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return fmt.Sprintf("Error: invalid parameters: %v", err), nil
+	}
+	id, n := strings.TrimSpace(p.ID), 2
+	if base, page, ok := strings.Cut(id, "."); ok {
+		var err error
+		if n, err = strconv.Atoi(page); err != nil || n < 1 {
+			return fmt.Sprintf("Error: invalid id %q (want the id from the truncation note, e.g. r3.2)", p.ID), nil
+		}
+		id = base
+	}
+	if id == "" {
+		return "Error: id is required", nil
+	}
+	page, err := t.Pages.page(id, n)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+	return page, nil
+}
@@ -0,0 +1,148 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gavinyap/stormtrooper/internal/llm"
+	"github.com/gavinyap/stormtrooper/internal/permission"
+	"github.com/gavinyap/stormtrooper/internal/tool"
+)
+
+// numberedLines returns n lines, each naming its number.
+func numberedLines(n int) string {
+	var b strings.Builder
+	for i := 1; i <= n; i++ {
+		fmt.Fprintf(&b, "line %03d\n", i)
+	}
+	return b.String()
+}
+
+func TestPagesPaginate(t *testing.T) {
+	pages := NewPages(100)
+	if got := pages.Paginate("short"); got != "short" {
+		t.Errorf("short result changed: %q", got)
+	}
+
+	result := numberedLines(25) // 225 bytes, 9 per line
+	first := pages.Paginate(result)
+	if !strings.HasPrefix(first, "line 001\n") || !strings.HasSuffix(first, "[truncated, call read_more with id=r1.2 for the next page (showing bytes 1-99 of 225)]") {
+		t.Errorf("first page:\n%s", first)
+	}
+	// Pages end on a line break.
+	if strings.Contains(first, "line 012") {
+		t.Errorf("first page runs past its size:\n%s", first)
+	}
+
+	rm := &ReadMoreTool{Pages: pages}
+	var got strings.Builder
+	got.WriteString(strings.Split(first, "\n\n[")[0])
+	for _, id := range []string{"r1.2", "r1.3"} {
+		page, _ := rm.Execute(context.Background(), json.RawMessage(`{"id":"`+id+`"}`))
+		got.WriteString(strings.Split(page, "\n\n[")[0])
+		if id == "r1.3" && !strings.HasSuffix(page, "[end of result r1: page 3, bytes 199-225 of 225]") {
+			t.Errorf("last page:\n%s", page)
+		}
+	}
+	if got.String() != result {
+		t.Errorf("pages joined:\n%s\nwant:\n%s", got.String(), result)
+	}
+}
+
+func TestReadMoreInvalid(t *testing.T) {
+	pages := NewPages(10)
+	pages.Paginate(numberedLines(3))
+	rm := &ReadMoreTool{Pages: pages}
+	tests := []struct {
+		params string
+		want   string
+	}{
+		{`{invalid`, "Error: invalid parameters"},
+		{`{"id":""}`, "Error: id is required"},
+		{`{"id":"r1.x"}`, "Error: invalid id"},
+		{`{"id":"r9.2"}`, "Error: no stored result r9"},
+		{`{"id":"r1.9"}`, "Error: result r1 has only 3 page(s)"},
+	}
+	for _, tt := range tests {
+		got, _ := rm.Execute(context.Background(), json.RawMessage(tt.params))
+		if !strings.HasPrefix(got, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.params, got, tt.want)
+		}
+	}
+
+	// A bare result ID reads its second page.
+	if got, _ := rm.Execute(context.Background(), json.RawMessage(`{"id":"r1"}`)); !strings.HasPrefix(got, "line 002") {
+		t.Errorf("bare id: %q", got)
+	}
+}
+
+func TestPagesEvictsOldest(t *testing.T) {
+	pages := NewPages(10)
+	for i := 0; i <= maxPagedResults; i++ {
+		pages.Paginate(numberedLines(3))
+	}
+	if _, err := pages.page("r1", 1); err == nil {
+		t.Error("the oldest result should have been dropped")
+	}
+	if _, err := pages.page(fmt.Sprintf("r%d", maxPagedResults+1), 1); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAgentPaginatesToolResults(t *testing.T) {
+	turns := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		turns++
+		w.Header().Set("Content-Type", "text/event-stream")
+		switch turns {
+		case 1:
+			w.Write([]byte(sseToolCallResponse("call_1", "build", `{}`)))
+		case 2:
+			w.Write([]byte(sseToolCallResponse("call_2", "read_more", `{"id":"r1.2"}`)))
+		default:
+			w.Write([]byte(sseTextResponse("done")))
+		}
+	}))
+	defer server.Close()
+	client := llm.NewClient("test-key")
+	client.SetBaseURL(server.URL)
+
+	pages := NewPages(DefaultPageSize)
+	output := numberedLines(8000)
+	reg := tool.NewRegistry()
+	reg.Register(&mockTool{name: "build", perm: tool.PermissionAuto, result: output})
+	reg.Register(&ReadMoreTool{Pages: pages})
+	ag := New(Options{
+		Client:     client,
+		Registry:   reg,
+		Permission: permission.AllowAll{},
+		Model:      "main",
+		Pages:      pages,
+	})
+	ag.SetOutput(&bytes.Buffer{}, &bytes.Buffer{})
+	if err := ag.Send(context.Background(), "build it"); err != nil {
+		t.Fatal(err)
+	}
+
+	var results []string
+	for _, m := range ag.History() {
+		if m.Role == "tool" {
+			results = append(results, m.Content)
+		}
+	}
+	if len(results) != 2 {
+		t.Fatalf("got %d tool results", len(results))
+	}
+	if len(results[0]) > DefaultPageSize+200 || !strings.Contains(results[0], "call read_more with id=r1.2") {
+		t.Errorf("build result is %d bytes, ending %q", len(results[0]), results[0][len(results[0])-120:])
+	}
+	if !strings.HasPrefix(results[1], "line 3") || !strings.Contains(results[1], "id=r1.3") {
+		t.Errorf("read_more result starts %q", results[1][:40])
+	}
+}
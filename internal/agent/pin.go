@@ -0,0 +1,140 @@
+package agent
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/gavinyap/stormtrooper/internal/llm"
+	"github.com/gavinyap/stormtrooper/internal/tool"
+)
+
+// Limits on the pinned files sent with each request.
+const (
+	maxPinnedFile  = 32 * 1024
+	maxPinnedFiles = 128 * 1024
+)
+
+// Pins are files whose current contents accompany every model request,
+// so the model does not lose track of them as the conversation grows.
+// Pins are safe for concurrent use.
+type Pins struct {
+	mu    sync.Mutex
+	fs    tool.FileSystem
+	paths []string
+}
+
+// NewPins returns an empty set of pins read from fsys; nil means the host.
+func NewPins(fsys tool.FileSystem) *Pins {
+	if fsys == nil {
+		fsys = tool.LocalFS{}
+	}
+	return &Pins{fs: fsys}
+}
+
+// Pin adds path. The file must exist and be a regular file.
+func (p *Pins) Pin(path string) error {
+	path = filepath.Clean(path)
+	info, err := p.fs.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return fmt.Errorf("%s is a directory", path)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, existing := range p.paths {
+		if existing == path {
+			return nil
+		}
+	}
+	p.paths = append(p.paths, path)
+	return nil
+}
+
+// Unpin removes path and reports whether it was pinned.
+func (p *Pins) Unpin(path string) bool {
+	path = filepath.Clean(path)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, existing := range p.paths {
+		if existing == path {
+			p.paths = append(p.paths[:i:i], p.paths[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Clear removes every pin and returns the paths that were pinned.
+func (p *Pins) Clear() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	paths := p.paths
+	p.paths = nil
+	return paths
+}
+
+// List returns the pinned paths in the order they were pinned.
+func (p *Pins) List() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.paths...)
+}
+
+// pinsPreamble starts the message that carries the pinned files.
+const pinsPreamble = "The user pinned these files. Their current contents follow and are refreshed before every request, so prefer them over older read_file results.\n"
+
+// message reads every pinned file now, so edits made since the last
+// request are picked up, and returns them as a system message. ok is
+// false when nothing is pinned.
+func (p *Pins) message() (llm.Message, bool) {
+	paths := p.List()
+	if len(paths) == 0 {
+		return llm.Message{}, false
+	}
+	var b strings.Builder
+	b.WriteString(pinsPreamble)
+	budget := maxPinnedFiles
+	for _, path := range paths {
+		data, err := p.fs.ReadFile(path)
+		switch {
+		case err != nil:
+			fmt.Fprintf(&b, "\n## %s\n(could not read: %v)\n", path, err)
+			continue
+		case budget <= 0:
+			fmt.Fprintf(&b, "\n## %s\n(not included: the pinned files are too large; use read_file)\n", path)
+			continue
+		}
+		content, note := string(data), ""
+		if limit := min(maxPinnedFile, budget); len(content) > limit {
+			content, note = content[:limit], "\n[truncated; use read_file for the rest]"
+		}
+		budget -= len(content)
+		fmt.Fprintf(&b, "\n## %s\n```\n%s\n```%s\n", path, strings.TrimRight(content, "\n"), note)
+	}
+	return llm.Message{Role: "system", Content: b.String()}, true
+}
+
+// withPins returns history with the pinned files inserted after the
+// system prompt. history itself is not modified, so the files never
+// pile up in the conversation.
+func (p *Pins) withPins(history []llm.Message) []llm.Message {
+	if p == nil {
+		return history
+	}
+	msg, ok := p.message()
+	if !ok {
+		return history
+	}
+	at := 0
+	if len(history) > 0 && history[0].Role == "system" {
+		at = 1
+	}
+	out := make([]llm.Message, 0, len(history)+1)
+	out = append(out, history[:at]...)
+	out = append(out, msg)
+	return append(out, history[at:]...)
+}
@@ -0,0 +1,96 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gavinyap/stormtrooper/internal/llm"
+	"github.com/gavinyap/stormtrooper/internal/permission"
+	"github.com/gavinyap/stormtrooper/internal/tool"
+)
+
+func TestPins_PinAndUnpin(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.go"), filepath.Join(dir, "b.go")
+	os.WriteFile(a, []byte("a"), 0644)
+	os.WriteFile(b, []byte("b"), 0644)
+
+	p := NewPins(nil)
+	for _, path := range []string{a, b, a} {
+		if err := p.Pin(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := p.List(); !reflect.DeepEqual(got, []string{a, b}) {
+		t.Errorf("List = %q", got)
+	}
+	if err := p.Pin(filepath.Join(dir, "missing.go")); err == nil {
+		t.Error("pinning a missing file should fail")
+	}
+	if err := p.Pin(dir); err == nil {
+		t.Error("pinning a directory should fail")
+	}
+	if !p.Unpin(a) || p.Unpin(a) {
+		t.Error("Unpin should report whether the file was pinned")
+	}
+	if got := p.Clear(); !reflect.DeepEqual(got, []string{b}) || len(p.List()) != 0 {
+		t.Errorf("Clear = %q", got)
+	}
+}
+
+func TestAgent_PinnedFilesRefreshedEachRequest(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "notes.md")
+	os.WriteFile(path, []byte("version one"), 0644)
+
+	var requests []llm.ChatCompletionRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req llm.ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		requests = append(requests, req)
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(sseTextResponse("ok")))
+	}))
+	defer server.Close()
+	client := llm.NewClient("test-key")
+	client.SetBaseURL(server.URL)
+
+	pins := NewPins(nil)
+	pins.Pin(path)
+	ag := New(Options{
+		Client:       client,
+		Registry:     tool.NewRegistry(),
+		Permission:   permission.AllowAll{},
+		Model:        "test-model",
+		SystemPrompt: "system",
+		Pins:         pins,
+	})
+	ag.SetOutput(&bytes.Buffer{}, &bytes.Buffer{})
+
+	ag.Send(context.Background(), "first")
+	os.WriteFile(path, []byte("version two"), 0644)
+	ag.Send(context.Background(), "second")
+
+	if len(requests) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(requests))
+	}
+	for i, want := range []string{"version one", "version two"} {
+		msgs := requests[i].Messages
+		if msgs[0].Content != "system" || msgs[1].Role != "system" || !strings.Contains(msgs[1].Content, want) {
+			t.Errorf("request %d: expected %q pinned after the system prompt, got %+v", i, want, msgs[:2])
+		}
+	}
+	for _, m := range ag.History() {
+		if strings.Contains(m.Content, "version") {
+			t.Error("pinned contents should not be stored in the history")
+		}
+	}
+}
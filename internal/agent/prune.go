@@ -0,0 +1,82 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gavinyap/stormtrooper/internal/llm"
+)
+
+// PruneOptions controls how old tool results are shortened before each
+// request. Tool results dominate a long conversation's tokens but are
+// rarely needed again once the model has acted on them.
+type PruneOptions struct {
+	// AfterTurns is how many of the most recent turns keep their tool
+	// results in full; 0 disables pruning.
+	AfterTurns int
+	// MinBytes is the size below which a result is never pruned.
+	MinBytes int
+}
+
+// What a pruned result keeps as a hint of its contents: its first lines,
+// each cut to a length.
+const (
+	prunedLines    = 5
+	prunedLineSize = 200
+)
+
+// prune returns history with the tool results from before the last
+// opts.AfterTurns turns replaced by a short summary. User and assistant
+// messages are never changed, and history itself is not modified, so
+// saved sessions and /rewind still see the full results.
+func prune(history []llm.Message, opts PruneOptions) []llm.Message {
+	if opts.AfterTurns <= 0 {
+		return history
+	}
+
+	// Find where the kept turns start.
+	cut, turns := 0, 0
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].Role == "user" {
+			turns++
+			if turns == opts.AfterTurns {
+				cut = i
+				break
+			}
+		}
+	}
+	if turns < opts.AfterTurns {
+		return history
+	}
+
+	var out []llm.Message
+	for i := 0; i < cut; i++ {
+		m := history[i]
+		if m.Role != "tool" || len(m.Content) <= opts.MinBytes {
+			continue
+		}
+		if out == nil {
+			out = append([]llm.Message(nil), history...)
+		}
+		out[i].Content = summarizeResult(m.Name, m.Content)
+	}
+	if out == nil {
+		return history
+	}
+	return out
+}
+
+// summarizeResult is what remains of a pruned tool result.
+func summarizeResult(name, content string) string {
+	lines := strings.SplitN(content, "\n", prunedLines+1)
+	if len(lines) > prunedLines {
+		lines = lines[:prunedLines]
+	}
+	for i, line := range lines {
+		if len(line) > prunedLineSize {
+			lines[i] = line[:prunedLineSize] + "..."
+		}
+	}
+	return fmt.Sprintf("[Pruned to save context: this %s result from an earlier turn was %d bytes. It began:\n%s\nCall the tool again if you need it.]",
+		name, len(content), strings.Join(lines, "\n"))
+}
@@ -0,0 +1,55 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gavinyap/stormtrooper/internal/llm"
+)
+
+func TestPrune(t *testing.T) {
+	big := strings.Repeat("line\n", 1000)
+	history := []llm.Message{
+		{Role: "system", Content: "system"},
+		{Role: "user", Content: "turn 1"},
+		{Role: "assistant", ToolCalls: []llm.ToolCall{{ID: "1"}}},
+		{Role: "tool", Name: "read_file", Content: big},
+		{Role: "tool", Name: "glob", Content: "a.go"},
+		{Role: "assistant", Content: strings.Repeat("answer ", 1000)},
+		{Role: "user", Content: "turn 2"},
+		{Role: "tool", Name: "read_file", Content: big},
+		{Role: "user", Content: "turn 3"},
+	}
+
+	got := prune(history, PruneOptions{AfterTurns: 2, MinBytes: 100})
+	if !strings.HasPrefix(got[3].Content, "[Pruned to save context: this read_file result") || !strings.Contains(got[3].Content, "5000 bytes") {
+		t.Errorf("old large result should be pruned, got %q", got[3].Content[:80])
+	}
+	if got[4].Content != "a.go" {
+		t.Error("small results should be kept")
+	}
+	if got[5].Content != history[5].Content {
+		t.Error("assistant messages should never be pruned")
+	}
+	if got[7].Content != big {
+		t.Error("results from the kept turns should be left alone")
+	}
+	if history[3].Content != big {
+		t.Error("prune must not modify the history")
+	}
+
+	if got := prune(history, PruneOptions{AfterTurns: 3, MinBytes: 100}); got[3].Content != big {
+		t.Error("nothing should be pruned while the conversation is within AfterTurns")
+	}
+	if got := prune(history, PruneOptions{}); got[3].Content != big {
+		t.Error("AfterTurns 0 should disable pruning")
+	}
+}
+
+func TestSummarizeResult(t *testing.T) {
+	content := strings.Repeat("x", 300) + "\n2\n3\n4\n5\n6\n7"
+	got := summarizeResult("grep", content)
+	if !strings.Contains(got, strings.Repeat("x", 200)+"...") || strings.Contains(got, "\n6\n") {
+		t.Errorf("expected the first 5 lines, cut to 200 bytes:\n%s", got)
+	}
+}
@@ -0,0 +1,109 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gavinyap/stormtrooper/internal/llm"
+)
+
+// RepeatGuardOptions controls the guard that stops a response in which
+// the model repeats the same sentence or tokens over and over, as some
+// open models do, and asks again with a frequency penalty.
+type RepeatGuardOptions struct {
+	// MinRepeats is how many back-to-back copies of a pattern stop the
+	// response; 0 means DefaultMinRepeats and negative turns the guard
+	// off.
+	MinRepeats int
+	// Penalty is the frequency_penalty of the second attempt; 0 means
+	// DefaultRepeatPenalty.
+	Penalty float64
+}
+
+// Defaults of the repetition guard.
+const (
+	DefaultMinRepeats    = 8
+	DefaultRepeatPenalty = 0.5
+)
+
+// Limits of repetition detection: the streamed text kept for it, the
+// longest pattern looked for, and how much text the repeats must cover,
+// so short runs such as a line of dashes do not count.
+const (
+	repeatWindow    = 4096
+	repeatMaxPeriod = 512
+	repeatMinSpan   = 400
+)
+
+// errRepeating is returned by streamOnce when it stopped a response that
+// was repeating itself.
+var errRepeating = errors.New("the model kept repeating itself")
+
+func (o RepeatGuardOptions) minRepeats() int {
+	if o.MinRepeats == 0 {
+		return DefaultMinRepeats
+	}
+	return o.MinRepeats
+}
+
+func (o RepeatGuardOptions) penalty() float64 {
+	if o.Penalty == 0 {
+		return DefaultRepeatPenalty
+	}
+	return o.Penalty
+}
+
+// stream sends req and streams the response to stdout. If the response
+// starts repeating itself, it is stopped and asked for once more with a
+// frequency penalty; if that one repeats too, the turn fails.
+func (a *Agent) stream(ctx context.Context, req llm.ChatCompletionRequest) (*llm.Message, string, error) {
+	msg, finish, err := a.streamOnce(ctx, req)
+	if !errors.Is(err, errRepeating) {
+		return msg, finish, err
+	}
+	a.emit(TokenDelta{Content: "\n"})
+	req.FrequencyPenalty = a.repeat.penalty()
+	a.warn("The model was repeating itself; stopped the response and asking again with frequency_penalty %.2g", req.FrequencyPenalty)
+	msg, finish, err = a.streamOnce(ctx, req)
+	if errors.Is(err, errRepeating) {
+		a.emit(TokenDelta{Content: "\n"})
+		return nil, "", fmt.Errorf("LLM request failed: %w; try again or switch models", err)
+	}
+	return msg, finish, err
+}
+
+// repeatDetector watches streamed text for a pattern repeated back to
+// back.
+type repeatDetector struct {
+	minRepeats int
+	tail       []byte
+}
+
+// add appends streamed text and reports whether it now ends in at least
+// minRepeats copies of one pattern.
+func (d *repeatDetector) add(s string) bool {
+	d.tail = append(d.tail, s...)
+	if over := len(d.tail) - repeatWindow; over > 0 {
+		d.tail = d.tail[:copy(d.tail, d.tail[over:])]
+	}
+	return repeating(d.tail, d.minRepeats)
+}
+
+// repeating reports whether b ends in at least minRepeats copies of a
+// pattern of up to repeatMaxPeriod bytes, together covering at least
+// repeatMinSpan bytes.
+func repeating(b []byte, minRepeats int) bool {
+	n := len(b)
+	for p := 1; p <= repeatMaxPeriod && p*minRepeats <= n; p++ {
+		// Walk back while the text keeps repeating with period p.
+		i := n - p - 1
+		for i >= 0 && b[i] == b[i+p] {
+			i--
+		}
+		if span := n - i - 1; span >= p*minRepeats && span >= repeatMinSpan {
+			return true
+		}
+	}
+	return false
+}
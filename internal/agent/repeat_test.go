@@ -0,0 +1,151 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gavinyap/stormtrooper/internal/llm"
+	"github.com/gavinyap/stormtrooper/internal/tool"
+)
+
+func TestRepeating(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want bool
+	}{
+		{"sentence loop", "Here is the plan. " + strings.Repeat("I will now check the file. ", 20), true},
+		{"token loop", strings.Repeat("foo ", 120), true},
+		{"too few repeats", strings.Repeat("I will now check the file and then report back. ", 7), false},
+		{"short run", strings.Repeat("-", 80), false},
+		{"prose", strings.Repeat("The quick brown fox jumps over the lazy dog while ", 1) + "the cat watches from a distance, unimpressed by it all.", false},
+	}
+	for _, tt := range tests {
+		if got := repeating([]byte(tt.text), DefaultMinRepeats); got != tt.want {
+			t.Errorf("%s: repeating = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRepeatDetector_Window(t *testing.T) {
+	d := &repeatDetector{minRepeats: DefaultMinRepeats}
+	for i := 0; i < 500; i++ {
+		if d.add(fmt.Sprintf("line %d is different\n", i)) {
+			t.Fatalf("distinct lines detected as repeating at line %d", i)
+		}
+	}
+	if len(d.tail) > repeatWindow {
+		t.Errorf("tail grew to %d bytes", len(d.tail))
+	}
+}
+
+// sseChunks streams each part as its own content chunk.
+func sseChunks(parts []string) string {
+	var b strings.Builder
+	for _, p := range parts {
+		fmt.Fprintf(&b, "data: {\"id\":\"1\",\"choices\":[{\"index\":0,\"delta\":{\"role\":\"assistant\",\"content\":%s},\"finish_reason\":null}]}\n\n", jsonStr(p))
+	}
+	b.WriteString("data: {\"id\":\"1\",\"choices\":[{\"index\":0,\"delta\":{},\"finish_reason\":\"stop\"}]}\n\ndata: [DONE]\n")
+	return b.String()
+}
+
+// loopingServer streams a response stuck in a loop to requests without a
+// frequency penalty, or to all of them if always is set, and a normal
+// answer otherwise.
+func loopingServer(t *testing.T, always bool, requests *[]llm.ChatCompletionRequest) *llm.Client {
+	t.Helper()
+	loop := make([]string, 200)
+	for i := range loop {
+		loop[i] = "I will check the file again. "
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req llm.ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		*requests = append(*requests, req)
+		w.Header().Set("Content-Type", "text/event-stream")
+		if always || req.FrequencyPenalty == 0 {
+			w.Write([]byte(sseChunks(loop)))
+			return
+		}
+		w.Write([]byte(sseTextResponse("The file is fine.")))
+	}))
+	t.Cleanup(server.Close)
+	client := llm.NewClient("test-key")
+	client.SetBaseURL(server.URL)
+	return client
+}
+
+func TestAgent_RepeatGuard(t *testing.T) {
+	var requests []llm.ChatCompletionRequest
+	ag := New(Options{
+		Client:   loopingServer(t, false, &requests),
+		Registry: tool.NewRegistry(),
+		Model:    "test-model",
+		Stop:     []string{"<|end|>"},
+	})
+	var stdout, stderr bytes.Buffer
+	ag.SetOutput(&stdout, &stderr)
+
+	if err := ag.Send(context.Background(), "Check the file"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(requests) != 2 {
+		t.Fatalf("requests = %d, want 2", len(requests))
+	}
+	if requests[1].FrequencyPenalty != DefaultRepeatPenalty {
+		t.Errorf("retry frequency_penalty = %v, want %v", requests[1].FrequencyPenalty, DefaultRepeatPenalty)
+	}
+	if strings.Join(requests[0].Stop, ",") != "<|end|>" {
+		t.Errorf("stop = %q, want the configured sequences", requests[0].Stop)
+	}
+	if n := strings.Count(stdout.String(), "I will check the file again."); n > 2*DefaultMinRepeats {
+		t.Errorf("%d repeats reached the output before the guard stopped it", n)
+	}
+	if !strings.Contains(stderr.String(), "[warning] The model was repeating itself") {
+		t.Errorf("stderr = %q, want a warning", stderr.String())
+	}
+	h := ag.History()
+	if last := h[len(h)-1]; last.Content != "The file is fine." {
+		t.Errorf("last message = %q, want the second attempt", last.Content)
+	}
+}
+
+func TestAgent_RepeatGuardGivesUp(t *testing.T) {
+	var requests []llm.ChatCompletionRequest
+	ag := New(Options{Client: loopingServer(t, true, &requests), Registry: tool.NewRegistry(), Model: "test-model"})
+	var stdout, stderr bytes.Buffer
+	ag.SetOutput(&stdout, &stderr)
+
+	err := ag.Send(context.Background(), "Check the file")
+	if err == nil || !strings.Contains(err.Error(), "repeating itself") {
+		t.Fatalf("err = %v, want a repetition error", err)
+	}
+	if len(requests) != 2 {
+		t.Errorf("requests = %d, want 2", len(requests))
+	}
+}
+
+func TestAgent_RepeatGuardOff(t *testing.T) {
+	var requests []llm.ChatCompletionRequest
+	ag := New(Options{
+		Client:      loopingServer(t, true, &requests),
+		Registry:    tool.NewRegistry(),
+		Model:       "test-model",
+		RepeatGuard: RepeatGuardOptions{MinRepeats: -1},
+	})
+	var stdout, stderr bytes.Buffer
+	ag.SetOutput(&stdout, &stderr)
+
+	if err := ag.Send(context.Background(), "Check the file"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(requests) != 1 || strings.Count(stdout.String(), "I will check the file again.") != 200 {
+		t.Errorf("requests = %d; the whole response should pass with the guard off", len(requests))
+	}
+}
@@ -0,0 +1,71 @@
+package agent
+
+import (
+	"regexp"
+	"strings"
+)
+
+// RouteOptions sends simple turns, such as a short question about a
+// concept, to a cheaper or faster model, keeping the agent's model for
+// turns that read and change code.
+type RouteOptions struct {
+	// Model answers simple turns; empty turns routing off.
+	Model string
+	// MaxChars is the longest prompt that can count as simple; 0 means
+	// DefaultRouteMaxChars.
+	MaxChars int
+}
+
+// DefaultRouteMaxChars is the longest simple prompt by default.
+const DefaultRouteMaxChars = 300
+
+// codingWords mark a prompt that likely needs tools: it asks for code to
+// be read, changed, or run.
+var codingWords = map[string]bool{
+	"fix": true, "implement": true, "refactor": true, "write": true, "create": true,
+	"edit": true, "add": true, "change": true, "update": true, "delete": true,
+	"remove": true, "rename": true, "move": true, "run": true, "test": true,
+	"tests": true, "build": true, "debug": true, "install": true, "commit": true,
+	"deploy": true, "migrate": true, "generate": true, "review": true, "read": true,
+	"open": true, "look": true, "check": true, "find": true, "search": true,
+	"grep": true, "list": true, "show": true, "replace": true, "file": true,
+	"files": true, "repo": true, "repository": true, "codebase": true, "project": true,
+}
+
+// pathPattern matches file paths and names, which point at code in the
+// working tree.
+var pathPattern = regexp.MustCompile(`\S+/\S+|\b[\w-]+\.(go|py|js|ts|tsx|jsx|rs|java|kt|c|h|cc|cpp|rb|php|sh|md|json|ya?ml|toml|sql|html|css|mod)\b`)
+
+// routeModel returns the model that should answer prompt: the route model
+// if prompt looks like simple Q&A, otherwise "" for the agent's own.
+func (o RouteOptions) routeModel(prompt string) string {
+	if o.Model == "" || !simpleTurn(prompt, o.MaxChars) {
+		return ""
+	}
+	return o.Model
+}
+
+// simpleTurn reports whether prompt looks like a question that can be
+// answered without tools: it is short, has no code or paths, and does
+// not ask for anything to be read, changed, or run. It errs toward the
+// agent's own model.
+func simpleTurn(prompt string, maxChars int) bool {
+	if maxChars <= 0 {
+		maxChars = DefaultRouteMaxChars
+	}
+	prompt = strings.TrimSpace(prompt)
+	if prompt == "" || len(prompt) > maxChars {
+		return false
+	}
+	if strings.Contains(prompt, "`") || strings.Count(prompt, "\n") > 2 || pathPattern.MatchString(prompt) {
+		return false
+	}
+	for _, w := range strings.FieldsFunc(strings.ToLower(prompt), func(r rune) bool {
+		return !('a' <= r && r <= 'z' || r == '\'')
+	}) {
+		if codingWords[w] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,128 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gavinyap/stormtrooper/internal/llm"
+	"github.com/gavinyap/stormtrooper/internal/tool"
+)
+
+func TestSimpleTurn(t *testing.T) {
+	tests := []struct {
+		prompt string
+		want   bool
+	}{
+		{"What is a goroutine?", true},
+		{"thanks, that makes sense", true},
+		{"How do channels differ from mutexes in Go?", true},
+		{"", false},
+		{"Fix the failing test", false},
+		{"Why does internal/agent/agent.go lock mu?", false},
+		{"what does main.go do", false},
+		{"Explain `defer` ordering", false},
+		{"Can you look at the config loader?", false},
+		{strings.Repeat("why ", 100), false},
+	}
+	for _, tt := range tests {
+		if got := simpleTurn(tt.prompt, 0); got != tt.want {
+			t.Errorf("simpleTurn(%q) = %v, want %v", tt.prompt, got, tt.want)
+		}
+	}
+	if simpleTurn("What is a goroutine?", 10) {
+		t.Error("a prompt longer than MaxChars should not be simple")
+	}
+}
+
+// routeServer answers with a tool call for prompts containing "tool"
+// on the first step, text otherwise, and records the model of each
+// request.
+func routeServer(t *testing.T, models *[]string) *llm.Client {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req llm.ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		for _, m := range req.Messages {
+			if m.Model != "" {
+				t.Errorf("message sent with model annotation %q", m.Model)
+			}
+		}
+		*models = append(*models, req.Model)
+		w.Header().Set("Content-Type", "text/event-stream")
+		last := req.Messages[len(req.Messages)-1]
+		if last.Role == "user" && strings.Contains(last.Content, "tool") {
+			w.Write([]byte(sseToolCallResponse("call_1", "test_tool", `{}`)))
+			return
+		}
+		w.Write([]byte(sseTextResponse("answer")))
+	}))
+	t.Cleanup(server.Close)
+	client := llm.NewClient("test-key")
+	client.SetBaseURL(server.URL)
+	return client
+}
+
+func TestAgent_Route(t *testing.T) {
+	var models []string
+	reg := tool.NewRegistry()
+	reg.Register(&mockTool{name: "test_tool", perm: tool.PermissionAuto, result: "ok"})
+	ag := New(Options{
+		Client:   routeServer(t, &models),
+		Registry: reg,
+		Model:    "main",
+		Route:    RouteOptions{Model: "cheap"},
+	})
+	var stdout, stderr bytes.Buffer
+	ag.SetOutput(&stdout, &stderr)
+
+	if err := ag.Send(context.Background(), "What is a goroutine?"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ag.Send(context.Background(), "Refactor the parser"); err != nil {
+		t.Fatal(err)
+	}
+	// Misjudged: the route model calls a tool, and the agent's model
+	// carries on with the result.
+	if err := ag.Send(context.Background(), "what does the tool say?"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"cheap", "main", "cheap", "main"}
+	if strings.Join(models, ",") != strings.Join(want, ",") {
+		t.Errorf("request models = %v, want %v", models, want)
+	}
+	var annotated []string
+	for _, m := range ag.History() {
+		if m.Role == "assistant" {
+			annotated = append(annotated, m.Model)
+		}
+	}
+	if strings.Join(annotated, ",") != strings.Join(want, ",") {
+		t.Errorf("assistant message models = %v, want %v", annotated, want)
+	}
+	if !strings.Contains(stderr.String(), "[model] cheap\n") || !strings.Contains(stderr.String(), "[model] main\n") {
+		t.Errorf("stderr = %q, want [model] lines", stderr.String())
+	}
+}
+
+func TestAgent_NoRouteByDefault(t *testing.T) {
+	var models []string
+	ag := New(Options{Client: routeServer(t, &models), Registry: tool.NewRegistry(), Model: "main"})
+	var stdout, stderr bytes.Buffer
+	ag.SetOutput(&stdout, &stderr)
+
+	if err := ag.Send(context.Background(), "What is a goroutine?"); err != nil {
+		t.Fatal(err)
+	}
+	if len(models) != 1 || models[0] != "main" {
+		t.Errorf("request models = %v, want [main]", models)
+	}
+	if h := ag.History(); h[len(h)-1].Model != "" || strings.Contains(stderr.String(), "[model]") {
+		t.Errorf("unrouted turn annotated: %+v, stderr %q", h[len(h)-1], stderr.String())
+	}
+}
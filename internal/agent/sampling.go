@@ -0,0 +1,55 @@
+package agent
+
+import "github.com/gavinyap/stormtrooper/internal/config"
+
+// Sampling holds the sampling parameters of a model request. Unset ones
+// are left to the provider.
+type Sampling struct {
+	Temperature *float64
+	TopP        *float64
+	MaxTokens   int
+	Stop        []string
+}
+
+// ConfigSampling returns the sampling overrides configured by model.
+func ConfigSampling(cfg *config.Config) map[string]Sampling {
+	sampling := make(map[string]Sampling, len(cfg.Models))
+	for model, m := range cfg.Models {
+		sampling[model] = Sampling{Temperature: m.Temperature, TopP: m.TopP, MaxTokens: m.MaxTokens, Stop: m.Stop}
+	}
+	return sampling
+}
+
+// override returns s with the parameters set in o in place of its own.
+func (s Sampling) override(o Sampling) Sampling {
+	if o.Temperature != nil {
+		s.Temperature = o.Temperature
+	}
+	if o.TopP != nil {
+		s.TopP = o.TopP
+	}
+	if o.MaxTokens != 0 {
+		s.MaxTokens = o.MaxTokens
+	}
+	if len(o.Stop) > 0 {
+		s.Stop = o.Stop
+	}
+	return s
+}
+
+// SetSampling changes the temperature and top_p sent with subsequent
+// requests, nil leaving them to the provider, and the overrides for
+// requests to particular models, keyed by model name.
+func (a *Agent) SetSampling(temperature, topP *float64, byModel map[string]Sampling) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.temperature, a.topP, a.models = temperature, topP, byModel
+}
+
+// sampling returns the sampling parameters for a request to model.
+func (a *Agent) sampling(model string) Sampling {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	s := Sampling{Temperature: a.temperature, TopP: a.topP, MaxTokens: a.maxTokens, Stop: a.stop}
+	return s.override(a.models[model])
+}
@@ -0,0 +1,59 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gavinyap/stormtrooper/internal/llm"
+	"github.com/gavinyap/stormtrooper/internal/tool"
+)
+
+func TestAgent_Sampling(t *testing.T) {
+	var requests []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]any
+		json.NewDecoder(r.Body).Decode(&req)
+		requests = append(requests, req)
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(sseTextResponse("ok")))
+	}))
+	defer server.Close()
+	client := llm.NewClient("test-key")
+	client.SetBaseURL(server.URL)
+
+	zero, one, half := 0.0, 1.0, 0.5
+	ag := New(Options{
+		Client:        client,
+		Registry:      tool.NewRegistry(),
+		Model:         "chat-model",
+		MaxTokens:     512,
+		Stop:          []string{"</answer>"},
+		Temperature:   &zero,
+		ModelSampling: map[string]Sampling{"reasoning-model": {Temperature: &one, MaxTokens: 8000}},
+	})
+	ag.SetOutput(&bytes.Buffer{}, &bytes.Buffer{})
+
+	ag.Send(context.Background(), "Hi")
+	ag.SetModel("reasoning-model")
+	ag.Send(context.Background(), "Think")
+	ag.SetSampling(nil, &half, nil)
+	ag.Send(context.Background(), "Again")
+
+	if len(requests) != 3 {
+		t.Fatalf("got %d requests", len(requests))
+	}
+	// A temperature of 0 is sent rather than left out.
+	if got := requests[0]; got["temperature"] != 0.0 || got["max_tokens"] != 512.0 || got["top_p"] != nil {
+		t.Errorf("first request = %v", got)
+	}
+	if got := requests[1]; got["temperature"] != 1.0 || got["max_tokens"] != 8000.0 || got["stop"] == nil {
+		t.Errorf("the override should replace only the parameters it sets, got %v", got)
+	}
+	if got := requests[2]; got["temperature"] != nil || got["top_p"] != 0.5 || got["max_tokens"] != 512.0 {
+		t.Errorf("after SetSampling = %v", got)
+	}
+}
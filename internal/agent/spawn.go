@@ -6,19 +6,38 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/gavinyap/stormtrooper/internal/llm"
 	"github.com/gavinyap/stormtrooper/internal/permission"
 	"github.com/gavinyap/stormtrooper/internal/tool"
 )
 
-// SpawnAgentTool creates and runs a sub-agent with a focused task.
+// SpawnAgentTool creates and runs a sub-agent with a focused task. By
+// default it blocks until the sub-agent finishes; a background sub-agent
+// is instead steered through the message_agent and agent_status tools
+// returned by Tools.
 type SpawnAgentTool struct {
 	Client   *llm.Client
 	Registry *tool.Registry
 	Perm     permission.Handler
 	Model    string // parent's model as default
+	// Rules, if set, are the project's remembered approvals, shared
+	// with the sub-agents.
+	Rules *permission.Rules
+	// Summary controls when a long result is condensed before it is
+	// returned to the parent.
+	Summary SubagentSummaryOptions
+	// Pages, if set, is the session's cache of long tool results,
+	// shared with the sub-agents.
+	Pages *Pages
+
+	mu         sync.Mutex
+	background map[string]*backgroundAgent
+	nextID     int
 }
 
 // NewSpawnAgentTool creates a spawn_agent tool with the given shared resources.
@@ -32,8 +51,9 @@ func NewSpawnAgentTool(client *llm.Client, registry *tool.Registry, perm permiss
 }
 
 type spawnAgentParams struct {
-	Task  string `json:"task"`
-	Model string `json:"model"`
+	Task       string `json:"task"`
+	Model      string `json:"model"`
+	Background bool   `json:"background"`
 }
 
 func (t *SpawnAgentTool) Name() string        { return "spawn_agent" }
@@ -51,6 +71,10 @@ func (t *SpawnAgentTool) Schema() json.RawMessage {
 		"model": {
 			"type": "string",
 			"description": "Model to use for the sub-agent (optional, defaults to parent's model)"
+		},
+		"background": {
+			"type": "boolean",
+			"description": "Return at once with an agent ID instead of waiting; check on the sub-agent with agent_status and steer it with message_agent"
 		}
 	},
 	"required": ["task"]
@@ -88,10 +112,15 @@ func (t *SpawnAgentTool) Execute(ctx context.Context, params json.RawMessage) (s
 	if len(taskPreview) > 80 {
 		taskPreview = taskPreview[:80] + "..."
 	}
-	fmt.Fprintf(os.Stderr, "[agent] Spawning sub-agent: %s\n", taskPreview)
+	emit := emitterFrom(ctx)
 
 	systemPrompt := "You are a sub-agent. Complete the following task:\n\n" + p.Task + "\n\nWhen done, provide a concise summary of what you did and the results."
 
+	if p.Background {
+		return t.startBackground(ctx, p.Task, taskPreview, model, systemPrompt, emit), nil
+	}
+	emit(SubAgentSpawn{Task: taskPreview})
+
 	// Create child agent
 	child := New(Options{
 		Client:       t.Client,
@@ -99,11 +128,28 @@ func (t *SpawnAgentTool) Execute(ctx context.Context, params json.RawMessage) (s
 		Permission:   t.Perm,
 		Model:        model,
 		SystemPrompt: systemPrompt,
+		Rules:        t.Rules,
+		Pages:        t.Pages,
 	})
 
-	// Capture child output
+	return runToCompletion(ctx, child, p.Task, t.Summary, emit, 0), nil
+}
+
+// runToCompletion sends task to child and waits for it to finish or for
+// ctx to be cancelled, returning the child's output, summarized if it is
+// long, as a tool result. The child's progress goes to emit as events of
+// sub-agent n; see SubAgentEvent.
+func runToCompletion(ctx context.Context, child *Agent, task string, summary SubagentSummaryOptions, emit func(Event), n int) string {
+	// Capture child output; the reply is the tool result, not progress.
 	var outputBuf bytes.Buffer
-	child.SetOutput(&outputBuf, os.Stderr)
+	child.SetOutput(&outputBuf, io.Discard)
+	child.OnEvent(func(e Event) {
+		switch e.(type) {
+		case TokenDelta, TurnDone:
+			return
+		}
+		emit(SubAgentEvent{Agent: n, Event: e})
+	})
 
 	// Run sub-agent in a goroutine and block on the result
 	type result struct {
@@ -118,38 +164,340 @@ func (t *SpawnAgentTool) Execute(ctx context.Context, params json.RawMessage) (s
 				ch <- result{err: fmt.Errorf("sub-agent panic: %v", r)}
 			}
 		}()
-		err := child.Send(ctx, p.Task)
+		err := child.Send(ctx, task)
 		ch <- result{output: outputBuf.String(), err: err}
 	}()
 
 	// Block until sub-agent completes or context is cancelled
 	select {
 	case r := <-ch:
-		fmt.Fprintf(os.Stderr, "[agent] Sub-agent completed\n")
+		if n == 0 {
+			emit(SubAgentDone{})
+		} else {
+			emit(SubAgentEvent{Agent: n, Event: SubAgentDone{}})
+		}
 		if r.err != nil {
-			return fmt.Sprintf("Sub-agent error: %v", r.err), nil
+			return fmt.Sprintf("Sub-agent error: %v", r.err)
 		}
 		if r.output == "" {
-			return "Sub-agent completed with no output", nil
+			return "Sub-agent completed with no output"
 		}
-		return r.output, nil
+		return child.summarizeResult(ctx, summary, task, r.output)
 	case <-ctx.Done():
-		return fmt.Sprintf("Sub-agent cancelled: %v", ctx.Err()), nil
+		return fmt.Sprintf("Sub-agent cancelled: %v", ctx.Err())
+	}
+}
+
+// backgroundAgent is a sub-agent started with background set.
+type backgroundAgent struct {
+	id      string
+	task    string
+	started time.Time
+	mailbox *Mailbox
+	cancel  context.CancelFunc
+	done    chan struct{} // closed when the sub-agent stops
+	child   *Agent
+
+	mu       sync.Mutex // guards finished and err against post
+	finished time.Time
+	err      error
+
+	resultOnce sync.Once
+	result     string
+}
+
+// post delivers msg unless the sub-agent has already stopped.
+func (b *backgroundAgent) post(msg string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.finished.IsZero() {
+		return false
+	}
+	b.mailbox.Post(msg)
+	return true
+}
+
+// finish records the outcome, unless messages arrived after the
+// sub-agent's last reply; then it reports false and the sub-agent must
+// carry on with them.
+func (b *backgroundAgent) finish(err error) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err == nil && b.mailbox.Pending() > 0 {
+		return false
+	}
+	b.finished, b.err = time.Now(), err
+	close(b.done)
+	return true
+}
+
+// startBackground starts a background sub-agent. Its progress goes to
+// emit as events of sub-agent N, its number in agent-N, for as long as
+// it runs.
+func (t *SpawnAgentTool) startBackground(ctx context.Context, task, taskPreview, model, systemPrompt string, emit func(Event)) string {
+	mb := NewMailbox()
+	child := New(Options{
+		Client:       t.Client,
+		Registry:     t.Registry,
+		Permission:   t.Perm,
+		Model:        model,
+		SystemPrompt: systemPrompt,
+		Mailbox:      mb,
+		Rules:        t.Rules,
+		Pages:        t.Pages,
+	})
+	child.SetOutput(mb, io.Discard)
+
+	// The sub-agent outlives this tool call, so it must not be stopped
+	// when the parent's turn ends; Close stops it instead. Nobody waits
+	// on its requests, so they yield to the main agent's.
+	runCtx, cancel := context.WithCancel(llm.WithPriority(context.WithoutCancel(ctx), llm.PriorityBackground))
+	t.mu.Lock()
+	if t.background == nil {
+		t.background = map[string]*backgroundAgent{}
+	}
+	t.nextID++
+	n := t.nextID
+	b := &backgroundAgent{
+		id:      fmt.Sprintf("agent-%d", n),
+		task:    task,
+		started: time.Now(),
+		mailbox: mb,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+		child:   child,
+	}
+	t.background[b.id] = b
+	t.mu.Unlock()
+
+	emit(SubAgentEvent{Agent: n, Event: SubAgentSpawn{Task: taskPreview}})
+	child.OnEvent(func(e Event) {
+		switch e.(type) {
+		case TokenDelta, TurnDone:
+			return
+		}
+		emit(SubAgentEvent{Agent: n, Event: e})
+	})
+
+	go func() {
+		defer cancel()
+		err := func() (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					err = fmt.Errorf("sub-agent panic: %v", r)
+				}
+			}()
+			err = child.Send(runCtx, task)
+			for !b.finish(err) {
+				err = child.loop(runCtx)
+			}
+			return nil
+		}()
+		if err != nil {
+			b.finish(err)
+		}
+		emit(SubAgentEvent{Agent: n, Event: SubAgentDone{}})
+	}()
+
+	return fmt.Sprintf("Started sub-agent %s in the background. Check on it with agent_status and send it instructions with message_agent.", b.id)
+}
+
+// lookup returns the background sub-agent with the given ID.
+func (t *SpawnAgentTool) lookup(id string) *backgroundAgent {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.background[id]
+}
+
+// Close stops any background sub-agents still running.
+func (t *SpawnAgentTool) Close() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, b := range t.background {
+		b.cancel()
+	}
+}
+
+// Tools returns the message_agent and agent_status tools for steering
+// this tool's background sub-agents.
+func (t *SpawnAgentTool) Tools() []tool.Tool {
+	return []tool.Tool{&MessageAgentTool{Spawner: t}, &AgentStatusTool{Spawner: t}}
+}
+
+// MessageAgentTool sends a follow-up instruction to a background
+// sub-agent, which reads it before its next model request.
+type MessageAgentTool struct {
+	Spawner *SpawnAgentTool
+}
+
+type messageAgentParams struct {
+	AgentID string `json:"agent_id"`
+	Message string `json:"message"`
+}
+
+func (t *MessageAgentTool) Name() string { return "message_agent" }
+func (t *MessageAgentTool) Description() string {
+	return "Send a follow-up instruction to a sub-agent running in the background, to steer it without restarting it"
+}
+func (t *MessageAgentTool) Permission() tool.PermissionLevel { return tool.PermissionAuto }
+
+func (t *MessageAgentTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+	"type": "object",
+	"properties": {
+		"agent_id": {
+			"type": "string",
+			"description": "The ID spawn_agent returned (e.g., 'agent-1')"
+		},
+		"message": {
+			"type": "string",
+			"description": "The instruction to send"
+		}
+	},
+	"required": ["agent_id", "message"]
+}`)
+}
+
+func (t *MessageAgentTool) Execute(_ context.Context, params json.RawMessage) (string, error) {
+	var p messageAgentParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return fmt.Sprintf("Error: invalid parameters: %v", err), nil
+	}
+	if p.Message == "" {
+		return "Error: message is required", nil
 	}
+	b := t.Spawner.lookup(p.AgentID)
+	if b == nil {
+		return fmt.Sprintf("Error: no background sub-agent %q", p.AgentID), nil
+	}
+	if !b.post(p.Message) {
+		return fmt.Sprintf("Error: sub-agent %s has already finished; use agent_status for its result", b.id), nil
+	}
+	return fmt.Sprintf("Message queued for %s; it will read it before its next step.", b.id), nil
 }
 
-// prefixWriter wraps a writer and prefixes each write with a given string.
-// Kept for future use if we want prefixed sub-agent output.
-type prefixWriter struct {
-	prefix string
-	w      io.Writer
+// AgentStatusTool reports on background sub-agents: whether each is
+// still running, and its output so far or its result.
+type AgentStatusTool struct {
+	Spawner *SpawnAgentTool
 }
 
-func (pw *prefixWriter) Write(p []byte) (int, error) {
-	prefixed := append([]byte(pw.prefix), p...)
-	_, err := pw.w.Write(prefixed)
-	if err != nil {
-		return 0, err
+type agentStatusParams struct {
+	AgentID     string `json:"agent_id"`
+	WaitSeconds int    `json:"wait_seconds"`
+}
+
+// maxStatusWait caps wait_seconds.
+const maxStatusWait = 300
+
+// statusTail is how much of a running sub-agent's output is shown.
+const statusTail = 4 * 1024
+
+func (t *AgentStatusTool) Name() string { return "agent_status" }
+func (t *AgentStatusTool) Description() string {
+	return "Check on background sub-agents: with an agent_id, its progress so far or its final result; without, a list of all of them"
+}
+func (t *AgentStatusTool) Permission() tool.PermissionLevel { return tool.PermissionAuto }
+func (t *AgentStatusTool) Capabilities() []tool.Capability { return []tool.Capability{tool.CapRead} }
+
+func (t *AgentStatusTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+	"type": "object",
+	"properties": {
+		"agent_id": {
+			"type": "string",
+			"description": "The sub-agent to report on; omit to list all"
+		},
+		"wait_seconds": {
+			"type": "integer",
+			"description": "Wait up to this many seconds (max 300) for the sub-agent to finish before reporting"
+		}
+	}
+}`)
+}
+
+func (t *AgentStatusTool) Execute(ctx context.Context, params json.RawMessage) (string, error) {
+	var p agentStatusParams
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &p); err != nil {
+			return fmt.Sprintf("Error: invalid parameters: %v", err), nil
+		}
+	}
+	if p.AgentID == "" {
+		return t.list(), nil
+	}
+	b := t.Spawner.lookup(p.AgentID)
+	if b == nil {
+		return fmt.Sprintf("Error: no background sub-agent %q", p.AgentID), nil
+	}
+	if p.WaitSeconds > 0 {
+		timer := time.NewTimer(time.Duration(min(p.WaitSeconds, maxStatusWait)) * time.Second)
+		defer timer.Stop()
+		select {
+		case <-b.done:
+		case <-timer.C:
+		case <-ctx.Done():
+		}
+	}
+
+	b.mu.Lock()
+	finished, runErr := b.finished, b.err
+	b.mu.Unlock()
+	output := b.mailbox.Output()
+	var sb strings.Builder
+	switch {
+	case finished.IsZero():
+		fmt.Fprintf(&sb, "%s: running for %s", b.id, time.Since(b.started).Round(time.Second))
+		if n := b.mailbox.Pending(); n > 0 {
+			fmt.Fprintf(&sb, ", %d message(s) not yet read", n)
+		}
+		fmt.Fprintf(&sb, "\nTask: %s\n", b.task)
+		if len(output) > statusTail {
+			output = "[...]" + output[len(output)-statusTail:]
+		}
+		if output == "" {
+			output = "(no output yet)"
+		}
+		sb.WriteString("Output so far:\n" + output)
+	case runErr != nil:
+		fmt.Fprintf(&sb, "%s: failed after %s: %v\n%s", b.id, finished.Sub(b.started).Round(time.Second), runErr, output)
+	default:
+		b.resultOnce.Do(func() {
+			b.result = b.child.summarizeResult(ctx, t.Spawner.Summary, b.task, output)
+		})
+		fmt.Fprintf(&sb, "%s: finished after %s\n%s", b.id, finished.Sub(b.started).Round(time.Second), b.result)
+	}
+	return sb.String(), nil
+}
+
+func (t *AgentStatusTool) list() string {
+	s := t.Spawner
+	s.mu.Lock()
+	agents := make([]*backgroundAgent, 0, len(s.background))
+	for _, b := range s.background {
+		agents = append(agents, b)
+	}
+	s.mu.Unlock()
+	if len(agents) == 0 {
+		return "No background sub-agents."
+	}
+	sort.Slice(agents, func(i, j int) bool { return agents[i].started.Before(agents[j].started) })
+	var sb strings.Builder
+	for _, b := range agents {
+		b.mu.Lock()
+		state := "running"
+		switch {
+		case b.err != nil:
+			state = "failed"
+		case !b.finished.IsZero():
+			state = "finished"
+		}
+		b.mu.Unlock()
+		task := b.task
+		if len(task) > 80 {
+			task = task[:80] + "..."
+		}
+		fmt.Fprintf(&sb, "%s (%s): %s\n", b.id, state, task)
 	}
-	return len(p), nil
+	return strings.TrimRight(sb.String(), "\n")
 }
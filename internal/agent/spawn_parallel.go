@@ -0,0 +1,155 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/gavinyap/stormtrooper/internal/tool"
+)
+
+// Limits on spawn_agents_parallel.
+const (
+	DefaultMaxParallel = 4  // sub-agents running at once when max_parallel is unset
+	maxParallel        = 8  // cap on max_parallel
+	maxParallelTasks   = 16 // tasks in one call
+)
+
+// SpawnParallelTool runs several sub-agents at once, each on its own
+// task, and returns all their results. Each sub-agent's events come
+// in a SubAgentEvent numbered from 1 in task order, shown on stderr with
+// an "[agent:N]" prefix, so the TUI can show them separately.
+type SpawnParallelTool struct {
+	// Spawner supplies the client, tools, permission handler, default
+	// model, and result summaries.
+	Spawner *SpawnAgentTool
+}
+
+type spawnParallelParams struct {
+	Tasks       []string `json:"tasks"`
+	Model       string   `json:"model"`
+	MaxParallel int      `json:"max_parallel"`
+}
+
+func (t *SpawnParallelTool) Name() string { return "spawn_agents_parallel" }
+func (t *SpawnParallelTool) Description() string {
+	return "Spawn several sub-agents that work on independent tasks at the same time, and wait for all their results"
+}
+func (t *SpawnParallelTool) Permission() tool.PermissionLevel { return tool.PermissionPrompt }
+
+func (t *SpawnParallelTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+	"type": "object",
+	"properties": {
+		"tasks": {
+			"type": "array",
+			"items": {"type": "string"},
+			"description": "One task description per sub-agent (at most 16); the tasks must not depend on each other"
+		},
+		"model": {
+			"type": "string",
+			"description": "Model to use for the sub-agents (optional, defaults to parent's model)"
+		},
+		"max_parallel": {
+			"type": "integer",
+			"description": "How many sub-agents run at once (default 4, max 8)"
+		}
+	},
+	"required": ["tasks"]
+}`)
+}
+
+// Preview returns a description for the permission prompt.
+func (t *SpawnParallelTool) Preview(params json.RawMessage) string {
+	var p spawnParallelParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return "Spawn sub-agents (invalid params)"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Spawn %d sub-agents, %d at a time:", len(p.Tasks), p.limit())
+	for i, task := range p.Tasks {
+		fmt.Fprintf(&b, "\n  %d. %s", i+1, shortTask(task))
+	}
+	return b.String()
+}
+
+// limit returns how many sub-agents run at once.
+func (p spawnParallelParams) limit() int {
+	n := p.MaxParallel
+	if n <= 0 {
+		n = DefaultMaxParallel
+	}
+	return max(1, min(n, maxParallel, len(p.Tasks)))
+}
+
+func (t *SpawnParallelTool) Execute(ctx context.Context, params json.RawMessage) (string, error) {
+	var p spawnParallelParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return fmt.Sprintf("Error: invalid parameters: %v", err), nil
+	}
+	if len(p.Tasks) == 0 {
+		return "Error: tasks is required", nil
+	}
+	if len(p.Tasks) > maxParallelTasks {
+		return fmt.Sprintf("Error: at most %d tasks can run in one call, got %d", maxParallelTasks, len(p.Tasks)), nil
+	}
+	for i, task := range p.Tasks {
+		if strings.TrimSpace(task) == "" {
+			return fmt.Sprintf("Error: task %d is empty", i+1), nil
+		}
+	}
+
+	s := t.Spawner
+	model := s.Model
+	if p.Model != "" {
+		model = p.Model
+	}
+
+	emit := emitterFrom(ctx)
+	results := make([]string, len(p.Tasks))
+	slots := make(chan struct{}, p.limit())
+	var wg sync.WaitGroup
+	for i, task := range p.Tasks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case slots <- struct{}{}:
+				defer func() { <-slots }()
+			case <-ctx.Done():
+				results[i] = fmt.Sprintf("Sub-agent cancelled: %v", ctx.Err())
+				return
+			}
+			emit(SubAgentEvent{Agent: i + 1, Event: SubAgentSpawn{Task: shortTask(task)}})
+			child := New(Options{
+				Client:       s.Client,
+				Registry:     s.Registry,
+				Permission:   s.Perm,
+				Model:        model,
+				SystemPrompt: "You are a sub-agent. Complete the following task:\n\n" + task + "\n\nOther sub-agents are working on related tasks at the same time; stay within yours. When done, provide a concise summary of what you did and the results.",
+				Rules:        s.Rules,
+				Pages:        s.Pages,
+			})
+			results[i] = runToCompletion(ctx, child, task, s.Summary, emit, i+1)
+		}()
+	}
+	wg.Wait()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Ran %d sub-agents, %d at a time.", len(p.Tasks), p.limit())
+	for i, task := range p.Tasks {
+		fmt.Fprintf(&b, "\n\n## Sub-agent %d: %s\n%s", i+1, shortTask(task), strings.TrimSpace(results[i]))
+	}
+	return b.String(), nil
+}
+
+// shortTask shortens task for progress lines and previews.
+func shortTask(task string) string {
+	task = strings.Join(strings.Fields(task), " ")
+	if len(task) > 80 {
+		task = task[:80] + "..."
+	}
+	return task
+}
@@ -0,0 +1,116 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gavinyap/stormtrooper/internal/llm"
+	"github.com/gavinyap/stormtrooper/internal/permission"
+	"github.com/gavinyap/stormtrooper/internal/tool"
+)
+
+func TestSpawnParallel(t *testing.T) {
+	var mu sync.Mutex
+	running, most := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req llm.ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		mu.Lock()
+		running++
+		most = max(most, running)
+		mu.Unlock()
+		time.Sleep(50 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+
+		task := req.Messages[len(req.Messages)-1].Content
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(sseTextResponse("Finished " + task)))
+	}))
+	defer server.Close()
+	client := llm.NewClient("test-key")
+	client.SetBaseURL(server.URL)
+
+	st := &SpawnParallelTool{Spawner: NewSpawnAgentTool(client, tool.NewRegistry(), permission.AllowAll{}, "main")}
+	params, _ := json.Marshal(spawnParallelParams{Tasks: []string{"lint", "test", "docs"}, MaxParallel: 2})
+	result, err := st.Execute(context.Background(), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if most > 2 {
+		t.Errorf("%d sub-agents ran at once, want at most 2", most)
+	}
+	want := []string{
+		"Ran 3 sub-agents, 2 at a time.",
+		"## Sub-agent 1: lint\nFinished lint",
+		"## Sub-agent 2: test\nFinished test",
+		"## Sub-agent 3: docs\nFinished docs",
+	}
+	last := -1
+	for _, w := range want {
+		i := strings.Index(result, w)
+		if i <= last {
+			t.Fatalf("result is missing %q in order:\n%s", w, result)
+		}
+		last = i
+	}
+}
+
+func TestSpawnParallelInvalid(t *testing.T) {
+	st := &SpawnParallelTool{Spawner: &SpawnAgentTool{Model: "test-model"}}
+	tests := []struct {
+		params string
+		want   string
+	}{
+		{`{invalid`, "Error: invalid parameters"},
+		{`{"tasks":[]}`, "Error: tasks is required"},
+		{`{"tasks":["a"," "]}`, "Error: task 2 is empty"},
+		{`{"tasks":["1","2","3","4","5","6","7","8","9","10","11","12","13","14","15","16","17"]}`, "Error: at most 16 tasks"},
+	}
+	for _, tt := range tests {
+		result, err := st.Execute(context.Background(), json.RawMessage(tt.params))
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.params, err)
+		}
+		if !strings.HasPrefix(result, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.params, result, tt.want)
+		}
+	}
+}
+
+func TestSpawnParallelPreview(t *testing.T) {
+	st := &SpawnParallelTool{}
+	params, _ := json.Marshal(spawnParallelParams{Tasks: []string{"Fix the parser", strings.Repeat("a", 100)}, MaxParallel: 20})
+	preview := st.Preview(params)
+	for _, want := range []string{"Spawn 2 sub-agents, 2 at a time:", "1. Fix the parser", "2. " + strings.Repeat("a", 80) + "..."} {
+		if !strings.Contains(preview, want) {
+			t.Errorf("preview missing %q:\n%s", want, preview)
+		}
+	}
+}
+
+func TestSpawnParallelLimit(t *testing.T) {
+	tests := []struct {
+		tasks, maxParallel, want int
+	}{
+		{10, 0, DefaultMaxParallel},
+		{10, 3, 3},
+		{10, 50, maxParallel},
+		{2, 0, 2},
+		{10, -1, DefaultMaxParallel},
+	}
+	for _, tt := range tests {
+		p := spawnParallelParams{Tasks: make([]string, tt.tasks), MaxParallel: tt.maxParallel}
+		if got := p.limit(); got != tt.want {
+			t.Errorf("limit(%d tasks, max_parallel %d) = %d, want %d", tt.tasks, tt.maxParallel, got, tt.want)
+		}
+	}
+}
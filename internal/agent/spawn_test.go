@@ -3,9 +3,14 @@ package agent
 import (
 	"context"
 	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/gavinyap/stormtrooper/internal/llm"
 	"github.com/gavinyap/stormtrooper/internal/permission"
 	"github.com/gavinyap/stormtrooper/internal/tool"
 )
@@ -107,3 +112,105 @@ func TestSpawnAgentContextCancellation(t *testing.T) {
 		t.Fatalf("expected cancellation or error message, got %q", result)
 	}
 }
+
+func TestSpawnAgentBackground(t *testing.T) {
+	// The first model request is held until the parent has posted a
+	// message, so the message arrives while the sub-agent is busy and
+	// after its reply it must carry on with it.
+	received, release := make(chan struct{}), make(chan struct{})
+	var requests [][]llm.Message
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req llm.ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		requests = append(requests, req.Messages)
+		reply := "second pass"
+		if len(requests) == 1 {
+			close(received)
+			<-release
+			reply = "first pass"
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(sseTextResponse(reply)))
+	}))
+	defer server.Close()
+
+	client := llm.NewClient("test-key")
+	client.SetBaseURL(server.URL)
+	st := NewSpawnAgentTool(client, tool.NewRegistry(), permission.AllowAll{}, "test-model")
+	defer st.Close()
+	tools := st.Tools()
+	message, status := tools[0], tools[1]
+
+	// Progress goes to the parent's events for as long as it runs.
+	var mu sync.Mutex
+	var events []string
+	finished := make(chan struct{})
+	ctx := withEmitter(context.Background(), func(e Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, eventLine(e))
+		if sub, ok := e.(SubAgentEvent); ok && sub.Event == (SubAgentDone{}) {
+			close(finished)
+		}
+	})
+
+	params, _ := json.Marshal(spawnAgentParams{Task: "audit the handlers", Background: true})
+	result, _ := st.Execute(ctx, params)
+	if !strings.Contains(result, "agent-1") {
+		t.Fatalf("spawn result = %q", result)
+	}
+
+	<-received
+	got, _ := status.Execute(context.Background(), json.RawMessage(`{"agent_id": "agent-1"}`))
+	if !strings.HasPrefix(got, "agent-1: running for") || !strings.Contains(got, "(no output yet)") {
+		t.Errorf("status while running = %q", got)
+	}
+	got, _ = message.Execute(context.Background(), json.RawMessage(`{"agent_id": "agent-1", "message": "skip the admin handlers"}`))
+	if !strings.Contains(got, "queued") {
+		t.Errorf("message result = %q", got)
+	}
+	close(release)
+
+	got, _ = status.Execute(context.Background(), json.RawMessage(`{"agent_id": "agent-1", "wait_seconds": 5}`))
+	if !strings.HasPrefix(got, "agent-1: finished after") || !strings.Contains(got, "first pass") || !strings.Contains(got, "second pass") {
+		t.Errorf("final status = %q", got)
+	}
+	last := requests[len(requests)-1]
+	if m := last[len(last)-1]; m.Content != "Message from the agent that started you: skip the admin handlers" {
+		t.Errorf("last message sent to the model = %+v", m)
+	}
+
+	got, _ = message.Execute(context.Background(), json.RawMessage(`{"agent_id": "agent-1", "message": "more"}`))
+	if !strings.Contains(got, "already finished") {
+		t.Errorf("message after finish = %q", got)
+	}
+	got, _ = status.Execute(context.Background(), json.RawMessage(`{}`))
+	if got != "agent-1 (finished): audit the handlers" {
+		t.Errorf("list = %q", got)
+	}
+
+	select {
+	case <-finished:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no completion event")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if want := "[agent:1] [agent] Spawning sub-agent: audit the handlers|[agent:1] [agent] Sub-agent completed"; strings.Join(events, "|") != want {
+		t.Errorf("events = %q, want %q", events, want)
+	}
+}
+
+func TestAgentStatusUnknown(t *testing.T) {
+	st := &SpawnAgentTool{}
+	for _, tl := range st.Tools() {
+		got, _ := tl.Execute(context.Background(), json.RawMessage(`{"agent_id": "agent-9", "message": "x"}`))
+		if !strings.Contains(got, "no background sub-agent") {
+			t.Errorf("%s: %q", tl.Name(), got)
+		}
+	}
+	got, _ := st.Tools()[1].Execute(context.Background(), nil)
+	if got != "No background sub-agents." {
+		t.Errorf("empty list = %q", got)
+	}
+}
@@ -0,0 +1,129 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/gavinyap/stormtrooper/internal/llm"
+	"github.com/gavinyap/stormtrooper/internal/metrics"
+	"github.com/gavinyap/stormtrooper/internal/tool"
+)
+
+// SubagentSummaryOptions controls when a sub-agent's result is condensed
+// into a structured report before it is returned to the agent that
+// started it, so a long transcript does not land in that agent's history.
+type SubagentSummaryOptions struct {
+	// Threshold is the estimated size of the result, in tokens, above
+	// which it is summarized; 0 means DefaultSubagentSummaryTokens and
+	// negative turns summaries off.
+	Threshold int
+	// Model writes the report; empty means the sub-agent's model.
+	Model string
+	// Pad, if set, keeps each full result, so the model can page through
+	// it with scratchpad_read.
+	Pad *tool.Scratchpad
+}
+
+// DefaultSubagentSummaryTokens is the result size above which a
+// sub-agent's result is summarized by default.
+const DefaultSubagentSummaryTokens = 2000
+
+// maxSubagentSummary caps the report.
+const maxSubagentSummary = 1024
+
+const subagentSummaryPrompt = "You report a sub-agent's work to the agent that delegated the task, which will see only your report. Answer with exactly three sections. \"Done:\" what was done and found, with the conclusions, names, and numbers the agent needs. \"Files changed:\" each path created, edited, or deleted and how, or \"none\". \"Open issues:\" errors left unresolved, verbatim, work not finished, and suggested follow-ups, or \"none\". Be specific and brief. Answer with the report only."
+
+func (o SubagentSummaryOptions) threshold() int {
+	if o.Threshold == 0 {
+		return DefaultSubagentSummaryTokens
+	}
+	return o.Threshold
+}
+
+// summarizeResult returns output, the result of the task a sub-agent was
+// given, or a report on the sub-agent's work when output is long. If the
+// report cannot be written, output is returned unchanged.
+func (a *Agent) summarizeResult(ctx context.Context, opts SubagentSummaryOptions, task, output string) string {
+	threshold := opts.threshold()
+	tokens := len(output) / bytesPerToken
+	if threshold < 0 || tokens <= threshold {
+		return output
+	}
+
+	model := opts.Model
+	if model == "" {
+		model = a.Model()
+	}
+	messages := a.history
+	if len(messages) > 0 && messages[0].Role == "system" {
+		messages = messages[1:]
+	}
+	input := fmt.Sprintf("Task:\n%s\n\nTranscript:\n\n%s", task, transcript(messages))
+	if files := a.writtenFiles(); len(files) > 0 {
+		input += "\nFiles the sub-agent wrote to: " + strings.Join(files, ", ")
+	}
+	metrics.LLMRequests.Inc(model)
+	resp, err := a.client.ChatCompletion(ctx, llm.ChatCompletionRequest{
+		Model: model,
+		Messages: []llm.Message{
+			{Role: "system", Content: subagentSummaryPrompt},
+			{Role: "user", Content: input},
+		},
+		MaxTokens: maxSubagentSummary,
+	})
+	if err == nil {
+		a.recordUsage(model, resp.Usage)
+		if len(resp.Choices) == 0 || strings.TrimSpace(resp.Choices[0].Message.Content) == "" {
+			err = fmt.Errorf("empty response")
+		}
+	}
+	if err != nil {
+		metrics.LLMErrors.Inc(model)
+		a.warn("Could not summarize the sub-agent's result: %v", err)
+		return output
+	}
+
+	note := fmt.Sprintf("[The sub-agent's result was about %d tokens, so it was summarized.]", tokens)
+	if opts.Pad != nil {
+		if entry, err := opts.Pad.Save("spawn_agent", output); err == nil {
+			note = fmt.Sprintf("[The sub-agent's result was about %d tokens, so it was summarized. The full result is scratchpad entry %q; use scratchpad_read with offset and limit for exact lines.]", tokens, entry)
+		}
+	}
+	return note + "\n\n" + strings.TrimSpace(resp.Choices[0].Message.Content)
+}
+
+// writtenFiles returns the paths passed to the agent's file-writing tool
+// calls, in the order first written.
+func (a *Agent) writtenFiles() []string {
+	var files []string
+	add := func(path string) {
+		if path != "" && !slices.Contains(files, path) {
+			files = append(files, path)
+		}
+	}
+	for _, m := range a.history {
+		for _, tc := range m.ToolCalls {
+			t := a.registry.Get(tc.Function.Name)
+			if t == nil || !slices.Contains(tool.CapabilitiesOf(t), tool.CapWrite) {
+				continue
+			}
+			var args struct {
+				FilePath string `json:"file_path"`
+				Files    []struct {
+					FilePath string `json:"file_path"`
+				} `json:"files"`
+			}
+			if json.Unmarshal([]byte(tc.Function.Arguments), &args) != nil {
+				continue
+			}
+			add(args.FilePath)
+			for _, f := range args.Files {
+				add(f.FilePath)
+			}
+		}
+	}
+	return files
+}
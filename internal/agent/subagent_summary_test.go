@@ -0,0 +1,131 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gavinyap/stormtrooper/internal/llm"
+	"github.com/gavinyap/stormtrooper/internal/permission"
+	"github.com/gavinyap/stormtrooper/internal/tool"
+)
+
+// writeTool is a mockTool that declares it writes files.
+type writeTool struct{ mockTool }
+
+func (w *writeTool) Capabilities() []tool.Capability { return []tool.Capability{tool.CapWrite} }
+
+// subagentServer has the sub-agent write a file and then answer with
+// answer. Requests for the "cheap" model get report, or a failure when
+// report is empty.
+func subagentServer(t *testing.T, answer, report string, reportRequests *[]llm.ChatCompletionRequest) *llm.Client {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req llm.ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Model == "cheap" {
+			*reportRequests = append(*reportRequests, req)
+			if report == "" {
+				http.Error(w, "overloaded", http.StatusServiceUnavailable)
+				return
+			}
+			json.NewEncoder(w).Encode(llm.ChatCompletionResponse{
+				Choices: []llm.Choice{{Message: llm.Message{Role: "assistant", Content: report}}},
+			})
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		if last := req.Messages[len(req.Messages)-1]; last.Role == "user" {
+			w.Write([]byte(sseToolCallResponse("call_1", "write_file", `{"file_path":"internal/api/handler.go","content":"package api"}`)))
+			return
+		}
+		w.Write([]byte(sseTextResponse(answer)))
+	}))
+	t.Cleanup(server.Close)
+	client := llm.NewClient("test-key")
+	client.SetBaseURL(server.URL)
+	return client
+}
+
+// longAnswer returns n distinct lines, which the repetition guard lets
+// through.
+func longAnswer(n int) string {
+	var b strings.Builder
+	for i := range n {
+		fmt.Fprintf(&b, "Handler %d checks its input.\n", i)
+	}
+	return b.String()
+}
+
+func spawnWithSummary(t *testing.T, client *llm.Client, summary SubagentSummaryOptions) string {
+	t.Helper()
+	reg := tool.NewRegistry()
+	reg.Register(&writeTool{mockTool{name: "write_file", perm: tool.PermissionAuto, result: "ok"}})
+	st := NewSpawnAgentTool(client, reg, permission.AllowAll{}, "main")
+	st.Summary = summary
+	params, _ := json.Marshal(spawnAgentParams{Task: "add a health handler"})
+	result, err := st.Execute(context.Background(), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestSubagentSummary_LongResult(t *testing.T) {
+	var requests []llm.ChatCompletionRequest
+	answer := "I looked at every handler in the package.\n" + longAnswer(40)
+	report := "Done: added /healthz.\nFiles changed: internal/api/handler.go (new handler)\nOpen issues: none"
+	pad := &tool.Scratchpad{}
+	t.Cleanup(func() { pad.Close() })
+
+	result := spawnWithSummary(t, subagentServer(t, answer, report, &requests), SubagentSummaryOptions{Threshold: 100, Model: "cheap", Pad: pad})
+
+	if !strings.HasSuffix(result, report) || !strings.Contains(result, "so it was summarized") {
+		t.Errorf("result = %q, want the report", result)
+	}
+	if !strings.Contains(result, "scratchpad entry") {
+		t.Errorf("result = %q, want the scratchpad entry of the full result", result)
+	}
+	if len(requests) != 1 {
+		t.Fatalf("report requests = %d, want 1", len(requests))
+	}
+	input := requests[0].Messages[1].Content
+	for _, want := range []string{"add a health handler", "I looked at every handler", "Files the sub-agent wrote to: internal/api/handler.go"} {
+		if !strings.Contains(input, want) {
+			t.Errorf("report request missing %q:\n%s", want, input)
+		}
+	}
+	if strings.Contains(input, "You are a sub-agent") {
+		t.Error("report request should not include the sub-agent's system prompt")
+	}
+}
+
+func TestSubagentSummary_ShortResult(t *testing.T) {
+	var requests []llm.ChatCompletionRequest
+	result := spawnWithSummary(t, subagentServer(t, "Added /healthz.", "report", &requests), SubagentSummaryOptions{Model: "cheap"})
+	if !strings.Contains(result, "Added /healthz.") || len(requests) != 0 {
+		t.Errorf("result = %q with %d report requests; a short result should be returned as it is", result, len(requests))
+	}
+}
+
+func TestSubagentSummary_Failure(t *testing.T) {
+	var requests []llm.ChatCompletionRequest
+	answer := longAnswer(40)
+	result := spawnWithSummary(t, subagentServer(t, answer, "", &requests), SubagentSummaryOptions{Threshold: 100, Model: "cheap"})
+	if len(requests) != 1 || !strings.Contains(result, answer) {
+		t.Errorf("result = %q; a failed report should leave the result as it is", result)
+	}
+}
+
+func TestSubagentSummary_Off(t *testing.T) {
+	var requests []llm.ChatCompletionRequest
+	answer := longAnswer(400)
+	result := spawnWithSummary(t, subagentServer(t, answer, "report", &requests), SubagentSummaryOptions{Threshold: -1, Model: "cheap"})
+	if len(requests) != 0 || !strings.Contains(result, answer) {
+		t.Errorf("%d report requests with summaries off", len(requests))
+	}
+}
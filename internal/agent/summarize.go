@@ -0,0 +1,94 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/gavinyap/stormtrooper/internal/llm"
+	"github.com/gavinyap/stormtrooper/internal/metrics"
+	"github.com/gavinyap/stormtrooper/internal/tool"
+)
+
+// SummarizeOptions controls how long tool results, such as build logs
+// and test output, are condensed by a cheaper model before they enter
+// the history.
+type SummarizeOptions struct {
+	// Model writes the summaries; empty disables summarization.
+	Model string
+	// MinTokens is the estimated size above which a result is summarized.
+	MinTokens int
+	// Pad keeps each raw result, so the model can page through it with
+	// scratchpad_read. Summarization is disabled without it.
+	Pad *tool.Scratchpad
+}
+
+// keepWhole lists tools whose results are never summarized: the model
+// needs read_file's exact text to edit it, and scratchpad_read is how a
+// summarized result is paged back in.
+var keepWhole = map[string]bool{
+	"read_file":       true,
+	"scratchpad_read": true,
+}
+
+// Limits on a summarization request: the output sent, and the summary.
+const (
+	maxSummarizeInput = 256 * 1024
+	maxSummaryTokens  = 1024
+)
+
+// bytesPerToken is a rough size of a token, good enough for a threshold.
+const bytesPerToken = 4
+
+const summarizePrompt = "You condense tool output for a coding agent that cannot see the original. Keep every error and failure with its file, line, and message verbatim, the final status or exit code, and any numbers the agent may need. Drop progress lines, repeated warnings, and passing tests. Answer with the summary only."
+
+// summarize returns result, or a summary of it when it is long. The raw
+// result is saved to the scratchpad first; if anything fails, result is
+// returned unchanged.
+func (a *Agent) summarize(ctx context.Context, name, result string) string {
+	opts := a.summarizer
+	if opts.Model == "" || opts.Pad == nil || keepWhole[name] {
+		return result
+	}
+	tokens := len(result) / bytesPerToken
+	if tokens < opts.MinTokens {
+		return result
+	}
+
+	entry, err := opts.Pad.Save(name, result)
+	if err != nil {
+		fmt.Fprintf(a.stderr, "[summarize] skipped %s: %v\n", name, err)
+		return result
+	}
+	input := result
+	if len(input) > maxSummarizeInput {
+		// Failures are usually reported at the end.
+		input = "[earlier output omitted]\n" + input[len(input)-maxSummarizeInput:]
+	}
+	metrics.LLMRequests.Inc(opts.Model)
+	resp, err := a.client.ChatCompletion(ctx, llm.ChatCompletionRequest{
+		Model: opts.Model,
+		Messages: []llm.Message{
+			{Role: "system", Content: summarizePrompt},
+			{Role: "user", Content: fmt.Sprintf("Output of the %s tool:\n\n%s", name, input)},
+		},
+		MaxTokens: maxSummaryTokens,
+	})
+	if err == nil && (len(resp.Choices) == 0 || strings.TrimSpace(resp.Choices[0].Message.Content) == "") {
+		err = fmt.Errorf("empty response")
+	}
+	if err != nil {
+		metrics.LLMErrors.Inc(opts.Model)
+		fmt.Fprintf(a.stderr, "[summarize] %s failed: %v\n", name, err)
+		return result
+	}
+	if u := resp.Usage; u != nil {
+		metrics.LLMTokens.Add(float64(u.PromptTokens), opts.Model, "prompt")
+		metrics.LLMTokens.Add(float64(u.CompletionTokens), opts.Model, "completion")
+	}
+	a.recordUsage(opts.Model, resp.Usage)
+
+	lines := strings.Count(result, "\n") + 1
+	return fmt.Sprintf("[This %s result was %d lines (about %d tokens), so it was summarized. The full output is scratchpad entry %q; use scratchpad_read with offset and limit for exact lines.]\n\n%s",
+		name, lines, tokens, entry, strings.TrimSpace(resp.Choices[0].Message.Content))
+}
@@ -0,0 +1,126 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gavinyap/stormtrooper/internal/llm"
+	"github.com/gavinyap/stormtrooper/internal/permission"
+	"github.com/gavinyap/stormtrooper/internal/tool"
+)
+
+// summarizeServer answers requests for the "cheap" model with summary,
+// or a failure when summary is empty, and the main model with a tool
+// call followed by a text reply.
+func summarizeServer(t *testing.T, summary string, summaryRequests *[]llm.ChatCompletionRequest) *llm.Client {
+	t.Helper()
+	turns := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req llm.ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Model == "cheap" {
+			*summaryRequests = append(*summaryRequests, req)
+			if summary == "" {
+				http.Error(w, "overloaded", http.StatusServiceUnavailable)
+				return
+			}
+			json.NewEncoder(w).Encode(llm.ChatCompletionResponse{
+				Choices: []llm.Choice{{Message: llm.Message{Role: "assistant", Content: summary}}},
+			})
+			return
+		}
+		turns++
+		w.Header().Set("Content-Type", "text/event-stream")
+		if turns == 1 {
+			w.Write([]byte(sseToolCallResponse("call_1", "build", `{}`)))
+		} else {
+			w.Write([]byte(sseTextResponse("done")))
+		}
+	}))
+	t.Cleanup(server.Close)
+	client := llm.NewClient("test-key")
+	client.SetBaseURL(server.URL)
+	return client
+}
+
+func runSummarized(t *testing.T, client *llm.Client, output string, pad *tool.Scratchpad) *Agent {
+	t.Helper()
+	reg := tool.NewRegistry()
+	reg.Register(&mockTool{name: "build", perm: tool.PermissionAuto, result: output})
+	ag := New(Options{
+		Client:     client,
+		Registry:   reg,
+		Permission: permission.NewCheckerWithIO(strings.NewReader(""), &bytes.Buffer{}),
+		Model:      "main",
+		Summarize:  SummarizeOptions{Model: "cheap", MinTokens: 100, Pad: pad},
+	})
+	ag.SetOutput(&bytes.Buffer{}, &bytes.Buffer{})
+	if err := ag.Send(context.Background(), "build it"); err != nil {
+		t.Fatal(err)
+	}
+	return ag
+}
+
+func toolResult(ag *Agent) string {
+	for _, m := range ag.History() {
+		if m.Role == "tool" {
+			return m.Content
+		}
+	}
+	return ""
+}
+
+func TestSummarize_LongResult(t *testing.T) {
+	var requests []llm.ChatCompletionRequest
+	client := summarizeServer(t, "main.go:3: undefined: foo", &requests)
+	pad := &tool.Scratchpad{}
+	defer pad.Close()
+
+	output := strings.Repeat("compiling...\n", 100) + "main.go:3: undefined: foo\n"
+	got := toolResult(runSummarized(t, client, output, pad))
+
+	if len(requests) != 1 || !strings.Contains(requests[0].Messages[1].Content, "undefined: foo") {
+		t.Fatalf("summary requests = %+v", requests)
+	}
+	if !strings.HasSuffix(got, "main.go:3: undefined: foo") || !strings.Contains(got, "scratchpad entry") {
+		t.Errorf("tool result = %q", got)
+	}
+	entry := got[strings.Index(got, `"`)+1:]
+	entry = entry[:strings.Index(entry, `"`)]
+	params, _ := json.Marshal(map[string]string{"name": entry})
+	raw, _ := (&tool.ScratchpadReadTool{Pad: pad}).Execute(context.Background(), params)
+	if raw != output {
+		t.Errorf("the raw output should be kept in the scratchpad, got %q", raw)
+	}
+}
+
+func TestSummarize_ShortResultUnchanged(t *testing.T) {
+	var requests []llm.ChatCompletionRequest
+	client := summarizeServer(t, "summary", &requests)
+	pad := &tool.Scratchpad{}
+	defer pad.Close()
+
+	if got := toolResult(runSummarized(t, client, "ok\n", pad)); got != "ok\n" {
+		t.Errorf("tool result = %q", got)
+	}
+	if len(requests) != 0 {
+		t.Errorf("short results should not be summarized, got %d requests", len(requests))
+	}
+}
+
+func TestSummarize_FailureKeepsResult(t *testing.T) {
+	var requests []llm.ChatCompletionRequest
+	client := summarizeServer(t, "", &requests)
+	pad := &tool.Scratchpad{}
+	defer pad.Close()
+
+	output := strings.Repeat("x", 1000)
+	if got := toolResult(runSummarized(t, client, output, pad)); got != output {
+		t.Errorf("a failed summary should keep the raw result, got %q", got)
+	}
+}
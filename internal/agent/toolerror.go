@@ -0,0 +1,142 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"runtime/debug"
+	"strings"
+
+	"github.com/gavinyap/stormtrooper/internal/tool"
+)
+
+// Error classes reported in a ToolError.
+const (
+	ClassPanic      = "panic"
+	ClassTimeout    = "timeout"
+	ClassCanceled   = "canceled"
+	ClassBadArgs    = "invalid_arguments"
+	ClassNotFound   = "not_found"
+	ClassPermission = "permission"
+	ClassError      = "error"
+)
+
+// suggestions tell the model how to recover from each class of error.
+var suggestions = map[string]string{
+	ClassPanic:      "The tool crashed, which is a bug in the tool rather than in your request. Do not repeat the same call; get the result another way or tell the user.",
+	ClassTimeout:    "The call took too long. Narrow it, for example to fewer files or a smaller range, and try again.",
+	ClassCanceled:   "The call was cancelled, usually by the user. Do not retry it unless asked to.",
+	ClassBadArgs:    "The arguments do not match the tool's schema. Check the parameter names and types, then call it again.",
+	ClassNotFound:   "A file or resource does not exist. Check the name, for example with glob, before retrying.",
+	ClassPermission: "The operating system refused access. Use a path the user can read and write, or ask the user.",
+	ClassError:      "Read the error, fix its cause, and try again, or take a different approach.",
+}
+
+// maxStack caps the stack trace sent to the model.
+const maxStack = 2000
+
+// ToolError describes a tool call that failed with a Go error or a
+// panic, in the same shape every time so that the model can tell what
+// went wrong and what to do next.
+type ToolError struct {
+	Tool       string
+	Args       string // the arguments, shortened
+	Class      string // one of the Class constants
+	Message    string
+	Stack      string // panics only
+	Suggestion string
+}
+
+// NewToolError classifies err, returned by or recovered from the tool
+// name called with args.
+func NewToolError(name string, args json.RawMessage, err error) *ToolError {
+	e := &ToolError{Tool: name, Args: truncateArgs(string(args), 200), Message: err.Error()}
+	var p *panicError
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &p):
+		e.Class, e.Message, e.Stack = ClassPanic, fmt.Sprint(p.value), p.stack
+	case errors.Is(err, context.DeadlineExceeded):
+		e.Class = ClassTimeout
+	case errors.Is(err, context.Canceled):
+		e.Class = ClassCanceled
+	case errors.As(err, &syntaxErr), errors.As(err, &typeErr):
+		e.Class = ClassBadArgs
+	case errors.Is(err, fs.ErrNotExist):
+		e.Class = ClassNotFound
+	case errors.Is(err, fs.ErrPermission):
+		e.Class = ClassPermission
+	default:
+		e.Class = ClassError
+	}
+	e.Suggestion = suggestions[e.Class]
+	return e
+}
+
+// Error returns the class and message on one line, for status output.
+func (e *ToolError) Error() string {
+	return e.Class + ": " + e.Message
+}
+
+// String formats the error as the tool result the model sees.
+func (e *ToolError) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Tool error: %s failed (%s): %s\n", e.Tool, e.Class, e.Message)
+	fmt.Fprintf(&b, "Arguments: %s\n", e.Args)
+	fmt.Fprintf(&b, "Suggestion: %s", e.Suggestion)
+	if e.Stack != "" {
+		fmt.Fprintf(&b, "\nStack:\n%s", e.Stack)
+	}
+	return b.String()
+}
+
+// panicError is a panic recovered from a tool.
+type panicError struct {
+	value any
+	stack string
+}
+
+func (p *panicError) Error() string {
+	return fmt.Sprintf("panic: %v", p.value)
+}
+
+// recoverRun calls run, turning a panic into a *panicError so one broken
+// tool cannot take the whole session down.
+func recoverRun(run func() (string, error)) (result string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &panicError{value: r, stack: panicStack(debug.Stack())}
+		}
+	}()
+	return run()
+}
+
+// RunTool executes t with args, recovering from a panic as recoverRun
+// does (for running tools outside the agent loop, such as /run-tool).
+func RunTool(ctx context.Context, t tool.Tool, args json.RawMessage) (string, error) {
+	return recoverRun(func() (string, error) { return t.Execute(ctx, args) })
+}
+
+// panicStack trims a stack captured in a deferred recover to the frames
+// from the panic down, capped at maxStack bytes.
+func panicStack(stack []byte) string {
+	s := string(stack)
+	if i := strings.Index(s, "\npanic("); i >= 0 {
+		// Skip the panic( line and its file line.
+		rest := s[i+1:]
+		for range 2 {
+			if j := strings.IndexByte(rest, '\n'); j >= 0 {
+				rest = rest[j+1:]
+			}
+		}
+		s = rest
+	}
+	s = strings.TrimRight(s, "\n")
+	if len(s) > maxStack {
+		s = s[:maxStack] + "\n..."
+	}
+	return s
+}
@@ -0,0 +1,129 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gavinyap/stormtrooper/internal/llm"
+	"github.com/gavinyap/stormtrooper/internal/permission"
+	"github.com/gavinyap/stormtrooper/internal/tool"
+)
+
+// panickyTool panics whenever it runs.
+type panickyTool struct{ mockTool }
+
+func (p *panickyTool) Execute(context.Context, json.RawMessage) (string, error) {
+	var paths []string
+	return paths[3], nil
+}
+
+func TestNewToolError(t *testing.T) {
+	var syntaxErr error = json.Unmarshal([]byte("{"), &struct{}{})
+	_, notFound := os.ReadFile("/does/not/exist")
+	tests := []struct {
+		err   error
+		class string
+	}{
+		{fmt.Errorf("run: %w", context.DeadlineExceeded), ClassTimeout},
+		{context.Canceled, ClassCanceled},
+		{fmt.Errorf("invalid parameters: %w", syntaxErr), ClassBadArgs},
+		{json.Unmarshal([]byte(`{"n":"x"}`), &struct{ N int }{}), ClassBadArgs},
+		{notFound, ClassNotFound},
+		{fmt.Errorf("open: %w", os.ErrPermission), ClassPermission},
+		{errors.New("tmux: no server running"), ClassError},
+	}
+	for _, tt := range tests {
+		e := NewToolError("read_file", json.RawMessage(`{"file_path":"a.go"}`), tt.err)
+		if e.Class != tt.class {
+			t.Errorf("%v: class = %q, want %q", tt.err, e.Class, tt.class)
+		}
+		if e.Suggestion == "" || e.Stack != "" {
+			t.Errorf("%v: unexpected suggestion %q or stack %q", tt.err, e.Suggestion, e.Stack)
+		}
+	}
+}
+
+func TestToolError_String(t *testing.T) {
+	e := NewToolError("shell_exec", json.RawMessage(`{"command":"go test"}`), context.DeadlineExceeded)
+	want := "Tool error: shell_exec failed (timeout): context deadline exceeded\n" +
+		"Arguments: {\"command\":\"go test\"}\n" +
+		"Suggestion: " + suggestions[ClassTimeout]
+	if got := e.String(); got != want {
+		t.Errorf("String() =\n%s\nwant\n%s", got, want)
+	}
+	if got := e.Error(); got != "timeout: context deadline exceeded" {
+		t.Errorf("Error() = %q", got)
+	}
+}
+
+func TestRecoverRun(t *testing.T) {
+	_, err := RunTool(context.Background(), &panickyTool{}, nil)
+	e := NewToolError("broken", nil, err)
+	if e.Class != ClassPanic || !strings.Contains(e.Message, "index out of range") {
+		t.Fatalf("unexpected error %+v", e)
+	}
+	// The stack starts at the panicking tool, not in the recover machinery.
+	if !strings.HasPrefix(e.Stack, "github.com/gavinyap/stormtrooper/internal/agent.(*panickyTool).Execute") {
+		t.Errorf("unexpected stack:\n%s", e.Stack)
+	}
+	if !strings.Contains(e.String(), "\nStack:\n") {
+		t.Errorf("expected the stack in the result:\n%s", e.String())
+	}
+
+	if out, err := recoverRun(func() (string, error) { return "fine", nil }); out != "fine" || err != nil {
+		t.Errorf("recoverRun = %q, %v", out, err)
+	}
+}
+
+func TestAgent_ToolPanicIsReported(t *testing.T) {
+	var requests []llm.ChatCompletionRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req llm.ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		requests = append(requests, req)
+		w.Header().Set("Content-Type", "text/event-stream")
+		if len(requests) == 1 {
+			w.Write([]byte(sseToolCallResponse("call_1", "broken", `{"input":"x"}`)))
+		} else {
+			w.Write([]byte(sseTextResponse("The tool is broken.")))
+		}
+	}))
+	defer server.Close()
+
+	client := llm.NewClient("test-key")
+	client.SetBaseURL(server.URL)
+	reg := tool.NewRegistry()
+	reg.Register(&panickyTool{mockTool{name: "broken", perm: tool.PermissionAuto}})
+
+	ag := New(Options{
+		Client:     client,
+		Registry:   reg,
+		Permission: permission.NewCheckerWithIO(strings.NewReader(""), &bytes.Buffer{}),
+		Model:      "test-model",
+	})
+	var stderr bytes.Buffer
+	ag.SetOutput(&bytes.Buffer{}, &stderr)
+
+	if err := ag.Send(context.Background(), "Use the tool"); err != nil {
+		t.Fatalf("a panicking tool should not end the turn: %v", err)
+	}
+	if len(requests) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(requests))
+	}
+	msgs := requests[1].Messages
+	result := msgs[len(msgs)-1].Content
+	if !strings.HasPrefix(result, "Tool error: broken failed (panic): runtime error: index out of range") || !strings.Contains(result, "Arguments: {\"input\":\"x\"}") {
+		t.Errorf("unexpected tool result:\n%s", result)
+	}
+	if !strings.Contains(stderr.String(), "[tool:error] broken: panic: runtime error: index out of range") {
+		t.Errorf("expected the error on stderr, got %q", stderr.String())
+	}
+}
@@ -0,0 +1,87 @@
+package agent
+
+import (
+	"fmt"
+
+	"github.com/gavinyap/stormtrooper/internal/config"
+	"github.com/gavinyap/stormtrooper/internal/llm"
+)
+
+// Price is what a model costs, in US dollars per million tokens.
+type Price struct {
+	Prompt     float64
+	Completion float64
+}
+
+// ConfigPrices returns the prices configured by model.
+func ConfigPrices(cfg *config.Config) map[string]Price {
+	prices := make(map[string]Price, len(cfg.Prices))
+	for model, p := range cfg.Prices {
+		prices[model] = Price{Prompt: p.Prompt, Completion: p.Completion}
+	}
+	return prices
+}
+
+// Usage totals the tokens and cost of an agent's model requests, as
+// reported by the provider. Requests whose response carried no usage are
+// not counted.
+type Usage struct {
+	Requests         int
+	PromptTokens     int
+	CompletionTokens int
+	// Cost is in US dollars: the provider's figure where it reports one,
+	// otherwise estimated from the configured price of the model.
+	Cost float64
+	// Unpriced counts requests with neither a reported cost nor a price,
+	// which Cost leaves out.
+	Unpriced int
+}
+
+// FormatCost returns the cost for display, with more decimals for
+// amounts under a cent, or "unknown" when no request could be priced.
+func (u Usage) FormatCost() string {
+	switch {
+	case u.Requests > 0 && u.Unpriced == u.Requests:
+		return "unknown"
+	case u.Cost < 0.01:
+		return fmt.Sprintf("$%.4f", u.Cost)
+	default:
+		return fmt.Sprintf("$%.2f", u.Cost)
+	}
+}
+
+// Usage returns the totals of the requests made so far. It is safe to
+// call while a turn is running.
+func (a *Agent) Usage() Usage {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.usage
+}
+
+// SetPrices replaces the prices, keyed by model, used to estimate the
+// cost of later requests whose provider reports none.
+func (a *Agent) SetPrices(prices map[string]Price) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.prices = prices
+}
+
+// recordUsage adds a request to model, and its reported usage, to the
+// totals.
+func (a *Agent) recordUsage(model string, u *llm.Usage) {
+	if u == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.usage.Requests++
+	a.usage.PromptTokens += u.PromptTokens
+	a.usage.CompletionTokens += u.CompletionTokens
+	if u.Cost != nil {
+		a.usage.Cost += *u.Cost
+	} else if p, ok := a.prices[model]; ok {
+		a.usage.Cost += (float64(u.PromptTokens)*p.Prompt + float64(u.CompletionTokens)*p.Completion) / 1e6
+	} else {
+		a.usage.Unpriced++
+	}
+}
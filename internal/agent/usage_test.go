@@ -0,0 +1,70 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gavinyap/stormtrooper/internal/llm"
+	"github.com/gavinyap/stormtrooper/internal/tool"
+)
+
+func TestAgent_Usage(t *testing.T) {
+	usage := []string{
+		`{"prompt_tokens":1000,"completion_tokens":100,"total_tokens":1100,"cost":0.5}`,
+		`{"prompt_tokens":2000,"completion_tokens":200,"total_tokens":2200}`,
+	}
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: {\"id\":\"1\",\"choices\":[],\"usage\":" + usage[calls%2] + "}\n\n"))
+		w.Write([]byte(sseTextResponse("done")))
+		calls++
+	}))
+	defer server.Close()
+
+	client := llm.NewClient("test-key")
+	client.SetBaseURL(server.URL)
+	ag := New(Options{
+		Client:   client,
+		Registry: tool.NewRegistry(),
+		Model:    "priced",
+		Prices:   map[string]Price{"priced": {Prompt: 1, Completion: 10}},
+	})
+	ag.SetOutput(&bytes.Buffer{}, &bytes.Buffer{})
+
+	if got := ag.Usage().FormatCost(); got != "$0.0000" {
+		t.Errorf("cost before any request = %q", got)
+	}
+	for i := 0; i < 2; i++ {
+		if err := ag.Send(context.Background(), "go"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The reported cost is used as is; the second is priced at
+	// 2000*1/1e6 + 200*10/1e6.
+	u := ag.Usage()
+	if u.Requests != 2 || u.PromptTokens != 3000 || u.CompletionTokens != 300 || u.Unpriced != 0 {
+		t.Errorf("usage = %+v", u)
+	}
+	if got := u.FormatCost(); got != "$0.50" {
+		t.Errorf("cost = %q, want $0.50", got)
+	}
+
+	ag.SetModel("unpriced")
+	ag.SetPrices(nil)
+	if err := ag.Send(context.Background(), "go"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ag.Send(context.Background(), "go"); err != nil {
+		t.Fatal(err)
+	}
+	if u := ag.Usage(); u.Requests != 4 || u.Unpriced != 1 || u.Cost != 1.004 {
+		t.Errorf("usage after switching models = %+v", u)
+	}
+	if got := (Usage{Requests: 2, Unpriced: 2}).FormatCost(); got != "unknown" {
+		t.Errorf("cost with no prices = %q, want unknown", got)
+	}
+}
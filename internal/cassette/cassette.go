@@ -0,0 +1,145 @@
+// Package cassette records LLM request/response pairs and tool results to
+// a file, and replays them later without touching the network or the
+// filesystem. Recorded sessions make agent behaviour reproducible: users
+// can regression-test their prompts and configs, and maintainers can
+// replay a reported issue exactly.
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+const formatVersion = 1
+
+// Interaction is one recorded LLM HTTP exchange.
+type Interaction struct {
+	Request  json.RawMessage `json:"request"`
+	Status   int             `json:"status"`
+	Response string          `json:"response"`
+
+	used bool
+}
+
+// ToolResult is one recorded tool execution.
+type ToolResult struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+	Result    string `json:"result"`
+	Error     string `json:"error,omitempty"`
+
+	used bool
+}
+
+// Cassette holds a recorded session. A cassette is either recording
+// (created with Record) or replaying (opened with Load).
+type Cassette struct {
+	Version      int            `json:"version"`
+	Interactions []*Interaction `json:"interactions"`
+	Tools        []*ToolResult  `json:"tools"`
+
+	mu        sync.Mutex
+	path      string
+	replaying bool
+}
+
+// Record creates an empty cassette that is written to path after every
+// recorded exchange, so a crash mid-session still leaves a usable file.
+func Record(path string) (*Cassette, error) {
+	c := &Cassette{Version: formatVersion, path: path}
+	if err := c.save(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Load opens a recorded cassette for replay.
+func Load(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &Cassette{path: path, replaying: true}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("invalid cassette %s: %w", path, err)
+	}
+	if c.Version != formatVersion {
+		return nil, fmt.Errorf("cassette %s: unsupported version %d", path, c.Version)
+	}
+	return c, nil
+}
+
+// Replaying reports whether the cassette serves recorded data.
+func (c *Cassette) Replaying() bool {
+	return c.replaying
+}
+
+func (c *Cassette) addInteraction(req []byte, status int, resp []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Interactions = append(c.Interactions, &Interaction{
+		Request:  compactJSON(req),
+		Status:   status,
+		Response: string(resp),
+	})
+	c.save()
+}
+
+func (c *Cassette) addToolResult(r *ToolResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Tools = append(c.Tools, r)
+	c.save()
+}
+
+// takeInteraction returns the first unused interaction whose request
+// matches req. Matching by content rather than position lets replay skip
+// exchanges that no longer happen, such as a replayed sub-agent's calls.
+func (c *Cassette) takeInteraction(req []byte) *Interaction {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	want := compactJSON(req)
+	for _, it := range c.Interactions {
+		if !it.used && bytes.Equal(compactJSON(it.Request), want) {
+			it.used = true
+			return it
+		}
+	}
+	return nil
+}
+
+// takeToolResult returns the first unused result for the same call.
+func (c *Cassette) takeToolResult(name, args string) *ToolResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, r := range c.Tools {
+		if !r.used && r.Name == name && r.Arguments == args {
+			r.used = true
+			return r
+		}
+	}
+	return nil
+}
+
+// save writes the cassette to disk. Callers must hold c.mu (or own c
+// exclusively, as in Record).
+func (c *Cassette) save() error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(c.path, data, 0644)
+}
+
+// compactJSON normalizes whitespace so equivalent requests compare equal.
+// Invalid JSON is returned unchanged.
+func compactJSON(data []byte) []byte {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, data); err != nil {
+		return data
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,268 @@
+package cassette
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gavinyap/stormtrooper/internal/llm"
+	"github.com/gavinyap/stormtrooper/internal/tool"
+)
+
+const sseHello = "data: {\"id\":\"1\",\"choices\":[{\"index\":0,\"delta\":{\"role\":\"assistant\",\"content\":\"Hello\"},\"finish_reason\":null}]}\n\n" +
+	"data: {\"id\":\"1\",\"choices\":[{\"index\":0,\"delta\":{},\"finish_reason\":\"stop\"}]}\n\n" +
+	"data: [DONE]\n"
+
+func chatRequest(content string) llm.ChatCompletionRequest {
+	return llm.ChatCompletionRequest{
+		Model:    "test-model",
+		Messages: []llm.Message{{Role: "user", Content: content}},
+	}
+}
+
+func TestRecordThenReplay(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(sseHello))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "session.json")
+
+	// Record.
+	rec, err := Record(path)
+	if err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	client := llm.NewClient("test-key")
+	client.SetBaseURL(server.URL)
+	client.SetTransport(rec.Transport(client.Transport()))
+
+	msg, err := client.ChatCompletionStream(context.Background(), chatRequest("hi"), nil)
+	if err != nil {
+		t.Fatalf("record request: %v", err)
+	}
+	if msg.Content != "Hello" {
+		t.Fatalf("recorded content = %q", msg.Content)
+	}
+
+	// Replay against a dead server: nothing may reach the network.
+	server.Close()
+	tape, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if !tape.Replaying() {
+		t.Error("loaded cassette should be replaying")
+	}
+	replayClient := llm.NewClient("")
+	replayClient.SetBaseURL(server.URL)
+	replayClient.SetTransport(tape.Transport(replayClient.Transport()))
+
+	msg, err = replayClient.ChatCompletionStream(context.Background(), chatRequest("hi"), nil)
+	if err != nil {
+		t.Fatalf("replay request: %v", err)
+	}
+	if msg.Content != "Hello" {
+		t.Errorf("replayed content = %q, want Hello", msg.Content)
+	}
+	if hits.Load() != 1 {
+		t.Errorf("server hit %d times, want 1", hits.Load())
+	}
+}
+
+func TestReplay_MismatchedRequest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.json")
+	body, _ := json.Marshal(chatRequest("hi"))
+	c := &Cassette{Version: formatVersion, path: path}
+	c.addInteraction(body, http.StatusOK, []byte(sseHello))
+
+	tape, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	client := llm.NewClient("")
+	client.SetTransport(tape.Transport(nil))
+
+	_, err = client.ChatCompletionStream(context.Background(), chatRequest("something else"), nil)
+	if err == nil || !strings.Contains(err.Error(), "no recorded response") {
+		t.Errorf("expected no-recorded-response error, got %v", err)
+	}
+}
+
+func TestReplay_EachInteractionUsedOnce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.json")
+	body := []byte(`{"model":"m"}`)
+	c := &Cassette{Version: formatVersion, path: path}
+	c.addInteraction(body, http.StatusOK, []byte("first"))
+	c.addInteraction(body, http.StatusOK, []byte("second"))
+
+	tape, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	for _, want := range []string{"first", "second"} {
+		it := tape.takeInteraction([]byte(`{ "model": "m" }`))
+		if it == nil || it.Response != want {
+			t.Fatalf("got %+v, want response %q", it, want)
+		}
+	}
+	if tape.takeInteraction(body) != nil {
+		t.Error("expected cassette to be exhausted")
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	dir := t.TempDir()
+
+	if _, err := Load(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("expected error for missing file")
+	}
+
+	bad := filepath.Join(dir, "bad.json")
+	os.WriteFile(bad, []byte("not json"), 0644)
+	if _, err := Load(bad); err == nil {
+		t.Error("expected error for invalid JSON")
+	}
+
+	future := filepath.Join(dir, "future.json")
+	os.WriteFile(future, []byte(`{"version": 99}`), 0644)
+	if _, err := Load(future); err == nil || !strings.Contains(err.Error(), "unsupported version") {
+		t.Errorf("expected unsupported version error, got %v", err)
+	}
+}
+
+// countingTool counts executions and echoes its arguments.
+type countingTool struct {
+	runs int
+}
+
+func (t *countingTool) Name() string                     { return "echo" }
+func (t *countingTool) Description() string              { return "Echo" }
+func (t *countingTool) Schema() json.RawMessage          { return json.RawMessage(`{}`) }
+func (t *countingTool) Permission() tool.PermissionLevel { return tool.PermissionPrompt }
+func (t *countingTool) Preview(params json.RawMessage) string {
+	return "echo " + string(params)
+}
+func (t *countingTool) Execute(ctx context.Context, params json.RawMessage) (string, error) {
+	t.runs++
+	return "ran " + string(params), nil
+}
+
+// hunkTool is a countingTool whose changes can be reviewed by hunk.
+type hunkTool struct {
+	countingTool
+	written string
+}
+
+func (t *hunkTool) Propose(params json.RawMessage) (path, before, after string, err error) {
+	return "f.txt", "a\n", "b\n", nil
+}
+func (t *hunkTool) WriteProposed(path, content string) error {
+	t.written = content
+	return nil
+}
+
+func TestWrapRegistry_KeepsProposer(t *testing.T) {
+	rec, err := Record(filepath.Join(t.TempDir(), "session.json"))
+	if err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	real := &hunkTool{}
+	reg := tool.NewRegistry()
+	reg.Register(real)
+
+	p, ok := rec.WrapRegistry(reg).Get("echo").(tool.Proposer)
+	if !ok {
+		t.Fatal("recording should keep the tool's per-hunk review")
+	}
+	if path, _, after, _ := p.Propose(json.RawMessage(`{}`)); path != "f.txt" || after != "b\n" {
+		t.Errorf("Propose = %q, %q", path, after)
+	}
+	if err := p.WriteProposed("f.txt", "c\n"); err != nil || real.written != "c\n" {
+		t.Errorf("WriteProposed wrote %q, err %v", real.written, err)
+	}
+}
+
+// commandTool is a countingTool that runs commands.
+type commandTool struct {
+	countingTool
+}
+
+func (t *commandTool) Command(params json.RawMessage) string { return "echo " + string(params) }
+
+func TestWrapRegistry_KeepsCommand(t *testing.T) {
+	rec, err := Record(filepath.Join(t.TempDir(), "session.json"))
+	if err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	reg := tool.NewRegistry()
+	reg.Register(&commandTool{})
+
+	if got := tool.CommandFor(rec.WrapRegistry(reg).Get("echo"), json.RawMessage(`{}`)); got != "echo {}" {
+		t.Errorf("CommandFor = %q, want the wrapped tool's command", got)
+	}
+}
+
+func TestWrapRegistry_RecordThenReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.json")
+	args := json.RawMessage(`{"x":1}`)
+
+	// Record.
+	rec, err := Record(path)
+	if err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	real := &countingTool{}
+	reg := tool.NewRegistry()
+	reg.Register(real)
+	wrapped := rec.WrapRegistry(reg)
+
+	recTool := wrapped.Get("echo")
+	if recTool.Permission() != tool.PermissionPrompt {
+		t.Error("recording should keep the tool's permission level")
+	}
+	if p, ok := recTool.(tool.Previewer); !ok || p.Preview(args) != `echo {"x":1}` {
+		t.Error("recording should keep the tool's preview")
+	}
+	if out, _ := recTool.Execute(context.Background(), args); out != `ran {"x":1}` {
+		t.Fatalf("recorded result = %q", out)
+	}
+
+	// Replay.
+	tape, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	fresh := &countingTool{}
+	reg = tool.NewRegistry()
+	reg.Register(fresh)
+	replayTool := tape.WrapRegistry(reg).Get("echo")
+
+	if replayTool.Permission() != tool.PermissionAuto {
+		t.Error("replayed tools have no side effects and should not prompt")
+	}
+	out, err := replayTool.Execute(context.Background(), args)
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	if out != `ran {"x":1}` {
+		t.Errorf("replayed result = %q", out)
+	}
+	if fresh.runs != 0 {
+		t.Error("replay must not execute the real tool")
+	}
+
+	out, _ = replayTool.Execute(context.Background(), json.RawMessage(`{"x":2}`))
+	if !strings.HasPrefix(out, "Error: cassette has no recorded result") {
+		t.Errorf("expected missing-result error, got %q", out)
+	}
+}
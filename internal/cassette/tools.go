@@ -0,0 +1,120 @@
+package cassette
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/gavinyap/stormtrooper/internal/tool"
+)
+
+// WrapRegistry returns a new registry whose tools record their results
+// into the cassette, or, when replaying, return recorded results without
+// running at all. Replayed tools never prompt for permission since they
+// have no side effects.
+func (c *Cassette) WrapRegistry(reg *tool.Registry) *tool.Registry {
+	wrapped := tool.NewRegistry()
+	wrapped.SetGuard(reg.Guard())
+	for _, def := range reg.Definitions() {
+		name := def.Function.Name
+		t := reg.Get(name)
+		if t == nil {
+			continue // unregistered since Definitions
+		}
+		rt := &recordedTool{Tool: t, c: c}
+		p, previews := t.(tool.Previewer)
+		pr, proposes := t.(tool.Proposer)
+		switch {
+		case previews && proposes:
+			wrapped.Replace(name, &proposingTool{previewingTool: &previewingTool{recordedTool: rt, previewer: p}, proposer: pr})
+		case previews:
+			wrapped.Replace(name, &previewingTool{recordedTool: rt, previewer: p})
+		default:
+			wrapped.Replace(name, rt)
+		}
+	}
+	return wrapped
+}
+
+// recordedTool wraps a tool for recording or replay.
+type recordedTool struct {
+	tool.Tool
+	c *Cassette
+}
+
+func (t *recordedTool) Permission() tool.PermissionLevel {
+	if t.c.replaying {
+		return tool.PermissionAuto
+	}
+	return t.Tool.Permission()
+}
+
+func (t *recordedTool) PermissionFor(params json.RawMessage) tool.PermissionLevel {
+	if t.c.replaying {
+		return tool.PermissionAuto
+	}
+	return tool.PermissionFor(t.Tool, params)
+}
+
+// Command passes through the invocation the wrapped tool runs, so rules
+// that allow a command for good still match.
+func (t *recordedTool) Command(params json.RawMessage) string {
+	return tool.CommandFor(t.Tool, params)
+}
+
+// Capabilities passes through the wrapped tool's capabilities, so tool
+// profiles treat it the same way.
+func (t *recordedTool) Capabilities() []tool.Capability {
+	return tool.CapabilitiesOf(t.Tool)
+}
+
+func (t *recordedTool) Execute(ctx context.Context, params json.RawMessage) (string, error) {
+	name := t.Tool.Name()
+	args := string(params)
+
+	if t.c.replaying {
+		r := t.c.takeToolResult(name, args)
+		if r == nil {
+			return fmt.Sprintf("Error: cassette has no recorded result for %s(%s)", name, args), nil
+		}
+		if r.Error != "" {
+			return r.Result, errors.New(r.Error)
+		}
+		return r.Result, nil
+	}
+
+	result, err := t.Tool.Execute(ctx, params)
+	rec := &ToolResult{Name: name, Arguments: args, Result: result}
+	if err != nil {
+		rec.Error = err.Error()
+	}
+	t.c.addToolResult(rec)
+	return result, err
+}
+
+// previewingTool keeps the Previewer implementation of the wrapped tool
+// visible to the agent's permission prompt.
+type previewingTool struct {
+	*recordedTool
+	previewer tool.Previewer
+}
+
+func (t *previewingTool) Preview(params json.RawMessage) string {
+	return t.previewer.Preview(params)
+}
+
+// proposingTool also keeps the Proposer implementation visible, so the
+// user can still review a change hunk by hunk.
+type proposingTool struct {
+	*previewingTool
+	proposer tool.Proposer
+}
+
+func (t *proposingTool) Propose(params json.RawMessage) (path, before, after string, err error) {
+	return t.proposer.Propose(params)
+}
+
+func (t *proposingTool) WriteProposed(path, content string) error {
+	return t.proposer.WriteProposed(path, content)
+}
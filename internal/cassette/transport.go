@@ -0,0 +1,110 @@
+package cassette
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Transport returns an http.RoundTripper for the LLM client. When
+// recording, requests go to next and both sides are captured; when
+// replaying, responses come from the cassette and next is never used.
+func (c *Cassette) Transport(next http.RoundTripper) http.RoundTripper {
+	if c.replaying {
+		return &replayTransport{c: c}
+	}
+	return &recordTransport{c: c, next: next}
+}
+
+type recordTransport struct {
+	c    *Cassette
+	next http.RoundTripper
+}
+
+func (t *recordTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Tee the body so streaming still reaches the caller live; the
+	// interaction is stored once the caller closes it.
+	status := resp.StatusCode
+	resp.Body = &teeBody{
+		rc: resp.Body,
+		onClose: func(data []byte) {
+			t.c.addInteraction(body, status, data)
+		},
+	}
+	return resp, nil
+}
+
+type replayTransport struct {
+	c *Cassette
+}
+
+func (t *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	it := t.c.takeInteraction(body)
+	if it == nil {
+		return nil, fmt.Errorf("cassette: no recorded response for this request to %s (prompt, config, or tools changed since recording?)", req.URL.Path)
+	}
+
+	contentType := "application/json"
+	if strings.HasPrefix(it.Response, "data:") {
+		contentType = "text/event-stream"
+	}
+	return &http.Response{
+		StatusCode: it.Status,
+		Status:     fmt.Sprintf("%d %s", it.Status, http.StatusText(it.Status)),
+		Header:     http.Header{"Content-Type": []string{contentType}},
+		Body:       io.NopCloser(strings.NewReader(it.Response)),
+		Request:    req,
+	}, nil
+}
+
+// readBody reads and restores the request body.
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("cassette: read request body: %w", err)
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// teeBody copies everything read from rc and hands it to onClose once.
+type teeBody struct {
+	rc      io.ReadCloser
+	buf     bytes.Buffer
+	once    sync.Once
+	onClose func([]byte)
+}
+
+func (b *teeBody) Read(p []byte) (int, error) {
+	n, err := b.rc.Read(p)
+	b.buf.Write(p[:n])
+	return n, err
+}
+
+func (b *teeBody) Close() error {
+	err := b.rc.Close()
+	b.once.Do(func() { b.onClose(b.buf.Bytes()) })
+	return err
+}
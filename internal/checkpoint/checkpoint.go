@@ -0,0 +1,171 @@
+// Package checkpoint snapshots the conversation and the files the agent
+// changed at the end of every turn, so a turn can be undone with /rewind.
+package checkpoint
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/gavinyap/stormtrooper/internal/llm"
+	"github.com/gavinyap/stormtrooper/internal/tool"
+)
+
+// Checkpoint is the state at the end of a turn.
+type Checkpoint struct {
+	// Messages is the conversation as it stood.
+	Messages []llm.Message
+	// Files maps each file changed during the turn to its contents before
+	// the turn; nil means the file did not exist.
+	Files map[string][]byte
+}
+
+// Tracker records checkpoints. Files are only tracked when written
+// through the FileSystem returned by FS; changes made by shell commands
+// cannot be undone. A Tracker is safe for concurrent use.
+type Tracker struct {
+	mu      sync.Mutex
+	fs      tool.FileSystem
+	base    []llm.Message // conversation before the first turn
+	turns   []Checkpoint
+	pending map[string][]byte // originals of files changed this turn
+}
+
+// New returns a tracker for files in fsys; nil means the host.
+func New(fsys tool.FileSystem) *Tracker {
+	if fsys == nil {
+		fsys = tool.LocalFS{}
+	}
+	return &Tracker{fs: fsys, pending: map[string][]byte{}}
+}
+
+// FS returns a FileSystem that records each file's original contents the
+// first time it is written in a turn.
+func (t *Tracker) FS() tool.FileSystem {
+	return trackingFS{t}
+}
+
+// Start records the conversation before the first turn, the state a
+// rewind past every turn returns to.
+func (t *Tracker) Start(messages []llm.Message) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.base = append([]llm.Message(nil), messages...)
+}
+
+// Commit ends the current turn with the given conversation.
+func (t *Tracker) Commit(messages []llm.Message) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.turns = append(t.turns, Checkpoint{
+		Messages: append([]llm.Message(nil), messages...),
+		Files:    t.pending,
+	})
+	t.pending = map[string][]byte{}
+}
+
+// Turns returns how many checkpoints can be rewound.
+func (t *Tracker) Turns() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.turns)
+}
+
+// Rewind undoes the last n turns: every file they changed is restored to
+// its contents before them, and the conversation to return to is
+// returned along with the restored files, sorted.
+func (t *Tracker) Rewind(n int) ([]llm.Message, []string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if n < 1 {
+		return nil, nil, fmt.Errorf("cannot rewind %d turns", n)
+	}
+	if n > len(t.turns) {
+		return nil, nil, fmt.Errorf("only %d turn(s) to rewind", len(t.turns))
+	}
+
+	// Walk back from the newest change so that each file ends up with the
+	// contents it had before the earliest rewound turn.
+	originals := map[string][]byte{}
+	undo := append(append([]Checkpoint(nil), t.turns[len(t.turns)-n:]...), Checkpoint{Files: t.pending})
+	for i := len(undo) - 1; i >= 0; i-- {
+		for path, data := range undo[i].Files {
+			originals[path] = data
+		}
+	}
+	var restored []string
+	for path, data := range originals {
+		var err error
+		if data == nil {
+			err = t.remove(path)
+		} else {
+			err = t.fs.WriteFile(path, data, 0644)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("restoring %s: %w", path, err)
+		}
+		restored = append(restored, path)
+	}
+	sort.Strings(restored)
+
+	t.turns = t.turns[:len(t.turns)-n]
+	t.pending = map[string][]byte{}
+	messages := t.base
+	if len(t.turns) > 0 {
+		messages = t.turns[len(t.turns)-1].Messages
+	}
+	return append([]llm.Message(nil), messages...), restored, nil
+}
+
+// remove deletes a file the rewound turns created. Only the host
+// filesystem and file systems with a Remove method support this.
+func (t *Tracker) remove(path string) error {
+	var err error
+	switch fsys := t.fs.(type) {
+	case interface{ Remove(string) error }:
+		err = fsys.Remove(path)
+	case tool.LocalFS:
+		err = os.Remove(path)
+	default:
+		return fmt.Errorf("cannot delete files on this file system")
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// record saves path's current contents if this is its first write in the
+// turn.
+func (t *Tracker) record(path string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.pending[path]; ok {
+		return
+	}
+	data, err := t.fs.ReadFile(path)
+	if err != nil {
+		data = nil
+	} else if data == nil {
+		data = []byte{}
+	}
+	t.pending[path] = data
+}
+
+// trackingFS records originals before delegating writes.
+type trackingFS struct{ t *Tracker }
+
+func (f trackingFS) Stat(name string) (fs.FileInfo, error) { return f.t.fs.Stat(name) }
+func (f trackingFS) ReadFile(name string) ([]byte, error)  { return f.t.fs.ReadFile(name) }
+func (f trackingFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	f.t.record(name)
+	return f.t.fs.WriteFile(name, data, perm)
+}
+func (f trackingFS) MkdirAll(path string, perm fs.FileMode) error { return f.t.fs.MkdirAll(path, perm) }
+func (f trackingFS) Remove(name string) error {
+	f.t.record(name)
+	return f.t.remove(name)
+}
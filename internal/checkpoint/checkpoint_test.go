@@ -0,0 +1,120 @@
+package checkpoint
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/gavinyap/stormtrooper/internal/llm"
+)
+
+func msgs(contents ...string) []llm.Message {
+	var m []llm.Message
+	for _, c := range contents {
+		m = append(m, llm.Message{Role: "user", Content: c})
+	}
+	return m
+}
+
+func TestRewindRestoresFilesAndConversation(t *testing.T) {
+	dir := t.TempDir()
+	edited := filepath.Join(dir, "main.go")
+	created := filepath.Join(dir, "new.go")
+	os.WriteFile(edited, []byte("v0"), 0644)
+
+	tr := New(nil)
+	fsys := tr.FS()
+	tr.Start(msgs("system"))
+
+	// Turn 1 edits main.go twice; turn 2 edits it again and creates new.go.
+	fsys.WriteFile(edited, []byte("v1"), 0644)
+	fsys.WriteFile(edited, []byte("v1b"), 0644)
+	tr.Commit(msgs("system", "one"))
+	fsys.WriteFile(edited, []byte("v2"), 0644)
+	fsys.WriteFile(created, []byte("new"), 0644)
+	tr.Commit(msgs("system", "one", "two"))
+
+	history, files, err := tr.Rewind(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(history, msgs("system", "one")) {
+		t.Errorf("history = %+v", history)
+	}
+	if !reflect.DeepEqual(files, []string{edited, created}) && !reflect.DeepEqual(files, []string{created, edited}) {
+		t.Errorf("restored = %v", files)
+	}
+	if data, _ := os.ReadFile(edited); string(data) != "v1b" {
+		t.Errorf("main.go = %q, want the end of turn 1", data)
+	}
+	if _, err := os.Stat(created); !os.IsNotExist(err) {
+		t.Errorf("new.go should have been removed, stat err = %v", err)
+	}
+
+	history, _, err = tr.Rewind(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(history, msgs("system")) {
+		t.Errorf("history after rewinding everything = %+v", history)
+	}
+	if data, _ := os.ReadFile(edited); string(data) != "v0" {
+		t.Errorf("main.go = %q, want the original", data)
+	}
+	if tr.Turns() != 0 {
+		t.Errorf("turns = %d", tr.Turns())
+	}
+}
+
+func TestRewindRestoresRemovedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "old.go")
+	os.WriteFile(path, []byte("v0"), 0644)
+
+	tr := New(nil)
+	tr.Start(msgs("system"))
+	if err := tr.FS().(interface{ Remove(string) error }).Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	tr.Commit(msgs("system", "one"))
+
+	if _, _, err := tr.Rewind(1); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "v0" {
+		t.Errorf("old.go = %q, want it restored", data)
+	}
+}
+
+func TestRewindSeveralTurns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "f")
+	os.WriteFile(path, []byte("orig"), 0644)
+	tr := New(nil)
+	for _, v := range []string{"a", "b", "c"} {
+		tr.FS().WriteFile(path, []byte(v), 0644)
+		tr.Commit(msgs(v))
+	}
+	history, _, err := tr.Rewind(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(history, msgs("a")) {
+		t.Errorf("history = %+v", history)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "a" {
+		t.Errorf("file = %q", data)
+	}
+}
+
+func TestRewindBounds(t *testing.T) {
+	tr := New(nil)
+	tr.Commit(msgs("one"))
+	for _, n := range []int{0, -1, 2} {
+		if _, _, err := tr.Rewind(n); err == nil {
+			t.Errorf("Rewind(%d) should fail", n)
+		}
+	}
+	if tr.Turns() != 1 {
+		t.Errorf("a failed rewind changed the checkpoints: %d turns", tr.Turns())
+	}
+}
@@ -0,0 +1,26 @@
+package command
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gavinyap/stormtrooper/internal/agent"
+)
+
+// Clear returns the /clear command, which starts the conversation over,
+// keeping only the system prompt. /rewind brings it back.
+func Clear(ag *agent.Agent) Command {
+	return Command{
+		Name:   "clear",
+		Usage:  "/clear",
+		Help:   "Start the conversation over; /rewind undoes it",
+		Reload: true,
+		Run: func(_ context.Context, args []string) (string, error) {
+			if len(args) > 0 {
+				return "", fmt.Errorf("usage: /clear")
+			}
+			ag.Restore(nil)
+			return "Cleared the conversation.", nil
+		},
+	}
+}
@@ -0,0 +1,29 @@
+package command
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gavinyap/stormtrooper/internal/agent"
+	"github.com/gavinyap/stormtrooper/internal/llm"
+	"github.com/gavinyap/stormtrooper/internal/tool"
+)
+
+func TestClearCommand(t *testing.T) {
+	ag := agent.New(agent.Options{Registry: tool.NewRegistry(), Model: "test-model", SystemPrompt: "system"})
+	ag.Restore([]llm.Message{{Role: "user", Content: "hi"}, {Role: "assistant", Content: "hello"}})
+
+	cmd := Clear(ag)
+	if !cmd.Reload {
+		t.Error("/clear should redraw the conversation")
+	}
+	if _, err := cmd.Run(context.Background(), []string{"all"}); err == nil {
+		t.Error("expected a usage error")
+	}
+	if _, err := cmd.Run(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+	if h := ag.History(); len(h) != 1 || h[0].Role != "system" {
+		t.Errorf("history after /clear = %+v, want only the system prompt", h)
+	}
+}
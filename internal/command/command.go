@@ -0,0 +1,208 @@
+// Package command implements the slash commands (such as /rewind) that
+// the REPL and the TUI handle themselves instead of sending to the agent.
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+// Command is a slash command.
+type Command struct {
+	// Name is the command without its slash, e.g. "rewind".
+	Name string
+	// Usage shows the arguments, e.g. "/rewind [n]".
+	Usage string
+	// Help is a one-line description.
+	Help string
+	// Run executes the command and returns the text to show the user.
+	Run func(ctx context.Context, args []string) (string, error)
+	// Exec, set instead of Run, returns an interactive program such as an
+	// editor, which the front end runs in the foreground.
+	Exec func(args []string) (*exec.Cmd, error)
+	// Form, if set, is tried before Run. When it returns a form, the front
+	// end collects the arguments with it; when it returns nil, Run runs.
+	Form func(args []string) (*Form, error)
+	// Pick, if set, is tried before Run like Form. When it returns a
+	// picker, the front end lets the user choose one of its items.
+	Pick func(ctx context.Context, args []string) (*Picker, error)
+	// Reload reports that the command replaces the conversation, so the
+	// front end should redraw it afterwards.
+	Reload bool
+	// Exit reports that the front end should quit after showing any
+	// output.
+	Exit bool
+	// Long reports that the command can take a while, such as waiting for
+	// the model, so a full-screen front end runs it in the background
+	// and lets the user stop it.
+	Long bool
+}
+
+// Form describes arguments for the front end to collect before calling
+// Submit with them.
+type Form struct {
+	// Title names what the form is for, e.g. the tool being run.
+	Title string
+	// Schema is the JSON schema of the arguments: an object whose
+	// properties become the form's fields.
+	Schema json.RawMessage
+	// Submit is called with the collected arguments as a JSON object and
+	// returns the text to show the user.
+	Submit func(ctx context.Context, args json.RawMessage) (string, error)
+}
+
+// Picker is a list for the front end to show, calling Choose with the
+// index of the item the user picks.
+type Picker struct {
+	// Title says what is being picked, e.g. "Resume a session".
+	Title string
+	// Items are the lines to choose from.
+	Items []string
+	// Filter lets the user type to narrow a long list of items.
+	Filter bool
+	// Choose is called with the chosen index and returns the text to
+	// show the user.
+	Choose func(ctx context.Context, i int) (string, error)
+}
+
+// Result is the outcome of a slash command.
+type Result struct {
+	// Output is the text to show the user.
+	Output string
+	// Exec, if set, is a program the caller must run with the terminal,
+	// suspending any full-screen UI until it exits.
+	Exec *exec.Cmd
+	// Form, if set, collects arguments for the command; see Command.Form.
+	Form *Form
+	// Picker, if set, lists choices for the command; see Command.Pick.
+	Picker *Picker
+	// Reload is copied from Command.Reload.
+	Reload bool
+	// Exit is copied from Command.Exit.
+	Exit bool
+}
+
+// Dispatcher routes slash commands to their implementations.
+type Dispatcher struct {
+	commands map[string]Command
+}
+
+// NewDispatcher returns a dispatcher with only the built-in /help and
+// /exit.
+func NewDispatcher() *Dispatcher {
+	d := &Dispatcher{commands: map[string]Command{}}
+	d.Register(Command{
+		Name:  "help",
+		Usage: "/help",
+		Help:  "List the available commands",
+		Run: func(context.Context, []string) (string, error) {
+			return d.help(), nil
+		},
+	})
+	d.Register(Command{
+		Name:  "exit",
+		Usage: "/exit",
+		Help:  "Quit stormtrooper",
+		Exit:  true,
+		Run: func(context.Context, []string) (string, error) {
+			return "", nil
+		},
+	})
+	return d
+}
+
+// Register adds a command, replacing any command of the same name.
+func (d *Dispatcher) Register(c Command) {
+	d.commands[c.Name] = c
+}
+
+// Parse splits input into a command name and its arguments. ok is false
+// when the input is not a slash command.
+func Parse(input string) (name string, args []string, ok bool) {
+	input = strings.TrimSpace(input)
+	if !strings.HasPrefix(input, "/") || strings.Contains(input, "\n") {
+		return "", nil, false
+	}
+	fields := strings.Fields(input[1:])
+	if len(fields) == 0 {
+		return "", nil, false
+	}
+	return fields[0], fields[1:], true
+}
+
+// Dispatch runs input if it names a registered command. handled is false
+// when the input should go to the agent instead, including slash-prefixed
+// text that is not a known command, such as a path.
+func (d *Dispatcher) Dispatch(ctx context.Context, input string) (res Result, handled bool, err error) {
+	name, args, ok := Parse(input)
+	if !ok {
+		return Result{}, false, nil
+	}
+	c, ok := d.commands[name]
+	if !ok {
+		return Result{}, false, nil
+	}
+	res.Reload, res.Exit = c.Reload, c.Exit
+	if c.Form != nil {
+		if res.Form, err = c.Form(args); err != nil || res.Form != nil {
+			return res, true, err
+		}
+	}
+	if c.Pick != nil {
+		if res.Picker, err = c.Pick(ctx, args); err != nil || res.Picker != nil {
+			return res, true, err
+		}
+	}
+	if c.Exec != nil {
+		res.Exec, err = c.Exec(args)
+	} else {
+		res.Output, err = c.Run(ctx, args)
+	}
+	return res, true, err
+}
+
+// Long reports whether input names a registered command marked Long.
+func (d *Dispatcher) Long(input string) bool {
+	name, _, ok := Parse(input)
+	return ok && d.commands[name].Long
+}
+
+// Complete returns the commands, with their slash, whose names start
+// with the partial command name in input, sorted. It returns nil when
+// input is not the start of a command name, such as text or a command
+// already followed by arguments.
+func (d *Dispatcher) Complete(input string) []string {
+	if !strings.HasPrefix(input, "/") || strings.ContainsAny(input, " \t\n") {
+		return nil
+	}
+	var matches []string
+	for _, name := range d.names() {
+		if strings.HasPrefix(name, input[1:]) {
+			matches = append(matches, "/"+name)
+		}
+	}
+	return matches
+}
+
+// names returns the names of the registered commands, sorted.
+func (d *Dispatcher) names() []string {
+	names := make([]string, 0, len(d.commands))
+	for name := range d.commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (d *Dispatcher) help() string {
+	var b strings.Builder
+	for _, name := range d.names() {
+		c := d.commands[name]
+		fmt.Fprintf(&b, "%-16s %s\n", c.Usage, c.Help)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
@@ -0,0 +1,138 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input string
+		name  string
+		args  []string
+		ok    bool
+	}{
+		{"/rewind", "rewind", []string{}, true},
+		{"  /rewind 3 ", "rewind", []string{"3"}, true},
+		{"rewind", "", nil, false},
+		{"/", "", nil, false},
+		{"/rewind\nand more", "", nil, false},
+	}
+	for _, tt := range tests {
+		name, args, ok := Parse(tt.input)
+		if name != tt.name || ok != tt.ok || (ok && !reflect.DeepEqual(args, tt.args)) {
+			t.Errorf("Parse(%q) = %q, %q, %v", tt.input, name, args, ok)
+		}
+	}
+}
+
+func TestDispatch(t *testing.T) {
+	d := NewDispatcher()
+	var got []string
+	d.Register(Command{
+		Name:  "echo",
+		Usage: "/echo <text>",
+		Help:  "Repeat the text",
+		Run: func(_ context.Context, args []string) (string, error) {
+			got = args
+			if len(args) == 0 {
+				return "", errors.New("nothing to echo")
+			}
+			return strings.Join(args, " "), nil
+		},
+	})
+
+	res, handled, err := d.Dispatch(context.Background(), "/echo hello there")
+	if !handled || err != nil || res.Output != "hello there" || res.Exec != nil {
+		t.Errorf("Dispatch = %+v, %v, %v", res, handled, err)
+	}
+	if !reflect.DeepEqual(got, []string{"hello", "there"}) {
+		t.Errorf("args = %q", got)
+	}
+	if _, handled, err := d.Dispatch(context.Background(), "/echo"); !handled || err == nil {
+		t.Errorf("errors should be reported as handled, got %v, %v", handled, err)
+	}
+
+	// Unknown commands and paths go to the agent.
+	for _, input := range []string{"/usr/bin/env is missing", "explain /echo", "/unknown"} {
+		if _, handled, _ := d.Dispatch(context.Background(), input); handled {
+			t.Errorf("Dispatch(%q) should not be handled", input)
+		}
+	}
+
+	res, _, _ = d.Dispatch(context.Background(), "/help")
+	if !strings.Contains(res.Output, "/echo <text>") || !strings.Contains(res.Output, "List the available commands") {
+		t.Errorf("help = %q", res.Output)
+	}
+}
+
+func TestDispatch_Exit(t *testing.T) {
+	res, handled, err := NewDispatcher().Dispatch(context.Background(), "/exit")
+	if !handled || err != nil || !res.Exit {
+		t.Errorf("Dispatch(/exit) = %+v, %v, %v", res, handled, err)
+	}
+}
+
+func TestDispatcher_Long(t *testing.T) {
+	d := NewDispatcher()
+	d.Register(Command{Name: "compact", Long: true})
+	for input, want := range map[string]bool{"/compact": true, "/compact tests": true, "/help": false, "/missing": false, "compact": false} {
+		if got := d.Long(input); got != want {
+			t.Errorf("Long(%q) = %v, want %v", input, got, want)
+		}
+	}
+}
+
+func TestComplete(t *testing.T) {
+	d := NewDispatcher()
+	for _, name := range []string{"compact", "context", "clear"} {
+		d.Register(Command{Name: name, Usage: "/" + name})
+	}
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"/co", []string{"/compact", "/context"}},
+		{"/cl", []string{"/clear"}},
+		{"/", []string{"/clear", "/compact", "/context", "/exit", "/help"}},
+		{"/zz", nil},
+		{"/compact now", nil},
+		{"hello", nil},
+	}
+	for _, tt := range tests {
+		if got := d.Complete(tt.input); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Complete(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestDispatch_Pick(t *testing.T) {
+	d := NewDispatcher()
+	d.Register(Command{
+		Name:   "choose",
+		Usage:  "/choose [item]",
+		Help:   "Choose an item",
+		Reload: true,
+		Pick: func(_ context.Context, args []string) (*Picker, error) {
+			if len(args) > 0 {
+				return nil, nil
+			}
+			return &Picker{Title: "Items", Items: []string{"a", "b"}}, nil
+		},
+		Run: func(_ context.Context, args []string) (string, error) {
+			return "chose " + args[0], nil
+		},
+	})
+
+	res, handled, err := d.Dispatch(context.Background(), "/choose")
+	if !handled || err != nil || res.Picker == nil || len(res.Picker.Items) != 2 || !res.Reload {
+		t.Errorf("Dispatch = %+v, %v, %v", res, handled, err)
+	}
+	res, _, _ = d.Dispatch(context.Background(), "/choose b")
+	if res.Picker != nil || res.Output != "chose b" || !res.Reload {
+		t.Errorf("with an argument Run should run, got %+v", res)
+	}
+}
@@ -0,0 +1,28 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/gavinyap/stormtrooper/internal/agent"
+)
+
+// Compact returns the /compact command, which replaces all but the most
+// recent turns with a summary written by the model. Any arguments say
+// what the summary should focus on.
+func Compact(ag *agent.Agent) Command {
+	return Command{
+		Name:  "compact",
+		Usage: "/compact [focus]",
+		Help:  "Summarize older turns to free context; /rewind undoes it",
+		Long:  true,
+		Run: func(ctx context.Context, args []string) (string, error) {
+			res, err := ag.Compact(ctx, strings.Join(args, " "))
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("Summarized %d earlier messages. The next request sends about %d tokens instead of %d.", res.Messages, res.After, res.Before), nil
+		},
+	}
+}
@@ -0,0 +1,54 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gavinyap/stormtrooper/internal/agent"
+	"github.com/gavinyap/stormtrooper/internal/llm"
+	"github.com/gavinyap/stormtrooper/internal/tool"
+)
+
+func TestCompactCommand(t *testing.T) {
+	var focus string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req llm.ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		focus = req.Messages[0].Content
+		json.NewEncoder(w).Encode(llm.ChatCompletionResponse{
+			Choices: []llm.Choice{{Message: llm.Message{Role: "assistant", Content: "short"}}},
+		})
+	}))
+	defer server.Close()
+	client := llm.NewClient("key")
+	client.SetBaseURL(server.URL)
+
+	ag := agent.New(agent.Options{Client: client, Registry: tool.NewRegistry(), Model: "test-model"})
+	cmd := Compact(ag)
+	if _, err := cmd.Run(context.Background(), nil); !errors.Is(err, agent.ErrNothingToCompact) {
+		t.Errorf("expected ErrNothingToCompact on an empty conversation, got %v", err)
+	}
+
+	ag.Restore([]llm.Message{
+		{Role: "user", Content: strings.Repeat("a", 4000)},
+		{Role: "assistant", Content: "one"},
+		{Role: "user", Content: "two"},
+		{Role: "assistant", Content: "three"},
+		{Role: "user", Content: "four"},
+	})
+	out, err := cmd.Run(context.Background(), []string{"the", "schema"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out, "Summarized 2 earlier messages. The next request sends about ") {
+		t.Errorf("output = %q", out)
+	}
+	if !strings.HasSuffix(focus, "Pay most attention to: the schema") {
+		t.Errorf("the arguments should set the focus, got %q", focus)
+	}
+}
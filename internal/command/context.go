@@ -0,0 +1,74 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/gavinyap/stormtrooper/internal/agent"
+)
+
+// contextKinds names the kinds of context part, in display order.
+var contextKinds = []struct{ kind, name string }{
+	{"system", "System prompt"},
+	{"pins", "Pinned files"},
+	{"summary", "Compacted turns"},
+	{"user", "Your messages"},
+	{"assistant", "Assistant replies"},
+	{"tool", "Tool results"},
+	{"tools", "Tool definitions"},
+}
+
+// barWidth is the width of the bars drawn by /context.
+const barWidth = 20
+
+// Context returns the /context command, which shows what the next
+// request sends to the model: totals per kind, then every part of the
+// prompt in order, each with its estimated tokens and share.
+func Context(ag *agent.Agent) Command {
+	return Command{
+		Name:  "context",
+		Usage: "/context",
+		Help:  "Show what fills the context: system prompt, pins, messages, and tool results, with token estimates",
+		Run: func(_ context.Context, args []string) (string, error) {
+			if len(args) > 0 {
+				return "", fmt.Errorf("usage: /context")
+			}
+			return renderContext(ag.ContextBreakdown()), nil
+		},
+	}
+}
+
+// renderContext formats parts as a table with a bar per row.
+func renderContext(parts []agent.ContextPart) string {
+	total := 0
+	byKind := map[string]int{}
+	for _, p := range parts {
+		total += p.Tokens
+		byKind[p.Kind] += p.Tokens
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "The next request sends about %d tokens (estimated at 4 bytes per token).\n\n", total)
+	for _, k := range contextKinds {
+		if n, ok := byKind[k.kind]; ok {
+			fmt.Fprintf(&b, "%s  %s\n", contextRow(n, total), k.name)
+		}
+	}
+	b.WriteString("\nIn the order sent:\n")
+	for _, p := range parts {
+		fmt.Fprintf(&b, "%s  %s\n", contextRow(p.Tokens, total), p.Label)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// contextRow formats n tokens of total as a count, a percentage, and a
+// bar.
+func contextRow(n, total int) string {
+	pct := 0.0
+	if total > 0 {
+		pct = float64(n) * 100 / float64(total)
+	}
+	filled := int(math.Round(pct * barWidth / 100))
+	return fmt.Sprintf("%7d %5.1f%%  %s%s", n, pct, strings.Repeat("█", filled), strings.Repeat("░", barWidth-filled))
+}
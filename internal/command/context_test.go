@@ -0,0 +1,47 @@
+package command
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/gavinyap/stormtrooper/internal/agent"
+	"github.com/gavinyap/stormtrooper/internal/llm"
+	"github.com/gavinyap/stormtrooper/internal/tool"
+)
+
+func TestContextCommand(t *testing.T) {
+	ag := agent.New(agent.Options{
+		Registry:     tool.NewRegistry(),
+		Model:        "test-model",
+		SystemPrompt: strings.Repeat("s", 400),
+	})
+	ag.Restore([]llm.Message{
+		{Role: "user", Content: strings.Repeat("u", 400)},
+		{Role: "assistant", ToolCalls: []llm.ToolCall{{ID: "1", Function: llm.FunctionCall{Name: "grep"}}}},
+		{Role: "tool", ToolCallID: "1", Name: "grep", Content: strings.Repeat("t", 1200)},
+	})
+
+	cmd := Context(ag)
+	if _, err := cmd.Run(context.Background(), []string{"extra"}); err == nil {
+		t.Error("expected a usage error")
+	}
+	out, err := cmd.Run(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"The next request sends about 501 tokens",
+		"    300  59.9%  ████████████░░░░░░░░  Tool results",
+		"    100  20.0%  ████░░░░░░░░░░░░░░░░  System prompt",
+		"      1   0.2%  ░░░░░░░░░░░░░░░░░░░░  Assistant: calls grep",
+		"Tool result: grep",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "Pinned files") || strings.Contains(out, "Tool definitions") {
+		t.Errorf("kinds with no parts should be left out:\n%s", out)
+	}
+}
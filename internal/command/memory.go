@@ -0,0 +1,34 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/gavinyap/stormtrooper/internal/memory"
+)
+
+// Memory returns the /memory command, which shows the project memory in
+// dir: the notes the agent keeps across sessions with memory_write.
+func Memory(dir string) Command {
+	return Command{
+		Name:  "memory",
+		Usage: "/memory",
+		Help:  "Show the project memory kept across sessions",
+		Run: func(_ context.Context, args []string) (string, error) {
+			if len(args) > 0 {
+				return "", fmt.Errorf("usage: /memory")
+			}
+			mem, err := memory.Load(dir)
+			if err != nil {
+				return "", err
+			}
+			path := filepath.Join(memory.Dir(dir), "MEMORY.md")
+			if strings.TrimSpace(mem) == "" {
+				return "No project memory yet. The agent keeps it in " + path + " with memory_write.", nil
+			}
+			return path + ":\n\n" + strings.TrimRight(mem, "\n"), nil
+		},
+	}
+}
@@ -0,0 +1,30 @@
+package command
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gavinyap/stormtrooper/internal/memory"
+)
+
+func TestMemoryCommand(t *testing.T) {
+	dir := t.TempDir()
+	cmd := Memory(dir)
+	out, err := cmd.Run(context.Background(), nil)
+	if err != nil || !strings.HasPrefix(out, "No project memory yet.") {
+		t.Errorf("/memory without a memory = %q, %v", out, err)
+	}
+
+	os.MkdirAll(memory.Dir(dir), 0755)
+	os.WriteFile(filepath.Join(memory.Dir(dir), "MEMORY.md"), []byte("Tests need Docker.\n"), 0644)
+	out, err = cmd.Run(context.Background(), nil)
+	if err != nil || !strings.HasSuffix(out, "MEMORY.md:\n\nTests need Docker.") {
+		t.Errorf("/memory = %q, %v", out, err)
+	}
+	if _, err := cmd.Run(context.Background(), []string{"edit"}); err == nil {
+		t.Error("expected a usage error")
+	}
+}
@@ -0,0 +1,97 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gavinyap/stormtrooper/internal/agent"
+	"github.com/gavinyap/stormtrooper/internal/llm"
+)
+
+// listModelsTimeout bounds the request for the provider's models.
+const listModelsTimeout = 15 * time.Second
+
+// Model returns the /model [name] command, which switches the agent's
+// model for the rest of the session, keeping the conversation. Without
+// a name it lists the provider's models to choose from, using list, or
+// shows the current model when list is nil. allowed, if not nil, rejects
+// models the organization policy forbids, and they are not listed.
+func Model(ag *agent.Agent, allowed func(model string) bool, list func(context.Context) ([]llm.Model, error)) Command {
+	switchTo := func(model string) (string, error) {
+		if allowed != nil && !allowed(model) {
+			return "", fmt.Errorf("the organization policy does not allow the model %s", model)
+		}
+		ag.SetModel(model)
+		return "Switched to " + model + ". Saving the config file switches back to its model.", nil
+	}
+	return Command{
+		Name:  "model",
+		Usage: "/model [name]",
+		Help:  "Switch to another model for the rest of the session; without a name, choose from the provider's models",
+		// Listing the models waits for the provider.
+		Long: true,
+		Pick: func(ctx context.Context, args []string) (*Picker, error) {
+			if len(args) > 0 || list == nil {
+				return nil, nil
+			}
+			ctx, cancel := context.WithTimeout(ctx, listModelsTimeout)
+			defer cancel()
+			models, err := list(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("listing models: %w (use /model <name> instead)", err)
+			}
+			var ids, items []string
+			for _, m := range models {
+				if allowed != nil && !allowed(m.ID) {
+					continue
+				}
+				ids = append(ids, m.ID)
+				items = append(items, describeModel(m, ag.Model()))
+			}
+			if len(ids) == 0 {
+				return nil, nil
+			}
+			return &Picker{
+				Title:  "Switch model (now " + ag.Model() + ")",
+				Items:  items,
+				Filter: true,
+				Choose: func(_ context.Context, i int) (string, error) {
+					return switchTo(ids[i])
+				},
+			}, nil
+		},
+		Run: func(_ context.Context, args []string) (string, error) {
+			switch len(args) {
+			case 0:
+				return "Model: " + ag.Model(), nil
+			case 1:
+				return switchTo(args[0])
+			}
+			return "", fmt.Errorf("usage: /model [name]")
+		},
+	}
+}
+
+// describeModel returns a picker line for m: its ID, context window, and
+// price per million prompt and completion tokens, where known.
+func describeModel(m llm.Model, current string) string {
+	s := m.ID
+	if m.ContextLength > 0 {
+		s += fmt.Sprintf(" · %dK context", m.ContextLength/1000)
+	}
+	prompt, err1 := strconv.ParseFloat(m.Pricing.Prompt, 64)
+	completion, err2 := strconv.ParseFloat(m.Pricing.Completion, 64)
+	switch {
+	case err1 != nil || err2 != nil:
+	case prompt == 0 && completion == 0:
+		s += " · free"
+	default:
+		s += fmt.Sprintf(" · $%.2f/$%.2f per M tokens", prompt*1e6, completion*1e6)
+	}
+	if m.ID == current {
+		s += " (current)"
+	}
+	return s
+}
@@ -0,0 +1,66 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gavinyap/stormtrooper/internal/agent"
+	"github.com/gavinyap/stormtrooper/internal/llm"
+	"github.com/gavinyap/stormtrooper/internal/tool"
+)
+
+func TestModelCommand(t *testing.T) {
+	ag := agent.New(agent.Options{Registry: tool.NewRegistry(), Model: "test-model"})
+	cmd := Model(ag, func(model string) bool { return strings.HasPrefix(model, "openai/") }, nil)
+
+	if out, err := cmd.Run(context.Background(), nil); err != nil || out != "Model: test-model" {
+		t.Errorf("/model = %q, %v", out, err)
+	}
+	if _, err := cmd.Run(context.Background(), []string{"anthropic/claude"}); err == nil || ag.Model() != "test-model" {
+		t.Errorf("a model the policy forbids should be refused, got %v and model %s", err, ag.Model())
+	}
+	if _, err := cmd.Run(context.Background(), []string{"openai/gpt-5"}); err != nil || ag.Model() != "openai/gpt-5" {
+		t.Errorf("/model openai/gpt-5 = %v, model %s", err, ag.Model())
+	}
+	if _, err := cmd.Run(context.Background(), []string{"a", "b"}); err == nil {
+		t.Error("expected a usage error")
+	}
+
+	// Without a policy any model goes.
+	if _, err := Model(ag, nil, nil).Run(context.Background(), []string{"anthropic/claude"}); err != nil || ag.Model() != "anthropic/claude" {
+		t.Errorf("/model without a policy = %v, model %s", err, ag.Model())
+	}
+}
+
+func TestModelCommand_Pick(t *testing.T) {
+	ag := agent.New(agent.Options{Registry: tool.NewRegistry(), Model: "openai/gpt-4o"})
+	models := []llm.Model{
+		{ID: "anthropic/claude", ContextLength: 200000},
+		{ID: "openai/gpt-4o", ContextLength: 128000, Pricing: llm.ModelPricing{Prompt: "0.0000025", Completion: "0.00001"}},
+		{ID: "openai/gpt-oss", Pricing: llm.ModelPricing{Prompt: "0", Completion: "0"}},
+	}
+	list := func(context.Context) ([]llm.Model, error) { return models, nil }
+	cmd := Model(ag, func(model string) bool { return strings.HasPrefix(model, "openai/") }, list)
+
+	if p, err := cmd.Pick(context.Background(), []string{"openai/gpt-oss"}); p != nil || err != nil {
+		t.Errorf("/model with a name should not pick, got %v, %v", p, err)
+	}
+	p, err := cmd.Pick(context.Background(), nil)
+	if err != nil || p == nil {
+		t.Fatalf("Pick = %v, %v", p, err)
+	}
+	want := []string{"openai/gpt-4o · 128K context · $2.50/$10.00 per M tokens (current)", "openai/gpt-oss · free"}
+	if strings.Join(p.Items, "\n") != strings.Join(want, "\n") || !p.Filter {
+		t.Errorf("items = %q, want %q", p.Items, want)
+	}
+	if _, err := p.Choose(context.Background(), 1); err != nil || ag.Model() != "openai/gpt-oss" {
+		t.Errorf("Choose = %v, model %s", err, ag.Model())
+	}
+
+	failing := Model(ag, nil, func(context.Context) ([]llm.Model, error) { return nil, errors.New("offline") })
+	if _, err := failing.Pick(context.Background(), nil); err == nil || !strings.Contains(err.Error(), "/model <name>") {
+		t.Errorf("expected an error suggesting /model <name>, got %v", err)
+	}
+}
@@ -0,0 +1,26 @@
+package command
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/gavinyap/stormtrooper/internal/editor"
+)
+
+// Open returns the /open <path>[:line] command, which opens a file in
+// the user's editor.
+func Open(ed *editor.Editor) Command {
+	return Command{
+		Name:  "open",
+		Usage: "/open <path>[:line]",
+		Help:  "Open a file in your editor, optionally at a line",
+		Exec: func(args []string) (*exec.Cmd, error) {
+			path, line, err := editor.ParseTarget(strings.Join(args, " "))
+			if err != nil {
+				return nil, fmt.Errorf("usage: /open <path>[:line]: %w", err)
+			}
+			return ed.Command(path, line), nil
+		},
+	}
+}
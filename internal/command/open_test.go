@@ -0,0 +1,26 @@
+package command
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/gavinyap/stormtrooper/internal/editor"
+)
+
+func TestOpen(t *testing.T) {
+	d := NewDispatcher()
+	d.Register(Open(editor.New("code -g {path}:{line}", "/work", func(string) string { return "" })))
+
+	res, handled, err := d.Dispatch(context.Background(), "/open internal/tui/app.go:42")
+	if !handled || err != nil {
+		t.Fatalf("Dispatch = %v, %v", handled, err)
+	}
+	if res.Exec == nil || strings.Join(res.Exec.Args, " ") != "code -g /work/internal/tui/app.go:42" {
+		t.Errorf("Exec = %+v", res.Exec)
+	}
+
+	if _, _, err := d.Dispatch(context.Background(), "/open"); err == nil || !strings.Contains(err.Error(), "usage") {
+		t.Errorf("/open without a path: %v", err)
+	}
+}
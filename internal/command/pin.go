@@ -0,0 +1,58 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/gavinyap/stormtrooper/internal/agent"
+)
+
+// Pin returns the /pin <path> command, which sends a file's current
+// contents with every request until it is unpinned. Without a path it
+// lists the pinned files.
+func Pin(pins *agent.Pins) Command {
+	return Command{
+		Name:  "pin",
+		Usage: "/pin [path]",
+		Help:  "Keep a file's latest contents in context every turn; without a path, list pinned files",
+		Run: func(_ context.Context, args []string) (string, error) {
+			if len(args) == 0 {
+				paths := pins.List()
+				if len(paths) == 0 {
+					return "No pinned files.", nil
+				}
+				return "Pinned: " + strings.Join(paths, ", "), nil
+			}
+			path := strings.Join(args, " ")
+			if err := pins.Pin(path); err != nil {
+				return "", fmt.Errorf("cannot pin %s: %w", path, err)
+			}
+			return "Pinned " + path + ".", nil
+		},
+	}
+}
+
+// Unpin returns the /unpin [path] command, which stops sending a pinned
+// file, or every pinned file when no path is given.
+func Unpin(pins *agent.Pins) Command {
+	return Command{
+		Name:  "unpin",
+		Usage: "/unpin [path]",
+		Help:  "Stop sending a pinned file (default: all of them)",
+		Run: func(_ context.Context, args []string) (string, error) {
+			if len(args) == 0 {
+				paths := pins.Clear()
+				if len(paths) == 0 {
+					return "No pinned files.", nil
+				}
+				return "Unpinned " + strings.Join(paths, ", ") + ".", nil
+			}
+			path := strings.Join(args, " ")
+			if !pins.Unpin(path) {
+				return "", fmt.Errorf("%s is not pinned", path)
+			}
+			return "Unpinned " + path + ".", nil
+		},
+	}
+}
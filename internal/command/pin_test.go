@@ -0,0 +1,39 @@
+package command
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gavinyap/stormtrooper/internal/agent"
+)
+
+func TestPinCommands(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "notes.md")
+	os.WriteFile(path, []byte("notes"), 0644)
+
+	pins := agent.NewPins(nil)
+	d := NewDispatcher()
+	d.Register(Pin(pins))
+	d.Register(Unpin(pins))
+	ctx := context.Background()
+
+	if res, _, err := d.Dispatch(ctx, "/pin "+path); err != nil || !strings.Contains(res.Output, "Pinned "+path) {
+		t.Fatalf("/pin = %q, %v", res.Output, err)
+	}
+	if res, _, _ := d.Dispatch(ctx, "/pin"); res.Output != "Pinned: "+path {
+		t.Errorf("/pin without a path should list the pins, got %q", res.Output)
+	}
+	if _, _, err := d.Dispatch(ctx, "/pin "+filepath.Join(dir, "missing")); err == nil {
+		t.Error("pinning a missing file should fail")
+	}
+	if _, _, err := d.Dispatch(ctx, "/unpin other.md"); err == nil {
+		t.Error("unpinning a file that is not pinned should fail")
+	}
+	if res, _, err := d.Dispatch(ctx, "/unpin"); err != nil || !strings.Contains(res.Output, path) || len(pins.List()) != 0 {
+		t.Errorf("/unpin = %q, %v", res.Output, err)
+	}
+}
@@ -0,0 +1,90 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gavinyap/stormtrooper/internal/session"
+)
+
+// maxResumeChoices caps how many sessions /resume lists.
+const maxResumeChoices = 20
+
+// Resume returns the /resume [id|last] command, which continues a
+// session saved in dir. resume is called with the chosen session and
+// replaces the current conversation with it. Without an argument, the
+// front end offers the most recent sessions to pick from.
+func Resume(dir string, resume func(*session.Session) (string, error)) Command {
+	recent := func() ([]*session.Session, error) {
+		sessions, err := session.List(dir)
+		if err != nil {
+			return nil, err
+		}
+		sort.SliceStable(sessions, func(i, j int) bool {
+			return sessions[i].Updated.After(sessions[j].Updated)
+		})
+		if len(sessions) > maxResumeChoices {
+			sessions = sessions[:maxResumeChoices]
+		}
+		return sessions, nil
+	}
+	return Command{
+		Name:   "resume",
+		Usage:  "/resume [id|last]",
+		Help:   "Continue a saved session; without an id, choose from recent ones",
+		Reload: true,
+		Pick: func(_ context.Context, args []string) (*Picker, error) {
+			if len(args) > 0 {
+				return nil, nil
+			}
+			sessions, err := recent()
+			if err != nil || len(sessions) == 0 {
+				return nil, err
+			}
+			items := make([]string, len(sessions))
+			for i, s := range sessions {
+				items[i] = describeSession(s)
+			}
+			return &Picker{
+				Title: "Resume a session",
+				Items: items,
+				Choose: func(_ context.Context, i int) (string, error) {
+					return resume(sessions[i])
+				},
+			}, nil
+		},
+		Run: func(_ context.Context, args []string) (string, error) {
+			if len(args) > 1 {
+				return "", fmt.Errorf("usage: /resume [id|last]")
+			}
+			if len(args) == 1 {
+				s, err := session.Resolve(dir, args[0])
+				if err != nil {
+					return "", err
+				}
+				return resume(s)
+			}
+			// Front ends without a picker get the list instead.
+			sessions, err := recent()
+			if err != nil {
+				return "", err
+			}
+			if len(sessions) == 0 {
+				return "No saved sessions.", nil
+			}
+			var b strings.Builder
+			for _, s := range sessions {
+				b.WriteString(describeSession(s) + "\n")
+			}
+			b.WriteString("Use /resume <id> to continue one.")
+			return b.String(), nil
+		},
+	}
+}
+
+// describeSession is a session's line in the /resume list.
+func describeSession(s *session.Session) string {
+	return fmt.Sprintf("%s  %s  %s (%d prompt(s))", s.ID, s.Updated.Format("Jan 2 15:04"), s.Title(), len(s.Prompts()))
+}
@@ -0,0 +1,60 @@
+package command
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gavinyap/stormtrooper/internal/llm"
+	"github.com/gavinyap/stormtrooper/internal/session"
+)
+
+func TestResume(t *testing.T) {
+	dir := t.TempDir()
+	var resumed string
+	resume := Resume(dir, func(s *session.Session) (string, error) {
+		resumed = s.ID
+		return "Resumed " + s.ID + ".", nil
+	})
+
+	if p, err := resume.Pick(context.Background(), nil); p != nil || err != nil {
+		t.Errorf("expected no picker without sessions, got %+v, %v", p, err)
+	}
+	if out, _ := resume.Run(context.Background(), nil); out != "No saved sessions." {
+		t.Errorf("output = %q", out)
+	}
+
+	for _, s := range []*session.Session{
+		{ID: "older", Started: time.Now().Add(-time.Hour), Messages: []llm.Message{{Role: "user", Content: "add tests"}}},
+		{ID: "newer", Started: time.Now(), Messages: []llm.Message{{Role: "user", Content: "fix the build"}}},
+	} {
+		if err := s.Save(dir); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p, err := resume.Pick(context.Background(), nil)
+	if err != nil || p == nil || len(p.Items) != 2 {
+		t.Fatalf("Pick = %+v, %v", p, err)
+	}
+	if !strings.HasPrefix(p.Items[0], "newer  ") || !strings.Contains(p.Items[0], "fix the build (1 prompt(s))") {
+		t.Errorf("expected the most recent session first, got %q", p.Items)
+	}
+	if out, err := p.Choose(context.Background(), 1); err != nil || out != "Resumed older." || resumed != "older" {
+		t.Errorf("Choose = %q, %v; resumed %q", out, err, resumed)
+	}
+
+	if p, _ := resume.Pick(context.Background(), []string{"last"}); p != nil {
+		t.Error("an argument should skip the picker")
+	}
+	if _, err := resume.Run(context.Background(), []string{"last"}); err != nil || resumed != "newer" {
+		t.Errorf("/resume last resumed %q, %v", resumed, err)
+	}
+	if _, err := resume.Run(context.Background(), []string{"missing"}); err == nil {
+		t.Error("expected an error for an unknown session")
+	}
+	if out, _ := resume.Run(context.Background(), nil); !strings.Contains(out, "older") || !strings.HasSuffix(out, "Use /resume <id> to continue one.") {
+		t.Errorf("list = %q", out)
+	}
+}
@@ -0,0 +1,61 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/gavinyap/stormtrooper/internal/staging"
+)
+
+// Changes returns the /changes command, which shows the staged file
+// changes as a unified diff.
+func Changes(o *staging.Overlay) Command {
+	return Command{
+		Name:  "changes",
+		Usage: "/changes",
+		Help:  "Show the staged file changes as a diff",
+		Run: func(context.Context, []string) (string, error) {
+			if o.Len() == 0 {
+				return "No staged changes.", nil
+			}
+			return strings.TrimRight(o.Diff(), "\n"), nil
+		},
+	}
+}
+
+// Apply returns the /apply command, which writes every staged change to
+// disk at once.
+func Apply(o *staging.Overlay) Command {
+	return Command{
+		Name:  "apply",
+		Usage: "/apply",
+		Help:  "Write all staged changes to disk",
+		Run: func(context.Context, []string) (string, error) {
+			files, err := o.Apply()
+			if err != nil {
+				return "", err
+			}
+			if len(files) == 0 {
+				return "No staged changes.", nil
+			}
+			return fmt.Sprintf("Applied %d file(s): %s", len(files), strings.Join(files, ", ")), nil
+		},
+	}
+}
+
+// Discard returns the /discard command, which drops every staged change.
+func Discard(o *staging.Overlay) Command {
+	return Command{
+		Name:  "discard",
+		Usage: "/discard",
+		Help:  "Drop all staged changes",
+		Run: func(context.Context, []string) (string, error) {
+			files := o.Discard()
+			if len(files) == 0 {
+				return "No staged changes.", nil
+			}
+			return fmt.Sprintf("Discarded %d file(s): %s", len(files), strings.Join(files, ", ")), nil
+		},
+	}
+}
@@ -0,0 +1,52 @@
+package command
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gavinyap/stormtrooper/internal/staging"
+)
+
+func TestReviewCommands(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")
+	os.WriteFile(a, []byte("old\n"), 0644)
+
+	o := staging.New(nil)
+	d := NewDispatcher()
+	d.Register(Changes(o))
+	d.Register(Apply(o))
+	d.Register(Discard(o))
+	ctx := context.Background()
+
+	res, _, _ := d.Dispatch(ctx, "/changes")
+	if res.Output != "No staged changes." {
+		t.Errorf("/changes with nothing staged = %q", res.Output)
+	}
+
+	o.WriteFile(a, []byte("new\n"), 0644)
+	res, _, _ = d.Dispatch(ctx, "/changes")
+	if !strings.Contains(res.Output, "-old") || !strings.Contains(res.Output, "+new") {
+		t.Errorf("/changes = %q", res.Output)
+	}
+
+	res, _, err := d.Dispatch(ctx, "/apply")
+	if err != nil || !strings.Contains(res.Output, "Applied 1 file(s)") {
+		t.Fatalf("/apply = %q, %v", res.Output, err)
+	}
+	if data, _ := os.ReadFile(a); string(data) != "new\n" {
+		t.Errorf("a.txt = %q", data)
+	}
+
+	o.WriteFile(b, []byte("draft"), 0644)
+	res, _, _ = d.Dispatch(ctx, "/discard")
+	if !strings.Contains(res.Output, "Discarded 1 file(s)") {
+		t.Errorf("/discard = %q", res.Output)
+	}
+	if _, err := os.Stat(b); err == nil {
+		t.Error("a discarded file should not be written")
+	}
+}
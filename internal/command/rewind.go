@@ -0,0 +1,42 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gavinyap/stormtrooper/internal/agent"
+)
+
+// Rewind returns the /rewind [n] command, which undoes the agent's last n
+// turns (default 1): the conversation returns to where it stood and the
+// files changed in those turns get their earlier contents back.
+func Rewind(ag *agent.Agent) Command {
+	return Command{
+		Name:  "rewind",
+		Usage: "/rewind [n]",
+		Help:  "Undo the last n turns (default 1), restoring the conversation and changed files",
+		Run: func(_ context.Context, args []string) (string, error) {
+			n := 1
+			if len(args) > 1 {
+				return "", fmt.Errorf("usage: /rewind [n]")
+			}
+			if len(args) == 1 {
+				var err error
+				if n, err = strconv.Atoi(args[0]); err != nil || n < 1 {
+					return "", fmt.Errorf("usage: /rewind [n], where n is a positive number of turns")
+				}
+			}
+			files, err := ag.Rewind(n)
+			if err != nil {
+				return "", err
+			}
+			out := fmt.Sprintf("Rewound %d turn(s).", n)
+			if len(files) > 0 {
+				out += " Restored: " + strings.Join(files, ", ")
+			}
+			return out, nil
+		},
+	}
+}
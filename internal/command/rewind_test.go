@@ -0,0 +1,77 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gavinyap/stormtrooper/internal/agent"
+	"github.com/gavinyap/stormtrooper/internal/checkpoint"
+	"github.com/gavinyap/stormtrooper/internal/llm"
+	"github.com/gavinyap/stormtrooper/internal/permission"
+	"github.com/gavinyap/stormtrooper/internal/tool"
+)
+
+const textReply = "data: {\"id\":\"1\",\"choices\":[{\"index\":0,\"delta\":{\"role\":\"assistant\",\"content\":\"done\"},\"finish_reason\":\"stop\"}]}\n\ndata: [DONE]\n"
+
+func TestRewind(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(textReply))
+	}))
+	defer server.Close()
+	client := llm.NewClient("test-key")
+	client.SetBaseURL(server.URL)
+
+	tracker := checkpoint.New(nil)
+	ag := agent.New(agent.Options{
+		Client:       client,
+		Registry:     tool.NewRegistry(),
+		Permission:   permission.AllowAll{},
+		Model:        "test-model",
+		SystemPrompt: "system",
+		Checkpoints:  tracker,
+	})
+	ag.SetOutput(&bytes.Buffer{}, &bytes.Buffer{})
+
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	if err := ag.Send(context.Background(), "first"); err != nil {
+		t.Fatal(err)
+	}
+	tracker.FS().WriteFile(path, []byte("written in turn two"), 0644)
+	if err := ag.Send(context.Background(), "second"); err != nil {
+		t.Fatal(err)
+	}
+
+	rewind := Rewind(ag)
+	for _, args := range [][]string{{"x"}, {"0"}, {"1", "2"}, {"5"}} {
+		if _, err := rewind.Run(context.Background(), args); err == nil {
+			t.Errorf("/rewind %v should fail", args)
+		}
+	}
+
+	out, err := rewind.Run(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "Rewound 1 turn(s). Restored: "+path {
+		t.Errorf("output = %q", out)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("file created in the rewound turn still exists")
+	}
+	history := ag.History()
+	if len(history) != 3 || history[1].Content != "first" {
+		t.Errorf("history after rewind = %+v", history)
+	}
+
+	out, _ = rewind.Run(context.Background(), []string{"1"})
+	if !strings.HasPrefix(out, "Rewound 1 turn(s).") || len(ag.History()) != 1 {
+		t.Errorf("rewinding to the start: %q, %d messages", out, len(ag.History()))
+	}
+}
@@ -0,0 +1,80 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/gavinyap/stormtrooper/internal/agent"
+	"github.com/gavinyap/stormtrooper/internal/tool"
+)
+
+// RunTool returns the /run-tool <name> [json] command, which runs a tool
+// directly and adds its result to the conversation, so the model sees it
+// on the next turn. Without JSON arguments, the TUI asks for them with a
+// form built from the tool's schema.
+func RunTool(ag *agent.Agent, reg *tool.Registry) Command {
+	const usage = "/run-tool <name> [json arguments]"
+	run := func(ctx context.Context, name string, args json.RawMessage) (string, error) {
+		t := reg.Get(name)
+		if t == nil {
+			return "", fmt.Errorf("unknown tool %q", name)
+		}
+		var obj map[string]any
+		if err := json.Unmarshal(args, &obj); err != nil {
+			return "", fmt.Errorf("arguments must be a JSON object: %w", err)
+		}
+		// An organization policy applies to the user's own calls too, and
+		// they are audited like the model's.
+		guard := reg.Guard()
+		if guard != nil {
+			if reason := guard.Check(name, args); reason != "" {
+				guard.Record(name, args, "blocked")
+				return "", fmt.Errorf("blocked by the organization policy: %s", reason)
+			}
+		}
+		outcome := "ok"
+		result, err := agent.RunTool(ctx, t, args)
+		if err != nil {
+			result = agent.NewToolError(name, args, err).String()
+			outcome = "error"
+		} else if strings.HasPrefix(result, "Error:") {
+			outcome = "error"
+		}
+		if guard != nil {
+			guard.Record(name, args, outcome)
+		}
+		ag.AddToolResult(name, args, result)
+		return fmt.Sprintf("%s result (added to the conversation):\n%s", name, result), nil
+	}
+	return Command{
+		Name:  "run-tool",
+		Usage: usage,
+		Help:  "Run a tool yourself and add its result to the conversation",
+		Form: func(args []string) (*Form, error) {
+			if len(args) != 1 {
+				return nil, nil
+			}
+			t := reg.Get(args[0])
+			if t == nil {
+				return nil, fmt.Errorf("unknown tool %q", args[0])
+			}
+			name := args[0]
+			return &Form{
+				Title:  name,
+				Schema: t.Schema(),
+				Submit: func(ctx context.Context, args json.RawMessage) (string, error) {
+					return run(ctx, name, args)
+				},
+			}, nil
+		},
+		Run: func(ctx context.Context, args []string) (string, error) {
+			if len(args) < 2 {
+				return "", fmt.Errorf("usage: %s", usage)
+			}
+			// Arguments are split on spaces; rejoin them into the JSON.
+			return run(ctx, args[0], json.RawMessage(strings.Join(args[1:], " ")))
+		},
+	}
+}
@@ -0,0 +1,106 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gavinyap/stormtrooper/internal/agent"
+	"github.com/gavinyap/stormtrooper/internal/permission"
+	"github.com/gavinyap/stormtrooper/internal/tool"
+)
+
+func TestRunToolCommand(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	os.WriteFile(path, []byte("remember the milk"), 0644)
+
+	reg := tool.NewRegistry()
+	reg.Register(&tool.ReadFileTool{})
+	ag := agent.New(agent.Options{Registry: reg, Permission: permission.AllowAll{}})
+
+	d := NewDispatcher()
+	d.Register(RunTool(ag, reg))
+	ctx := context.Background()
+
+	// With JSON arguments the tool runs right away.
+	res, _, err := d.Dispatch(ctx, `/run-tool read_file {"file_path": "`+path+`"}`)
+	if err != nil || res.Form != nil || !strings.Contains(res.Output, "remember the milk") {
+		t.Fatalf("/run-tool with arguments = %+v, %v", res, err)
+	}
+	history := ag.History()
+	if len(history) != 2 || history[0].ToolCalls[0].Function.Name != "read_file" || history[1].Role != "tool" || history[1].ToolCallID != history[0].ToolCalls[0].ID {
+		t.Fatalf("expected a tool call and its result in the history, got %+v", history)
+	}
+
+	// With only a name the front end is asked for a form.
+	res, _, err = d.Dispatch(ctx, "/run-tool read_file")
+	if err != nil || res.Form == nil || res.Form.Title != "read_file" {
+		t.Fatalf("/run-tool read_file = %+v, %v", res, err)
+	}
+	args, _ := json.Marshal(map[string]string{"file_path": path})
+	if out, err := res.Form.Submit(ctx, args); err != nil || !strings.Contains(out, "remember the milk") {
+		t.Errorf("Submit = %q, %v", out, err)
+	}
+	if len(ag.History()) != 4 {
+		t.Errorf("expected the form's result in the history, got %d messages", len(ag.History()))
+	}
+
+	for _, input := range []string{"/run-tool", "/run-tool nope", `/run-tool read_file "path"`} {
+		if _, handled, err := d.Dispatch(ctx, input); !handled || err == nil {
+			t.Errorf("%s: expected an error, got %v, %v", input, handled, err)
+		}
+	}
+}
+
+// denyGuard forbids calls to one tool and records the outcome of each.
+type denyGuard struct {
+	tool     string
+	outcomes []string
+}
+
+func (g *denyGuard) Check(name string, _ json.RawMessage) string {
+	if name == g.tool {
+		return "not allowed here"
+	}
+	return ""
+}
+
+func (g *denyGuard) Record(name string, _ json.RawMessage, outcome string) {
+	g.outcomes = append(g.outcomes, name+":"+outcome)
+}
+
+func TestRunToolCommand_Policy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	os.WriteFile(path, []byte("remember the milk"), 0644)
+
+	reg := tool.NewRegistry()
+	reg.Register(&tool.ReadFileTool{})
+	reg.Register(&tool.WriteFileTool{})
+	guard := &denyGuard{tool: "write_file"}
+	reg.SetGuard(guard)
+	ag := agent.New(agent.Options{Registry: reg, Permission: permission.AllowAll{}})
+	d := NewDispatcher()
+	d.Register(RunTool(ag, reg))
+	ctx := context.Background()
+
+	_, _, err := d.Dispatch(ctx, `/run-tool write_file {"file_path": "`+path+`", "content": "forget it"}`)
+	if err == nil || !strings.Contains(err.Error(), "blocked by the organization policy: not allowed here") {
+		t.Fatalf("expected the policy to block write_file, got %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "remember the milk" {
+		t.Errorf("a blocked call must not run, file = %q", data)
+	}
+	if len(ag.History()) != 0 {
+		t.Errorf("a blocked call must not reach the conversation, got %+v", ag.History())
+	}
+
+	if _, _, err := d.Dispatch(ctx, `/run-tool read_file {"file_path": "`+path+`"}`); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(guard.outcomes, ",") != "write_file:blocked,read_file:ok" {
+		t.Errorf("audit = %v", guard.outcomes)
+	}
+}
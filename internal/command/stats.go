@@ -0,0 +1,28 @@
+package command
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gavinyap/stormtrooper/internal/llm"
+)
+
+// Stats returns the /stats command, which shows the latency of this
+// session's model responses: time to first token, total time, and
+// tokens per second, per model.
+func Stats(stats *llm.Stats) Command {
+	return Command{
+		Name:  "stats",
+		Usage: "/stats",
+		Help:  "Show time to first token and throughput per model for this session",
+		Run: func(_ context.Context, args []string) (string, error) {
+			if len(args) > 0 {
+				return "", fmt.Errorf("usage: /stats")
+			}
+			if out := stats.String(); out != "" {
+				return out, nil
+			}
+			return "No model responses yet.", nil
+		},
+	}
+}
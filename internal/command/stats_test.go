@@ -0,0 +1,34 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gavinyap/stormtrooper/internal/llm"
+)
+
+func TestStatsCommand(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\"hi\"}}]}\n\ndata: [DONE]\n\n")
+	}))
+	defer server.Close()
+	client := llm.NewClient("key")
+	client.SetBaseURL(server.URL)
+
+	d := NewDispatcher()
+	d.Register(Stats(client.Stats()))
+	ctx := context.Background()
+
+	if res, _, err := d.Dispatch(ctx, "/stats"); err != nil || res.Output != "No model responses yet." {
+		t.Errorf("/stats before any request = %q, %v", res.Output, err)
+	}
+	client.ChatCompletionStream(ctx, llm.ChatCompletionRequest{Model: "test-model"}, nil)
+	if res, _, err := d.Dispatch(ctx, "/stats"); err != nil || !strings.HasPrefix(res.Output, "test-model: 1 requests, first token ") {
+		t.Errorf("/stats = %q, %v", res.Output, err)
+	}
+}
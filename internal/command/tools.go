@@ -0,0 +1,45 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/gavinyap/stormtrooper/internal/agent"
+	"github.com/gavinyap/stormtrooper/internal/tool"
+)
+
+// Tools returns the /tools [profile] command. Without an argument it shows
+// the current tool profile and the tools it offers the model; with one it
+// switches to that profile from the next request on.
+func Tools(ag *agent.Agent, reg *tool.Registry) Command {
+	usage := "/tools [" + strings.Join(tool.ProfileNames(), "|") + "]"
+	return Command{
+		Name:  "tools",
+		Usage: usage,
+		Help:  "Show the tools offered to the model, or switch tool profile",
+		Run: func(_ context.Context, args []string) (string, error) {
+			switch len(args) {
+			case 0:
+				p := ag.ToolProfile()
+				var names []string
+				for _, def := range reg.DefinitionsFor(p) {
+					names = append(names, def.Function.Name)
+				}
+				name := p.Name
+				if name == "" {
+					name = "all"
+				}
+				return fmt.Sprintf("Tool profile: %s\nTools: %s", name, strings.Join(names, ", ")), nil
+			case 1:
+				p, ok := tool.Profiles[args[0]]
+				if !ok {
+					return "", fmt.Errorf("unknown tool profile %q; usage: %s", args[0], usage)
+				}
+				ag.SetToolProfile(p)
+				return fmt.Sprintf("Tool profile set to %s.", p.Name), nil
+			}
+			return "", fmt.Errorf("usage: %s", usage)
+		},
+	}
+}
@@ -0,0 +1,37 @@
+package command
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gavinyap/stormtrooper/internal/agent"
+	"github.com/gavinyap/stormtrooper/internal/permission"
+	"github.com/gavinyap/stormtrooper/internal/tool"
+)
+
+func TestToolsCommand(t *testing.T) {
+	reg := tool.NewRegistry()
+	reg.Register(&tool.ReadFileTool{})
+	reg.Register(&tool.ShellExecTool{})
+	ag := agent.New(agent.Options{Registry: reg, Permission: permission.AllowAll{}})
+
+	d := NewDispatcher()
+	d.Register(Tools(ag, reg))
+	ctx := context.Background()
+
+	if res, _, err := d.Dispatch(ctx, "/tools"); err != nil || res.Output != "Tool profile: all\nTools: read_file, shell_exec" {
+		t.Errorf("/tools = %q, %v", res.Output, err)
+	}
+	if _, _, err := d.Dispatch(ctx, "/tools plan"); err != nil {
+		t.Fatalf("/tools plan: %v", err)
+	}
+	if got := ag.ToolProfile().Name; got != "plan" {
+		t.Errorf("profile = %q, want plan", got)
+	}
+	if res, _, err := d.Dispatch(ctx, "/tools"); err != nil || res.Output != "Tool profile: plan\nTools: read_file" {
+		t.Errorf("/tools after plan = %q, %v", res.Output, err)
+	}
+	if _, _, err := d.Dispatch(ctx, "/tools bogus"); err == nil {
+		t.Error("expected an error for an unknown profile")
+	}
+}
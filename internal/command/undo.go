@@ -0,0 +1,54 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gavinyap/stormtrooper/internal/undo"
+)
+
+// Undo returns the /undo [id|list] command, which reverts one logged
+// change to a file: the most recent by default, or the one with the
+// given ID. /undo list shows the changes that can be undone.
+func Undo(log *undo.Log) Command {
+	return Command{
+		Name:  "undo",
+		Usage: "/undo [id|list]",
+		Help:  "Undo the last file change, or the change with the given ID; list shows them",
+		Run: func(_ context.Context, args []string) (string, error) {
+			if len(args) > 1 {
+				return "", fmt.Errorf("usage: /undo [id|list]")
+			}
+			if len(args) == 1 && args[0] == "list" {
+				ops := log.Ops()
+				if len(ops) == 0 {
+					return "No file changes to undo.", nil
+				}
+				var b strings.Builder
+				b.WriteString("File changes, newest first:")
+				for i := len(ops) - 1; i >= 0; i-- {
+					fmt.Fprintf(&b, "\n  %s  %s", ops[i].Time.Format("2006-01-02 15:04:05"), ops[i])
+				}
+				return b.String(), nil
+			}
+
+			var op undo.Op
+			var err error
+			if len(args) == 0 {
+				op, err = log.UndoLast()
+			} else {
+				id, convErr := strconv.Atoi(strings.TrimPrefix(args[0], "#"))
+				if convErr != nil {
+					return "", fmt.Errorf("usage: /undo [id|list], where id is a change listed by /undo list")
+				}
+				op, err = log.Undo(id)
+			}
+			if err != nil {
+				return "", err
+			}
+			return "Undid " + op.Reverted() + ".", nil
+		},
+	}
+}
@@ -0,0 +1,69 @@
+package command
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gavinyap/stormtrooper/internal/undo"
+)
+
+func TestUndoCommand(t *testing.T) {
+	dir := t.TempDir()
+	log, err := undo.Open(filepath.Join(dir, "undo"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd := Undo(log)
+
+	if out, _ := cmd.Run(context.Background(), []string{"list"}); out != "No file changes to undo." {
+		t.Errorf("empty list = %q", out)
+	}
+	if _, err := cmd.Run(context.Background(), nil); err == nil {
+		t.Error("/undo with nothing to undo should fail")
+	}
+
+	a := filepath.Join(dir, "a.txt")
+	b := filepath.Join(dir, "b.txt")
+	os.WriteFile(a, []byte("original"), 0644)
+	log.FS().WriteFile(a, []byte("edited"), 0644)
+	log.FS().WriteFile(b, []byte("new"), 0644)
+
+	out, err := cmd.Run(context.Background(), []string{"list"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "#2: created "+b) || strings.Index(out, "#2") > strings.Index(out, "#1: changed "+a) {
+		t.Errorf("list:\n%s", out)
+	}
+
+	for _, args := range [][]string{{"x"}, {"1", "2"}, {"7"}} {
+		if _, err := cmd.Run(context.Background(), args); err == nil {
+			t.Errorf("/undo %v should fail", args)
+		}
+	}
+
+	out, err = cmd.Run(context.Background(), []string{"#1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "Undid #1: restored "+a+"." {
+		t.Errorf("output = %q", out)
+	}
+	if data, _ := os.ReadFile(a); string(data) != "original" {
+		t.Errorf("a.txt = %q", data)
+	}
+
+	out, err = cmd.Run(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "Undid #2: deleted "+b+", which it created." {
+		t.Errorf("output = %q", out)
+	}
+	if _, err := os.Stat(b); !os.IsNotExist(err) {
+		t.Error("b.txt still exists")
+	}
+}
@@ -0,0 +1,42 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/gavinyap/stormtrooper/internal/agent"
+)
+
+// Usage returns the /usage command, which shows the tokens this session
+// has used and what they cost.
+func Usage(ag *agent.Agent) Command {
+	return Command{
+		Name:  "usage",
+		Usage: "/usage",
+		Help:  "Show the tokens used in this session and their estimated cost",
+		Run: func(_ context.Context, args []string) (string, error) {
+			if len(args) > 0 {
+				return "", fmt.Errorf("usage: /usage")
+			}
+			return renderUsage(ag.Usage()), nil
+		},
+	}
+}
+
+// renderUsage formats the totals in u.
+func renderUsage(u agent.Usage) string {
+	if u.Requests == 0 {
+		return "No token usage reported yet."
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Requests:           %d\n", u.Requests)
+	fmt.Fprintf(&b, "Prompt tokens:      %d\n", u.PromptTokens)
+	fmt.Fprintf(&b, "Completion tokens:  %d\n", u.CompletionTokens)
+	fmt.Fprintf(&b, "Total tokens:       %d\n", u.PromptTokens+u.CompletionTokens)
+	fmt.Fprintf(&b, "Estimated cost:     %s", u.FormatCost())
+	if u.Unpriced > 0 {
+		fmt.Fprintf(&b, "\n\n%d request(s) went to models with no reported cost or price and are not in the cost. Set their prices under prices: in the config.", u.Unpriced)
+	}
+	return b.String()
+}
@@ -0,0 +1,35 @@
+package command
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/gavinyap/stormtrooper/internal/agent"
+	"github.com/gavinyap/stormtrooper/internal/tool"
+)
+
+func TestUsageCommand(t *testing.T) {
+	cmd := Usage(agent.New(agent.Options{Registry: tool.NewRegistry(), Model: "test-model"}))
+	if _, err := cmd.Run(context.Background(), []string{"extra"}); err == nil {
+		t.Error("expected a usage error")
+	}
+	if out, err := cmd.Run(context.Background(), nil); err != nil || out != "No token usage reported yet." {
+		t.Errorf("/usage before any request = %q, %v", out, err)
+	}
+
+	out := renderUsage(agent.Usage{Requests: 3, PromptTokens: 12000, CompletionTokens: 800, Cost: 0.0421})
+	for _, want := range []string{"Requests:           3", "Total tokens:       12800", "Estimated cost:     $0.04"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "prices:") {
+		t.Errorf("no note expected when every request is priced:\n%s", out)
+	}
+
+	out = renderUsage(agent.Usage{Requests: 3, PromptTokens: 100, Unpriced: 1})
+	if !strings.Contains(out, "1 request(s) went to models with no reported cost or price") {
+		t.Errorf("expected the unpriced note in:\n%s", out)
+	}
+}
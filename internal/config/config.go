@@ -5,64 +5,641 @@ package config
 import (
 	"errors"
 	"fmt"
+	"net"
+	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
+// projectPath is the project config file, relative to the working directory.
+var projectPath = filepath.Join(".stormtrooper", "config.yaml")
+
 // Config holds all runtime configuration.
 type Config struct {
 	APIKey  string `yaml:"api_key"`
 	Model   string `yaml:"model"`
 	BaseURL string `yaml:"base_url"`
+
+	// Language selects the UI message catalog (e.g. "en", "de_DE").
+	// Empty means detect from LC_ALL / LC_MESSAGES / LANG.
+	Language string `yaml:"language"`
+
+	// Provider selects the LLM backend. Empty means the OpenAI-compatible
+	// API at BaseURL; ProviderMock serves responses from MockScript.
+	Provider   string `yaml:"provider"`
+	MockScript string `yaml:"mock_script"`
+
+	// Profiles are named providers, such as a local Ollama server, that
+	// -profile (or Profile) switches to; see ProfileConfig.
+	Profiles map[string]ProfileConfig `yaml:"profiles"`
+	Profile  string                   `yaml:"profile"`
+
+	// ToolCalling is how tools are offered to the model: "auto"
+	// (default; natively, falling back to describing them in the prompt
+	// if the server rejects them), "native", or "prompt".
+	ToolCalling string `yaml:"tool_calling"`
+
+	// Sandbox runs shell_exec inside a container instead of on the host.
+	Sandbox SandboxConfig `yaml:"sandbox"`
+
+	// Remote runs shell_exec and the file tools on another host over SSH.
+	Remote RemoteConfig `yaml:"remote"`
+
+	// ShellEnv controls which host environment variables the commands
+	// run on the host inherit.
+	ShellEnv ShellEnvConfig `yaml:"shell_env"`
+
+	// Devcontainer controls whether a project's devcontainer.json is used
+	// as the sandbox: "ask" (default), "always", or "never".
+	Devcontainer string `yaml:"devcontainer"`
+
+	// Forge configures the issue and pull request tools.
+	Forge ForgeConfig `yaml:"forge"`
+
+	// Tracker enables the issue tracker tools.
+	Tracker TrackerConfig `yaml:"tracker"`
+
+	// Notify reports the outcome of headless runs (-p and workflows).
+	Notify NotifyConfig `yaml:"notify"`
+
+	// Slack configures "stormtrooper slack".
+	Slack SlackConfig `yaml:"slack"`
+
+	// Databases are the named connections available to db_query.
+	Databases map[string]DatabaseConfig `yaml:"databases"`
+
+	// Markdown controls how the TUI renders assistant messages.
+	Markdown MarkdownConfig `yaml:"markdown"`
+
+	// Hyperlinks controls the clickable file paths in TUI tool messages.
+	Hyperlinks HyperlinkConfig `yaml:"hyperlinks"`
+
+	// Editor is the command /open and the TUI use to open files, with
+	// optional {path} and {line} placeholders (e.g. "code -g {path}:{line}").
+	// Empty means $VISUAL, then $EDITOR, then vi.
+	Editor string `yaml:"editor"`
+
+	// Prune shortens old tool results before each request.
+	Prune PruneConfig `yaml:"prune"`
+
+	// Summarize condenses long tool results with a cheaper model.
+	Summarize SummarizeConfig `yaml:"summarize"`
+
+	// Compact replaces older turns with a summary as the conversation
+	// nears the context window.
+	Compact CompactConfig `yaml:"compact"`
+
+	// SubagentSummary condenses a sub-agent's long result into a report
+	// before it reaches the parent's history.
+	SubagentSummary SubagentSummaryConfig `yaml:"subagent_summary"`
+
+	// Verbosity is "concise", "normal" (default), or "detailed". It adds
+	// an instruction on answer length to the system prompt, and concise
+	// caps each response at ConciseMaxTokens unless MaxTokens is set.
+	Verbosity string `yaml:"verbosity"`
+
+	// ToolProfile limits the tools offered to the model by capability:
+	// "all" (default), "plan" (read-only tools), or "review" (no shell
+	// commands). /tools switches it during a session.
+	ToolProfile string `yaml:"tool_profile"`
+
+	// MaxTokens caps the tokens in each model response; 0 leaves it to
+	// the provider.
+	MaxTokens int `yaml:"max_tokens"`
+
+	// MaxStreamLineMB is the longest line, in megabytes, accepted in a
+	// streamed response; 0 means the client's default of 64.
+	MaxStreamLineMB int `yaml:"max_stream_line_mb"`
+
+	// MaxContinuations caps the follow-up requests that finish a response
+	// cut off by max_tokens; 0 means the agent's default of 3 and negative
+	// turns continuation off.
+	MaxContinuations int `yaml:"max_continuations"`
+
+	// Stop sequences are sent with every agent request; the model's
+	// response ends where one of them would appear.
+	Stop []string `yaml:"stop"`
+
+	// Temperature and TopP are sent with every agent request when set;
+	// otherwise the provider's defaults apply.
+	Temperature *float64 `yaml:"temperature"`
+	TopP        *float64 `yaml:"top_p"`
+
+	// Models overrides the sampling parameters for particular models,
+	// keyed by model name, such as a reasoning model that only accepts
+	// a temperature of 1.
+	Models map[string]ModelConfig `yaml:"models"`
+
+	// RepeatGuard stops a response that repeats itself and asks again.
+	RepeatGuard RepeatGuardConfig `yaml:"repeat_guard"`
+
+	// Concurrency is how many LLM requests may be in flight at once, so
+	// sub-agents do not trip the provider's rate limits. Negative means
+	// no limit.
+	Concurrency int `yaml:"concurrency"`
+
+	// Retry controls how LLM requests that fail with a rate limit, a
+	// server error, or a network timeout are tried again.
+	Retry RetryConfig `yaml:"retry"`
+
+	// Route sends simple turns, such as a short question, to a cheaper
+	// model, keeping Model for coding work.
+	Route RouteConfig `yaml:"route"`
+
+	// Prices are what models cost, keyed by model name, for estimating
+	// a session's cost when the provider does not report it.
+	Prices map[string]PriceConfig `yaml:"prices"`
+
+	// Offline is for air-gapped environments: the provider must be a
+	// local one, such as Ollama or a llama.cpp server, and tools may not
+	// reach the network. Once a layer turns it on, later ones cannot
+	// turn it off.
+	Offline bool `yaml:"offline"`
+}
+
+// ConciseMaxTokens is the response cap of the "concise" verbosity. It
+// leaves room for tool calls, such as write_file, whose arguments count
+// toward the cap.
+const ConciseMaxTokens = 4096
+
+// ResponseMaxTokens returns the response cap in effect: MaxTokens if set,
+// otherwise the verbosity's default.
+func (c *Config) ResponseMaxTokens() int {
+	if c.MaxTokens == 0 && c.Verbosity == "concise" {
+		return ConciseMaxTokens
+	}
+	return c.MaxTokens
+}
+
+// ProfileConfig is a named provider. Selecting it replaces the base URL
+// and API key, so a key meant for one provider is never sent to another,
+// and the model and tool calling mode when set.
+type ProfileConfig struct {
+	BaseURL     string `yaml:"base_url"`     // e.g. "http://localhost:11434/v1" for Ollama
+	Model       string `yaml:"model"`        // e.g. "llama3.1"
+	APIKey      string `yaml:"api_key"`      // empty for local servers
+	ToolCalling string `yaml:"tool_calling"` // as Config.ToolCalling
+}
+
+// ModelConfig overrides the sampling parameters for one model. Unset
+// fields keep the values that apply to every model.
+type ModelConfig struct {
+	Temperature *float64 `yaml:"temperature"`
+	TopP        *float64 `yaml:"top_p"`
+	MaxTokens   int      `yaml:"max_tokens"`
+	Stop        []string `yaml:"stop"`
+}
+
+// PruneConfig controls when old tool results are replaced by a summary.
+type PruneConfig struct {
+	AfterTurns int `yaml:"after_turns"` // turns that keep full results (default 4); negative disables pruning
+	MinBytes   int `yaml:"min_bytes"`   // results up to this size are never pruned (default 2048)
+}
+
+// SummarizeConfig controls when long tool results are summarized.
+type SummarizeConfig struct {
+	Model     string `yaml:"model"`      // model that writes the summaries; empty disables summarization
+	MinTokens int    `yaml:"min_tokens"` // estimated result size that triggers a summary (default 4000)
+}
+
+// CompactConfig controls when older turns are summarized.
+type CompactConfig struct {
+	Threshold int    `yaml:"threshold"`  // estimated request tokens that trigger it (default 96000); negative disables it
+	KeepTurns int    `yaml:"keep_turns"` // recent turns kept as they are (default 2)
+	Model     string `yaml:"model"`      // model that writes the summary; empty means the session's model
+}
+
+// SubagentSummaryConfig controls when a sub-agent's result is summarized.
+type SubagentSummaryConfig struct {
+	Threshold int    `yaml:"threshold"` // estimated result tokens that trigger it (default 2000); negative disables it
+	Model     string `yaml:"model"`     // model that writes the report; empty means the sub-agent's model
+}
+
+// RepeatGuardConfig controls the guard against responses stuck repeating
+// the same sentence or tokens.
+type RepeatGuardConfig struct {
+	MinRepeats int     `yaml:"min_repeats"` // back-to-back copies that stop a response (default 8); -1 disables the guard
+	Penalty    float64 `yaml:"penalty"`     // frequency_penalty when asking again (default 0.5)
+}
+
+// RouteConfig controls routing of simple turns to a cheaper model.
+type RouteConfig struct {
+	Model    string `yaml:"model"`     // model for simple turns; empty (default) turns routing off
+	MaxChars int    `yaml:"max_chars"` // longest prompt that can count as simple (default 300)
+}
+
+// RetryConfig controls retries of transient LLM request failures, with
+// exponential backoff.
+type RetryConfig struct {
+	MaxRetries int           `yaml:"max_retries"` // retries after the first attempt (default 3); negative disables them
+	BaseDelay  time.Duration `yaml:"base_delay"`  // wait before the first retry, doubled for each one after (default 1s)
+}
+
+// PriceConfig is what a model costs, in US dollars per million tokens.
+type PriceConfig struct {
+	Prompt     float64 `yaml:"prompt"`
+	Completion float64 `yaml:"completion"`
+}
+
+// HyperlinkConfig controls OSC 8 hyperlinks on file paths in the TUI.
+type HyperlinkConfig struct {
+	Enabled string `yaml:"enabled"` // "auto" (default; terminals known to support them), "always", or "never"
+	URL     string `yaml:"url"`     // template with {path} and {line}; default "file://{path}", e.g. "vscode://file/{path}:{line}"
+}
+
+// MarkdownConfig selects the TUI's markdown renderer options.
+type MarkdownConfig struct {
+	Style    string `yaml:"style"`     // "dark" (default), "light", "notty", another glamour style, or a JSON style file
+	WordWrap int    `yaml:"word_wrap"` // columns; 0 fits the chat panel
+}
+
+// DatabaseConfig is a db_query connection.
+type DatabaseConfig struct {
+	Driver      string `yaml:"driver"`       // "postgres", "mysql", or "sqlite"
+	DSN         string `yaml:"dsn"`          // connection URL, or the file path for sqlite
+	AllowWrites bool   `yaml:"allow_writes"` // permit non-SELECT statements (still prompts)
+}
+
+// ForgeConfig overrides what is detected from the origin remote.
+type ForgeConfig struct {
+	Type  string `yaml:"type"`  // "github", "gitlab", or "bitbucket"; detected from the remote host when empty
+	URL   string `yaml:"url"`   // API base URL, for self-hosted instances
+	Token string `yaml:"token"` // default: $GITHUB_TOKEN, $GITLAB_TOKEN, or $BITBUCKET_TOKEN
+}
+
+// SandboxConfig describes the per-session container for shell_exec.
+// The sandbox is enabled when Image is set.
+type SandboxConfig struct {
+	Engine  string   `yaml:"engine"`  // "docker" (default) or "podman"
+	Image   string   `yaml:"image"`   // e.g. "golang:1.25"
+	Network string   `yaml:"network"` // "none" (default), "bridge", or "host"
+	Mounts  []string `yaml:"mounts"`  // extra bind mounts, "host:container[:ro]"
+
+	// Env sets environment variables inside the container.
+	Env map[string]string `yaml:"env"`
+}
+
+// ShellEnvConfig filters the host environment passed to commands.
+// Patterns are shell globs matched against variable names, ignoring case.
+type ShellEnvConfig struct {
+	Allow []string `yaml:"allow"` // when set, only matching variables are inherited
+	Strip []string `yaml:"strip"` // matching variables are never inherited (default DefaultStripEnv; [] strips nothing)
+}
+
+// DefaultStripEnv keeps the usual secret-bearing variables, such as
+// OPENROUTER_API_KEY and GITHUB_TOKEN, out of the agent's commands.
+var DefaultStripEnv = []string{
+	"*TOKEN*", "*SECRET*", "*PASSWORD*", "*PASSWD*",
+	"*API_KEY*", "*APIKEY*", "*ACCESS_KEY*", "*PRIVATE_KEY*", "*CREDENTIAL*",
+}
+
+// RemoteConfig describes the SSH target for remote execution. Remote
+// execution is enabled when Host is set.
+type RemoteConfig struct {
+	Host    string `yaml:"host"`    // "user@build-server" or an ~/.ssh/config alias
+	Port    int    `yaml:"port"`    // default: ssh's own default
+	Key     string `yaml:"key"`     // identity file (optional)
+	WorkDir string `yaml:"workdir"` // remote project directory
+}
+
+// TrackerConfig configures the Jira or Linear issue tools. They are
+// enabled when Type is set.
+type TrackerConfig struct {
+	Type  string `yaml:"type"`  // "jira" or "linear"
+	URL   string `yaml:"url"`   // Jira site, e.g. "https://acme.atlassian.net"
+	Email string `yaml:"email"` // Jira Cloud account email; empty for a Jira Server token
+	Token string `yaml:"token"` // default: $JIRA_API_TOKEN or $LINEAR_API_KEY
+}
+
+// NotifyConfig configures where headless run reports are sent.
+type NotifyConfig struct {
+	// On is "always" (default) or "failure".
+	On       string          `yaml:"on"`
+	Webhooks []WebhookConfig `yaml:"webhooks"`
+	Email    EmailConfig     `yaml:"email"`
+}
+
+// WebhookConfig is a URL that receives run reports.
+type WebhookConfig struct {
+	URL string `yaml:"url"`
+	// Format is "slack", "discord", or "json"; default: slack or discord
+	// for their webhook URLs, otherwise json.
+	Format string `yaml:"format"`
+}
+
+// EmailConfig sends run reports over SMTP. It is enabled when SMTP is set.
+type EmailConfig struct {
+	SMTP     string   `yaml:"smtp"`     // host:port, e.g. "smtp.example.com:587"
+	Username string   `yaml:"username"` // empty for no authentication
+	Password string   `yaml:"password"` // default: $SMTP_PASSWORD
+	From     string   `yaml:"from"`
+	To       []string `yaml:"to"`
+}
+
+// SlackConfig configures the Slack bot. Both tokens come from a Slack app
+// with Socket Mode enabled.
+type SlackConfig struct {
+	AppToken string   `yaml:"app_token"` // xapp-... token; default: $SLACK_APP_TOKEN
+	BotToken string   `yaml:"bot_token"` // xoxb-... token; default: $SLACK_BOT_TOKEN
+	Channels []string `yaml:"channels"`  // channel IDs the bot answers in; empty means any it is invited to
+}
+
+// ProviderMock is the offline provider driven by a YAML script.
+const ProviderMock = "mock"
+
+// OfflineEnv turns offline mode on when set to a true value, such as
+// "1". Offline runs set it so the sessions they start stay offline too.
+const OfflineEnv = "STORMTROOPER_OFFLINE"
+
+// isLocalURL reports whether rawURL points at this machine.
+func isLocalURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
 }
 
 // defaults returns a Config populated with hardcoded default values.
 func defaults() Config {
 	return Config{
-		Model:   "moonshotai/kimi-k2",
-		BaseURL: "https://openrouter.ai/api/v1",
+		Model:       "moonshotai/kimi-k2",
+		BaseURL:     "https://openrouter.ai/api/v1",
+		Concurrency: 4,
+		Retry:       RetryConfig{MaxRetries: 3, BaseDelay: time.Second},
+		Prune:       PruneConfig{AfterTurns: 4, MinBytes: 2048},
+
+		Summarize: SummarizeConfig{MinTokens: 4000},
+		Compact:   CompactConfig{Threshold: 96000, KeepTurns: 2},
+		ShellEnv:  ShellEnvConfig{Strip: DefaultStripEnv},
+
+		SubagentSummary: SubagentSummaryConfig{Threshold: 2000},
 	}
 }
 
+// LoadOptions controls which layers Load consults.
+type LoadOptions struct {
+	CLIModel string // --model flag value (empty string if not set)
+
+	// CLIVerbosity and CLIMaxTokens are the --verbosity and --max-tokens
+	// flag values (zero if not set).
+	CLIVerbosity string
+	CLIMaxTokens int
+
+	// CLITemperature, CLITopP, and CLIStop are the --temperature,
+	// --top-p, and --stop flag values (nil if not set).
+	CLITemperature *float64
+	CLITopP        *float64
+	CLIStop        []string
+
+	// CLIToolProfile is the --tool-profile flag value (empty if not set).
+	CLIToolProfile string
+
+	// CLIOffline is the --offline flag.
+	CLIOffline bool
+
+	// CLIProfile is the --profile flag value (empty if not set).
+	CLIProfile string
+
+	// SkipProject ignores .stormtrooper/config.yaml in the working
+	// directory. Used for untrusted workspaces, whose project config
+	// could otherwise redirect base_url and capture the API key.
+	SkipProject bool
+
+	// AllowMissingKey skips the API key check. Used by --replay, which
+	// never contacts the provider.
+	AllowMissingKey bool
+}
+
 // Load reads config from all layers and returns the merged result.
 // cliModel is the --model flag value (empty string if not set).
 func Load(cliModel string) (*Config, error) {
+	return LoadWithOptions(LoadOptions{CLIModel: cliModel})
+}
+
+// LoadWithOptions is Load with control over which layers are read.
+func LoadWithOptions(opts LoadOptions) (*Config, error) {
 	cfg := defaults()
 
 	// Layer 2: Global config
-	if home, err := os.UserHomeDir(); err == nil {
-		globalPath := filepath.Join(home, ".stormtrooper", "config.yaml")
+	if globalPath := GlobalPath(); globalPath != "" {
 		if err := mergeFromFile(&cfg, globalPath); err != nil {
 			return nil, fmt.Errorf("global config %s: %w", globalPath, err)
 		}
 	}
 
 	// Layer 3: Project config
-	projectPath := filepath.Join(".stormtrooper", "config.yaml")
-	if err := mergeFromFile(&cfg, projectPath); err != nil {
-		return nil, fmt.Errorf("project config %s: %w", projectPath, err)
+	if !opts.SkipProject {
+		if err := mergeFromFile(&cfg, projectPath); err != nil {
+			return nil, fmt.Errorf("project config %s: %w", projectPath, err)
+		}
 	}
 
 	// Layer 4: Environment variables
 	if key := os.Getenv("OPENROUTER_API_KEY"); key != "" {
 		cfg.APIKey = key
 	}
+	if offline, _ := strconv.ParseBool(os.Getenv(OfflineEnv)); offline {
+		cfg.Offline = true
+	}
 
-	// Layer 5: CLI flags
-	if cliModel != "" {
-		cfg.Model = cliModel
+	// Layer 5: CLI flags. A profile comes first, so --model still wins.
+	if opts.CLIProfile != "" {
+		cfg.Profile = opts.CLIProfile
+	}
+	if cfg.Profile != "" {
+		if err := cfg.applyProfile(cfg.Profile); err != nil {
+			return nil, err
+		}
+	}
+	if opts.CLIModel != "" {
+		cfg.Model = opts.CLIModel
+	}
+	if opts.CLIVerbosity != "" {
+		cfg.Verbosity = opts.CLIVerbosity
+	}
+	if opts.CLIMaxTokens != 0 {
+		cfg.MaxTokens = opts.CLIMaxTokens
+	}
+	if opts.CLITemperature != nil {
+		cfg.Temperature = opts.CLITemperature
+	}
+	if opts.CLITopP != nil {
+		cfg.TopP = opts.CLITopP
+	}
+	if len(opts.CLIStop) > 0 {
+		cfg.Stop = opts.CLIStop
+	}
+	if opts.CLIToolProfile != "" {
+		cfg.ToolProfile = opts.CLIToolProfile
+	}
+	if opts.CLIOffline {
+		cfg.Offline = true
 	}
 
 	// Validate
-	if cfg.APIKey == "" {
+	if cfg.Sandbox.Image != "" && cfg.Remote.Host != "" {
+		return nil, errors.New("sandbox and remote cannot both be configured")
+	}
+	switch cfg.Devcontainer {
+	case "", "ask", "always", "never":
+	default:
+		return nil, fmt.Errorf("devcontainer: unsupported value %q (use ask, always, or never)", cfg.Devcontainer)
+	}
+	switch cfg.Hyperlinks.Enabled {
+	case "", "auto", "always", "never":
+	default:
+		return nil, fmt.Errorf("hyperlinks.enabled: unsupported value %q (use auto, always, or never)", cfg.Hyperlinks.Enabled)
+	}
+	switch cfg.Verbosity {
+	case "", "concise", "normal", "detailed":
+	default:
+		return nil, fmt.Errorf("verbosity: unsupported value %q (use concise, normal, or detailed)", cfg.Verbosity)
+	}
+	switch cfg.ToolProfile {
+	case "", "all", "plan", "review":
+	default:
+		return nil, fmt.Errorf("tool_profile: unsupported value %q (use all, plan, or review)", cfg.ToolProfile)
+	}
+	switch cfg.ToolCalling {
+	case "", "auto", "native", "prompt":
+	default:
+		return nil, fmt.Errorf("tool_calling: unsupported value %q (use auto, native, or prompt)", cfg.ToolCalling)
+	}
+	switch cfg.Notify.On {
+	case "", "always", "failure":
+	default:
+		return nil, fmt.Errorf("notify.on: unsupported value %q (use always or failure)", cfg.Notify.On)
+	}
+	for _, w := range cfg.Notify.Webhooks {
+		switch w.Format {
+		case "", "slack", "discord", "json":
+		default:
+			return nil, fmt.Errorf("notify.webhooks: unsupported format %q (use slack, discord, or json)", w.Format)
+		}
+	}
+	if cfg.Notify.Email.SMTP != "" && (cfg.Notify.Email.From == "" || len(cfg.Notify.Email.To) == 0) {
+		return nil, errors.New("notify.email: from and to are required with smtp")
+	}
+	if cfg.MaxTokens < 0 {
+		return nil, fmt.Errorf("max_tokens: must not be negative, got %d", cfg.MaxTokens)
+	}
+	if err := validateSampling("", cfg.Temperature, cfg.TopP); err != nil {
+		return nil, err
+	}
+	for model, m := range cfg.Models {
+		if err := validateSampling("models."+model+".", m.Temperature, m.TopP); err != nil {
+			return nil, err
+		}
+		if m.MaxTokens < 0 {
+			return nil, fmt.Errorf("models.%s.max_tokens: must not be negative, got %d", model, m.MaxTokens)
+		}
+	}
+	if cfg.MaxStreamLineMB < 0 {
+		return nil, fmt.Errorf("max_stream_line_mb: must not be negative, got %d", cfg.MaxStreamLineMB)
+	}
+	if cfg.RepeatGuard.Penalty < -2 || cfg.RepeatGuard.Penalty > 2 {
+		return nil, fmt.Errorf("repeat_guard.penalty: must be between -2 and 2, got %g", cfg.RepeatGuard.Penalty)
+	}
+	if cfg.Route.MaxChars < 0 {
+		return nil, fmt.Errorf("route.max_chars: must not be negative, got %d", cfg.Route.MaxChars)
+	}
+	if cfg.Retry.BaseDelay < 0 {
+		return nil, fmt.Errorf("retry.base_delay: must not be negative, got %s", cfg.Retry.BaseDelay)
+	}
+	if cfg.Summarize.MinTokens < 0 {
+		return nil, fmt.Errorf("summarize.min_tokens: must not be negative, got %d", cfg.Summarize.MinTokens)
+	}
+	if cfg.Compact.KeepTurns < 0 {
+		return nil, fmt.Errorf("compact.keep_turns: must not be negative, got %d", cfg.Compact.KeepTurns)
+	}
+	if cfg.Prune.MinBytes < 0 {
+		return nil, fmt.Errorf("prune.min_bytes: must not be negative, got %d", cfg.Prune.MinBytes)
+	}
+	if cfg.Markdown.WordWrap < 0 {
+		return nil, fmt.Errorf("markdown.word_wrap: must not be negative, got %d", cfg.Markdown.WordWrap)
+	}
+	if cfg.Provider == ProviderMock && cfg.MockScript == "" {
+		return nil, errors.New("provider: mock requires mock_script to point at a YAML script")
+	}
+	if cfg.Offline {
+		if cfg.Provider != ProviderMock && !isLocalURL(cfg.BaseURL) {
+			return nil, fmt.Errorf("offline: base_url %s is not a local provider; point it at Ollama (http://localhost:11434/v1) or a llama.cpp server (http://localhost:8080/v1)", cfg.BaseURL)
+		}
+		if cfg.Remote.Host != "" {
+			return nil, errors.New("offline: remote execution needs the network")
+		}
+		// Containers get no network either.
+		cfg.Sandbox.Network = "none"
+	}
+	// Local providers do not need a key.
+	if cfg.APIKey == "" && !opts.AllowMissingKey && cfg.Provider != ProviderMock && !cfg.Offline && !isLocalURL(cfg.BaseURL) {
 		return nil, errors.New("OPENROUTER_API_KEY not set. Set it as an environment variable or in ~/.stormtrooper/config.yaml")
 	}
 
 	return &cfg, nil
 }
 
+// validateSampling checks a temperature and top_p, named with prefix in
+// errors.
+func validateSampling(prefix string, temperature, topP *float64) error {
+	if temperature != nil && (*temperature < 0 || *temperature > 2) {
+		return fmt.Errorf("%stemperature: must be between 0 and 2, got %g", prefix, *temperature)
+	}
+	if topP != nil && (*topP <= 0 || *topP > 1) {
+		return fmt.Errorf("%stop_p: must be above 0 and at most 1, got %g", prefix, *topP)
+	}
+	return nil
+}
+
+// applyProfile switches to the named profile.
+func (c *Config) applyProfile(name string) error {
+	p, ok := c.Profiles[name]
+	if !ok {
+		var names []string
+		for n := range c.Profiles {
+			names = append(names, n)
+		}
+		if len(names) == 0 {
+			return fmt.Errorf("profile %q: no profiles are configured", name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("profile %q is not configured (have %s)", name, strings.Join(names, ", "))
+	}
+	if p.BaseURL == "" {
+		return fmt.Errorf("profile %q: base_url is required", name)
+	}
+	c.BaseURL, c.APIKey = p.BaseURL, p.APIKey
+	if p.Model != "" {
+		c.Model = p.Model
+	}
+	if p.ToolCalling != "" {
+		c.ToolCalling = p.ToolCalling
+	}
+	return nil
+}
+
+// GlobalPath returns the path of the global config file
+// (~/.stormtrooper/config.yaml), or "" if the home directory is unknown.
+func GlobalPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".stormtrooper", "config.yaml")
+}
+
 // mergeFromFile reads a YAML config file and merges non-zero values into cfg.
 // If the file does not exist, it is silently skipped.
 func mergeFromFile(cfg *Config, path string) error {
@@ -88,6 +665,186 @@ func mergeFromFile(cfg *Config, path string) error {
 	if fileCfg.BaseURL != "" {
 		cfg.BaseURL = fileCfg.BaseURL
 	}
+	if fileCfg.Language != "" {
+		cfg.Language = fileCfg.Language
+	}
+	if fileCfg.Provider != "" {
+		cfg.Provider = fileCfg.Provider
+	}
+	if fileCfg.MockScript != "" {
+		cfg.MockScript = fileCfg.MockScript
+	}
+	for name, p := range fileCfg.Profiles {
+		if cfg.Profiles == nil {
+			cfg.Profiles = make(map[string]ProfileConfig)
+		}
+		cfg.Profiles[name] = p
+	}
+	if fileCfg.Profile != "" {
+		cfg.Profile = fileCfg.Profile
+	}
+	if fileCfg.ToolCalling != "" {
+		cfg.ToolCalling = fileCfg.ToolCalling
+	}
+	if fileCfg.Devcontainer != "" {
+		cfg.Devcontainer = fileCfg.Devcontainer
+	}
+	if fileCfg.Markdown.Style != "" {
+		cfg.Markdown.Style = fileCfg.Markdown.Style
+	}
+	if fileCfg.Markdown.WordWrap != 0 {
+		cfg.Markdown.WordWrap = fileCfg.Markdown.WordWrap
+	}
+	if fileCfg.Hyperlinks.Enabled != "" {
+		cfg.Hyperlinks.Enabled = fileCfg.Hyperlinks.Enabled
+	}
+	if fileCfg.Hyperlinks.URL != "" {
+		cfg.Hyperlinks.URL = fileCfg.Hyperlinks.URL
+	}
+	if fileCfg.Editor != "" {
+		cfg.Editor = fileCfg.Editor
+	}
+	if fileCfg.Verbosity != "" {
+		cfg.Verbosity = fileCfg.Verbosity
+	}
+	if fileCfg.MaxTokens != 0 {
+		cfg.MaxTokens = fileCfg.MaxTokens
+	}
+	if fileCfg.MaxStreamLineMB != 0 {
+		cfg.MaxStreamLineMB = fileCfg.MaxStreamLineMB
+	}
+	if fileCfg.ToolProfile != "" {
+		cfg.ToolProfile = fileCfg.ToolProfile
+	}
+	if fileCfg.MaxContinuations != 0 {
+		cfg.MaxContinuations = fileCfg.MaxContinuations
+	}
+	if fileCfg.Concurrency != 0 {
+		cfg.Concurrency = fileCfg.Concurrency
+	}
+	if len(fileCfg.Stop) > 0 {
+		cfg.Stop = fileCfg.Stop
+	}
+	if fileCfg.Temperature != nil {
+		cfg.Temperature = fileCfg.Temperature
+	}
+	if fileCfg.TopP != nil {
+		cfg.TopP = fileCfg.TopP
+	}
+	for model, m := range fileCfg.Models {
+		if cfg.Models == nil {
+			cfg.Models = make(map[string]ModelConfig)
+		}
+		cfg.Models[model] = m
+	}
+	if fileCfg.RepeatGuard.MinRepeats != 0 {
+		cfg.RepeatGuard.MinRepeats = fileCfg.RepeatGuard.MinRepeats
+	}
+	if fileCfg.RepeatGuard.Penalty != 0 {
+		cfg.RepeatGuard.Penalty = fileCfg.RepeatGuard.Penalty
+	}
+	if fileCfg.Route.Model != "" {
+		cfg.Route.Model = fileCfg.Route.Model
+	}
+	if fileCfg.Route.MaxChars != 0 {
+		cfg.Route.MaxChars = fileCfg.Route.MaxChars
+	}
+	if fileCfg.Retry.MaxRetries != 0 {
+		cfg.Retry.MaxRetries = fileCfg.Retry.MaxRetries
+	}
+	if fileCfg.Retry.BaseDelay != 0 {
+		cfg.Retry.BaseDelay = fileCfg.Retry.BaseDelay
+	}
+	if fileCfg.Offline {
+		cfg.Offline = true
+	}
+	if fileCfg.Prune.AfterTurns != 0 {
+		cfg.Prune.AfterTurns = fileCfg.Prune.AfterTurns
+	}
+	if fileCfg.Prune.MinBytes != 0 {
+		cfg.Prune.MinBytes = fileCfg.Prune.MinBytes
+	}
+	if fileCfg.Summarize.Model != "" {
+		cfg.Summarize.Model = fileCfg.Summarize.Model
+	}
+	if fileCfg.Summarize.MinTokens != 0 {
+		cfg.Summarize.MinTokens = fileCfg.Summarize.MinTokens
+	}
+	if fileCfg.Compact.Threshold != 0 {
+		cfg.Compact.Threshold = fileCfg.Compact.Threshold
+	}
+	if fileCfg.Compact.KeepTurns != 0 {
+		cfg.Compact.KeepTurns = fileCfg.Compact.KeepTurns
+	}
+	if fileCfg.Compact.Model != "" {
+		cfg.Compact.Model = fileCfg.Compact.Model
+	}
+	if fileCfg.SubagentSummary.Threshold != 0 {
+		cfg.SubagentSummary.Threshold = fileCfg.SubagentSummary.Threshold
+	}
+	if fileCfg.SubagentSummary.Model != "" {
+		cfg.SubagentSummary.Model = fileCfg.SubagentSummary.Model
+	}
+	if fileCfg.ShellEnv.Allow != nil {
+		cfg.ShellEnv.Allow = fileCfg.ShellEnv.Allow
+	}
+	if fileCfg.ShellEnv.Strip != nil {
+		cfg.ShellEnv.Strip = fileCfg.ShellEnv.Strip
+	}
+	if fileCfg.Forge.Type != "" {
+		cfg.Forge.Type = fileCfg.Forge.Type
+	}
+	if fileCfg.Forge.URL != "" {
+		cfg.Forge.URL = fileCfg.Forge.URL
+	}
+	if fileCfg.Forge.Token != "" {
+		cfg.Forge.Token = fileCfg.Forge.Token
+	}
+	for name, db := range fileCfg.Databases {
+		if cfg.Databases == nil {
+			cfg.Databases = make(map[string]DatabaseConfig)
+		}
+		cfg.Databases[name] = db
+	}
+	for model, price := range fileCfg.Prices {
+		if cfg.Prices == nil {
+			cfg.Prices = make(map[string]PriceConfig)
+		}
+		cfg.Prices[model] = price
+	}
+	if fileCfg.Tracker.Type != "" {
+		// Tracker settings belong together; a project switching from Jira
+		// to Linear must not inherit the Jira URL.
+		cfg.Tracker = fileCfg.Tracker
+	}
+	if fileCfg.Notify.On != "" {
+		cfg.Notify.On = fileCfg.Notify.On
+	}
+	if len(fileCfg.Notify.Webhooks) > 0 {
+		cfg.Notify.Webhooks = fileCfg.Notify.Webhooks
+	}
+	if fileCfg.Notify.Email.SMTP != "" {
+		// Like the tracker, an email block replaces the lower layer's so
+		// credentials for one server are never sent to another.
+		cfg.Notify.Email = fileCfg.Notify.Email
+	}
+	if fileCfg.Slack.AppToken != "" {
+		cfg.Slack.AppToken = fileCfg.Slack.AppToken
+	}
+	if fileCfg.Slack.BotToken != "" {
+		cfg.Slack.BotToken = fileCfg.Slack.BotToken
+	}
+	if len(fileCfg.Slack.Channels) > 0 {
+		cfg.Slack.Channels = fileCfg.Slack.Channels
+	}
+	if fileCfg.Remote.Host != "" {
+		cfg.Remote = fileCfg.Remote
+	}
+	if fileCfg.Sandbox.Image != "" {
+		// A sandbox block replaces the lower layer's as a whole, so mounts
+		// and network policy are never mixed between files.
+		cfg.Sandbox = fileCfg.Sandbox
+	}
 
 	return nil
 }
@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestDefaults(t *testing.T) {
@@ -54,6 +55,533 @@ func TestMergeFromFile_ValidYAML(t *testing.T) {
 	}
 }
 
+func TestMergeFromFile_Language(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	os.WriteFile(path, []byte("language: de_DE\n"), 0644)
+
+	cfg := defaults()
+	if err := mergeFromFile(&cfg, path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Language != "de_DE" {
+		t.Errorf("expected language 'de_DE', got %q", cfg.Language)
+	}
+}
+
+func TestMergeFromFile_MarkdownFieldwise(t *testing.T) {
+	dir := t.TempDir()
+	global := filepath.Join(dir, "global.yaml")
+	project := filepath.Join(dir, "project.yaml")
+	os.WriteFile(global, []byte("markdown:\n  style: light\n  word_wrap: 100\n"), 0644)
+	os.WriteFile(project, []byte("markdown:\n  style: notty\n"), 0644)
+
+	cfg := defaults()
+	mergeFromFile(&cfg, global)
+	mergeFromFile(&cfg, project)
+	if cfg.Markdown.Style != "notty" || cfg.Markdown.WordWrap != 100 {
+		t.Errorf("markdown = %+v", cfg.Markdown)
+	}
+}
+
+func TestMergeFromFile_SandboxReplacedAsWhole(t *testing.T) {
+	dir := t.TempDir()
+	global := filepath.Join(dir, "global.yaml")
+	project := filepath.Join(dir, "project.yaml")
+	os.WriteFile(global, []byte("sandbox:\n  image: alpine\n  network: bridge\n  mounts: [/cache:/cache]\n"), 0644)
+	os.WriteFile(project, []byte("sandbox:\n  image: golang:1.25\n"), 0644)
+
+	cfg := defaults()
+	mergeFromFile(&cfg, global)
+	mergeFromFile(&cfg, project)
+
+	if cfg.Sandbox.Image != "golang:1.25" {
+		t.Errorf("expected project image, got %q", cfg.Sandbox.Image)
+	}
+	if cfg.Sandbox.Network != "" || len(cfg.Sandbox.Mounts) != 0 {
+		t.Errorf("global sandbox settings should not leak into project sandbox: %+v", cfg.Sandbox)
+	}
+}
+
+func TestMergeFromFile_Remote(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	os.WriteFile(path, []byte("remote:\n  host: ci@build-1\n  port: 2222\n  workdir: /srv/app\n"), 0644)
+
+	cfg := defaults()
+	if err := mergeFromFile(&cfg, path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := RemoteConfig{Host: "ci@build-1", Port: 2222, WorkDir: "/srv/app"}
+	if cfg.Remote != want {
+		t.Errorf("expected %+v, got %+v", want, cfg.Remote)
+	}
+}
+
+func TestMergeFromFile_ForgeFieldwise(t *testing.T) {
+	dir := t.TempDir()
+	global := filepath.Join(dir, "global.yaml")
+	project := filepath.Join(dir, "project.yaml")
+	os.WriteFile(global, []byte("forge:\n  token: secret\n"), 0644)
+	os.WriteFile(project, []byte("forge:\n  type: gitlab\n  url: https://git.acme.com/api/v4\n"), 0644)
+
+	cfg := defaults()
+	mergeFromFile(&cfg, global)
+	mergeFromFile(&cfg, project)
+
+	want := ForgeConfig{Type: "gitlab", URL: "https://git.acme.com/api/v4", Token: "secret"}
+	if cfg.Forge != want {
+		t.Errorf("expected %+v, got %+v", want, cfg.Forge)
+	}
+}
+
+func TestMergeFromFile_TrackerReplacedAsWhole(t *testing.T) {
+	dir := t.TempDir()
+	global := filepath.Join(dir, "global.yaml")
+	project := filepath.Join(dir, "project.yaml")
+	os.WriteFile(global, []byte("tracker:\n  type: jira\n  url: https://acme.atlassian.net\n  email: me@acme.com\n"), 0644)
+	os.WriteFile(project, []byte("tracker:\n  type: linear\n"), 0644)
+
+	cfg := defaults()
+	mergeFromFile(&cfg, global)
+	mergeFromFile(&cfg, project)
+
+	if cfg.Tracker != (TrackerConfig{Type: "linear"}) {
+		t.Errorf("Jira settings should not leak into the Linear tracker: %+v", cfg.Tracker)
+	}
+}
+
+func TestMergeFromFile_Notify(t *testing.T) {
+	dir := t.TempDir()
+	global := filepath.Join(dir, "global.yaml")
+	project := filepath.Join(dir, "project.yaml")
+	os.WriteFile(global, []byte("notify:\n  webhooks:\n    - url: https://hooks.slack.com/services/T/B/X\n  email:\n    smtp: smtp.example.com:587\n    username: bot\n    from: bot@example.com\n    to: [a@example.com]\n"), 0644)
+	os.WriteFile(project, []byte("notify:\n  on: failure\n  email:\n    smtp: localhost:25\n    from: ci@example.com\n    to: [team@example.com]\n"), 0644)
+
+	cfg := defaults()
+	mergeFromFile(&cfg, global)
+	mergeFromFile(&cfg, project)
+
+	if cfg.Notify.On != "failure" {
+		t.Errorf("on = %q, want failure", cfg.Notify.On)
+	}
+	if len(cfg.Notify.Webhooks) != 1 {
+		t.Errorf("webhooks should be kept from the global config: %+v", cfg.Notify.Webhooks)
+	}
+	if cfg.Notify.Email.SMTP != "localhost:25" || cfg.Notify.Email.Username != "" {
+		t.Errorf("the project email settings should replace the global ones: %+v", cfg.Notify.Email)
+	}
+}
+
+func TestMergeFromFile_Slack(t *testing.T) {
+	dir := t.TempDir()
+	global := filepath.Join(dir, "global.yaml")
+	project := filepath.Join(dir, "project.yaml")
+	os.WriteFile(global, []byte("slack:\n  app_token: xapp-1\n  bot_token: xoxb-1\n"), 0644)
+	os.WriteFile(project, []byte("slack:\n  channels: [C123]\n"), 0644)
+
+	cfg := defaults()
+	mergeFromFile(&cfg, global)
+	mergeFromFile(&cfg, project)
+
+	if cfg.Slack.AppToken != "xapp-1" || cfg.Slack.BotToken != "xoxb-1" {
+		t.Errorf("tokens should be kept from the global config: %+v", cfg.Slack)
+	}
+	if len(cfg.Slack.Channels) != 1 || cfg.Slack.Channels[0] != "C123" {
+		t.Errorf("channels = %v, want [C123]", cfg.Slack.Channels)
+	}
+}
+
+func TestMergeFromFile_DatabasesByName(t *testing.T) {
+	dir := t.TempDir()
+	global := filepath.Join(dir, "global.yaml")
+	project := filepath.Join(dir, "project.yaml")
+	os.WriteFile(global, []byte("databases:\n  app:\n    driver: postgres\n    dsn: postgres://global/app\n  metrics:\n    driver: sqlite\n    dsn: m.db\n"), 0644)
+	os.WriteFile(project, []byte("databases:\n  app:\n    driver: postgres\n    dsn: postgres://localhost/app\n    allow_writes: true\n"), 0644)
+
+	cfg := defaults()
+	mergeFromFile(&cfg, global)
+	mergeFromFile(&cfg, project)
+
+	if len(cfg.Databases) != 2 {
+		t.Fatalf("expected both databases, got %+v", cfg.Databases)
+	}
+	if app := cfg.Databases["app"]; app.DSN != "postgres://localhost/app" || !app.AllowWrites {
+		t.Errorf("project should override app: %+v", app)
+	}
+}
+
+func TestMergeFromFile_PricesByModel(t *testing.T) {
+	dir := t.TempDir()
+	global := filepath.Join(dir, "global.yaml")
+	project := filepath.Join(dir, "project.yaml")
+	os.WriteFile(global, []byte("prices:\n  a:\n    prompt: 1\n    completion: 2\n  b:\n    prompt: 3\n"), 0644)
+	os.WriteFile(project, []byte("prices:\n  a:\n    prompt: 0.5\n    completion: 1.5\n"), 0644)
+
+	cfg := defaults()
+	mergeFromFile(&cfg, global)
+	mergeFromFile(&cfg, project)
+
+	if len(cfg.Prices) != 2 || cfg.Prices["a"] != (PriceConfig{Prompt: 0.5, Completion: 1.5}) || cfg.Prices["b"].Prompt != 3 {
+		t.Errorf("prices = %+v", cfg.Prices)
+	}
+}
+
+func TestLoad_SandboxAndRemoteConflict(t *testing.T) {
+	dir := t.TempDir()
+
+	origDir, _ := os.Getwd()
+	os.Chdir(dir)
+	defer os.Chdir(origDir)
+
+	t.Setenv("HOME", dir)
+	t.Setenv("OPENROUTER_API_KEY", "test-key")
+	os.MkdirAll(".stormtrooper", 0755)
+	os.WriteFile(projectPath, []byte("sandbox:\n  image: alpine\nremote:\n  host: build-1\n"), 0644)
+
+	if _, err := Load(""); err == nil || !strings.Contains(err.Error(), "sandbox and remote") {
+		t.Fatalf("expected sandbox/remote conflict error, got %v", err)
+	}
+}
+
+func TestLoad_DevcontainerValue(t *testing.T) {
+	dir := t.TempDir()
+
+	origDir, _ := os.Getwd()
+	os.Chdir(dir)
+	defer os.Chdir(origDir)
+
+	t.Setenv("HOME", dir)
+	t.Setenv("OPENROUTER_API_KEY", "test-key")
+	os.MkdirAll(".stormtrooper", 0755)
+
+	os.WriteFile(projectPath, []byte("devcontainer: always\n"), 0644)
+	cfg, err := Load("")
+	if err != nil || cfg.Devcontainer != "always" {
+		t.Fatalf("expected devcontainer=always, got %+v, %v", cfg, err)
+	}
+
+	os.WriteFile(projectPath, []byte("devcontainer: sometimes\n"), 0644)
+	if _, err := Load(""); err == nil || !strings.Contains(err.Error(), "devcontainer") {
+		t.Fatalf("expected invalid devcontainer error, got %v", err)
+	}
+}
+
+func TestLoad_NotifyValidation(t *testing.T) {
+	dir := t.TempDir()
+
+	origDir, _ := os.Getwd()
+	os.Chdir(dir)
+	defer os.Chdir(origDir)
+
+	t.Setenv("HOME", dir)
+	t.Setenv("OPENROUTER_API_KEY", "test-key")
+	os.MkdirAll(".stormtrooper", 0755)
+
+	for content, want := range map[string]string{
+		"notify:\n  on: sometimes\n":                              "notify.on",
+		"notify:\n  webhooks:\n    - url: x\n      format: xml\n": "notify.webhooks",
+		"notify:\n  email:\n    smtp: localhost:25\n":             "notify.email",
+	} {
+		os.WriteFile(projectPath, []byte(content), 0644)
+		if _, err := Load(""); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: expected a %s error, got %v", content, want, err)
+		}
+	}
+}
+
+func TestLoad_HyperlinksValue(t *testing.T) {
+	dir := t.TempDir()
+
+	origDir, _ := os.Getwd()
+	os.Chdir(dir)
+	defer os.Chdir(origDir)
+
+	t.Setenv("HOME", dir)
+	t.Setenv("OPENROUTER_API_KEY", "test-key")
+	os.MkdirAll(".stormtrooper", 0755)
+
+	os.WriteFile(projectPath, []byte("hyperlinks:\n  enabled: always\n  url: vscode://file/{path}:{line}\n"), 0644)
+	cfg, err := Load("")
+	if err != nil || cfg.Hyperlinks.Enabled != "always" || cfg.Hyperlinks.URL != "vscode://file/{path}:{line}" {
+		t.Fatalf("expected hyperlinks config, got %+v, %v", cfg, err)
+	}
+
+	os.WriteFile(projectPath, []byte("hyperlinks:\n  enabled: yes\n"), 0644)
+	if _, err := Load(""); err == nil || !strings.Contains(err.Error(), "hyperlinks.enabled") {
+		t.Fatalf("expected invalid hyperlinks error, got %v", err)
+	}
+}
+
+func TestMergeFromFile_Editor(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	os.WriteFile(path, []byte("editor: code -g {path}:{line}\n"), 0644)
+
+	cfg := defaults()
+	if err := mergeFromFile(&cfg, path); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Editor != "code -g {path}:{line}" {
+		t.Errorf("Editor = %q", cfg.Editor)
+	}
+}
+
+func TestMergeFromFile_Prune(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	os.WriteFile(path, []byte("prune:\n  after_turns: -1\n"), 0644)
+
+	cfg := defaults()
+	if err := mergeFromFile(&cfg, path); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Prune.AfterTurns != -1 || cfg.Prune.MinBytes != 2048 {
+		t.Errorf("Prune = %+v, want after_turns -1 and the default min_bytes", cfg.Prune)
+	}
+}
+
+func TestMergeFromFile_ToolProfile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	cfg := defaults()
+	os.WriteFile(path, []byte("tool_profile: plan\n"), 0644)
+	if err := mergeFromFile(&cfg, path); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ToolProfile != "plan" {
+		t.Errorf("ToolProfile = %q, want plan", cfg.ToolProfile)
+	}
+}
+
+func TestMergeFromFile_MaxContinuations(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	cfg := defaults()
+	os.WriteFile(path, []byte("max_continuations: -1\n"), 0644)
+	if err := mergeFromFile(&cfg, path); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.MaxContinuations != -1 {
+		t.Errorf("MaxContinuations = %d, want -1", cfg.MaxContinuations)
+	}
+}
+
+func TestMergeFromFile_Concurrency(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	cfg := defaults()
+	if cfg.Concurrency != 4 {
+		t.Errorf("default Concurrency = %d, want 4", cfg.Concurrency)
+	}
+	os.WriteFile(path, []byte("concurrency: -1\nmax_stream_line_mb: 256\n"), 0644)
+	if err := mergeFromFile(&cfg, path); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Concurrency != -1 || cfg.MaxStreamLineMB != 256 {
+		t.Errorf("Concurrency = %d, MaxStreamLineMB = %d", cfg.Concurrency, cfg.MaxStreamLineMB)
+	}
+}
+
+func TestMergeFromFile_StopAndRepeatGuard(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	os.WriteFile(path, []byte("stop: [\"<|im_end|>\", \"</answer>\"]\nrepeat_guard:\n  min_repeats: -1\n  penalty: 0.8\n"), 0644)
+
+	cfg := defaults()
+	if err := mergeFromFile(&cfg, path); err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Stop) != 2 || cfg.Stop[1] != "</answer>" {
+		t.Errorf("Stop = %q", cfg.Stop)
+	}
+	if cfg.RepeatGuard.MinRepeats != -1 || cfg.RepeatGuard.Penalty != 0.8 {
+		t.Errorf("RepeatGuard = %+v", cfg.RepeatGuard)
+	}
+}
+
+func TestMergeFromFile_Route(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	os.WriteFile(path, []byte("route:\n  model: openai/gpt-4o-mini\n  max_chars: 200\n"), 0644)
+
+	cfg := defaults()
+	if cfg.Route.Model != "" {
+		t.Errorf("routing should be off by default, got model %q", cfg.Route.Model)
+	}
+	if err := mergeFromFile(&cfg, path); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Route.Model != "openai/gpt-4o-mini" || cfg.Route.MaxChars != 200 {
+		t.Errorf("Route = %+v", cfg.Route)
+	}
+}
+
+func TestMergeFromFile_Retry(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	cfg := defaults()
+	if cfg.Retry.MaxRetries != 3 || cfg.Retry.BaseDelay != time.Second {
+		t.Errorf("default Retry = %+v", cfg.Retry)
+	}
+	os.WriteFile(path, []byte("retry:\n  max_retries: 5\n  base_delay: 250ms\n"), 0644)
+	if err := mergeFromFile(&cfg, path); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Retry.MaxRetries != 5 || cfg.Retry.BaseDelay != 250*time.Millisecond {
+		t.Errorf("Retry = %+v, want 5 retries after 250ms", cfg.Retry)
+	}
+}
+
+func TestMergeFromFile_Summarize(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	os.WriteFile(path, []byte("summarize:\n  model: openai/gpt-4o-mini\n"), 0644)
+
+	cfg := defaults()
+	if err := mergeFromFile(&cfg, path); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Summarize.Model != "openai/gpt-4o-mini" || cfg.Summarize.MinTokens != 4000 {
+		t.Errorf("Summarize = %+v, want the model and the default min_tokens", cfg.Summarize)
+	}
+}
+
+func TestMergeFromFile_Compact(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	os.WriteFile(path, []byte("compact:\n  threshold: -1\n  model: openai/gpt-4o-mini\n"), 0644)
+
+	cfg := defaults()
+	if err := mergeFromFile(&cfg, path); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Compact != (CompactConfig{Threshold: -1, KeepTurns: 2, Model: "openai/gpt-4o-mini"}) {
+		t.Errorf("Compact = %+v, want the threshold, the model, and the default keep_turns", cfg.Compact)
+	}
+}
+
+func TestMergeFromFile_SubagentSummary(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	os.WriteFile(path, []byte("subagent_summary:\n  model: openai/gpt-4o-mini\n"), 0644)
+
+	cfg := defaults()
+	if err := mergeFromFile(&cfg, path); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.SubagentSummary != (SubagentSummaryConfig{Threshold: 2000, Model: "openai/gpt-4o-mini"}) {
+		t.Errorf("SubagentSummary = %+v, want the model and the default threshold", cfg.SubagentSummary)
+	}
+}
+
+func TestMergeFromFile_ShellEnv(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	os.WriteFile(path, []byte("shell_env:\n  allow: [PATH, HOME, \"GO*\"]\n"), 0644)
+
+	cfg := defaults()
+	if err := mergeFromFile(&cfg, path); err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.ShellEnv.Allow) != 3 || len(cfg.ShellEnv.Strip) != len(DefaultStripEnv) {
+		t.Errorf("ShellEnv = %+v, want the allow list and the default strip list", cfg.ShellEnv)
+	}
+
+	os.WriteFile(path, []byte("shell_env:\n  strip: []\n"), 0644)
+	if err := mergeFromFile(&cfg, path); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ShellEnv.Strip == nil || len(cfg.ShellEnv.Strip) != 0 {
+		t.Errorf("an empty strip list should turn stripping off, got %#v", cfg.ShellEnv.Strip)
+	}
+}
+
+func TestMergeFromFile_Sampling(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	os.WriteFile(path, []byte("temperature: 0\ntop_p: 0.9\nmodels:\n  o3-mini:\n    temperature: 1\n    max_tokens: 8000\n"), 0644)
+
+	cfg := defaults()
+	if err := mergeFromFile(&cfg, path); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Temperature == nil || *cfg.Temperature != 0 || cfg.TopP == nil || *cfg.TopP != 0.9 {
+		t.Errorf("Temperature = %v, TopP = %v", cfg.Temperature, cfg.TopP)
+	}
+	m := cfg.Models["o3-mini"]
+	if m.Temperature == nil || *m.Temperature != 1 || m.MaxTokens != 8000 || m.TopP != nil {
+		t.Errorf("Models = %+v", cfg.Models)
+	}
+
+	// A layer without sampling settings keeps the ones below it.
+	os.WriteFile(path, []byte("models:\n  gpt-4o:\n    top_p: 0.5\n"), 0644)
+	if err := mergeFromFile(&cfg, path); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Temperature == nil || len(cfg.Models) != 2 {
+		t.Errorf("Temperature = %v, Models = %+v", cfg.Temperature, cfg.Models)
+	}
+}
+
+func TestLoad_Sampling(t *testing.T) {
+	dir := t.TempDir()
+
+	origDir, _ := os.Getwd()
+	os.Chdir(dir)
+	defer os.Chdir(origDir)
+
+	t.Setenv("HOME", dir)
+	t.Setenv("OPENROUTER_API_KEY", "test-key")
+	os.MkdirAll(".stormtrooper", 0755)
+
+	os.WriteFile(projectPath, []byte("temperature: 0.7\nstop: [\"</answer>\"]\n"), 0644)
+	zero := 0.0
+	cfg, err := LoadWithOptions(LoadOptions{CLITemperature: &zero, CLIStop: []string{"END"}})
+	if err != nil || *cfg.Temperature != 0 || len(cfg.Stop) != 1 || cfg.Stop[0] != "END" {
+		t.Fatalf("flags should override the config, got %+v, %v", cfg, err)
+	}
+
+	for _, tt := range []struct{ yaml, want string }{
+		{"temperature: 2.5\n", "temperature: must be between 0 and 2"},
+		{"top_p: 0\n", "top_p: must be above 0"},
+		{"models:\n  o3-mini:\n    top_p: 1.5\n", "models.o3-mini.top_p"},
+		{"models:\n  o3-mini:\n    max_tokens: -1\n", "models.o3-mini.max_tokens"},
+	} {
+		os.WriteFile(projectPath, []byte(tt.yaml), 0644)
+		if _, err := Load(""); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: expected %q error, got %v", tt.yaml, tt.want, err)
+		}
+	}
+}
+
+func TestLoad_Verbosity(t *testing.T) {
+	dir := t.TempDir()
+
+	origDir, _ := os.Getwd()
+	os.Chdir(dir)
+	defer os.Chdir(origDir)
+
+	t.Setenv("HOME", dir)
+	t.Setenv("OPENROUTER_API_KEY", "test-key")
+	os.MkdirAll(".stormtrooper", 0755)
+
+	os.WriteFile(projectPath, []byte("verbosity: concise\n"), 0644)
+	cfg, err := Load("")
+	if err != nil || cfg.ResponseMaxTokens() != ConciseMaxTokens {
+		t.Fatalf("concise should default the response cap, got %+v, %v", cfg, err)
+	}
+
+	cfg, err = LoadWithOptions(LoadOptions{CLIVerbosity: "detailed", CLIMaxTokens: 1000})
+	if err != nil || cfg.Verbosity != "detailed" || cfg.ResponseMaxTokens() != 1000 {
+		t.Fatalf("flags should override the config, got %+v, %v", cfg, err)
+	}
+
+	if _, err := LoadWithOptions(LoadOptions{CLIVerbosity: "chatty"}); err == nil || !strings.Contains(err.Error(), "verbosity") {
+		t.Fatalf("expected invalid verbosity error, got %v", err)
+	}
+}
+
 func TestMergeFromFile_InvalidYAML(t *testing.T) {
 	dir := t.TempDir()
 	path := filepath.Join(dir, "config.yaml")
@@ -66,6 +594,27 @@ func TestMergeFromFile_InvalidYAML(t *testing.T) {
 	}
 }
 
+func TestLoad_CLIToolProfile(t *testing.T) {
+	dir := t.TempDir()
+
+	origDir, _ := os.Getwd()
+	os.Chdir(dir)
+	defer os.Chdir(origDir)
+
+	t.Setenv("HOME", dir)
+	t.Setenv("OPENROUTER_API_KEY", "test-key")
+	os.MkdirAll(".stormtrooper", 0755)
+	os.WriteFile(projectPath, []byte("tool_profile: review\n"), 0644)
+
+	cfg, err := LoadWithOptions(LoadOptions{CLIToolProfile: "plan"})
+	if err != nil || cfg.ToolProfile != "plan" {
+		t.Fatalf("the flag should override the config, got %+v, %v", cfg, err)
+	}
+	if _, err := LoadWithOptions(LoadOptions{CLIToolProfile: "yolo"}); err == nil || !strings.Contains(err.Error(), "tool_profile") {
+		t.Fatalf("expected invalid tool profile error, got %v", err)
+	}
+}
+
 func TestLoad_EnvOverridesFile(t *testing.T) {
 	// Create a project config with an api_key.
 	dir := t.TempDir()
@@ -138,6 +687,53 @@ func TestLoad_MissingAPIKey(t *testing.T) {
 	}
 }
 
+func TestLoadWithOptions_AllowMissingKey(t *testing.T) {
+	dir := t.TempDir()
+
+	origDir, _ := os.Getwd()
+	os.Chdir(dir)
+	defer os.Chdir(origDir)
+
+	t.Setenv("HOME", dir)
+	t.Setenv("OPENROUTER_API_KEY", "")
+
+	cfg, err := LoadWithOptions(LoadOptions{AllowMissingKey: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.APIKey != "" {
+		t.Errorf("expected empty API key, got %q", cfg.APIKey)
+	}
+}
+
+func TestLoad_MockProvider(t *testing.T) {
+	dir := t.TempDir()
+
+	origDir, _ := os.Getwd()
+	os.Chdir(dir)
+	defer os.Chdir(origDir)
+
+	t.Setenv("HOME", dir)
+	t.Setenv("OPENROUTER_API_KEY", "")
+	os.MkdirAll(".stormtrooper", 0755)
+
+	// The mock provider needs a script.
+	os.WriteFile(projectPath, []byte("provider: mock\n"), 0644)
+	if _, err := Load(""); err == nil || !strings.Contains(err.Error(), "mock_script") {
+		t.Fatalf("expected missing mock_script error, got %v", err)
+	}
+
+	// With a script, no API key is required.
+	os.WriteFile(projectPath, []byte("provider: mock\nmock_script: demo.yaml\n"), 0644)
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Provider != ProviderMock || cfg.MockScript != "demo.yaml" {
+		t.Errorf("unexpected config %+v", cfg)
+	}
+}
+
 func TestLoad_DefaultsUsedWhenNoFiles(t *testing.T) {
 	dir := t.TempDir()
 
@@ -159,3 +755,132 @@ func TestLoad_DefaultsUsedWhenNoFiles(t *testing.T) {
 		t.Errorf("expected default base URL, got %q", cfg.BaseURL)
 	}
 }
+
+func TestLoadWithOptions_SkipProject(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, ".stormtrooper"), 0755)
+	os.WriteFile(filepath.Join(dir, ".stormtrooper", "config.yaml"),
+		[]byte("model: file-model\nbase_url: https://attacker.example/v1\n"), 0644)
+
+	origDir, _ := os.Getwd()
+	os.Chdir(dir)
+	defer os.Chdir(origDir)
+
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("OPENROUTER_API_KEY", "env-key")
+
+	cfg, err := LoadWithOptions(LoadOptions{SkipProject: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if cfg.Model != "moonshotai/kimi-k2" {
+		t.Errorf("project model should be ignored, got %q", cfg.Model)
+	}
+	if cfg.BaseURL != "https://openrouter.ai/api/v1" {
+		t.Errorf("project base_url should be ignored, got %q", cfg.BaseURL)
+	}
+}
+
+func TestLoad_Offline(t *testing.T) {
+	dir := t.TempDir()
+
+	origDir, _ := os.Getwd()
+	os.Chdir(dir)
+	defer os.Chdir(origDir)
+
+	t.Setenv("HOME", dir)
+	t.Setenv("OPENROUTER_API_KEY", "")
+	t.Setenv(OfflineEnv, "")
+	os.MkdirAll(".stormtrooper", 0755)
+
+	// The default provider is on the internet.
+	if _, err := LoadWithOptions(LoadOptions{CLIOffline: true}); err == nil || !strings.Contains(err.Error(), "not a local provider") {
+		t.Fatalf("expected a local provider error, got %v", err)
+	}
+
+	// A local provider needs no key, and sandboxes lose their network.
+	os.WriteFile(projectPath, []byte("base_url: http://localhost:11434/v1\nsandbox:\n  image: golang:1.25\n  network: bridge\n"), 0644)
+	cfg, err := LoadWithOptions(LoadOptions{CLIOffline: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cfg.Offline || cfg.Sandbox.Network != "none" {
+		t.Errorf("unexpected config %+v", cfg)
+	}
+
+	// The environment turns it on too, and a file cannot turn it off.
+	os.WriteFile(projectPath, []byte("base_url: http://127.0.0.1:8080/v1\noffline: false\n"), 0644)
+	t.Setenv(OfflineEnv, "1")
+	if cfg, err := Load(""); err != nil || !cfg.Offline {
+		t.Errorf("expected offline from the environment, got %+v, %v", cfg, err)
+	}
+
+	os.WriteFile(projectPath, []byte("base_url: http://localhost:11434/v1\nremote:\n  host: build-server\n"), 0644)
+	if _, err := Load(""); err == nil || !strings.Contains(err.Error(), "remote execution") {
+		t.Errorf("expected a remote error, got %v", err)
+	}
+}
+
+func TestLoad_Profile(t *testing.T) {
+	dir := t.TempDir()
+
+	origDir, _ := os.Getwd()
+	os.Chdir(dir)
+	defer os.Chdir(origDir)
+
+	t.Setenv("HOME", dir)
+	t.Setenv("OPENROUTER_API_KEY", "openrouter-key")
+	os.MkdirAll(".stormtrooper", 0755)
+	os.WriteFile(projectPath, []byte(`profiles:
+  local:
+    base_url: http://localhost:11434/v1
+    model: llama3.1
+    tool_calling: prompt
+  work:
+    base_url: https://llm.example.com/v1
+    api_key: work-key
+`), 0644)
+
+	// The local profile gets no key: the OpenRouter one stays with
+	// OpenRouter.
+	cfg, err := LoadWithOptions(LoadOptions{CLIProfile: "local"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.BaseURL != "http://localhost:11434/v1" || cfg.Model != "llama3.1" || cfg.APIKey != "" || cfg.ToolCalling != "prompt" {
+		t.Errorf("local profile: %+v", cfg)
+	}
+
+	// --model beats the profile's model.
+	cfg, err = LoadWithOptions(LoadOptions{CLIProfile: "work", CLIModel: "gpt-4o"})
+	if err != nil || cfg.APIKey != "work-key" || cfg.Model != "gpt-4o" || cfg.BaseURL != "https://llm.example.com/v1" {
+		t.Errorf("work profile: %+v, %v", cfg, err)
+	}
+
+	if _, err := LoadWithOptions(LoadOptions{CLIProfile: "home"}); err == nil || !strings.Contains(err.Error(), "have local, work") {
+		t.Errorf("expected an unknown profile error, got %v", err)
+	}
+
+	// A config file can pick the default profile.
+	os.WriteFile(filepath.Join(dir, ".stormtrooper", "config.yaml"), []byte("profile: local\nprofiles:\n  local:\n    base_url: http://localhost:8080/v1\n"), 0644)
+	if cfg, err := Load(""); err != nil || cfg.BaseURL != "http://localhost:8080/v1" {
+		t.Errorf("default profile: %+v, %v", cfg, err)
+	}
+}
+
+func TestLoad_ToolCallingValue(t *testing.T) {
+	dir := t.TempDir()
+
+	origDir, _ := os.Getwd()
+	os.Chdir(dir)
+	defer os.Chdir(origDir)
+
+	t.Setenv("HOME", dir)
+	t.Setenv("OPENROUTER_API_KEY", "test-key")
+	os.MkdirAll(".stormtrooper", 0755)
+	os.WriteFile(projectPath, []byte("tool_calling: sometimes\n"), 0644)
+	if _, err := Load(""); err == nil || !strings.Contains(err.Error(), "tool_calling") {
+		t.Errorf("expected a tool_calling error, got %v", err)
+	}
+}
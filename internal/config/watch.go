@@ -0,0 +1,158 @@
+// watch.go polls the config files and reloads them when they change,
+// so long-running sessions can pick up edits without a restart.
+package config
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// DefaultWatchInterval is how often Watcher checks the config files.
+const DefaultWatchInterval = 2 * time.Second
+
+// Watcher polls the global and project config files for modifications.
+// Polling keeps us free of platform-specific file notification APIs and
+// is cheap for two small files.
+type Watcher struct {
+	opts     LoadOptions
+	paths    []string
+	interval time.Duration
+	stamps   map[string]fileStamp
+}
+
+// fileStamp identifies one version of a file on disk.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+	exists  bool
+}
+
+// NewWatcher creates a Watcher for the files LoadWithOptions would read.
+func NewWatcher(opts LoadOptions, interval time.Duration) *Watcher {
+	var paths []string
+	if p := GlobalPath(); p != "" {
+		paths = append(paths, p)
+	}
+	if !opts.SkipProject {
+		paths = append(paths, projectPath)
+	}
+	return newWatcher(opts, interval, paths)
+}
+
+func newWatcher(opts LoadOptions, interval time.Duration, paths []string) *Watcher {
+	w := &Watcher{
+		opts:     opts,
+		paths:    paths,
+		interval: interval,
+		stamps:   make(map[string]fileStamp),
+	}
+	for _, p := range paths {
+		w.stamps[p] = stat(p)
+	}
+	return w
+}
+
+// Changed reports whether any watched file was created, modified, or
+// removed since the last call, and records the current state.
+func (w *Watcher) Changed() bool {
+	changed := false
+	for _, p := range w.paths {
+		cur := stat(p)
+		if cur != w.stamps[p] {
+			changed = true
+			w.stamps[p] = cur
+		}
+	}
+	return changed
+}
+
+// Run polls until ctx is cancelled. Each time a file changes, the full
+// config is reloaded and passed to onReload along with any load error.
+func (w *Watcher) Run(ctx context.Context, onReload func(*Config, error)) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if w.Changed() {
+				cfg, err := LoadWithOptions(w.opts)
+				onReload(cfg, err)
+			}
+		}
+	}
+}
+
+func stat(path string) fileStamp {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}
+	}
+	return fileStamp{modTime: info.ModTime(), size: info.Size(), exists: true}
+}
+
+// Changes describes the differences between two configs as short,
+// human-readable lines. Secrets are never included in the output.
+func Changes(old, new *Config) []string {
+	var lines []string
+	if old.Model != new.Model {
+		lines = append(lines, fmt.Sprintf("model: %s -> %s", old.Model, new.Model))
+	}
+	if old.BaseURL != new.BaseURL {
+		lines = append(lines, fmt.Sprintf("base_url: %s -> %s (restart required)", old.BaseURL, new.BaseURL))
+	}
+	if old.Provider != new.Provider {
+		lines = append(lines, fmt.Sprintf("provider: %s -> %s (restart required)", old.Provider, new.Provider))
+	}
+	if old.Markdown.Style != new.Markdown.Style {
+		lines = append(lines, fmt.Sprintf("markdown.style: %s -> %s", old.Markdown.Style, new.Markdown.Style))
+	}
+	if old.Markdown.WordWrap != new.Markdown.WordWrap {
+		lines = append(lines, fmt.Sprintf("markdown.word_wrap: %d -> %d", old.Markdown.WordWrap, new.Markdown.WordWrap))
+	}
+	if old.Verbosity != new.Verbosity {
+		lines = append(lines, fmt.Sprintf("verbosity: %s -> %s (restart required)", old.Verbosity, new.Verbosity))
+	}
+	if old.MaxTokens != new.MaxTokens {
+		lines = append(lines, fmt.Sprintf("max_tokens: %d -> %d", old.MaxTokens, new.MaxTokens))
+	}
+	if !equalOptional(old.Temperature, new.Temperature) {
+		lines = append(lines, fmt.Sprintf("temperature: %s -> %s", formatOptional(old.Temperature), formatOptional(new.Temperature)))
+	}
+	if !equalOptional(old.TopP, new.TopP) {
+		lines = append(lines, fmt.Sprintf("top_p: %s -> %s", formatOptional(old.TopP), formatOptional(new.TopP)))
+	}
+	if !reflect.DeepEqual(old.Models, new.Models) {
+		lines = append(lines, "models changed")
+	}
+	if !maps.Equal(old.Prices, new.Prices) {
+		lines = append(lines, "prices changed")
+	}
+	if old.APIKey != new.APIKey {
+		lines = append(lines, "api_key changed (restart required)")
+	}
+	return lines
+}
+
+// equalOptional reports whether two optional settings are the same.
+func equalOptional(a, b *float64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// formatOptional returns an optional setting for display.
+func formatOptional(v *float64) string {
+	if v == nil {
+		return "default"
+	}
+	return strconv.FormatFloat(*v, 'g', -1, 64)
+}
@@ -0,0 +1,117 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatcher_DetectsCreateModifyRemove(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	w := newWatcher(LoadOptions{}, time.Hour, []string{path})
+
+	if w.Changed() {
+		t.Fatal("no change expected before the file exists")
+	}
+
+	os.WriteFile(path, []byte("model: a\n"), 0644)
+	if !w.Changed() {
+		t.Fatal("expected change after create")
+	}
+	if w.Changed() {
+		t.Fatal("change should only be reported once")
+	}
+
+	os.WriteFile(path, []byte("model: longer-name\n"), 0644)
+	if !w.Changed() {
+		t.Fatal("expected change after modify")
+	}
+
+	os.Remove(path)
+	if !w.Changed() {
+		t.Fatal("expected change after remove")
+	}
+}
+
+func TestWatcher_RunReloads(t *testing.T) {
+	dir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(dir)
+	defer os.Chdir(origDir)
+
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("OPENROUTER_API_KEY", "env-key")
+
+	w := NewWatcher(LoadOptions{}, 10*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	reloaded := make(chan *Config, 1)
+	go w.Run(ctx, func(cfg *Config, err error) {
+		if err == nil {
+			select {
+			case reloaded <- cfg:
+			default:
+			}
+		}
+	})
+
+	os.MkdirAll(filepath.Join(dir, ".stormtrooper"), 0755)
+	os.WriteFile(filepath.Join(dir, ".stormtrooper", "config.yaml"), []byte("model: hot-model\n"), 0644)
+
+	select {
+	case cfg := <-reloaded:
+		if cfg.Model != "hot-model" {
+			t.Errorf("expected reloaded model 'hot-model', got %q", cfg.Model)
+		}
+	case <-ctx.Done():
+		t.Fatal("timed out waiting for reload")
+	}
+}
+
+func TestChanges(t *testing.T) {
+	old := &Config{APIKey: "secret-1", Model: "a", BaseURL: "https://x"}
+	new := &Config{APIKey: "secret-2", Model: "b", BaseURL: "https://x"}
+
+	lines := Changes(old, new)
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 changes, got %v", lines)
+	}
+	joined := strings.Join(lines, "\n")
+	if !strings.Contains(joined, "model: a -> b") {
+		t.Errorf("expected model change, got %q", joined)
+	}
+	if strings.Contains(joined, "secret") {
+		t.Errorf("changes must not leak the API key: %q", joined)
+	}
+
+	restyled := *old
+	restyled.Markdown = MarkdownConfig{Style: "light", WordWrap: 100}
+	joined = strings.Join(Changes(old, &restyled), "\n")
+	if joined != "markdown.style:  -> light\nmarkdown.word_wrap: 0 -> 100" {
+		t.Errorf("markdown changes = %q", joined)
+	}
+
+	repriced := *old
+	repriced.Prices = map[string]PriceConfig{"a": {Prompt: 1}}
+	if got := Changes(old, &repriced); len(got) != 1 || got[0] != "prices changed" {
+		t.Errorf("price changes = %v", got)
+	}
+
+	cooled := *old
+	zero := 0.0
+	cooled.Temperature = &zero
+	cooled.Models = map[string]ModelConfig{"b": {MaxTokens: 100}}
+	joined = strings.Join(Changes(old, &cooled), "\n")
+	if joined != "temperature: default -> 0\nmodels changed" {
+		t.Errorf("sampling changes = %q", joined)
+	}
+
+	if got := Changes(old, old); len(got) != 0 {
+		t.Errorf("expected no changes, got %v", got)
+	}
+}
@@ -20,6 +20,14 @@ type ProjectContext struct {
 	Memory       string // Contents of MEMORY.md
 	Platform     string // runtime.GOOS
 	Date         string // current date YYYY-MM-DD
+	Verbosity    string // "concise", "normal", or "detailed"; see verbosityPrompts
+}
+
+// verbosityPrompts are the answer-length instructions for each verbosity.
+// "normal" adds none.
+var verbosityPrompts = map[string]string{
+	"concise":  "Keep answers short: a sentence or two for simple questions, and a brief summary after finishing a task. Skip preambles, restating the question, and explanations nobody asked for.",
+	"detailed": "Give thorough answers: explain your reasoning, the alternatives you considered, and the trade-offs, and walk through changes you made.",
 }
 
 // instructionFiles lists project instruction files in priority order.
@@ -81,6 +89,11 @@ func (pc *ProjectContext) BuildSystemPrompt() string {
 		b.WriteString(pc.Memory)
 	}
 
+	if prompt := verbosityPrompts[pc.Verbosity]; prompt != "" {
+		b.WriteString("\n\n# Response Style\n\n")
+		b.WriteString(prompt)
+	}
+
 	b.WriteString("\n\n# Environment\n")
 	b.WriteString(fmt.Sprintf("- Working directory: %s\n", pc.WorkingDir))
 	b.WriteString(fmt.Sprintf("- Platform: %s\n", pc.Platform))
@@ -135,6 +135,24 @@ func TestBuildSystemPromptFull(t *testing.T) {
 	}
 }
 
+func TestBuildSystemPromptVerbosity(t *testing.T) {
+	for _, tt := range []struct {
+		verbosity string
+		want      string
+	}{
+		{"concise", "Keep answers short"},
+		{"detailed", "Give thorough answers"},
+		{"normal", ""},
+		{"", ""},
+	} {
+		pc := &ProjectContext{WorkingDir: "/p", Verbosity: tt.verbosity}
+		prompt := pc.BuildSystemPrompt()
+		if has := strings.Contains(prompt, "# Response Style"); has != (tt.want != "") || !strings.Contains(prompt, tt.want) {
+			t.Errorf("verbosity %q: prompt = %q", tt.verbosity, prompt)
+		}
+	}
+}
+
 func TestBuildSystemPromptMinimal(t *testing.T) {
 	pc := &ProjectContext{
 		WorkingDir: "/my/project",
@@ -0,0 +1,178 @@
+package daemon
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gavinyap/stormtrooper/internal/i18n"
+)
+
+// AttachOptions configures Attach.
+type AttachOptions struct {
+	// All replays the whole log instead of only the events since this
+	// machine last attached.
+	All bool
+	In  io.Reader // prompts and permission answers, one per line
+	Out io.Writer
+}
+
+// Attach connects to the session in dir, prints the events missed since
+// the last attach, and then follows the session live. Lines read from In
+// answer a waiting permission prompt (y or n) or are sent as prompts;
+// "/detach" or the end of In detaches and "/stop" stops the daemon. A
+// session whose daemon has exited is replayed from its log.
+func Attach(ctx context.Context, dir string, opts AttachOptions) error {
+	after := 0
+	if !opts.All {
+		after = readSeen(dir)
+	}
+	r := &renderer{out: opts.Out, last: after}
+	defer func() { writeSeen(dir, r.seq()) }()
+
+	conn, err := net.Dial("unix", filepath.Join(dir, socketFile))
+	if err != nil {
+		events, err := ReadEvents(dir, after)
+		if err != nil {
+			return err
+		}
+		for _, e := range events {
+			r.render(e)
+		}
+		if !r.exited {
+			fmt.Fprintln(opts.Out, "\n[daemon] not running")
+		}
+		return nil
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	var mu sync.Mutex
+	enc := json.NewEncoder(conn)
+	send := func(req Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		enc.Encode(req)
+	}
+	send(Request{Type: "attach", After: after})
+
+	go func() {
+		scanner := bufio.NewScanner(opts.In)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if id := r.pending(); id != "" {
+				switch strings.ToLower(line) {
+				case "y", "yes":
+					send(Request{Type: "answer", ID: id, Allow: true})
+					continue
+				case "", "n", "no":
+					send(Request{Type: "answer", ID: id})
+					continue
+				}
+			}
+			switch line {
+			case "":
+			case "/detach":
+				send(Request{Type: "detach"})
+				return
+			case "/stop":
+				send(Request{Type: "stop"})
+			default:
+				send(Request{Type: "prompt", Text: line})
+			}
+		}
+		send(Request{Type: "detach"})
+	}()
+
+	dec := json.NewDecoder(conn)
+	for {
+		var e Event
+		if dec.Decode(&e) != nil {
+			break
+		}
+		r.render(e)
+	}
+	if !r.exited {
+		fmt.Fprintln(opts.Out, "\n[daemon] detached; the session keeps running")
+	}
+	return nil
+}
+
+// renderer prints events for people and tracks the prompt awaiting an
+// answer.
+type renderer struct {
+	out io.Writer
+
+	mu      sync.Mutex
+	last    int
+	waiting string // ID of the unanswered permission prompt
+	exited  bool
+}
+
+func (r *renderer) render(e Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.last = max(r.last, e.Seq)
+	switch e.Kind {
+	case KindOutput, KindLog:
+		fmt.Fprint(r.out, e.Text)
+	case KindPrompt:
+		fmt.Fprintf(r.out, "\n> %s\n\n", e.Text)
+	case KindPermission:
+		r.waiting = e.ID
+		fmt.Fprint(r.out, "\n"+i18n.T("permission.prompt", e.Tool, e.Text))
+	case KindAnswer:
+		if r.waiting == e.ID {
+			r.waiting = ""
+		}
+		if e.Allow {
+			fmt.Fprintln(r.out, "[permission] allowed")
+		} else {
+			fmt.Fprintln(r.out, "[permission] denied")
+		}
+	case KindDone:
+		if e.Text != "" {
+			fmt.Fprintf(r.out, "\n[error] %s\n", e.Text)
+		} else {
+			fmt.Fprintln(r.out, "\n[done]")
+		}
+	case KindExit:
+		r.exited = true
+		fmt.Fprintf(r.out, "[daemon] exited: %s\n", e.Text)
+	}
+}
+
+func (r *renderer) pending() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.waiting
+}
+
+func (r *renderer) seq() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.last
+}
+
+// readSeen returns the last event this machine printed for the session.
+func readSeen(dir string) int {
+	data, err := os.ReadFile(filepath.Join(dir, seenFile))
+	if err != nil {
+		return 0
+	}
+	n, _ := strconv.Atoi(strings.TrimSpace(string(data)))
+	return n
+}
+
+func writeSeen(dir string, seq int) {
+	os.WriteFile(filepath.Join(dir, seenFile), []byte(strconv.Itoa(seq)+"\n"), 0600)
+}
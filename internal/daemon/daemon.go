@@ -0,0 +1,122 @@
+// Package daemon keeps an agent session running in the background after
+// the terminal that started it goes away. The daemon records everything
+// the agent prints, asks, and is told in an event log and serves it on a
+// unix socket; clients attach, replay the events they missed, and then
+// follow along live, answering permission prompts and sending follow-up
+// prompts until they detach.
+package daemon
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Files in a session's directory.
+const (
+	infoFile   = "info.json"
+	eventsFile = "events.jsonl"
+	socketFile = "sock"
+	seenFile   = "seen"
+	// LogFile receives the daemon process's own stdout and stderr.
+	LogFile = "daemon.log"
+)
+
+// Info describes a detached session.
+type Info struct {
+	ID      string    `json:"id"`
+	Dir     string    `json:"dir"` // working directory of the session
+	Prompt  string    `json:"prompt"`
+	PID     int       `json:"pid,omitempty"`
+	Started time.Time `json:"started"`
+}
+
+// DefaultRoot returns ~/.stormtrooper/daemons, where sessions live. It
+// is kept short because unix socket paths are limited to about 100 bytes.
+func DefaultRoot() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".stormtrooper", "daemons"), nil
+}
+
+// NewID returns a short random session ID.
+func NewID() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Create makes the directory for a new session under root and writes
+// its info.
+func Create(root string, info Info) (string, error) {
+	dir := filepath.Join(root, info.ID)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	return dir, WriteInfo(dir, info)
+}
+
+// WriteInfo saves info in the session directory dir.
+func WriteInfo(dir string, info Info) error {
+	data, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, infoFile), data, 0600)
+}
+
+// ReadInfo loads the info of the session in dir.
+func ReadInfo(dir string) (Info, error) {
+	var info Info
+	data, err := os.ReadFile(filepath.Join(dir, infoFile))
+	if err != nil {
+		return info, err
+	}
+	if err := json.Unmarshal(data, &info); err != nil {
+		return info, fmt.Errorf("invalid session info in %s: %w", dir, err)
+	}
+	return info, nil
+}
+
+// Running reports whether the daemon for the session in dir is accepting
+// connections.
+func Running(dir string) bool {
+	conn, err := net.DialTimeout("unix", filepath.Join(dir, socketFile), time.Second)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// List returns the sessions under root, newest first.
+func List(root string) ([]Info, error) {
+	entries, err := os.ReadDir(root)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var infos []Info
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		info, err := ReadInfo(filepath.Join(root, e.Name()))
+		if err != nil {
+			continue
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Started.After(infos[j].Started) })
+	return infos, nil
+}
@@ -0,0 +1,42 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCreateAndList(t *testing.T) {
+	root := t.TempDir()
+	older := Info{ID: "aaaa", Dir: "/src/app", Prompt: "bump deps", Started: time.Now().Add(-time.Hour)}
+	newer := Info{ID: "bbbb", Dir: "/src/app", Prompt: "refactor", Started: time.Now()}
+	for _, info := range []Info{older, newer} {
+		if _, err := Create(root, info); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	infos, err := List(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 2 || infos[0].ID != "bbbb" || infos[1].Prompt != "bump deps" {
+		t.Errorf("List = %+v", infos)
+	}
+
+	dir, _ := Create(root, Info{ID: "cccc"})
+	if Running(dir) {
+		t.Error("a session without a daemon should not be running")
+	}
+	s, err := Listen(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if !Running(dir) {
+		t.Error("expected the session to be running")
+	}
+
+	if infos, err := List(t.TempDir() + "/missing"); err != nil || infos != nil {
+		t.Errorf("missing root: %v, %v", infos, err)
+	}
+}
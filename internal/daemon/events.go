@@ -0,0 +1,54 @@
+package daemon
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Kinds of event in the log.
+const (
+	KindOutput     = "output"     // the agent's response text
+	KindLog        = "log"        // tool status and other narration
+	KindPrompt     = "prompt"     // a prompt started running
+	KindPermission = "permission" // a tool call waits for approval
+	KindAnswer     = "answer"     // a permission prompt was answered
+	KindDone       = "done"       // a prompt finished; Text holds any error
+	KindExit       = "exit"       // the daemon stopped; Text says why
+)
+
+// Event is one entry in a session's event log.
+type Event struct {
+	Seq   int       `json:"seq"`
+	Time  time.Time `json:"time"`
+	Kind  string    `json:"kind"`
+	Text  string    `json:"text,omitempty"`
+	ID    string    `json:"id,omitempty"`   // permission prompt
+	Tool  string    `json:"tool,omitempty"` // permission prompt
+	Allow bool      `json:"allow,omitempty"`
+}
+
+// ReadEvents returns the events logged for the session in dir after
+// sequence number after.
+func ReadEvents(dir string, after int) ([]Event, error) {
+	f, err := os.Open(filepath.Join(dir, eventsFile))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var events []Event
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16<<20)
+	for scanner.Scan() {
+		var e Event
+		if json.Unmarshal(scanner.Bytes(), &e) != nil {
+			continue // a line cut short by a crash
+		}
+		if e.Seq > after {
+			events = append(events, e)
+		}
+	}
+	return events, scanner.Err()
+}
@@ -0,0 +1,319 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/gavinyap/stormtrooper/internal/permission"
+)
+
+// Request is what a client sends the daemon, one JSON object per line.
+// The first request on a connection must be "attach".
+type Request struct {
+	Type  string `json:"type"` // attach, prompt, answer, detach, or stop
+	After int    `json:"after,omitempty"`
+	Text  string `json:"text,omitempty"`
+	ID    string `json:"id,omitempty"`
+	Allow bool   `json:"allow,omitempty"`
+}
+
+// clientBuffer is how many events may wait for a slow client before it
+// is disconnected; it can reattach and replay them from the log.
+const clientBuffer = 4096
+
+// writeTimeout bounds each write to a client.
+const writeTimeout = 10 * time.Second
+
+var _ permission.Handler = (*Server)(nil)
+
+// Server runs in the daemon process: it logs events, serves them to
+// attached clients, and takes their prompts and permission answers.
+type Server struct {
+	ln  net.Listener
+	log *os.File
+
+	mu      sync.Mutex
+	events  []Event
+	clients map[*client]bool
+	answers map[string]chan bool
+	nextID  int
+
+	prompts  chan string
+	wake     chan struct{}
+	stop     chan struct{}
+	stopOnce sync.Once
+	writers  sync.WaitGroup
+}
+
+type client struct {
+	events chan Event
+}
+
+// Listen starts serving the session in dir, creating its event log.
+func Listen(dir string) (*Server, error) {
+	sock := filepath.Join(dir, socketFile)
+	os.Remove(sock) // left behind by a daemon that crashed
+	ln, err := net.Listen("unix", sock)
+	if err != nil {
+		return nil, err
+	}
+	log, err := os.OpenFile(filepath.Join(dir, eventsFile), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		ln.Close()
+		return nil, err
+	}
+	s := &Server{
+		ln:      ln,
+		log:     log,
+		clients: map[*client]bool{},
+		answers: map[string]chan bool{},
+		prompts: make(chan string, 16),
+		wake:    make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+	}
+	go s.accept()
+	return s, nil
+}
+
+// Stdout returns a writer that logs the agent's response text.
+func (s *Server) Stdout() io.Writer { return eventWriter{s, KindOutput} }
+
+// Stderr returns a writer that logs the agent's narration.
+func (s *Server) Stderr() io.Writer { return eventWriter{s, KindLog} }
+
+type eventWriter struct {
+	s    *Server
+	kind string
+}
+
+func (w eventWriter) Write(p []byte) (int, error) {
+	w.s.emit(Event{Kind: w.kind, Text: string(p)})
+	return len(p), nil
+}
+
+// Submit queues a prompt for Run.
+func (s *Server) Submit(prompt string) {
+	select {
+	case s.prompts <- prompt:
+	default:
+		s.emit(Event{Kind: KindLog, Text: "[daemon] too many queued prompts; dropped one\n"})
+	}
+}
+
+// Check logs a permission prompt and waits until an attached client
+// answers it, however long that takes, or ctx is done. It implements
+// permission.Handler.
+func (s *Server) Check(ctx context.Context, toolName, preview string) bool {
+	s.mu.Lock()
+	s.nextID++
+	id := fmt.Sprintf("perm-%d", s.nextID)
+	answer := make(chan bool, 1)
+	s.answers[id] = answer
+	s.mu.Unlock()
+
+	s.emit(Event{Kind: KindPermission, ID: id, Tool: toolName, Text: preview})
+	select {
+	case allow := <-answer:
+		return allow
+	case <-s.stop:
+		return false
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// answer settles permission prompt id; later answers are ignored.
+func (s *Server) answer(id string, allow bool) {
+	s.mu.Lock()
+	ch := s.answers[id]
+	delete(s.answers, id)
+	s.mu.Unlock()
+	if ch != nil {
+		s.emit(Event{Kind: KindAnswer, ID: id, Allow: allow})
+		ch <- allow
+	}
+}
+
+// Stop makes Run return, cancelling the prompt in progress.
+func (s *Server) Stop() {
+	s.stopOnce.Do(func() { close(s.stop) })
+}
+
+// Run sends the queued prompts to send one at a time. It returns once no
+// prompt is queued and no client is attached to send another, when a
+// client asks it to stop, or when ctx is done.
+func (s *Server) Run(ctx context.Context, send func(ctx context.Context, prompt string) error) error {
+	for {
+		select {
+		case prompt := <-s.prompts:
+			s.run(ctx, send, prompt)
+			continue
+		default:
+		}
+		if s.attached() == 0 {
+			s.emit(Event{Kind: KindExit, Text: "finished"})
+			return nil
+		}
+		select {
+		case prompt := <-s.prompts:
+			s.run(ctx, send, prompt)
+		case <-s.wake:
+		case <-s.stop:
+			s.emit(Event{Kind: KindExit, Text: "stopped"})
+			return nil
+		case <-ctx.Done():
+			s.emit(Event{Kind: KindExit, Text: "interrupted"})
+			return ctx.Err()
+		}
+	}
+}
+
+func (s *Server) run(ctx context.Context, send func(context.Context, string) error, prompt string) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-s.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	s.emit(Event{Kind: KindPrompt, Text: prompt})
+	done := Event{Kind: KindDone}
+	if err := send(ctx, prompt); err != nil {
+		done.Text = err.Error()
+	}
+	s.emit(done)
+}
+
+// Close stops serving, delivers the remaining events to attached
+// clients, and closes the log.
+func (s *Server) Close() error {
+	s.Stop()
+	s.ln.Close()
+	s.mu.Lock()
+	for c := range s.clients {
+		delete(s.clients, c)
+		close(c.events)
+	}
+	s.mu.Unlock()
+	s.writers.Wait()
+	return s.log.Close()
+}
+
+// emit numbers e, appends it to the log, and sends it to every client.
+func (s *Server) emit(e Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e.Seq = len(s.events) + 1
+	e.Time = time.Now().UTC()
+	s.events = append(s.events, e)
+	if data, err := json.Marshal(e); err == nil {
+		s.log.Write(append(data, '\n'))
+	}
+	for c := range s.clients {
+		select {
+		case c.events <- e:
+		default:
+			delete(s.clients, c)
+			close(c.events)
+		}
+	}
+}
+
+func (s *Server) attached() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.clients)
+}
+
+func (s *Server) accept() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.serve(conn)
+	}
+}
+
+// serve replays the events a client missed, then streams new ones while
+// reading its requests.
+func (s *Server) serve(conn net.Conn) {
+	dec := json.NewDecoder(conn)
+	var req Request
+	if dec.Decode(&req) != nil || req.Type != "attach" {
+		conn.Close()
+		return
+	}
+
+	c := &client{events: make(chan Event, clientBuffer)}
+	s.mu.Lock()
+	// Permission prompts still waiting are sent again even if the
+	// client saw them before, so it knows to answer them.
+	var backlog []Event
+	after := min(max(req.After, 0), len(s.events))
+	for _, e := range s.events[:after] {
+		if e.Kind == KindPermission && s.answers[e.ID] != nil {
+			backlog = append(backlog, e)
+		}
+	}
+	backlog = append(backlog, s.events[after:]...)
+	s.clients[c] = true
+	s.writers.Add(1)
+	s.mu.Unlock()
+
+	go func() {
+		defer s.writers.Done()
+		defer conn.Close()
+		enc := json.NewEncoder(conn)
+		write := func(e Event) bool {
+			conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			return enc.Encode(e) == nil
+		}
+		for _, e := range backlog {
+			if !write(e) {
+				return
+			}
+		}
+		for e := range c.events {
+			if !write(e) {
+				return
+			}
+		}
+	}()
+
+	for {
+		var req Request
+		if dec.Decode(&req) != nil || req.Type == "detach" {
+			break
+		}
+		switch req.Type {
+		case "prompt":
+			s.Submit(req.Text)
+		case "answer":
+			s.answer(req.ID, req.Allow)
+		case "stop":
+			s.Stop()
+		}
+	}
+
+	// Closing the channel makes the writer finish and close conn.
+	s.mu.Lock()
+	if s.clients[c] {
+		delete(s.clients, c)
+		close(c.events)
+	}
+	s.mu.Unlock()
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,165 @@
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// screen collects a client's output and lets a test wait for text.
+type screen struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (s *screen) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.buf.Write(p)
+}
+
+func (s *screen) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.buf.String()
+}
+
+func (s *screen) waitFor(t *testing.T, text string, count int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for strings.Count(s.String(), text) < count {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %q in:\n%s", text, s.String())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func kinds(events []Event) string {
+	var k []string
+	for _, e := range events {
+		k = append(k, e.Kind)
+	}
+	return strings.Join(k, ",")
+}
+
+func TestServer_RunsQueuedPromptsThenExits(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Listen(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Submit("refactor the parser")
+	err = s.Run(context.Background(), func(_ context.Context, prompt string) error {
+		fmt.Fprintf(s.Stdout(), "working on %s", prompt)
+		fmt.Fprintln(s.Stderr(), "[tool] edit_file")
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	events, err := ReadEvents(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := kinds(events); got != "prompt,output,log,done,exit" {
+		t.Errorf("events = %s", got)
+	}
+	if events[4].Seq != 5 || events[4].Text != "finished" {
+		t.Errorf("unexpected exit event %+v", events[4])
+	}
+
+	// The daemon is gone, so attaching replays the log, and only once.
+	var out bytes.Buffer
+	if err := Attach(context.Background(), dir, AttachOptions{In: strings.NewReader(""), Out: &out}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"> refactor the parser", "working on refactor the parser", "[tool] edit_file", "[done]", "[daemon] exited: finished"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("replay missing %q:\n%s", want, out.String())
+		}
+	}
+	out.Reset()
+	Attach(context.Background(), dir, AttachOptions{In: strings.NewReader(""), Out: &out})
+	if strings.Contains(out.String(), "refactor") || !strings.Contains(out.String(), "not running") {
+		t.Errorf("expected nothing new on the second attach:\n%s", out.String())
+	}
+	out.Reset()
+	Attach(context.Background(), dir, AttachOptions{All: true, In: strings.NewReader(""), Out: &out})
+	if !strings.Contains(out.String(), "refactor") {
+		t.Errorf("--all should replay everything:\n%s", out.String())
+	}
+}
+
+func TestAttach_AnswersPermissionsAcrossReattach(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Listen(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	// Attach first so the daemon does not exit once the prompt is done.
+	in1, w1 := io.Pipe()
+	out1 := &screen{}
+	attached := make(chan error, 1)
+	go func() { attached <- Attach(context.Background(), dir, AttachOptions{In: in1, Out: out1}) }()
+	for s.attached() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	s.Submit("clean up")
+	ran := make(chan error, 1)
+	go func() {
+		ran <- s.Run(context.Background(), func(_ context.Context, prompt string) error {
+			if s.Check(context.Background(), "write_file", "Write 3 bytes to a.go") {
+				fmt.Fprint(s.Stdout(), "wrote a.go")
+			} else {
+				fmt.Fprint(s.Stdout(), "skipped a.go")
+			}
+			return nil
+		})
+	}()
+
+	// Leave without answering; the run waits.
+	out1.waitFor(t, "[y/n]", 1)
+	fmt.Fprintln(w1, "/detach")
+	if err := <-attached; err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out1.String(), "the session keeps running") {
+		t.Errorf("expected a detach notice:\n%s", out1.String())
+	}
+
+	// Reattaching shows the prompt that is still waiting, even though
+	// this client saw it before, and nothing else again.
+	in2, w2 := io.Pipe()
+	out2 := &screen{}
+	go func() { attached <- Attach(context.Background(), dir, AttachOptions{In: in2, Out: out2}) }()
+	out2.waitFor(t, "[y/n]", 1)
+	if strings.Contains(out2.String(), "> clean up") {
+		t.Errorf("events seen before detaching should not be replayed:\n%s", out2.String())
+	}
+	fmt.Fprintln(w2, "n")
+	out2.waitFor(t, "skipped a.go", 1)
+	out2.waitFor(t, "[done]", 1)
+
+	fmt.Fprintln(w2, "/stop")
+	if err := <-ran; err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+	if err := <-attached; err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out2.String(), "[permission] denied") || !strings.Contains(out2.String(), "[daemon] exited: stopped") {
+		t.Errorf("unexpected output:\n%s", out2.String())
+	}
+}
@@ -0,0 +1,336 @@
+// Package devcontainer reads a project's devcontainer.json so that
+// shell_exec can run inside the project's official development
+// environment.
+//
+// Only the parts that matter for running commands are supported: the
+// image or Dockerfile build, and the declared environment variables.
+// Features, lifecycle commands, and Docker Compose setups are ignored.
+package devcontainer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gavinyap/stormtrooper/internal/config"
+)
+
+// candidates are the devcontainer.json locations checked, in order.
+var candidates = []string{
+	filepath.Join(".devcontainer", "devcontainer.json"),
+	".devcontainer.json",
+}
+
+// Config is the subset of devcontainer.json that stormtrooper uses.
+type Config struct {
+	Name         string            `json:"name"`
+	Image        string            `json:"image"`
+	DockerFile   string            `json:"dockerFile"` // legacy spelling of build.dockerfile
+	Build        *Build            `json:"build"`
+	ComposeFile  json.RawMessage   `json:"dockerComposeFile"`
+	ContainerEnv map[string]string `json:"containerEnv"`
+	RemoteEnv    map[string]string `json:"remoteEnv"`
+
+	// Path is the devcontainer.json file this was read from.
+	Path string `json:"-"`
+
+	workDir string
+
+	// run executes an engine command; replaced in tests.
+	run func(ctx context.Context, name string, args ...string) ([]byte, error)
+}
+
+// Build describes how to build the container image from a Dockerfile.
+type Build struct {
+	Dockerfile string            `json:"dockerfile"`
+	Context    string            `json:"context"`
+	Args       map[string]string `json:"args"`
+}
+
+// Find looks for a devcontainer.json in projectDir. It returns nil and no
+// error when the project has none.
+func Find(projectDir string) (*Config, error) {
+	for _, rel := range candidates {
+		path := filepath.Join(projectDir, rel)
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		c, err := Parse(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		c.Path = path
+		c.workDir = projectDir
+		return c, nil
+	}
+	return nil, nil
+}
+
+// Parse decodes devcontainer.json content, which may contain comments and
+// trailing commas.
+func Parse(data []byte) (*Config, error) {
+	var c Config
+	if err := json.Unmarshal(standardize(data), &c); err != nil {
+		return nil, fmt.Errorf("invalid devcontainer.json: %w", err)
+	}
+	c.run = runEngine
+	return &c, nil
+}
+
+// dockerfile returns the Dockerfile path relative to devcontainer.json,
+// or "" when the config names an image instead.
+func (c *Config) dockerfile() string {
+	if c.Build != nil && c.Build.Dockerfile != "" {
+		return c.Build.Dockerfile
+	}
+	return c.DockerFile
+}
+
+// Sandbox returns a sandbox configuration that runs the dev container
+// with engine ("docker" when empty), building the image first if the
+// config declares a Dockerfile and it has not been built yet.
+//
+// The dev container gets bridge networking, since project environments
+// usually need to fetch dependencies. When offline, it gets no network,
+// and the image must already be on this machine: nothing is pulled or
+// built.
+func (c *Config) Sandbox(ctx context.Context, engine string, offline bool) (config.SandboxConfig, error) {
+	if engine == "" {
+		engine = "docker"
+	}
+	if len(c.ComposeFile) > 0 {
+		return config.SandboxConfig{}, errors.New("devcontainer: Docker Compose configurations are not supported")
+	}
+	image := c.substitute(c.Image)
+	if c.dockerfile() != "" {
+		var err error
+		if image, err = c.buildImage(ctx, engine, offline); err != nil {
+			return config.SandboxConfig{}, err
+		}
+	}
+	if image == "" {
+		return config.SandboxConfig{}, errors.New("devcontainer: neither image nor build.dockerfile is set")
+	}
+	network := "bridge"
+	if offline {
+		network = "none"
+		if _, err := c.run(ctx, engine, "image", "inspect", image); err != nil {
+			return config.SandboxConfig{}, fmt.Errorf("devcontainer: image %s is not on this machine, and offline mode cannot pull it", image)
+		}
+	}
+	return config.SandboxConfig{
+		Engine:  engine,
+		Image:   image,
+		Network: network,
+		Env:     c.Env(),
+	}, nil
+}
+
+// buildImage builds the Dockerfile, tagging the image with a hash of its
+// inputs so an unchanged Dockerfile is built only once. When offline, it
+// only finds an image built before.
+func (c *Config) buildImage(ctx context.Context, engine string, offline bool) (string, error) {
+	base := filepath.Dir(c.Path)
+	dockerfile := filepath.Join(base, c.dockerfile())
+	buildCtx := base
+	var args map[string]string
+	if c.Build != nil {
+		if c.Build.Context != "" {
+			buildCtx = filepath.Join(base, c.Build.Context)
+		}
+		args = c.Build.Args
+	}
+
+	content, err := os.ReadFile(dockerfile)
+	if err != nil {
+		return "", fmt.Errorf("devcontainer: %w", err)
+	}
+	h := sha256.New()
+	h.Write(content)
+	fmt.Fprintln(h, buildCtx)
+	buildArgs := []string{"build", "--file", dockerfile}
+	for _, k := range sortedKeys(args) {
+		v := c.substitute(args[k])
+		fmt.Fprintf(h, "%s=%s\n", k, v)
+		buildArgs = append(buildArgs, "--build-arg", k+"="+v)
+	}
+	tag := "stormtrooper-devcontainer:" + hex.EncodeToString(h.Sum(nil))[:12]
+
+	if _, err := c.run(ctx, engine, "image", "inspect", tag); err == nil {
+		return tag, nil
+	}
+	if offline {
+		return "", fmt.Errorf("devcontainer: %s has not been built, and offline mode cannot build it", c.dockerfile())
+	}
+	buildArgs = append(buildArgs, "--tag", tag, buildCtx)
+	if out, err := c.run(ctx, engine, buildArgs...); err != nil {
+		return "", fmt.Errorf("devcontainer: %s build failed: %v: %s", engine, err, lastLines(out, 20))
+	}
+	return tag, nil
+}
+
+// Env returns the variables to set in the container: containerEnv,
+// overridden by remoteEnv. Entries that refer to ${containerEnv:...}
+// cannot be resolved before the container starts and are skipped.
+func (c *Config) Env() map[string]string {
+	env := make(map[string]string)
+	for _, vars := range []map[string]string{c.ContainerEnv, c.RemoteEnv} {
+		for k, v := range vars {
+			if strings.Contains(v, "${containerEnv:") {
+				continue
+			}
+			env[k] = c.substitute(v)
+		}
+	}
+	return env
+}
+
+var variablePattern = regexp.MustCompile(`\$\{([^}]+)\}`)
+
+// substitute expands ${localEnv:NAME}, ${localEnv:NAME:default},
+// ${localWorkspaceFolder}, and ${containerWorkspaceFolder}. The project is
+// mounted at the same path in the container, so both folders are the
+// local working directory. Unknown variables are left as they are.
+func (c *Config) substitute(s string) string {
+	return variablePattern.ReplaceAllStringFunc(s, func(m string) string {
+		name := m[2 : len(m)-1]
+		switch name {
+		case "localWorkspaceFolder", "containerWorkspaceFolder":
+			return c.workDir
+		case "localWorkspaceFolderBasename", "containerWorkspaceFolderBasename":
+			return filepath.Base(c.workDir)
+		}
+		if rest, ok := strings.CutPrefix(name, "localEnv:"); ok {
+			key, def, _ := strings.Cut(rest, ":")
+			if v, ok := os.LookupEnv(key); ok {
+				return v
+			}
+			return def
+		}
+		return m
+	})
+}
+
+// Ask prompts on out and reads a yes/no answer from in.
+func Ask(in io.Reader, out io.Writer, path string) (bool, error) {
+	fmt.Fprintf(out, "\nThis project has a dev container:\n  %s\n", path)
+	fmt.Fprintln(out, "Run commands inside it (building the image if needed)?")
+	fmt.Fprint(out, "[y/n]: ")
+
+	scanner := bufio.NewScanner(in)
+	if !scanner.Scan() {
+		fmt.Fprintln(out)
+		if err := scanner.Err(); err != nil {
+			return false, err
+		}
+		return false, io.EOF
+	}
+	line := strings.TrimSpace(scanner.Text())
+	return len(line) > 0 && (line[0] == 'y' || line[0] == 'Y'), nil
+}
+
+// standardize turns JSON with comments and trailing commas into plain
+// JSON. String contents are left untouched.
+func standardize(data []byte) []byte {
+	return dropTrailingCommas(stripComments(data))
+}
+
+func stripComments(data []byte) []byte {
+	var out bytes.Buffer
+	for i := 0; i < len(data); i++ {
+		switch {
+		case data[i] == '"':
+			end := stringEnd(data, i)
+			out.Write(data[i:end])
+			i = end - 1
+		case data[i] == '/' && i+1 < len(data) && data[i+1] == '/':
+			for i+1 < len(data) && data[i+1] != '\n' {
+				i++
+			}
+		case data[i] == '/' && i+1 < len(data) && data[i+1] == '*':
+			end := bytes.Index(data[i+2:], []byte("*/"))
+			if end < 0 {
+				return out.Bytes()
+			}
+			i += end + 3
+			out.WriteByte(' ')
+		default:
+			out.WriteByte(data[i])
+		}
+	}
+	return out.Bytes()
+}
+
+func dropTrailingCommas(data []byte) []byte {
+	var out bytes.Buffer
+	for i := 0; i < len(data); i++ {
+		switch data[i] {
+		case '"':
+			end := stringEnd(data, i)
+			out.Write(data[i:end])
+			i = end - 1
+		case ',':
+			j := i + 1
+			for j < len(data) && strings.IndexByte(" \t\r\n", data[j]) >= 0 {
+				j++
+			}
+			if j < len(data) && (data[j] == '}' || data[j] == ']') {
+				continue
+			}
+			out.WriteByte(',')
+		default:
+			out.WriteByte(data[i])
+		}
+	}
+	return out.Bytes()
+}
+
+// stringEnd returns the index just past the JSON string starting at i.
+func stringEnd(data []byte, i int) int {
+	for j := i + 1; j < len(data); j++ {
+		switch data[j] {
+		case '\\':
+			j++
+		case '"':
+			return j + 1
+		}
+	}
+	return len(data)
+}
+
+func lastLines(out []byte, n int) string {
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func runEngine(ctx context.Context, name string, args ...string) ([]byte, error) {
+	return exec.CommandContext(ctx, name, args...).CombinedOutput()
+}
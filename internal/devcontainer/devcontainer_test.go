@@ -0,0 +1,203 @@
+package devcontainer
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeConfig(t *testing.T, dir, content string) {
+	t.Helper()
+	os.MkdirAll(filepath.Join(dir, ".devcontainer"), 0755)
+	if err := os.WriteFile(filepath.Join(dir, ".devcontainer", "devcontainer.json"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// fakeEngine records engine invocations; image inspect fails unless the
+// image has been "built".
+type fakeEngine struct {
+	calls [][]string
+	built map[string]bool
+}
+
+func (f *fakeEngine) run(ctx context.Context, name string, args ...string) ([]byte, error) {
+	f.calls = append(f.calls, append([]string{name}, args...))
+	switch args[0] {
+	case "image":
+		if !f.built[args[2]] {
+			return []byte("no such image"), errors.New("exit status 1")
+		}
+	case "build":
+		f.built[args[len(args)-2]] = true
+	}
+	return nil, nil
+}
+
+func TestFind_None(t *testing.T) {
+	c, err := Find(t.TempDir())
+	if c != nil || err != nil {
+		t.Errorf("expected nil, nil; got %v, %v", c, err)
+	}
+}
+
+func TestParse_Comments(t *testing.T) {
+	c, err := Parse([]byte(`{
+		// The official toolchain.
+		"name": "app // not a comment",
+		/* block
+		   comment */
+		"image": "mcr.microsoft.com/devcontainers/go:1.25",
+		"containerEnv": {"URL": "http://example.com/*x*/",},
+	}`))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if c.Name != "app // not a comment" {
+		t.Errorf("name = %q", c.Name)
+	}
+	if c.Image != "mcr.microsoft.com/devcontainers/go:1.25" {
+		t.Errorf("image = %q", c.Image)
+	}
+	if c.ContainerEnv["URL"] != "http://example.com/*x*/" {
+		t.Errorf("env = %v", c.ContainerEnv)
+	}
+}
+
+func TestSandbox_Image(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, `{
+		"image": "golang:1.25",
+		"containerEnv": {"GOFLAGS": "-mod=mod", "SRC": "${containerWorkspaceFolder}/src"},
+		"remoteEnv": {"GOFLAGS": "-mod=vendor", "TOKEN": "${localEnv:DC_TEST_TOKEN}", "PATH": "${containerEnv:PATH}:/opt/bin"}
+	}`)
+	t.Setenv("DC_TEST_TOKEN", "secret")
+
+	c, err := Find(dir)
+	if err != nil || c == nil {
+		t.Fatalf("Find: %v, %v", c, err)
+	}
+	sb, err := c.Sandbox(context.Background(), "", false)
+	if err != nil {
+		t.Fatalf("Sandbox: %v", err)
+	}
+	if sb.Engine != "docker" || sb.Image != "golang:1.25" || sb.Network != "bridge" {
+		t.Errorf("unexpected sandbox %+v", sb)
+	}
+	want := map[string]string{"GOFLAGS": "-mod=vendor", "SRC": dir + "/src", "TOKEN": "secret"}
+	if len(sb.Env) != len(want) {
+		t.Errorf("env = %v, want %v", sb.Env, want)
+	}
+	for k, v := range want {
+		if sb.Env[k] != v {
+			t.Errorf("env[%s] = %q, want %q", k, sb.Env[k], v)
+		}
+	}
+}
+
+func TestSandbox_BuildsOnce(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, `{"build": {"dockerfile": "Dockerfile", "context": "..", "args": {"GO": "1.25"}}}`)
+	os.WriteFile(filepath.Join(dir, ".devcontainer", "Dockerfile"), []byte("FROM golang:1.25\n"), 0644)
+
+	fake := &fakeEngine{built: map[string]bool{}}
+	for i := 0; i < 2; i++ {
+		c, _ := Find(dir)
+		c.run = fake.run
+		sb, err := c.Sandbox(context.Background(), "podman", false)
+		if err != nil {
+			t.Fatalf("Sandbox: %v", err)
+		}
+		if !strings.HasPrefix(sb.Image, "stormtrooper-devcontainer:") {
+			t.Errorf("image = %q", sb.Image)
+		}
+	}
+
+	var builds []string
+	for _, call := range fake.calls {
+		if call[1] == "build" {
+			builds = append(builds, strings.Join(call, " "))
+		}
+	}
+	if len(builds) != 1 {
+		t.Fatalf("expected one build, got %v", builds)
+	}
+	for _, want := range []string{
+		"podman build --file " + filepath.Join(dir, ".devcontainer", "Dockerfile"),
+		"--build-arg GO=1.25",
+		" " + dir,
+	} {
+		if !strings.Contains(builds[0], want) {
+			t.Errorf("build %q missing %q", builds[0], want)
+		}
+	}
+}
+
+func TestSandbox_Errors(t *testing.T) {
+	tests := []struct {
+		name, json, want string
+	}{
+		{"nothing to run", `{"name": "x"}`, "neither image"},
+		{"compose", `{"dockerComposeFile": "compose.yml"}`, "Compose"},
+		{"missing dockerfile", `{"build": {"dockerfile": "Nope"}}`, "Nope"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeConfig(t, dir, tt.json)
+			c, _ := Find(dir)
+			c.run = (&fakeEngine{built: map[string]bool{}}).run
+			if _, err := c.Sandbox(context.Background(), "", false); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestSandbox_Offline(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, `{"image": "golang:1.25"}`)
+	fake := &fakeEngine{built: map[string]bool{}}
+	c, _ := Find(dir)
+	c.run = fake.run
+
+	if _, err := c.Sandbox(context.Background(), "", true); err == nil || !strings.Contains(err.Error(), "cannot pull") {
+		t.Errorf("expected a missing image to be refused, got %v", err)
+	}
+	fake.built["golang:1.25"] = true
+	sb, err := c.Sandbox(context.Background(), "", true)
+	if err != nil || sb.Network != "none" {
+		t.Errorf("offline sandbox = %+v, %v", sb, err)
+	}
+
+	// A Dockerfile is never built offline.
+	writeConfig(t, dir, `{"build": {"dockerfile": "Dockerfile"}}`)
+	os.WriteFile(filepath.Join(dir, ".devcontainer", "Dockerfile"), []byte("FROM golang:1.25\n"), 0644)
+	c, _ = Find(dir)
+	c.run = fake.run
+	if _, err := c.Sandbox(context.Background(), "", true); err == nil || !strings.Contains(err.Error(), "cannot build") {
+		t.Errorf("expected the build to be refused, got %v", err)
+	}
+	for _, call := range fake.calls {
+		if call[1] != "image" {
+			t.Errorf("offline mode ran %q", strings.Join(call, " "))
+		}
+	}
+}
+
+func TestAsk(t *testing.T) {
+	var out strings.Builder
+	ok, err := Ask(strings.NewReader("y\n"), &out, "/p/.devcontainer/devcontainer.json")
+	if err != nil || !ok {
+		t.Errorf("Ask(y) = %v, %v", ok, err)
+	}
+	if !strings.Contains(out.String(), "/p/.devcontainer/devcontainer.json") {
+		t.Errorf("prompt should name the file: %q", out.String())
+	}
+	if ok, _ := Ask(strings.NewReader("n\n"), &out, "x"); ok {
+		t.Error("Ask(n) should be false")
+	}
+}
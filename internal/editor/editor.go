@@ -0,0 +1,75 @@
+// Package editor opens files in the user's editor.
+package editor
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Editor builds the command that opens a file at a line.
+type Editor struct {
+	args []string
+	dir  string
+}
+
+// New returns an editor for command, a program and its arguments in
+// which {path} and {line} are replaced (e.g. "code -g {path}:{line}").
+// Without {path}, the file is appended with a "+line" argument first,
+// which vi, vim, nano, emacs, and most terminal editors understand. An
+// empty command means $VISUAL, then $EDITOR, then vi. Relative paths
+// resolve against dir.
+func New(command, dir string, getenv func(string) string) *Editor {
+	for _, c := range []string{command, getenv("VISUAL"), getenv("EDITOR")} {
+		if args := strings.Fields(c); len(args) > 0 {
+			return &Editor{args: args, dir: dir}
+		}
+	}
+	return &Editor{args: []string{"vi"}, dir: dir}
+}
+
+// Command returns the process that opens path at line; a line of 0
+// opens the file at the top. The process is not started.
+func (e *Editor) Command(path string, line int) *exec.Cmd {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(e.dir, path)
+	}
+	lineArg := strconv.Itoa(max(line, 1))
+
+	var args []string
+	templated := false
+	for _, a := range e.args[1:] {
+		if strings.Contains(a, "{path}") {
+			templated = true
+		}
+		args = append(args, strings.NewReplacer("{path}", path, "{line}", lineArg).Replace(a))
+	}
+	if !templated {
+		if line > 0 {
+			args = append(args, "+"+lineArg)
+		}
+		args = append(args, path)
+	}
+	cmd := exec.Command(e.args[0], args...)
+	cmd.Dir = e.dir
+	return cmd
+}
+
+// ParseTarget splits "path:line" into its parts. Without a line suffix,
+// line is 0.
+func ParseTarget(target string) (path string, line int, err error) {
+	if target == "" {
+		return "", 0, fmt.Errorf("no file given")
+	}
+	if i := strings.LastIndex(target, ":"); i > 0 {
+		if n, err := strconv.Atoi(target[i+1:]); err == nil {
+			if n < 1 {
+				return "", 0, fmt.Errorf("invalid line %d", n)
+			}
+			return target[:i], n, nil
+		}
+	}
+	return target, 0, nil
+}
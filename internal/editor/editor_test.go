@@ -0,0 +1,58 @@
+package editor
+
+import (
+	"strings"
+	"testing"
+)
+
+func env(vars map[string]string) func(string) string {
+	return func(k string) string { return vars[k] }
+}
+
+func TestCommand(t *testing.T) {
+	tests := []struct {
+		command string
+		env     map[string]string
+		path    string
+		line    int
+		want    string
+	}{
+		{"", nil, "main.go", 0, "vi /work/main.go"},
+		{"", map[string]string{"EDITOR": "nano"}, "main.go", 12, "nano +12 /work/main.go"},
+		{"", map[string]string{"VISUAL": "nvim", "EDITOR": "nano"}, "/etc/hosts", 3, "nvim +3 /etc/hosts"},
+		{"code -g {path}:{line}", map[string]string{"EDITOR": "nano"}, "a/b.go", 7, "code -g /work/a/b.go:7"},
+		{"code -g {path}:{line}", nil, "a/b.go", 0, "code -g /work/a/b.go:1"},
+		{"emacsclient -n", nil, "x.go", 5, "emacsclient -n +5 /work/x.go"},
+	}
+	for _, tt := range tests {
+		cmd := New(tt.command, "/work", env(tt.env)).Command(tt.path, tt.line)
+		if got := strings.Join(cmd.Args, " "); got != tt.want {
+			t.Errorf("New(%q, env %v).Command(%q, %d) = %q, want %q", tt.command, tt.env, tt.path, tt.line, got, tt.want)
+		}
+		if cmd.Dir != "/work" {
+			t.Errorf("Dir = %q", cmd.Dir)
+		}
+	}
+}
+
+func TestParseTarget(t *testing.T) {
+	tests := []struct {
+		target string
+		path   string
+		line   int
+		ok     bool
+	}{
+		{"main.go", "main.go", 0, true},
+		{"main.go:42", "main.go", 42, true},
+		{`C:\src\main.go:7`, `C:\src\main.go`, 7, true},
+		{"notes:todo.txt", "notes:todo.txt", 0, true},
+		{"main.go:0", "", 0, false},
+		{"", "", 0, false},
+	}
+	for _, tt := range tests {
+		path, line, err := ParseTarget(tt.target)
+		if (err == nil) != tt.ok || path != tt.path || line != tt.line {
+			t.Errorf("ParseTarget(%q) = %q, %d, %v", tt.target, path, line, err)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package forge
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+type bitbucket struct {
+	c    *client
+	repo string // workspace/repo
+}
+
+func (b *bitbucket) Kind() string { return Bitbucket }
+
+type bitbucketContent struct {
+	Raw string `json:"raw"`
+}
+
+type bitbucketLinks struct {
+	HTML struct {
+		Href string `json:"href"`
+	} `json:"html"`
+}
+
+func (b *bitbucket) Issue(ctx context.Context, number int) (*Issue, error) {
+	var issue struct {
+		ID      int              `json:"id"`
+		Title   string           `json:"title"`
+		State   string           `json:"state"`
+		Content bitbucketContent `json:"content"`
+		Links   bitbucketLinks   `json:"links"`
+	}
+	if err := b.c.do(ctx, http.MethodGet, fmt.Sprintf("/repositories/%s/issues/%d", b.repo, number), nil, &issue); err != nil {
+		return nil, err
+	}
+	var comments struct {
+		Values []struct {
+			Content bitbucketContent `json:"content"`
+			User    struct {
+				DisplayName string `json:"display_name"`
+			} `json:"user"`
+		} `json:"values"`
+	}
+	if err := b.c.do(ctx, http.MethodGet, fmt.Sprintf("/repositories/%s/issues/%d/comments?pagelen=100", b.repo, number), nil, &comments); err != nil {
+		return nil, err
+	}
+	result := &Issue{Number: issue.ID, Title: issue.Title, State: issue.State, Body: issue.Content.Raw, URL: issue.Links.HTML.Href}
+	for _, c := range comments.Values {
+		if c.Content.Raw == "" {
+			continue // status changes are recorded as empty comments
+		}
+		result.Comments = append(result.Comments, Comment{Author: c.User.DisplayName, Body: c.Content.Raw})
+	}
+	return result, nil
+}
+
+func (b *bitbucket) Comment(ctx context.Context, number int, pullRequest bool, body string) error {
+	kind := "issues"
+	if pullRequest {
+		kind = "pullrequests"
+	}
+	return b.c.do(ctx, http.MethodPost, fmt.Sprintf("/repositories/%s/%s/%d/comments", b.repo, kind, number),
+		map[string]any{"content": bitbucketContent{Raw: body}}, nil)
+}
+
+// OpenChangeRequest omits the destination when no target is given;
+// Bitbucket then uses the repository's main branch.
+func (b *bitbucket) OpenChangeRequest(ctx context.Context, cr ChangeRequest) (string, error) {
+	branch := func(name string) map[string]any {
+		return map[string]any{"branch": map[string]string{"name": name}}
+	}
+	req := map[string]any{
+		"title":       cr.Title,
+		"description": cr.Body,
+		"source":      branch(cr.SourceBranch),
+	}
+	if cr.TargetBranch != "" {
+		req["destination"] = branch(cr.TargetBranch)
+	}
+	var pr struct {
+		Links bitbucketLinks `json:"links"`
+	}
+	err := b.c.do(ctx, http.MethodPost, fmt.Sprintf("/repositories/%s/pullrequests", b.repo), req, &pr)
+	return pr.Links.HTML.Href, err
+}
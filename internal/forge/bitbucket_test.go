@@ -0,0 +1,57 @@
+package forge
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/gavinyap/stormtrooper/internal/config"
+)
+
+func TestBitbucket(t *testing.T) {
+	srv, reqs := fakeServer(t, map[string]string{
+		"GET /repositories/w/r/issues/2":                      `{"id":2,"title":"Typo","state":"new","content":{"raw":"In README"},"links":{"html":{"href":"https://bitbucket.org/w/r/issues/2"}}}`,
+		"GET /repositories/w/r/issues/2/comments?pagelen=100": `{"values":[{"content":{"raw":""},"user":{"display_name":"Bot"}},{"content":{"raw":"Line 3"},"user":{"display_name":"Sam"}}]}`,
+		"POST /repositories/w/r/issues/2/comments":            `{}`,
+		"POST /repositories/w/r/pullrequests":                 `{"links":{"html":{"href":"https://bitbucket.org/w/r/pull-requests/9"}}}`,
+	})
+	f, err := New(config.ForgeConfig{URL: srv.URL, Token: "sam:app-pass"}, "git@bitbucket.org:w/r.git")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	issue, err := f.Issue(ctx, 2)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	if issue.Body != "In README" || len(issue.Comments) != 1 || issue.Comments[0].Author != "Sam" {
+		t.Errorf("unexpected issue %+v", issue)
+	}
+	if user, pass, ok := srvBasicAuth((*reqs)[0].Header.Get("Authorization")); !ok || user != "sam" || pass != "app-pass" {
+		t.Errorf("expected basic auth, got %q", (*reqs)[0].Header.Get("Authorization"))
+	}
+
+	if err := f.Comment(ctx, 2, false, "Fixed"); err != nil {
+		t.Fatalf("Comment: %v", err)
+	}
+	if content, _ := (*reqs)[2].Body["content"].(map[string]any); content["raw"] != "Fixed" {
+		t.Errorf("unexpected comment body %+v", (*reqs)[2].Body)
+	}
+
+	url, err := f.OpenChangeRequest(ctx, ChangeRequest{Title: "Fix typo", SourceBranch: "typo"})
+	if err != nil || url != "https://bitbucket.org/w/r/pull-requests/9" {
+		t.Fatalf("OpenChangeRequest = %q, %v", url, err)
+	}
+	last := (*reqs)[len(*reqs)-1]
+	if _, ok := last.Body["destination"]; ok {
+		t.Error("destination should be omitted without a target branch")
+	}
+}
+
+// srvBasicAuth decodes a basic Authorization header.
+func srvBasicAuth(header string) (user, pass string, ok bool) {
+	r, _ := http.NewRequest("GET", "/", nil)
+	r.Header.Set("Authorization", header)
+	return r.BasicAuth()
+}
@@ -0,0 +1,231 @@
+// Package forge talks to the code hosting service a repository lives on
+// (GitHub, GitLab, or Bitbucket) so the agent can read issues, comment,
+// and open pull or merge requests.
+//
+// The service is detected from the origin remote; config can override
+// the type and API URL for self-hosted instances.
+package forge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/gavinyap/stormtrooper/internal/config"
+)
+
+// Supported forge types.
+const (
+	GitHub    = "github"
+	GitLab    = "gitlab"
+	Bitbucket = "bitbucket"
+)
+
+// Issue is an issue with its discussion.
+type Issue struct {
+	Number   int
+	Title    string
+	State    string
+	Body     string
+	URL      string
+	Comments []Comment
+}
+
+// Comment is one message in an issue discussion.
+type Comment struct {
+	Author string
+	Body   string
+}
+
+// ChangeRequest describes a pull request (merge request on GitLab) to open.
+type ChangeRequest struct {
+	Title        string
+	Body         string
+	SourceBranch string
+	TargetBranch string // empty for the repository's default branch
+}
+
+// Forge is a code hosting service API for one repository.
+type Forge interface {
+	// Kind returns GitHub, GitLab, or Bitbucket.
+	Kind() string
+	Issue(ctx context.Context, number int) (*Issue, error)
+	// Comment adds a comment to an issue, or to a pull request when
+	// pullRequest is true.
+	Comment(ctx context.Context, number int, pullRequest bool, body string) error
+	// OpenChangeRequest opens a pull or merge request and returns its URL.
+	OpenChangeRequest(ctx context.Context, cr ChangeRequest) (string, error)
+}
+
+// Remote identifies a repository on a host.
+type Remote struct {
+	Host string // e.g. "github.com"
+	Path string // e.g. "owner/repo" or "group/subgroup/repo"
+}
+
+// ParseRemote parses an https, ssh, or scp-style git remote URL.
+func ParseRemote(url string) (Remote, error) {
+	url = strings.TrimSpace(url)
+	rest := url
+	if _, after, ok := strings.Cut(url, "://"); ok {
+		rest = after
+		// Drop user info and port: git@host:2222/path -> host/path.
+		if i := strings.Index(rest, "@"); i >= 0 && i < strings.Index(rest+"/", "/") {
+			rest = rest[i+1:]
+		}
+		host, path, _ := strings.Cut(rest, "/")
+		host, _, _ = strings.Cut(host, ":")
+		rest = host + "/" + path
+	} else {
+		// scp-style: git@host:owner/repo.git
+		if i := strings.Index(rest, "@"); i >= 0 {
+			rest = rest[i+1:]
+		}
+		rest = strings.Replace(rest, ":", "/", 1)
+	}
+	host, path, _ := strings.Cut(rest, "/")
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	if host == "" || !strings.Contains(path, "/") {
+		return Remote{}, fmt.Errorf("unrecognized remote URL %q", url)
+	}
+	return Remote{Host: host, Path: path}, nil
+}
+
+// OriginRemote returns the origin remote URL of the repository in dir.
+func OriginRemote(dir string) (string, error) {
+	cmd := exec.Command("git", "remote", "get-url", "origin")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("no origin remote: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// ErrNoForge is returned by New when the remote is not on a supported
+// service and config does not name one.
+var ErrNoForge = errors.New("origin remote is not on a supported forge")
+
+// tokenEnv is the environment variable holding each forge's token.
+var tokenEnv = map[string]string{
+	GitHub:    "GITHUB_TOKEN",
+	GitLab:    "GITLAB_TOKEN",
+	Bitbucket: "BITBUCKET_TOKEN",
+}
+
+// New returns the Forge for remoteURL. The type is taken from cfg or
+// guessed from the host name, and the token from cfg or the forge's
+// environment variable.
+func New(cfg config.ForgeConfig, remoteURL string) (Forge, error) {
+	remote, err := ParseRemote(remoteURL)
+	if err != nil {
+		return nil, err
+	}
+	kind := cfg.Type
+	if kind == "" {
+		for _, k := range []string{GitHub, GitLab, Bitbucket} {
+			if strings.Contains(remote.Host, k) {
+				kind = k
+				break
+			}
+		}
+	}
+	token := cfg.Token
+	if token == "" {
+		token = os.Getenv(tokenEnv[kind])
+	}
+
+	c := &client{http: &http.Client{Timeout: 30 * time.Second}, baseURL: strings.TrimSuffix(cfg.URL, "/")}
+	switch kind {
+	case GitHub:
+		if c.baseURL == "" {
+			c.baseURL = "https://api.github.com"
+			if remote.Host != "github.com" {
+				c.baseURL = "https://" + remote.Host + "/api/v3" // GitHub Enterprise
+			}
+		}
+		c.auth = func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+token) }
+		return &github{c: c, repo: remote.Path}, nil
+	case GitLab:
+		if c.baseURL == "" {
+			c.baseURL = "https://" + remote.Host + "/api/v4"
+		}
+		c.auth = func(r *http.Request) { r.Header.Set("PRIVATE-TOKEN", token) }
+		return &gitlab{c: c, project: remote.Path}, nil
+	case Bitbucket:
+		if c.baseURL == "" {
+			c.baseURL = "https://api.bitbucket.org/2.0"
+		}
+		c.auth = func(r *http.Request) {
+			// "user:app_password" uses basic auth; anything else is an
+			// access token.
+			if user, pass, ok := strings.Cut(token, ":"); ok {
+				r.SetBasicAuth(user, pass)
+			} else {
+				r.Header.Set("Authorization", "Bearer "+token)
+			}
+		}
+		return &bitbucket{c: c, repo: remote.Path}, nil
+	case "":
+		return nil, ErrNoForge
+	}
+	return nil, fmt.Errorf("unsupported forge type %q (use github, gitlab, or bitbucket)", kind)
+}
+
+// client is a small JSON-over-HTTP helper shared by the forges.
+type client struct {
+	http    *http.Client
+	baseURL string
+	auth    func(*http.Request)
+}
+
+// do sends a request with an optional JSON body and decodes the JSON
+// response into out (when non-nil).
+func (c *client) do(ctx context.Context, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	c.auth(req)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg := strings.TrimSpace(string(data))
+		if len(msg) > 300 {
+			msg = msg[:300] + "..."
+		}
+		return fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, msg)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
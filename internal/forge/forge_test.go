@@ -0,0 +1,118 @@
+package forge
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gavinyap/stormtrooper/internal/config"
+)
+
+func TestParseRemote(t *testing.T) {
+	tests := []struct {
+		url  string
+		want Remote
+	}{
+		{"https://github.com/owner/repo.git", Remote{"github.com", "owner/repo"}},
+		{"https://github.com/owner/repo", Remote{"github.com", "owner/repo"}},
+		{"git@github.com:owner/repo.git", Remote{"github.com", "owner/repo"}},
+		{"ssh://git@gitlab.example.com:2222/group/sub/repo.git", Remote{"gitlab.example.com", "group/sub/repo"}},
+		{"https://user@bitbucket.org/workspace/repo.git", Remote{"bitbucket.org", "workspace/repo"}},
+	}
+	for _, tt := range tests {
+		got, err := ParseRemote(tt.url)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseRemote(%q) = %+v, %v; want %+v", tt.url, got, err, tt.want)
+		}
+	}
+	if _, err := ParseRemote("/local/path"); err == nil {
+		t.Error("expected error for a path without host and repo")
+	}
+}
+
+func TestNew_Detection(t *testing.T) {
+	tests := []struct {
+		remote   string
+		cfg      config.ForgeConfig
+		wantKind string
+		wantURL  string
+	}{
+		{"git@github.com:o/r.git", config.ForgeConfig{}, GitHub, "https://api.github.com"},
+		{"https://github.acme.com/o/r", config.ForgeConfig{}, GitHub, "https://github.acme.com/api/v3"},
+		{"https://gitlab.com/g/r.git", config.ForgeConfig{}, GitLab, "https://gitlab.com/api/v4"},
+		{"git@bitbucket.org:w/r.git", config.ForgeConfig{}, Bitbucket, "https://api.bitbucket.org/2.0"},
+		{"https://git.acme.com/g/r", config.ForgeConfig{Type: GitLab, URL: "https://git.acme.com/api/v4/"}, GitLab, "https://git.acme.com/api/v4"},
+	}
+	for _, tt := range tests {
+		f, err := New(tt.cfg, tt.remote)
+		if err != nil {
+			t.Errorf("New(%q): %v", tt.remote, err)
+			continue
+		}
+		if f.Kind() != tt.wantKind {
+			t.Errorf("New(%q) kind = %s, want %s", tt.remote, f.Kind(), tt.wantKind)
+		}
+		var base string
+		switch f := f.(type) {
+		case *github:
+			base = f.c.baseURL
+		case *gitlab:
+			base = f.c.baseURL
+		case *bitbucket:
+			base = f.c.baseURL
+		}
+		if base != tt.wantURL {
+			t.Errorf("New(%q) API URL = %s, want %s", tt.remote, base, tt.wantURL)
+		}
+	}
+
+	if _, err := New(config.ForgeConfig{}, "https://git.acme.com/g/r"); err != ErrNoForge {
+		t.Errorf("expected ErrNoForge for unknown host, got %v", err)
+	}
+	if _, err := New(config.ForgeConfig{Type: "gitea"}, "https://git.acme.com/g/r"); err == nil {
+		t.Error("expected error for unsupported type")
+	}
+}
+
+// recordedRequest is what the fake forge server saw.
+type recordedRequest struct {
+	Method string
+	Path   string // escaped path and query
+	Header http.Header
+	Body   map[string]any
+}
+
+// fakeServer serves canned JSON responses keyed by "METHOD path" and
+// records every request.
+func fakeServer(t *testing.T, responses map[string]string) (*httptest.Server, *[]recordedRequest) {
+	t.Helper()
+	var reqs []recordedRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := recordedRequest{Method: r.Method, Path: r.URL.RequestURI(), Header: r.Header}
+		data, _ := io.ReadAll(r.Body)
+		json.Unmarshal(data, &rec.Body)
+		reqs = append(reqs, rec)
+
+		resp, ok := responses[r.Method+" "+r.URL.RequestURI()]
+		if !ok {
+			http.Error(w, `{"message":"not found"}`, http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(resp))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &reqs
+}
+
+func TestClient_ErrorStatus(t *testing.T) {
+	srv, _ := fakeServer(t, nil)
+	f, _ := New(config.ForgeConfig{URL: srv.URL, Token: "t"}, "https://github.com/o/r")
+	_, err := f.Issue(context.Background(), 1)
+	if err == nil || !strings.Contains(err.Error(), "404") || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected status and body in error, got %v", err)
+	}
+}
@@ -0,0 +1,71 @@
+package forge
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+type github struct {
+	c    *client
+	repo string // owner/repo
+}
+
+func (g *github) Kind() string { return GitHub }
+
+func (g *github) Issue(ctx context.Context, number int) (*Issue, error) {
+	var issue struct {
+		Number  int    `json:"number"`
+		Title   string `json:"title"`
+		State   string `json:"state"`
+		Body    string `json:"body"`
+		HTMLURL string `json:"html_url"`
+	}
+	if err := g.c.do(ctx, http.MethodGet, fmt.Sprintf("/repos/%s/issues/%d", g.repo, number), nil, &issue); err != nil {
+		return nil, err
+	}
+	var comments []struct {
+		Body string `json:"body"`
+		User struct {
+			Login string `json:"login"`
+		} `json:"user"`
+	}
+	if err := g.c.do(ctx, http.MethodGet, fmt.Sprintf("/repos/%s/issues/%d/comments?per_page=100", g.repo, number), nil, &comments); err != nil {
+		return nil, err
+	}
+	result := &Issue{Number: issue.Number, Title: issue.Title, State: issue.State, Body: issue.Body, URL: issue.HTMLURL}
+	for _, c := range comments {
+		result.Comments = append(result.Comments, Comment{Author: c.User.Login, Body: c.Body})
+	}
+	return result, nil
+}
+
+// Comment posts to the issue comments endpoint, which GitHub also uses
+// for pull request conversations.
+func (g *github) Comment(ctx context.Context, number int, pullRequest bool, body string) error {
+	return g.c.do(ctx, http.MethodPost, fmt.Sprintf("/repos/%s/issues/%d/comments", g.repo, number),
+		map[string]string{"body": body}, nil)
+}
+
+func (g *github) OpenChangeRequest(ctx context.Context, cr ChangeRequest) (string, error) {
+	base := cr.TargetBranch
+	if base == "" {
+		var repo struct {
+			DefaultBranch string `json:"default_branch"`
+		}
+		if err := g.c.do(ctx, http.MethodGet, "/repos/"+g.repo, nil, &repo); err != nil {
+			return "", err
+		}
+		base = repo.DefaultBranch
+	}
+	var pr struct {
+		HTMLURL string `json:"html_url"`
+	}
+	err := g.c.do(ctx, http.MethodPost, "/repos/"+g.repo+"/pulls", map[string]string{
+		"title": cr.Title,
+		"body":  cr.Body,
+		"head":  cr.SourceBranch,
+		"base":  base,
+	}, &pr)
+	return pr.HTMLURL, err
+}
@@ -0,0 +1,48 @@
+package forge
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gavinyap/stormtrooper/internal/config"
+)
+
+func TestGitHub(t *testing.T) {
+	srv, reqs := fakeServer(t, map[string]string{
+		"GET /repos/o/r/issues/7":                       `{"number":7,"title":"Crash","state":"open","body":"Steps...","html_url":"https://github.com/o/r/issues/7"}`,
+		"GET /repos/o/r/issues/7/comments?per_page=100": `[{"body":"Same here","user":{"login":"ana"}}]`,
+		"POST /repos/o/r/issues/7/comments":             `{}`,
+		"GET /repos/o/r":                                `{"default_branch":"trunk"}`,
+		"POST /repos/o/r/pulls":                         `{"html_url":"https://github.com/o/r/pull/8"}`,
+	})
+	t.Setenv("GITHUB_TOKEN", "env-token")
+	f, err := New(config.ForgeConfig{URL: srv.URL}, "git@github.com:o/r.git")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	issue, err := f.Issue(ctx, 7)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	if issue.Title != "Crash" || issue.Body != "Steps..." || len(issue.Comments) != 1 || issue.Comments[0].Author != "ana" {
+		t.Errorf("unexpected issue %+v", issue)
+	}
+	if got := (*reqs)[0].Header.Get("Authorization"); got != "Bearer env-token" {
+		t.Errorf("Authorization = %q", got)
+	}
+
+	if err := f.Comment(ctx, 7, false, "Fixed in #8"); err != nil {
+		t.Fatalf("Comment: %v", err)
+	}
+
+	url, err := f.OpenChangeRequest(ctx, ChangeRequest{Title: "Fix crash", Body: "Fixes #7", SourceBranch: "fix-7"})
+	if err != nil || url != "https://github.com/o/r/pull/8" {
+		t.Fatalf("OpenChangeRequest = %q, %v", url, err)
+	}
+	last := (*reqs)[len(*reqs)-1]
+	if last.Body["head"] != "fix-7" || last.Body["base"] != "trunk" || last.Body["title"] != "Fix crash" {
+		t.Errorf("unexpected PR request %+v", last.Body)
+	}
+}
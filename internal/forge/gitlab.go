@@ -0,0 +1,84 @@
+package forge
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+type gitlab struct {
+	c       *client
+	project string // group/subgroup/repo
+}
+
+func (g *gitlab) Kind() string { return GitLab }
+
+// projectPath returns the API path of the project, addressed by its
+// URL-encoded full path.
+func (g *gitlab) projectPath() string {
+	return "/projects/" + url.PathEscape(g.project)
+}
+
+func (g *gitlab) Issue(ctx context.Context, number int) (*Issue, error) {
+	var issue struct {
+		IID         int    `json:"iid"`
+		Title       string `json:"title"`
+		State       string `json:"state"`
+		Description string `json:"description"`
+		WebURL      string `json:"web_url"`
+	}
+	if err := g.c.do(ctx, http.MethodGet, fmt.Sprintf("%s/issues/%d", g.projectPath(), number), nil, &issue); err != nil {
+		return nil, err
+	}
+	var notes []struct {
+		Body   string `json:"body"`
+		System bool   `json:"system"`
+		Author struct {
+			Username string `json:"username"`
+		} `json:"author"`
+	}
+	if err := g.c.do(ctx, http.MethodGet, fmt.Sprintf("%s/issues/%d/notes?sort=asc&per_page=100", g.projectPath(), number), nil, &notes); err != nil {
+		return nil, err
+	}
+	result := &Issue{Number: issue.IID, Title: issue.Title, State: issue.State, Body: issue.Description, URL: issue.WebURL}
+	for _, n := range notes {
+		if n.System {
+			continue // "changed the label" and similar events
+		}
+		result.Comments = append(result.Comments, Comment{Author: n.Author.Username, Body: n.Body})
+	}
+	return result, nil
+}
+
+func (g *gitlab) Comment(ctx context.Context, number int, pullRequest bool, body string) error {
+	kind := "issues"
+	if pullRequest {
+		kind = "merge_requests"
+	}
+	return g.c.do(ctx, http.MethodPost, fmt.Sprintf("%s/%s/%d/notes", g.projectPath(), kind, number),
+		map[string]string{"body": body}, nil)
+}
+
+func (g *gitlab) OpenChangeRequest(ctx context.Context, cr ChangeRequest) (string, error) {
+	target := cr.TargetBranch
+	if target == "" {
+		var project struct {
+			DefaultBranch string `json:"default_branch"`
+		}
+		if err := g.c.do(ctx, http.MethodGet, g.projectPath(), nil, &project); err != nil {
+			return "", err
+		}
+		target = project.DefaultBranch
+	}
+	var mr struct {
+		WebURL string `json:"web_url"`
+	}
+	err := g.c.do(ctx, http.MethodPost, g.projectPath()+"/merge_requests", map[string]string{
+		"title":         cr.Title,
+		"description":   cr.Body,
+		"source_branch": cr.SourceBranch,
+		"target_branch": target,
+	}, &mr)
+	return mr.WebURL, err
+}
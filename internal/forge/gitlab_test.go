@@ -0,0 +1,46 @@
+package forge
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gavinyap/stormtrooper/internal/config"
+)
+
+func TestGitLab(t *testing.T) {
+	srv, reqs := fakeServer(t, map[string]string{
+		"GET /projects/g%2Fsub%2Fr/issues/3":                             `{"iid":3,"title":"Slow build","state":"opened","description":"CI takes 40m","web_url":"https://gitlab.com/g/sub/r/-/issues/3"}`,
+		"GET /projects/g%2Fsub%2Fr/issues/3/notes?sort=asc&per_page=100": `[{"body":"added ~perf label","system":true,"author":{"username":"bot"}},{"body":"Caching would help","author":{"username":"li"}}]`,
+		"POST /projects/g%2Fsub%2Fr/merge_requests/4/notes":              `{}`,
+		"POST /projects/g%2Fsub%2Fr/merge_requests":                      `{"web_url":"https://gitlab.com/g/sub/r/-/merge_requests/5"}`,
+	})
+	f, err := New(config.ForgeConfig{URL: srv.URL, Token: "glpat"}, "https://gitlab.com/g/sub/r.git")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	issue, err := f.Issue(ctx, 3)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	if issue.Body != "CI takes 40m" || len(issue.Comments) != 1 || issue.Comments[0].Author != "li" {
+		t.Errorf("system notes should be skipped: %+v", issue)
+	}
+	if got := (*reqs)[0].Header.Get("PRIVATE-TOKEN"); got != "glpat" {
+		t.Errorf("PRIVATE-TOKEN = %q", got)
+	}
+
+	if err := f.Comment(ctx, 4, true, "Rebased"); err != nil {
+		t.Fatalf("Comment: %v", err)
+	}
+
+	url, err := f.OpenChangeRequest(ctx, ChangeRequest{Title: "Cache deps", SourceBranch: "cache", TargetBranch: "main"})
+	if err != nil || url != "https://gitlab.com/g/sub/r/-/merge_requests/5" {
+		t.Fatalf("OpenChangeRequest = %q, %v", url, err)
+	}
+	last := (*reqs)[len(*reqs)-1]
+	if last.Body["source_branch"] != "cache" || last.Body["target_branch"] != "main" {
+		t.Errorf("unexpected MR request %+v", last.Body)
+	}
+}
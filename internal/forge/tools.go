@@ -0,0 +1,229 @@
+package forge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/gavinyap/stormtrooper/internal/tool"
+)
+
+// Tools returns the forge tools for f: forge_issue_read,
+// forge_comment, and forge_open_pr.
+func Tools(f Forge) []tool.Tool {
+	return []tool.Tool{&IssueReadTool{Forge: f}, &CommentTool{Forge: f}, &OpenPRTool{Forge: f}}
+}
+
+// changeRequestNoun is what the forge calls a pull request.
+func changeRequestNoun(f Forge) string {
+	if f.Kind() == GitLab {
+		return "merge request"
+	}
+	return "pull request"
+}
+
+// IssueReadTool reads an issue and its comments.
+type IssueReadTool struct {
+	Forge Forge
+}
+
+type issueReadParams struct {
+	Number int `json:"number"`
+}
+
+func (t *IssueReadTool) Name() string { return "forge_issue_read" }
+func (t *IssueReadTool) Description() string {
+	return fmt.Sprintf("Read an issue and its comments from the repository's %s issue tracker", t.Forge.Kind())
+}
+func (t *IssueReadTool) Permission() tool.PermissionLevel { return tool.PermissionAuto }
+func (t *IssueReadTool) Capabilities() []tool.Capability {
+	return []tool.Capability{tool.CapRead, tool.CapNetwork}
+}
+
+func (t *IssueReadTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+	"type": "object",
+	"properties": {
+		"number": {
+			"type": "integer",
+			"description": "Issue number"
+		}
+	},
+	"required": ["number"]
+}`)
+}
+
+func (t *IssueReadTool) Execute(ctx context.Context, params json.RawMessage) (string, error) {
+	var p issueReadParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return fmt.Sprintf("Error: invalid parameters: %v", err), nil
+	}
+	if p.Number <= 0 {
+		return "Error: number is required", nil
+	}
+	issue, err := t.Forge.Issue(ctx, p.Number)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "#%d %s [%s]\n%s\n\n%s\n", issue.Number, issue.Title, issue.State, issue.URL, issue.Body)
+	for _, c := range issue.Comments {
+		fmt.Fprintf(&b, "\n--- %s:\n%s\n", c.Author, c.Body)
+	}
+	return b.String(), nil
+}
+
+// CommentTool comments on an issue or pull request.
+type CommentTool struct {
+	Forge Forge
+}
+
+type commentParams struct {
+	Number      int    `json:"number"`
+	PullRequest bool   `json:"pull_request"`
+	Body        string `json:"body"`
+}
+
+func (t *CommentTool) Name() string { return "forge_comment" }
+func (t *CommentTool) Description() string {
+	return fmt.Sprintf("Post a comment on an issue or %s on %s", changeRequestNoun(t.Forge), t.Forge.Kind())
+}
+func (t *CommentTool) Permission() tool.PermissionLevel { return tool.PermissionPrompt }
+func (t *CommentTool) Capabilities() []tool.Capability {
+	return []tool.Capability{tool.CapWrite, tool.CapNetwork}
+}
+
+func (t *CommentTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+	"type": "object",
+	"properties": {
+		"number": {
+			"type": "integer",
+			"description": "Issue or pull/merge request number"
+		},
+		"pull_request": {
+			"type": "boolean",
+			"description": "Comment on the pull/merge request with this number instead of the issue (default: false)"
+		},
+		"body": {
+			"type": "string",
+			"description": "Comment text (Markdown)"
+		}
+	},
+	"required": ["number", "body"]
+}`)
+}
+
+// Preview returns a description for the permission prompt.
+func (t *CommentTool) Preview(params json.RawMessage) string {
+	var p commentParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return "Post comment (invalid params)"
+	}
+	target := "issue"
+	if p.PullRequest {
+		target = changeRequestNoun(t.Forge)
+	}
+	return fmt.Sprintf("Comment on %s #%d:\n%s", target, p.Number, p.Body)
+}
+
+func (t *CommentTool) Execute(ctx context.Context, params json.RawMessage) (string, error) {
+	var p commentParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return fmt.Sprintf("Error: invalid parameters: %v", err), nil
+	}
+	if p.Number <= 0 {
+		return "Error: number is required", nil
+	}
+	if strings.TrimSpace(p.Body) == "" {
+		return "Error: body is required", nil
+	}
+	if err := t.Forge.Comment(ctx, p.Number, p.PullRequest, p.Body); err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+	return fmt.Sprintf("Comment posted on #%d", p.Number), nil
+}
+
+// OpenPRTool opens a pull request (merge request on GitLab).
+type OpenPRTool struct {
+	Forge Forge
+}
+
+type openPRParams struct {
+	Title        string `json:"title"`
+	Body         string `json:"body"`
+	SourceBranch string `json:"source_branch"`
+	TargetBranch string `json:"target_branch"`
+}
+
+func (t *OpenPRTool) Name() string { return "forge_open_pr" }
+func (t *OpenPRTool) Description() string {
+	return fmt.Sprintf("Open a %s on %s from a pushed branch", changeRequestNoun(t.Forge), t.Forge.Kind())
+}
+func (t *OpenPRTool) Permission() tool.PermissionLevel { return tool.PermissionPrompt }
+func (t *OpenPRTool) Capabilities() []tool.Capability {
+	return []tool.Capability{tool.CapWrite, tool.CapNetwork}
+}
+
+func (t *OpenPRTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+	"type": "object",
+	"properties": {
+		"title": {
+			"type": "string",
+			"description": "Title"
+		},
+		"body": {
+			"type": "string",
+			"description": "Description (Markdown); mention the issue it fixes"
+		},
+		"source_branch": {
+			"type": "string",
+			"description": "Branch with the changes; it must already be pushed"
+		},
+		"target_branch": {
+			"type": "string",
+			"description": "Branch to merge into (optional, defaults to the repository's default branch)"
+		}
+	},
+	"required": ["title", "source_branch"]
+}`)
+}
+
+// Preview returns a description for the permission prompt.
+func (t *OpenPRTool) Preview(params json.RawMessage) string {
+	var p openPRParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return "Open " + changeRequestNoun(t.Forge) + " (invalid params)"
+	}
+	target := p.TargetBranch
+	if target == "" {
+		target = "default branch"
+	}
+	return fmt.Sprintf("Open %s %s -> %s: %s", changeRequestNoun(t.Forge), p.SourceBranch, target, p.Title)
+}
+
+func (t *OpenPRTool) Execute(ctx context.Context, params json.RawMessage) (string, error) {
+	var p openPRParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return fmt.Sprintf("Error: invalid parameters: %v", err), nil
+	}
+	if p.Title == "" {
+		return "Error: title is required", nil
+	}
+	if p.SourceBranch == "" {
+		return "Error: source_branch is required", nil
+	}
+	url, err := t.Forge.OpenChangeRequest(ctx, ChangeRequest{
+		Title:        p.Title,
+		Body:         p.Body,
+		SourceBranch: p.SourceBranch,
+		TargetBranch: p.TargetBranch,
+	})
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+	return fmt.Sprintf("Opened %s: %s", changeRequestNoun(t.Forge), url), nil
+}
@@ -0,0 +1,124 @@
+package forge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gavinyap/stormtrooper/internal/tool"
+)
+
+var (
+	_ tool.Previewer = (*CommentTool)(nil)
+	_ tool.Previewer = (*OpenPRTool)(nil)
+)
+
+// fakeForge records calls and returns canned data.
+type fakeForge struct {
+	kind     string
+	comments []string
+	opened   []ChangeRequest
+	err      error
+}
+
+func (f *fakeForge) Kind() string { return f.kind }
+
+func (f *fakeForge) Issue(ctx context.Context, number int) (*Issue, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &Issue{Number: number, Title: "Crash on start", State: "open", Body: "It crashes.", URL: "https://x/1",
+		Comments: []Comment{{Author: "ana", Body: "Me too"}}}, nil
+}
+
+func (f *fakeForge) Comment(ctx context.Context, number int, pullRequest bool, body string) error {
+	f.comments = append(f.comments, body)
+	return f.err
+}
+
+func (f *fakeForge) OpenChangeRequest(ctx context.Context, cr ChangeRequest) (string, error) {
+	f.opened = append(f.opened, cr)
+	return "https://x/mr/2", f.err
+}
+
+func TestTools(t *testing.T) {
+	f := &fakeForge{kind: GitLab}
+	tools := Tools(f)
+	names := []string{}
+	for _, tl := range tools {
+		names = append(names, tl.Name())
+		var schema map[string]any
+		if err := json.Unmarshal(tl.Schema(), &schema); err != nil {
+			t.Errorf("%s: invalid schema: %v", tl.Name(), err)
+		}
+	}
+	if strings.Join(names, ",") != "forge_issue_read,forge_comment,forge_open_pr" {
+		t.Errorf("tools = %v", names)
+	}
+	if tools[0].Permission() != tool.PermissionAuto || tools[1].Permission() != tool.PermissionPrompt || tools[2].Permission() != tool.PermissionPrompt {
+		t.Error("reading should be automatic and writing should prompt")
+	}
+	if !strings.Contains(tools[2].Description(), "merge request") {
+		t.Errorf("GitLab tools should say merge request: %q", tools[2].Description())
+	}
+}
+
+func TestIssueReadTool(t *testing.T) {
+	tl := &IssueReadTool{Forge: &fakeForge{kind: GitHub}}
+	out, _ := tl.Execute(context.Background(), json.RawMessage(`{"number":1}`))
+	for _, want := range []string{"#1 Crash on start [open]", "It crashes.", "--- ana:\nMe too"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+
+	out, _ = tl.Execute(context.Background(), json.RawMessage(`{}`))
+	if out != "Error: number is required" {
+		t.Errorf("got %q", out)
+	}
+
+	tl.Forge = &fakeForge{kind: GitHub, err: errors.New("401 Unauthorized")}
+	out, _ = tl.Execute(context.Background(), json.RawMessage(`{"number":1}`))
+	if !strings.HasPrefix(out, "Error: 401") {
+		t.Errorf("got %q", out)
+	}
+}
+
+func TestCommentTool(t *testing.T) {
+	f := &fakeForge{kind: Bitbucket}
+	tl := &CommentTool{Forge: f}
+	params := json.RawMessage(`{"number":4,"pull_request":true,"body":"LGTM"}`)
+
+	if got := tl.Preview(params); got != "Comment on pull request #4:\nLGTM" {
+		t.Errorf("Preview = %q", got)
+	}
+	out, _ := tl.Execute(context.Background(), params)
+	if out != "Comment posted on #4" || len(f.comments) != 1 {
+		t.Errorf("Execute = %q, comments %v", out, f.comments)
+	}
+	if out, _ := tl.Execute(context.Background(), json.RawMessage(`{"number":4,"body":" "}`)); out != "Error: body is required" {
+		t.Errorf("got %q", out)
+	}
+}
+
+func TestOpenPRTool(t *testing.T) {
+	f := &fakeForge{kind: GitLab}
+	tl := &OpenPRTool{Forge: f}
+	params := json.RawMessage(`{"title":"Fix crash","body":"Closes #1","source_branch":"fix-1"}`)
+
+	if got := tl.Preview(params); got != "Open merge request fix-1 -> default branch: Fix crash" {
+		t.Errorf("Preview = %q", got)
+	}
+	out, _ := tl.Execute(context.Background(), params)
+	if out != "Opened merge request: https://x/mr/2" {
+		t.Errorf("Execute = %q", out)
+	}
+	if len(f.opened) != 1 || f.opened[0].Body != "Closes #1" {
+		t.Errorf("opened %+v", f.opened)
+	}
+	if out, _ := tl.Execute(context.Background(), json.RawMessage(`{"title":"x"}`)); out != "Error: source_branch is required" {
+		t.Errorf("got %q", out)
+	}
+}
@@ -0,0 +1,66 @@
+// Package hunk splits a change to a file into the hunks of its unified
+// diff, so that each can be accepted or rejected on its own.
+package hunk
+
+import (
+	"strings"
+
+	udiff "github.com/aymanbagabas/go-udiff"
+)
+
+// Hunk is one hunk of a change.
+type Hunk struct {
+	// Diff is the hunk in unified diff form, from its "@@" line on.
+	Diff string
+	h    *udiff.Hunk
+}
+
+// Split returns the hunks that turn before into after, as the unified
+// diffs of the review screen show them.
+func Split(before, after string) []Hunk {
+	u, err := udiff.ToUnifiedDiff("a", "b", before, udiff.Strings(before, after), udiff.DefaultContextLines)
+	if err != nil {
+		return nil
+	}
+	hunks := make([]Hunk, len(u.Hunks))
+	for i, h := range u.Hunks {
+		one := udiff.UnifiedDiff{From: u.From, To: u.To, Hunks: []*udiff.Hunk{h}}
+		// Drop the "---" and "+++" lines.
+		_, diff, _ := strings.Cut(one.String(), "\n")
+		_, diff, _ = strings.Cut(diff, "\n")
+		hunks[i] = Hunk{Diff: diff, h: h}
+	}
+	return hunks
+}
+
+// Apply returns before with only the hunks for which accepted is true
+// applied. hunks must come from Split(before, ...).
+func Apply(before string, hunks []Hunk, accepted []bool) string {
+	lines := strings.SplitAfter(before, "\n")
+	var b strings.Builder
+	next := 0 // the first line of before not yet copied
+	for i, h := range hunks {
+		ok := i < len(accepted) && accepted[i]
+		for ; next < h.h.FromLine-1 && next < len(lines); next++ {
+			b.WriteString(lines[next])
+		}
+		for _, l := range h.h.Lines {
+			switch {
+			case l.Kind == udiff.Equal:
+				b.WriteString(l.Content)
+				next++
+			case l.Kind == udiff.Delete:
+				if !ok {
+					b.WriteString(l.Content)
+				}
+				next++
+			case ok:
+				b.WriteString(l.Content)
+			}
+		}
+	}
+	for ; next < len(lines); next++ {
+		b.WriteString(lines[next])
+	}
+	return b.String()
+}
@@ -0,0 +1,92 @@
+package hunk
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// numbered returns lines "1\n" through "n\n".
+func numbered(n int) []string {
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("%d\n", i+1)
+	}
+	return lines
+}
+
+func TestSplitAndApply(t *testing.T) {
+	lines := numbered(30)
+	before := strings.Join(lines, "")
+	lines[1] = "two\n"
+	lines[2] = "three\n"
+	lines[25] = "twenty-six\n"
+	after := strings.Join(lines, "")
+
+	hunks := Split(before, after)
+	if len(hunks) != 2 {
+		t.Fatalf("expected 2 hunks, got %d: %+v", len(hunks), hunks)
+	}
+	if !strings.HasPrefix(hunks[0].Diff, "@@ -1,6 +1,6 @@\n") || !strings.Contains(hunks[0].Diff, "-2\n+two\n-3\n+three\n") {
+		t.Errorf("unexpected first hunk:\n%s", hunks[0].Diff)
+	}
+	if !strings.Contains(hunks[1].Diff, "-26\n+twenty-six\n") {
+		t.Errorf("unexpected second hunk:\n%s", hunks[1].Diff)
+	}
+
+	tests := []struct {
+		accepted []bool
+		want     string
+	}{
+		{[]bool{true, true}, after},
+		{[]bool{false, false}, before},
+		{[]bool{false, true}, strings.Replace(before, "\n26\n", "\ntwenty-six\n", 1)},
+		{[]bool{true}, strings.Replace(before, "\n2\n3\n", "\ntwo\nthree\n", 1)},
+	}
+	for _, tt := range tests {
+		if got := Apply(before, hunks, tt.accepted); got != tt.want {
+			t.Errorf("Apply(%v) =\n%s\nwant\n%s", tt.accepted, got, tt.want)
+		}
+	}
+}
+
+func TestSplit_NearbyChangesShareAHunk(t *testing.T) {
+	lines := numbered(20)
+	before := strings.Join(lines, "")
+	lines[4] = "five\n"
+	lines[9] = "ten\n"
+	if hunks := Split(before, strings.Join(lines, "")); len(hunks) != 1 {
+		t.Errorf("expected 1 hunk, got %d", len(hunks))
+	}
+}
+
+func TestApply_NoNewlineAtEnd(t *testing.T) {
+	before := strings.Join(numbered(20), "") + "last"
+	after := strings.Replace(before, "\n2\n", "\nTWO\n", 1) + " line"
+	hunks := Split(before, after)
+	if len(hunks) != 2 {
+		t.Fatalf("expected 2 hunks, got %d", len(hunks))
+	}
+	if got := Apply(before, hunks, []bool{false, true}); got != before+" line" {
+		t.Errorf("Apply = %q", got)
+	}
+	if got := Apply(before, hunks, []bool{true, true}); got != after {
+		t.Errorf("Apply = %q", got)
+	}
+}
+
+func TestSplit_NoChange(t *testing.T) {
+	if hunks := Split("same\n", "same\n"); len(hunks) != 0 {
+		t.Errorf("expected no hunks, got %d", len(hunks))
+	}
+}
+
+func TestSplit_NewFile(t *testing.T) {
+	hunks := Split("", "a\nb\n")
+	if len(hunks) != 1 {
+		t.Fatalf("expected 1 hunk, got %d", len(hunks))
+	}
+	if got := Apply("", hunks, []bool{true}); got != "a\nb\n" {
+		t.Errorf("Apply = %q", got)
+	}
+}
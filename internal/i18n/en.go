@@ -0,0 +1,133 @@
+package i18n
+
+// english is the source catalog. Every message ID must be defined here;
+// other catalogs may translate any subset.
+var english = Catalog{
+	// REPL
+	"repl.banner":      "Stormtrooper v%s — AI coding assistant",
+	"repl.exit_hint":   "Type /help for commands, /exit or Ctrl+C to quit.",
+	"repl.input_error": "Input error: %v",
+	"repl.goodbye":     "Goodbye!",
+
+	// Shared
+	"error":     "Error: %v",
+	"warning":   "Warning: %s",
+	"cancelled": "Cancelled",
+
+	// Permission prompts
+	"permission.prompt":      "[permission] %s\n%s\n[y/n]: ",
+	"permission.tui_title":   "[PERMISSION]",
+	"permission.tui_keys":    "[y] allow  [n] deny",
+	"permission.allowed":     "-> Allowed",
+	"permission.denied":      "-> Denied",
+	"permission.hunk":        "%s, hunk %d of %d\n%s",
+	"permission.hunk_prompt": "[permission] %s %s, hunk %d of %d\n%s\n[y] apply  [n] skip  [a] apply the rest  [d] skip the rest: ",
+
+	// Permission prompts that can remember the answer
+	"permission.decide_prompt":         "[permission] %s\n%s\n[y] allow once  [a] always allow %s  [n] deny: ",
+	"permission.decide_prompt_command": "[permission] %s\n%s\n[y] allow once  [a] always allow %s  [c] always allow this command  [n] deny: ",
+	"permission.tui_keys_always":       "[y] allow once  [a] always allow %s  [n] deny",
+	"permission.tui_keys_command":      "[y] allow once  [a] always allow %s  [c] always allow this command  [n] deny",
+	"permission.allowed_tool":          "-> Allowed; %s will not ask again in this project",
+	"permission.allowed_command":       "-> Allowed; this command will not ask again in this project",
+
+	// Chat
+	"chat.you":           "You:",
+	"chat.assistant":     "Assistant:",
+	"chat.raw":           "(markdown source)",
+	"chat.style_failed":  "Markdown style not applied: %v",
+	"chat.wide":          "(wider than the chat: select with [ ] and scroll with left/right)",
+	"chat.wide_scroll":   "(columns %d-%d of %d: scroll with left/right)",
+	"chat.no_link":       "No reference [%d] in this message",
+	"chat.link_failed":   "Could not open %s: %v",
+	"chat.no_path":       "No file [%d] in this message",
+	"chat.editor_failed": "Editor exited with an error: %v",
+	"chat.more_paths":    "… %d more",
+
+	"chat.reply_message":   "Replying to this answer: your next message will quote it (> again to quote one of its code blocks)",
+	"chat.reply_only":      "Replying to this answer: your next message will quote it",
+	"chat.reply_block":     "Replying to code block %d of %d of this answer",
+	"chat.reply_cancelled": "Reply cancelled",
+	"chat.no_reply":        "No answer to reply to",
+
+	"chat.auto_approved": "✔ %s auto-approved by rule `%s` (w: why)",
+	"chat.auto_denied":   "✘ %s auto-denied by policy: %s (w: why)",
+	"chat.rule_tool":     "Allowed without asking by this rule in %s:\n\ntools:\n  - %s\n\nDelete it to be asked again.",
+	"chat.rule_command":  "Allowed without asking by this rule in %s:\n\ncommands:\n  %s:\n    - %q\n\nDelete it to be asked again.",
+	"chat.rule_policy":   "Denied by the organization policy: %s\n\nOnly an administrator can change the policy.",
+	"chat.no_rule":       "No automatic permission decision to explain",
+
+	// Input
+	"input.placeholder": "Type a message... (Enter to send, Ctrl+J for newline)",
+	"input.thinking":    "Thinking...",
+
+	"input.completions": "Commands: %s",
+
+	// Sidebar
+	"sidebar.tool_activity":  "Tool Activity",
+	"sidebar.no_activity":    "No activity",
+	"sidebar.agent_status":   "Agent Status",
+	"sidebar.idle":           "Idle",
+	"sidebar.project_info":   "Project Info",
+	"sidebar.dir":            "Dir: %s",
+	"sidebar.memory":         "Memory: %s",
+	"sidebar.memory_loaded":  "loaded",
+	"sidebar.memory_missing": "not loaded",
+	"sidebar.tools":          "Tools: %d",
+	"sidebar.model":          "Model: %s",
+	"sidebar.restricted":     "Trust: restricted",
+	"sidebar.pinned":         "Pinned Files",
+	"sidebar.usage":          "Usage",
+	"sidebar.tokens":         "Tokens: %s in, %s out",
+	"sidebar.cost":           "Cost: %s",
+
+	// Accessible mode
+	"accessible.prompt":            "Your message: ",
+	"accessible.continuation":      "Continue message: ",
+	"accessible.response_start":    "Assistant response:",
+	"accessible.response_end":      "End of response.",
+	"accessible.permission_prompt": "Permission needed. The tool %s wants to do the following:\n%s\nAllow this? Type yes or no, then press Enter: ",
+	"accessible.hunk_prompt":       "Permission needed. The tool %s wants to change %s. Change %d of %d:\n%s\nApply this change? Type yes, no, all to apply it and the rest, or done to skip it and the rest, then press Enter: ",
+	"accessible.tool_start":        "Running tool %s.",
+	"accessible.tool_done":         "Tool %s finished.",
+	"accessible.tool_error":        "Tool %s failed.",
+	"accessible.tool_error_detail": "Tool %s failed with %s.",
+	"accessible.tool_denied":       "Tool %s was not allowed.",
+	"accessible.tool_unknown":      "The model asked for an unknown tool: %s.",
+	"accessible.subagent_start":    "Starting a sub-agent: %s",
+	"accessible.subagent_done":     "Sub-agent finished.",
+
+	"accessible.decide_prompt":         "Permission needed. The tool %s wants to do the following:\n%s\nAllow this? Type yes, always to allow %s from now on, or no, then press Enter: ",
+	"accessible.decide_prompt_command": "Permission needed. The tool %s wants to do the following:\n%s\nAllow this? Type yes, always to allow %s from now on, command to always allow this exact command, or no, then press Enter: ",
+
+	// Config reload
+	"config.reloaded":      "Config reloaded",
+	"config.reload_failed": "Config reload failed: %v",
+
+	// Review screen
+	"review.title":     "Staged changes (%d file(s))",
+	"review.help":      "[ ] file · up/down scroll · a apply all · d discard all · Esc close",
+	"review.created":   "new file",
+	"review.deleted":   "deleted",
+	"review.pending":   "%d file(s) staged. Press Ctrl+R to review them, or use /apply and /discard.",
+	"review.empty":     "No staged changes.",
+	"review.applied":   "Applied %d file(s): %s",
+	"review.discarded": "Discarded %d file(s): %s",
+
+	// Argument forms
+	"form.title":       "Run %s",
+	"form.help":        "Tab/Shift+Tab field · Enter run · Esc cancel · * required",
+	"form.no_fields":   "No arguments.",
+	"form.running":     "Running %s…",
+	"form.unsupported": "Forms need the TUI; give the arguments for %s as JSON after the command instead.",
+
+	// Pickers
+	"picker.help":    "↑/↓ choose · Enter select · Esc cancel",
+	"picker.prompt":  "Enter a number, or press Enter to cancel.",
+	"picker.invalid": "Not one of the choices: %s",
+
+	"picker.filter":        "Filter: %s",
+	"picker.filter_help":   "Type to filter · ↑/↓ choose · Enter select · Esc cancel",
+	"picker.no_matches":    "No matches",
+	"picker.filter_prompt": "Enter a number, text to filter the list, or press Enter to cancel.",
+}
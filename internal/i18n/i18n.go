@@ -0,0 +1,118 @@
+// Package i18n provides a message catalog for user-facing TUI and REPL
+// strings. Catalogs are keyed by message ID; missing translations fall
+// back to English, and missing IDs fall back to the ID itself so a typo
+// is visible rather than blank.
+package i18n
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultLanguage is used when no language is configured or detected.
+const DefaultLanguage = "en"
+
+// Catalog maps message IDs to format strings for one language.
+type Catalog map[string]string
+
+var (
+	mu       sync.RWMutex
+	catalogs = map[string]Catalog{DefaultLanguage: english}
+	current  = DefaultLanguage
+)
+
+// Register adds or replaces the catalog for lang (e.g. "de").
+func Register(lang string, c Catalog) {
+	mu.Lock()
+	defer mu.Unlock()
+	catalogs[normalize(lang)] = c
+}
+
+// SetLanguage selects the active catalog. Locale strings such as
+// "pt_BR.UTF-8" are accepted; an exact match ("pt_br") is preferred over
+// the base language ("pt"). Unknown languages fall back to English.
+// It returns the language actually selected.
+func SetLanguage(lang string) string {
+	mu.Lock()
+	defer mu.Unlock()
+
+	lang = normalize(lang)
+	if _, ok := catalogs[lang]; ok {
+		current = lang
+		return current
+	}
+	if base, _, found := strings.Cut(lang, "_"); found {
+		if _, ok := catalogs[base]; ok {
+			current = base
+			return current
+		}
+	}
+	current = DefaultLanguage
+	return current
+}
+
+// Language returns the active language.
+func Language() string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+// Languages returns all registered languages, sorted.
+func Languages() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	langs := make([]string, 0, len(catalogs))
+	for l := range catalogs {
+		langs = append(langs, l)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// Detect picks the language to use: the configured value if set, else the
+// first of LC_ALL, LC_MESSAGES, LANG that names a real locale.
+func Detect(configured string) string {
+	if configured != "" {
+		return configured
+	}
+	for _, env := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		v := os.Getenv(env)
+		if v != "" && v != "C" && v != "POSIX" {
+			return v
+		}
+	}
+	return DefaultLanguage
+}
+
+// T returns the message for id in the active language, formatted with
+// args when any are given.
+func T(id string, args ...any) string {
+	mu.RLock()
+	msg, ok := catalogs[current][id]
+	if !ok {
+		msg, ok = english[id]
+	}
+	mu.RUnlock()
+
+	if !ok {
+		msg = id
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+// normalize lowercases a locale and strips its encoding and modifier,
+// so "en_US.UTF-8@euro" becomes "en_us".
+func normalize(lang string) string {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if i := strings.IndexAny(lang, ".@"); i >= 0 {
+		lang = lang[:i]
+	}
+	return strings.ReplaceAll(lang, "-", "_")
+}
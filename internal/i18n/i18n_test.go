@@ -0,0 +1,90 @@
+package i18n
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestT_English(t *testing.T) {
+	SetLanguage("en")
+	if got := T("repl.goodbye"); got != "Goodbye!" {
+		t.Errorf("expected 'Goodbye!', got %q", got)
+	}
+	if got := T("sidebar.tools", 8); got != "Tools: 8" {
+		t.Errorf("expected 'Tools: 8', got %q", got)
+	}
+}
+
+func TestT_UnknownIDFallsBackToID(t *testing.T) {
+	if got := T("no.such.message"); got != "no.such.message" {
+		t.Errorf("expected ID fallback, got %q", got)
+	}
+}
+
+func TestSetLanguage_Locales(t *testing.T) {
+	Register("de", Catalog{"repl.goodbye": "Tschüss!"})
+	Register("pt_br", Catalog{"repl.goodbye": "Tchau!"})
+	defer func() {
+		mu.Lock()
+		delete(catalogs, "de")
+		delete(catalogs, "pt_br")
+		mu.Unlock()
+		SetLanguage(DefaultLanguage)
+	}()
+
+	tests := []struct {
+		locale string
+		want   string
+	}{
+		{"de", "de"},
+		{"de_DE.UTF-8", "de"},
+		{"pt-BR", "pt_br"},
+		{"pt_BR.UTF-8", "pt_br"},
+		{"fr_FR.UTF-8", "en"},
+		{"", "en"},
+	}
+	for _, tt := range tests {
+		if got := SetLanguage(tt.locale); got != tt.want {
+			t.Errorf("SetLanguage(%q) = %q, want %q", tt.locale, got, tt.want)
+		}
+	}
+
+	SetLanguage("de_AT")
+	if got := T("repl.goodbye"); got != "Tschüss!" {
+		t.Errorf("expected German goodbye, got %q", got)
+	}
+	// Untranslated IDs fall back to English.
+	if got := T("sidebar.idle"); got != "Idle" {
+		t.Errorf("expected English fallback 'Idle', got %q", got)
+	}
+}
+
+func TestDetect(t *testing.T) {
+	t.Setenv("LC_ALL", "")
+	t.Setenv("LC_MESSAGES", "")
+	t.Setenv("LANG", "de_DE.UTF-8")
+
+	if got := Detect("fr"); got != "fr" {
+		t.Errorf("configured language should win, got %q", got)
+	}
+	if got := Detect(""); got != "de_DE.UTF-8" {
+		t.Errorf("expected LANG, got %q", got)
+	}
+
+	t.Setenv("LC_ALL", "C")
+	if got := Detect(""); got != "de_DE.UTF-8" {
+		t.Errorf("C locale should be skipped, got %q", got)
+	}
+
+	t.Setenv("LANG", "")
+	t.Setenv("LC_ALL", "")
+	if got := Detect(""); got != DefaultLanguage {
+		t.Errorf("expected default, got %q", got)
+	}
+}
+
+func TestLanguages(t *testing.T) {
+	if got := strings.Join(Languages(), ","); !strings.Contains(got, "en") {
+		t.Errorf("expected English to be registered, got %q", got)
+	}
+}
@@ -0,0 +1,199 @@
+// Package inject lets other programs hand a running interactive session
+// a message or a tool result, such as the code selected in an editor,
+// over a unix socket in the project's .stormtrooper/run/ directory. Each
+// session listens on its own socket, named after its process ID, and
+// removes it on exit.
+package inject
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Kinds of request.
+const (
+	KindMessage    = "message"     // Text becomes a user message
+	KindToolResult = "tool_result" // Name, Args, and Result become a tool call
+)
+
+// Request is what a program sends, one JSON object per line.
+type Request struct {
+	Type   string          `json:"type"`
+	Source string          `json:"source,omitempty"` // who sent it, e.g. "vscode"
+	Text   string          `json:"text,omitempty"`
+	Name   string          `json:"name,omitempty"`
+	Args   json.RawMessage `json:"args,omitempty"`
+	Result string          `json:"result,omitempty"`
+}
+
+// Response answers each request.
+type Response struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// maxRequest caps a request line, which may carry a whole file.
+const maxRequest = 4 << 20
+
+// Dir returns the directory of sockets for the given project directory.
+func Dir(projectDir string) string {
+	return filepath.Join(projectDir, ".stormtrooper", "run")
+}
+
+// validate checks req and fills in defaults.
+func (req *Request) validate() error {
+	if req.Source == "" {
+		req.Source = "another program"
+	}
+	switch req.Type {
+	case KindMessage:
+		if req.Text == "" {
+			return errors.New("a message needs text")
+		}
+	case KindToolResult:
+		if req.Name == "" {
+			return errors.New("a tool result needs a name")
+		}
+		if len(req.Args) > 0 {
+			var obj map[string]any
+			if err := json.Unmarshal(req.Args, &obj); err != nil {
+				return fmt.Errorf("args must be a JSON object: %w", err)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown request type %q", req.Type)
+	}
+	return nil
+}
+
+// Server accepts requests on a session's socket.
+type Server struct {
+	ln      net.Listener
+	path    string
+	deliver func(Request)
+}
+
+// Listen serves a socket for this process in Dir(projectDir), calling
+// deliver with each valid request.
+func Listen(projectDir string, deliver func(Request)) (*Server, error) {
+	dir := Dir(projectDir)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, strconv.Itoa(os.Getpid())+".sock")
+	os.Remove(path) // left behind by a crashed session with the same PID
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{ln: ln, path: path, deliver: deliver}
+	go s.accept()
+	return s, nil
+}
+
+// Path returns the socket's path.
+func (s *Server) Path() string {
+	return s.path
+}
+
+// Close stops listening and removes the socket.
+func (s *Server) Close() error {
+	err := s.ln.Close()
+	os.Remove(s.path)
+	return err
+}
+
+func (s *Server) accept() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.serve(conn)
+	}
+}
+
+// serve handles one connection's requests until it closes.
+func (s *Server) serve(conn net.Conn) {
+	defer conn.Close()
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 64*1024), maxRequest)
+	enc := json.NewEncoder(conn)
+	for scanner.Scan() {
+		var req Request
+		err := json.Unmarshal(scanner.Bytes(), &req)
+		if err == nil {
+			err = req.validate()
+		}
+		if err != nil {
+			enc.Encode(Response{Error: err.Error()})
+			continue
+		}
+		s.deliver(req)
+		enc.Encode(Response{OK: true})
+	}
+}
+
+// Sockets returns the sockets of the sessions running in projectDir,
+// most recently started first. Sockets nothing is listening on any more
+// are removed.
+func Sockets(projectDir string) ([]string, error) {
+	dir := Dir(projectDir)
+	paths, err := filepath.Glob(filepath.Join(dir, "*.sock"))
+	if err != nil {
+		return nil, err
+	}
+	type socket struct {
+		path    string
+		started time.Time
+	}
+	var live []socket
+	for _, path := range paths {
+		conn, err := net.DialTimeout("unix", path, time.Second)
+		if err != nil {
+			os.Remove(path)
+			continue
+		}
+		conn.Close()
+		fi, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		live = append(live, socket{path, fi.ModTime()})
+	}
+	sort.Slice(live, func(i, j int) bool { return live[i].started.After(live[j].started) })
+	out := make([]string, len(live))
+	for i, s := range live {
+		out[i] = s.path
+	}
+	return out, nil
+}
+
+// Send delivers req to the session listening on the socket at path.
+func Send(path string, req Request) error {
+	conn, err := net.DialTimeout("unix", path, 5*time.Second)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(30 * time.Second))
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return err
+	}
+	var resp Response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return fmt.Errorf("no answer from the session: %w", err)
+	}
+	if !resp.OK {
+		return errors.New(resp.Error)
+	}
+	return nil
+}
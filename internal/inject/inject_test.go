@@ -0,0 +1,77 @@
+package inject
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestServer(t *testing.T) {
+	dir := t.TempDir()
+	var mu sync.Mutex
+	var got []Request
+	s, err := Listen(dir, func(req Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, req)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Send(s.Path(), Request{Type: KindMessage, Source: "vim", Text: "look at this"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Send(s.Path(), Request{Type: KindToolResult, Name: "editor_selection", Args: json.RawMessage(`{"file":"a.go"}`), Result: "x := 1"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, req := range []Request{
+		{Type: "shout"},
+		{Type: KindMessage},
+		{Type: KindToolResult},
+		{Type: KindToolResult, Name: "x", Args: json.RawMessage(`[1]`)},
+	} {
+		if err := Send(s.Path(), req); err == nil {
+			t.Errorf("expected %+v to be rejected", req)
+		}
+	}
+
+	mu.Lock()
+	if len(got) != 2 || got[0].Text != "look at this" || got[0].Source != "vim" || got[1].Result != "x := 1" || got[1].Source != "another program" {
+		t.Errorf("delivered %+v", got)
+	}
+	mu.Unlock()
+
+	socks, err := Sockets(dir)
+	if err != nil || len(socks) != 1 || socks[0] != s.Path() {
+		t.Errorf("Sockets = %v, %v", socks, err)
+	}
+
+	s.Close()
+	if _, err := os.Stat(s.Path()); !os.IsNotExist(err) {
+		t.Error("Close should remove the socket")
+	}
+	if err := Send(s.Path(), Request{Type: KindMessage, Text: "hi"}); err == nil {
+		t.Error("expected an error sending to a closed session")
+	}
+}
+
+func TestSockets_RemovesStale(t *testing.T) {
+	dir := t.TempDir()
+	stale := filepath.Join(Dir(dir), "999999.sock")
+	os.MkdirAll(Dir(dir), 0700)
+	os.WriteFile(stale, nil, 0600)
+
+	socks, err := Sockets(dir)
+	if err != nil || len(socks) != 0 {
+		t.Errorf("Sockets = %v, %v", socks, err)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Error("expected the stale socket to be removed")
+	}
+	if socks, err := Sockets(filepath.Join(dir, "missing")); err != nil || len(socks) != 0 {
+		t.Errorf("missing dir: %v, %v", socks, err)
+	}
+}
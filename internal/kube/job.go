@@ -0,0 +1,221 @@
+// Package kube runs headless agent tasks as Kubernetes Jobs, one per
+// repository, and collects their output, so one CLI invocation can drive
+// a fleet of maintenance agents.
+//
+// It drives kubectl, so the current kubeconfig context and its
+// credentials are used as they are in the shell.
+package kube
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Labels identifying stormtrooper jobs.
+const (
+	labelApp   = "app.kubernetes.io/name"
+	labelBatch = "stormtrooper.dev/batch"
+	annoRepo   = "stormtrooper.dev/repo"
+	annoRef    = "stormtrooper.dev/ref"
+)
+
+// changesMarker separates the agent's output from the diff of the
+// changes it made in the job log.
+const changesMarker = "---stormtrooper-changes---"
+
+// workspace is where the job clones the repository.
+const workspace = "/workspace"
+
+// script clones the repository, runs the agent headlessly with every tool
+// approved (the pod is disposable), and prints the resulting diff after
+// the marker. The workspace is recorded as trusted so project
+// instructions and file tools are available.
+var script = `set -e
+git clone --quiet "$REPO" ` + workspace + `
+cd ` + workspace + `
+if [ -n "$REF" ]; then git checkout --quiet "$REF"; fi
+mkdir -p "$HOME/.stormtrooper"
+if [ -n "$STORMTROOPER_CONFIG" ]; then printf '%s\n' "$STORMTROOPER_CONFIG" > "$HOME/.stormtrooper/config.yaml"; fi
+printf '{"workspaces":{"` + workspace + `":{"trusted":true,"decided_at":"1970-01-01T00:00:00Z"}}}' > "$HOME/.stormtrooper/trusted.json"
+status=0
+stormtrooper --yes ${MODEL:+--model "$MODEL"} -p "$PROMPT" || status=$?
+echo "` + changesMarker + `"
+git add -A && git diff --cached
+exit $status
+`
+
+// Target is a repository to run the task against.
+type Target struct {
+	Repo string // clone URL
+	Ref  string // branch, tag, or commit; empty for the default branch
+}
+
+// ParseTarget parses "URL" or "URL@ref". An "@" inside the URL (as in
+// git@host:org/repo or https://user@host/repo) is not mistaken for a ref
+// separator.
+func ParseTarget(s string) Target {
+	s = strings.TrimSpace(s)
+	i := strings.LastIndex(s, "@")
+	if i <= 0 || strings.Contains(s[i:], ":") {
+		return Target{Repo: s}
+	}
+	// The part before the separator must already be a repository path.
+	repo := s[:i]
+	if _, rest, ok := strings.Cut(repo, "://"); ok {
+		repo = rest
+	}
+	if !strings.Contains(repo, "/") {
+		return Target{Repo: s}
+	}
+	return Target{Repo: s[:i], Ref: s[i+1:]}
+}
+
+// Spec is a headless run to package as Jobs.
+type Spec struct {
+	Batch     string // groups the jobs of one invocation
+	Namespace string
+	Image     string // must contain stormtrooper and git
+	Prompt    string
+	Model     string // optional model override
+	Config    string // optional config.yaml content
+	Secret    string // Secret whose keys become environment variables (e.g. OPENROUTER_API_KEY)
+	Deadline  int64  // activeDeadlineSeconds; 0 for none
+	Targets   []Target
+}
+
+// JobName returns the name of the i-th job in the batch.
+func (s *Spec) JobName(i int) string {
+	return fmt.Sprintf("stormtrooper-%s-%d", s.Batch, i)
+}
+
+// Validate reports missing required fields.
+func (s *Spec) Validate() error {
+	switch {
+	case s.Batch == "":
+		return fmt.Errorf("batch is required")
+	case s.Image == "":
+		return fmt.Errorf("image is required")
+	case strings.TrimSpace(s.Prompt) == "":
+		return fmt.Errorf("prompt is required")
+	case len(s.Targets) == 0:
+		return fmt.Errorf("at least one repository is required")
+	}
+	for _, t := range s.Targets {
+		if t.Repo == "" {
+			return fmt.Errorf("repository URL is empty")
+		}
+	}
+	return nil
+}
+
+// Manifests renders one Job per target as a multi-document YAML stream
+// for kubectl apply.
+func (s *Spec) Manifests() ([]byte, error) {
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	var docs []string
+	for i, t := range s.Targets {
+		data, err := yaml.Marshal(s.job(i, t))
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, string(data))
+	}
+	return []byte(strings.Join(docs, "---\n")), nil
+}
+
+func (s *Spec) job(i int, t Target) job {
+	env := []envVar{
+		{Name: "REPO", Value: t.Repo},
+		{Name: "REF", Value: t.Ref},
+		{Name: "PROMPT", Value: s.Prompt},
+	}
+	if s.Model != "" {
+		env = append(env, envVar{Name: "MODEL", Value: s.Model})
+	}
+	if s.Config != "" {
+		env = append(env, envVar{Name: "STORMTROOPER_CONFIG", Value: s.Config})
+	}
+	c := container{
+		Name:    "agent",
+		Image:   s.Image,
+		Command: []string{"sh", "-c", script},
+		Env:     env,
+	}
+	if s.Secret != "" {
+		c.EnvFrom = []envFrom{{SecretRef: secretRef{Name: s.Secret}}}
+	}
+
+	backoff := int32(0) // agent runs are not idempotent; never retry
+	ttl := int32(7 * 24 * 60 * 60)
+	j := job{
+		APIVersion: "batch/v1",
+		Kind:       "Job",
+		Metadata: metadata{
+			Name:        s.JobName(i),
+			Namespace:   s.Namespace,
+			Labels:      map[string]string{labelApp: "stormtrooper", labelBatch: s.Batch},
+			Annotations: map[string]string{annoRepo: t.Repo, annoRef: t.Ref},
+		},
+	}
+	j.Spec.BackoffLimit = &backoff
+	j.Spec.TTLSecondsAfterFinished = &ttl
+	if s.Deadline > 0 {
+		j.Spec.ActiveDeadlineSeconds = &s.Deadline
+	}
+	j.Spec.Template.Metadata.Labels = map[string]string{labelApp: "stormtrooper", labelBatch: s.Batch}
+	j.Spec.Template.Spec.RestartPolicy = "Never"
+	j.Spec.Template.Spec.Containers = []container{c}
+	return j
+}
+
+// The types below are the subset of the batch/v1 Job schema used here.
+
+type job struct {
+	APIVersion string   `yaml:"apiVersion"`
+	Kind       string   `yaml:"kind"`
+	Metadata   metadata `yaml:"metadata"`
+	Spec       struct {
+		BackoffLimit            *int32 `yaml:"backoffLimit,omitempty"`
+		ActiveDeadlineSeconds   *int64 `yaml:"activeDeadlineSeconds,omitempty"`
+		TTLSecondsAfterFinished *int32 `yaml:"ttlSecondsAfterFinished,omitempty"`
+		Template                struct {
+			Metadata metadata `yaml:"metadata"`
+			Spec     struct {
+				RestartPolicy string      `yaml:"restartPolicy"`
+				Containers    []container `yaml:"containers"`
+			} `yaml:"spec"`
+		} `yaml:"template"`
+	} `yaml:"spec"`
+}
+
+type metadata struct {
+	Name        string            `yaml:"name,omitempty"`
+	Namespace   string            `yaml:"namespace,omitempty"`
+	Labels      map[string]string `yaml:"labels,omitempty"`
+	Annotations map[string]string `yaml:"annotations,omitempty"`
+}
+
+type container struct {
+	Name    string    `yaml:"name"`
+	Image   string    `yaml:"image"`
+	Command []string  `yaml:"command"`
+	Env     []envVar  `yaml:"env,omitempty"`
+	EnvFrom []envFrom `yaml:"envFrom,omitempty"`
+}
+
+type envVar struct {
+	Name  string `yaml:"name"`
+	Value string `yaml:"value"`
+}
+
+type envFrom struct {
+	SecretRef secretRef `yaml:"secretRef"`
+}
+
+type secretRef struct {
+	Name string `yaml:"name"`
+}
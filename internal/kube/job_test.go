@@ -0,0 +1,117 @@
+package kube
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestParseTarget(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Target
+	}{
+		{"https://github.com/org/repo.git", Target{Repo: "https://github.com/org/repo.git"}},
+		{"https://github.com/org/repo.git@v1.2", Target{Repo: "https://github.com/org/repo.git", Ref: "v1.2"}},
+		{"https://github.com/org/repo@feature/x", Target{Repo: "https://github.com/org/repo", Ref: "feature/x"}},
+		{"git@github.com:org/repo.git", Target{Repo: "git@github.com:org/repo.git"}},
+		{"git@github.com:org/repo.git@main", Target{Repo: "git@github.com:org/repo.git", Ref: "main"}},
+		{"https://user@host/repo", Target{Repo: "https://user@host/repo"}},
+		{"  https://host/org/repo  ", Target{Repo: "https://host/org/repo"}},
+	}
+	for _, tt := range tests {
+		if got := ParseTarget(tt.in); got != tt.want {
+			t.Errorf("ParseTarget(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := Spec{Batch: "b", Image: "img", Prompt: "p", Targets: []Target{{Repo: "r"}}}
+	tests := []struct {
+		name   string
+		modify func(*Spec)
+		want   string
+	}{
+		{"image", func(s *Spec) { s.Image = "" }, "image"},
+		{"prompt", func(s *Spec) { s.Prompt = "  " }, "prompt"},
+		{"targets", func(s *Spec) { s.Targets = nil }, "repository"},
+		{"empty repo", func(s *Spec) { s.Targets = []Target{{}} }, "repository"},
+	}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("valid spec: %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := valid
+			tt.modify(&s)
+			if err := s.Validate(); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestManifests(t *testing.T) {
+	spec := &Spec{
+		Batch:     "abc123",
+		Namespace: "agents",
+		Image:     "ghcr.io/acme/stormtrooper:latest",
+		Prompt:    "bump the linter",
+		Model:     "some/model",
+		Config:    "language: en",
+		Secret:    "stormtrooper-keys",
+		Deadline:  1800,
+		Targets: []Target{
+			{Repo: "https://git.example.com/a.git"},
+			{Repo: "https://git.example.com/b.git", Ref: "develop"},
+		},
+	}
+	data, err := spec.Manifests()
+	if err != nil {
+		t.Fatalf("Manifests: %v", err)
+	}
+
+	docs := strings.Split(string(data), "---\n")
+	if len(docs) != 2 {
+		t.Fatalf("expected 2 documents, got %d", len(docs))
+	}
+	var j job
+	if err := yaml.Unmarshal([]byte(docs[1]), &j); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	if j.Kind != "Job" || j.Metadata.Name != "stormtrooper-abc123-1" || j.Metadata.Namespace != "agents" {
+		t.Errorf("unexpected metadata %+v", j.Metadata)
+	}
+	if j.Metadata.Labels[labelBatch] != "abc123" || j.Spec.Template.Metadata.Labels[labelBatch] != "abc123" {
+		t.Errorf("batch label missing: %+v", j)
+	}
+	if j.Metadata.Annotations[annoRepo] != "https://git.example.com/b.git" || j.Metadata.Annotations[annoRef] != "develop" {
+		t.Errorf("annotations = %v", j.Metadata.Annotations)
+	}
+	if *j.Spec.BackoffLimit != 0 || *j.Spec.ActiveDeadlineSeconds != 1800 {
+		t.Errorf("unexpected job spec limits")
+	}
+	if j.Spec.Template.Spec.RestartPolicy != "Never" {
+		t.Errorf("restartPolicy = %q", j.Spec.Template.Spec.RestartPolicy)
+	}
+
+	c := j.Spec.Template.Spec.Containers[0]
+	if c.Image != spec.Image || c.EnvFrom[0].SecretRef.Name != "stormtrooper-keys" {
+		t.Errorf("unexpected container %+v", c)
+	}
+	env := map[string]string{}
+	for _, e := range c.Env {
+		env[e.Name] = e.Value
+	}
+	for k, v := range map[string]string{"REPO": "https://git.example.com/b.git", "REF": "develop", "PROMPT": "bump the linter", "MODEL": "some/model", "STORMTROOPER_CONFIG": "language: en"} {
+		if env[k] != v {
+			t.Errorf("env %s = %q, want %q", k, env[k], v)
+		}
+	}
+	if !strings.Contains(c.Command[2], `stormtrooper --yes`) || !strings.Contains(c.Command[2], changesMarker) {
+		t.Errorf("script does not run the agent headlessly:\n%s", c.Command[2])
+	}
+}
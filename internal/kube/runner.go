@@ -0,0 +1,205 @@
+package kube
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Result is the outcome of one job.
+type Result struct {
+	Job       string
+	Repo      string
+	Ref       string
+	Succeeded bool
+	Output    string // agent output and tool status lines
+	Diff      string // changes the agent made, as a git diff
+}
+
+// Runner submits jobs and collects their results with kubectl.
+type Runner struct {
+	Namespace string
+	Poll      time.Duration // status polling interval
+
+	// kubectl runs kubectl with stdin; replaced in tests.
+	kubectl func(ctx context.Context, stdin []byte, args ...string) ([]byte, error)
+}
+
+// NewRunner creates a Runner for namespace ("" for the context's default).
+func NewRunner(namespace string) *Runner {
+	return &Runner{Namespace: namespace, Poll: 5 * time.Second, kubectl: runKubectl}
+}
+
+func (r *Runner) run(ctx context.Context, stdin []byte, args ...string) ([]byte, error) {
+	verb := args[0]
+	if r.Namespace != "" {
+		args = append([]string{"--namespace", r.Namespace}, args...)
+	}
+	out, err := r.kubectl(ctx, stdin, args...)
+	if err != nil {
+		return nil, fmt.Errorf("kubectl %s: %w", verb, err)
+	}
+	return out, nil
+}
+
+// Submit creates the jobs described by spec.
+func (r *Runner) Submit(ctx context.Context, spec *Spec) error {
+	manifests, err := spec.Manifests()
+	if err != nil {
+		return err
+	}
+	_, err = r.run(ctx, manifests, "create", "-f", "-")
+	return err
+}
+
+// jobList is the subset of "kubectl get jobs -o json" used here.
+type jobList struct {
+	Items []struct {
+		Metadata struct {
+			Name        string            `json:"name"`
+			Annotations map[string]string `json:"annotations"`
+		} `json:"metadata"`
+		Status struct {
+			Succeeded int `json:"succeeded"`
+			Failed    int `json:"failed"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+// Wait polls until every job in the batch has finished and returns their
+// outcomes, sorted by job name. Output and Diff are not filled in; see
+// Collect.
+func (r *Runner) Wait(ctx context.Context, batch string, progress io.Writer) ([]Result, error) {
+	lastDone := -1
+	for {
+		out, err := r.run(ctx, nil, "get", "jobs", "--selector", labelBatch+"="+batch, "--output", "json")
+		if err != nil {
+			return nil, err
+		}
+		var list jobList
+		if err := json.Unmarshal(out, &list); err != nil {
+			return nil, fmt.Errorf("kubectl get jobs: %w", err)
+		}
+		if len(list.Items) == 0 {
+			return nil, fmt.Errorf("no jobs found for batch %s", batch)
+		}
+
+		var results []Result
+		done := 0
+		for _, item := range list.Items {
+			finished := item.Status.Succeeded > 0 || item.Status.Failed > 0
+			if finished {
+				done++
+			}
+			results = append(results, Result{
+				Job:       item.Metadata.Name,
+				Repo:      item.Metadata.Annotations[annoRepo],
+				Ref:       item.Metadata.Annotations[annoRef],
+				Succeeded: item.Status.Succeeded > 0,
+			})
+		}
+		if done != lastDone && progress != nil {
+			fmt.Fprintf(progress, "%d/%d jobs finished\n", done, len(results))
+			lastDone = done
+		}
+		if done == len(results) {
+			sort.Slice(results, func(i, j int) bool { return results[i].Job < results[j].Job })
+			return results, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(r.Poll):
+		}
+	}
+}
+
+// Collect fetches each job's log, splits it into output and diff, and
+// writes them to dir/<job>/output.log and dir/<job>/changes.diff.
+func (r *Runner) Collect(ctx context.Context, results []Result, dir string) error {
+	for i := range results {
+		res := &results[i]
+		logs, err := r.run(ctx, nil, "logs", "job/"+res.Job)
+		if err != nil {
+			return err
+		}
+		res.Output, res.Diff = splitLog(string(logs))
+
+		jobDir := filepath.Join(dir, res.Job)
+		if err := os.MkdirAll(jobDir, 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(jobDir, "output.log"), []byte(res.Output), 0644); err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(jobDir, "changes.diff"), []byte(res.Diff), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitLog separates agent output from the diff printed after the marker.
+func splitLog(logs string) (output, diff string) {
+	output, diff, found := strings.Cut(logs, changesMarker+"\n")
+	if !found {
+		return logs, ""
+	}
+	return output, diff
+}
+
+// WriteSummary prints one line per result.
+func WriteSummary(w io.Writer, results []Result) {
+	for _, res := range results {
+		status := "ok"
+		if !res.Succeeded {
+			status = "FAILED"
+		}
+		target := res.Repo
+		if res.Ref != "" {
+			target += "@" + res.Ref
+		}
+		changed := "no changes"
+		if n := filesChanged(res.Diff); n > 0 {
+			changed = fmt.Sprintf("%d files changed", n)
+		}
+		fmt.Fprintf(w, "%-6s  %s  %s  (%s)\n", status, res.Job, target, changed)
+	}
+}
+
+func filesChanged(diff string) int {
+	n := 0
+	for _, line := range strings.Split(diff, "\n") {
+		if strings.HasPrefix(line, "diff --git ") {
+			n++
+		}
+	}
+	return n
+}
+
+// runKubectl returns kubectl's stdout; stderr is included in the error.
+func runKubectl(ctx context.Context, stdin []byte, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "kubectl", args...)
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%v: %s", err, msg)
+		}
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,114 @@
+package kube
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeKubectl answers kubectl invocations from canned responses keyed by
+// the verb.
+type fakeKubectl struct {
+	calls     [][]string
+	stdin     []byte
+	responses map[string][]string // verb -> successive outputs
+}
+
+func (f *fakeKubectl) run(ctx context.Context, stdin []byte, args ...string) ([]byte, error) {
+	f.calls = append(f.calls, args)
+	if stdin != nil {
+		f.stdin = stdin
+	}
+	verb := args[2] // after --namespace <ns>
+	queue := f.responses[verb]
+	if len(queue) == 0 {
+		return nil, errors.New("unexpected call " + strings.Join(args, " "))
+	}
+	out := queue[0]
+	if len(queue) > 1 {
+		f.responses[verb] = queue[1:]
+	}
+	return []byte(out), nil
+}
+
+func newTestRunner(f *fakeKubectl) *Runner {
+	r := NewRunner("agents")
+	r.Poll = 0
+	r.kubectl = f.run
+	return r
+}
+
+func TestSubmit(t *testing.T) {
+	f := &fakeKubectl{responses: map[string][]string{"create": {"job created"}}}
+	spec := &Spec{Batch: "b1", Image: "img", Prompt: "p", Targets: []Target{{Repo: "r"}}}
+	if err := newTestRunner(f).Submit(context.Background(), spec); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	if got := strings.Join(f.calls[0], " "); got != "--namespace agents create -f -" {
+		t.Errorf("kubectl args = %q", got)
+	}
+	if !strings.Contains(string(f.stdin), "stormtrooper-b1-0") {
+		t.Errorf("manifests not passed on stdin: %s", f.stdin)
+	}
+}
+
+const (
+	jobsRunning = `{"items":[
+		{"metadata":{"name":"stormtrooper-b1-1","annotations":{"stormtrooper.dev/repo":"r2","stormtrooper.dev/ref":"dev"}},"status":{}},
+		{"metadata":{"name":"stormtrooper-b1-0","annotations":{"stormtrooper.dev/repo":"r1"}},"status":{"succeeded":1}}]}`
+	jobsDone = `{"items":[
+		{"metadata":{"name":"stormtrooper-b1-1","annotations":{"stormtrooper.dev/repo":"r2","stormtrooper.dev/ref":"dev"}},"status":{"failed":1}},
+		{"metadata":{"name":"stormtrooper-b1-0","annotations":{"stormtrooper.dev/repo":"r1"}},"status":{"succeeded":1}}]}`
+)
+
+func TestWaitAndCollect(t *testing.T) {
+	f := &fakeKubectl{responses: map[string][]string{
+		"get": {jobsRunning, jobsDone},
+		"logs": {
+			"[tool] edit_file\nBumped the linter.\n" + changesMarker + "\ndiff --git a/.golangci.yml b/.golangci.yml\n+v2\n",
+			"Error: provider unavailable\n" + changesMarker + "\n",
+		},
+	}}
+	r := newTestRunner(f)
+
+	var progress strings.Builder
+	results, err := r.Wait(context.Background(), "b1", &progress)
+	if err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+	if progress.String() != "1/2 jobs finished\n2/2 jobs finished\n" {
+		t.Errorf("progress = %q", progress.String())
+	}
+	if len(results) != 2 || results[0].Job != "stormtrooper-b1-0" || !results[0].Succeeded || results[1].Succeeded || results[1].Ref != "dev" {
+		t.Fatalf("unexpected results %+v", results)
+	}
+
+	dir := t.TempDir()
+	if err := r.Collect(context.Background(), results, dir); err != nil {
+		t.Fatalf("Collect: %v", err)
+	}
+	if results[0].Output != "[tool] edit_file\nBumped the linter.\n" {
+		t.Errorf("output = %q", results[0].Output)
+	}
+	diff, _ := os.ReadFile(filepath.Join(dir, "stormtrooper-b1-0", "changes.diff"))
+	if !strings.HasPrefix(string(diff), "diff --git") {
+		t.Errorf("changes.diff = %q", diff)
+	}
+
+	var summary strings.Builder
+	WriteSummary(&summary, results)
+	want := "ok      stormtrooper-b1-0  r1  (1 files changed)\nFAILED  stormtrooper-b1-1  r2@dev  (no changes)\n"
+	if summary.String() != want {
+		t.Errorf("summary:\n%s\nwant:\n%s", summary.String(), want)
+	}
+}
+
+func TestWait_NoJobs(t *testing.T) {
+	f := &fakeKubectl{responses: map[string][]string{"get": {`{"items":[]}`}}}
+	if _, err := newTestRunner(f).Wait(context.Background(), "nope", nil); err == nil {
+		t.Error("expected error for unknown batch")
+	}
+}
@@ -6,26 +6,74 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"slices"
+	"sync/atomic"
+	"time"
 )
 
 const defaultBaseURL = "https://openrouter.ai/api/v1"
 
+// maxIdleConnsPerHost keeps a warm connection for each agent that may be
+// talking to the provider at once; the default of 2 would make sub-agents
+// dial and handshake again for most requests.
+const maxIdleConnsPerHost = 16
+
 // Client is an HTTP client for the OpenRouter chat completions API.
 type Client struct {
 	apiKey  string
 	baseURL string
 	http    *http.Client
+	queue   *queue
+	stats   Stats
+	maxLine int
+	guard   Guard
+
+	maxRetries int
+	baseDelay  time.Duration
+
+	toolCalling string
+	promptTools atomic.Bool // describe tools in the prompt; see SetToolCalling
 }
 
-// NewClient creates a new LLM client with the given API key.
+// Guard vets requests before they are sent and is told the tokens each
+// one used, so an organization policy can restrict models and cap spend.
+type Guard interface {
+	// Allow returns an error to refuse a request for model.
+	Allow(model string) error
+	// Used records the prompt and completion tokens of a request,
+	// estimated when the provider does not report them.
+	Used(model string, tokens int)
+}
+
+// NewClient creates a new LLM client with the given API key. Share one
+// client between agents so their requests reuse pooled connections.
 func NewClient(apiKey string) *Client {
 	return &Client{
 		apiKey:  apiKey,
 		baseURL: defaultBaseURL,
-		http:    &http.Client{},
+		http:    &http.Client{Transport: newTransport()},
+	}
+}
+
+// newTransport returns a transport tuned for many requests to one host:
+// HTTP/2 where the provider offers it, TCP keep-alives, and enough idle
+// connections that each request can skip connection setup.
+func newTransport() *http.Transport {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	return &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   maxIdleConnsPerHost,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
 	}
 }
 
@@ -34,34 +82,113 @@ func (c *Client) SetBaseURL(url string) {
 	c.baseURL = url
 }
 
+// SetConcurrency limits the requests to the provider in flight at once;
+// further requests wait their turn, interactive ones first (see
+// WithPriority). n <= 0 removes the limit. It must be called before the
+// client is used.
+func (c *Client) SetConcurrency(n int) {
+	if n <= 0 {
+		c.queue = nil
+		return
+	}
+	c.queue = &queue{limit: n}
+}
+
+// SetMaxLineSize sets the longest line accepted in a streamed response;
+// n <= 0 means DefaultMaxLineSize.
+func (c *Client) SetMaxLineSize(n int) {
+	c.maxLine = n
+}
+
+// SetGuard makes every request pass g first. It must be called before
+// the client is used.
+func (c *Client) SetGuard(g Guard) {
+	c.guard = g
+}
+
+// Stats returns the latency of the streamed requests made so far.
+func (c *Client) Stats() *Stats {
+	return &c.stats
+}
+
+// SetTransport replaces the HTTP transport used for API requests. Used to
+// record, replay, or script responses without a network.
+func (c *Client) SetTransport(rt http.RoundTripper) {
+	c.http.Transport = rt
+}
+
+// Transport returns the HTTP transport in use, or http.DefaultTransport
+// if none has been set.
+func (c *Client) Transport() http.RoundTripper {
+	if c.http.Transport == nil {
+		return http.DefaultTransport
+	}
+	return c.http.Transport
+}
+
 // ChatCompletion sends a non-streaming chat completion request.
 func (c *Client) ChatCompletion(ctx context.Context, req ChatCompletionRequest) (*ChatCompletionResponse, error) {
+	if !c.promptTools.Load() {
+		resp, err := c.chatCompletion(ctx, req)
+		if err == nil || !c.fallBackToPromptTools(req, err) {
+			return resp, err
+		}
+	}
+	resp, err := c.chatCompletion(ctx, promptRequest(req))
+	if err != nil || len(req.Tools) == 0 {
+		return resp, err
+	}
+	for i := range resp.Choices {
+		msg := &resp.Choices[i].Message
+		msg.Content, msg.ToolCalls = parsePromptToolCalls(msg.Content)
+	}
+	return resp, nil
+}
+
+func (c *Client) chatCompletion(ctx context.Context, req ChatCompletionRequest) (*ChatCompletionResponse, error) {
 	req.Stream = false
+	req.Messages = withoutModels(req.Messages)
+	if c.guard != nil {
+		if err := c.guard.Allow(req.Model); err != nil {
+			return nil, err
+		}
+	}
 
 	body, err := json.Marshal(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/chat/completions", bytes.NewReader(body))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-	c.setHeaders(httpReq)
+	var result ChatCompletionResponse
+	err = c.withRetry(ctx, req.Model, func() error {
+		resp, err := c.send(ctx, body)
+		if err != nil {
+			return err
+		}
+		defer c.queue.release()
+		defer closeBody(resp)
 
-	resp, err := c.http.Do(httpReq)
+		result = ChatCompletionResponse{}
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			return fmt.Errorf("failed to decode response: %w", err)
+		}
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, readAPIError(resp)
+	for i := range result.Choices {
+		fillToolCalls(&result.Choices[i].Message)
 	}
-
-	var result ChatCompletionResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	if c.guard != nil {
+		tokens := len(body) / 4
+		for _, choice := range result.Choices {
+			tokens += EstimateTokens(choice.Message)
+		}
+		if result.Usage != nil {
+			tokens = result.Usage.PromptTokens + result.Usage.CompletionTokens
+		}
+		c.guard.Used(req.Model, tokens)
 	}
 
 	return &result, nil
@@ -70,44 +197,224 @@ func (c *Client) ChatCompletion(ctx context.Context, req ChatCompletionRequest)
 // ChatCompletionStream sends a streaming chat completion request.
 // The callback is called for each chunk as it arrives (for real-time display).
 // Returns the fully accumulated assistant message after the stream ends.
+// The request asks for token usage, which providers send in a final chunk.
 func (c *Client) ChatCompletionStream(ctx context.Context, req ChatCompletionRequest, callback StreamCallback) (*Message, error) {
+	if !c.promptTools.Load() {
+		msg, err := c.chatCompletionStream(ctx, req, callback)
+		if err == nil || !c.fallBackToPromptTools(req, err) {
+			return msg, err
+		}
+	}
+
+	// Show the reply up to its first tool call.
+	filter := &toolCallFilter{}
+	filtered := callback
+	if callback != nil && len(req.Tools) > 0 {
+		filtered = func(chunk ChatCompletionChunk) {
+			chunk.Choices = slices.Clone(chunk.Choices)
+			for i := range chunk.Choices {
+				chunk.Choices[i].Delta.Content = filter.next(chunk.Choices[i].Delta.Content)
+			}
+			callback(chunk)
+		}
+	}
+	msg, err := c.chatCompletionStream(ctx, promptRequest(req), filtered)
+	if err != nil || len(req.Tools) == 0 {
+		return msg, err
+	}
+	msg.Content, msg.ToolCalls = parsePromptToolCalls(msg.Content)
+	return msg, nil
+}
+
+func (c *Client) chatCompletionStream(ctx context.Context, req ChatCompletionRequest, callback StreamCallback) (*Message, error) {
 	req.Stream = true
+	req.StreamOptions = &StreamOptions{IncludeUsage: true}
+	req.Messages = withoutModels(req.Messages)
+	if c.guard != nil {
+		if err := c.guard.Allow(req.Model); err != nil {
+			return nil, err
+		}
+	}
 
 	body, err := json.Marshal(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
+	// A stream that fails before its first chunk is tried again; after
+	// that, the caller has seen part of the reply and gets the error.
+	var (
+		acc   *DeltaAccumulator
+		start time.Time
+		ttft  time.Duration
+		usage *Usage
+	)
+	err = c.withRetry(ctx, req.Model, func() error {
+		start = time.Now()
+		resp, err := c.send(ctx, body)
+		if err != nil {
+			return err
+		}
+		// The slot is held until the stream ends.
+		defer c.queue.release()
+		defer closeBody(resp)
+
+		acc, ttft, usage = NewDeltaAccumulator(), 0, nil
+		delivered := false
+		// Some servers neither send [DONE] nor close the stream after the
+		// last chunk. Once a choice has finished, a stream that stays quiet
+		// for finishGrace is over.
+		var idle *time.Timer
+		var ended atomic.Bool
+		defer func() {
+			if idle != nil {
+				idle.Stop()
+			}
+		}()
+		err = parseSSEStream(resp.Body, c.maxLine, func(chunk ChatCompletionChunk) {
+			delivered = true
+			if idle != nil {
+				idle.Reset(finishGrace)
+			} else if finished(chunk) {
+				idle = time.AfterFunc(finishGrace, func() {
+					ended.Store(true)
+					resp.Body.Close()
+				})
+			}
+			if ttft == 0 && hasOutput(chunk) {
+				ttft = time.Since(start)
+			}
+			if chunk.Usage != nil {
+				usage = chunk.Usage
+			}
+			acc.Add(chunk)
+			if callback != nil {
+				callback(chunk)
+			}
+		})
+		if err != nil && ended.Load() {
+			err = nil
+		}
+		if err != nil {
+			err = fmt.Errorf("stream error: %w", err)
+			if delivered {
+				return &streamStarted{err}
+			}
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		var started *streamStarted
+		if errors.As(err, &started) {
+			return nil, started.error
+		}
+		return nil, err
+	}
+
+	msg := acc.Message()
+	fillToolCalls(&msg)
+	tokens := 0
+	if usage != nil {
+		tokens = usage.CompletionTokens
+	} else {
+		tokens = EstimateTokens(msg)
+	}
+	c.stats.record(req.Model, ttft, time.Since(start), tokens)
+	if c.guard != nil {
+		if usage != nil {
+			tokens += usage.PromptTokens
+		} else {
+			tokens += len(body) / 4
+		}
+		c.guard.Used(req.Model, tokens)
+	}
+	return &msg, nil
+}
+
+// send posts body to the chat completions endpoint once a queue slot is
+// free. On success the caller owns the slot and the response, and must
+// release and close them; on failure both are already released.
+func (c *Client) send(ctx context.Context, body []byte) (*http.Response, error) {
 	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/chat/completions", bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 	c.setHeaders(httpReq)
 
+	if err := c.queue.acquire(ctx); err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
 	resp, err := c.http.Do(httpReq)
 	if err != nil {
+		c.queue.release()
 		return nil, fmt.Errorf("request failed: %w", err)
 	}
-	defer resp.Body.Close()
-
 	if resp.StatusCode != http.StatusOK {
+		defer c.queue.release()
+		defer closeBody(resp)
 		return nil, readAPIError(resp)
 	}
+	return resp, nil
+}
 
-	acc := NewDeltaAccumulator()
+// finishGrace is how long a stream may stay quiet after a choice has
+// finished before it is taken to be over.
+var finishGrace = 2 * time.Second
 
-	err = ParseSSEStream(resp.Body, func(chunk ChatCompletionChunk) {
-		acc.Add(chunk)
-		if callback != nil {
-			callback(chunk)
+// finished reports whether chunk ends a choice.
+func finished(chunk ChatCompletionChunk) bool {
+	for _, choice := range chunk.Choices {
+		if choice.FinishReason != nil && *choice.FinishReason != "" {
+			return true
 		}
-	})
-	if err != nil {
-		return nil, fmt.Errorf("stream error: %w", err)
 	}
+	return false
+}
 
-	msg := acc.Message()
-	return &msg, nil
+// hasOutput reports whether chunk carries generated text or tool call
+// arguments, as opposed to only a role or a finish reason.
+func hasOutput(chunk ChatCompletionChunk) bool {
+	for _, choice := range chunk.Choices {
+		if choice.Delta.Content != "" || len(choice.Delta.ToolCalls) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// withoutModels returns messages without their Model annotations, which
+// are not part of the API, copying them only if any has one.
+func withoutModels(messages []Message) []Message {
+	for i, m := range messages {
+		if m.Model != "" {
+			out := slices.Clone(messages)
+			for j := i; j < len(out); j++ {
+				out[j].Model = ""
+			}
+			return out
+		}
+	}
+	return messages
+}
+
+// EstimateTokens guesses a message's size, at about four bytes per
+// token, for when the provider does not report usage and for showing
+// what fills the context.
+func EstimateTokens(msg Message) int {
+	n := len(msg.Content)
+	for _, tc := range msg.ToolCalls {
+		n += len(tc.Function.Name) + len(tc.Function.Arguments)
+	}
+	return n / 4
+}
+
+// closeBody drains what is left of a response, such as the newline
+// after a JSON body or events after [DONE], so the connection goes back
+// to the pool instead of being closed.
+func closeBody(resp *http.Response) {
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	resp.Body.Close()
 }
 
 func (c *Client) setHeaders(req *http.Request) {
@@ -116,20 +423,32 @@ func (c *Client) setHeaders(req *http.Request) {
 	req.Header.Set("HTTP-Referer", "https://github.com/gavinyap/stormtrooper")
 }
 
-// APIError represents an error response from the API.
+// APIError represents an error response from the API. Failures the user
+// can act on are returned as the more specific errors in errors.go,
+// which wrap an APIError.
 type APIError struct {
 	StatusCode int
 	Body       string
+	// Message is the error message from the body, if it had one.
+	Message string
+	// RetryAfter is how long the Retry-After header asked to wait
+	// before trying again; zero if it was absent.
+	RetryAfter time.Duration
 }
 
 func (e *APIError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("API error (status %d): %s", e.StatusCode, e.Message)
+	}
 	return fmt.Sprintf("API error (status %d): %s", e.StatusCode, e.Body)
 }
 
 func readAPIError(resp *http.Response) error {
 	body, _ := io.ReadAll(resp.Body)
-	return &APIError{
-		StatusCode: resp.StatusCode,
-		Body:       string(body),
+	err := parseAPIError(resp.StatusCode, body)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		apiErr.RetryAfter = retryAfter(resp.Header.Get("Retry-After"))
 	}
+	return err
 }
@@ -3,9 +3,15 @@ package llm
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestChatCompletion_Success(t *testing.T) {
@@ -132,6 +138,48 @@ data: [DONE]
 	}
 }
 
+func TestChatCompletionStream_Usage(t *testing.T) {
+	sseData := `data: {"id":"1","choices":[{"index":0,"delta":{"role":"assistant","content":"Hi"},"finish_reason":"stop"}]}
+
+data: {"id":"1","choices":[],"usage":{"prompt_tokens":120,"completion_tokens":8,"total_tokens":128,"cost":0.0004}}
+
+data: [DONE]
+`
+
+	var sent ChatCompletionRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&sent)
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(sseData))
+	}))
+	defer server.Close()
+
+	client := NewClient("test-key")
+	client.SetBaseURL(server.URL)
+
+	var usage *Usage
+	msg, err := client.ChatCompletionStream(context.Background(), ChatCompletionRequest{
+		Model:    "test-model",
+		Messages: []Message{{Role: "user", Content: "Hi"}},
+	}, func(chunk ChatCompletionChunk) {
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sent.StreamOptions == nil || !sent.StreamOptions.IncludeUsage {
+		t.Errorf("expected the request to ask for usage, got %+v", sent.StreamOptions)
+	}
+	if msg.Content != "Hi" {
+		t.Errorf("expected 'Hi', got %q", msg.Content)
+	}
+	if usage == nil || usage.PromptTokens != 120 || usage.CompletionTokens != 8 || usage.Cost == nil || *usage.Cost != 0.0004 {
+		t.Errorf("usage = %+v", usage)
+	}
+}
+
 func TestChatCompletionStream_ToolCall(t *testing.T) {
 	sseData := `data: {"id":"1","choices":[{"index":0,"delta":{"role":"assistant","tool_calls":[{"index":0,"id":"call_abc","type":"function","function":{"name":"read_file","arguments":"{\"file"}}]},"finish_reason":null}]}
 
@@ -208,3 +256,206 @@ func TestChatCompletionStream_APIError(t *testing.T) {
 		t.Errorf("expected status 429, got %d", apiErr.StatusCode)
 	}
 }
+
+// roundTripFunc adapts a function to http.RoundTripper.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestSetTransport(t *testing.T) {
+	client := NewClient("test-key")
+	if tr, ok := client.Transport().(*http.Transport); !ok || tr.MaxIdleConnsPerHost != maxIdleConnsPerHost || !tr.ForceAttemptHTTP2 {
+		t.Fatalf("expected the pooled transport before SetTransport, got %#v", client.Transport())
+	}
+
+	called := false
+	client.SetTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		called = true
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(`{"id":"x","choices":[{"index":0,"message":{"role":"assistant","content":"via transport"}}]}`)),
+		}, nil
+	}))
+
+	resp, err := client.ChatCompletion(context.Background(), ChatCompletionRequest{Model: "m"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !called {
+		t.Fatal("expected custom transport to be used")
+	}
+	if resp.Choices[0].Message.Content != "via transport" {
+		t.Errorf("unexpected content %q", resp.Choices[0].Message.Content)
+	}
+}
+
+func TestClient_ReusesConnections(t *testing.T) {
+	var conns atomic.Int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Stream {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Write([]byte("data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\"hi\"}}]}\n\ndata: [DONE]\n\n"))
+			return
+		}
+		json.NewEncoder(w).Encode(ChatCompletionResponse{Choices: []Choice{{Message: Message{Content: "hi"}}}})
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	client := NewClient("test-key")
+	client.SetBaseURL(server.URL)
+	for i := 0; i < 3; i++ {
+		if _, err := client.ChatCompletionStream(context.Background(), ChatCompletionRequest{Model: "m"}, nil); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := client.ChatCompletion(context.Background(), ChatCompletionRequest{Model: "m"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := conns.Load(); n != 1 {
+		t.Errorf("6 sequential requests opened %d connections, want 1", n)
+	}
+}
+
+// budgetGuard allows one model and records the tokens used.
+type budgetGuard struct {
+	allowed string
+	used    map[string]int
+}
+
+func (g *budgetGuard) Allow(model string) error {
+	if model != g.allowed {
+		return errors.New("model not allowed: " + model)
+	}
+	return nil
+}
+
+func (g *budgetGuard) Used(model string, tokens int) {
+	g.used[model] += tokens
+}
+
+func TestClient_Guard(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(`data: {"id":"1","choices":[{"index":0,"delta":{"content":"Hi"},"finish_reason":"stop"}],"usage":{"prompt_tokens":12,"completion_tokens":3,"total_tokens":15}}
+
+data: [DONE]
+`))
+	}))
+	defer server.Close()
+
+	guard := &budgetGuard{allowed: "ok-model", used: map[string]int{}}
+	client := NewClient("test-key")
+	client.SetBaseURL(server.URL)
+	client.SetGuard(guard)
+
+	if _, err := client.ChatCompletionStream(context.Background(), ChatCompletionRequest{Model: "ok-model"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if guard.used["ok-model"] != 15 {
+		t.Errorf("used = %v, want 15 tokens", guard.used)
+	}
+
+	_, err := client.ChatCompletionStream(context.Background(), ChatCompletionRequest{Model: "other"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "not allowed") {
+		t.Errorf("expected the guard's error, got %v", err)
+	}
+	if _, err := client.ChatCompletion(context.Background(), ChatCompletionRequest{Model: "other"}); err == nil {
+		t.Error("expected ChatCompletion to be refused too")
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("refused requests reached the server: %d requests", n)
+	}
+}
+
+func TestWithoutModels(t *testing.T) {
+	plain := []Message{{Role: "user", Content: "hi"}}
+	if got := withoutModels(plain); &got[0] != &plain[0] {
+		t.Error("messages without annotations should not be copied")
+	}
+	annotated := []Message{{Role: "user", Content: "hi"}, {Role: "assistant", Content: "hello", Model: "cheap"}}
+	got := withoutModels(annotated)
+	if got[1].Model != "" {
+		t.Errorf("Model = %q, want it cleared", got[1].Model)
+	}
+	if annotated[1].Model != "cheap" {
+		t.Error("the caller's messages were modified")
+	}
+}
+
+func TestChatCompletionStream_NoDone(t *testing.T) {
+	defer func(d time.Duration) { finishGrace = d }(finishGrace)
+	finishGrace = 50 * time.Millisecond
+
+	// The server finishes its reply but neither sends [DONE] nor closes
+	// the stream.
+	release := make(chan struct{})
+	defer close(release)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(`data: {"choices":[{"index":0,"delta":{"role":"assistant","content":"Hello"},"finish_reason":null}]}` + "\n\n"))
+		w.Write([]byte(`data: {"choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}` + "\n\n"))
+		w.(http.Flusher).Flush()
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient("")
+	client.SetBaseURL(server.URL)
+	done := make(chan struct{})
+	var msg *Message
+	var err error
+	go func() {
+		msg, err = client.ChatCompletionStream(context.Background(), ChatCompletionRequest{Model: "llama3"}, nil)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream without [DONE] never ended")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Content != "Hello" {
+		t.Errorf("content = %q", msg.Content)
+	}
+}
+
+func TestChatCompletionStream_MissingToolCallID(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(`data: {"choices":[{"index":0,"delta":{"role":"assistant","tool_calls":[{"index":0,"function":{"name":"glob","arguments":"{}"}},{"index":1,"function":{"name":"grep","arguments":"{}"}}]},"finish_reason":"tool_calls"}]}` + "\n\n"))
+	}))
+	defer server.Close()
+
+	client := NewClient("")
+	client.SetBaseURL(server.URL)
+	msg, err := client.ChatCompletionStream(context.Background(), ChatCompletionRequest{Model: "llama3"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msg.ToolCalls) != 2 {
+		t.Fatalf("got %d tool calls", len(msg.ToolCalls))
+	}
+	a, b := msg.ToolCalls[0], msg.ToolCalls[1]
+	if !strings.HasPrefix(a.ID, "call_") || a.ID == b.ID {
+		t.Errorf("IDs %q and %q should be distinct generated IDs", a.ID, b.ID)
+	}
+	if a.Type != "function" {
+		t.Errorf("type = %q", a.Type)
+	}
+}
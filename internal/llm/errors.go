@@ -0,0 +1,201 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// errorBody is the error payload of OpenAI-compatible APIs. OpenRouter
+// adds metadata that says why a request failed.
+type errorBody struct {
+	Error json.RawMessage `json:"error"`
+}
+
+type errorDetail struct {
+	Message  string `json:"message"`
+	Metadata struct {
+		Reasons      []string          `json:"reasons"`       // moderation
+		FlaggedInput string            `json:"flagged_input"` // moderation
+		ProviderName string            `json:"provider_name"`
+		ModelSlug    string            `json:"model_slug"`
+		Raw          json.RawMessage   `json:"raw"`     // the upstream provider's own error
+		Headers      map[string]string `json:"headers"` // rate limits
+	} `json:"metadata"`
+}
+
+// ModerationError is a request blocked by a provider's moderation.
+type ModerationError struct {
+	*APIError
+	Reasons      []string
+	FlaggedInput string
+	Provider     string
+	Model        string
+}
+
+func (e *ModerationError) Error() string {
+	msg := "the request was blocked by moderation"
+	if e.Provider != "" {
+		msg += " at " + e.Provider
+	}
+	if len(e.Reasons) > 0 {
+		msg += " (" + strings.Join(e.Reasons, ", ") + ")"
+	}
+	if e.FlaggedInput != "" {
+		msg += fmt.Sprintf(", flagged: %q", e.FlaggedInput)
+	}
+	return msg + ". " + e.Hint()
+}
+
+// Hint says what the user can do about the error.
+func (e *ModerationError) Hint() string {
+	return "Rephrase the request, or switch to a model whose provider does not moderate."
+}
+
+func (e *ModerationError) Unwrap() error { return e.APIError }
+
+// ProviderError is a request the upstream provider failed, after
+// OpenRouter's fallbacks, if any, failed too.
+type ProviderError struct {
+	*APIError
+	Provider string
+	Model    string
+	// Detail is the provider's own error, when it gave one.
+	Detail string
+}
+
+func (e *ProviderError) Error() string {
+	who := e.Provider
+	if who == "" {
+		who = "the provider"
+	}
+	msg := fmt.Sprintf("%s could not serve the request", who)
+	if e.Model != "" {
+		msg += " for " + e.Model
+	}
+	msg += ": " + e.Message
+	if e.Detail != "" && e.Detail != e.Message {
+		msg += " (" + e.Detail + ")"
+	}
+	return msg + ". " + e.Hint()
+}
+
+// Hint says what the user can do about the error.
+func (e *ProviderError) Hint() string {
+	return "Retry in a moment, or pick another model with -model."
+}
+
+func (e *ProviderError) Unwrap() error { return e.APIError }
+
+// CreditsError is a request refused because the account is out of
+// credits.
+type CreditsError struct {
+	*APIError
+}
+
+func (e *CreditsError) Error() string {
+	return fmt.Sprintf("the account is out of credits: %s. %s", e.Message, e.Hint())
+}
+
+// Hint says what the user can do about the error.
+func (e *CreditsError) Hint() string {
+	return "Add credits at https://openrouter.ai/settings/credits, or use a free model (one whose name ends in :free)."
+}
+
+func (e *CreditsError) Unwrap() error { return e.APIError }
+
+// FreeTierError is a request refused because the daily or per-minute
+// limit on free models is used up.
+type FreeTierError struct {
+	*APIError
+	// Reset is when the limit resets; zero if unknown.
+	Reset time.Time
+}
+
+func (e *FreeTierError) Error() string {
+	msg := "the free-model limit is used up: " + e.Message
+	if !e.Reset.IsZero() {
+		msg += fmt.Sprintf(" (resets at %s)", e.Reset.Local().Format("15:04 MST"))
+	}
+	return msg + ". " + e.Hint()
+}
+
+// Hint says what the user can do about the error.
+func (e *FreeTierError) Hint() string {
+	return "Wait for the limit to reset, add credits to raise it, or switch to a paid model."
+}
+
+func (e *FreeTierError) Unwrap() error { return e.APIError }
+
+// parseAPIError returns the error for a failed request: a typed error
+// for the failures the user can act on, otherwise an *APIError.
+func parseAPIError(status int, body []byte) error {
+	apiErr := &APIError{StatusCode: status, Body: string(body)}
+	var eb errorBody
+	if json.Unmarshal(body, &eb) != nil || len(eb.Error) == 0 {
+		return apiErr
+	}
+	var d errorDetail
+	if json.Unmarshal(eb.Error, &d) != nil {
+		// Some servers send the error as a plain string.
+		json.Unmarshal(eb.Error, &apiErr.Message)
+		return apiErr
+	}
+	apiErr.Message = d.Message
+	md := d.Metadata
+
+	switch {
+	case len(md.Reasons) > 0 || md.FlaggedInput != "":
+		return &ModerationError{APIError: apiErr, Reasons: md.Reasons, FlaggedInput: md.FlaggedInput, Provider: md.ProviderName, Model: md.ModelSlug}
+	case status == http.StatusPaymentRequired:
+		return &CreditsError{APIError: apiErr}
+	case status == http.StatusTooManyRequests && strings.Contains(d.Message, "free-models"):
+		e := &FreeTierError{APIError: apiErr}
+		if ms, err := strconv.ParseInt(md.Headers["X-RateLimit-Reset"], 10, 64); err == nil {
+			e.Reset = time.UnixMilli(ms)
+		}
+		return e
+	case md.ProviderName != "" || status == http.StatusBadGateway || status == http.StatusServiceUnavailable:
+		return &ProviderError{APIError: apiErr, Provider: md.ProviderName, Model: md.ModelSlug, Detail: rawDetail(md.Raw)}
+	}
+	return apiErr
+}
+
+// rawDetail extracts a readable message from a provider's raw error,
+// which may be a JSON string, a JSON object, or absent.
+func rawDetail(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		raw = json.RawMessage(s)
+	}
+	var nested struct {
+		Message string `json:"message"`
+		Error   struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(raw, &nested) == nil {
+		if nested.Error.Message != "" {
+			return nested.Error.Message
+		}
+		if nested.Message != "" {
+			return nested.Message
+		}
+	}
+	return oneLine(strings.TrimSpace(string(raw)), 200)
+}
+
+// oneLine returns the first line of s, cut to n bytes.
+func oneLine(s string, n int) string {
+	s, _, _ = strings.Cut(s, "\n")
+	if len(s) > n {
+		s = s[:n] + "..."
+	}
+	return s
+}
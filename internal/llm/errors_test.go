@@ -0,0 +1,142 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseAPIError(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		check  func(t *testing.T, err error)
+	}{
+		{
+			name:   "moderation",
+			status: 403,
+			body:   `{"error":{"code":403,"message":"Input flagged","metadata":{"reasons":["violence"],"flagged_input":"how to blow up the build","provider_name":"OpenAI","model_slug":"openai/gpt-4o"}}}`,
+			check: func(t *testing.T, err error) {
+				var e *ModerationError
+				if !errors.As(err, &e) || e.Provider != "OpenAI" || e.Model != "openai/gpt-4o" || e.Reasons[0] != "violence" {
+					t.Fatalf("got %#v", err)
+				}
+				if got := err.Error(); !strings.Contains(got, `blocked by moderation at OpenAI (violence), flagged: "how to blow up the build". Rephrase`) {
+					t.Errorf("Error() = %q", got)
+				}
+			},
+		},
+		{
+			name:   "provider with raw error",
+			status: 502,
+			body:   `{"error":{"code":502,"message":"Provider returned error","metadata":{"provider_name":"Together","raw":"{\"error\":{\"message\":\"model overloaded\"}}"}}}`,
+			check: func(t *testing.T, err error) {
+				var e *ProviderError
+				if !errors.As(err, &e) || e.Detail != "model overloaded" {
+					t.Fatalf("got %#v", err)
+				}
+				if got := err.Error(); got != "Together could not serve the request: Provider returned error (model overloaded). Retry in a moment, or pick another model with -model." {
+					t.Errorf("Error() = %q", got)
+				}
+			},
+		},
+		{
+			name:   "no provider available",
+			status: 503,
+			body:   `{"error":{"code":503,"message":"No endpoints found that support tool use"}}`,
+			check: func(t *testing.T, err error) {
+				var e *ProviderError
+				if !errors.As(err, &e) || !strings.HasPrefix(err.Error(), "the provider could not serve the request: No endpoints found") {
+					t.Fatalf("got %v", err)
+				}
+			},
+		},
+		{
+			name:   "out of credits",
+			status: 402,
+			body:   `{"error":{"code":402,"message":"Insufficient credits"}}`,
+			check: func(t *testing.T, err error) {
+				var e *CreditsError
+				if !errors.As(err, &e) || !strings.Contains(err.Error(), "openrouter.ai/settings/credits") {
+					t.Fatalf("got %v", err)
+				}
+			},
+		},
+		{
+			name:   "free tier",
+			status: 429,
+			body:   `{"error":{"code":429,"message":"Rate limit exceeded: free-models-per-day","metadata":{"headers":{"X-RateLimit-Limit":"50","X-RateLimit-Remaining":"0","X-RateLimit-Reset":"1760572800000"}}}}`,
+			check: func(t *testing.T, err error) {
+				var e *FreeTierError
+				if !errors.As(err, &e) || !e.Reset.Equal(time.UnixMilli(1760572800000)) {
+					t.Fatalf("got %#v", err)
+				}
+				if !strings.Contains(err.Error(), "free-model limit is used up: Rate limit exceeded: free-models-per-day (resets at ") {
+					t.Errorf("Error() = %q", err.Error())
+				}
+			},
+		},
+		{
+			name:   "other rate limit",
+			status: 429,
+			body:   `{"error":{"code":429,"message":"Too many requests"}}`,
+			check: func(t *testing.T, err error) {
+				e, ok := err.(*APIError)
+				if !ok || err.Error() != "API error (status 429): Too many requests" || e.Message != "Too many requests" {
+					t.Fatalf("got %#v", err)
+				}
+			},
+		},
+		{
+			name:   "string error",
+			status: 401,
+			body:   `{"error":"invalid api key"}`,
+			check: func(t *testing.T, err error) {
+				if err.Error() != "API error (status 401): invalid api key" {
+					t.Errorf("Error() = %q", err.Error())
+				}
+			},
+		},
+		{
+			name:   "not JSON",
+			status: 500,
+			body:   "upstream connect error",
+			check: func(t *testing.T, err error) {
+				if err.Error() != "API error (status 500): upstream connect error" {
+					t.Errorf("Error() = %q", err.Error())
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := parseAPIError(tt.status, []byte(tt.body))
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) || apiErr.StatusCode != tt.status {
+				t.Fatalf("every error should unwrap to an APIError with the status, got %#v", err)
+			}
+			tt.check(t, err)
+		})
+	}
+}
+
+func TestChatCompletion_TypedError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusPaymentRequired)
+		w.Write([]byte(`{"error":{"code":402,"message":"Insufficient credits"}}`))
+	}))
+	defer server.Close()
+	client := NewClient("test-key")
+	client.SetBaseURL(server.URL)
+
+	_, err := client.ChatCompletionStream(context.Background(), ChatCompletionRequest{Model: "test-model"}, nil)
+	var e *CreditsError
+	if !errors.As(err, &e) {
+		t.Fatalf("expected *CreditsError, got %T: %v", err, err)
+	}
+}
@@ -0,0 +1,160 @@
+// Package mock implements the offline "mock" LLM provider. Responses come
+// from a YAML script instead of the network, so demos, documentation
+// recordings, and end-to-end tests run deterministically without an API
+// key.
+//
+// A script is a list of rules. Each rule matches the latest user message
+// with a regular expression and lists the assistant replies for that
+// turn, in order: the first reply answers the user message, the next one
+// answers the results of the first reply's tool calls, and so on.
+//
+//	chunk_delay: 20ms
+//	default: "I don't have a scripted answer for that."
+//	rules:
+//	  - match: "(?i)what does main\\.go do"
+//	    replies:
+//	      - tool_calls:
+//	          - name: read_file
+//	            arguments: {path: main.go}
+//	      - content: "main.go wires up the CLI flags and starts the TUI."
+package mock
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/gavinyap/stormtrooper/internal/llm"
+	"gopkg.in/yaml.v3"
+)
+
+// Script is a parsed mock provider script.
+type Script struct {
+	// ChunkDelay is the pause between streamed chunks, to make demos look
+	// like a live model. Zero streams as fast as possible.
+	ChunkDelay time.Duration `yaml:"chunk_delay"`
+
+	// Default is the reply when no rule matches.
+	Default string `yaml:"default"`
+
+	Rules []Rule `yaml:"rules"`
+}
+
+// Rule maps a user message pattern to the assistant replies for that turn.
+type Rule struct {
+	Match   string  `yaml:"match"`
+	Replies []Reply `yaml:"replies"`
+
+	re *regexp.Regexp
+}
+
+// Reply is one scripted assistant message.
+type Reply struct {
+	Content   string     `yaml:"content"`
+	ToolCalls []ToolCall `yaml:"tool_calls"`
+}
+
+// ToolCall is a scripted tool invocation. Arguments may be written as a
+// YAML mapping; they are sent to the agent as JSON.
+type ToolCall struct {
+	Name      string         `yaml:"name"`
+	Arguments map[string]any `yaml:"arguments"`
+}
+
+// Load reads and validates a script file.
+func Load(path string) (*Script, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse parses and validates a script.
+func Parse(data []byte) (*Script, error) {
+	var s Script
+	if err := yaml.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("invalid mock script: %w", err)
+	}
+	for i := range s.Rules {
+		r := &s.Rules[i]
+		re, err := regexp.Compile(r.Match)
+		if err != nil {
+			return nil, fmt.Errorf("mock script rule %d: invalid match: %w", i+1, err)
+		}
+		r.re = re
+		if len(r.Replies) == 0 {
+			return nil, fmt.Errorf("mock script rule %d: no replies", i+1)
+		}
+		for j, reply := range r.Replies {
+			for _, tc := range reply.ToolCalls {
+				if tc.Name == "" {
+					return nil, fmt.Errorf("mock script rule %d, reply %d: tool call without a name", i+1, j+1)
+				}
+			}
+		}
+	}
+	return &s, nil
+}
+
+// Respond returns the scripted assistant message for a conversation.
+func (s *Script) Respond(messages []llm.Message) llm.Message {
+	user, step := lastUserMessage(messages)
+
+	for _, r := range s.Rules {
+		if !r.re.MatchString(user) {
+			continue
+		}
+		if step >= len(r.Replies) {
+			// The script ran out of replies for this turn; end it rather
+			// than repeating tool calls forever.
+			return llm.Message{Role: "assistant"}
+		}
+		return r.Replies[step].message(step)
+	}
+
+	if step > 0 {
+		return llm.Message{Role: "assistant"}
+	}
+	content := s.Default
+	if content == "" {
+		content = fmt.Sprintf("(mock) No scripted response for: %s", user)
+	}
+	return llm.Message{Role: "assistant", Content: content}
+}
+
+// lastUserMessage returns the latest user message and how many assistant
+// replies have followed it in this turn.
+func lastUserMessage(messages []llm.Message) (string, int) {
+	step := 0
+	for i := len(messages) - 1; i >= 0; i-- {
+		switch messages[i].Role {
+		case "user":
+			return messages[i].Content, step
+		case "assistant":
+			step++
+		}
+	}
+	return "", step
+}
+
+func (r Reply) message(step int) llm.Message {
+	msg := llm.Message{Role: "assistant", Content: r.Content}
+	for i, tc := range r.ToolCalls {
+		args := []byte("{}")
+		if tc.Arguments != nil {
+			args, _ = json.Marshal(tc.Arguments)
+		}
+		msg.ToolCalls = append(msg.ToolCalls, llm.ToolCall{
+			ID:   fmt.Sprintf("mock_%d_%d", step, i),
+			Type: "function",
+			Function: llm.FunctionCall{
+				Name:      tc.Name,
+				Arguments: string(args),
+			},
+		})
+	}
+	return msg
+}
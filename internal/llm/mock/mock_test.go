@@ -0,0 +1,107 @@
+package mock
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gavinyap/stormtrooper/internal/llm"
+)
+
+const testScript = `
+default: "no idea"
+rules:
+  - match: "(?i)read main"
+    replies:
+      - tool_calls:
+          - name: read_file
+            arguments: {path: main.go}
+      - content: "It is the entry point."
+  - match: "hello"
+    replies:
+      - content: "Hi there!"
+`
+
+func user(content string) llm.Message {
+	return llm.Message{Role: "user", Content: content}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   string
+	}{
+		{"invalid yaml", "rules: [", "invalid mock script"},
+		{"bad regexp", "rules:\n  - match: \"(\"\n    replies: [{content: x}]\n", "invalid match"},
+		{"no replies", "rules:\n  - match: x\n", "no replies"},
+		{"unnamed tool", "rules:\n  - match: x\n    replies: [{tool_calls: [{arguments: {}}]}]\n", "without a name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.script))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestRespond_StepsThroughReplies(t *testing.T) {
+	s, err := Parse([]byte(testScript))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	conv := []llm.Message{{Role: "system", Content: "sys"}, user("Please READ MAIN")}
+	first := s.Respond(conv)
+	if len(first.ToolCalls) != 1 {
+		t.Fatalf("expected one tool call, got %+v", first)
+	}
+	tc := first.ToolCalls[0]
+	if tc.Function.Name != "read_file" || tc.Function.Arguments != `{"path":"main.go"}` {
+		t.Errorf("unexpected tool call %+v", tc)
+	}
+	if tc.ID == "" || tc.Type != "function" {
+		t.Errorf("tool call needs an id and type, got %+v", tc)
+	}
+
+	conv = append(conv, first, llm.Message{Role: "tool", ToolCallID: tc.ID, Content: "package main"})
+	second := s.Respond(conv)
+	if second.Content != "It is the entry point." || len(second.ToolCalls) != 0 {
+		t.Errorf("unexpected second reply %+v", second)
+	}
+
+	conv = append(conv, second)
+	if third := s.Respond(conv); third.Content != "" || len(third.ToolCalls) != 0 {
+		t.Errorf("exhausted rule should end the turn, got %+v", third)
+	}
+
+	// A new user message starts the next turn from the first reply.
+	conv = append(conv, user("hello"))
+	if got := s.Respond(conv); got.Content != "Hi there!" {
+		t.Errorf("got %q, want Hi there!", got.Content)
+	}
+}
+
+func TestRespond_Default(t *testing.T) {
+	s, _ := Parse([]byte(testScript))
+	if got := s.Respond([]llm.Message{user("something else")}); got.Content != "no idea" {
+		t.Errorf("got %q, want default reply", got.Content)
+	}
+
+	s, _ = Parse([]byte("rules: []"))
+	got := s.Respond([]llm.Message{user("ping")})
+	if !strings.Contains(got.Content, "No scripted response for: ping") {
+		t.Errorf("got %q", got.Content)
+	}
+}
+
+func TestLoad_ExampleScript(t *testing.T) {
+	s, err := Load("../../../docs/examples/mock-script.yaml")
+	if err != nil {
+		t.Fatalf("example script should load: %v", err)
+	}
+	if got := s.Respond([]llm.Message{user("hello")}); got.Content == "" {
+		t.Error("expected a greeting from the example script")
+	}
+}
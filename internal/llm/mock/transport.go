@@ -0,0 +1,136 @@
+package mock
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gavinyap/stormtrooper/internal/llm"
+)
+
+// Transport returns an http.RoundTripper that answers chat completion
+// requests from the script, in the same wire format as the real API.
+func (s *Script) Transport() http.RoundTripper {
+	return &transport{script: s}
+}
+
+type transport struct {
+	script *Script
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var chatReq llm.ChatCompletionRequest
+	if err := json.NewDecoder(req.Body).Decode(&chatReq); err != nil {
+		return nil, fmt.Errorf("mock provider: decode request: %w", err)
+	}
+	req.Body.Close()
+
+	msg := t.script.Respond(chatReq.Messages)
+
+	resp := &http.Response{
+		StatusCode: http.StatusOK,
+		Status:     "200 OK",
+		Header:     http.Header{},
+		Request:    req,
+	}
+	if !chatReq.Stream {
+		data, err := json.Marshal(llm.ChatCompletionResponse{
+			ID: "mock",
+			Choices: []llm.Choice{{
+				Message:      msg,
+				FinishReason: finishReason(msg),
+			}},
+			Usage: usage(chatReq.Messages, msg),
+		})
+		if err != nil {
+			return nil, err
+		}
+		resp.Header.Set("Content-Type", "application/json")
+		resp.Body = io.NopCloser(bytes.NewReader(data))
+		return resp, nil
+	}
+
+	pr, pw := io.Pipe()
+	go t.stream(req, pw, chatReq, msg)
+	resp.Header.Set("Content-Type", "text/event-stream")
+	resp.Body = pr
+	return resp, nil
+}
+
+// stream writes msg as SSE chunks: content word by word, then each tool
+// call, then the finish reason, and then usage when it was asked for.
+func (t *transport) stream(req *http.Request, w *io.PipeWriter, chatReq llm.ChatCompletionRequest, msg llm.Message) {
+	ctx := req.Context()
+	send := func(chunk llm.ChatCompletionChunk) error {
+		if t.script.ChunkDelay > 0 {
+			select {
+			case <-time.After(t.script.ChunkDelay):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		chunk.ID = "mock"
+		data, err := json.Marshal(chunk)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "data: %s\n\n", data)
+		return err
+	}
+
+	delta := func(d llm.MessageDelta, finish *string) llm.ChatCompletionChunk {
+		return llm.ChatCompletionChunk{Choices: []llm.ChunkChoice{{Delta: d, FinishReason: finish}}}
+	}
+
+	err := send(delta(llm.MessageDelta{Role: "assistant"}, nil))
+	for _, word := range strings.SplitAfter(msg.Content, " ") {
+		if err != nil || word == "" {
+			break
+		}
+		err = send(delta(llm.MessageDelta{Content: word}, nil))
+	}
+	for i, tc := range msg.ToolCalls {
+		if err != nil {
+			break
+		}
+		err = send(delta(llm.MessageDelta{ToolCalls: []llm.ToolCallDelta{{
+			Index:    i,
+			ID:       tc.ID,
+			Type:     tc.Type,
+			Function: tc.Function,
+		}}}, nil))
+	}
+	if err == nil {
+		reason := finishReason(msg)
+		err = send(delta(llm.MessageDelta{}, &reason))
+	}
+	if err == nil && chatReq.StreamOptions != nil && chatReq.StreamOptions.IncludeUsage {
+		err = send(llm.ChatCompletionChunk{Choices: []llm.ChunkChoice{}, Usage: usage(chatReq.Messages, msg)})
+	}
+	if err == nil {
+		_, err = io.WriteString(w, "data: [DONE]\n\n")
+	}
+	w.CloseWithError(err)
+}
+
+// usage estimates the tokens of a request and its reply, as a real
+// provider would report them.
+func usage(history []llm.Message, reply llm.Message) *llm.Usage {
+	u := &llm.Usage{CompletionTokens: llm.EstimateTokens(reply)}
+	for _, m := range history {
+		u.PromptTokens += llm.EstimateTokens(m)
+	}
+	u.TotalTokens = u.PromptTokens + u.CompletionTokens
+	return u
+}
+
+func finishReason(msg llm.Message) string {
+	if len(msg.ToolCalls) > 0 {
+		return "tool_calls"
+	}
+	return "stop"
+}
@@ -0,0 +1,93 @@
+package mock
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gavinyap/stormtrooper/internal/llm"
+)
+
+func newMockClient(t *testing.T, script string) *llm.Client {
+	t.Helper()
+	s, err := Parse([]byte(script))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	client := llm.NewClient("")
+	client.SetTransport(s.Transport())
+	return client
+}
+
+func TestTransport_Stream(t *testing.T) {
+	client := newMockClient(t, testScript)
+
+	var chunks int
+	var usage *llm.Usage
+	msg, err := client.ChatCompletionStream(context.Background(), llm.ChatCompletionRequest{
+		Messages: []llm.Message{user("hello")},
+	}, func(chunk llm.ChatCompletionChunk) {
+		chunks++
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+	})
+	if err != nil {
+		t.Fatalf("stream: %v", err)
+	}
+	if msg.Content != "Hi there!" {
+		t.Errorf("content = %q, want Hi there!", msg.Content)
+	}
+	if chunks < 3 {
+		t.Errorf("expected content to arrive in several chunks, got %d", chunks)
+	}
+	if usage == nil || usage.PromptTokens == 0 || usage.CompletionTokens == 0 {
+		t.Errorf("expected estimated usage in the last chunk, got %+v", usage)
+	}
+}
+
+func TestTransport_StreamToolCalls(t *testing.T) {
+	client := newMockClient(t, testScript)
+
+	msg, err := client.ChatCompletionStream(context.Background(), llm.ChatCompletionRequest{
+		Messages: []llm.Message{user("read main")},
+	}, nil)
+	if err != nil {
+		t.Fatalf("stream: %v", err)
+	}
+	if len(msg.ToolCalls) != 1 || msg.ToolCalls[0].Function.Arguments != `{"path":"main.go"}` {
+		t.Errorf("unexpected tool calls %+v", msg.ToolCalls)
+	}
+}
+
+func TestTransport_NonStreaming(t *testing.T) {
+	client := newMockClient(t, testScript)
+
+	resp, err := client.ChatCompletion(context.Background(), llm.ChatCompletionRequest{
+		Messages: []llm.Message{user("hello")},
+	})
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	if len(resp.Choices) != 1 || resp.Choices[0].Message.Content != "Hi there!" {
+		t.Errorf("unexpected response %+v", resp)
+	}
+	if resp.Choices[0].FinishReason != "stop" {
+		t.Errorf("finish reason = %q", resp.Choices[0].FinishReason)
+	}
+	if resp.Usage == nil || resp.Usage.TotalTokens != resp.Usage.PromptTokens+resp.Usage.CompletionTokens {
+		t.Errorf("usage = %+v", resp.Usage)
+	}
+}
+
+func TestTransport_ChunkDelayHonoursCancel(t *testing.T) {
+	client := newMockClient(t, "chunk_delay: 1h\ndefault: slow")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := client.ChatCompletionStream(ctx, llm.ChatCompletionRequest{
+		Messages: []llm.Message{user("hi")},
+	}, nil); err == nil {
+		t.Error("expected error after cancellation")
+	}
+}
@@ -0,0 +1,260 @@
+package llm
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Tool calling modes; see SetToolCalling.
+const (
+	// ToolCallingAuto sends tools natively and switches to prompt-based
+	// calling for the rest of the session if the server rejects them.
+	ToolCallingAuto = "auto"
+	// ToolCallingNative always sends tools with the tools parameter.
+	ToolCallingNative = "native"
+	// ToolCallingPrompt describes tools in the system prompt and parses
+	// calls out of the reply, for servers or models without tools support.
+	ToolCallingPrompt = "prompt"
+)
+
+// SetToolCalling selects how tools are offered to the model: one of the
+// ToolCalling constants; "" means ToolCallingAuto. It must be called
+// before the client is used.
+func (c *Client) SetToolCalling(mode string) {
+	c.toolCalling = mode
+	c.promptTools.Store(mode == ToolCallingPrompt)
+}
+
+// fallBackToPromptTools reports whether a request that failed with err
+// should be tried again with prompt-based tool calling, and switches the
+// client to it if so.
+func (c *Client) fallBackToPromptTools(req ChatCompletionRequest, err error) bool {
+	if len(req.Tools) == 0 || c.toolCalling == ToolCallingNative || c.promptTools.Load() || !toolsUnsupported(err) {
+		return false
+	}
+	c.promptTools.Store(true)
+	return true
+}
+
+// PromptTools reports whether the client describes tools in the prompt,
+// because it was configured to or fell back to it.
+func (c *Client) PromptTools() bool {
+	return c.promptTools.Load()
+}
+
+// toolsUnsupportedMessages are what servers say when they refuse the
+// tools parameter: Ollama for models without tools support, llama.cpp
+// without --jinja, and vLLM without --enable-auto-tool-choice.
+var toolsUnsupportedMessages = []string{
+	"does not support tools",
+	"does not support tool calling",
+	"does not support function calling",
+	"tools are not supported",
+	"tool calling is not supported",
+	"--jinja",
+	"--enable-auto-tool-choice",
+}
+
+// toolsUnsupported reports whether err is a server refusing the tools
+// parameter with one of toolsUnsupportedMessages. Other errors that
+// mention tools, such as a malformed schema, are left to the caller.
+func toolsUnsupported(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	switch apiErr.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusTooManyRequests:
+		return false
+	}
+	if apiErr.StatusCode < 400 {
+		return false
+	}
+	msg := strings.ToLower(apiErr.Message + " " + apiErr.Body)
+	for _, m := range toolsUnsupportedMessages {
+		if strings.Contains(msg, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// Markers around a tool call in prompt-based tool calling.
+const (
+	toolCallOpen  = "<tool_call>"
+	toolCallClose = "</tool_call>"
+)
+
+var toolCallPattern = regexp.MustCompile(`(?s)<tool_call>\s*(.*?)\s*(?:</tool_call>|$)`)
+
+// promptRequest rewrites req for a server without tools support: the
+// tools are described in the system prompt, and earlier tool calls and
+// results become plain assistant and user messages.
+func promptRequest(req ChatCompletionRequest) ChatCompletionRequest {
+	var messages []Message
+	if len(req.Tools) > 0 {
+		instructions := toolInstructions(req.Tools)
+		if len(req.Messages) > 0 && req.Messages[0].Role == "system" {
+			first := req.Messages[0]
+			first.Content += "\n\n" + instructions
+			messages = append(messages, first)
+			req.Messages = req.Messages[1:]
+		} else {
+			messages = append(messages, Message{Role: "system", Content: instructions})
+		}
+	}
+	for _, m := range req.Messages {
+		switch {
+		case m.Role == "assistant" && len(m.ToolCalls) > 0:
+			var b strings.Builder
+			b.WriteString(m.Content)
+			for _, tc := range m.ToolCalls {
+				call, _ := json.Marshal(struct {
+					Name      string          `json:"name"`
+					Arguments json.RawMessage `json:"arguments"`
+				}{tc.Function.Name, rawArguments(tc.Function.Arguments)})
+				if b.Len() > 0 {
+					b.WriteString("\n")
+				}
+				fmt.Fprintf(&b, "%s\n%s\n%s", toolCallOpen, call, toolCallClose)
+			}
+			messages = append(messages, Message{Role: "assistant", Content: b.String()})
+		case m.Role == "tool":
+			result := fmt.Sprintf("<tool_result name=%q>\n%s\n</tool_result>", m.Name, m.Content)
+			// Results of one turn's calls go back in one message, since
+			// some chat templates require roles to alternate.
+			if last := len(messages) - 1; last >= 0 && messages[last].Role == "user" && strings.HasPrefix(messages[last].Content, "<tool_result") {
+				messages[last].Content += "\n" + result
+				continue
+			}
+			messages = append(messages, Message{Role: "user", Content: result})
+		default:
+			m.ToolCalls, m.ToolCallID, m.Name = nil, "", ""
+			messages = append(messages, m)
+		}
+	}
+	req.Messages = messages
+	req.Tools = nil
+	return req
+}
+
+// toolInstructions tells the model how to call tools without the tools
+// parameter, and lists them.
+func toolInstructions(tools []ToolDef) string {
+	var b strings.Builder
+	b.WriteString("# Tools\n\nYou can call the tools below. To call one, write a block like this, and stop after your last block:\n\n")
+	b.WriteString(toolCallOpen + "\n{\"name\": \"tool_name\", \"arguments\": {\"param\": \"value\"}}\n" + toolCallClose + "\n\n")
+	b.WriteString("You may call several tools in one reply, each in its own block. Their results come back in the next message, inside <tool_result> blocks. If you need no tool, just answer.\n\nAvailable tools:")
+	for _, t := range tools {
+		fmt.Fprintf(&b, "\n\n## %s\n%s\nParameters (JSON Schema): %s", t.Function.Name, t.Function.Description, compactJSON(t.Function.Parameters))
+	}
+	return b.String()
+}
+
+// parsePromptToolCalls splits a reply written under toolInstructions into
+// its text and its tool calls. A block that is not a valid call is left
+// in the text.
+func parsePromptToolCalls(content string) (string, []ToolCall) {
+	var calls []ToolCall
+	text := toolCallPattern.ReplaceAllStringFunc(content, func(block string) string {
+		body := toolCallPattern.FindStringSubmatch(block)[1]
+		var call struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		if err := json.Unmarshal([]byte(body), &call); err != nil || call.Name == "" {
+			return block
+		}
+		args := string(call.Arguments)
+		// Some models write the arguments as a JSON string.
+		var s string
+		if json.Unmarshal(call.Arguments, &s) == nil {
+			args = s
+		}
+		if args == "" || args == "null" {
+			args = "{}"
+		}
+		calls = append(calls, ToolCall{ID: newToolCallID(), Type: "function", Function: FunctionCall{Name: call.Name, Arguments: args}})
+		return ""
+	})
+	return strings.TrimSpace(text), calls
+}
+
+// rawArguments returns a call's arguments as JSON, quoting them if they
+// are not valid JSON.
+func rawArguments(args string) json.RawMessage {
+	if json.Valid([]byte(args)) {
+		return json.RawMessage(args)
+	}
+	quoted, _ := json.Marshal(args)
+	return quoted
+}
+
+func compactJSON(data json.RawMessage) string {
+	var b bytes.Buffer
+	if err := json.Compact(&b, data); err != nil {
+		return string(data)
+	}
+	return b.String()
+}
+
+// toolCallFilter holds back streamed text from the first tool call
+// block on, so the raw calls are not shown as the reply.
+type toolCallFilter struct {
+	text    strings.Builder
+	sent    int
+	stopped bool
+}
+
+// next returns the part of delta to show.
+func (f *toolCallFilter) next(delta string) string {
+	if f.stopped || delta == "" {
+		return ""
+	}
+	f.text.WriteString(delta)
+	s := f.text.String()
+	end := len(s)
+	if i := strings.Index(s, toolCallOpen); i >= 0 {
+		f.stopped, end = true, i
+	} else {
+		// Hold back what may be the start of a block.
+		for k := min(len(toolCallOpen)-1, len(s)); k > 0; k-- {
+			if strings.HasSuffix(s, toolCallOpen[:k]) {
+				end = len(s) - k
+				break
+			}
+		}
+	}
+	if end < f.sent {
+		return ""
+	}
+	out := s[f.sent:end]
+	f.sent = end
+	return out
+}
+
+// fillToolCalls gives tool calls the ID and type some servers leave
+// out, so each result can be matched to its call.
+func fillToolCalls(msg *Message) {
+	for i := range msg.ToolCalls {
+		if msg.ToolCalls[i].ID == "" {
+			msg.ToolCalls[i].ID = newToolCallID()
+		}
+		if msg.ToolCalls[i].Type == "" {
+			msg.ToolCalls[i].Type = "function"
+		}
+	}
+}
+
+func newToolCallID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return "call_" + hex.EncodeToString(b)
+}
@@ -0,0 +1,181 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+var readFileDef = ToolDef{Type: "function", Function: FunctionDef{
+	Name:        "read_file",
+	Description: "Read a file",
+	Parameters:  json.RawMessage(`{"type": "object", "properties": {"path": {"type": "string"}}}`),
+}}
+
+func TestPromptRequest(t *testing.T) {
+	req := promptRequest(ChatCompletionRequest{
+		Model: "llama3",
+		Tools: []ToolDef{readFileDef},
+		Messages: []Message{
+			{Role: "system", Content: "You are helpful."},
+			{Role: "user", Content: "Compare a.go and b.go"},
+			{Role: "assistant", Content: "Reading both.", ToolCalls: []ToolCall{
+				{ID: "1", Type: "function", Function: FunctionCall{Name: "read_file", Arguments: `{"path":"a.go"}`}},
+				{ID: "2", Type: "function", Function: FunctionCall{Name: "read_file", Arguments: `{"path":"b.go"}`}},
+			}},
+			{Role: "tool", ToolCallID: "1", Name: "read_file", Content: "package a"},
+			{Role: "tool", ToolCallID: "2", Name: "read_file", Content: "package b"},
+		},
+	})
+
+	if req.Tools != nil {
+		t.Error("tools parameter still set")
+	}
+	if len(req.Messages) != 4 {
+		t.Fatalf("got %d messages: %+v", len(req.Messages), req.Messages)
+	}
+	system := req.Messages[0].Content
+	if !strings.HasPrefix(system, "You are helpful.\n\n# Tools") || !strings.Contains(system, `## read_file
+Read a file
+Parameters (JSON Schema): {"type":"object","properties":{"path":{"type":"string"}}}`) {
+		t.Errorf("system prompt:\n%s", system)
+	}
+	want := "Reading both.\n<tool_call>\n{\"name\":\"read_file\",\"arguments\":{\"path\":\"a.go\"}}\n</tool_call>\n<tool_call>\n{\"name\":\"read_file\",\"arguments\":{\"path\":\"b.go\"}}\n</tool_call>"
+	if got := req.Messages[2]; got.Role != "assistant" || got.Content != want || got.ToolCalls != nil {
+		t.Errorf("assistant message = %+v", got)
+	}
+	want = "<tool_result name=\"read_file\">\npackage a\n</tool_result>\n<tool_result name=\"read_file\">\npackage b\n</tool_result>"
+	if got := req.Messages[3]; got.Role != "user" || got.Content != want {
+		t.Errorf("tool results = %+v", got)
+	}
+}
+
+func TestParsePromptToolCalls(t *testing.T) {
+	content := "Let me look.\n<tool_call>\n{\"name\": \"read_file\", \"arguments\": {\"path\": \"main.go\"}}\n</tool_call>\n" +
+		"<tool_call>{\"name\": \"glob\", \"arguments\": \"{\\\"pattern\\\":\\\"*.go\\\"}\"}</tool_call>\n" +
+		"<tool_call>not json</tool_call>"
+	text, calls := parsePromptToolCalls(content)
+	if text != "Let me look.\n\n\n<tool_call>not json</tool_call>" {
+		t.Errorf("text = %q", text)
+	}
+	if len(calls) != 2 {
+		t.Fatalf("got %d calls", len(calls))
+	}
+	if calls[0].Function.Name != "read_file" || calls[0].Function.Arguments != `{"path": "main.go"}` || calls[0].ID == "" {
+		t.Errorf("first call = %+v", calls[0])
+	}
+	if calls[1].Function.Name != "glob" || calls[1].Function.Arguments != `{"pattern":"*.go"}` {
+		t.Errorf("second call = %+v", calls[1])
+	}
+
+	// A block cut off by the end of the reply still counts.
+	_, calls = parsePromptToolCalls("<tool_call>\n{\"name\": \"list\"}")
+	if len(calls) != 1 || calls[0].Function.Arguments != "{}" {
+		t.Errorf("unclosed block: %+v", calls)
+	}
+}
+
+func TestToolCallFilter(t *testing.T) {
+	f := &toolCallFilter{}
+	var shown strings.Builder
+	for _, delta := range []string{"I will ", "check <", "b>this</b> <tool", "_call>\n{\"name\"", ": \"x\"}"} {
+		shown.WriteString(f.next(delta))
+	}
+	if shown.String() != "I will check <b>this</b> " {
+		t.Errorf("shown %q", shown.String())
+	}
+}
+
+func TestChatCompletionStream_PromptToolsFallback(t *testing.T) {
+	var requests atomic.Int32
+	var lastBody ChatCompletionRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		var req ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		lastBody = req
+		if len(req.Tools) > 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":{"message":"registry.ollama.ai/library/gemma:latest does not support tools"}}`))
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		chunk, _ := json.Marshal(ChatCompletionChunk{Choices: []ChunkChoice{{Delta: MessageDelta{Content: "Reading it.\n<tool_call>\n{\"name\": \"read_file\", \"arguments\": {\"path\": \"go.mod\"}}\n</tool_call>"}}}})
+		w.Write([]byte("data: " + string(chunk) + "\n\n"))
+	}))
+	defer server.Close()
+
+	client := NewClient("")
+	client.SetBaseURL(server.URL)
+	req := ChatCompletionRequest{Model: "gemma", Tools: []ToolDef{readFileDef}, Messages: []Message{{Role: "user", Content: "What module is this?"}}}
+	var shown strings.Builder
+	msg, err := client.ChatCompletionStream(context.Background(), req, func(chunk ChatCompletionChunk) {
+		shown.WriteString(chunk.Choices[0].Delta.Content)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Content != "Reading it." || len(msg.ToolCalls) != 1 || msg.ToolCalls[0].Function.Arguments != `{"path": "go.mod"}` {
+		t.Errorf("message = %+v", msg)
+	}
+	if shown.String() != "Reading it.\n" {
+		t.Errorf("shown %q", shown.String())
+	}
+	if lastBody.Messages[0].Role != "system" || !strings.Contains(lastBody.Messages[0].Content, "## read_file") {
+		t.Errorf("prompt-based request = %+v", lastBody.Messages)
+	}
+
+	if !client.PromptTools() {
+		t.Error("PromptTools should report the fallback")
+	}
+
+	// Later requests go straight to prompt-based calling.
+	requests.Store(0)
+	if _, err := client.ChatCompletionStream(context.Background(), req, nil); err != nil {
+		t.Fatal(err)
+	}
+	if requests.Load() != 1 {
+		t.Errorf("%d requests after the fallback, want 1", requests.Load())
+	}
+}
+
+func TestChatCompletionStream_NativeToolsNoFallback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":{"message":"model does not support tools"}}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("")
+	client.SetBaseURL(server.URL)
+	client.SetToolCalling(ToolCallingNative)
+	_, err := client.ChatCompletionStream(context.Background(), ChatCompletionRequest{Model: "gemma", Tools: []ToolDef{readFileDef}}, nil)
+	if err == nil || !strings.Contains(err.Error(), "does not support tools") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestToolsUnsupported(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&APIError{StatusCode: 400, Message: "model does not support tools"}, true},
+		{&APIError{StatusCode: 500, Body: "tools param requires --jinja flag"}, true},
+		{&APIError{StatusCode: 400, Message: `"auto" tool choice requires --enable-auto-tool-choice and --tool-call-parser to be set`}, true},
+		{&APIError{StatusCode: 400, Message: "context length exceeded"}, false},
+		{&APIError{StatusCode: 400, Message: "invalid schema for tool read_file"}, false},
+		{&APIError{StatusCode: 422, Body: `{"detail": "tool_choice must be one of none, auto"}`}, false},
+		{&APIError{StatusCode: 401, Message: "invalid key for tool use"}, false},
+		{context.Canceled, false},
+	}
+	for _, tt := range tests {
+		if got := toolsUnsupported(tt.err); got != tt.want {
+			t.Errorf("toolsUnsupported(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package llm
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// Priority orders the requests waiting for a free slot when a client's
+// concurrency is limited.
+type Priority int
+
+const (
+	// PriorityInteractive is for the agent the user is waiting on. It is
+	// the default.
+	PriorityInteractive Priority = iota
+	// PriorityBackground is for work nobody is watching, such as
+	// background sub-agents.
+	PriorityBackground
+)
+
+type priorityKey struct{}
+
+// WithPriority returns a context whose requests queue at priority p.
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+func priorityOf(ctx context.Context) Priority {
+	if p, ok := ctx.Value(priorityKey{}).(Priority); ok && p == PriorityBackground {
+		return p
+	}
+	return PriorityInteractive
+}
+
+// queue limits the requests in flight. A freed slot goes to the oldest
+// interactive waiter, then to the oldest background one.
+type queue struct {
+	mu      sync.Mutex
+	limit   int
+	active  int
+	waiting [2][]chan struct{} // indexed by Priority
+}
+
+// acquire waits for a slot. A nil queue never waits.
+func (q *queue) acquire(ctx context.Context) error {
+	if q == nil {
+		return nil
+	}
+	q.mu.Lock()
+	if q.active < q.limit {
+		q.active++
+		q.mu.Unlock()
+		return nil
+	}
+	p := priorityOf(ctx)
+	ready := make(chan struct{})
+	q.waiting[p] = append(q.waiting[p], ready)
+	q.mu.Unlock()
+
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		if i := slices.Index(q.waiting[p], ready); i >= 0 {
+			q.waiting[p] = slices.Delete(q.waiting[p], i, i+1)
+			return ctx.Err()
+		}
+		// The slot was handed over as we gave up; pass it on.
+		q.releaseLocked()
+		return ctx.Err()
+	}
+}
+
+// release frees a slot taken by acquire.
+func (q *queue) release() {
+	if q == nil {
+		return
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.releaseLocked()
+}
+
+func (q *queue) releaseLocked() {
+	for p := range q.waiting {
+		if len(q.waiting[p]) > 0 {
+			next := q.waiting[p][0]
+			q.waiting[p] = q.waiting[p][1:]
+			close(next) // the slot passes to next; active is unchanged
+			return
+		}
+	}
+	q.active--
+}
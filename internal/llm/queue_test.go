@@ -0,0 +1,118 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestQueue_InteractiveFirst(t *testing.T) {
+	q := &queue{limit: 1}
+	if err := q.acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var order []string
+	var wg sync.WaitGroup
+	wait := func(name string, ctx context.Context) {
+		defer wg.Done()
+		if err := q.acquire(ctx); err != nil {
+			t.Error(err)
+			return
+		}
+		mu.Lock()
+		order = append(order, name)
+		mu.Unlock()
+		q.release()
+	}
+	wg.Add(1)
+	go wait("background", WithPriority(context.Background(), PriorityBackground))
+	waitQueued(t, q, 1)
+	wg.Add(1)
+	go wait("interactive", context.Background())
+	waitQueued(t, q, 2)
+
+	q.release()
+	wg.Wait()
+	if len(order) != 2 || order[0] != "interactive" {
+		t.Errorf("order = %v, want the interactive request first", order)
+	}
+	if q.active != 0 {
+		t.Errorf("active = %d after all releases", q.active)
+	}
+}
+
+func TestQueue_CancelWhileWaiting(t *testing.T) {
+	q := &queue{limit: 1}
+	q.acquire(context.Background())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- q.acquire(ctx) }()
+	waitQueued(t, q, 1)
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("acquire = %v, want context.Canceled", err)
+	}
+
+	q.release()
+	if q.active != 0 || len(q.waiting[PriorityInteractive]) != 0 {
+		t.Errorf("queue not empty: active %d, waiting %d", q.active, len(q.waiting[PriorityInteractive]))
+	}
+}
+
+// waitQueued waits until n requests are waiting for a slot.
+func waitQueued(t *testing.T, q *queue, n int) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		q.mu.Lock()
+		got := len(q.waiting[0]) + len(q.waiting[1])
+		q.mu.Unlock()
+		if got == n {
+			return
+		}
+	}
+	t.Fatalf("timed out waiting for %d queued requests", n)
+}
+
+func TestClient_Concurrency(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		json.NewEncoder(w).Encode(ChatCompletionResponse{Choices: []Choice{{Message: Message{Role: "assistant", Content: "ok"}}}})
+	}))
+	defer server.Close()
+
+	c := NewClient("key")
+	c.SetBaseURL(server.URL)
+	c.SetConcurrency(2)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.ChatCompletion(context.Background(), ChatCompletionRequest{Model: "m"}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if peak.Load() > 2 {
+		t.Errorf("%d requests ran at once, want at most 2", peak.Load())
+	}
+}
@@ -0,0 +1,126 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gavinyap/stormtrooper/internal/metrics"
+)
+
+// Limits on waiting between attempts: the longest backoff, and the
+// longest Retry-After honored. A provider asking for a longer wait gets
+// its error returned instead.
+const (
+	maxRetryDelay  = 30 * time.Second
+	maxRetryAfter  = 2 * time.Minute
+	defaultBackoff = time.Second
+)
+
+// retryStatus lists the responses worth trying again.
+var retryStatus = map[int]bool{
+	http.StatusTooManyRequests:     true,
+	http.StatusInternalServerError: true,
+	http.StatusBadGateway:          true,
+	http.StatusServiceUnavailable:  true,
+	http.StatusGatewayTimeout:      true,
+}
+
+// SetRetry makes requests that fail with a transient error, such as a
+// 429, a 5xx, or a network timeout, be tried up to maxRetries more times,
+// waiting baseDelay, then twice that, and so on, with jitter, or as long
+// as the provider's Retry-After says. baseDelay <= 0 means one second.
+// It must be called before the client is used.
+func (c *Client) SetRetry(maxRetries int, baseDelay time.Duration) {
+	if baseDelay <= 0 {
+		baseDelay = defaultBackoff
+	}
+	c.maxRetries, c.baseDelay = maxRetries, baseDelay
+}
+
+// withRetry calls attempt until it succeeds, fails with an error that
+// is not transient, or runs out of retries.
+func (c *Client) withRetry(ctx context.Context, model string, attempt func() error) error {
+	for n := 0; ; n++ {
+		err := attempt()
+		if err == nil || n >= c.maxRetries || ctx.Err() != nil {
+			return err
+		}
+		delay, reason, ok := c.retryDelay(n, err)
+		if !ok {
+			return err
+		}
+		metrics.LLMRetries.Inc(model, reason)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return err
+		}
+	}
+}
+
+// retryDelay reports whether err, from attempt n (0 for the first), is
+// transient, how long to wait before the next, and the reason for the
+// retry metric.
+func (c *Client) retryDelay(n int, err error) (time.Duration, string, bool) {
+	var apiErr *APIError
+	var freeTier *FreeTierError
+	var netErr net.Error
+	var started *streamStarted
+	var reason string
+	switch {
+	case errors.As(err, &started):
+		return 0, "", false
+	case errors.As(err, &freeTier):
+		// The daily limit will not lift in a few seconds.
+		return 0, "", false
+	case errors.As(err, &apiErr):
+		if !retryStatus[apiErr.StatusCode] {
+			return 0, "", false
+		}
+		if apiErr.RetryAfter > maxRetryAfter {
+			return 0, "", false
+		}
+		if apiErr.RetryAfter > 0 {
+			return apiErr.RetryAfter, strconv.Itoa(apiErr.StatusCode), true
+		}
+		reason = strconv.Itoa(apiErr.StatusCode)
+	case errors.As(err, &netErr), errors.Is(err, io.ErrUnexpectedEOF):
+		reason = "network"
+	default:
+		return 0, "", false
+	}
+
+	// Equal jitter: half the backoff is fixed, half random, so clients
+	// that failed together do not retry together.
+	backoff := maxRetryDelay
+	if n < 16 {
+		backoff = min(c.baseDelay<<n, maxRetryDelay)
+	}
+	return backoff/2 + rand.N(backoff/2+1), reason, true
+}
+
+// streamStarted marks a stream that failed after delivering chunks, which
+// must not be retried.
+type streamStarted struct{ error }
+
+func (e *streamStarted) Unwrap() error { return e.error }
+
+// retryAfter parses a Retry-After header, given in seconds or as a date.
+func retryAfter(h string) time.Duration {
+	if h == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(h); err == nil {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(h); err == nil {
+		return max(time.Until(t), 0)
+	}
+	return 0
+}
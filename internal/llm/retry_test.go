@@ -0,0 +1,235 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// flakyServer fails the first failures requests with status, then
+// answers with a completion (or a stream, when the request asks for one).
+func flakyServer(t *testing.T, failures int, status int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if int(calls.Add(1)) <= failures {
+			w.WriteHeader(status)
+			w.Write([]byte(`{"error":{"message":"try again"}}`))
+			return
+		}
+		var req ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Stream {
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "data: {\"id\":\"1\",\"choices\":[{\"index\":0,\"delta\":{\"role\":\"assistant\",\"content\":\"ok\"},\"finish_reason\":\"stop\"}]}\n\ndata: [DONE]\n")
+			return
+		}
+		json.NewEncoder(w).Encode(ChatCompletionResponse{Choices: []Choice{{Message: Message{Role: "assistant", Content: "ok"}}}})
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func TestChatCompletion_RetriesTransientErrors(t *testing.T) {
+	for _, status := range []int{429, 500, 502, 503, 504} {
+		t.Run(fmt.Sprint(status), func(t *testing.T) {
+			server, calls := flakyServer(t, 2, status)
+			client := NewClient("test-key")
+			client.SetBaseURL(server.URL)
+			client.SetRetry(3, time.Millisecond)
+
+			resp, err := client.ChatCompletion(context.Background(), ChatCompletionRequest{Model: "m"})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resp.Choices[0].Message.Content != "ok" {
+				t.Errorf("content = %q, want ok", resp.Choices[0].Message.Content)
+			}
+			if calls.Load() != 3 {
+				t.Errorf("calls = %d, want 3", calls.Load())
+			}
+		})
+	}
+}
+
+func TestChatCompletion_RetryGivesUp(t *testing.T) {
+	server, calls := flakyServer(t, 10, http.StatusServiceUnavailable)
+	client := NewClient("test-key")
+	client.SetBaseURL(server.URL)
+	client.SetRetry(2, time.Millisecond)
+
+	_, err := client.ChatCompletion(context.Background(), ChatCompletionRequest{Model: "m"})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("err = %v, want the last 503", err)
+	}
+	if calls.Load() != 3 {
+		t.Errorf("calls = %d, want 3 (the request and 2 retries)", calls.Load())
+	}
+}
+
+func TestChatCompletion_NoRetryOnClientError(t *testing.T) {
+	server, calls := flakyServer(t, 1, http.StatusBadRequest)
+	client := NewClient("test-key")
+	client.SetBaseURL(server.URL)
+	client.SetRetry(3, time.Millisecond)
+
+	if _, err := client.ChatCompletion(context.Background(), ChatCompletionRequest{Model: "m"}); err == nil {
+		t.Fatal("expected error for 400 response")
+	}
+	if calls.Load() != 1 {
+		t.Errorf("calls = %d, want 1", calls.Load())
+	}
+}
+
+func TestChatCompletion_NoRetryByDefault(t *testing.T) {
+	server, calls := flakyServer(t, 1, http.StatusServiceUnavailable)
+	client := NewClient("test-key")
+	client.SetBaseURL(server.URL)
+
+	if _, err := client.ChatCompletion(context.Background(), ChatCompletionRequest{Model: "m"}); err == nil {
+		t.Fatal("expected error for 503 response")
+	}
+	if calls.Load() != 1 {
+		t.Errorf("calls = %d, want 1", calls.Load())
+	}
+}
+
+func TestChatCompletion_RetryStopsOnCancel(t *testing.T) {
+	server, calls := flakyServer(t, 10, http.StatusServiceUnavailable)
+	client := NewClient("test-key")
+	client.SetBaseURL(server.URL)
+	client.SetRetry(3, time.Hour)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := client.ChatCompletion(ctx, ChatCompletionRequest{Model: "m"}); err == nil {
+		t.Fatal("expected error")
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("waited %v after the context ended", time.Since(start))
+	}
+	if calls.Load() != 1 {
+		t.Errorf("calls = %d, want 1", calls.Load())
+	}
+}
+
+func TestChatCompletionStream_RetriesBeforeFirstChunk(t *testing.T) {
+	server, calls := flakyServer(t, 1, http.StatusBadGateway)
+	client := NewClient("test-key")
+	client.SetBaseURL(server.URL)
+	client.SetRetry(3, time.Millisecond)
+
+	msg, err := client.ChatCompletionStream(context.Background(), ChatCompletionRequest{Model: "m"}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if msg.Content != "ok" {
+		t.Errorf("content = %q, want ok", msg.Content)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("calls = %d, want 2", calls.Load())
+	}
+}
+
+func TestChatCompletionStream_NoRetryAfterFirstChunk(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"id\":\"1\",\"choices\":[{\"index\":0,\"delta\":{\"content\":\"par\"}}]}\n\n")
+		w.(http.Flusher).Flush()
+		// Drop the connection mid-stream.
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		conn.Close()
+	}))
+	defer server.Close()
+
+	client := NewClient("test-key")
+	client.SetBaseURL(server.URL)
+	client.SetRetry(3, time.Millisecond)
+
+	chunks := 0
+	_, err := client.ChatCompletionStream(context.Background(), ChatCompletionRequest{Model: "m"}, func(ChatCompletionChunk) {
+		chunks++
+	})
+	if err == nil {
+		t.Fatal("expected stream error")
+	}
+	if calls.Load() != 1 || chunks != 1 {
+		t.Errorf("calls = %d, chunks = %d; want 1 and 1", calls.Load(), chunks)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	c := NewClient("k")
+	c.SetRetry(5, 100*time.Millisecond)
+	tests := []struct {
+		name     string
+		n        int
+		err      error
+		min, max time.Duration
+		ok       bool
+	}{
+		{"503 first", 0, &APIError{StatusCode: 503}, 50 * time.Millisecond, 100 * time.Millisecond, true},
+		{"503 third", 2, &APIError{StatusCode: 503}, 200 * time.Millisecond, 400 * time.Millisecond, true},
+		{"capped", 15, &APIError{StatusCode: 500}, maxRetryDelay / 2, maxRetryDelay, true},
+		{"retry-after", 0, &APIError{StatusCode: 429, RetryAfter: 3 * time.Second}, 3 * time.Second, 3 * time.Second, true},
+		{"retry-after too long", 0, &APIError{StatusCode: 429, RetryAfter: time.Hour}, 0, 0, false},
+		{"typed provider error", 0, &ProviderError{APIError: &APIError{StatusCode: 502}}, 50 * time.Millisecond, 100 * time.Millisecond, true},
+		{"free tier", 0, &FreeTierError{APIError: &APIError{StatusCode: 429}}, 0, 0, false},
+		{"unauthorized", 0, &APIError{StatusCode: 401}, 0, 0, false},
+		{"other", 0, errors.New("failed to decode response"), 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, _, ok := c.retryDelay(tt.n, tt.err)
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			if ok && (d < tt.min || d > tt.max) {
+				t.Errorf("delay = %v, want between %v and %v", d, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	if got := retryAfter("7"); got != 7*time.Second {
+		t.Errorf("retryAfter(7) = %v", got)
+	}
+	date := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	if got := retryAfter(date); got < 50*time.Second || got > time.Minute {
+		t.Errorf("retryAfter(%q) = %v, want about a minute", date, got)
+	}
+	if got := retryAfter("soon"); got != 0 {
+		t.Errorf("retryAfter(soon) = %v, want 0", got)
+	}
+}
+
+func TestReadAPIError_RetryAfter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "2")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`{"error":{"message":"overloaded"}}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("test-key")
+	client.SetBaseURL(server.URL)
+	_, err := client.ChatCompletion(context.Background(), ChatCompletionRequest{Model: "m"})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("err = %v, want an APIError", err)
+	}
+	if apiErr.RetryAfter != 2*time.Second {
+		t.Errorf("RetryAfter = %v, want 2s", apiErr.RetryAfter)
+	}
+}
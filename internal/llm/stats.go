@@ -0,0 +1,106 @@
+package llm
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ModelStats is the latency of the streamed requests to one model.
+type ModelStats struct {
+	Model        string
+	Requests     int
+	FirstTokens  int           // requests that produced output, the ones TTFT covers
+	TTFT         time.Duration // total time to first token
+	Duration     time.Duration // total time from sending to the end of the stream
+	OutputTokens int           // reported by the provider, or estimated from the output
+}
+
+// AvgTTFT is the mean time to first token.
+func (m ModelStats) AvgTTFT() time.Duration {
+	if m.FirstTokens == 0 {
+		return 0
+	}
+	return m.TTFT / time.Duration(m.FirstTokens)
+}
+
+// AvgDuration is the mean time a response took.
+func (m ModelStats) AvgDuration() time.Duration {
+	if m.Requests == 0 {
+		return 0
+	}
+	return m.Duration / time.Duration(m.Requests)
+}
+
+// TokensPerSecond is the output rate once tokens start arriving.
+func (m ModelStats) TokensPerSecond() float64 {
+	generating := (m.Duration - m.TTFT).Seconds()
+	if generating <= 0 {
+		return 0
+	}
+	return float64(m.OutputTokens) / generating
+}
+
+// String formats the stats as one line.
+func (m ModelStats) String() string {
+	return fmt.Sprintf("%s: %d requests, first token %s avg, %s per response, %.1f tokens/s",
+		m.Model, m.Requests, roundDuration(m.AvgTTFT()), roundDuration(m.AvgDuration()), m.TokensPerSecond())
+}
+
+func roundDuration(d time.Duration) time.Duration {
+	if d < time.Second {
+		return d.Round(time.Millisecond)
+	}
+	return d.Round(10 * time.Millisecond)
+}
+
+// Stats collects ModelStats per model. The zero value is ready to use,
+// and it is safe for concurrent use.
+type Stats struct {
+	mu      sync.Mutex
+	byModel map[string]*ModelStats
+}
+
+// record adds one request. ttft is zero if no output arrived.
+func (s *Stats) record(model string, ttft, duration time.Duration, tokens int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.byModel == nil {
+		s.byModel = map[string]*ModelStats{}
+	}
+	m := s.byModel[model]
+	if m == nil {
+		m = &ModelStats{Model: model}
+		s.byModel[model] = m
+	}
+	m.Requests++
+	m.Duration += duration
+	if ttft > 0 {
+		m.FirstTokens++
+		m.TTFT += ttft
+		m.OutputTokens += tokens
+	}
+}
+
+// Models returns the stats of every model used, sorted by name.
+func (s *Stats) Models() []ModelStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	models := make([]ModelStats, 0, len(s.byModel))
+	for _, m := range s.byModel {
+		models = append(models, *m)
+	}
+	sort.Slice(models, func(i, j int) bool { return models[i].Model < models[j].Model })
+	return models
+}
+
+// String formats the stats one model per line, or "" if there are none.
+func (s *Stats) String() string {
+	var lines []string
+	for _, m := range s.Models() {
+		lines = append(lines, m.String())
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,65 @@
+package llm
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClient_RecordsStreamStats(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		flusher := w.(http.Flusher)
+		w.Write([]byte("data: {\"choices\":[{\"index\":0,\"delta\":{\"role\":\"assistant\"}}]}\n\n"))
+		flusher.Flush()
+		time.Sleep(30 * time.Millisecond)
+		w.Write([]byte("data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\"hello\"}}]}\n\n"))
+		flusher.Flush()
+		time.Sleep(20 * time.Millisecond)
+		w.Write([]byte("data: {\"choices\":[],\"usage\":{\"prompt_tokens\":10,\"completion_tokens\":40,\"total_tokens\":50}}\n\ndata: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	client := NewClient("key")
+	client.SetBaseURL(server.URL)
+	for i := 0; i < 2; i++ {
+		if _, err := client.ChatCompletionStream(context.Background(), ChatCompletionRequest{Model: "m"}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	models := client.Stats().Models()
+	if len(models) != 1 {
+		t.Fatalf("models = %+v", models)
+	}
+	m := models[0]
+	if m.Model != "m" || m.Requests != 2 || m.OutputTokens != 80 {
+		t.Errorf("stats = %+v", m)
+	}
+	if m.AvgTTFT() < 30*time.Millisecond || m.AvgDuration() < 50*time.Millisecond || m.AvgTTFT() >= m.AvgDuration() {
+		t.Errorf("ttft %v, duration %v", m.AvgTTFT(), m.AvgDuration())
+	}
+	if m.TokensPerSecond() <= 0 {
+		t.Errorf("tokens/s = %v", m.TokensPerSecond())
+	}
+}
+
+func TestStats_String(t *testing.T) {
+	var s Stats
+	if s.String() != "" {
+		t.Errorf("empty stats = %q", s.String())
+	}
+	s.record("b", 500*time.Millisecond, 2500*time.Millisecond, 100)
+	s.record("a", 0, time.Second, 0)
+	want := "a: 1 requests, first token 0s avg, 1s per response, 0.0 tokens/s\n" +
+		"b: 1 requests, first token 500ms avg, 2.5s per response, 50.0 tokens/s"
+	if got := s.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if !strings.HasPrefix(s.Models()[0].String(), "a:") {
+		t.Error("models should be sorted by name")
+	}
+}
@@ -4,7 +4,9 @@ package llm
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"strings"
@@ -13,42 +15,213 @@ import (
 // StreamCallback is called for each parsed chunk from the SSE stream.
 type StreamCallback func(chunk ChatCompletionChunk)
 
+// Event is one server-sent event.
+type Event struct {
+	Type string // the event field; empty means "message"
+	ID   string
+	Data string // data lines joined with "\n"
+}
+
 // ParseSSEStream reads an SSE stream from reader and calls callback for each
 // data chunk. It returns when the stream ends (data: [DONE]) or an error occurs.
+// Lines longer than DefaultMaxLineSize fail with ErrLineTooLong.
+// An "error" event ends the stream with its data as the error.
 func ParseSSEStream(reader io.Reader, callback StreamCallback) error {
-	scanner := bufio.NewScanner(reader)
-	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
-
-	for scanner.Scan() {
-		line := scanner.Text()
+	return parseSSEStream(reader, DefaultMaxLineSize, callback)
+}
 
-		if line == "" {
-			continue
+func parseSSEStream(reader io.Reader, maxLine int, callback StreamCallback) error {
+	err := parseSSEEvents(reader, maxLine, func(ev Event) error {
+		switch ev.Type {
+		case "", "message":
+		case "error":
+			return fmt.Errorf("provider error event: %s", ev.Data)
+		default:
+			return nil // e.g. keep-alive pings
+		}
+		switch ev.Data {
+		case "":
+			return nil
+		case "[DONE]":
+			return errStop
 		}
+		var chunk ChatCompletionChunk
+		err := json.Unmarshal([]byte(ev.Data), &chunk)
+		if err != nil && strings.Contains(ev.Data, "\n") {
+			// Some proxies put several chunks in one event, one per
+			// data line, instead of separating them with blank lines.
+			return parseDataLines(ev.Data, callback)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to parse SSE chunk: %w", err)
+		}
+		callback(chunk)
+		return nil
+	})
+	if err == errStop {
+		return nil
+	}
+	return err
+}
 
-		if !strings.HasPrefix(line, "data: ") {
-			continue
+// parseDataLines parses each line of data as a chunk of its own.
+func parseDataLines(data string, callback StreamCallback) error {
+	for _, line := range strings.Split(data, "\n") {
+		if line == "[DONE]" {
+			return errStop
+		}
+		var chunk ChatCompletionChunk
+		if err := json.Unmarshal([]byte(line), &chunk); err != nil {
+			return fmt.Errorf("failed to parse SSE chunk: %w", err)
 		}
+		callback(chunk)
+	}
+	return nil
+}
 
-		data := strings.TrimPrefix(line, "data: ")
+// errStop ends ParseSSEEvents early without an error.
+var errStop = errors.New("stop")
 
-		if data == "[DONE]" {
+// ParseSSEEvents reads an SSE stream and calls fn for each event, following
+// the grammar of the HTML Living Standard: lines end in LF, CRLF, or CR;
+// lines starting with a colon are comments; the data lines of an event are
+// joined with newlines; and a blank line ends the event. Events without
+// data are not dispatched. A final event not followed by a blank line is
+// still dispatched, since some servers close the stream right after it.
+// Parsing stops at the first error fn returns.
+func ParseSSEEvents(reader io.Reader, fn func(Event) error) error {
+	return parseSSEEvents(reader, DefaultMaxLineSize, fn)
+}
+
+func parseSSEEvents(reader io.Reader, maxLine int, fn func(Event) error) error {
+	lines := newLineReader(reader, maxLine)
+
+	var ev Event
+	var data strings.Builder
+	dataLines := 0
+	dispatch := func() error {
+		defer func() {
+			ev.Type, dataLines = "", 0
+			data.Reset()
+		}()
+		if dataLines == 0 {
 			return nil
 		}
+		ev.Data = data.String()
+		return fn(ev)
+	}
 
-		var chunk ChatCompletionChunk
-		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
-			return fmt.Errorf("failed to parse SSE chunk: %w", err)
+	for {
+		b, err := lines.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("SSE stream read error: %w", err)
 		}
+		line := string(b)
+		if line == "" {
+			if err := dispatch(); err != nil {
+				return err
+			}
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "data":
+			if dataLines > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(value)
+			dataLines++
+		case "event":
+			ev.Type = value
+		case "id":
+			// The ID persists across events until changed.
+			if !strings.ContainsRune(value, 0) {
+				ev.ID = value
+			}
+		case "retry":
+			// Reconnection delay; requests are not resumed, so it is unused.
+		}
+	}
+	return dispatch()
+}
 
-		callback(chunk)
+// DefaultMaxLineSize is the longest SSE line accepted unless the client
+// is configured otherwise. A line holds one whole chunk, and a tool call
+// writing a large file can send its arguments in a single chunk.
+const DefaultMaxLineSize = 64 << 20
+
+// ErrLineTooLong is returned when a stream line exceeds the size limit.
+var ErrLineTooLong = errors.New("SSE line exceeds the size limit")
+
+// lineReader reads lines ending in LF, CRLF, or CR, growing its buffer
+// as needed up to max bytes.
+type lineReader struct {
+	r      *bufio.Reader
+	max    int
+	line   []byte
+	skipLF bool // the last line ended in CR, so a leading LF belongs to it
+}
+
+func formatSize(n int) string {
+	if n >= 1<<20 && n%(1<<20) == 0 {
+		return fmt.Sprintf("%d MB", n>>20)
 	}
+	return fmt.Sprintf("%d bytes", n)
+}
 
-	if err := scanner.Err(); err != nil {
-		return fmt.Errorf("SSE stream read error: %w", err)
+func newLineReader(r io.Reader, max int) *lineReader {
+	if max <= 0 {
+		max = DefaultMaxLineSize
 	}
+	return &lineReader{r: bufio.NewReaderSize(r, 64*1024), max: max}
+}
 
-	return nil
+// next returns the next line without its terminator. A final line
+// without one is returned before io.EOF.
+func (l *lineReader) next() ([]byte, error) {
+	l.line = l.line[:0]
+	for {
+		// Wait for input only when nothing is buffered, so a line
+		// ending in CR is returned without waiting for the next byte.
+		if l.r.Buffered() == 0 {
+			if _, err := l.r.Peek(1); err != nil {
+				if err == io.EOF && len(l.line) > 0 {
+					return l.line, nil
+				}
+				return nil, err
+			}
+		}
+		buf, _ := l.r.Peek(l.r.Buffered())
+		if l.skipLF {
+			l.skipLF = false
+			if buf[0] == '\n' {
+				l.r.Discard(1)
+				continue
+			}
+		}
+		i := bytes.IndexAny(buf, "\r\n")
+		if i < 0 {
+			i = len(buf)
+		}
+		if len(l.line)+i > l.max {
+			return nil, fmt.Errorf("%w of %s", ErrLineTooLong, formatSize(l.max))
+		}
+		l.line = append(l.line, buf[:i]...)
+		if i == len(buf) {
+			l.r.Discard(i)
+			continue
+		}
+		l.skipLF = buf[i] == '\r'
+		l.r.Discard(i + 1)
+		return l.line, nil
+	}
 }
 
 // DeltaAccumulator collects streaming deltas into a final Message.
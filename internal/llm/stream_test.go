@@ -1,8 +1,14 @@
 package llm
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
+	"testing/iotest"
 )
 
 func TestParseSSEStream_TextContent(t *testing.T) {
@@ -88,6 +94,141 @@ func TestParseSSEStream_EmptyStream(t *testing.T) {
 	}
 }
 
+func TestParseSSEEvents_Grammar(t *testing.T) {
+	input := "id: 7\r\nevent: update\r\ndata: first\r\ndata:second\r\n\r\n" +
+		": keep-alive\rdata\r\r" +
+		"retry: 3000\nevent: ignored-without-data\n\n" +
+		"data: {\"a\":\n" +
+		"data:  1}\n\n" +
+		"data: tail"
+
+	var events []Event
+	err := ParseSSEEvents(strings.NewReader(input), func(ev Event) error {
+		events = append(events, ev)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []Event{
+		{Type: "update", ID: "7", Data: "first\nsecond"},
+		{ID: "7", Data: ""},
+		{ID: "7", Data: "{\"a\":\n 1}"},
+		{ID: "7", Data: "tail"},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events %+v, want %d", len(events), events, len(want))
+	}
+	for i := range want {
+		if events[i] != want[i] {
+			t.Errorf("event %d = %+v, want %+v", i, events[i], want[i])
+		}
+	}
+}
+
+func TestParseSSEStream_CRLFAndMultiLineData(t *testing.T) {
+	input := "data: {\"choices\":[{\"index\":0,\r\n" +
+		"data: \"delta\":{\"content\":\"split\"}}]}\r\n\r\n" +
+		"event: ping\r\ndata: {}\r\n\r\n" +
+		"data\r\n\r\n" +
+		"data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\"a\"}}]}\n" +
+		"data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\"b\"}}]}\n\n" +
+		"data: [DONE]\r\n\r\n" +
+		"data: {\"after\": \"done\"}\r\n\r\n"
+
+	var content []string
+	err := ParseSSEStream(strings.NewReader(input), func(chunk ChatCompletionChunk) {
+		content = append(content, chunk.Choices[0].Delta.Content)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(content, "|") != "split|a|b" {
+		t.Errorf("content = %q", content)
+	}
+}
+
+func TestParseSSEStream_ErrorEvent(t *testing.T) {
+	input := "event: error\ndata: {\"message\":\"overloaded\"}\n\n"
+	err := ParseSSEStream(strings.NewReader(input), func(ChatCompletionChunk) {})
+	if err == nil || !strings.Contains(err.Error(), "overloaded") {
+		t.Errorf("expected the error event to end the stream, got %v", err)
+	}
+}
+
+// bigToolCallStream returns a stream whose tool call arguments, n bytes
+// of JSON, arrive in a single chunk.
+func bigToolCallStream(n int) (stream, args string) {
+	argsJSON, _ := json.Marshal(map[string]string{"content": strings.Repeat("x", n)})
+	args = string(argsJSON)
+	chunk, _ := json.Marshal(ChatCompletionChunk{Choices: []ChunkChoice{{Delta: MessageDelta{
+		Role:      "assistant",
+		ToolCalls: []ToolCallDelta{{ID: "call_1", Type: "function", Function: FunctionCall{Name: "write_file", Arguments: args}}},
+	}}}})
+	return "data: " + string(chunk) + "\r\n\r\ndata: [DONE]\r\n\r\n", args
+}
+
+func TestParseSSEStream_MultiMegabyteLine(t *testing.T) {
+	stream, args := bigToolCallStream(5 << 20)
+	acc := NewDeltaAccumulator()
+	if err := ParseSSEStream(strings.NewReader(stream), acc.Add); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	msg := acc.Message()
+	if len(msg.ToolCalls) != 1 || msg.ToolCalls[0].Function.Arguments != args {
+		t.Errorf("tool call arguments were not preserved (%d tool calls)", len(msg.ToolCalls))
+	}
+}
+
+func TestParseSSEStream_LineTooLong(t *testing.T) {
+	stream, _ := bigToolCallStream(2 << 20)
+	err := parseSSEStream(strings.NewReader(stream), 1<<20, func(ChatCompletionChunk) {
+		t.Error("no chunk should be delivered")
+	})
+	if !errors.Is(err, ErrLineTooLong) || !strings.Contains(err.Error(), "1 MB") {
+		t.Errorf("expected ErrLineTooLong naming the limit, got %v", err)
+	}
+}
+
+func TestParseSSEEvents_OneByteReads(t *testing.T) {
+	input := "data: a\r\n\r\ndata: b\r\rdata: c\n\n"
+	var data []string
+	err := ParseSSEEvents(iotest.OneByteReader(strings.NewReader(input)), func(ev Event) error {
+		data = append(data, ev.Data)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(data, "|") != "a|b|c" {
+		t.Errorf("events = %q", data)
+	}
+}
+
+func TestClient_LargeToolCallChunk(t *testing.T) {
+	stream, args := bigToolCallStream(3 << 20)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(stream))
+	}))
+	defer server.Close()
+
+	client := NewClient("key")
+	client.SetBaseURL(server.URL)
+	msg, err := client.ChatCompletionStream(context.Background(), ChatCompletionRequest{Model: "m"}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(msg.ToolCalls) != 1 || msg.ToolCalls[0].Function.Arguments != args {
+		t.Error("tool call arguments were not preserved")
+	}
+
+	client.SetMaxLineSize(1 << 20)
+	if _, err := client.ChatCompletionStream(context.Background(), ChatCompletionRequest{Model: "m"}, nil); !errors.Is(err, ErrLineTooLong) {
+		t.Errorf("expected ErrLineTooLong with a 1 MB limit, got %v", err)
+	}
+}
+
 func TestDeltaAccumulator_TextOnly(t *testing.T) {
 	acc := NewDeltaAccumulator()
 
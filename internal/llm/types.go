@@ -6,10 +6,25 @@ import "encoding/json"
 
 // ChatCompletionRequest is the request body for the chat completions endpoint.
 type ChatCompletionRequest struct {
-	Model    string    `json:"model"`
-	Messages []Message `json:"messages"`
-	Tools    []ToolDef `json:"tools,omitempty"`
-	Stream   bool      `json:"stream,omitempty"`
+	Model     string    `json:"model"`
+	Messages  []Message `json:"messages"`
+	Tools     []ToolDef `json:"tools,omitempty"`
+	Stream    bool      `json:"stream,omitempty"`
+	MaxTokens int       `json:"max_tokens,omitempty"`
+	Stop      []string  `json:"stop,omitempty"`
+
+	// Temperature and TopP are pointers so that 0 is sent; nil leaves
+	// them to the provider.
+	Temperature *float64 `json:"temperature,omitempty"`
+	TopP        *float64 `json:"top_p,omitempty"`
+
+	FrequencyPenalty float64        `json:"frequency_penalty,omitempty"`
+	StreamOptions    *StreamOptions `json:"stream_options,omitempty"`
+}
+
+// StreamOptions configures a streaming request.
+type StreamOptions struct {
+	IncludeUsage bool `json:"include_usage"` // send usage in a final chunk
 }
 
 // Message represents a chat message in the conversation.
@@ -19,6 +34,9 @@ type Message struct {
 	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
 	ToolCallID string     `json:"tool_call_id,omitempty"`
 	Name       string     `json:"name,omitempty"`
+	// Model is the model that wrote an assistant message, when the agent
+	// records it. It is saved with sessions but not sent to the provider.
+	Model string `json:"model,omitempty"`
 }
 
 // ToolCall represents an LLM-requested tool invocation.
@@ -66,12 +84,17 @@ type Usage struct {
 	PromptTokens     int `json:"prompt_tokens"`
 	CompletionTokens int `json:"completion_tokens"`
 	TotalTokens      int `json:"total_tokens"`
+
+	// Cost is what the request cost in US dollars, reported by some
+	// providers such as OpenRouter; nil when not reported.
+	Cost *float64 `json:"cost,omitempty"`
 }
 
 // ChatCompletionChunk is a single chunk from a streaming response.
 type ChatCompletionChunk struct {
 	ID      string        `json:"id"`
 	Choices []ChunkChoice `json:"choices"`
+	Usage   *Usage        `json:"usage,omitempty"` // usually only on the final chunk
 }
 
 // ChunkChoice represents a streaming delta choice.
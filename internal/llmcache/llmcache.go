@@ -0,0 +1,165 @@
+// Package llmcache keeps model responses on disk, keyed by a hash of the
+// request, so a headless run that is started again after failing part
+// way through gets the answers it already paid for without asking the
+// provider again. A request is only answered from the cache when the
+// model, messages, tools, and settings are all identical.
+package llmcache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+)
+
+// Cache is a directory of cached responses.
+type Cache struct {
+	dir    string
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+// entry is one cached response.
+type entry struct {
+	ContentType string `json:"content_type"`
+	Body        string `json:"body"`
+}
+
+// Open returns the cache in dir, creating the directory.
+func Open(dir string) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("response cache: %w", err)
+	}
+	return &Cache{dir: dir}, nil
+}
+
+// DefaultDir returns ~/.stormtrooper/cache/responses.
+func DefaultDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".stormtrooper", "cache", "responses"), nil
+}
+
+// Stats returns how many requests were answered from the cache and how
+// many went to the provider.
+func (c *Cache) Stats() (hits, misses int64) {
+	return c.hits.Load(), c.misses.Load()
+}
+
+// Transport returns an http.RoundTripper for the LLM client that answers
+// from the cache when it can and otherwise sends the request to next,
+// caching a successful response once it has been read to the end.
+func (c *Cache) Transport(next http.RoundTripper) http.RoundTripper {
+	return &transport{c: c, next: next}
+}
+
+type transport struct {
+	c    *Cache
+	next http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("response cache: read request body: %w", err)
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	path := filepath.Join(t.c.dir, key(req, body)+".json")
+
+	if data, err := os.ReadFile(path); err == nil {
+		var e entry
+		if json.Unmarshal(data, &e) == nil {
+			t.c.hits.Add(1)
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Status:     "200 OK",
+				Header:     http.Header{"Content-Type": []string{e.ContentType}},
+				Body:       io.NopCloser(strings.NewReader(e.Body)),
+				Request:    req,
+			}, nil
+		}
+	}
+
+	t.c.misses.Add(1)
+	resp, err := t.next.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return resp, err
+	}
+	contentType := resp.Header.Get("Content-Type")
+	resp.Body = &teeBody{rc: resp.Body, onEOF: func(data []byte) {
+		// A stream that ended without [DONE] broke off, perhaps with an
+		// error event.
+		if strings.HasPrefix(contentType, "text/event-stream") && !bytes.Contains(data, []byte("data: [DONE]")) {
+			return
+		}
+		save(path, entry{ContentType: contentType, Body: string(data)})
+	}}
+	return resp, nil
+}
+
+// key hashes the endpoint, host included so that providers with the same
+// API do not share answers, and the request body, compacted so that
+// formatting does not matter.
+func key(req *http.Request, body []byte) string {
+	var buf bytes.Buffer
+	if json.Compact(&buf, body) != nil {
+		buf.Reset()
+		buf.Write(body)
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s://%s%s\n", req.Method, req.URL.Scheme, req.URL.Host, req.URL.Path)
+	h.Write(buf.Bytes())
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// save writes e to path through a temporary file, so a crash cannot
+// leave a truncated entry behind.
+func save(path string, e entry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// teeBody copies everything read from rc and hands it to onEOF once rc
+// has been read to the end; a response cut short is not cached.
+type teeBody struct {
+	rc    io.ReadCloser
+	buf   bytes.Buffer
+	done  bool
+	onEOF func([]byte)
+}
+
+func (b *teeBody) Read(p []byte) (int, error) {
+	n, err := b.rc.Read(p)
+	b.buf.Write(p[:n])
+	if errors.Is(err, io.EOF) && !b.done {
+		b.done = true
+		b.onEOF(b.buf.Bytes())
+	}
+	return n, err
+}
+
+func (b *teeBody) Close() error {
+	return b.rc.Close()
+}
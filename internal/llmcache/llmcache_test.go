@@ -0,0 +1,109 @@
+package llmcache
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func post(t *testing.T, client *http.Client, url, body string) string {
+	t.Helper()
+	resp, err := client.Post(url, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	return string(data)
+}
+
+func TestTransport(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		body, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(body), "broken") {
+			w.Header().Set("Content-Type", "text/event-stream")
+			io.WriteString(w, "data: {\"error\":\"overloaded\"}\n\n")
+			return
+		}
+		if strings.Contains(string(body), "fail") {
+			http.Error(w, "rate limited", http.StatusTooManyRequests)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, "data: {\"n\":"+string(rune('0'+n))+"}\n\ndata: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	c, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: c.Transport(http.DefaultTransport)}
+
+	first := post(t, client, server.URL+"/chat/completions", `{"model":"m","messages":[{"role":"user","content":"hi"}]}`)
+	// The same request, formatted differently, is answered from the cache.
+	again := post(t, client, server.URL+"/chat/completions", `{"model": "m", "messages": [{"role": "user", "content": "hi"}]}`)
+	if again != first || calls.Load() != 1 {
+		t.Errorf("expected a cached response, got %q after %d calls", again, calls.Load())
+	}
+	// A different model is a different request.
+	post(t, client, server.URL+"/chat/completions", `{"model":"other","messages":[{"role":"user","content":"hi"}]}`)
+	if calls.Load() != 2 {
+		t.Errorf("expected a new call for another model, got %d calls", calls.Load())
+	}
+
+	// Errors and streams that break off are not cached.
+	for _, body := range []string{`{"model":"m","messages":"fail"}`, `{"model":"m","messages":"broken"}`} {
+		before := calls.Load()
+		post(t, client, server.URL+"/chat/completions", body)
+		post(t, client, server.URL+"/chat/completions", body)
+		if calls.Load() != before+2 {
+			t.Errorf("expected %s not to be cached", body)
+		}
+	}
+
+	if hits, misses := c.Stats(); hits != 1 || misses != 6 {
+		t.Errorf("Stats() = %d hits, %d misses; want 1, 6", hits, misses)
+	}
+
+	// The cache outlives the process.
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 {
+		t.Errorf("expected 2 cached responses, got %d", len(entries))
+	}
+	c2, _ := Open(dir)
+	client2 := &http.Client{Transport: c2.Transport(http.DefaultTransport)}
+	if got := post(t, client2, server.URL+"/chat/completions", `{"model":"m","messages":[{"role":"user","content":"hi"}]}`); got != first {
+		t.Errorf("expected the cached response after reopening, got %q", got)
+	}
+}
+
+func TestTransport_KeyedByHost(t *testing.T) {
+	var calls atomic.Int32
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		io.WriteString(w, `{"host":"`+r.Host+`"}`)
+	})
+	a, b := httptest.NewServer(handler), httptest.NewServer(handler)
+	defer a.Close()
+	defer b.Close()
+
+	c, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: c.Transport(http.DefaultTransport)}
+	body := `{"model":"m","messages":[{"role":"user","content":"hi"}]}`
+	fromA := post(t, client, a.URL+"/chat/completions", body)
+	fromB := post(t, client, b.URL+"/chat/completions", body)
+	if fromA == fromB || calls.Load() != 2 {
+		t.Errorf("another provider was answered from the cache: %q, %q after %d calls", fromA, fromB, calls.Load())
+	}
+}
@@ -0,0 +1,95 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// freshCounter returns an unregistered counter so tests do not depend on
+// each other.
+func freshCounter(labels ...string) *Counter {
+	return &Counter{name: "test_total", help: "Test counter.", labels: labels, values: make(map[string]float64)}
+}
+
+func TestCounter(t *testing.T) {
+	c := freshCounter("tool", "outcome")
+	c.Inc("read_file", "ok")
+	c.Inc("read_file", "ok")
+	c.Add(3, "shell_exec", "error")
+	c.Add(-1, "shell_exec", "error") // ignored
+
+	if got := c.Value("read_file", "ok"); got != 2 {
+		t.Errorf("read_file ok = %v, want 2", got)
+	}
+
+	var buf bytes.Buffer
+	c.write(&buf)
+	want := `# HELP test_total Test counter.
+# TYPE test_total counter
+test_total{tool="read_file",outcome="ok"} 2
+test_total{tool="shell_exec",outcome="error"} 3
+`
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestCounter_EscapesLabels(t *testing.T) {
+	c := freshCounter("model")
+	c.Inc("a\"b\\c\nd")
+
+	var buf bytes.Buffer
+	c.write(&buf)
+	if !strings.Contains(buf.String(), `test_total{model="a\"b\\c\nd"} 1`) {
+		t.Errorf("label not escaped:\n%s", buf.String())
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	h := &Histogram{name: "lat_seconds", help: "Latency.", labels: []string{"tool"}, buckets: []float64{0.1, 1}, series: make(map[string]*histogramSeries)}
+	h.Observe(0.05, "grep")
+	h.Observe(0.5, "grep")
+	h.Observe(5, "grep")
+
+	if h.Count("grep") != 3 || h.Count("glob") != 0 {
+		t.Errorf("unexpected counts: grep=%d glob=%d", h.Count("grep"), h.Count("glob"))
+	}
+
+	var buf bytes.Buffer
+	h.write(&buf)
+	for _, want := range []string{
+		`lat_seconds_bucket{tool="grep",le="0.1"} 1`,
+		`lat_seconds_bucket{tool="grep",le="1"} 2`,
+		`lat_seconds_bucket{tool="grep",le="+Inf"} 3`,
+		`lat_seconds_sum{tool="grep"} 5.55`,
+		`lat_seconds_count{tool="grep"} 3`,
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("missing %q in:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	LLMRequests.Inc("handler-test-model")
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("unexpected content type %q", ct)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{
+		"# TYPE stormtrooper_llm_requests_total counter",
+		`stormtrooper_llm_requests_total{model="handler-test-model"}`,
+		"# TYPE stormtrooper_tool_duration_seconds histogram",
+		"# TYPE stormtrooper_permission_denials_total counter",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("missing %q in:\n%s", want, body)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gavinyap/stormtrooper/internal/config"
+)
+
+// email sends reports over SMTP.
+type email struct {
+	cfg config.EmailConfig
+	// sendMail is smtp.SendMail, replaced in tests.
+	sendMail func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+func newEmail(cfg config.EmailConfig) *email {
+	if cfg.Password == "" {
+		cfg.Password = os.Getenv("SMTP_PASSWORD")
+	}
+	return &email{cfg: cfg, sendMail: smtp.SendMail}
+}
+
+func (e *email) send(ctx context.Context, r Report) error {
+	var auth smtp.Auth
+	if e.cfg.Username != "" {
+		host, _, _ := net.SplitHostPort(e.cfg.SMTP)
+		auth = smtp.PlainAuth("", e.cfg.Username, e.cfg.Password, host)
+	}
+	// smtp.SendMail takes no context; give up waiting when ctx ends.
+	done := make(chan error, 1)
+	go func() { done <- e.sendMail(e.cfg.SMTP, auth, e.cfg.From, e.cfg.To, e.message(r)) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("email: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("email: %w", ctx.Err())
+	}
+}
+
+// message builds a MIME message with the excerpt as the body and the
+// full report attached.
+func (e *email) message(r Report) []byte {
+	var boundary [12]byte
+	rand.Read(boundary[:])
+	b := hex.EncodeToString(boundary[:])
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", e.cfg.From)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(e.cfg.To, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", r.Subject()))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: multipart/mixed; boundary=%q\r\n\r\n", b)
+
+	fmt.Fprintf(&buf, "--%s\r\n", b)
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+	body := fmt.Sprintf("%s\n\n%s\n\nThe full report is attached.\n", r.Subject(), r.excerpt(4000))
+	writeBase64(&buf, body)
+
+	fmt.Fprintf(&buf, "--%s\r\n", b)
+	buf.WriteString("Content-Type: text/markdown; charset=utf-8\r\n")
+	fmt.Fprintf(&buf, "Content-Disposition: attachment; filename=%q\r\n", reportFile)
+	buf.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+	writeBase64(&buf, r.Markdown())
+	fmt.Fprintf(&buf, "--%s--\r\n", b)
+	return buf.Bytes()
+}
+
+// writeBase64 writes s base64-encoded in 76-character lines.
+func writeBase64(buf *bytes.Buffer, s string) {
+	enc := base64.StdEncoding.EncodeToString([]byte(s))
+	for len(enc) > 76 {
+		buf.WriteString(enc[:76] + "\r\n")
+		enc = enc[76:]
+	}
+	buf.WriteString(enc + "\r\n")
+}
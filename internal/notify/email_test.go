@@ -0,0 +1,89 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"testing"
+
+	"github.com/gavinyap/stormtrooper/internal/config"
+)
+
+func TestEmail(t *testing.T) {
+	t.Setenv("SMTP_PASSWORD", "hunter2")
+	e := newEmail(config.EmailConfig{
+		SMTP:     "smtp.example.com:587",
+		Username: "bot",
+		From:     "bot@example.com",
+		To:       []string{"a@example.com", "b@example.com"},
+	})
+	var gotAddr string
+	var gotAuth smtp.Auth
+	var gotTo []string
+	var gotMsg []byte
+	e.sendMail = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		gotAddr, gotAuth, gotTo, gotMsg = addr, a, to, msg
+		return nil
+	}
+	if err := e.send(context.Background(), failedReport()); err != nil {
+		t.Fatal(err)
+	}
+	if gotAddr != "smtp.example.com:587" || gotAuth == nil || len(gotTo) != 2 {
+		t.Errorf("sendMail(%q, %v, %v)", gotAddr, gotAuth, gotTo)
+	}
+	if e.cfg.Password != "hunter2" {
+		t.Error("the password should default to $SMTP_PASSWORD")
+	}
+
+	msg, err := mail.ReadMessage(bytes.NewReader(gotMsg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject, _ := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if subject != "stormtrooper audit-deps failed in /src/app" {
+		t.Errorf("Subject = %q", subject)
+	}
+	_, params, _ := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	var parts []string
+	var attachment string
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts = append(parts, p.Header.Get("Content-Type"))
+		if p.FileName() == reportFile {
+			data, _ := io.ReadAll(p)
+			attachment = string(data)
+		}
+	}
+	if len(parts) != 2 {
+		t.Fatalf("expected a body and an attachment, got %v", parts)
+	}
+	// multipart.Reader decodes quoted-printable but not base64.
+	report, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(attachment, "\r\n", ""))
+	if err != nil || !strings.Contains(string(report), "\n## Post-mortem") {
+		t.Errorf("attachment = %q, %v", report, err)
+	}
+}
+
+func TestEmail_NoAuthWithoutUsername(t *testing.T) {
+	e := newEmail(config.EmailConfig{SMTP: "localhost:25", From: "a@b", To: []string{"c@d"}})
+	e.sendMail = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		if a != nil {
+			t.Error("expected no authentication")
+		}
+		return nil
+	}
+	e.send(context.Background(), failedReport())
+}
@@ -0,0 +1,114 @@
+// Package notify reports how a headless run ended, so scheduled and
+// unattended jobs do not fail silently. Reports go to webhooks (Slack,
+// Discord, or generic JSON) and by email, with the run's output or
+// post-mortem attached.
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gavinyap/stormtrooper/internal/config"
+)
+
+// Report describes a finished headless run.
+type Report struct {
+	Command  string // what ran, e.g. `-p "bump the Go version"` or "audit-deps"
+	Dir      string // working directory
+	Err      error  // nil if the run succeeded
+	Duration time.Duration
+	// Output is the final response or the workflow's report.
+	Output string
+	// PostMortem is the triage report of a failed run, in markdown.
+	PostMortem string
+}
+
+// Succeeded reports whether the run succeeded.
+func (r Report) Succeeded() bool { return r.Err == nil }
+
+// Subject is a one-line summary, used as the email subject and the first
+// line of webhook messages.
+func (r Report) Subject() string {
+	status := "succeeded"
+	if !r.Succeeded() {
+		status = "failed"
+	}
+	return fmt.Sprintf("stormtrooper %s %s in %s", r.Command, status, r.Dir)
+}
+
+// Markdown is the full report, sent as the attachment.
+func (r Report) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", r.Subject())
+	fmt.Fprintf(&b, "- Duration: %s\n", r.Duration.Round(time.Second))
+	if r.Err != nil {
+		fmt.Fprintf(&b, "- Error: %v\n", r.Err)
+	}
+	if strings.TrimSpace(r.Output) != "" {
+		fmt.Fprintf(&b, "\n## Output\n\n%s\n", strings.TrimSpace(r.Output))
+	}
+	if r.PostMortem != "" {
+		// Demote the post-mortem's headings under this report's.
+		b.WriteString("\n" + strings.ReplaceAll("\n"+strings.TrimSpace(r.PostMortem), "\n#", "\n##") + "\n")
+	}
+	return b.String()
+}
+
+// excerpt returns the start of the output, or the error, for messages
+// that cannot carry the full report.
+func (r Report) excerpt(n int) string {
+	text := strings.TrimSpace(r.Output)
+	if r.Err != nil {
+		text = r.Err.Error()
+	}
+	if len(text) > n {
+		text = strings.ToValidUTF8(text[:n], "") + "…"
+	}
+	return text
+}
+
+// sender delivers a report to one destination.
+type sender interface {
+	send(ctx context.Context, r Report) error
+}
+
+// Notifier sends reports to every configured destination.
+type Notifier struct {
+	onlyFailures bool
+	senders      []sender
+}
+
+// New creates a Notifier from cfg. It returns nil when nothing is
+// configured; a nil Notifier sends nothing.
+func New(cfg config.NotifyConfig) *Notifier {
+	n := &Notifier{onlyFailures: cfg.On == "failure"}
+	for _, w := range cfg.Webhooks {
+		n.senders = append(n.senders, newWebhook(w))
+	}
+	if cfg.Email.SMTP != "" {
+		n.senders = append(n.senders, newEmail(cfg.Email))
+	}
+	if len(n.senders) == 0 {
+		return nil
+	}
+	return n
+}
+
+// Notify sends r to every destination, unless only failures are
+// reported and the run succeeded. It tries them all and returns their
+// errors joined.
+func (n *Notifier) Notify(ctx context.Context, r Report) error {
+	if n == nil || (n.onlyFailures && r.Succeeded()) {
+		return nil
+	}
+	var errs []error
+	for _, s := range n.senders {
+		if err := s.send(ctx, r); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,85 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gavinyap/stormtrooper/internal/config"
+)
+
+func failedReport() Report {
+	return Report{
+		Command:    "audit-deps",
+		Dir:        "/src/app",
+		Err:        errors.New("exit status 1"),
+		Duration:   90 * time.Second,
+		Output:     "2 modules need updates",
+		PostMortem: "# Post-mortem\n\n## Last steps\n\n1. shell_exec go build",
+	}
+}
+
+func TestReport_Markdown(t *testing.T) {
+	r := failedReport()
+	if got := r.Subject(); got != "stormtrooper audit-deps failed in /src/app" {
+		t.Errorf("Subject = %q", got)
+	}
+	md := r.Markdown()
+	for _, want := range []string{"- Duration: 1m30s", "- Error: exit status 1", "## Output\n\n2 modules need updates", "\n## Post-mortem", "\n### Last steps"} {
+		if !strings.Contains(md, want) {
+			t.Errorf("report missing %q:\n%s", want, md)
+		}
+	}
+}
+
+func TestNew_NothingConfigured(t *testing.T) {
+	n := New(config.NotifyConfig{On: "failure"})
+	if n != nil {
+		t.Fatalf("expected nil, got %+v", n)
+	}
+	if err := n.Notify(context.Background(), failedReport()); err != nil {
+		t.Errorf("a nil Notifier should do nothing, got %v", err)
+	}
+}
+
+func TestNotifier_OnlyFailures(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { hits.Add(1) }))
+	defer srv.Close()
+	n := New(config.NotifyConfig{On: "failure", Webhooks: []config.WebhookConfig{{URL: srv.URL}}})
+
+	ok := failedReport()
+	ok.Err = nil
+	n.Notify(context.Background(), ok)
+	if hits.Load() != 0 {
+		t.Error("a successful run should not be reported")
+	}
+	n.Notify(context.Background(), failedReport())
+	if hits.Load() != 1 {
+		t.Error("a failed run should be reported")
+	}
+}
+
+func TestNotifier_TriesEveryDestination(t *testing.T) {
+	var hits atomic.Int32
+	good := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { hits.Add(1) }))
+	defer good.Close()
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "gone", http.StatusGone)
+	}))
+	defer bad.Close()
+
+	n := New(config.NotifyConfig{Webhooks: []config.WebhookConfig{{URL: bad.URL + "/secret"}, {URL: good.URL}}})
+	err := n.Notify(context.Background(), failedReport())
+	if err == nil || !strings.Contains(err.Error(), "410") {
+		t.Errorf("expected the failing webhook's error, got %v", err)
+	}
+	if hits.Load() != 1 {
+		t.Error("the second webhook should still be called")
+	}
+}
@@ -0,0 +1,118 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gavinyap/stormtrooper/internal/config"
+)
+
+// reportFile is the name of the attached report.
+const reportFile = "stormtrooper-report.md"
+
+// webhook posts reports to a URL.
+type webhook struct {
+	http   *http.Client
+	url    string
+	format string
+}
+
+func newWebhook(cfg config.WebhookConfig) *webhook {
+	w := &webhook{http: &http.Client{Timeout: 30 * time.Second}, url: cfg.URL, format: cfg.Format}
+	if w.format == "" {
+		w.format = formatFor(cfg.URL)
+	}
+	return w
+}
+
+// formatFor guesses the payload format from a webhook URL.
+func formatFor(url string) string {
+	switch {
+	case strings.Contains(url, "hooks.slack.com/"):
+		return "slack"
+	case strings.Contains(url, "discord.com/api/webhooks/"), strings.Contains(url, "discordapp.com/api/webhooks/"):
+		return "discord"
+	}
+	return "json"
+}
+
+func (w *webhook) send(ctx context.Context, r Report) error {
+	var body io.Reader
+	var contentType string
+	switch w.format {
+	case "slack":
+		// Incoming webhooks cannot upload files, so Slack gets the
+		// start of the report in the message itself.
+		text := fmt.Sprintf("%s %s\n```%s```", icon(r), r.Subject(), r.excerpt(2500))
+		data, _ := json.Marshal(map[string]string{"text": text})
+		body, contentType = bytes.NewReader(data), "application/json"
+	case "discord":
+		// Discord caps content at 2,000 characters; the full report
+		// goes along as a file.
+		content := fmt.Sprintf("%s %s\n```%s```", icon(r), r.Subject(), r.excerpt(1500))
+		payload, _ := json.Marshal(map[string]string{"content": content})
+		var buf bytes.Buffer
+		mw := multipart.NewWriter(&buf)
+		mw.WriteField("payload_json", string(payload))
+		fw, _ := mw.CreateFormFile("files[0]", reportFile)
+		io.WriteString(fw, r.Markdown())
+		mw.Close()
+		body, contentType = &buf, mw.FormDataContentType()
+	default:
+		payload := map[string]any{
+			"event":            "run.succeeded",
+			"command":          r.Command,
+			"dir":              r.Dir,
+			"duration_seconds": r.Duration.Seconds(),
+			"output":           r.Output,
+			"report":           r.Markdown(),
+		}
+		if r.Err != nil {
+			payload["event"] = "run.failed"
+			payload["error"] = r.Err.Error()
+		}
+		data, _ := json.Marshal(payload)
+		body, contentType = bytes.NewReader(data), "application/json"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, body)
+	if err != nil {
+		return fmt.Errorf("webhook: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	resp, err := w.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("webhook %s: %s: %s", redactURL(w.url), resp.Status, strings.TrimSpace(string(data)))
+	}
+	return nil
+}
+
+func icon(r Report) string {
+	if r.Succeeded() {
+		return "✅"
+	}
+	return "❌"
+}
+
+// redactURL drops the path of a webhook URL for error messages, since
+// Slack and Discord webhook paths are secrets.
+func redactURL(url string) string {
+	if i := strings.Index(url, "://"); i >= 0 {
+		if j := strings.IndexByte(url[i+3:], '/'); j >= 0 {
+			return url[:i+3+j] + "/…"
+		}
+	}
+	return url
+}
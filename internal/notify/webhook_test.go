@@ -0,0 +1,99 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gavinyap/stormtrooper/internal/config"
+)
+
+// capture serves one webhook request and keeps it.
+func capture(t *testing.T) (*httptest.Server, *http.Request, *[]byte) {
+	t.Helper()
+	var req http.Request
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req = *r
+		body, _ = io.ReadAll(r.Body)
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/") {
+			// Keep the parsed form for the caller.
+			r.Body = io.NopCloser(strings.NewReader(string(body)))
+			r.ParseMultipartForm(1 << 20)
+			req = *r
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &req, &body
+}
+
+func TestWebhook_Slack(t *testing.T) {
+	srv, _, body := capture(t)
+	w := newWebhook(config.WebhookConfig{URL: srv.URL, Format: "slack"})
+	if err := w.send(context.Background(), failedReport()); err != nil {
+		t.Fatal(err)
+	}
+	var msg map[string]string
+	json.Unmarshal(*body, &msg)
+	if !strings.HasPrefix(msg["text"], "❌ stormtrooper audit-deps failed") || !strings.Contains(msg["text"], "exit status 1") {
+		t.Errorf("text = %q", msg["text"])
+	}
+}
+
+func TestWebhook_DiscordAttachesReport(t *testing.T) {
+	srv, req, _ := capture(t)
+	w := newWebhook(config.WebhookConfig{URL: srv.URL, Format: "discord"})
+	if err := w.send(context.Background(), failedReport()); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(req.FormValue("payload_json"), "audit-deps failed") {
+		t.Errorf("payload_json = %q", req.FormValue("payload_json"))
+	}
+	file, header, err := req.FormFile("files[0]")
+	if err != nil {
+		t.Fatalf("expected an attached report: %v", err)
+	}
+	data, _ := io.ReadAll(file)
+	if header.Filename != reportFile || !strings.Contains(string(data), "## Post-mortem") {
+		t.Errorf("attachment %s = %q", header.Filename, data)
+	}
+}
+
+func TestWebhook_JSON(t *testing.T) {
+	srv, _, body := capture(t)
+	w := newWebhook(config.WebhookConfig{URL: srv.URL})
+	if err := w.send(context.Background(), failedReport()); err != nil {
+		t.Fatal(err)
+	}
+	var payload map[string]any
+	json.Unmarshal(*body, &payload)
+	if payload["event"] != "run.failed" || payload["error"] != "exit status 1" || payload["duration_seconds"] != 90.0 {
+		t.Errorf("payload = %v", payload)
+	}
+	if !strings.Contains(payload["report"].(string), "## Output") {
+		t.Errorf("report = %q", payload["report"])
+	}
+}
+
+func TestFormatFor(t *testing.T) {
+	cases := map[string]string{
+		"https://hooks.slack.com/services/T0/B0/xyz": "slack",
+		"https://discord.com/api/webhooks/123/abc":   "discord",
+		"https://ci.example.com/hooks/stormtrooper":  "json",
+	}
+	for url, want := range cases {
+		if got := formatFor(url); got != want {
+			t.Errorf("formatFor(%q) = %q, want %q", url, got, want)
+		}
+	}
+}
+
+func TestRedactURL(t *testing.T) {
+	if got := redactURL("https://hooks.slack.com/services/T0/B0/xyz"); got != "https://hooks.slack.com/…" {
+		t.Errorf("got %q", got)
+	}
+}
@@ -0,0 +1,36 @@
+package permission
+
+import "strings"
+
+// ANSI colors of diff lines in prompts.
+const (
+	ansiGreen = "\x1b[32m"
+	ansiRed   = "\x1b[31m"
+	ansiCyan  = "\x1b[36m"
+	ansiReset = "\x1b[0m"
+)
+
+// colorDiff colors the lines of a unified diff in text by kind: from the
+// diff's "---" and "+++" header on, or from the start if inDiff is set,
+// as for a single hunk.
+func colorDiff(text string, inDiff bool) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		color := ""
+		switch {
+		case strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ "):
+			inDiff = true
+		case !inDiff, strings.HasPrefix(line, "+++ "):
+		case strings.HasPrefix(line, "+"):
+			color = ansiGreen
+		case strings.HasPrefix(line, "-"):
+			color = ansiRed
+		case strings.HasPrefix(line, "@@"):
+			color = ansiCyan
+		}
+		if color != "" {
+			lines[i] = color + line + ansiReset
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -4,23 +4,39 @@ package permission
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"io"
 	"os"
 	"strings"
+
+	"github.com/gavinyap/stormtrooper/internal/i18n"
 )
 
 // Handler is the interface for permission checking.
 // The REPL uses Checker (reads stdin), the TUI uses its own implementation.
+// ctx is that of the tool call; a handler waiting for an answer denies
+// the call once it is done.
 type Handler interface {
-	Check(toolName string, preview string) bool
+	Check(ctx context.Context, toolName string, preview string) bool
+}
+
+// HunkReviewer is an optional interface for handlers that can approve a
+// change to a file one hunk at a time. Handlers without it approve the
+// whole change with Check.
+type HunkReviewer interface {
+	// ReviewHunks asks about each hunk of the change toolName wants to
+	// make to path and reports which were accepted.
+	ReviewHunks(ctx context.Context, toolName, path string, hunks []string) []bool
 }
 
 // Checker handles permission prompts for tool execution.
 // It implements the Handler interface.
 type Checker struct {
-	in  io.Reader
-	out io.Writer
+	in         io.Reader
+	out        io.Writer
+	accessible bool
+	color      bool
 }
 
 // NewChecker creates a Checker that reads from stdin and writes to stderr.
@@ -36,11 +52,39 @@ func NewCheckerWithIO(in io.Reader, out io.Writer) *Checker {
 	return &Checker{in: in, out: out}
 }
 
+// SetAccessible switches to a spoken-friendly prompt that reads as full
+// sentences instead of bracketed tags.
+func (c *Checker) SetAccessible(on bool) {
+	c.accessible = on
+}
+
+// SetColor turns on coloring of the diffs in prompts. Accessible
+// prompts are never colored.
+func (c *Checker) SetColor(on bool) {
+	c.color = on
+}
+
+// colored returns text with the lines of its diff colored, if coloring
+// is on; inDiff is as for colorDiff.
+func (c *Checker) colored(text string, inDiff bool) string {
+	if !c.color || c.accessible {
+		return text
+	}
+	return colorDiff(text, inDiff)
+}
+
 // Check prompts the user for approval and returns true if approved.
 // toolName is the name of the tool requesting permission.
 // preview is a description of what the tool will do.
-func (c *Checker) Check(toolName string, preview string) bool {
-	fmt.Fprintf(c.out, "\n[permission] %s\n%s\n[y/n]: ", toolName, preview)
+func (c *Checker) Check(ctx context.Context, toolName string, preview string) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if c.accessible {
+		fmt.Fprint(c.out, "\n"+i18n.T("accessible.permission_prompt", toolName, preview))
+	} else {
+		fmt.Fprint(c.out, "\n"+i18n.T("permission.prompt", toolName, c.colored(preview, false)))
+	}
 
 	scanner := bufio.NewScanner(c.in)
 	if !scanner.Scan() {
@@ -49,3 +93,78 @@ func (c *Checker) Check(toolName string, preview string) bool {
 	line := strings.TrimSpace(scanner.Text())
 	return len(line) > 0 && (line[0] == 'y' || line[0] == 'Y')
 }
+
+// Decide prompts like Check, also offering to allow the tool, or the
+// exact command if there is one, from now on.
+func (c *Checker) Decide(ctx context.Context, toolName, preview, command string) Decision {
+	if ctx.Err() != nil {
+		return Deny
+	}
+	key := "permission.decide_prompt"
+	if c.accessible {
+		key = "accessible.decide_prompt"
+	}
+	if command != "" {
+		key += "_command"
+	}
+	fmt.Fprint(c.out, "\n"+i18n.T(key, toolName, c.colored(preview, false), toolName))
+
+	scanner := bufio.NewScanner(c.in)
+	if !scanner.Scan() {
+		return Deny
+	}
+	line := strings.ToLower(strings.TrimSpace(scanner.Text()))
+	switch {
+	case strings.HasPrefix(line, "y"):
+		return AllowOnce
+	case strings.HasPrefix(line, "a"):
+		return AllowTool
+	case strings.HasPrefix(line, "c") && command != "":
+		return AllowCommand
+	}
+	return Deny
+}
+
+// ReviewHunks prompts for each hunk in turn. Besides yes and no, "a"
+// accepts the hunk and the rest, and "d" rejects the hunk and the rest;
+// no answer rejects the remaining hunks.
+func (c *Checker) ReviewHunks(ctx context.Context, toolName, path string, hunks []string) []bool {
+	accepted := make([]bool, len(hunks))
+	if ctx.Err() != nil {
+		return accepted
+	}
+	scanner := bufio.NewScanner(c.in)
+	for i, h := range hunks {
+		if c.accessible {
+			fmt.Fprint(c.out, "\n"+i18n.T("accessible.hunk_prompt", toolName, path, i+1, len(hunks), h))
+		} else {
+			fmt.Fprint(c.out, "\n"+i18n.T("permission.hunk_prompt", toolName, path, i+1, len(hunks), c.colored(strings.TrimRight(h, "\n"), true)))
+		}
+		if !scanner.Scan() {
+			return accepted
+		}
+		line := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		switch {
+		case strings.HasPrefix(line, "y"):
+			accepted[i] = true
+		case strings.HasPrefix(line, "a"):
+			for j := i; j < len(hunks); j++ {
+				accepted[j] = true
+			}
+			return accepted
+		case strings.HasPrefix(line, "d"):
+			return accepted
+		}
+	}
+	return accepted
+}
+
+// AllowAll approves every tool call without asking. It is meant for
+// disposable environments such as Kubernetes jobs, where nobody is
+// there to answer.
+type AllowAll struct{}
+
+// Check always returns true.
+func (AllowAll) Check(ctx context.Context, toolName string, preview string) bool {
+	return true
+}
@@ -2,6 +2,7 @@ package permission
 
 import (
 	"bytes"
+	"context"
 	"strings"
 	"testing"
 )
@@ -28,7 +29,7 @@ func TestCheckApproved(t *testing.T) {
 			out := &bytes.Buffer{}
 			c := NewCheckerWithIO(in, out)
 
-			got := c.Check("test_tool", "preview text")
+			got := c.Check(context.Background(), "test_tool", "preview text")
 			if got != tt.want {
 				t.Errorf("Check() with input %q = %v, want %v", tt.input, got, tt.want)
 			}
@@ -41,7 +42,7 @@ func TestCheckOutput(t *testing.T) {
 	out := &bytes.Buffer{}
 	c := NewCheckerWithIO(in, out)
 
-	c.Check("shell_exec", "Run command: ls -la")
+	c.Check(context.Background(), "shell_exec", "Run command: ls -la")
 
 	output := out.String()
 	if !strings.Contains(output, "shell_exec") {
@@ -61,3 +62,81 @@ func TestNewChecker(t *testing.T) {
 		t.Fatal("NewChecker returned nil")
 	}
 }
+
+func TestCheckAccessiblePrompt(t *testing.T) {
+	out := &bytes.Buffer{}
+	c := NewCheckerWithIO(strings.NewReader("yes\n"), out)
+	c.SetAccessible(true)
+
+	if !c.Check(context.Background(), "shell_exec", "Run command: ls") {
+		t.Fatal("expected approval")
+	}
+
+	output := out.String()
+	if strings.Contains(output, "[y/n]") || strings.Contains(output, "[permission]") {
+		t.Errorf("accessible prompt should not use bracketed tags, got %q", output)
+	}
+	if !strings.Contains(output, "Type yes or no") {
+		t.Errorf("expected spoken instructions, got %q", output)
+	}
+}
+
+func TestAllowAll(t *testing.T) {
+	var h Handler = AllowAll{}
+	if !h.Check(context.Background(), "shell_exec", "rm -rf build") {
+		t.Error("AllowAll should approve every call")
+	}
+}
+
+func TestReviewHunks(t *testing.T) {
+	hunks := []string{"@@ -1 +1 @@\n-a\n+b\n", "@@ -5 +5 @@\n-c\n+d\n", "@@ -9 +9 @@\n-e\n+f\n"}
+	tests := []struct {
+		input string
+		want  []bool
+	}{
+		{"y\nn\ny\n", []bool{true, false, true}},
+		{"n\na\n", []bool{false, true, true}},
+		{"y\nd\n", []bool{true, false, false}},
+		{"yes\n", []bool{true, false, false}},
+		{"", []bool{false, false, false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			c := NewCheckerWithIO(strings.NewReader(tt.input), out)
+			var _ HunkReviewer = c
+
+			got := c.ReviewHunks(context.Background(), "edit_file", "main.go", hunks)
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Fatalf("ReviewHunks() with input %q = %v, want %v", tt.input, got, tt.want)
+				}
+			}
+			if !strings.Contains(out.String(), "main.go, hunk 1 of 3") || !strings.Contains(out.String(), "+b") {
+				t.Errorf("expected the first hunk in the prompt, got %q", out.String())
+			}
+		})
+	}
+}
+
+func TestCheckerColorsDiff(t *testing.T) {
+	preview := "Edit a.go\n--- a/a.go\n+++ b/a.go\n@@ -1 +1 @@\n-old\n+new"
+	var out bytes.Buffer
+	c := NewCheckerWithIO(strings.NewReader("y\n"), &out)
+	c.SetColor(true)
+	c.Check(context.Background(), "edit_file", preview)
+	for _, want := range []string{"\x1b[31m-old\x1b[0m", "\x1b[32m+new\x1b[0m", "\x1b[36m@@ -1 +1 @@\x1b[0m", "\n--- a/a.go\n+++ b/a.go\n"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("prompt missing %q:\n%q", want, out.String())
+		}
+	}
+
+	out.Reset()
+	c = NewCheckerWithIO(strings.NewReader("y\n"), &out)
+	c.SetColor(true)
+	c.SetAccessible(true)
+	c.Check(context.Background(), "edit_file", preview)
+	if strings.Contains(out.String(), "\x1b[") {
+		t.Errorf("accessible prompt colored: %q", out.String())
+	}
+}
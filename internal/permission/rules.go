@@ -0,0 +1,122 @@
+package permission
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RulesFile is where a project's remembered approvals are kept, relative
+// to the project root.
+var RulesFile = filepath.Join(".stormtrooper", "permissions.yaml")
+
+// Decision is an answer to a permission prompt that may be remembered.
+type Decision int
+
+const (
+	Deny         Decision = iota
+	AllowOnce             // this call only
+	AllowTool             // every call of this tool from now on
+	AllowCommand          // this exact command from now on
+)
+
+// Decider is an optional interface for handlers that can offer to
+// remember an approval. command is the exact command the call runs, or
+// "" if it does not run one, in which case AllowCommand is not offered.
+type Decider interface {
+	Decide(ctx context.Context, toolName, preview, command string) Decision
+}
+
+// Rules are the approvals remembered for a project: tools allowed
+// outright, and exact commands allowed per tool. A nil *Rules allows
+// nothing and remembers nothing.
+type Rules struct {
+	Tools    []string            `yaml:"tools,omitempty"`
+	Commands map[string][]string `yaml:"commands,omitempty"`
+
+	path string
+	mu   sync.Mutex
+}
+
+// LoadRules reads the rules of the project at dir. A missing file gives
+// empty rules, which are saved to it once something is remembered.
+func LoadRules(dir string) (*Rules, error) {
+	r := &Rules{path: filepath.Join(dir, RulesFile)}
+	data, err := os.ReadFile(r.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, r); err != nil {
+		return nil, fmt.Errorf("%s: %w", r.path, err)
+	}
+	return r, nil
+}
+
+// Allows reports whether a call of toolName, running command if it runs
+// one, was approved for good.
+func (r *Rules) Allows(toolName, command string) bool {
+	return r.Match(toolName, command) != ""
+}
+
+// Match returns the remembered approval that allows a call of toolName
+// running command: toolName if the tool is allowed outright, command if
+// the command is, or "" if neither is.
+func (r *Rules) Match(toolName, command string) string {
+	if r == nil {
+		return ""
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if slices.Contains(r.Tools, toolName) {
+		return toolName
+	}
+	if command != "" && slices.Contains(r.Commands[toolName], command) {
+		return command
+	}
+	return ""
+}
+
+// Remember records an AllowTool or AllowCommand decision and saves the
+// rules. Other decisions are not remembered.
+func (r *Rules) Remember(d Decision, toolName, command string) error {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch {
+	case d == AllowTool && !slices.Contains(r.Tools, toolName):
+		r.Tools = append(r.Tools, toolName)
+	case d == AllowCommand && command != "" && !slices.Contains(r.Commands[toolName], command):
+		if r.Commands == nil {
+			r.Commands = make(map[string][]string)
+		}
+		r.Commands[toolName] = append(r.Commands[toolName], command)
+	default:
+		return nil
+	}
+	return r.save()
+}
+
+// save writes the rules to disk. Callers must hold r.mu.
+func (r *Rules) save() error {
+	data, err := yaml.Marshal(r)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0755); err != nil {
+		return err
+	}
+	header := "# Approvals remembered with \"always allow\" at the permission prompt.\n# Delete an entry to be asked again.\n"
+	return os.WriteFile(r.path, append([]byte(header), data...), 0644)
+}
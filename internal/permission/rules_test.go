@@ -0,0 +1,122 @@
+package permission
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRules_RememberAndReload(t *testing.T) {
+	dir := t.TempDir()
+	r, err := LoadRules(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Allows("write_file", "") {
+		t.Fatal("empty rules should allow nothing")
+	}
+
+	if err := r.Remember(AllowTool, "write_file", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Remember(AllowCommand, "shell_exec", "go test ./..."); err != nil {
+		t.Fatal(err)
+	}
+	// Repeats and one-off decisions are not recorded.
+	r.Remember(AllowCommand, "shell_exec", "go test ./...")
+	r.Remember(AllowOnce, "edit_file", "")
+	r.Remember(Deny, "http_request", "")
+
+	reloaded, err := LoadRules(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		tool, command string
+		want          bool
+	}{
+		{"write_file", "", true},
+		{"shell_exec", "go test ./...", true},
+		{"shell_exec", "go test ./... && rm -rf /", false},
+		{"shell_exec", "", false},
+		{"edit_file", "", false},
+	} {
+		if got := reloaded.Allows(c.tool, c.command); got != c.want {
+			t.Errorf("Allows(%s, %q) = %v, want %v", c.tool, c.command, got, c.want)
+		}
+	}
+	if len(reloaded.Commands["shell_exec"]) != 1 {
+		t.Errorf("commands = %q, want one entry", reloaded.Commands["shell_exec"])
+	}
+
+	data, _ := os.ReadFile(filepath.Join(dir, RulesFile))
+	if !strings.HasPrefix(string(data), "# Approvals remembered") {
+		t.Errorf("file does not start with its header:\n%s", data)
+	}
+}
+
+func TestRules_Match(t *testing.T) {
+	r := &Rules{Tools: []string{"write_file"}, Commands: map[string][]string{"shell_exec": {"go test ./..."}}}
+	for _, c := range []struct {
+		tool, command, want string
+	}{
+		{"write_file", "", "write_file"},
+		{"shell_exec", "go test ./...", "go test ./..."},
+		{"shell_exec", "go vet ./...", ""},
+		{"edit_file", "", ""},
+	} {
+		if got := r.Match(c.tool, c.command); got != c.want {
+			t.Errorf("Match(%s, %q) = %q, want %q", c.tool, c.command, got, c.want)
+		}
+	}
+	var none *Rules
+	if got := none.Match("write_file", ""); got != "" {
+		t.Errorf("nil rules matched %q", got)
+	}
+}
+
+func TestRules_Nil(t *testing.T) {
+	var r *Rules
+	if r.Allows("write_file", "") {
+		t.Error("nil rules should allow nothing")
+	}
+	if err := r.Remember(AllowTool, "write_file", ""); err != nil {
+		t.Errorf("Remember on nil rules = %v", err)
+	}
+}
+
+func TestLoadRules_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, ".stormtrooper"), 0755)
+	os.WriteFile(filepath.Join(dir, RulesFile), []byte("tools: {"), 0644)
+	if _, err := LoadRules(dir); err == nil {
+		t.Error("expected an error for invalid YAML")
+	}
+}
+
+func TestCheckerDecide(t *testing.T) {
+	tests := []struct {
+		input, command string
+		want           Decision
+	}{
+		{"y\n", "", AllowOnce},
+		{"always\n", "", AllowTool},
+		{"c\n", "go build", AllowCommand},
+		{"c\n", "", Deny},
+		{"n\n", "go build", Deny},
+		{"", "", Deny},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		c := NewCheckerWithIO(strings.NewReader(tt.input), &out)
+		if got := c.Decide(context.Background(), "shell_exec", "Run: go build", tt.command); got != tt.want {
+			t.Errorf("Decide(%q, command %q) = %v, want %v", tt.input, tt.command, got, tt.want)
+		}
+		if tt.command != "" && !strings.Contains(out.String(), "[c] always allow this command") {
+			t.Errorf("prompt for a command does not offer it: %q", out.String())
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package policy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gavinyap/stormtrooper/internal/secrets"
+)
+
+// maxAuditArgs caps the arguments kept in each audit entry; file
+// contents are the bulk of large ones.
+const maxAuditArgs = 2048
+
+// Entry is one line of the audit log.
+type Entry struct {
+	Time    time.Time `json:"time"`
+	User    string    `json:"user"`
+	Host    string    `json:"host"`
+	Dir     string    `json:"dir"`
+	PID     int       `json:"pid"`
+	Event   string    `json:"event"` // "model" or "tool"
+	Model   string    `json:"model,omitempty"`
+	Tokens  int       `json:"tokens,omitempty"`
+	Tool    string    `json:"tool,omitempty"`
+	Args    string    `json:"args,omitempty"` // secrets redacted, truncated
+	Outcome string    `json:"outcome"`        // "ok", "error", "denied", or "blocked"
+	Reason  string    `json:"reason,omitempty"`
+}
+
+// Enforcer applies a policy to one run. It is the guard of the LLM
+// client and of the tool registry, so it sees every model request and
+// tool call, sub-agents' included.
+type Enforcer struct {
+	policy *Policy
+	base   Entry // who and where, stamped on every entry
+
+	mu       sync.Mutex
+	tokens   int
+	requests int
+	audit    io.WriteCloser
+	auditErr error // the last failed write; nothing runs after one
+}
+
+// Enforce starts applying p to a run in dir, opening the audit log if
+// the policy requires one.
+func (p *Policy) Enforce(dir string) (*Enforcer, error) {
+	e := &Enforcer{policy: p, base: Entry{Dir: dir, PID: os.Getpid()}}
+	if u, err := user.Current(); err == nil {
+		e.base.User = u.Username
+	}
+	e.base.Host, _ = os.Hostname()
+	if p.AuditLog == "" {
+		return e, nil
+	}
+	name := p.AuditLog
+	if rest, ok := strings.CutPrefix(name, "~/"); ok {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("audit log: %w", err)
+		}
+		name = filepath.Join(home, rest)
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0700); err != nil {
+		return nil, fmt.Errorf("audit log: %w", err)
+	}
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("audit log: %w", err)
+	}
+	e.audit = f
+	return e, nil
+}
+
+// Close closes the audit log.
+func (e *Enforcer) Close() error {
+	if e.audit == nil {
+		return nil
+	}
+	return e.audit.Close()
+}
+
+// Allow refuses models the policy does not allow and requests over the
+// budget. It implements llm.Guard.
+func (e *Enforcer) Allow(model string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var reason string
+	budget := e.policy.Budget
+	switch {
+	case e.auditErr != nil:
+		return fmt.Errorf("the organization policy requires an audit log, which cannot be written: %w", e.auditErr)
+	case !e.policy.AllowsModel(model):
+		reason = fmt.Sprintf("the organization policy does not allow the model %s (allowed: %s)", model, strings.Join(e.policy.AllowedModels, ", "))
+	case budget.MaxRequests > 0 && e.requests >= budget.MaxRequests:
+		reason = fmt.Sprintf("the organization policy's budget of %d model requests per run is used up", budget.MaxRequests)
+	case budget.MaxTokens > 0 && e.tokens >= budget.MaxTokens:
+		reason = fmt.Sprintf("the organization policy's budget of %d tokens per run is used up", budget.MaxTokens)
+	}
+	if reason != "" {
+		e.log(Entry{Event: "model", Model: model, Outcome: "blocked", Reason: reason})
+		return errors.New(reason)
+	}
+	e.requests++
+	return nil
+}
+
+// Used counts a request's tokens toward the budget. It implements
+// llm.Guard.
+func (e *Enforcer) Used(model string, tokens int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.tokens += tokens
+	e.log(Entry{Event: "model", Model: model, Tokens: tokens, Outcome: "ok"})
+}
+
+// Spent returns the tokens and requests used so far.
+func (e *Enforcer) Spent() (tokens, requests int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.tokens, e.requests
+}
+
+// Check returns why a deny rule forbids the call, or "". It implements
+// tool.Guard.
+func (e *Enforcer) Check(name string, args json.RawMessage) string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.auditErr != nil {
+		return "the audit log it requires cannot be written"
+	}
+	reason := e.policy.denies(name, stringArgs(args))
+	if reason != "" {
+		e.log(Entry{Event: "tool", Tool: name, Args: auditArgs(args), Outcome: "blocked", Reason: reason})
+	}
+	return reason
+}
+
+// Record logs how a call ended; blocked calls were logged by Check. It
+// implements tool.Guard.
+func (e *Enforcer) Record(name string, args json.RawMessage, outcome string) {
+	if outcome == "blocked" {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.log(Entry{Event: "tool", Tool: name, Args: auditArgs(args), Outcome: outcome})
+}
+
+// log appends entry to the audit log. e.mu must be held.
+func (e *Enforcer) log(entry Entry) {
+	if e.audit == nil || e.auditErr != nil {
+		return
+	}
+	entry.Time = time.Now().UTC()
+	entry.User, entry.Host, entry.Dir, entry.PID = e.base.User, e.base.Host, e.base.Dir, e.base.PID
+	line, _ := json.Marshal(entry)
+	if _, err := e.audit.Write(append(line, '\n')); err != nil {
+		e.auditErr = err
+	}
+}
+
+// stringArgs returns every string in a call's arguments, so deny rules
+// see a command or a path however the tool nests it.
+func stringArgs(args json.RawMessage) []string {
+	var v any
+	if json.Unmarshal(args, &v) != nil {
+		return []string{string(args)}
+	}
+	var out []string
+	var walk func(any)
+	walk = func(v any) {
+		switch v := v.(type) {
+		case string:
+			out = append(out, v)
+		case []any:
+			for _, item := range v {
+				walk(item)
+			}
+		case map[string]any:
+			for _, item := range v {
+				walk(item)
+			}
+		}
+	}
+	walk(v)
+	return out
+}
+
+func auditArgs(args json.RawMessage) string {
+	s, _ := secrets.Redact(string(args))
+	if len(s) > maxAuditArgs {
+		s = s[:maxAuditArgs] + "...[truncated]"
+	}
+	return s
+}
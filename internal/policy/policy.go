@@ -0,0 +1,304 @@
+// Package policy loads the organization policy: a file signed by an
+// administrator that restricts the models stormtrooper may use, forbids
+// tool calls, caps what a run may spend, and requires an audit log.
+//
+// Installing a public key in SystemDir turns the policy on. From then on
+// user and project config cannot relax it, and a missing, unsigned, or
+// tampered policy stops stormtrooper from starting rather than letting it
+// run unguarded.
+package policy
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Files in SystemDir. A policy is read from PolicyFile, or downloaded
+// from the URL in URLFile; either way its signature is the base64
+// ed25519 signature in the same name plus SigSuffix.
+const (
+	KeyFile    = "policy.pub"
+	PolicyFile = "policy.yaml"
+	URLFile    = "policy.url"
+	SigSuffix  = ".sig"
+)
+
+// SystemDir is where administrators install the policy. Packagers can
+// move it with -ldflags "-X
+// github.com/gavinyap/stormtrooper/internal/policy.SystemDir=...".
+var SystemDir = "/etc/stormtrooper"
+
+// downloadTimeout bounds fetching a policy from its URL at startup.
+const downloadTimeout = 15 * time.Second
+
+// Policy is an organization's guardrails.
+type Policy struct {
+	// AllowedModels are the models that may be used, as globs such as
+	// "anthropic/*"; empty allows any model.
+	AllowedModels []string `yaml:"allowed_models"`
+	// Deny forbids tool calls, even ones the user approves.
+	Deny []DenyRule `yaml:"deny"`
+	// Budget caps what one run may spend.
+	Budget Budget `yaml:"budget"`
+	// AuditLog is the file every model request and tool call is
+	// appended to, one JSON object per line; "~/" is the user's home.
+	// When set, a run that cannot write it does nothing.
+	AuditLog string `yaml:"audit_log"`
+
+	// Source is where the policy was loaded from.
+	Source string `yaml:"-"`
+}
+
+// DenyRule forbids the calls of matching tools.
+type DenyRule struct {
+	Tool   string `yaml:"tool"`   // tool name glob, e.g. "shell_exec" or "mcp_*"; empty means every tool
+	Match  string `yaml:"match"`  // regular expression tested against each string argument; empty matches every call
+	Reason string `yaml:"reason"` // shown to the model and the user
+
+	match *regexp.Regexp
+}
+
+// Budget caps one run of stormtrooper, sub-agents included. A run may
+// go over by the request that crosses the cap.
+type Budget struct {
+	MaxTokens   int `yaml:"max_tokens"`   // prompt plus completion tokens; 0 is unlimited
+	MaxRequests int `yaml:"max_requests"` // model requests; 0 is unlimited
+}
+
+// Load reads the policy installed in SystemDir, keeping a copy of a
+// downloaded policy in ~/.stormtrooper/policy for when its URL cannot be
+// reached. It returns nil when no public key is installed.
+func Load(ctx context.Context) (*Policy, error) {
+	var cacheDir string
+	if home, err := os.UserHomeDir(); err == nil {
+		cacheDir = filepath.Join(home, ".stormtrooper", "policy")
+	}
+	return LoadFrom(ctx, SystemDir, cacheDir)
+}
+
+// LoadFrom is Load with the policy installed in dir and downloads cached
+// in cacheDir.
+func LoadFrom(ctx context.Context, dir, cacheDir string) (*Policy, error) {
+	keyText, err := os.ReadFile(filepath.Join(dir, KeyFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("policy key: %w", err)
+	}
+	raw, err := decode(keyText, ed25519.PublicKeySize)
+	if err != nil {
+		return nil, fmt.Errorf("policy key %s: %w", filepath.Join(dir, KeyFile), err)
+	}
+	key := ed25519.PublicKey(raw)
+
+	rawURL, err := os.ReadFile(filepath.Join(dir, URLFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return loadFile(key, filepath.Join(dir, PolicyFile))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("policy URL: %w", err)
+	}
+	url := strings.TrimSpace(string(rawURL))
+	p, data, sig, err := download(ctx, key, url)
+	if err == nil {
+		if cacheDir != "" && os.MkdirAll(cacheDir, 0700) == nil {
+			os.WriteFile(filepath.Join(cacheDir, PolicyFile), data, 0600)
+			os.WriteFile(filepath.Join(cacheDir, PolicyFile+SigSuffix), sig, 0600)
+		}
+		return p, nil
+	}
+	// Keep working offline under the last policy that verified.
+	if cacheDir != "" {
+		if cached, cacheErr := loadFile(key, filepath.Join(cacheDir, PolicyFile)); cacheErr == nil {
+			cached.Source += " (cached copy of " + url + ")"
+			return cached, nil
+		}
+	}
+	return nil, err
+}
+
+// loadFile reads the policy at name and its signature.
+func loadFile(key ed25519.PublicKey, name string) (*Policy, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("policy: %w", err)
+	}
+	sig, err := os.ReadFile(name + SigSuffix)
+	if err != nil {
+		return nil, fmt.Errorf("policy signature: %w", err)
+	}
+	return verify(key, data, sig, name)
+}
+
+// download fetches the policy at url and its signature, returning the
+// raw bytes too so they can be cached.
+func download(ctx context.Context, key ed25519.PublicKey, url string) (*Policy, []byte, []byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, downloadTimeout)
+	defer cancel()
+	data, err := get(ctx, url)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("policy %s: %w", url, err)
+	}
+	sig, err := get(ctx, url+SigSuffix)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("policy signature %s: %w", url+SigSuffix, err)
+	}
+	p, err := verify(key, data, sig, url)
+	return p, data, sig, err
+}
+
+func get(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+// verify checks sig over data before parsing it.
+func verify(key ed25519.PublicKey, data, sigText []byte, source string) (*Policy, error) {
+	sig, err := decode(sigText, ed25519.SignatureSize)
+	if err != nil {
+		return nil, fmt.Errorf("policy signature for %s: %w", source, err)
+	}
+	if !ed25519.Verify(key, data, sig) {
+		return nil, fmt.Errorf("policy %s: the signature does not match; it was changed after signing or signed with another key", source)
+	}
+	p, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("policy %s: %w", source, err)
+	}
+	p.Source = source
+	return p, nil
+}
+
+// Parse reads a policy without checking its signature. Unknown fields
+// are errors, so a misspelled rule is not silently ignored.
+func Parse(data []byte) (*Policy, error) {
+	var p Policy
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&p); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("invalid YAML: %w", err)
+	}
+	for _, pattern := range p.AllowedModels {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("allowed_models: bad pattern %q", pattern)
+		}
+	}
+	for i := range p.Deny {
+		rule := &p.Deny[i]
+		if _, err := path.Match(rule.Tool, ""); err != nil {
+			return nil, fmt.Errorf("deny: bad tool pattern %q", rule.Tool)
+		}
+		if rule.Match != "" {
+			re, err := regexp.Compile(rule.Match)
+			if err != nil {
+				return nil, fmt.Errorf("deny: bad match for %s: %w", rule.Tool, err)
+			}
+			rule.match = re
+		}
+	}
+	if p.Budget.MaxTokens < 0 || p.Budget.MaxRequests < 0 {
+		return nil, errors.New("budget: caps must not be negative")
+	}
+	return &p, nil
+}
+
+// AllowsModel reports whether model may be used.
+func (p *Policy) AllowsModel(model string) bool {
+	if len(p.AllowedModels) == 0 {
+		return true
+	}
+	for _, pattern := range p.AllowedModels {
+		if ok, _ := path.Match(pattern, model); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// denies returns the reason a rule forbids the call, or "".
+func (p *Policy) denies(name string, args []string) string {
+	for _, rule := range p.Deny {
+		if rule.Tool != "" {
+			if ok, _ := path.Match(rule.Tool, name); !ok {
+				continue
+			}
+		}
+		matched := rule.match == nil
+		for _, arg := range args {
+			if matched {
+				break
+			}
+			matched = rule.match.MatchString(arg)
+		}
+		if !matched {
+			continue
+		}
+		if rule.Reason != "" {
+			return rule.Reason
+		}
+		return "calls to " + name + " are not allowed"
+	}
+	return ""
+}
+
+// GenerateKey returns a new key pair, base64-encoded for KeyFile and for
+// Sign.
+func GenerateKey() (public, private []byte, err error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	return encode(pub), encode(priv), nil
+}
+
+// Sign returns the signature of data, base64-encoded for the signature
+// file, using a private key from GenerateKey.
+func Sign(privateKey, data []byte) ([]byte, error) {
+	priv, err := decode(privateKey, ed25519.PrivateKeySize)
+	if err != nil {
+		return nil, fmt.Errorf("private key: %w", err)
+	}
+	return encode(ed25519.Sign(ed25519.PrivateKey(priv), data)), nil
+}
+
+func encode(b []byte) []byte {
+	return []byte(base64.StdEncoding.EncodeToString(b) + "\n")
+}
+
+func decode(text []byte, size int) ([]byte, error) {
+	b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(text)))
+	if err != nil {
+		return nil, errors.New("not base64")
+	}
+	if len(b) != size {
+		return nil, fmt.Errorf("%d bytes, want %d", len(b), size)
+	}
+	return b, nil
+}
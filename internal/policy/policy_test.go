@@ -0,0 +1,132 @@
+package policy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// install writes a key pair and a signed policy into a system dir and
+// returns it with the private key.
+func install(t *testing.T, policy string) (string, []byte) {
+	t.Helper()
+	dir := t.TempDir()
+	pub, priv, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(dir, KeyFile), pub, 0644)
+	writeSigned(t, priv, filepath.Join(dir, PolicyFile), policy)
+	return dir, priv
+}
+
+func writeSigned(t *testing.T, priv []byte, name, policy string) {
+	t.Helper()
+	sig, err := Sign(priv, []byte(policy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(name, []byte(policy), 0644)
+	os.WriteFile(name+SigSuffix, sig, 0644)
+}
+
+const testPolicy = `allowed_models: ["anthropic/*", "openai/gpt-5"]
+deny:
+  - tool: shell_exec
+    match: '\bcurl\b'
+    reason: no network downloads
+budget:
+  max_tokens: 1000
+audit_log: ~/audit.jsonl
+`
+
+func TestLoadFrom_File(t *testing.T) {
+	if p, err := LoadFrom(context.Background(), t.TempDir(), ""); p != nil || err != nil {
+		t.Fatalf("without a key there is no policy, got %+v, %v", p, err)
+	}
+
+	dir, _ := install(t, testPolicy)
+	p, err := LoadFrom(context.Background(), dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Source != filepath.Join(dir, PolicyFile) || p.Budget.MaxTokens != 1000 || len(p.Deny) != 1 {
+		t.Errorf("unexpected policy %+v", p)
+	}
+	for model, want := range map[string]bool{"anthropic/claude-sonnet-4": true, "openai/gpt-5": true, "openai/gpt-5-mini": false, "moonshotai/kimi-k2": false} {
+		if got := p.AllowsModel(model); got != want {
+			t.Errorf("AllowsModel(%s) = %v", model, got)
+		}
+	}
+
+	// Any change after signing is rejected.
+	os.WriteFile(filepath.Join(dir, PolicyFile), []byte(strings.Replace(testPolicy, "1000", "999999", 1)), 0644)
+	if _, err := LoadFrom(context.Background(), dir, ""); err == nil || !strings.Contains(err.Error(), "signature does not match") {
+		t.Errorf("expected a signature error, got %v", err)
+	}
+	// So is a missing policy once a key is installed.
+	os.Remove(filepath.Join(dir, PolicyFile))
+	if _, err := LoadFrom(context.Background(), dir, ""); err == nil {
+		t.Error("expected an error for a missing policy")
+	}
+}
+
+func TestLoadFrom_URL(t *testing.T) {
+	dir, priv := install(t, "")
+	served := t.TempDir()
+	writeSigned(t, priv, filepath.Join(served, "policy.yaml"), testPolicy)
+	server := httptest.NewServer(http.FileServer(http.Dir(served)))
+	os.WriteFile(filepath.Join(dir, URLFile), []byte(server.URL+"/policy.yaml\n"), 0644)
+	cache := filepath.Join(t.TempDir(), "cache")
+
+	p, err := LoadFrom(context.Background(), dir, cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Source != server.URL+"/policy.yaml" || len(p.AllowedModels) != 2 {
+		t.Errorf("unexpected policy %+v", p)
+	}
+
+	// Offline, the cached copy is used.
+	server.Close()
+	p, err = LoadFrom(context.Background(), dir, cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(p.Source, "cached copy") || len(p.AllowedModels) != 2 {
+		t.Errorf("unexpected policy %+v", p)
+	}
+	if _, err := LoadFrom(context.Background(), dir, t.TempDir()); err == nil {
+		t.Error("expected an error offline without a cached copy")
+	}
+}
+
+func TestParse(t *testing.T) {
+	for _, bad := range []string{
+		"allowed_model: [x]",
+		"allowed_models: ['[']",
+		"deny: [{tool: shell_exec, match: '('}]",
+		"budget: {max_tokens: -1}",
+	} {
+		if _, err := Parse([]byte(bad)); err == nil {
+			t.Errorf("Parse(%q) should fail", bad)
+		}
+	}
+	if p, err := Parse(nil); err != nil || !p.AllowsModel("any/model") {
+		t.Errorf("an empty policy allows everything, got %+v, %v", p, err)
+	}
+}
+
+func TestSign_RejectsBadKeys(t *testing.T) {
+	if _, err := Sign([]byte("not a key"), []byte("x")); err == nil {
+		t.Error("expected an error")
+	}
+	pub, _, _ := GenerateKey()
+	if _, err := Sign(pub, []byte("x")); err == nil {
+		t.Error("a public key cannot sign")
+	}
+}
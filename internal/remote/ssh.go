@@ -0,0 +1,171 @@
+// Package remote runs tools on another machine over SSH, so stormtrooper
+// can run locally while building and testing on a remote build server.
+//
+// It drives the system ssh client, so ~/.ssh/config, ssh-agent, and
+// known_hosts work as they do in the shell. Files are transferred by
+// running cat over ssh, so neither side needs an SFTP client.
+package remote
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os/exec"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gavinyap/stormtrooper/internal/config"
+)
+
+// exitNotExist is the exit code the helper scripts below use for a
+// missing file.
+const exitNotExist = 3
+
+// SSH executes commands and accesses files on a remote host. It
+// implements tool.Executor and tool.FileSystem. Relative paths resolve
+// against the remote working directory.
+type SSH struct {
+	cfg config.RemoteConfig
+
+	// command builds the local process for a remote invocation; replaced
+	// in tests.
+	command func(ctx context.Context, name string, args ...string) *exec.Cmd
+}
+
+// New creates an SSH backend from cfg.
+func New(cfg config.RemoteConfig) *SSH {
+	return &SSH{cfg: cfg, command: exec.CommandContext}
+}
+
+// Describe summarizes the target for prompts and status lines.
+func (s *SSH) Describe() string {
+	return fmt.Sprintf("%s:%s", s.cfg.Host, s.cfg.WorkDir)
+}
+
+// sshArgs returns the ssh arguments that run remoteCmd on the host.
+func (s *SSH) sshArgs(remoteCmd string) []string {
+	// BatchMode fails instead of prompting for a password, which would
+	// otherwise hang behind the TUI.
+	args := []string{"-o", "BatchMode=yes"}
+	if s.cfg.Port != 0 {
+		args = append(args, "-p", strconv.Itoa(s.cfg.Port))
+	}
+	if s.cfg.Key != "" {
+		args = append(args, "-i", s.cfg.Key)
+	}
+	return append(args, s.cfg.Host, "--", remoteCmd)
+}
+
+// remoteCommand wraps script so it runs in the working directory with
+// args as positional parameters, quoted for the remote shell.
+func (s *SSH) remoteCommand(script string, args ...string) string {
+	parts := []string{"sh", "-c", quote(script), "sh"}
+	for _, a := range args {
+		parts = append(parts, quote(a))
+	}
+	cmd := strings.Join(parts, " ")
+	if s.cfg.WorkDir != "" {
+		cmd = "cd " + quote(s.cfg.WorkDir) + " && " + cmd
+	}
+	return cmd
+}
+
+// Command runs command with sh -c in the remote working directory.
+func (s *SSH) Command(ctx context.Context, command string) *exec.Cmd {
+	return s.command(ctx, "ssh", s.sshArgs(s.remoteCommand(command))...)
+}
+
+// CommandArgv runs argv in the remote working directory. The remote
+// side always has a shell, so each argument is quoted for it.
+func (s *SSH) CommandArgv(ctx context.Context, argv []string) *exec.Cmd {
+	return s.command(ctx, "ssh", s.sshArgs(s.remoteCommand(`exec "$@"`, argv...))...)
+}
+
+// run executes script remotely with stdin, returning stdout.
+func (s *SSH) run(script string, stdin []byte, args ...string) ([]byte, error) {
+	cmd := s.command(context.Background(), "ssh", s.sshArgs(s.remoteCommand(script, args...))...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == exitNotExist {
+			return nil, fs.ErrNotExist
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s: %s", s.cfg.Host, msg)
+		}
+		return nil, fmt.Errorf("%s: %w", s.cfg.Host, err)
+	}
+	return stdout.Bytes(), nil
+}
+
+func (s *SSH) Stat(name string) (fs.FileInfo, error) {
+	out, err := s.run(`if [ -d "$1" ]; then echo dir; elif [ -e "$1" ]; then echo file; else exit 3; fi`, nil, name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+	}
+	return fileInfo{name: path.Base(name), dir: strings.TrimSpace(string(out)) == "dir"}, nil
+}
+
+func (s *SSH) ReadFile(name string) ([]byte, error) {
+	data, err := s.run(`[ -e "$1" ] || exit 3; cat -- "$1"`, nil, name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return data, nil
+}
+
+func (s *SSH) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	_, err := s.run(`cat > "$1" && chmod "$2" "$1"`, data, name, fmt.Sprintf("%o", perm.Perm()))
+	if err != nil {
+		return &fs.PathError{Op: "write", Path: name, Err: err}
+	}
+	return nil
+}
+
+func (s *SSH) MkdirAll(dir string, perm fs.FileMode) error {
+	_, err := s.run(`mkdir -p -m "$2" -- "$1"`, nil, dir, fmt.Sprintf("%o", perm.Perm()))
+	if err != nil {
+		return &fs.PathError{Op: "mkdir", Path: dir, Err: err}
+	}
+	return nil
+}
+
+func (s *SSH) Remove(name string) error {
+	_, err := s.run(`[ -e "$1" ] || exit 3; rm -f -- "$1"`, nil, name)
+	if err != nil {
+		return &fs.PathError{Op: "remove", Path: name, Err: err}
+	}
+	return nil
+}
+
+// quote single-quotes s for a POSIX shell.
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// fileInfo is the little a remote stat reports: a name and whether it is
+// a directory.
+type fileInfo struct {
+	name string
+	dir  bool
+}
+
+func (f fileInfo) Name() string       { return f.name }
+func (f fileInfo) Size() int64        { return 0 }
+func (f fileInfo) ModTime() time.Time { return time.Time{} }
+func (f fileInfo) IsDir() bool        { return f.dir }
+func (f fileInfo) Sys() any           { return nil }
+func (f fileInfo) Mode() fs.FileMode {
+	if f.dir {
+		return fs.ModeDir | 0755
+	}
+	return 0644
+}
@@ -0,0 +1,144 @@
+package remote
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gavinyap/stormtrooper/internal/config"
+	"github.com/gavinyap/stormtrooper/internal/tool"
+)
+
+var (
+	_ tool.Executor     = (*SSH)(nil)
+	_ tool.ArgvExecutor = (*SSH)(nil)
+	_ tool.FileSystem   = (*SSH)(nil)
+)
+
+// newLoopback returns an SSH backend whose "remote host" is a local shell:
+// the remote command ssh would send is run with sh -c, which exercises
+// the quoting and helper scripts for real.
+func newLoopback(t *testing.T, workDir string) (*SSH, *[]string) {
+	t.Helper()
+	var sshArgs []string
+	s := New(config.RemoteConfig{Host: "builder", Port: 2222, Key: "/keys/id", WorkDir: workDir})
+	s.command = func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		sshArgs = args
+		return exec.CommandContext(ctx, "sh", "-c", args[len(args)-1])
+	}
+	return s, &sshArgs
+}
+
+func TestSSHArgs(t *testing.T) {
+	s, args := newLoopback(t, "/srv/proj")
+	s.Command(context.Background(), "make test")
+
+	got := strings.Join(*args, " ")
+	want := "-o BatchMode=yes -p 2222 -i /keys/id builder -- cd '/srv/proj' && sh -c 'make test' sh"
+	if got != want {
+		t.Errorf("ssh args:\n got %q\nwant %q", got, want)
+	}
+}
+
+func TestCommand_RunsInWorkDir(t *testing.T) {
+	dir := t.TempDir()
+	s, _ := newLoopback(t, dir)
+
+	out, err := s.Command(context.Background(), "pwd; echo \"it's quoted\"").Output()
+	if err != nil {
+		t.Fatalf("command: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	if resolved, _ := filepath.EvalSymlinks(dir); lines[0] != dir && lines[0] != resolved {
+		t.Errorf("pwd = %q, want %q", lines[0], dir)
+	}
+	if lines[1] != "it's quoted" {
+		t.Errorf("quoting broke the command: %q", lines[1])
+	}
+}
+
+func TestCommandArgv(t *testing.T) {
+	s, _ := newLoopback(t, t.TempDir())
+
+	out, err := s.CommandArgv(context.Background(), []string{"printf", "%s|", "it's", "$HOME", "a b"}).Output()
+	if err != nil {
+		t.Fatalf("command: %v", err)
+	}
+	if got := string(out); got != "it's|$HOME|a b|" {
+		t.Errorf("arguments should reach the program unchanged, got %q", got)
+	}
+}
+
+func TestFileSystem(t *testing.T) {
+	dir := t.TempDir()
+	s, _ := newLoopback(t, dir)
+
+	if err := s.MkdirAll("sub dir/nested", 0755); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+	content := []byte("line 1\nit's $HOME `not expanded`\n")
+	if err := s.WriteFile("sub dir/nested/f.txt", content, 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	onDisk, _ := os.ReadFile(filepath.Join(dir, "sub dir", "nested", "f.txt"))
+	if string(onDisk) != string(content) {
+		t.Errorf("written %q, want %q", onDisk, content)
+	}
+
+	data, err := s.ReadFile("sub dir/nested/f.txt")
+	if err != nil || string(data) != string(content) {
+		t.Errorf("ReadFile = %q, %v", data, err)
+	}
+
+	info, err := s.Stat("sub dir")
+	if err != nil || !info.IsDir() || info.Name() != "sub dir" {
+		t.Errorf("Stat dir = %+v, %v", info, err)
+	}
+	info, err = s.Stat("sub dir/nested/f.txt")
+	if err != nil || info.IsDir() {
+		t.Errorf("Stat file = %+v, %v", info, err)
+	}
+
+	if _, err := s.Stat("missing"); !os.IsNotExist(err) {
+		t.Errorf("Stat missing: expected not-exist, got %v", err)
+	}
+	if _, err := s.ReadFile("missing"); !os.IsNotExist(err) {
+		t.Errorf("ReadFile missing: expected not-exist, got %v", err)
+	}
+
+	if err := s.Remove("sub dir/nested/f.txt"); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "sub dir", "nested", "f.txt")); !os.IsNotExist(err) {
+		t.Errorf("expected the file removed, got %v", err)
+	}
+	if err := s.Remove("missing"); !os.IsNotExist(err) {
+		t.Errorf("Remove missing: expected not-exist, got %v", err)
+	}
+}
+
+func TestFileToolsOverSSH(t *testing.T) {
+	dir := t.TempDir()
+	s, _ := newLoopback(t, dir)
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0644)
+
+	out, _ := (&tool.EditFileTool{FS: s}).Execute(context.Background(),
+		[]byte(`{"file_path":"main.go","old_string":"main","new_string":"remote"}`))
+	if !strings.HasPrefix(out, "File edited") {
+		t.Fatalf("edit_file: %q", out)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "main.go")); string(data) != "package remote\n" {
+		t.Errorf("remote file = %q", data)
+	}
+}
+
+func TestRun_ReportsStderr(t *testing.T) {
+	s, _ := newLoopback(t, "/definitely/not/here")
+	_, err := s.ReadFile("x")
+	if err == nil || !strings.Contains(err.Error(), "builder:") {
+		t.Errorf("expected host-prefixed error, got %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+// accessible.go rewrites the agent's status lines into plain sentences for
+// screen readers.
+package repl
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/charmbracelet/x/ansi"
+	"github.com/gavinyap/stormtrooper/internal/i18n"
+)
+
+// AccessibleWriter wraps the agent's stderr. It buffers complete lines,
+// translates bracketed tags such as "[tool] read_file" into sentences
+// ("Running tool read_file."), and strips ANSI escape sequences so a
+// screen reader never announces raw control codes.
+type AccessibleWriter struct {
+	w   io.Writer
+	mu  sync.Mutex
+	buf []byte
+}
+
+// NewAccessibleWriter creates an AccessibleWriter that writes to w.
+func NewAccessibleWriter(w io.Writer) *AccessibleWriter {
+	return &AccessibleWriter{w: w}
+}
+
+func (a *AccessibleWriter) Write(p []byte) (int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.buf = append(a.buf, p...)
+	for {
+		idx := bytes.IndexByte(a.buf, '\n')
+		if idx < 0 {
+			break
+		}
+		line := string(a.buf[:idx])
+		a.buf = a.buf[idx+1:]
+		if _, err := io.WriteString(a.w, accessibleLine(line)+"\n"); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// accessibleLine translates a single status line.
+func accessibleLine(line string) string {
+	line = ansi.Strip(line)
+	trimmed := strings.TrimSpace(line)
+
+	switch {
+	case strings.HasPrefix(trimmed, "[tool:done] "):
+		return i18n.T("accessible.tool_done", strings.TrimPrefix(trimmed, "[tool:done] "))
+
+	case strings.HasPrefix(trimmed, "[tool:error] "):
+		name, detail, ok := strings.Cut(strings.TrimPrefix(trimmed, "[tool:error] "), ": ")
+		if ok {
+			return i18n.T("accessible.tool_error_detail", name, detail)
+		}
+		return i18n.T("accessible.tool_error", name)
+
+	case strings.HasPrefix(trimmed, "[tool] "):
+		rest := strings.TrimPrefix(trimmed, "[tool] ")
+		if name, ok := strings.CutSuffix(rest, ": permission denied"); ok {
+			return i18n.T("accessible.tool_denied", name)
+		}
+		if name, ok := strings.CutPrefix(rest, "Unknown tool: "); ok {
+			return i18n.T("accessible.tool_unknown", name)
+		}
+		return i18n.T("accessible.tool_start", rest)
+
+	case strings.HasPrefix(trimmed, "[agent] Spawning sub-agent: "):
+		return i18n.T("accessible.subagent_start", strings.TrimPrefix(trimmed, "[agent] Spawning sub-agent: "))
+
+	case trimmed == "[agent] Sub-agent completed":
+		return i18n.T("accessible.subagent_done")
+	}
+	return line
+}
@@ -0,0 +1,80 @@
+package repl
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAccessibleWriter_TranslatesStatusLines(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"[tool] read_file\n", "Running tool read_file.\n"},
+		{"[tool:done] read_file\n", "Tool read_file finished.\n"},
+		{"[tool:error] shell_exec\n", "Tool shell_exec failed.\n"},
+		{"[tool:error] shell_exec: timeout: context deadline exceeded\n", "Tool shell_exec failed with timeout: context deadline exceeded.\n"},
+		{"[tool] shell_exec: permission denied\n", "Tool shell_exec was not allowed.\n"},
+		{"[tool] Unknown tool: fly\n", "The model asked for an unknown tool: fly.\n"},
+		{"[agent] Spawning sub-agent: fix tests\n", "Starting a sub-agent: fix tests\n"},
+		{"[agent] Sub-agent completed\n", "Sub-agent finished.\n"},
+		{"plain text\n", "plain text\n"},
+		{"\x1b[31mred\x1b[0m\n", "red\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.TrimSpace(tt.in), func(t *testing.T) {
+			out := &bytes.Buffer{}
+			w := NewAccessibleWriter(out)
+			w.Write([]byte(tt.in))
+			if out.String() != tt.want {
+				t.Errorf("got %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestAccessibleWriter_BuffersPartialLines(t *testing.T) {
+	out := &bytes.Buffer{}
+	w := NewAccessibleWriter(out)
+
+	w.Write([]byte("[tool] gr"))
+	if out.Len() != 0 {
+		t.Fatalf("partial line should be buffered, got %q", out.String())
+	}
+	w.Write([]byte("ep\n"))
+	if out.String() != "Running tool grep.\n" {
+		t.Errorf("got %q", out.String())
+	}
+}
+
+func TestRun_AccessibleLabels(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(sseTextResponse("Hello")))
+	}))
+	defer server.Close()
+
+	ag := newTestAgent(t, server)
+	out := &bytes.Buffer{}
+	r := NewWithIO(ag, "0.2.5", NewInputReaderWithIO(strings.NewReader("hi\n"), out), out)
+	r.SetAccessible(true)
+
+	if err := r.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	output := out.String()
+	for _, want := range []string{"Your message: ", "Assistant response:", "End of response."} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output, got %q", want, output)
+		}
+	}
+	if strings.Contains(output, "> ") {
+		t.Errorf("symbolic prompt should be replaced in accessible mode, got %q", output)
+	}
+}
@@ -16,38 +16,50 @@ const (
 
 // InputReader reads user input with multi-line support.
 type InputReader struct {
-	scanner *bufio.Scanner
-	out     io.Writer
+	scanner      *bufio.Scanner
+	out          io.Writer
+	prompt       string
+	continuation string
 }
 
 // NewInputReader creates an InputReader that reads from stdin
 // and prints prompts to stderr.
 func NewInputReader() *InputReader {
 	return &InputReader{
-		scanner: bufio.NewScanner(os.Stdin),
-		out:     os.Stderr,
+		scanner:      bufio.NewScanner(os.Stdin),
+		out:          os.Stderr,
+		prompt:       primaryPrompt,
+		continuation: continuationPrompt,
 	}
 }
 
 // NewInputReaderWithIO creates an InputReader with custom I/O for testing.
 func NewInputReaderWithIO(in io.Reader, out io.Writer) *InputReader {
 	return &InputReader{
-		scanner: bufio.NewScanner(in),
-		out:     out,
+		scanner:      bufio.NewScanner(in),
+		out:          out,
+		prompt:       primaryPrompt,
+		continuation: continuationPrompt,
 	}
 }
 
+// SetPrompts replaces the primary and continuation prompts.
+func (r *InputReader) SetPrompts(prompt, continuation string) {
+	r.prompt = prompt
+	r.continuation = continuation
+}
+
 // ReadInput reads user input, supporting multi-line input via backslash
 // continuation. Returns io.EOF if the input stream is closed.
 func (r *InputReader) ReadInput() (string, error) {
-	fmt.Fprint(r.out, primaryPrompt)
+	fmt.Fprint(r.out, r.prompt)
 
 	var lines []string
 	first := true
 
 	for {
 		if !first {
-			fmt.Fprint(r.out, continuationPrompt)
+			fmt.Fprint(r.out, r.continuation)
 		}
 		first = false
 
@@ -7,8 +7,12 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/gavinyap/stormtrooper/internal/agent"
+	"github.com/gavinyap/stormtrooper/internal/command"
+	"github.com/gavinyap/stormtrooper/internal/i18n"
 )
 
 // REPL manages the read-eval-print loop.
@@ -17,6 +21,14 @@ type REPL struct {
 	input   *InputReader
 	out     io.Writer
 	version string
+
+	// commands handles slash commands such as /rewind; nil means only
+	// /exit is understood.
+	commands *command.Dispatcher
+
+	// accessible labels each response and uses wordier prompts for
+	// screen readers.
+	accessible bool
 }
 
 // New creates a new REPL with the given agent and version string.
@@ -39,10 +51,27 @@ func NewWithIO(ag *agent.Agent, version string, input *InputReader, out io.Write
 	}
 }
 
+// SetAccessible switches the REPL to screen-reader-friendly output: each
+// assistant response is introduced and closed with a spoken label, and the
+// input prompts are full words instead of symbols.
+func (r *REPL) SetAccessible(on bool) {
+	r.accessible = on
+	if on {
+		r.input.SetPrompts(i18n.T("accessible.prompt"), i18n.T("accessible.continuation"))
+	} else {
+		r.input.SetPrompts(primaryPrompt, continuationPrompt)
+	}
+}
+
+// SetCommands sets the slash commands the REPL handles itself.
+func (r *REPL) SetCommands(d *command.Dispatcher) {
+	r.commands = d
+}
+
 // Run starts the REPL loop. Blocks until the user exits or input is closed.
 func (r *REPL) Run(ctx context.Context) error {
-	fmt.Fprintf(r.out, "Stormtrooper v%s — AI coding assistant\n", r.version)
-	fmt.Fprintln(r.out, "Type /exit or Ctrl+C to quit.")
+	fmt.Fprintln(r.out, i18n.T("repl.banner", r.version))
+	fmt.Fprintln(r.out, i18n.T("repl.exit_hint"))
 	fmt.Fprintln(r.out)
 
 	for {
@@ -56,7 +85,7 @@ func (r *REPL) Run(ctx context.Context) error {
 			break
 		}
 		if err != nil {
-			fmt.Fprintf(r.out, "Input error: %v\n", err)
+			fmt.Fprintln(r.out, i18n.T("repl.input_error", err))
 			continue
 		}
 
@@ -68,17 +97,91 @@ func (r *REPL) Run(ctx context.Context) error {
 			break
 		}
 
+		if r.commands != nil {
+			res, handled, err := r.commands.Dispatch(ctx, input)
+			if handled {
+				if err == nil && res.Exit {
+					break
+				}
+				if err == nil && res.Exec != nil {
+					res.Exec.Stdin, res.Exec.Stdout, res.Exec.Stderr = os.Stdin, os.Stdout, os.Stderr
+					err = res.Exec.Run()
+				}
+				if err != nil {
+					fmt.Fprintln(r.out, i18n.T("error", err))
+				} else if res.Form != nil {
+					fmt.Fprintln(r.out, i18n.T("form.unsupported", res.Form.Title))
+				} else if res.Picker != nil {
+					r.pick(ctx, res.Picker)
+				} else if res.Output != "" {
+					fmt.Fprintln(r.out, res.Output)
+				}
+				fmt.Fprintln(r.out)
+				continue
+			}
+		}
+
+		if r.accessible {
+			fmt.Fprintln(r.out, i18n.T("accessible.response_start"))
+		}
+
 		if err := r.agent.Send(ctx, input); err != nil {
 			if ctx.Err() != nil {
 				break // Context cancelled (Ctrl+C), exit REPL
 			}
-			fmt.Fprintf(r.out, "Error: %v\n", err)
+			fmt.Fprintln(r.out, i18n.T("error", err))
 			continue
 		}
 
+		if r.accessible {
+			fmt.Fprintln(r.out, i18n.T("accessible.response_end"))
+		}
+
 		fmt.Fprintln(r.out)
 	}
 
-	fmt.Fprintln(r.out, "Goodbye!")
+	fmt.Fprintln(r.out, i18n.T("repl.goodbye"))
 	return nil
 }
+
+// pick lists the picker's items by number and calls Choose with the one
+// the user enters; an empty line cancels.
+func (r *REPL) pick(ctx context.Context, p *command.Picker) {
+	fmt.Fprintln(r.out, p.Title+":")
+	for i, item := range p.Items {
+		fmt.Fprintf(r.out, "%3d. %s\n", i+1, item)
+	}
+	prompt := "picker.prompt"
+	if p.Filter {
+		prompt = "picker.filter_prompt"
+	}
+	fmt.Fprintln(r.out, i18n.T(prompt))
+	input, err := r.input.ReadInput()
+	if err != nil || input == "" {
+		return
+	}
+	n, err := strconv.Atoi(input)
+	// Text narrows a filterable list, keeping each item's number.
+	for err != nil && p.Filter {
+		for i, item := range p.Items {
+			if strings.Contains(strings.ToLower(item), strings.ToLower(input)) {
+				fmt.Fprintf(r.out, "%3d. %s\n", i+1, item)
+			}
+		}
+		fmt.Fprintln(r.out, i18n.T(prompt))
+		if input, err = r.input.ReadInput(); err != nil || input == "" {
+			return
+		}
+		n, err = strconv.Atoi(input)
+	}
+	if err != nil || n < 1 || n > len(p.Items) {
+		fmt.Fprintln(r.out, i18n.T("picker.invalid", input))
+		return
+	}
+	out, err := p.Choose(ctx, n-1)
+	if err != nil {
+		fmt.Fprintln(r.out, i18n.T("error", err))
+	} else if out != "" {
+		fmt.Fprintln(r.out, out)
+	}
+}
@@ -7,10 +7,12 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"strings"
 	"testing"
 
 	"github.com/gavinyap/stormtrooper/internal/agent"
+	"github.com/gavinyap/stormtrooper/internal/command"
 	"github.com/gavinyap/stormtrooper/internal/llm"
 	"github.com/gavinyap/stormtrooper/internal/permission"
 	"github.com/gavinyap/stormtrooper/internal/tool"
@@ -65,6 +67,112 @@ func TestRun_ExitCommand(t *testing.T) {
 	}
 }
 
+func TestRun_SlashCommands(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("LLM should not be called for slash commands")
+	}))
+	defer server.Close()
+
+	ag := newTestAgent(t, server)
+	in := strings.NewReader("/help\n/rewind\n/exit\n")
+	out := &bytes.Buffer{}
+	r := NewWithIO(ag, "0.2.2", NewInputReaderWithIO(in, out), out)
+	commands := command.NewDispatcher()
+	commands.Register(command.Rewind(ag))
+	r.SetCommands(commands)
+
+	if err := r.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), "/rewind [n]") {
+		t.Errorf("expected /help output, got %q", out.String())
+	}
+	// The agent has no checkpoints, so /rewind reports an error and the
+	// REPL carries on.
+	if !strings.Contains(out.String(), "Error: checkpoints are not enabled") {
+		t.Errorf("expected /rewind error, got %q", out.String())
+	}
+}
+
+func TestRun_Picker(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("LLM should not be called for slash commands")
+	}))
+	defer server.Close()
+
+	ag := newTestAgent(t, server)
+	in := strings.NewReader("/choose\n2\n/choose\n9\n/choose\n\n/exit\n")
+	out := &bytes.Buffer{}
+	r := NewWithIO(ag, "0.2.2", NewInputReaderWithIO(in, out), out)
+	var chosen []int
+	commands := command.NewDispatcher()
+	commands.Register(command.Command{
+		Name: "choose",
+		Pick: func(context.Context, []string) (*command.Picker, error) {
+			return &command.Picker{
+				Title: "Pick a fruit",
+				Items: []string{"apple", "pear"},
+				Choose: func(_ context.Context, i int) (string, error) {
+					chosen = append(chosen, i)
+					return "Chose item " + strconv.Itoa(i) + ".", nil
+				},
+			}, nil
+		},
+	})
+	r.SetCommands(commands)
+
+	if err := r.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{"Pick a fruit:", "  2. pear", "Chose item 1.", "Not one of the choices: 9"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected %q in output, got %q", want, out.String())
+		}
+	}
+	if len(chosen) != 1 || chosen[0] != 1 {
+		t.Errorf("chosen = %v, want [1]", chosen)
+	}
+}
+
+func TestRun_PickerFilter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("LLM should not be called for slash commands")
+	}))
+	defer server.Close()
+
+	ag := newTestAgent(t, server)
+	in := strings.NewReader("/choose\npea\n3\n/exit\n")
+	out := &bytes.Buffer{}
+	r := NewWithIO(ag, "0.2.2", NewInputReaderWithIO(in, out), out)
+	commands := command.NewDispatcher()
+	commands.Register(command.Command{
+		Name: "choose",
+		Pick: func(context.Context, []string) (*command.Picker, error) {
+			return &command.Picker{
+				Title:  "Pick a fruit",
+				Items:  []string{"apple", "pear", "peach"},
+				Filter: true,
+				Choose: func(_ context.Context, i int) (string, error) {
+					return "Chose item " + strconv.Itoa(i) + ".", nil
+				},
+			}, nil
+		},
+	})
+	r.SetCommands(commands)
+
+	if err := r.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The filtered list keeps the numbers of the full one.
+	filtered := out.String()[strings.Index(out.String(), "text to filter")+1:]
+	if !strings.Contains(filtered, "  3. peach") || strings.Contains(filtered, "apple") {
+		t.Errorf("unexpected filtered list in %q", out.String())
+	}
+	if !strings.Contains(out.String(), "Chose item 2.") {
+		t.Errorf("expected item 2 to be chosen, got %q", out.String())
+	}
+}
+
 func TestRun_EOF(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		t.Fatal("LLM should not be called for EOF")
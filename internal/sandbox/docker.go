@@ -0,0 +1,124 @@
+// Package sandbox runs shell_exec commands inside a per-session
+// Docker or Podman container, so agents can install packages and run
+// risky commands without touching the host.
+package sandbox
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/gavinyap/stormtrooper/internal/config"
+)
+
+// Container is a running sandbox container. It implements tool.Executor.
+//
+// The project directory is bind-mounted at the same absolute path inside
+// the container, and commands run there. File tools keep operating on the
+// host, and both sides see the same files at the same paths.
+type Container struct {
+	cfg     config.SandboxConfig
+	engine  string
+	name    string
+	workDir string
+
+	// run executes an engine command; replaced in tests.
+	run func(ctx context.Context, name string, args ...string) ([]byte, error)
+}
+
+// New prepares a sandbox for workDir from cfg. Call Start before use.
+func New(cfg config.SandboxConfig, workDir string) *Container {
+	engine := cfg.Engine
+	if engine == "" {
+		engine = "docker"
+	}
+	return &Container{
+		cfg:     cfg,
+		engine:  engine,
+		name:    "stormtrooper-" + randomSuffix(),
+		workDir: workDir,
+		run:     runEngine,
+	}
+}
+
+// runArgs builds the engine arguments that create the container.
+func (c *Container) runArgs() []string {
+	network := c.cfg.Network
+	if network == "" {
+		network = "none"
+	}
+	args := []string{
+		"run", "--detach", "--rm",
+		"--name", c.name,
+		"--network", network,
+		"--volume", c.workDir + ":" + c.workDir,
+		"--workdir", c.workDir,
+	}
+	for _, m := range c.cfg.Mounts {
+		args = append(args, "--volume", m)
+	}
+	keys := make([]string, 0, len(c.cfg.Env))
+	for k := range c.cfg.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		args = append(args, "--env", k+"="+c.cfg.Env[k])
+	}
+	// Keep the container alive; commands are started with exec.
+	return append(args, c.cfg.Image, "sleep", "infinity")
+}
+
+// Start creates and starts the container.
+func (c *Container) Start(ctx context.Context) error {
+	if c.cfg.Image == "" {
+		return fmt.Errorf("sandbox: image is required")
+	}
+	switch c.cfg.Network {
+	case "", "none", "bridge", "host":
+	default:
+		return fmt.Errorf("sandbox: unsupported network %q (use none, bridge, or host)", c.cfg.Network)
+	}
+	if out, err := c.run(ctx, c.engine, c.runArgs()...); err != nil {
+		return fmt.Errorf("sandbox: %s run failed: %v: %s", c.engine, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// Stop removes the container.
+func (c *Container) Stop(ctx context.Context) error {
+	if out, err := c.run(ctx, c.engine, "rm", "--force", c.name); err != nil {
+		return fmt.Errorf("sandbox: %s rm failed: %v: %s", c.engine, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// Name returns the container name.
+func (c *Container) Name() string {
+	return c.name
+}
+
+// Command runs command with sh -c inside the container.
+func (c *Container) Command(ctx context.Context, command string) *exec.Cmd {
+	return exec.CommandContext(ctx, c.engine, "exec", "--interactive", "--workdir", c.workDir, c.name, "sh", "-c", command)
+}
+
+// CommandArgv runs argv inside the container without a shell.
+func (c *Container) CommandArgv(ctx context.Context, argv []string) *exec.Cmd {
+	args := append([]string{"exec", "--interactive", "--workdir", c.workDir, c.name}, argv...)
+	return exec.CommandContext(ctx, c.engine, args...)
+}
+
+func runEngine(ctx context.Context, name string, args ...string) ([]byte, error) {
+	return exec.CommandContext(ctx, name, args...).CombinedOutput()
+}
+
+func randomSuffix() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
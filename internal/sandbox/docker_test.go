@@ -0,0 +1,112 @@
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gavinyap/stormtrooper/internal/config"
+	"github.com/gavinyap/stormtrooper/internal/tool"
+)
+
+var (
+	_ tool.Executor     = (*Container)(nil)
+	_ tool.ArgvExecutor = (*Container)(nil)
+)
+
+// fakeEngine records engine invocations instead of running them.
+type fakeEngine struct {
+	calls [][]string
+	err   error
+}
+
+func (f *fakeEngine) run(ctx context.Context, name string, args ...string) ([]byte, error) {
+	f.calls = append(f.calls, append([]string{name}, args...))
+	if f.err != nil {
+		return []byte("engine said no"), f.err
+	}
+	return nil, nil
+}
+
+func TestStart_RunArgs(t *testing.T) {
+	c := New(config.SandboxConfig{
+		Image:  "golang:1.25",
+		Mounts: []string{"/cache:/root/.cache:ro"},
+		Env:    map[string]string{"GOFLAGS": "-mod=mod", "CGO_ENABLED": "0"},
+	}, "/work/proj")
+	fake := &fakeEngine{}
+	c.run = fake.run
+
+	if err := c.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	got := strings.Join(fake.calls[0], " ")
+	for _, want := range []string{
+		"docker run --detach --rm --name " + c.Name(),
+		"--network none",
+		"--volume /work/proj:/work/proj",
+		"--workdir /work/proj",
+		"--volume /cache:/root/.cache:ro",
+		"--env CGO_ENABLED=0 --env GOFLAGS=-mod=mod",
+		"golang:1.25 sleep infinity",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("run args %q missing %q", got, want)
+		}
+	}
+}
+
+func TestStart_Validation(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.SandboxConfig
+		want string
+	}{
+		{"no image", config.SandboxConfig{}, "image is required"},
+		{"bad network", config.SandboxConfig{Image: "alpine", Network: "wide-open"}, "unsupported network"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(tt.cfg, "/w")
+			c.run = (&fakeEngine{}).run
+			if err := c.Start(context.Background()); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestStart_EngineFailure(t *testing.T) {
+	c := New(config.SandboxConfig{Image: "alpine", Engine: "podman"}, "/w")
+	c.run = (&fakeEngine{err: errors.New("exit status 125")}).run
+
+	err := c.Start(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "podman run failed") || !strings.Contains(err.Error(), "engine said no") {
+		t.Errorf("expected engine output in error, got %v", err)
+	}
+}
+
+func TestCommandAndStop(t *testing.T) {
+	c := New(config.SandboxConfig{Image: "alpine", Engine: "podman"}, "/w")
+	fake := &fakeEngine{}
+	c.run = fake.run
+
+	cmd := c.Command(context.Background(), "go test ./...")
+	want := "podman exec --interactive --workdir /w " + c.Name() + " sh -c go test ./..."
+	if got := strings.Join(cmd.Args, " "); got != want {
+		t.Errorf("Command args = %q, want %q", got, want)
+	}
+
+	cmd = c.CommandArgv(context.Background(), []string{"echo", "a b"})
+	if got, want := cmd.Args[len(cmd.Args)-3:], []string{c.Name(), "echo", "a b"}; strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("CommandArgv should not go through sh, args = %q", cmd.Args)
+	}
+
+	if err := c.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	if got := strings.Join(fake.calls[0], " "); got != "podman rm --force "+c.Name() {
+		t.Errorf("Stop ran %q", got)
+	}
+}
@@ -0,0 +1,74 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func at(s string) time.Time {
+	t, err := time.ParseInLocation("2006-01-02 15:04", s, time.UTC)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestParseCron_Errors(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "* * * foo *", "@often"} {
+		if _, err := ParseCron(expr); err == nil {
+			t.Errorf("ParseCron(%q) should fail", expr)
+		}
+	}
+}
+
+func TestCron_Matches(t *testing.T) {
+	cases := []struct {
+		expr string
+		at   string
+		want bool
+	}{
+		{"* * * * *", "2026-03-04 05:06", true},
+		{"30 3 * * 1-5", "2026-03-04 03:30", true},  // Wednesday
+		{"30 3 * * 1-5", "2026-03-07 03:30", false}, // Saturday
+		{"*/15 * * * *", "2026-03-04 05:45", true},
+		{"*/15 * * * *", "2026-03-04 05:46", false},
+		{"5/20 * * * *", "2026-03-04 05:45", true},
+		{"0 9 * jan,jul mon", "2026-01-05 09:00", true},
+		{"0 0 * * 7", "2026-03-08 00:00", true}, // Sunday
+		// Both day fields restricted: either may match.
+		{"0 0 1 * mon", "2026-03-02 00:00", true},
+		{"0 0 1 * mon", "2026-04-01 00:00", true},
+		{"0 0 1 * mon", "2026-03-03 00:00", false},
+		{"@weekly", "2026-03-08 00:00", true},
+	}
+	for _, c := range cases {
+		cron, err := ParseCron(c.expr)
+		if err != nil {
+			t.Fatalf("ParseCron(%q): %v", c.expr, err)
+		}
+		if got := cron.Matches(at(c.at)); got != c.want {
+			t.Errorf("%q at %s = %v, want %v", c.expr, c.at, got, c.want)
+		}
+	}
+}
+
+func TestCron_Next(t *testing.T) {
+	cases := []struct {
+		expr, after, want string
+	}{
+		{"* * * * *", "2026-03-04 05:06", "2026-03-04 05:07"},
+		{"30 3 * * 1-5", "2026-03-06 04:00", "2026-03-09 03:30"},
+		{"0 0 1 * *", "2026-12-15 10:00", "2027-01-01 00:00"},
+		{"0 12 29 2 *", "2026-03-01 00:00", "2028-02-29 12:00"},
+	}
+	for _, c := range cases {
+		cron, _ := ParseCron(c.expr)
+		if got := cron.Next(at(c.after)); !got.Equal(at(c.want)) {
+			t.Errorf("%q after %s = %s, want %s", c.expr, c.after, got, c.want)
+		}
+	}
+	never, _ := ParseCron("0 0 30 2 *")
+	if got := never.Next(at("2026-01-01 00:00")); !got.IsZero() {
+		t.Errorf("Feb 30 should never be due, got %s", got)
+	}
+}
@@ -0,0 +1,164 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Runner runs a task, writing its output to log, and returns its exit
+// code. An error means the task could not be started.
+type Runner func(ctx context.Context, t Task, log io.Writer) (int, error)
+
+// Daemon starts tasks when they are due.
+type Daemon struct {
+	path    string
+	history *History
+	logDir  string
+	runner  Runner
+	log     io.Writer
+	now     func() time.Time
+
+	mu      sync.Mutex
+	running map[string]bool
+	wg      sync.WaitGroup
+}
+
+// NewDaemon creates a daemon for the tasks in the schedule file at path.
+// The run history and run logs are kept in dir.
+func NewDaemon(path, dir string, runner Runner, log io.Writer) *Daemon {
+	return &Daemon{
+		path:    path,
+		history: NewHistory(filepath.Join(dir, "history.jsonl")),
+		logDir:  filepath.Join(dir, "logs"),
+		runner:  runner,
+		log:     log,
+		now:     time.Now,
+		running: make(map[string]bool),
+	}
+}
+
+// History returns the daemon's run history.
+func (d *Daemon) History() *History { return d.history }
+
+// Serve checks for due tasks at the start of every minute until ctx is
+// done, then waits for running tasks, which ctx also cancels. The
+// schedule file is read on every check, so added and removed tasks take
+// effect without a restart.
+func (d *Daemon) Serve(ctx context.Context) error {
+	for {
+		now := d.now()
+		next := now.Truncate(time.Minute).Add(time.Minute)
+		select {
+		case <-ctx.Done():
+			d.wg.Wait()
+			return nil
+		case <-time.After(next.Sub(now)):
+		}
+		d.Tick(ctx, next)
+	}
+}
+
+// Tick starts every task due in the minute containing t.
+func (d *Daemon) Tick(ctx context.Context, t time.Time) {
+	store, err := Load(d.path)
+	if err != nil {
+		fmt.Fprintf(d.log, "[schedule] %v\n", err)
+		return
+	}
+	for _, task := range store.Tasks {
+		c, err := ParseCron(task.Cron)
+		if err != nil {
+			fmt.Fprintf(d.log, "[schedule] %s: %v\n", task.Name, err)
+			continue
+		}
+		if c.Matches(t) {
+			d.start(ctx, task)
+		}
+	}
+}
+
+// start runs task in the background, or records it as skipped if its
+// previous run has not finished.
+func (d *Daemon) start(ctx context.Context, task Task) {
+	if !d.claim(task.Name) {
+		now := d.now()
+		fmt.Fprintf(d.log, "[schedule] %s: skipped, the previous run is still going\n", task.Name)
+		d.record(Run{Task: task.Name, Start: now, End: now, Status: StatusSkipped})
+		return
+	}
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		defer d.release(task.Name)
+		d.execute(ctx, task)
+	}()
+}
+
+// RunNow runs task in the foreground, as if it were due, and returns the
+// recorded run. It fails if the task is already running in this daemon.
+func (d *Daemon) RunNow(ctx context.Context, task Task) (Run, error) {
+	if !d.claim(task.Name) {
+		return Run{}, fmt.Errorf("task %q is already running", task.Name)
+	}
+	defer d.release(task.Name)
+	return d.execute(ctx, task), nil
+}
+
+func (d *Daemon) claim(name string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.running[name] {
+		return false
+	}
+	d.running[name] = true
+	return true
+}
+
+func (d *Daemon) release(name string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.running, name)
+}
+
+// execute runs task with its output in a new log file and records the
+// outcome.
+func (d *Daemon) execute(ctx context.Context, task Task) Run {
+	run := Run{Task: task.Name, Start: d.now()}
+	fmt.Fprintf(d.log, "[schedule] %s: started\n", task.Name)
+
+	out := io.Discard
+	if err := os.MkdirAll(d.logDir, 0755); err == nil {
+		run.Log = filepath.Join(d.logDir, fmt.Sprintf("%s-%s.log", task.Name, run.Start.Format("20060102-150405")))
+		if f, err := os.Create(run.Log); err == nil {
+			defer f.Close()
+			out = f
+		} else {
+			run.Log = ""
+		}
+	}
+
+	code, err := d.runner(ctx, task, out)
+	run.End, run.ExitCode = d.now(), code
+	switch {
+	case err != nil:
+		run.Status, run.Error = StatusFailed, err.Error()
+	case code != 0:
+		run.Status = StatusFailed
+	default:
+		run.Status = StatusOK
+	}
+	fmt.Fprintf(d.log, "[schedule] %s: %s after %s\n", task.Name, run.Status, run.End.Sub(run.Start).Round(time.Second))
+	d.record(run)
+	return run
+}
+
+func (d *Daemon) record(r Run) {
+	if err := d.history.Append(r); err != nil {
+		fmt.Fprintf(d.log, "[schedule] could not record run: %v\n", err)
+	}
+}
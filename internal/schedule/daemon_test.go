@@ -0,0 +1,93 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newTestDaemon creates a daemon for the given tasks, run by runner.
+func newTestDaemon(t *testing.T, runner Runner, tasks ...Task) *Daemon {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "schedule.json")
+	s, _ := Load(path)
+	for _, task := range tasks {
+		if err := s.Add(task); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.Save()
+	return NewDaemon(path, filepath.Join(dir, "schedule"), runner, io.Discard)
+}
+
+func TestDaemon_RunsDueTasks(t *testing.T) {
+	ran := make(chan string, 2)
+	d := newTestDaemon(t, func(ctx context.Context, task Task, log io.Writer) (int, error) {
+		fmt.Fprintf(log, "ran %s\n", task.Prompt)
+		ran <- task.Name
+		return 0, nil
+	},
+		Task{Name: "deps", Cron: "0 3 * * 1", Prompt: "bump", Dir: "/src/app"},
+		Task{Name: "docs", Cron: "0 4 * * *", Prompt: "docs", Dir: "/src/app"},
+	)
+	d.Tick(context.Background(), at("2026-03-02 03:00")) // Monday
+	d.wg.Wait()
+
+	if len(ran) != 1 || <-ran != "deps" {
+		t.Fatal("only the due task should run")
+	}
+	runs, _ := d.History().Runs("", 0)
+	if len(runs) != 1 || runs[0].Status != StatusOK {
+		t.Fatalf("history = %+v", runs)
+	}
+	log, err := os.ReadFile(runs[0].Log)
+	if err != nil || string(log) != "ran bump\n" {
+		t.Errorf("run log = %q, %v", log, err)
+	}
+}
+
+func TestDaemon_SkipsOverlappingRuns(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 2)
+	d := newTestDaemon(t, func(ctx context.Context, task Task, log io.Writer) (int, error) {
+		started <- struct{}{}
+		<-release
+		return 1, nil
+	}, Task{Name: "slow", Cron: "* * * * *", Prompt: "p", Dir: "/src/app"})
+
+	ctx := context.Background()
+	d.Tick(ctx, at("2026-03-02 03:00"))
+	<-started
+	d.Tick(ctx, at("2026-03-02 03:01"))
+	if _, err := d.RunNow(ctx, Task{Name: "slow"}); err == nil {
+		t.Error("RunNow should refuse a task that is running")
+	}
+	close(release)
+	d.wg.Wait()
+
+	runs, _ := d.History().Runs("slow", 0)
+	if len(runs) != 2 || runs[0].Status != StatusSkipped || runs[1].Status != StatusFailed || runs[1].ExitCode != 1 {
+		t.Errorf("history = %+v", runs)
+	}
+	if len(started) != 0 {
+		t.Error("the overlapping run should not have started")
+	}
+}
+
+func TestDaemon_RunNowRecordsStartFailure(t *testing.T) {
+	d := newTestDaemon(t, func(ctx context.Context, task Task, log io.Writer) (int, error) {
+		return -1, errors.New("no such directory")
+	})
+	run, err := d.RunNow(context.Background(), Task{Name: "gone", Dir: "/nowhere"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Status != StatusFailed || run.Error != "no such directory" {
+		t.Errorf("run = %+v", run)
+	}
+}
@@ -0,0 +1,85 @@
+package schedule
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Run outcomes.
+const (
+	StatusOK      = "ok"
+	StatusFailed  = "failed"
+	StatusSkipped = "skipped" // the previous run was still going
+)
+
+// Run is one entry in the run history.
+type Run struct {
+	Task     string    `json:"task"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Status   string    `json:"status"`
+	ExitCode int       `json:"exit_code,omitempty"`
+	Error    string    `json:"error,omitempty"`
+	Log      string    `json:"log,omitempty"` // file with the run's output
+}
+
+// History is an append-only log of runs, one JSON object per line.
+type History struct {
+	path string
+}
+
+// NewHistory returns the history stored at path.
+func NewHistory(path string) *History {
+	return &History{path: path}
+}
+
+// Append records r.
+func (h *History) Append(r Run) error {
+	if err := os.MkdirAll(filepath.Dir(h.path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(h.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// Runs returns the last n runs of the named task (all tasks if task is
+// empty), oldest first. n <= 0 returns them all.
+func (h *History) Runs(task string, n int) ([]Run, error) {
+	f, err := os.Open(h.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	var runs []Run
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var r Run
+		// Skip a line cut short by a crash rather than losing the rest.
+		if json.Unmarshal(scanner.Bytes(), &r) != nil {
+			continue
+		}
+		if task == "" || r.Task == task {
+			runs = append(runs, r)
+		}
+	}
+	if n > 0 && len(runs) > n {
+		runs = runs[len(runs)-n:]
+	}
+	return runs, scanner.Err()
+}
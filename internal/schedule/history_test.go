@@ -0,0 +1,41 @@
+package schedule
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schedule", "history.jsonl")
+	h := NewHistory(path)
+	if runs, err := h.Runs("", 0); err != nil || runs != nil {
+		t.Fatalf("missing history should be empty, got %v, %v", runs, err)
+	}
+	for _, r := range []Run{
+		{Task: "deps", Status: StatusOK},
+		{Task: "docs", Status: StatusFailed, ExitCode: 1},
+		{Task: "deps", Status: StatusSkipped},
+		{Task: "deps", Status: StatusOK},
+	} {
+		if err := h.Append(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A line cut short by a crash is skipped.
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	f.WriteString(`{"task":"deps","sta`)
+	f.Close()
+
+	runs, err := h.Runs("deps", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 2 || runs[0].Status != StatusSkipped || runs[1].Status != StatusOK {
+		t.Errorf("last two deps runs = %+v", runs)
+	}
+	all, _ := h.Runs("", 0)
+	if len(all) != 4 {
+		t.Errorf("expected 4 runs, got %d", len(all))
+	}
+}
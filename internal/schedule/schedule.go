@@ -0,0 +1,130 @@
+// Package schedule runs recurring headless agent tasks, such as weekly
+// dependency bumps, from cron expressions. Tasks live in
+// ~/.stormtrooper/schedule.json and a foreground daemon runs them,
+// keeping a history and never starting a task while its previous run is
+// still going.
+package schedule
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gavinyap/stormtrooper/internal/tool"
+)
+
+const scheduleFile = "schedule.json"
+
+// Task is a recurring headless run.
+type Task struct {
+	Name   string `json:"name"`
+	Cron   string `json:"cron"`
+	Prompt string `json:"prompt"`
+	Dir    string `json:"dir"` // repository the task runs in
+	// Profile is the tool profile ("plan", "review"); empty for all tools.
+	Profile string `json:"profile,omitempty"`
+	// Yes approves every tool call, as with --yes.
+	Yes     bool      `json:"yes,omitempty"`
+	Created time.Time `json:"created"`
+}
+
+var taskNameRE = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// Validate checks the task's name, cron expression, tool profile,
+// prompt, and directory.
+func (t Task) Validate() error {
+	if !taskNameRE.MatchString(t.Name) {
+		return fmt.Errorf("invalid task name %q (use letters, digits, - and _)", t.Name)
+	}
+	if _, err := ParseCron(t.Cron); err != nil {
+		return err
+	}
+	if _, ok := tool.Profiles[t.Profile]; t.Profile != "" && !ok {
+		return fmt.Errorf("unknown tool profile %q (use %s)", t.Profile, strings.Join(tool.ProfileNames(), ", "))
+	}
+	if t.Prompt == "" {
+		return errors.New("a task needs a prompt")
+	}
+	if !filepath.IsAbs(t.Dir) {
+		return fmt.Errorf("task directory must be absolute: %s", t.Dir)
+	}
+	return nil
+}
+
+// Store holds the scheduled tasks.
+type Store struct {
+	path  string
+	Tasks []Task `json:"tasks"`
+}
+
+// DefaultPath returns ~/.stormtrooper/schedule.json.
+func DefaultPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".stormtrooper", scheduleFile), nil
+}
+
+// Load reads the store at path. A missing file yields an empty store.
+func Load(path string) (*Store, error) {
+	s := &Store{path: path}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return s, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("invalid schedule file %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Get returns the named task, or nil.
+func (s *Store) Get(name string) *Task {
+	for i := range s.Tasks {
+		if s.Tasks[i].Name == name {
+			return &s.Tasks[i]
+		}
+	}
+	return nil
+}
+
+// Add validates t and adds it. Call Save to persist it.
+func (s *Store) Add(t Task) error {
+	if err := t.Validate(); err != nil {
+		return err
+	}
+	if s.Get(t.Name) != nil {
+		return fmt.Errorf("task %q already exists", t.Name)
+	}
+	s.Tasks = append(s.Tasks, t)
+	return nil
+}
+
+// Remove deletes the named task and reports whether it existed.
+func (s *Store) Remove(name string) bool {
+	n := len(s.Tasks)
+	s.Tasks = slices.DeleteFunc(s.Tasks, func(t Task) bool { return t.Name == name })
+	return len(s.Tasks) < n
+}
+
+// Save writes the store back to disk, creating the parent directory.
+func (s *Store) Save() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, data, 0600)
+}
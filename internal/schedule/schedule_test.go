@@ -0,0 +1,57 @@
+package schedule
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStore_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schedule.json")
+	s, err := Load(path)
+	if err != nil || len(s.Tasks) != 0 {
+		t.Fatalf("missing file should yield an empty store, got %+v, %v", s, err)
+	}
+	task := Task{Name: "deps", Cron: "0 3 * * 1", Prompt: "bump dependencies", Dir: "/src/app", Profile: "review"}
+	if err := s.Add(task); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Add(task); err == nil {
+		t.Error("expected a duplicate name error")
+	}
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Get("deps"); got == nil || *got != task {
+		t.Errorf("Get = %+v", got)
+	}
+	if !s.Remove("deps") || s.Remove("deps") {
+		t.Error("Remove should report whether the task existed")
+	}
+}
+
+func TestTask_Validate(t *testing.T) {
+	ok := Task{Name: "docs", Cron: "@daily", Prompt: "refresh the docs", Dir: "/src/app"}
+	cases := map[string]func(*Task){
+		"task name": func(t *Task) { t.Name = "../x" },
+		"cron":      func(t *Task) { t.Cron = "daily" },
+		"prompt":    func(t *Task) { t.Prompt = "" },
+		"profile":   func(t *Task) { t.Profile = "yolo" },
+		"absolute":  func(t *Task) { t.Dir = "src/app" },
+	}
+	if err := ok.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	for want, mutate := range cases {
+		task := ok
+		mutate(&task)
+		if err := task.Validate(); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected a %s error, got %v", want, err)
+		}
+	}
+}
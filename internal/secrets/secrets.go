@@ -0,0 +1,57 @@
+// Package secrets finds credentials in text, such as API keys, tokens,
+// and private keys, so they can be redacted before the text is shared.
+package secrets
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Placeholder replaces each secret.
+const Placeholder = "[REDACTED]"
+
+// pattern matches a secret. The prefix and suffix submatches, when
+// nonzero, are kept, so that "API_KEY=abc123..." becomes
+// "API_KEY=[REDACTED]".
+type pattern struct {
+	re             *regexp.Regexp
+	prefix, suffix int
+}
+
+var patterns = []pattern{
+	{re: regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`)},
+	{re: regexp.MustCompile(`\bsk-[A-Za-z0-9_-]{20,}`)}, // OpenAI, OpenRouter, Anthropic
+	{re: regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})`)},
+	{re: regexp.MustCompile(`\bglpat-[A-Za-z0-9_-]{20,}`)},
+	{re: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{re: regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`)}, // Slack
+	{re: regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}`)},         // Google
+	{re: regexp.MustCompile(`(?i)(\bbearer\s+)[A-Za-z0-9._~+/-]{16,}=*`), prefix: 1},
+	{re: regexp.MustCompile(`(://[^\s/:@]+:)[^\s/@]+(@)`), prefix: 1, suffix: 2}, // passwords in URLs
+	{re: regexp.MustCompile(`(?i)(\b[a-z0-9_.-]*(?:api[_-]?key|secret|token|passw(?:or)?d)[a-z0-9_.-]*["']?\s*[:=]\s*["']?)[^\s"',;]{8,}`), prefix: 1},
+}
+
+// Redact replaces every secret in text with Placeholder and reports how
+// many it replaced.
+func Redact(text string) (string, int) {
+	count := 0
+	for _, p := range patterns {
+		text = p.re.ReplaceAllStringFunc(text, func(match string) string {
+			sub := p.re.FindStringSubmatch(match)
+			var prefix, suffix string
+			if p.prefix > 0 {
+				prefix = sub[p.prefix]
+			}
+			if p.suffix > 0 {
+				suffix = sub[p.suffix]
+			}
+			secret := strings.TrimSuffix(strings.TrimPrefix(match, prefix), suffix)
+			if strings.Contains(secret, Placeholder) {
+				return match // already redacted by an earlier pattern
+			}
+			count++
+			return prefix + Placeholder + suffix
+		})
+	}
+	return text, count
+}
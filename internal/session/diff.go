@@ -0,0 +1,230 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// fileTools are the tools whose file_path arguments, at the top level or
+// in a files list, name changed files.
+var fileTools = map[string]bool{
+	"write_file":  true,
+	"write_files": true,
+	"edit_file":   true,
+}
+
+// Turn is one user prompt and what the agent did in response.
+type Turn struct {
+	Prompt string
+	Answer string   // final assistant text of the turn
+	Tools  []string // tools called, in order
+}
+
+// Turns splits the conversation at each user message.
+func (s *Session) Turns() []Turn {
+	var turns []Turn
+	for _, m := range s.Messages {
+		switch m.Role {
+		case "user":
+			turns = append(turns, Turn{Prompt: m.Content})
+		case "assistant":
+			if len(turns) == 0 {
+				continue
+			}
+			t := &turns[len(turns)-1]
+			if strings.TrimSpace(m.Content) != "" {
+				t.Answer = m.Content
+			}
+			for _, tc := range m.ToolCalls {
+				t.Tools = append(t.Tools, tc.Function.Name)
+			}
+		}
+	}
+	return turns
+}
+
+// ToolCounts returns how many times each tool was called.
+func (s *Session) ToolCounts() map[string]int {
+	counts := make(map[string]int)
+	for _, m := range s.Messages {
+		for _, tc := range m.ToolCalls {
+			counts[tc.Function.Name]++
+		}
+	}
+	return counts
+}
+
+// FilesChanged returns the sorted paths successfully written or edited
+// during the session. Calls that failed or were denied are ignored.
+func (s *Session) FilesChanged() []string {
+	failed := make(map[string]bool)
+	for _, m := range s.Messages {
+		if m.Role == "tool" && (strings.HasPrefix(m.Content, "Error:") ||
+			strings.HasPrefix(m.Content, "Tool error:") ||
+			m.Content == "Permission denied by user") {
+			failed[m.ToolCallID] = true
+		}
+	}
+
+	seen := make(map[string]bool)
+	var files []string
+	for _, m := range s.Messages {
+		for _, tc := range m.ToolCalls {
+			if !fileTools[tc.Function.Name] || failed[tc.ID] {
+				continue
+			}
+			var args struct {
+				FilePath string `json:"file_path"`
+				Files    []struct {
+					FilePath string `json:"file_path"`
+				} `json:"files"`
+			}
+			if json.Unmarshal([]byte(tc.Function.Arguments), &args) != nil {
+				continue
+			}
+			paths := []string{args.FilePath}
+			for _, f := range args.Files {
+				paths = append(paths, f.FilePath)
+			}
+			for _, path := range paths {
+				if path != "" && !seen[path] {
+					seen[path] = true
+					files = append(files, path)
+				}
+			}
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+// Diff renders a readable comparison of two sessions: prompts, final
+// answers per turn, tool usage, and files changed.
+func Diff(a, b *Session) string {
+	var out strings.Builder
+	fmt.Fprintf(&out, "A: %s (model: %s)\n", a.ID, a.Model)
+	fmt.Fprintf(&out, "B: %s (model: %s)\n", b.ID, b.Model)
+
+	turnsA, turnsB := a.Turns(), b.Turns()
+	n := max(len(turnsA), len(turnsB))
+
+	out.WriteString("\nTurns\n")
+	for i := 0; i < n; i++ {
+		var ta, tb *Turn
+		if i < len(turnsA) {
+			ta = &turnsA[i]
+		}
+		if i < len(turnsB) {
+			tb = &turnsB[i]
+		}
+		fmt.Fprintf(&out, "\n  Turn %d\n", i+1)
+		writeField(&out, "prompt", turnField(ta, func(t *Turn) string { return t.Prompt }), turnField(tb, func(t *Turn) string { return t.Prompt }))
+		writeField(&out, "tools", turnField(ta, func(t *Turn) string { return strings.Join(t.Tools, ", ") }), turnField(tb, func(t *Turn) string { return strings.Join(t.Tools, ", ") }))
+		writeField(&out, "answer", turnField(ta, func(t *Turn) string { return t.Answer }), turnField(tb, func(t *Turn) string { return t.Answer }))
+	}
+
+	out.WriteString("\nTool calls\n")
+	countsA, countsB := a.ToolCounts(), b.ToolCounts()
+	names := unionKeys(countsA, countsB)
+	if len(names) == 0 {
+		out.WriteString("  (none)\n")
+	}
+	for _, name := range names {
+		marker := " "
+		if countsA[name] != countsB[name] {
+			marker = "*"
+		}
+		fmt.Fprintf(&out, " %s %-16s A: %-3d B: %d\n", marker, name, countsA[name], countsB[name])
+	}
+
+	out.WriteString("\nFiles changed\n")
+	filesA, filesB := toSet(a.FilesChanged()), toSet(b.FilesChanged())
+	paths := unionKeys(filesA, filesB)
+	if len(paths) == 0 {
+		out.WriteString("  (none)\n")
+	}
+	for _, p := range paths {
+		switch {
+		case filesA[p] && filesB[p]:
+			fmt.Fprintf(&out, "  both    %s\n", p)
+		case filesA[p]:
+			fmt.Fprintf(&out, "  only A  %s\n", p)
+		default:
+			fmt.Fprintf(&out, "  only B  %s\n", p)
+		}
+	}
+
+	return out.String()
+}
+
+// turnField extracts a field from a possibly missing turn.
+func turnField(t *Turn, f func(*Turn) string) *string {
+	if t == nil {
+		return nil
+	}
+	v := f(t)
+	return &v
+}
+
+// writeField prints a field once when both sides agree, or both sides
+// when they differ. A nil side means the session has no such turn.
+func writeField(out *strings.Builder, label string, a, b *string) {
+	if a != nil && b != nil && *a == *b {
+		fmt.Fprintf(out, "    %-7s (same) %s\n", label+":", summarize(*a))
+		return
+	}
+	fmt.Fprintf(out, "    %s\n", label+":")
+	fmt.Fprintf(out, "      A: %s\n", sideValue(a))
+	fmt.Fprintf(out, "      B: %s\n", sideValue(b))
+}
+
+func sideValue(v *string) string {
+	if v == nil {
+		return "(no such turn)"
+	}
+	return indent(*v, "         ")
+}
+
+// summarize shortens identical values to their first line.
+func summarize(s string) string {
+	if s == "" {
+		return "(empty)"
+	}
+	first, rest, multi := strings.Cut(s, "\n")
+	if multi && strings.TrimSpace(rest) != "" {
+		return first + " ..."
+	}
+	return first
+}
+
+func indent(s, prefix string) string {
+	if s == "" {
+		return "(empty)"
+	}
+	return strings.ReplaceAll(strings.TrimRight(s, "\n"), "\n", "\n"+prefix)
+}
+
+func toSet(items []string) map[string]bool {
+	set := make(map[string]bool, len(items))
+	for _, it := range items {
+		set[it] = true
+	}
+	return set
+}
+
+func unionKeys[V any](a, b map[string]V) []string {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, m := range []map[string]V{a, b} {
+		for k := range m {
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,111 @@
+package session
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gavinyap/stormtrooper/internal/llm"
+)
+
+func toolCall(id, name, args string) llm.ToolCall {
+	return llm.ToolCall{ID: id, Type: "function", Function: llm.FunctionCall{Name: name, Arguments: args}}
+}
+
+func sampleSession(id, model, answer string, calls ...llm.ToolCall) *Session {
+	s := &Session{ID: id, Model: model}
+	s.Messages = append(s.Messages,
+		llm.Message{Role: "system", Content: "sys"},
+		llm.Message{Role: "user", Content: "fix the bug"},
+	)
+	if len(calls) > 0 {
+		s.Messages = append(s.Messages, llm.Message{Role: "assistant", ToolCalls: calls})
+		for _, c := range calls {
+			s.Messages = append(s.Messages, llm.Message{Role: "tool", ToolCallID: c.ID, Content: "ok"})
+		}
+	}
+	s.Messages = append(s.Messages, llm.Message{Role: "assistant", Content: answer})
+	return s
+}
+
+func TestTurns(t *testing.T) {
+	s := sampleSession("a", "m", "done", toolCall("1", "read_file", `{}`), toolCall("2", "edit_file", `{}`))
+	turns := s.Turns()
+	if len(turns) != 1 {
+		t.Fatalf("expected 1 turn, got %d", len(turns))
+	}
+	if turns[0].Prompt != "fix the bug" || turns[0].Answer != "done" {
+		t.Errorf("unexpected turn %+v", turns[0])
+	}
+	if strings.Join(turns[0].Tools, ",") != "read_file,edit_file" {
+		t.Errorf("unexpected tools %v", turns[0].Tools)
+	}
+}
+
+func TestFilesChanged_SkipsFailedCalls(t *testing.T) {
+	s := &Session{Messages: []llm.Message{
+		{Role: "user", Content: "go"},
+		{Role: "assistant", ToolCalls: []llm.ToolCall{
+			toolCall("1", "write_file", `{"file_path":"b.go","content":"x"}`),
+			toolCall("2", "edit_file", `{"file_path":"a.go"}`),
+			toolCall("3", "write_file", `{"file_path":"denied.go"}`),
+			toolCall("4", "edit_file", `{"file_path":"broken.go"}`),
+			toolCall("5", "read_file", `{"file_path":"read.go"}`),
+			toolCall("6", "edit_file", `{"file_path":"a.go"}`),
+			toolCall("7", "write_files", `{"files":[{"file_path":"c.go","content":"x"},{"file_path":"b.go","content":"y"}]}`),
+		}},
+		{Role: "tool", ToolCallID: "1", Content: "Wrote b.go"},
+		{Role: "tool", ToolCallID: "2", Content: "Edited a.go"},
+		{Role: "tool", ToolCallID: "3", Content: "Permission denied by user"},
+		{Role: "tool", ToolCallID: "4", Content: "Error: old_string not found"},
+		{Role: "tool", ToolCallID: "5", Content: "..."},
+		{Role: "tool", ToolCallID: "6", Content: "Edited a.go"},
+		{Role: "tool", ToolCallID: "7", Content: "Files written: 2"},
+	}}
+
+	got := strings.Join(s.FilesChanged(), ",")
+	if got != "a.go,b.go,c.go" {
+		t.Errorf("FilesChanged() = %q, want a.go,b.go,c.go", got)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	a := sampleSession("s1", "model-a", "Fixed it.",
+		toolCall("1", "read_file", `{"file_path":"main.go"}`),
+		toolCall("2", "edit_file", `{"file_path":"main.go"}`))
+	b := sampleSession("s2", "model-b", "Fixed it differently.",
+		toolCall("1", "shell_exec", `{"command":"go test"}`),
+		toolCall("2", "write_file", `{"file_path":"fix.go"}`))
+
+	report := Diff(a, b)
+	for _, want := range []string{
+		"A: s1 (model: model-a)",
+		"B: s2 (model: model-b)",
+		"prompt: (same) fix the bug",
+		"A: Fixed it.",
+		"B: Fixed it differently.",
+		"read_file",
+		"shell_exec",
+		"only A  main.go",
+		"only B  fix.go",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report missing %q:\n%s", want, report)
+		}
+	}
+}
+
+func TestDiff_UnevenTurns(t *testing.T) {
+	a := sampleSession("s1", "m", "one")
+	b := sampleSession("s2", "m", "one")
+	b.Messages = append(b.Messages,
+		llm.Message{Role: "user", Content: "and another"},
+		llm.Message{Role: "assistant", Content: "two"})
+
+	report := Diff(a, b)
+	if !strings.Contains(report, "Turn 2") || !strings.Contains(report, "(no such turn)") {
+		t.Errorf("expected missing turn to be reported:\n%s", report)
+	}
+	if !strings.Contains(report, "(none)") {
+		t.Errorf("expected no files changed:\n%s", report)
+	}
+}
@@ -0,0 +1,216 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gavinyap/stormtrooper/internal/llm"
+)
+
+// maxPostMortemSteps is how many of the last tool calls a post-mortem
+// lists.
+const maxPostMortemSteps = 20
+
+// failurePrefixes start the tool results that report a failure.
+var failurePrefixes = []string{"Error:", "Tool error:", "Permission denied by user", "Exit code:", "Command timed out"}
+
+// Step is one tool call made during a session.
+type Step struct {
+	Tool   string
+	Target string // the command, file, or other main argument
+	Result string // first line of the result
+	Failed bool
+}
+
+// PostMortem describes a failed run for triage: what was asked and
+// tried, what went wrong, and what to do next.
+type PostMortem struct {
+	SessionID    string
+	Task         string
+	Error        string
+	Steps        []Step   // tool calls, in order
+	Unresolved   []Step   // failed calls that were never retried successfully
+	FilesTouched []string // files written or edited
+	NextSteps    []string
+}
+
+// PostMortem builds a post-mortem of the session, which ended with runErr.
+func (s *Session) PostMortem(runErr error) *PostMortem {
+	pm := &PostMortem{SessionID: s.ID, FilesTouched: s.FilesChanged()}
+	if prompts := s.Prompts(); len(prompts) > 0 {
+		pm.Task = prompts[0]
+	}
+	if runErr != nil {
+		pm.Error = runErr.Error()
+	}
+
+	calls := map[string]Step{}
+	for _, m := range s.Messages {
+		for _, tc := range m.ToolCalls {
+			calls[tc.ID] = Step{Tool: tc.Function.Name, Target: stepTarget(tc.Function.Arguments)}
+		}
+		if m.Role != "tool" {
+			continue
+		}
+		step, ok := calls[m.ToolCallID]
+		if !ok {
+			step = Step{Tool: m.Name}
+		}
+		step.Result, _, _ = strings.Cut(strings.TrimSpace(m.Content), "\n")
+		for _, p := range failurePrefixes {
+			if strings.HasPrefix(m.Content, p) {
+				step.Failed = true
+			}
+		}
+		pm.Steps = append(pm.Steps, step)
+	}
+
+	// A failure counts as resolved once the same call later succeeds.
+	for i, step := range pm.Steps {
+		if !step.Failed {
+			continue
+		}
+		resolved := false
+		for _, later := range pm.Steps[i+1:] {
+			if !later.Failed && later.Tool == step.Tool && later.Target == step.Target {
+				resolved = true
+				break
+			}
+		}
+		if !resolved {
+			pm.Unresolved = append(pm.Unresolved, step)
+		}
+	}
+
+	pm.NextSteps = nextSteps(runErr, pm)
+	return pm
+}
+
+// stepTarget picks the argument that best identifies a tool call.
+func stepTarget(args string) string {
+	var m map[string]any
+	if json.Unmarshal([]byte(args), &m) == nil {
+		for _, key := range []string{"command", "file_path", "path", "pattern", "url", "query", "task", "name"} {
+			if v, ok := m[key].(string); ok && v != "" {
+				return oneLine(v, 120)
+			}
+		}
+		if argv, ok := m["argv"].([]any); ok {
+			parts := make([]string, len(argv))
+			for i, a := range argv {
+				parts[i] = fmt.Sprint(a)
+			}
+			return oneLine(strings.Join(parts, " "), 120)
+		}
+	}
+	return oneLine(args, 120)
+}
+
+func oneLine(s string, n int) string {
+	s, _, cut := strings.Cut(s, "\n")
+	if len(s) > n {
+		s, cut = s[:n], true
+	}
+	if cut {
+		s += "..."
+	}
+	return s
+}
+
+// nextSteps suggests what to do about the failure.
+func nextSteps(runErr error, pm *PostMortem) []string {
+	var steps []string
+	var apiErr *llm.APIError
+	var hinted interface{ Hint() string }
+	switch {
+	case errors.Is(runErr, context.DeadlineExceeded), errors.Is(runErr, context.Canceled):
+		steps = append(steps, "The run was cancelled or timed out; rerun it with more time or a narrower task.")
+	case errors.As(runErr, &hinted):
+		steps = append(steps, hinted.Hint())
+	case errors.As(runErr, &apiErr) && (apiErr.StatusCode == 401 || apiErr.StatusCode == 403):
+		steps = append(steps, "The provider rejected the API key; check OPENROUTER_API_KEY or api_key.")
+	case errors.As(runErr, &apiErr) && apiErr.StatusCode == 429:
+		steps = append(steps, "The provider rate-limited the run; retry later or run fewer jobs at once.")
+	case errors.As(runErr, &apiErr) && apiErr.StatusCode >= 500:
+		steps = append(steps, "The provider failed; retry the run, or try another model with -model.")
+	case runErr != nil:
+		steps = append(steps, "Check the error above, then rerun the prompt.")
+	}
+	for _, step := range pm.Unresolved {
+		steps = append(steps, fmt.Sprintf("Look into the failed %s call (%s): %s", step.Tool, step.Target, step.Result))
+	}
+	if len(pm.FilesTouched) > 0 {
+		steps = append(steps, fmt.Sprintf("Review or revert the partial changes to %d file(s) before rerunning.", len(pm.FilesTouched)))
+	}
+	steps = append(steps, fmt.Sprintf("Read the whole transcript with `stormtrooper share %s`.", pm.SessionID))
+	return steps
+}
+
+// Markdown renders the post-mortem.
+func (pm *PostMortem) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Post-mortem: session %s\n\n", pm.SessionID)
+	fmt.Fprintf(&b, "## Task\n\n%s\n\n", orNone(strings.TrimSpace(pm.Task)))
+	fmt.Fprintf(&b, "## Last error\n\n%s\n\n", orNone(pm.Error))
+
+	b.WriteString("## What was attempted\n\n")
+	steps := pm.Steps
+	if len(steps) > maxPostMortemSteps {
+		fmt.Fprintf(&b, "(%d earlier tool calls omitted)\n", len(steps)-maxPostMortemSteps)
+		steps = steps[len(steps)-maxPostMortemSteps:]
+	}
+	if len(steps) == 0 {
+		b.WriteString("No tools were called.\n")
+	}
+	for i, step := range steps {
+		status := "ok"
+		if step.Failed {
+			status = "FAILED"
+		}
+		fmt.Fprintf(&b, "%d. %s `%s`: %s\n", i+1, step.Tool, step.Target, status)
+	}
+
+	b.WriteString("\n## Unresolved tool failures\n\n")
+	if len(pm.Unresolved) == 0 {
+		b.WriteString("None.\n")
+	}
+	for _, step := range pm.Unresolved {
+		fmt.Fprintf(&b, "- %s `%s`: %s\n", step.Tool, step.Target, step.Result)
+	}
+
+	b.WriteString("\n## Files touched\n\n")
+	if len(pm.FilesTouched) == 0 {
+		b.WriteString("None.\n")
+	}
+	for _, f := range pm.FilesTouched {
+		fmt.Fprintf(&b, "- %s\n", f)
+	}
+
+	b.WriteString("\n## Suggested next steps\n\n")
+	for _, s := range pm.NextSteps {
+		fmt.Fprintf(&b, "- %s\n", s)
+	}
+	return b.String()
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+	return s
+}
+
+// SavePostMortem writes the session's post-mortem next to the session
+// file, as <dir>/<id>.postmortem.md, and returns its path.
+func (s *Session) SavePostMortem(dir string, runErr error) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, s.ID+".postmortem.md")
+	return path, os.WriteFile(path, []byte(s.PostMortem(runErr).Markdown()), 0644)
+}
@@ -0,0 +1,89 @@
+package session
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gavinyap/stormtrooper/internal/llm"
+)
+
+func call(id, name, args string) llm.ToolCall {
+	tc := llm.ToolCall{ID: id, Type: "function"}
+	tc.Function.Name = name
+	tc.Function.Arguments = args
+	return tc
+}
+
+func failedRun() *Session {
+	s := New("/work", "m")
+	s.Messages = []llm.Message{
+		{Role: "system", Content: "sys"},
+		{Role: "user", Content: "fix the build"},
+		{Role: "assistant", ToolCalls: []llm.ToolCall{
+			call("1", "shell_exec", `{"command":"go build ./..."}`),
+			call("2", "edit_file", `{"file_path":"main.go","old_string":"a","new_string":"b"}`),
+		}},
+		{Role: "tool", ToolCallID: "1", Name: "shell_exec", Content: "Exit code: 1\nmain.go:3: undefined: foo"},
+		{Role: "tool", ToolCallID: "2", Name: "edit_file", Content: "Edited main.go"},
+		{Role: "assistant", ToolCalls: []llm.ToolCall{
+			call("3", "shell_exec", `{"command":"go test ./..."}`),
+		}},
+		{Role: "tool", ToolCallID: "3", Name: "shell_exec", Content: "Exit code: 2\nFAIL"},
+		{Role: "assistant", ToolCalls: []llm.ToolCall{
+			call("4", "shell_exec", `{"command":"go build ./..."}`),
+		}},
+		{Role: "tool", ToolCallID: "4", Name: "shell_exec", Content: ""},
+	}
+	return s
+}
+
+func TestPostMortem(t *testing.T) {
+	runErr := fmt.Errorf("LLM request failed: %w", &llm.APIError{StatusCode: 429, Body: "slow down"})
+	pm := failedRun().PostMortem(runErr)
+
+	if pm.Task != "fix the build" || !strings.Contains(pm.Error, "slow down") {
+		t.Errorf("task %q, error %q", pm.Task, pm.Error)
+	}
+	if len(pm.Steps) != 4 || !pm.Steps[0].Failed || pm.Steps[1].Failed {
+		t.Errorf("steps = %+v", pm.Steps)
+	}
+	// The failed build was retried successfully; the tests were not.
+	if len(pm.Unresolved) != 1 || pm.Unresolved[0].Target != "go test ./..." || pm.Unresolved[0].Result != "Exit code: 2" {
+		t.Errorf("unresolved = %+v", pm.Unresolved)
+	}
+	if len(pm.FilesTouched) != 1 || pm.FilesTouched[0] != "main.go" {
+		t.Errorf("files = %v", pm.FilesTouched)
+	}
+	if !strings.Contains(pm.NextSteps[0], "rate-limited") {
+		t.Errorf("next steps = %q", pm.NextSteps)
+	}
+
+	// A moderation block is a 403 too, but not a key problem.
+	runErr = fmt.Errorf("LLM request failed: %w", &llm.ModerationError{APIError: &llm.APIError{StatusCode: 403}})
+	if steps := failedRun().PostMortem(runErr).NextSteps; !strings.HasPrefix(steps[0], "Rephrase the request") {
+		t.Errorf("next steps for moderation = %q", steps)
+	}
+}
+
+func TestSavePostMortem(t *testing.T) {
+	dir := t.TempDir()
+	s := failedRun()
+	path, err := s.SavePostMortem(dir, fmt.Errorf("agent cancelled"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(path, s.ID+".postmortem.md") {
+		t.Errorf("path = %s", path)
+	}
+	data, _ := os.ReadFile(path)
+	for _, want := range []string{"## Last error\n\nagent cancelled", "shell_exec `go test ./...`: FAILED", "- main.go", "stormtrooper share " + s.ID} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("post-mortem missing %q:\n%s", want, data)
+		}
+	}
+	if sessions, _ := List(dir); len(sessions) != 0 {
+		t.Error("the post-mortem should not be listed as a session")
+	}
+}
@@ -0,0 +1,156 @@
+// Package session saves conversations to .stormtrooper/sessions/ so they
+// can be inspected, compared, and resumed after the fact.
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gavinyap/stormtrooper/internal/llm"
+)
+
+const sessionsDir = ".stormtrooper/sessions"
+
+// Session is a saved conversation.
+type Session struct {
+	ID         string        `json:"id"`
+	Model      string        `json:"model"`
+	WorkingDir string        `json:"working_dir"`
+	Started    time.Time     `json:"started"`
+	Updated    time.Time     `json:"updated"`
+	Messages   []llm.Message `json:"messages"`
+}
+
+// Dir returns the sessions directory for the given project directory.
+func Dir(projectDir string) string {
+	return filepath.Join(projectDir, sessionsDir)
+}
+
+// New creates an unsaved session whose ID is derived from the start time.
+func New(projectDir, model string) *Session {
+	now := time.Now()
+	return &Session{
+		ID:         now.Format("20060102-150405"),
+		Model:      model,
+		WorkingDir: projectDir,
+		Started:    now,
+		Updated:    now,
+	}
+}
+
+// Save writes the session to <dir>/<id>.json.
+func (s *Session) Save(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	s.Updated = time.Now()
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, s.ID+".json"), data, 0644)
+}
+
+// Load reads a session file.
+func Load(path string) (*Session, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var s Session
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("invalid session %s: %w", path, err)
+	}
+	return &s, nil
+}
+
+// Resolve loads a session given either a file path or a session ID in
+// dir; "last" is the most recently updated session.
+func Resolve(dir, ref string) (*Session, error) {
+	if ref == "last" {
+		return Latest(dir)
+	}
+	if _, err := os.Stat(ref); err == nil {
+		return Load(ref)
+	}
+	path := filepath.Join(dir, strings.TrimSuffix(ref, ".json")+".json")
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("session not found: %s", ref)
+	}
+	return Load(path)
+}
+
+// List returns the sessions in dir, oldest first. A missing directory
+// yields no sessions.
+func List(dir string) ([]*Session, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var sessions []*Session
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".json" {
+			continue
+		}
+		s, err := Load(filepath.Join(dir, e.Name()))
+		if err != nil {
+			continue // skip unreadable files rather than failing the listing
+		}
+		sessions = append(sessions, s)
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].Started.Before(sessions[j].Started)
+	})
+	return sessions, nil
+}
+
+// Latest returns the most recently updated session in dir.
+func Latest(dir string) (*Session, error) {
+	sessions, err := List(dir)
+	if err != nil {
+		return nil, err
+	}
+	var latest *Session
+	for _, s := range sessions {
+		if latest == nil || s.Updated.After(latest.Updated) {
+			latest = s
+		}
+	}
+	if latest == nil {
+		return nil, fmt.Errorf("no saved sessions in %s", dir)
+	}
+	return latest, nil
+}
+
+// Title returns the first line of the first prompt, shortened for lists.
+func (s *Session) Title() string {
+	prompts := s.Prompts()
+	if len(prompts) == 0 {
+		return ""
+	}
+	title, _, _ := strings.Cut(strings.TrimSpace(prompts[0]), "\n")
+	if len(title) > 60 {
+		title = title[:60] + "..."
+	}
+	return title
+}
+
+// Prompts returns the user messages in order.
+func (s *Session) Prompts() []string {
+	var prompts []string
+	for _, m := range s.Messages {
+		if m.Role == "user" {
+			prompts = append(prompts, m.Content)
+		}
+	}
+	return prompts
+}
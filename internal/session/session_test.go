@@ -0,0 +1,120 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gavinyap/stormtrooper/internal/llm"
+)
+
+func TestSaveAndLoad(t *testing.T) {
+	dir := t.TempDir()
+	s := New("/work", "model-a")
+	s.Messages = []llm.Message{
+		{Role: "system", Content: "sys"},
+		{Role: "user", Content: "hello"},
+		{Role: "assistant", Content: "hi"},
+	}
+	if err := s.Save(Dir(dir)); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	loaded, err := Load(filepath.Join(Dir(dir), s.ID+".json"))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if loaded.Model != "model-a" || loaded.WorkingDir != "/work" || len(loaded.Messages) != 3 {
+		t.Errorf("unexpected session %+v", loaded)
+	}
+	if got := loaded.Prompts(); len(got) != 1 || got[0] != "hello" {
+		t.Errorf("Prompts() = %v", got)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	dir := t.TempDir()
+	s := New(dir, "m")
+	s.Save(dir)
+
+	for _, ref := range []string{s.ID, s.ID + ".json", filepath.Join(dir, s.ID+".json")} {
+		got, err := Resolve(dir, ref)
+		if err != nil {
+			t.Errorf("Resolve(%q): %v", ref, err)
+			continue
+		}
+		if got.ID != s.ID {
+			t.Errorf("Resolve(%q) returned %q", ref, got.ID)
+		}
+	}
+
+	if _, err := Resolve(dir, "nope"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected not found error, got %v", err)
+	}
+}
+
+func TestList(t *testing.T) {
+	dir := t.TempDir()
+
+	sessions, err := List(filepath.Join(dir, "missing"))
+	if err != nil || len(sessions) != 0 {
+		t.Fatalf("missing dir: got %v, %v", sessions, err)
+	}
+
+	older := &Session{ID: "b", Started: time.Now().Add(-time.Hour)}
+	newer := &Session{ID: "a", Started: time.Now()}
+	newer.Save(dir)
+	older.Save(dir)
+	os.WriteFile(filepath.Join(dir, "broken.json"), []byte("{"), 0644)
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("x"), 0644)
+
+	sessions, err = List(dir)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(sessions) != 2 || sessions[0].ID != "b" || sessions[1].ID != "a" {
+		t.Errorf("expected sessions oldest first, got %+v", sessions)
+	}
+}
+
+func TestLatest(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := Latest(dir); err == nil {
+		t.Error("expected an error with no sessions")
+	}
+
+	// The most recently updated session wins, not the most recently started.
+	resumed := &Session{ID: "old", Started: time.Now().Add(-time.Hour)}
+	other := &Session{ID: "new", Started: time.Now()}
+	other.Save(dir)
+	resumed.Save(dir)
+
+	for _, get := range []func() (*Session, error){
+		func() (*Session, error) { return Latest(dir) },
+		func() (*Session, error) { return Resolve(dir, "last") },
+	} {
+		s, err := get()
+		if err != nil || s.ID != "old" {
+			t.Errorf("expected the session saved last, got %v, %v", s, err)
+		}
+	}
+}
+
+func TestTitle(t *testing.T) {
+	s := &Session{Messages: []llm.Message{
+		{Role: "system", Content: "sys"},
+		{Role: "user", Content: "  fix the build\nit fails on CI"},
+	}}
+	if got := s.Title(); got != "fix the build" {
+		t.Errorf("Title() = %q", got)
+	}
+	s.Messages[1].Content = strings.Repeat("x", 70)
+	if got := s.Title(); got != strings.Repeat("x", 60)+"..." {
+		t.Errorf("Title() = %q", got)
+	}
+	if got := (&Session{}).Title(); got != "" {
+		t.Errorf("Title() of an empty session = %q", got)
+	}
+}
@@ -0,0 +1,214 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/gavinyap/stormtrooper/internal/secrets"
+)
+
+// Share formats.
+const (
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+)
+
+// ShareOptions controls how a session is exported.
+type ShareOptions struct {
+	Format     string // FormatMarkdown (default) or FormatHTML
+	StripFiles bool   // omit file contents read or written by the file tools
+}
+
+// fileContentArgs are the arguments of the file tools that carry file
+// contents, at the top level or in each entry of a files list.
+var fileContentArgs = map[string][]string{
+	"write_file":  {"content"},
+	"write_files": {"content"},
+	"edit_file":   {"old_string", "new_string"},
+}
+
+const omitted = "(file contents omitted)"
+
+// entry is one item of a shared transcript.
+type entry struct {
+	role string // "user", "assistant", or "tool"
+	text string // message text, or the tool's result
+	tool string
+	args string
+}
+
+// Share renders the session as a self-contained document for attaching to
+// bug reports. System messages are left out, tool calls are collapsed,
+// and secrets are redacted; the number redacted is returned.
+func (s *Session) Share(opts ShareOptions) (string, int, error) {
+	entries := s.shareEntries(opts.StripFiles)
+	redacted := 0
+	redact := func(text string) string {
+		text, n := secrets.Redact(text)
+		redacted += n
+		return text
+	}
+	for i := range entries {
+		entries[i].text = redact(entries[i].text)
+		entries[i].args = redact(entries[i].args)
+	}
+
+	switch opts.Format {
+	case "", FormatMarkdown, "md":
+		return s.shareMarkdown(entries), redacted, nil
+	case FormatHTML:
+		return s.shareHTML(entries), redacted, nil
+	}
+	return "", 0, fmt.Errorf("unsupported format %q (use markdown or html)", opts.Format)
+}
+
+// shareEntries pairs each tool result with the call that produced it.
+func (s *Session) shareEntries(stripFiles bool) []entry {
+	calls := map[string]struct{ name, args string }{}
+	var entries []entry
+	for _, m := range s.Messages {
+		switch m.Role {
+		case "user":
+			entries = append(entries, entry{role: "user", text: m.Content})
+		case "assistant":
+			if strings.TrimSpace(m.Content) != "" {
+				entries = append(entries, entry{role: "assistant", text: m.Content})
+			}
+			for _, tc := range m.ToolCalls {
+				calls[tc.ID] = struct{ name, args string }{tc.Function.Name, tc.Function.Arguments}
+			}
+		case "tool":
+			call := calls[m.ToolCallID]
+			e := entry{role: "tool", tool: call.name, args: call.args, text: m.Content}
+			if e.tool == "" {
+				e.tool = m.Name
+			}
+			if stripFiles {
+				e.args = stripArgs(e.tool, e.args)
+				if e.tool == "read_file" && !strings.HasPrefix(e.text, "Error:") {
+					e.text = omitted
+				}
+			}
+			e.args = indentJSON(e.args)
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// stripArgs replaces the file contents in a file tool's arguments.
+func stripArgs(tool, args string) string {
+	keys := fileContentArgs[tool]
+	if len(keys) == 0 {
+		return args
+	}
+	var m map[string]any
+	if err := json.Unmarshal([]byte(args), &m); err != nil {
+		return args
+	}
+	objects := []map[string]any{m}
+	if files, ok := m["files"].([]any); ok {
+		for _, f := range files {
+			if obj, ok := f.(map[string]any); ok {
+				objects = append(objects, obj)
+			}
+		}
+	}
+	for _, obj := range objects {
+		for _, k := range keys {
+			if _, ok := obj[k]; ok {
+				obj[k] = omitted
+			}
+		}
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return args
+	}
+	return string(data)
+}
+
+// indentJSON pretty-prints args, or returns them unchanged if they are
+// not JSON.
+func indentJSON(args string) string {
+	var v any
+	if err := json.Unmarshal([]byte(args), &v); err != nil {
+		return args
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return args
+	}
+	return string(data)
+}
+
+func (s *Session) shareMarkdown(entries []entry) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Stormtrooper session %s\n\n", s.ID)
+	fmt.Fprintf(&b, "- Model: %s\n- Started: %s\n", s.Model, s.Started.Format("2006-01-02 15:04:05 MST"))
+	for _, e := range entries {
+		switch e.role {
+		case "user":
+			fmt.Fprintf(&b, "\n## You\n\n%s\n", strings.TrimSpace(e.text))
+		case "assistant":
+			fmt.Fprintf(&b, "\n## Assistant\n\n%s\n", strings.TrimSpace(e.text))
+		case "tool":
+			fmt.Fprintf(&b, "\n<details>\n<summary>Tool: %s</summary>\n\n", html.EscapeString(e.tool))
+			if e.args != "" {
+				f := fence(e.args)
+				fmt.Fprintf(&b, "%sjson\n%s\n%s\n\n", f, e.args, f)
+			}
+			f := fence(e.text)
+			fmt.Fprintf(&b, "%s\n%s\n%s\n\n</details>\n", f, strings.TrimRight(e.text, "\n"), f)
+		}
+	}
+	return b.String()
+}
+
+// fence returns a code fence longer than any backtick run in text.
+func fence(text string) string {
+	longest, run := 0, 0
+	for _, r := range text {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return strings.Repeat("`", max(3, longest+1))
+}
+
+const shareStyle = `body{font-family:system-ui,sans-serif;max-width:50rem;margin:2rem auto;padding:0 1rem;line-height:1.5}
+h2{font-size:1rem;margin:1.5rem 0 .25rem}
+.meta{color:#666}
+.text{white-space:pre-wrap}
+details{margin:.5rem 0;border:1px solid #ddd;border-radius:4px;padding:.25rem .5rem}
+summary{cursor:pointer;color:#555}
+pre{background:#f6f8fa;padding:.5rem;overflow-x:auto}`
+
+func (s *Session) shareHTML(entries []entry) string {
+	var b strings.Builder
+	title := html.EscapeString("Stormtrooper session " + s.ID)
+	fmt.Fprintf(&b, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n<style>\n%s\n</style>\n</head>\n<body>\n", title, shareStyle)
+	fmt.Fprintf(&b, "<h1>%s</h1>\n<p class=\"meta\">Model: %s<br>Started: %s</p>\n", title,
+		html.EscapeString(s.Model), s.Started.Format("2006-01-02 15:04:05 MST"))
+	for _, e := range entries {
+		switch e.role {
+		case "user":
+			fmt.Fprintf(&b, "<h2>You</h2>\n<div class=\"text\">%s</div>\n", html.EscapeString(strings.TrimSpace(e.text)))
+		case "assistant":
+			fmt.Fprintf(&b, "<h2>Assistant</h2>\n<div class=\"text\">%s</div>\n", html.EscapeString(strings.TrimSpace(e.text)))
+		case "tool":
+			fmt.Fprintf(&b, "<details>\n<summary>Tool: %s</summary>\n", html.EscapeString(e.tool))
+			if e.args != "" {
+				fmt.Fprintf(&b, "<pre>%s</pre>\n", html.EscapeString(e.args))
+			}
+			fmt.Fprintf(&b, "<pre>%s</pre>\n</details>\n", html.EscapeString(strings.TrimRight(e.text, "\n")))
+		}
+	}
+	b.WriteString("</body>\n</html>\n")
+	return b.String()
+}
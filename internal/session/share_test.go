@@ -0,0 +1,73 @@
+package session
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gavinyap/stormtrooper/internal/llm"
+)
+
+func shareSession() *Session {
+	s := New("/work", "model-a")
+	s.Messages = []llm.Message{
+		{Role: "system", Content: "system prompt"},
+		{Role: "user", Content: "my key is sk-or-v1-abcdefghijklmnopqrstuvwxyz, fix main.go"},
+		{Role: "assistant", ToolCalls: []llm.ToolCall{
+			{ID: "1", Function: llm.FunctionCall{Name: "read_file", Arguments: `{"file_path":"main.go"}`}},
+			{ID: "2", Function: llm.FunctionCall{Name: "write_file", Arguments: `{"file_path":"main.go","content":"package main // v2"}`}},
+			{ID: "3", Function: llm.FunctionCall{Name: "write_files", Arguments: `{"files":[{"file_path":"util.go","content":"package main // v3"}]}`}},
+		}},
+		{Role: "tool", ToolCallID: "1", Content: "package main // v1 <b>"},
+		{Role: "tool", ToolCallID: "2", Content: "Wrote main.go"},
+		{Role: "tool", ToolCallID: "3", Content: "Files written: 1"},
+		{Role: "assistant", Content: "Fixed."},
+	}
+	return s
+}
+
+func TestShare_Markdown(t *testing.T) {
+	out, redacted, err := shareSession().Share(ShareOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if redacted != 1 || strings.Contains(out, "abcdefghijklmnop") {
+		t.Errorf("expected the API key redacted (%d):\n%s", redacted, out)
+	}
+	if strings.Contains(out, "system prompt") {
+		t.Error("system messages should be left out")
+	}
+	for _, want := range []string{"## You", "## Assistant\n\nFixed.", "<summary>Tool: read_file</summary>", "package main // v1", `"file_path": "main.go"`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestShare_HTMLStripFiles(t *testing.T) {
+	out, _, err := shareSession().Share(ShareOptions{Format: FormatHTML, StripFiles: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out, "<!DOCTYPE html>") || !strings.Contains(out, "<details>") {
+		t.Errorf("expected an HTML document with collapsed tools:\n%s", out)
+	}
+	if strings.Contains(out, "v1") || strings.Contains(out, "v2") || strings.Contains(out, "v3") {
+		t.Errorf("file contents should be stripped:\n%s", out)
+	}
+	if !strings.Contains(out, "Wrote main.go") || !strings.Contains(out, omitted) {
+		t.Errorf("expected tool results kept and contents marked omitted:\n%s", out)
+	}
+
+	if _, _, err := shareSession().Share(ShareOptions{Format: "pdf"}); err == nil {
+		t.Error("expected an error for an unsupported format")
+	}
+}
+
+func TestFence(t *testing.T) {
+	if got := fence("a ``` b"); got != "````" {
+		t.Errorf("fence = %q", got)
+	}
+	if got := fence("plain"); got != "```" {
+		t.Errorf("fence = %q", got)
+	}
+}
@@ -0,0 +1,170 @@
+// Package slack runs the agent as a Slack bot. It connects over Socket
+// Mode, so no public URL is needed; every thread that mentions the bot
+// gets its own agent session, replies stream into a message that is
+// edited as tokens arrive, and permission prompts become buttons.
+package slack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gavinyap/stormtrooper/internal/config"
+)
+
+// API is a client for the parts of the Slack Web API the bot uses.
+type API struct {
+	http     *http.Client
+	baseURL  string
+	appToken string
+	botToken string
+}
+
+// NewAPI creates a client from cfg. The tokens default to
+// $SLACK_APP_TOKEN and $SLACK_BOT_TOKEN.
+func NewAPI(cfg config.SlackConfig) (*API, error) {
+	a := &API{
+		http:     &http.Client{Timeout: 30 * time.Second},
+		baseURL:  "https://slack.com/api",
+		appToken: cfg.AppToken,
+		botToken: cfg.BotToken,
+	}
+	if a.appToken == "" {
+		a.appToken = os.Getenv("SLACK_APP_TOKEN")
+	}
+	if a.botToken == "" {
+		a.botToken = os.Getenv("SLACK_BOT_TOKEN")
+	}
+	if a.appToken == "" {
+		return nil, errors.New("slack: requires app_token or SLACK_APP_TOKEN")
+	}
+	if a.botToken == "" {
+		return nil, errors.New("slack: requires bot_token or SLACK_BOT_TOKEN")
+	}
+	return a, nil
+}
+
+// SetBaseURL points the client at another server, for tests.
+func (a *API) SetBaseURL(url string) {
+	a.baseURL = strings.TrimSuffix(url, "/")
+}
+
+// Message is the content of a chat message. Blocks, if set, are shown
+// instead of Text, which remains the notification fallback.
+type Message struct {
+	Text   string  `json:"text"`
+	Blocks []Block `json:"blocks,omitempty"`
+}
+
+// Block is a Block Kit layout block.
+type Block map[string]any
+
+// OpenConnection returns a Socket Mode WebSocket URL.
+func (a *API) OpenConnection(ctx context.Context) (string, error) {
+	var out struct {
+		URL string `json:"url"`
+	}
+	if err := a.call(ctx, a.appToken, "apps.connections.open", nil, &out); err != nil {
+		return "", err
+	}
+	return out.URL, nil
+}
+
+// BotUserID returns the bot's own user ID, used to recognize mentions.
+func (a *API) BotUserID(ctx context.Context) (string, error) {
+	var out struct {
+		UserID string `json:"user_id"`
+	}
+	if err := a.call(ctx, a.botToken, "auth.test", nil, &out); err != nil {
+		return "", err
+	}
+	return out.UserID, nil
+}
+
+// PostMessage posts msg in channel, in the thread threadTS if it is set,
+// and returns the new message's timestamp.
+func (a *API) PostMessage(ctx context.Context, channel, threadTS string, msg Message) (string, error) {
+	body := map[string]any{"channel": channel, "text": msg.Text}
+	if threadTS != "" {
+		body["thread_ts"] = threadTS
+	}
+	if msg.Blocks != nil {
+		body["blocks"] = msg.Blocks
+	}
+	var out struct {
+		TS string `json:"ts"`
+	}
+	if err := a.call(ctx, a.botToken, "chat.postMessage", body, &out); err != nil {
+		return "", err
+	}
+	return out.TS, nil
+}
+
+// UpdateMessage replaces the content of the message ts in channel.
+func (a *API) UpdateMessage(ctx context.Context, channel, ts string, msg Message) error {
+	body := map[string]any{"channel": channel, "ts": ts, "text": msg.Text}
+	// An empty list removes earlier blocks, such as permission buttons.
+	if msg.Blocks != nil {
+		body["blocks"] = msg.Blocks
+	} else {
+		body["blocks"] = []Block{}
+	}
+	return a.call(ctx, a.botToken, "chat.update", body, nil)
+}
+
+// call invokes a Web API method with a JSON body and decodes the JSON
+// response into out (when non-nil). Slack reports failures with
+// "ok": false and an error code rather than an HTTP status.
+func (a *API) call(ctx context.Context, token, method string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.baseURL+"/"+method, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	}
+	resp, err := a.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("slack %s: %s: %s", method, resp.Status, strings.TrimSpace(string(data)))
+	}
+	var status struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(data, &status); err != nil {
+		return fmt.Errorf("slack %s: decoding response: %w", method, err)
+	}
+	if !status.OK {
+		return fmt.Errorf("slack %s: %s", method, status.Error)
+	}
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			return fmt.Errorf("slack %s: decoding response: %w", method, err)
+		}
+	}
+	return nil
+}
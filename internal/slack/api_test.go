@@ -0,0 +1,168 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gavinyap/stormtrooper/internal/config"
+	"golang.org/x/net/websocket"
+)
+
+// apiCall is a Web API request received by fakeSlack.
+type apiCall struct {
+	Method string
+	Token  string
+	Body   map[string]any
+}
+
+// fakeSlack serves the Web API methods the bot uses and a Socket Mode
+// endpoint that delivers the envelopes sent on its events channel.
+type fakeSlack struct {
+	srv    *httptest.Server
+	events chan envelope
+	acks   chan string
+
+	mu    sync.Mutex
+	calls []apiCall
+	ts    int
+}
+
+func newFakeSlack(t *testing.T) *fakeSlack {
+	t.Helper()
+	f := &fakeSlack{events: make(chan envelope, 16), acks: make(chan string, 16)}
+	mux := http.NewServeMux()
+	mux.Handle("/socket", websocket.Handler(func(conn *websocket.Conn) {
+		go func() {
+			for {
+				var ack map[string]string
+				if websocket.JSON.Receive(conn, &ack) != nil {
+					return
+				}
+				f.acks <- ack["envelope_id"]
+			}
+		}()
+		websocket.JSON.Send(conn, envelope{Type: "hello"})
+		for env := range f.events {
+			if websocket.JSON.Send(conn, env) != nil {
+				return
+			}
+		}
+	}))
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		call := apiCall{Method: strings.TrimPrefix(r.URL.Path, "/"), Token: strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "), Body: body}
+		f.mu.Lock()
+		f.calls = append(f.calls, call)
+		f.ts++
+		ts := strconv.Itoa(f.ts)
+		f.mu.Unlock()
+		switch call.Method {
+		case "apps.connections.open":
+			w.Write([]byte(`{"ok":true,"url":"ws` + strings.TrimPrefix(f.srv.URL, "http") + `/socket"}`))
+		case "auth.test":
+			w.Write([]byte(`{"ok":true,"user_id":"UBOT"}`))
+		case "chat.postMessage":
+			w.Write([]byte(`{"ok":true,"ts":"` + ts + `"}`))
+		case "chat.update":
+			w.Write([]byte(`{"ok":true}`))
+		default:
+			w.Write([]byte(`{"ok":false,"error":"unknown_method"}`))
+		}
+	})
+	f.srv = httptest.NewServer(mux)
+	t.Cleanup(func() {
+		close(f.events)
+		f.srv.CloseClientConnections()
+		f.srv.Close()
+	})
+	return f
+}
+
+func (f *fakeSlack) api(t *testing.T) *API {
+	t.Helper()
+	a, err := NewAPI(config.SlackConfig{AppToken: "xapp-test", BotToken: "xoxb-test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.SetBaseURL(f.srv.URL)
+	return a
+}
+
+// Calls returns the Web API calls so far to the given method.
+func (f *fakeSlack) Calls(method string) []apiCall {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var out []apiCall
+	for _, c := range f.calls {
+		if c.Method == method {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func TestNewAPI_TokensFromEnv(t *testing.T) {
+	t.Setenv("SLACK_APP_TOKEN", "xapp-env")
+	t.Setenv("SLACK_BOT_TOKEN", "")
+	if _, err := NewAPI(config.SlackConfig{}); err == nil || !strings.Contains(err.Error(), "SLACK_BOT_TOKEN") {
+		t.Fatalf("expected a missing bot token error, got %v", err)
+	}
+	t.Setenv("SLACK_BOT_TOKEN", "xoxb-env")
+	a, err := NewAPI(config.SlackConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.appToken != "xapp-env" || a.botToken != "xoxb-env" {
+		t.Errorf("tokens = %q, %q", a.appToken, a.botToken)
+	}
+}
+
+func TestAPI(t *testing.T) {
+	f := newFakeSlack(t)
+	a := f.api(t)
+	ctx := context.Background()
+
+	url, err := a.OpenConnection(ctx)
+	if err != nil || !strings.HasSuffix(url, "/socket") {
+		t.Fatalf("OpenConnection = %q, %v", url, err)
+	}
+	if tok := f.Calls("apps.connections.open")[0].Token; tok != "xapp-test" {
+		t.Errorf("apps.connections.open should use the app token, got %q", tok)
+	}
+
+	ts, err := a.PostMessage(ctx, "C1", "100.1", Message{Text: "hi"})
+	if err != nil || ts == "" {
+		t.Fatalf("PostMessage = %q, %v", ts, err)
+	}
+	post := f.Calls("chat.postMessage")[0]
+	if post.Token != "xoxb-test" || post.Body["channel"] != "C1" || post.Body["thread_ts"] != "100.1" || post.Body["text"] != "hi" {
+		t.Errorf("unexpected postMessage call %+v", post)
+	}
+
+	if err := a.UpdateMessage(ctx, "C1", ts, Message{Text: "edited"}); err != nil {
+		t.Fatal(err)
+	}
+	update := f.Calls("chat.update")[0]
+	if update.Body["ts"] != ts || update.Body["text"] != "edited" {
+		t.Errorf("unexpected update call %+v", update)
+	}
+	if blocks, ok := update.Body["blocks"].([]any); !ok || len(blocks) != 0 {
+		t.Errorf("an update without blocks should clear them, got %v", update.Body["blocks"])
+	}
+}
+
+func TestAPI_ReportsSlackErrors(t *testing.T) {
+	f := newFakeSlack(t)
+	a := f.api(t)
+	err := a.call(context.Background(), a.botToken, "chat.delete", nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "chat.delete: unknown_method") {
+		t.Errorf("expected the Slack error code, got %v", err)
+	}
+}
@@ -0,0 +1,367 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gavinyap/stormtrooper/internal/agent"
+	"github.com/gavinyap/stormtrooper/internal/permission"
+	"golang.org/x/net/websocket"
+)
+
+// DefaultUpdateInterval is the minimum time between edits of a streaming
+// reply, which keeps the bot within Slack's rate limit for chat.update.
+const DefaultUpdateInterval = time.Second
+
+// queueLen is how many messages a thread holds while its agent is busy.
+const queueLen = 16
+
+// Options configure a Bot.
+type Options struct {
+	API *API
+	// NewAgent creates the agent for a new thread. perm asks for
+	// permission in that thread.
+	NewAgent func(perm permission.Handler) *agent.Agent
+	// Channels, if set, are the only channel IDs the bot answers in.
+	Channels []string
+	// Log receives connection status and the agents' tool status lines.
+	Log io.Writer
+
+	UpdateInterval    time.Duration // default DefaultUpdateInterval
+	PermissionTimeout time.Duration // default DefaultPermissionTimeout
+	ReconnectDelay    time.Duration // default 5s
+}
+
+// Bot answers Slack messages with agent sessions, one per thread.
+type Bot struct {
+	api      *API
+	newAgent func(permission.Handler) *agent.Agent
+	channels []string
+	log      io.Writer
+
+	updateInterval    time.Duration
+	permissionTimeout time.Duration
+	reconnectDelay    time.Duration
+
+	mu      sync.Mutex
+	botUser string
+	threads map[string]*thread
+	prompts map[string]pendingPrompt
+	nextID  int
+}
+
+// thread is an agent session bound to a Slack thread.
+type thread struct {
+	channel string
+	ts      string // timestamp of the thread's first message
+	agent   *agent.Agent
+	queue   chan request
+
+	mu   sync.Mutex
+	user string // sender of the message being answered
+}
+
+// request is a message for a thread's agent.
+type request struct {
+	user string
+	text string
+}
+
+// New creates a Bot.
+func New(opts Options) *Bot {
+	b := &Bot{
+		api:               opts.API,
+		newAgent:          opts.NewAgent,
+		channels:          opts.Channels,
+		log:               opts.Log,
+		updateInterval:    opts.UpdateInterval,
+		permissionTimeout: opts.PermissionTimeout,
+		reconnectDelay:    opts.ReconnectDelay,
+		threads:           make(map[string]*thread),
+		prompts:           make(map[string]pendingPrompt),
+	}
+	if b.log == nil {
+		b.log = io.Discard
+	}
+	if b.updateInterval == 0 {
+		b.updateInterval = DefaultUpdateInterval
+	}
+	if b.permissionTimeout == 0 {
+		b.permissionTimeout = DefaultPermissionTimeout
+	}
+	if b.reconnectDelay == 0 {
+		b.reconnectDelay = 5 * time.Second
+	}
+	return b
+}
+
+// Run connects to Slack and answers messages until ctx is done,
+// reconnecting whenever Slack drops the connection. It returns an error
+// only if the first connection fails, which usually means a bad token.
+func (b *Bot) Run(ctx context.Context) error {
+	user, err := b.api.BotUserID(ctx)
+	if err != nil {
+		return err
+	}
+	b.mu.Lock()
+	b.botUser = user
+	b.mu.Unlock()
+
+	for first := true; ; first = false {
+		connected, err := b.connect(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if first && !connected {
+			return err
+		}
+		if err != nil {
+			fmt.Fprintf(b.log, "[slack] connection lost: %v; reconnecting\n", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(b.reconnectDelay):
+		}
+	}
+}
+
+// envelope is a Socket Mode message.
+type envelope struct {
+	EnvelopeID string          `json:"envelope_id"`
+	Type       string          `json:"type"`
+	Reason     string          `json:"reason"`
+	Payload    json.RawMessage `json:"payload"`
+}
+
+// connect opens one Socket Mode connection and handles envelopes until
+// it closes. It reports whether the connection was established.
+func (b *Bot) connect(ctx context.Context) (bool, error) {
+	url, err := b.api.OpenConnection(ctx)
+	if err != nil {
+		return false, err
+	}
+	cfg, err := websocket.NewConfig(url, "https://api.slack.com/")
+	if err != nil {
+		return false, err
+	}
+	conn, err := cfg.DialContext(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+	fmt.Fprintln(b.log, "[slack] connected")
+
+	for {
+		var env envelope
+		if err := websocket.JSON.Receive(conn, &env); err != nil {
+			return true, err
+		}
+		// Acknowledge first: Slack retries envelopes that are not
+		// acknowledged within three seconds.
+		if env.EnvelopeID != "" {
+			ack := map[string]string{"envelope_id": env.EnvelopeID}
+			if err := websocket.JSON.Send(conn, ack); err != nil {
+				return true, err
+			}
+		}
+		switch env.Type {
+		case "disconnect":
+			return true, fmt.Errorf("disconnected by Slack (%s)", env.Reason)
+		case "events_api":
+			b.handleEvent(ctx, env.Payload)
+		case "interactive":
+			b.handleInteraction(env.Payload)
+		}
+	}
+}
+
+// handleEvent starts or continues a thread's session. A mention starts
+// one; replies in a thread the bot is in need no mention.
+func (b *Bot) handleEvent(ctx context.Context, payload json.RawMessage) {
+	var p struct {
+		Event struct {
+			Type     string `json:"type"`
+			Subtype  string `json:"subtype"`
+			User     string `json:"user"`
+			BotID    string `json:"bot_id"`
+			Text     string `json:"text"`
+			Channel  string `json:"channel"`
+			TS       string `json:"ts"`
+			ThreadTS string `json:"thread_ts"`
+		} `json:"event"`
+	}
+	if err := json.Unmarshal(payload, &p); err != nil {
+		fmt.Fprintf(b.log, "[slack] bad event: %v\n", err)
+		return
+	}
+	ev := p.Event
+	// Skip edits, joins, and messages from bots, including our own.
+	if ev.Subtype != "" || ev.BotID != "" || ev.User == "" {
+		return
+	}
+	if len(b.channels) > 0 && !slices.Contains(b.channels, ev.Channel) {
+		return
+	}
+	root := ev.ThreadTS
+	if root == "" {
+		root = ev.TS
+	}
+
+	b.mu.Lock()
+	mention := "<@" + b.botUser + ">"
+	th := b.threads[ev.Channel+":"+root]
+	b.mu.Unlock()
+	switch ev.Type {
+	case "app_mention":
+		if th == nil {
+			th = b.startThread(ctx, ev.Channel, root)
+		}
+	case "message":
+		// Mentions also arrive as app_mention; answer those once.
+		if th == nil || strings.Contains(ev.Text, mention) {
+			return
+		}
+	default:
+		return
+	}
+
+	req := request{user: ev.User, text: stripMentions(ev.Text)}
+	select {
+	case th.queue <- req:
+	default:
+		b.api.PostMessage(ctx, ev.Channel, root, Message{Text: "_Still working on earlier messages; please wait and try again._"})
+	}
+}
+
+var mentionRE = regexp.MustCompile(`<@[A-Z0-9]+>`)
+
+// stripMentions removes user mentions, which the model cannot resolve.
+func stripMentions(text string) string {
+	return strings.TrimSpace(mentionRE.ReplaceAllString(text, ""))
+}
+
+// startThread creates the session for a thread and the goroutine that
+// answers its messages in order.
+func (b *Bot) startThread(ctx context.Context, channel, ts string) *thread {
+	th := &thread{channel: channel, ts: ts, queue: make(chan request, queueLen)}
+	th.agent = b.newAgent(&prompter{
+		bot:     b,
+		ctx:     ctx,
+		channel: channel,
+		thread:  ts,
+		user:    th.currentUser,
+		timeout: b.permissionTimeout,
+		log:     b.log,
+	})
+	b.mu.Lock()
+	b.threads[channel+":"+ts] = th
+	b.mu.Unlock()
+	fmt.Fprintf(b.log, "[slack] new session for thread %s in %s\n", ts, channel)
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case req := <-th.queue:
+				b.answer(ctx, th, req)
+			}
+		}
+	}()
+	return th
+}
+
+// answer runs one agent turn, streaming the reply into the thread.
+func (b *Bot) answer(ctx context.Context, th *thread, req request) {
+	th.mu.Lock()
+	th.user = req.user
+	th.mu.Unlock()
+
+	w := newReplyWriter(ctx, b.api, th.channel, th.ts, b.updateInterval, b.log)
+	th.agent.SetOutput(w, b.log)
+	err := th.agent.Send(ctx, req.text)
+	if errors.Is(err, context.Canceled) {
+		err = errors.New("stopped: the bot is shutting down")
+	}
+	w.Close(err)
+}
+
+func (th *thread) currentUser() string {
+	th.mu.Lock()
+	defer th.mu.Unlock()
+	return th.user
+}
+
+// handleInteraction delivers a permission button click.
+func (b *Bot) handleInteraction(payload json.RawMessage) {
+	var p struct {
+		Type string `json:"type"`
+		User struct {
+			ID string `json:"id"`
+		} `json:"user"`
+		Actions []struct {
+			ActionID string `json:"action_id"`
+			Value    string `json:"value"`
+		} `json:"actions"`
+	}
+	if err := json.Unmarshal(payload, &p); err != nil || p.Type != "block_actions" {
+		return
+	}
+	for _, a := range p.Actions {
+		if a.ActionID != actionAllow && a.ActionID != actionDeny {
+			continue
+		}
+		b.mu.Lock()
+		prompt, ok := b.prompts[a.Value]
+		if ok && prompt.user == p.User.ID {
+			// Answer once; later clicks are ignored.
+			delete(b.prompts, a.Value)
+		}
+		b.mu.Unlock()
+		if !ok {
+			continue
+		}
+		if prompt.user != p.User.ID {
+			fmt.Fprintf(b.log, "[slack] ignored permission click by %s; only %s can answer\n", p.User.ID, prompt.user)
+			continue
+		}
+		prompt.answer <- a.ActionID == actionAllow
+	}
+}
+
+// addPrompt registers a permission prompt that user can answer and
+// returns its ID and the channel the answer arrives on.
+func (b *Bot) addPrompt(user string) (string, chan bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.nextID++
+	id := "p" + strconv.Itoa(b.nextID)
+	answer := make(chan bool, 1)
+	b.prompts[id] = pendingPrompt{user: user, answer: answer}
+	return id, answer
+}
+
+func (b *Bot) removePrompt(id string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.prompts, id)
+}
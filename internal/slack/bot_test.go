@@ -0,0 +1,140 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gavinyap/stormtrooper/internal/agent"
+	"github.com/gavinyap/stormtrooper/internal/llm"
+	"github.com/gavinyap/stormtrooper/internal/permission"
+	"github.com/gavinyap/stormtrooper/internal/tool"
+)
+
+// sseTextResponse builds a complete SSE stream for a text-only response.
+func sseTextResponse(content string) string {
+	contentJSON, _ := json.Marshal(content)
+	return fmt.Sprintf("data: {\"id\":\"1\",\"choices\":[{\"index\":0,\"delta\":{\"role\":\"assistant\",\"content\":%s},\"finish_reason\":null}]}\n\n", contentJSON) +
+		"data: {\"id\":\"1\",\"choices\":[{\"index\":0,\"delta\":{},\"finish_reason\":\"stop\"}]}\n\n" +
+		"data: [DONE]\n"
+}
+
+// eventEnvelope wraps a message event in a Socket Mode envelope.
+func eventEnvelope(id string, event map[string]any) envelope {
+	payload, _ := json.Marshal(map[string]any{"type": "event_callback", "event": event})
+	return envelope{EnvelopeID: id, Type: "events_api", Payload: payload}
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestBot_AnswersInThreads(t *testing.T) {
+	var turns atomic.Int32
+	llmSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := turns.Add(1)
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, sseTextResponse(fmt.Sprintf("answer %d", n)))
+	}))
+	defer llmSrv.Close()
+
+	var agents []*agent.Agent
+	f := newFakeSlack(t)
+	b := New(Options{
+		API: f.api(t),
+		NewAgent: func(perm permission.Handler) *agent.Agent {
+			client := llm.NewClient("test-key")
+			client.SetBaseURL(llmSrv.URL)
+			ag := agent.New(agent.Options{Client: client, Registry: tool.NewRegistry(), Permission: perm, Model: "test-model"})
+			agents = append(agents, ag)
+			return ag
+		},
+		Channels:       []string{"C1"},
+		UpdateInterval: time.Millisecond,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error)
+	go func() { done <- b.Run(ctx) }()
+
+	updated := func(text string) func() bool {
+		return func() bool {
+			for _, c := range f.Calls("chat.update") {
+				if c.Body["text"] == text {
+					return true
+				}
+			}
+			return false
+		}
+	}
+
+	f.events <- eventEnvelope("e1", map[string]any{"type": "app_mention", "user": "U1", "text": "<@UBOT> what does main do?", "channel": "C1", "ts": "100.1"})
+	if id := <-f.acks; id != "e1" {
+		t.Errorf("ack = %q, want e1", id)
+	}
+	waitFor(t, "the first answer", updated("answer 1"))
+	if thread := f.Calls("chat.postMessage")[0].Body["thread_ts"]; thread != "100.1" {
+		t.Errorf("the reply should start a thread on the mention, got thread_ts %v", thread)
+	}
+
+	// Ignored: another channel, the bot's own messages, and plain
+	// messages outside the bot's threads.
+	f.events <- eventEnvelope("e2", map[string]any{"type": "app_mention", "user": "U1", "text": "<@UBOT> hi", "channel": "C2", "ts": "200.1"})
+	f.events <- eventEnvelope("e3", map[string]any{"type": "message", "bot_id": "B1", "text": "answer 1", "channel": "C1", "ts": "100.2", "thread_ts": "100.1"})
+	f.events <- eventEnvelope("e4", map[string]any{"type": "message", "user": "U2", "text": "lunch?", "channel": "C1", "ts": "300.1"})
+	// A reply in the thread continues the same session without a mention.
+	f.events <- eventEnvelope("e5", map[string]any{"type": "message", "user": "U1", "text": "and the tests?", "channel": "C1", "ts": "100.3", "thread_ts": "100.1"})
+	waitFor(t, "the second answer", updated("answer 2"))
+
+	if len(agents) != 1 {
+		t.Fatalf("expected one session, got %d", len(agents))
+	}
+	var prompts []string
+	for _, m := range agents[0].History() {
+		if m.Role == "user" {
+			prompts = append(prompts, m.Content)
+		}
+	}
+	if strings.Join(prompts, "|") != "what does main do?|and the tests?" {
+		t.Errorf("session prompts = %q", prompts)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Run after cancel = %v", err)
+	}
+}
+
+func TestBot_FailsOnBadToken(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"ok":false,"error":"invalid_auth"}`))
+	}))
+	defer srv.Close()
+	f := newFakeSlack(t)
+	api := f.api(t)
+	api.SetBaseURL(srv.URL)
+
+	err := New(Options{API: api}).Run(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "invalid_auth") {
+		t.Errorf("expected invalid_auth, got %v", err)
+	}
+}
+
+func TestStripMentions(t *testing.T) {
+	if got := stripMentions("<@UBOT> review <@U123>'s change"); got != "review 's change" {
+		t.Errorf("got %q", got)
+	}
+}
@@ -0,0 +1,103 @@
+package slack
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+// DefaultPermissionTimeout is how long a permission prompt waits for a
+// click before the tool call is denied.
+const DefaultPermissionTimeout = 10 * time.Minute
+
+// Action IDs of the permission buttons.
+const (
+	actionAllow = "stormtrooper_allow"
+	actionDeny  = "stormtrooper_deny"
+)
+
+// maxPreviewLen keeps a prompt under Slack's 3,000 character limit for
+// section text.
+const maxPreviewLen = 2500
+
+// pendingPrompt is a permission prompt waiting for a button click.
+type pendingPrompt struct {
+	user   string // only this user's click counts
+	answer chan bool
+}
+
+// prompter asks for permission in a thread. It implements
+// permission.Handler for the thread's agent.
+type prompter struct {
+	bot     *Bot
+	ctx     context.Context
+	channel string
+	thread  string
+	// user returns who sent the message being answered.
+	user    func() string
+	timeout time.Duration
+	log     io.Writer
+}
+
+// Check posts Allow and Deny buttons and waits for the user who asked
+// to click one. It denies on timeout or if the prompt cannot be posted.
+func (p *prompter) Check(ctx context.Context, toolName string, preview string) bool {
+	id, answer := p.bot.addPrompt(p.user())
+	defer p.bot.removePrompt(id)
+
+	if len(preview) > maxPreviewLen {
+		preview = preview[:splitPoint(preview, maxPreviewLen)] + "…"
+	}
+	text := fmt.Sprintf("*%s* wants to run:\n```%s```", toolName, preview)
+	msg := Message{
+		Text: "Permission needed for " + toolName,
+		Blocks: []Block{
+			{"type": "section", "text": map[string]any{"type": "mrkdwn", "text": text}},
+			{"type": "actions", "elements": []Block{
+				button("Allow", actionAllow, id, "primary"),
+				button("Deny", actionDeny, id, "danger"),
+			}},
+		},
+	}
+	ts, err := p.bot.api.PostMessage(p.ctx, p.channel, p.thread, msg)
+	if err != nil {
+		fmt.Fprintf(p.log, "[slack] permission prompt for %s: %v\n", toolName, err)
+		return false
+	}
+
+	allowed := false
+	verdict := "Timed out, denied"
+	select {
+	case allowed = <-answer:
+		verdict = "Denied"
+		if allowed {
+			verdict = "Allowed"
+		}
+	case <-time.After(p.timeout):
+	case <-p.ctx.Done():
+		verdict = "Cancelled"
+	case <-ctx.Done():
+		verdict = "Cancelled"
+	}
+	// Replace the buttons with the outcome so nobody clicks them later.
+	done := fmt.Sprintf("*%s* wants to run:\n```%s```\n%s", toolName, preview, verdict)
+	msg = Message{
+		Text:   verdict + ": " + toolName,
+		Blocks: []Block{{"type": "section", "text": map[string]any{"type": "mrkdwn", "text": done}}},
+	}
+	if err := p.bot.api.UpdateMessage(p.ctx, p.channel, ts, msg); err != nil {
+		fmt.Fprintf(p.log, "[slack] %v\n", err)
+	}
+	return allowed
+}
+
+func button(label, actionID, value, style string) Block {
+	return Block{
+		"type":      "button",
+		"text":      map[string]any{"type": "plain_text", "text": label},
+		"action_id": actionID,
+		"value":     value,
+		"style":     style,
+	}
+}
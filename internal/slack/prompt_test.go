@@ -0,0 +1,101 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+)
+
+// click builds a block_actions payload for a permission button.
+func click(user, actionID, promptID string) json.RawMessage {
+	data, _ := json.Marshal(map[string]any{
+		"type":    "block_actions",
+		"user":    map[string]any{"id": user},
+		"actions": []map[string]any{{"action_id": actionID, "value": promptID}},
+	})
+	return data
+}
+
+func newTestPrompter(t *testing.T, f *fakeSlack, timeout time.Duration) (*Bot, *prompter) {
+	t.Helper()
+	b := New(Options{API: f.api(t)})
+	p := &prompter{
+		bot:     b,
+		ctx:     context.Background(),
+		channel: "C1",
+		thread:  "100.1",
+		user:    func() string { return "UASKER" },
+		timeout: timeout,
+		log:     io.Discard,
+	}
+	return b, p
+}
+
+// promptID waits for the permission prompt to be posted and returns the
+// value of its buttons.
+func promptID(t *testing.T, f *fakeSlack) string {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if posts := f.Calls("chat.postMessage"); len(posts) > 0 {
+			blocks := posts[0].Body["blocks"].([]any)
+			buttons := blocks[1].(map[string]any)["elements"].([]any)
+			return buttons[0].(map[string]any)["value"].(string)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("no permission prompt was posted")
+	return ""
+}
+
+func TestPrompter_AllowedByAsker(t *testing.T) {
+	f := newFakeSlack(t)
+	b, p := newTestPrompter(t, f, time.Minute)
+	result := make(chan bool)
+	go func() { result <- p.Check(context.Background(), "shell_exec", "go test ./...") }()
+
+	id := promptID(t, f)
+	b.handleInteraction(click("USOMEONE", actionAllow, id))
+	select {
+	case <-result:
+		t.Fatal("a click by another user should be ignored")
+	case <-time.After(50 * time.Millisecond):
+	}
+	b.handleInteraction(click("UASKER", actionAllow, id))
+	if !<-result {
+		t.Error("expected the tool call to be allowed")
+	}
+
+	updates := f.Calls("chat.update")
+	if len(updates) != 1 || updates[0].Body["text"] != "Allowed: shell_exec" {
+		t.Errorf("the prompt should be replaced by the outcome, got %+v", updates)
+	}
+	if len(b.prompts) != 0 {
+		t.Errorf("answered prompts should be forgotten, got %v", b.prompts)
+	}
+}
+
+func TestPrompter_Denied(t *testing.T) {
+	f := newFakeSlack(t)
+	b, p := newTestPrompter(t, f, time.Minute)
+	result := make(chan bool)
+	go func() { result <- p.Check(context.Background(), "write_file", "main.go") }()
+
+	b.handleInteraction(click("UASKER", actionDeny, promptID(t, f)))
+	if <-result {
+		t.Error("expected the tool call to be denied")
+	}
+}
+
+func TestPrompter_DeniesOnTimeout(t *testing.T) {
+	f := newFakeSlack(t)
+	_, p := newTestPrompter(t, f, 10*time.Millisecond)
+	if p.Check(context.Background(), "shell_exec", "rm -rf build") {
+		t.Error("an unanswered prompt should deny")
+	}
+	if updates := f.Calls("chat.update"); len(updates) != 1 || updates[0].Body["text"] != "Timed out, denied: shell_exec" {
+		t.Errorf("unexpected updates %+v", updates)
+	}
+}
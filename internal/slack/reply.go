@@ -0,0 +1,116 @@
+package slack
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// maxMessageLen is where a reply is continued in a new message. Slack
+// truncates messages at 40,000 characters but folds anything past about
+// 4,000 behind "Show more", which hides the end of a streaming reply.
+const maxMessageLen = 3500
+
+// thinking is shown until the first tokens of a reply arrive.
+const thinking = "_Thinking…_"
+
+// replyWriter receives the agent's streamed output and mirrors it into
+// a thread. It posts a message at once and then edits it at most once
+// per interval, starting a new message when one gets too long.
+type replyWriter struct {
+	ctx      context.Context
+	api      *API
+	channel  string
+	thread   string
+	interval time.Duration
+	log      io.Writer
+
+	mu    sync.Mutex
+	ts    string // message being edited; empty before it is posted
+	buf   string // text of that message
+	shown string // text Slack has for it
+	last  time.Time
+}
+
+func newReplyWriter(ctx context.Context, api *API, channel, thread string, interval time.Duration, log io.Writer) *replyWriter {
+	w := &replyWriter{ctx: ctx, api: api, channel: channel, thread: thread, interval: interval, log: log}
+	w.show(thinking)
+	w.last = time.Now()
+	return w
+}
+
+func (w *replyWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.shown == thinking && strings.TrimSpace(w.buf) == "" {
+		// Leading blank lines would replace the placeholder with nothing.
+		w.buf = strings.TrimLeft(w.buf+string(p), "\n")
+	} else {
+		w.buf += string(p)
+	}
+	if time.Since(w.last) >= w.interval {
+		w.flush()
+	}
+	return len(p), nil
+}
+
+// Close shows the rest of the reply, followed by runErr if the turn
+// failed.
+func (w *replyWriter) Close(runErr error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf = strings.TrimRight(w.buf, "\n")
+	if runErr != nil {
+		w.buf += fmt.Sprintf("\n\n:warning: %v", runErr)
+	}
+	if strings.TrimSpace(w.buf) == "" {
+		w.buf = "_(no response)_"
+	}
+	w.flush()
+}
+
+// flush brings Slack up to date with buf.
+func (w *replyWriter) flush() {
+	for len(w.buf) > maxMessageLen {
+		cut := splitPoint(w.buf, maxMessageLen)
+		w.show(w.buf[:cut])
+		w.ts, w.shown = "", ""
+		w.buf = strings.TrimLeft(w.buf[cut:], "\n")
+	}
+	if strings.TrimSpace(w.buf) != "" && w.buf != w.shown {
+		w.show(w.buf)
+	}
+	w.last = time.Now()
+}
+
+// show sets the current message to text, posting it if needed.
+func (w *replyWriter) show(text string) {
+	var err error
+	if w.ts == "" {
+		w.ts, err = w.api.PostMessage(w.ctx, w.channel, w.thread, Message{Text: text})
+	} else {
+		err = w.api.UpdateMessage(w.ctx, w.channel, w.ts, Message{Text: text})
+	}
+	if err != nil {
+		fmt.Fprintf(w.log, "[slack] %v\n", err)
+		return
+	}
+	w.shown = text
+}
+
+// splitPoint returns where to split s so the first part is at most n
+// bytes: after the last newline if there is one in the second half,
+// otherwise at a rune boundary.
+func splitPoint(s string, n int) int {
+	if i := strings.LastIndexByte(s[:n], '\n'); i > n/2 {
+		return i + 1
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return n
+}
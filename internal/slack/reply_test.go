@@ -0,0 +1,81 @@
+package slack
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestReplyWriter_PostsThenEdits(t *testing.T) {
+	f := newFakeSlack(t)
+	w := newReplyWriter(context.Background(), f.api(t), "C1", "100.1", 0, io.Discard)
+	w.Write([]byte("\nHello"))
+	w.Write([]byte(", world"))
+	w.Close(nil)
+
+	posts := f.Calls("chat.postMessage")
+	if len(posts) != 1 || posts[0].Body["text"] != thinking {
+		t.Fatalf("expected one placeholder post, got %+v", posts)
+	}
+	updates := f.Calls("chat.update")
+	if len(updates) != 2 {
+		t.Fatalf("expected an edit per flush, got %d", len(updates))
+	}
+	if got := updates[1].Body["text"]; got != "Hello, world" {
+		t.Errorf("final text = %q", got)
+	}
+}
+
+func TestReplyWriter_Throttles(t *testing.T) {
+	f := newFakeSlack(t)
+	w := newReplyWriter(context.Background(), f.api(t), "C1", "100.1", 1<<40, io.Discard)
+	for range 50 {
+		w.Write([]byte("token "))
+	}
+	if n := len(f.Calls("chat.update")); n != 0 {
+		t.Errorf("expected no edits within the interval, got %d", n)
+	}
+	w.Close(nil)
+	if n := len(f.Calls("chat.update")); n != 1 {
+		t.Errorf("Close should flush once, got %d edits", n)
+	}
+}
+
+func TestReplyWriter_SplitsLongReplies(t *testing.T) {
+	f := newFakeSlack(t)
+	w := newReplyWriter(context.Background(), f.api(t), "C1", "100.1", 0, io.Discard)
+	line := strings.Repeat("x", 99) + "\n"
+	w.Write([]byte(strings.Repeat(line, 50)))
+	w.Close(nil)
+
+	posts := f.Calls("chat.postMessage")
+	if len(posts) != 2 {
+		t.Fatalf("expected the reply to continue in a second message, got %d posts", len(posts))
+	}
+	for _, c := range append(f.Calls("chat.update"), posts...) {
+		if n := len(c.Body["text"].(string)); n > maxMessageLen {
+			t.Errorf("message of %d bytes exceeds %d", n, maxMessageLen)
+		}
+	}
+}
+
+func TestReplyWriter_ShowsError(t *testing.T) {
+	f := newFakeSlack(t)
+	w := newReplyWriter(context.Background(), f.api(t), "C1", "100.1", 0, io.Discard)
+	w.Close(errors.New("rate limited"))
+	updates := f.Calls("chat.update")
+	if len(updates) != 1 || !strings.Contains(updates[0].Body["text"].(string), "rate limited") {
+		t.Errorf("expected the error in the reply, got %+v", updates)
+	}
+}
+
+func TestSplitPoint(t *testing.T) {
+	if got := splitPoint("aaaa\nbbbbbb", 7); got != 5 {
+		t.Errorf("should split after the newline, got %d", got)
+	}
+	if got := splitPoint("ab\ncdéfgh", 6); got != 5 {
+		t.Errorf("should not split inside a rune, got %d", got)
+	}
+}
@@ -0,0 +1,264 @@
+// Package staging holds the agent's file changes in memory until the user
+// reviews them, then applies or discards them all at once.
+package staging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	udiff "github.com/aymanbagabas/go-udiff"
+	"github.com/gavinyap/stormtrooper/internal/tool"
+)
+
+// Change is a staged change to one file.
+type Change struct {
+	Path string
+	// Old is the file's contents when it was first staged; nil means the
+	// file did not exist.
+	Old []byte
+	// New is the staged contents; nil means the file is deleted.
+	New []byte
+}
+
+// Diff returns the change as a unified diff.
+func (c Change) Diff() string {
+	from, to := "a/"+filepath.ToSlash(c.Path), "b/"+filepath.ToSlash(c.Path)
+	if c.Old == nil {
+		from = "/dev/null"
+	}
+	if c.New == nil {
+		to = "/dev/null"
+	}
+	return udiff.Unified(from, to, string(c.Old), string(c.New))
+}
+
+type staged struct {
+	old  []byte
+	data []byte
+	perm fs.FileMode
+}
+
+// Overlay is a FileSystem that reads through to an underlying file system
+// but keeps writes in memory. Directories are created when the changes
+// are applied. An Overlay is safe for concurrent use.
+type Overlay struct {
+	mu     sync.Mutex
+	fs     tool.FileSystem
+	staged map[string]*staged
+}
+
+// New returns an overlay on fsys; nil means the host.
+func New(fsys tool.FileSystem) *Overlay {
+	if fsys == nil {
+		fsys = tool.LocalFS{}
+	}
+	return &Overlay{fs: fsys, staged: map[string]*staged{}}
+}
+
+// Stat reports staged files as regular files.
+func (o *Overlay) Stat(name string) (fs.FileInfo, error) {
+	o.mu.Lock()
+	s, ok := o.staged[filepath.Clean(name)]
+	o.mu.Unlock()
+	if !ok {
+		return o.fs.Stat(name)
+	}
+	if s.data == nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+	return fileInfo{name: filepath.Base(name), size: int64(len(s.data)), mode: s.perm}, nil
+}
+
+// ReadFile returns the staged contents of name, if any.
+func (o *Overlay) ReadFile(name string) ([]byte, error) {
+	o.mu.Lock()
+	s, ok := o.staged[filepath.Clean(name)]
+	o.mu.Unlock()
+	if !ok {
+		return o.fs.ReadFile(name)
+	}
+	if s.data == nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return append([]byte(nil), s.data...), nil
+}
+
+// WriteFile stages data for name.
+func (o *Overlay) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	return o.stage(name, append([]byte{}, data...), perm)
+}
+
+// MkdirAll does nothing; Apply creates the directories staged files need.
+func (o *Overlay) MkdirAll(string, fs.FileMode) error { return nil }
+
+// Remove stages the deletion of name.
+func (o *Overlay) Remove(name string) error {
+	return o.stage(name, nil, 0)
+}
+
+// stage records data (nil to delete) as the new contents of name. A
+// change that returns the file to its original state is dropped.
+func (o *Overlay) stage(name string, data []byte, perm fs.FileMode) error {
+	key := filepath.Clean(name)
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	s, ok := o.staged[key]
+	if !ok {
+		s = &staged{old: o.read(name)}
+		if s.old == nil && data == nil {
+			return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrNotExist}
+		}
+		o.staged[key] = s
+	}
+	s.data, s.perm = data, perm
+	if sameContents(s.old, s.data) {
+		delete(o.staged, key)
+	}
+	return nil
+}
+
+// read returns the underlying contents of name, or nil if it does not
+// exist.
+func (o *Overlay) read(name string) []byte {
+	data, err := o.fs.ReadFile(name)
+	if err != nil {
+		return nil
+	}
+	if data == nil {
+		data = []byte{}
+	}
+	return data
+}
+
+// Changes returns the staged changes, sorted by path.
+func (o *Overlay) Changes() []Change {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	changes := make([]Change, 0, len(o.staged))
+	for path, s := range o.staged {
+		changes = append(changes, Change{Path: path, Old: s.old, New: s.data})
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes
+}
+
+// Diff returns every staged change as one unified diff.
+func (o *Overlay) Diff() string {
+	var b strings.Builder
+	for _, c := range o.Changes() {
+		b.WriteString(c.Diff())
+	}
+	return b.String()
+}
+
+// Apply writes every staged change to the underlying file system and
+// returns the changed paths. If a file changed underneath since it was
+// staged, nothing is written. If a write fails, the files already
+// written are restored and the changes stay staged.
+func (o *Overlay) Apply() ([]string, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	paths := make([]string, 0, len(o.staged))
+	for path := range o.staged {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var conflicts []string
+	for _, path := range paths {
+		if !sameContents(o.read(path), o.staged[path].old) {
+			conflicts = append(conflicts, path)
+		}
+	}
+	if len(conflicts) > 0 {
+		return nil, fmt.Errorf("changed since staged, nothing applied: %s", strings.Join(conflicts, ", "))
+	}
+
+	for i, path := range paths {
+		s := o.staged[path]
+		if err := o.write(path, s.data, s.perm); err != nil {
+			for _, done := range paths[:i] {
+				o.write(done, o.staged[done].old, 0644)
+			}
+			return nil, fmt.Errorf("applying %s: %w", path, err)
+		}
+	}
+	o.staged = map[string]*staged{}
+	return paths, nil
+}
+
+// write sets path in the underlying file system to data, deleting it if
+// data is nil.
+func (o *Overlay) write(path string, data []byte, perm fs.FileMode) error {
+	if data == nil {
+		var err error
+		switch fsys := o.fs.(type) {
+		case interface{ Remove(string) error }:
+			err = fsys.Remove(path)
+		case tool.LocalFS:
+			err = os.Remove(path)
+		default:
+			return fmt.Errorf("cannot delete files on this file system")
+		}
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	if err := o.fs.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if perm == 0 {
+		perm = 0644
+	}
+	return o.fs.WriteFile(path, data, perm)
+}
+
+// Discard drops every staged change and returns the paths it dropped.
+func (o *Overlay) Discard() []string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	paths := make([]string, 0, len(o.staged))
+	for path := range o.staged {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	o.staged = map[string]*staged{}
+	return paths
+}
+
+// Len returns how many files have staged changes.
+func (o *Overlay) Len() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.staged)
+}
+
+// sameContents reports whether a and b are equal, treating a missing
+// file (nil) as different from an empty one.
+func sameContents(a, b []byte) bool {
+	return (a == nil) == (b == nil) && bytes.Equal(a, b)
+}
+
+// fileInfo describes a staged file.
+type fileInfo struct {
+	name string
+	size int64
+	mode fs.FileMode
+}
+
+func (fi fileInfo) Name() string       { return fi.name }
+func (fi fileInfo) Size() int64        { return fi.size }
+func (fi fileInfo) Mode() fs.FileMode  { return fi.mode }
+func (fi fileInfo) ModTime() time.Time { return time.Time{} }
+func (fi fileInfo) IsDir() bool        { return false }
+func (fi fileInfo) Sys() any           { return nil }
@@ -0,0 +1,123 @@
+package staging
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gavinyap/stormtrooper/internal/tool"
+)
+
+func TestOverlay_StagesWrites(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "a.txt")
+	os.WriteFile(existing, []byte("one\n"), 0644)
+	created := filepath.Join(dir, "sub", "b.txt")
+
+	o := New(nil)
+	if err := o.WriteFile(existing, []byte("two\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	o.MkdirAll(filepath.Dir(created), 0755)
+	o.WriteFile(created, []byte("new\n"), 0644)
+
+	if data, _ := os.ReadFile(existing); string(data) != "one\n" {
+		t.Errorf("the file on disk changed before apply: %q", data)
+	}
+	if _, err := os.Stat(filepath.Dir(created)); !errors.Is(err, fs.ErrNotExist) {
+		t.Error("directories should not be created before apply")
+	}
+	if data, _ := o.ReadFile(existing); string(data) != "two\n" {
+		t.Errorf("reads should see the staged contents, got %q", data)
+	}
+	if info, err := o.Stat(created); err != nil || info.Size() != 4 {
+		t.Errorf("Stat of a staged file = %v, %v", info, err)
+	}
+
+	diff := o.Diff()
+	for _, want := range []string{"-one", "+two", "--- /dev/null", "+new"} {
+		if !strings.Contains(diff, want) {
+			t.Errorf("diff missing %q:\n%s", want, diff)
+		}
+	}
+
+	paths, err := o.Apply()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(paths, []string{existing, created}) {
+		t.Errorf("applied %q", paths)
+	}
+	if data, _ := os.ReadFile(created); string(data) != "new\n" {
+		t.Errorf("created file = %q", data)
+	}
+	if o.Len() != 0 {
+		t.Error("apply should clear the staged changes")
+	}
+}
+
+func TestOverlay_RevertDropsChange(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.txt")
+	os.WriteFile(path, []byte("one"), 0644)
+
+	o := New(nil)
+	o.WriteFile(path, []byte("two"), 0644)
+	o.WriteFile(path, []byte("one"), 0644)
+	if o.Len() != 0 {
+		t.Errorf("writing the original back should unstage the file, got %+v", o.Changes())
+	}
+
+	created := filepath.Join(dir, "b.txt")
+	o.WriteFile(created, []byte("x"), 0644)
+	if err := o.Remove(created); err != nil || o.Len() != 0 {
+		t.Errorf("removing a staged new file should unstage it, got %v, %+v", err, o.Changes())
+	}
+}
+
+func TestOverlay_RemoveAndDiscard(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.txt")
+	os.WriteFile(path, []byte("one\n"), 0644)
+
+	o := New(nil)
+	o.Remove(path)
+	if _, err := o.ReadFile(path); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("a staged deletion should hide the file, got %v", err)
+	}
+	if !strings.Contains(o.Diff(), "+++ /dev/null") {
+		t.Errorf("diff = %s", o.Diff())
+	}
+	if got := o.Discard(); !reflect.DeepEqual(got, []string{path}) {
+		t.Errorf("Discard = %q", got)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Error("discarding should leave the file alone")
+	}
+}
+
+func TestOverlay_ApplyConflict(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")
+	os.WriteFile(a, []byte("one"), 0644)
+	os.WriteFile(b, []byte("one"), 0644)
+
+	o := New(tool.LocalFS{})
+	o.WriteFile(a, []byte("two"), 0644)
+	o.WriteFile(b, []byte("two"), 0644)
+	os.WriteFile(b, []byte("edited elsewhere"), 0644)
+
+	if _, err := o.Apply(); err == nil || !strings.Contains(err.Error(), b) {
+		t.Fatalf("expected a conflict on %s, got %v", b, err)
+	}
+	if data, _ := os.ReadFile(a); string(data) != "one" {
+		t.Errorf("a conflict should apply nothing, a = %q", data)
+	}
+	if o.Len() != 2 {
+		t.Error("the changes should stay staged after a conflict")
+	}
+}
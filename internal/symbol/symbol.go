@@ -0,0 +1,471 @@
+// Package symbol indexes the declarations and references of identifiers
+// in a Go module. References are resolved with go/types, so a use of a
+// name only counts when it denotes the same object the compiler would
+// pick, not merely the same spelling.
+package symbol
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ErrNotModule is returned by Load for a directory without a go.mod.
+var ErrNotModule = errors.New("no go.mod found")
+
+// Location is a position in a module file.
+type Location struct {
+	// File is relative to the module root, with forward slashes.
+	File   string
+	Line   int
+	Column int
+	// Offset is the byte offset of the identifier in the file.
+	Offset int
+}
+
+func (l Location) String() string {
+	return fmt.Sprintf("%s:%d:%d", l.File, l.Line, l.Column)
+}
+
+// Symbol is a declared identifier and every reference to it.
+type Symbol struct {
+	Name string
+	// Kind is func, method, type, var, const, or field.
+	Kind string
+	// Package is the import path of the declaring package.
+	Package string
+	Decl    Location
+	// Refs are the uses of the symbol, in file order, excluding Decl.
+	Refs []Location
+
+	obj types.Object
+}
+
+// FileRefs counts a symbol's references in one file.
+type FileRefs struct {
+	File  string
+	Count int
+}
+
+// Files groups the declaration and references by file, declaring file
+// first and the rest by path.
+func (s *Symbol) Files() []FileRefs {
+	counts := map[string]int{s.Decl.File: 0}
+	for _, r := range s.Refs {
+		counts[r.File]++
+	}
+	files := make([]FileRefs, 0, len(counts))
+	for f, n := range counts {
+		files = append(files, FileRefs{File: f, Count: n})
+	}
+	sort.Slice(files, func(i, j int) bool {
+		if (files[i].File == s.Decl.File) != (files[j].File == s.Decl.File) {
+			return files[i].File == s.Decl.File
+		}
+		return files[i].File < files[j].File
+	})
+	return files
+}
+
+// CheckRename reports why the symbol cannot be renamed to name: it is not
+// an identifier, or something with that name is already declared where
+// the symbol is.
+func (s *Symbol) CheckRename(name string) error {
+	if !token.IsIdentifier(name) {
+		return fmt.Errorf("%q is not a valid Go identifier", name)
+	}
+	if name == s.Name {
+		return fmt.Errorf("%s is already called %s", s.Name, name)
+	}
+	switch obj := s.obj.(type) {
+	case *types.Func:
+		if recv := obj.Signature().Recv(); recv != nil {
+			if other, _, _ := types.LookupFieldOrMethod(recv.Type(), true, obj.Pkg(), name); other != nil {
+				return fmt.Errorf("%s already has a field or method %s", types.TypeString(recv.Type(), nil), name)
+			}
+			return nil
+		}
+	case *types.Var:
+		if obj.IsField() {
+			// Fields are checked by the compiler after the rename; there is
+			// no cheap way to find the struct from the field object.
+			return nil
+		}
+	}
+	if scope := s.obj.Parent(); scope != nil && scope.Lookup(name) != nil {
+		return fmt.Errorf("%s is already declared in package %s", name, s.obj.Pkg().Name())
+	}
+	return nil
+}
+
+// Index holds the type-checked packages of a module.
+type Index struct {
+	// Dir is the module root.
+	Dir string
+	// Module is the module path from go.mod.
+	Module string
+
+	fset     *token.FileSet
+	packages map[string]*pkgInfo // by import path
+	// checks are every type-check run: each package, each package with
+	// its in-package tests, and each external test package.
+	checks []*types.Info
+	// loading guards against import cycles.
+	loading map[string]bool
+	// exports and sources import packages outside the module.
+	exports types.Importer
+	sources types.ImporterFrom
+}
+
+// pkgInfo is one package directory of the module.
+type pkgInfo struct {
+	rel   string // directory relative to the module root
+	files []*ast.File
+	tests []*ast.File // in-package _test.go files
+	xtest []*ast.File // package <name>_test files
+	types *types.Package
+}
+
+// Load parses and type-checks every package in the module rooted at dir.
+// Type errors do not stop it: the index covers whatever resolves, which
+// is nearly everything in code that builds.
+func Load(dir string) (*Index, error) {
+	module := modulePath(dir)
+	if module == "" {
+		return nil, fmt.Errorf("%s: %w", dir, ErrNotModule)
+	}
+	ix := &Index{
+		Dir:      dir,
+		Module:   module,
+		fset:     token.NewFileSet(),
+		packages: map[string]*pkgInfo{},
+		loading:  map[string]bool{},
+	}
+	ix.exports = importer.ForCompiler(ix.fset, "gc", exportLookup(dir))
+	ix.sources, _ = importer.ForCompiler(ix.fset, "source", nil).(types.ImporterFrom)
+	if err := ix.parse(); err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0, len(ix.packages))
+	for path := range ix.packages {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		ix.check(path)
+	}
+	for _, path := range paths {
+		p := ix.packages[path]
+		if len(p.tests) > 0 {
+			ix.typeCheck(path, append(append([]*ast.File{}, p.files...), p.tests...))
+		}
+		if len(p.xtest) > 0 {
+			ix.typeCheck(path+"_test", p.xtest)
+		}
+	}
+	return ix, nil
+}
+
+// parse reads every Go file under the module root, skipping testdata,
+// vendor, hidden directories, and nested modules.
+func (ix *Index) parse() error {
+	return filepath.WalkDir(ix.Dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			name := d.Name()
+			if path != ix.Dir {
+				if strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "testdata" || name == "vendor" {
+					return filepath.SkipDir
+				}
+				if _, err := os.Stat(filepath.Join(path, "go.mod")); err == nil {
+					return filepath.SkipDir
+				}
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".go") {
+			return nil
+		}
+		if ok, err := build.Default.MatchFile(filepath.Dir(path), d.Name()); err != nil || !ok {
+			// Excluded by build constraints for this platform.
+			return nil
+		}
+		f, err := parser.ParseFile(ix.fset, path, nil, parser.ParseComments)
+		if err != nil {
+			// Unparsable files are left out rather than failing the index.
+			return nil
+		}
+		rel, _ := filepath.Rel(ix.Dir, filepath.Dir(path))
+		rel = filepath.ToSlash(rel)
+		importPath := ix.Module
+		if rel != "." {
+			importPath += "/" + rel
+		}
+		p := ix.packages[importPath]
+		if p == nil {
+			p = &pkgInfo{rel: rel}
+			ix.packages[importPath] = p
+		}
+		switch {
+		case strings.HasSuffix(f.Name.Name, "_test"):
+			p.xtest = append(p.xtest, f)
+		case strings.HasSuffix(path, "_test.go"):
+			p.tests = append(p.tests, f)
+		default:
+			p.files = append(p.files, f)
+		}
+		return nil
+	})
+}
+
+// check type-checks a module package, once, checking its imports first.
+func (ix *Index) check(path string) *types.Package {
+	p := ix.packages[path]
+	if p.types != nil || ix.loading[path] {
+		return p.types
+	}
+	ix.loading[path] = true
+	defer delete(ix.loading, path)
+	p.types = ix.typeCheck(path, p.files)
+	return p.types
+}
+
+func (ix *Index) typeCheck(path string, files []*ast.File) *types.Package {
+	info := &types.Info{
+		Defs: map[*ast.Ident]types.Object{},
+		Uses: map[*ast.Ident]types.Object{},
+	}
+	conf := types.Config{
+		Importer: importerFunc(ix.importPackage),
+		Error:    func(error) {},
+	}
+	pkg, _ := conf.Check(path, ix.fset, files, info)
+	ix.checks = append(ix.checks, info)
+	return pkg
+}
+
+// importPackage resolves module packages from the index, so that every
+// package shares one set of objects, and everything else from compiler
+// export data, falling back to source.
+func (ix *Index) importPackage(path string) (*types.Package, error) {
+	if _, ok := ix.packages[path]; ok {
+		if pkg := ix.check(path); pkg != nil {
+			return pkg, nil
+		}
+		return nil, fmt.Errorf("import cycle through %s", path)
+	}
+	if ix.exports != nil {
+		if pkg, err := ix.exports.Import(path); err == nil {
+			return pkg, nil
+		}
+	}
+	if ix.sources == nil {
+		return nil, fmt.Errorf("cannot import %s", path)
+	}
+	return ix.sources.ImportFrom(path, ix.Dir, 0)
+}
+
+// exportLookup returns a lookup function for the gc importer that finds
+// compiler export data for the module's dependencies, tests included.
+// One go list call builds and locates all of it, which is much faster
+// than the default importer's go list per package.
+func exportLookup(dir string) importer.Lookup {
+	cmd := exec.Command("go", "list", "-e", "-test", "-deps", "-export", "-f", "{{.ImportPath}}\t{{.Export}}", "./...")
+	cmd.Dir = dir
+	out, _ := cmd.Output()
+	files := map[string]string{}
+	for _, line := range strings.Split(string(out), "\n") {
+		if path, file, ok := strings.Cut(line, "\t"); ok && file != "" {
+			files[path] = file
+		}
+	}
+	return func(path string) (io.ReadCloser, error) {
+		file, ok := files[path]
+		if !ok {
+			return nil, fmt.Errorf("no export data for %s", path)
+		}
+		return os.Open(file)
+	}
+}
+
+type importerFunc func(path string) (*types.Package, error)
+
+func (f importerFunc) Import(path string) (*types.Package, error) { return f(path) }
+
+// Lookup finds the symbol named by query: a package, given as its
+// directory relative to the module root, its import path, or just its
+// last path element when that is unambiguous, followed by the name and,
+// for methods and fields, the member:
+//
+//	internal/tool.NewRegistry
+//	tool.Registry.Register
+//	.Version            (a symbol in the root package)
+//
+// A query that matches no package is tried as a name in the root package.
+func (ix *Index) Lookup(query string) (*Symbol, error) {
+	query = strings.TrimPrefix(query, ix.Module)
+	query = strings.TrimPrefix(strings.TrimPrefix(query, "/"), "./")
+
+	dir, names := ".", strings.TrimPrefix(query, ".")
+	slash := strings.LastIndex(query, "/")
+	if dot := strings.Index(query[slash+1:], "."); dot > 0 {
+		if p, err := ix.findPackage(query[:slash+1+dot]); err != nil {
+			return nil, err
+		} else if p != nil {
+			dir, names = p.rel, query[slash+1+dot+1:]
+		}
+	}
+	if slash >= 0 && dir == "." {
+		return nil, fmt.Errorf("no package for %q in module %s", query, ix.Module)
+	}
+
+	path := ix.Module
+	if dir != "." {
+		path += "/" + dir
+	}
+	p, ok := ix.packages[path]
+	if !ok || p.types == nil {
+		return nil, fmt.Errorf("no package for %q in module %s", query, ix.Module)
+	}
+	obj, err := lookupObject(p.types, names)
+	if err != nil {
+		return nil, err
+	}
+	return ix.symbol(obj), nil
+}
+
+// findPackage finds the package whose directory is dir or, failing
+// that, ends in /dir. It returns nil if there is none.
+func (ix *Index) findPackage(dir string) (*pkgInfo, error) {
+	var matches []*pkgInfo
+	for _, p := range ix.packages {
+		if p.rel == dir {
+			return p, nil
+		}
+		if strings.HasSuffix(p.rel, "/"+dir) {
+			matches = append(matches, p)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, nil
+	case 1:
+		return matches[0], nil
+	}
+	var dirs []string
+	for _, p := range matches {
+		dirs = append(dirs, p.rel)
+	}
+	sort.Strings(dirs)
+	return nil, fmt.Errorf("%q is ambiguous: %s", dir, strings.Join(dirs, ", "))
+}
+
+// lookupObject resolves "Name" or "Type.Member" in pkg.
+func lookupObject(pkg *types.Package, names string) (types.Object, error) {
+	name, member, hasMember := strings.Cut(names, ".")
+	obj := pkg.Scope().Lookup(name)
+	if obj == nil {
+		return nil, fmt.Errorf("%s is not declared in package %s", name, pkg.Name())
+	}
+	if !hasMember {
+		return obj, nil
+	}
+	tn, ok := obj.(*types.TypeName)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a type", name)
+	}
+	m, _, _ := types.LookupFieldOrMethod(types.NewPointer(tn.Type()), true, pkg, member)
+	if m == nil {
+		return nil, fmt.Errorf("%s has no field or method %s", name, member)
+	}
+	if m.Pkg() != pkg || m.Pos() == token.NoPos {
+		return nil, fmt.Errorf("%s.%s is promoted from an embedded type; name that type instead", name, member)
+	}
+	return m, nil
+}
+
+// symbol collects the references to obj across every type-check run.
+// Objects are matched by declaring position, since a package checked
+// with its tests has its own copy of each object.
+func (ix *Index) symbol(obj types.Object) *Symbol {
+	s := &Symbol{
+		Name:    obj.Name(),
+		Kind:    kindOf(obj),
+		Package: obj.Pkg().Path(),
+		Decl:    ix.location(obj.Pos()),
+		obj:     obj,
+	}
+	seen := map[token.Pos]bool{obj.Pos(): true}
+	for _, info := range ix.checks {
+		for id, use := range info.Uses {
+			if use.Pos() == obj.Pos() && use.Name() == obj.Name() && !seen[id.Pos()] {
+				seen[id.Pos()] = true
+				s.Refs = append(s.Refs, ix.location(id.Pos()))
+			}
+		}
+	}
+	sort.Slice(s.Refs, func(i, j int) bool {
+		if s.Refs[i].File != s.Refs[j].File {
+			return s.Refs[i].File < s.Refs[j].File
+		}
+		return s.Refs[i].Offset < s.Refs[j].Offset
+	})
+	return s
+}
+
+func (ix *Index) location(pos token.Pos) Location {
+	p := ix.fset.Position(pos)
+	rel, err := filepath.Rel(ix.Dir, p.Filename)
+	if err != nil {
+		rel = p.Filename
+	}
+	return Location{File: filepath.ToSlash(rel), Line: p.Line, Column: p.Column, Offset: p.Offset}
+}
+
+func kindOf(obj types.Object) string {
+	switch obj := obj.(type) {
+	case *types.Func:
+		if obj.Signature().Recv() != nil {
+			return "method"
+		}
+		return "func"
+	case *types.TypeName:
+		return "type"
+	case *types.Const:
+		return "const"
+	case *types.Var:
+		if obj.IsField() {
+			return "field"
+		}
+		return "var"
+	}
+	return "symbol"
+}
+
+// modulePath reads the module path from dir/go.mod.
+func modulePath(dir string) string {
+	data, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if rest, ok := strings.CutPrefix(strings.TrimSpace(line), "module"); ok {
+			return strings.Trim(strings.TrimSpace(rest), `"`)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,182 @@
+package symbol
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeModule lays out a small module: package shapes, a package that
+// uses it, and tests of both kinds.
+func writeModule(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/m\n\ngo 1.21\n",
+		"shapes/shapes.go": `package shapes
+
+type Circle struct{ R float64 }
+
+func (c Circle) Area() float64 { return 3 * c.R * c.R }
+
+func New(r float64) Circle { return Circle{R: r} }
+
+func Big() Circle { return New(10) }
+`,
+		"shapes/shapes_test.go": `package shapes
+
+import "testing"
+
+func TestNew(t *testing.T) {
+	if New(1).R != 1 {
+		t.Fail()
+	}
+}
+`,
+		"shapes/x_test.go": `package shapes_test
+
+import (
+	"testing"
+
+	"example.com/m/shapes"
+)
+
+func TestArea(t *testing.T) {
+	if shapes.New(1).Area() != 3 {
+		t.Fail()
+	}
+}
+`,
+		"cmd/app/main.go": `package main
+
+import (
+	"fmt"
+
+	"example.com/m/shapes"
+)
+
+func main() {
+	New := "shadowed"
+	fmt.Println(New, shapes.New(2).Area())
+}
+`,
+		"shapes/ignored.go": "//go:build ignore\n\npackage shapes\n\nfunc New() {}\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLookup(t *testing.T) {
+	ix, err := Load(writeModule(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		query, kind string
+		files       []FileRefs
+	}{
+		{"shapes.New", "func", []FileRefs{{"shapes/shapes.go", 1}, {"cmd/app/main.go", 1}, {"shapes/shapes_test.go", 1}, {"shapes/x_test.go", 1}}},
+		{"example.com/m/shapes.Circle.Area", "method", []FileRefs{{"shapes/shapes.go", 0}, {"cmd/app/main.go", 1}, {"shapes/x_test.go", 1}}},
+		{"./shapes.Circle.R", "field", []FileRefs{{"shapes/shapes.go", 3}, {"shapes/shapes_test.go", 1}}},
+		{"app.main", "func", []FileRefs{{"cmd/app/main.go", 0}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			s, err := ix.Lookup(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s.Kind != tt.kind {
+				t.Errorf("Kind = %q, want %q", s.Kind, tt.kind)
+			}
+			got := s.Files()
+			if len(got) != len(tt.files) {
+				t.Fatalf("Files() = %v, want %v", got, tt.files)
+			}
+			for i := range got {
+				if got[i] != tt.files[i] {
+					t.Errorf("Files()[%d] = %v, want %v", i, got[i], tt.files[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLookup_Locations(t *testing.T) {
+	ix, err := Load(writeModule(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := ix.Lookup("shapes.New")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Decl.String() != "shapes/shapes.go:7:6" {
+		t.Errorf("Decl = %v", s.Decl)
+	}
+	src, _ := os.ReadFile(filepath.Join(ix.Dir, "cmd", "app", "main.go"))
+	ref := s.Refs[0]
+	if ref.File != "cmd/app/main.go" || !strings.HasPrefix(string(src[ref.Offset:]), "New(2)") {
+		t.Errorf("Refs[0] = %v, want the call in main.go", ref)
+	}
+}
+
+func TestLookup_Errors(t *testing.T) {
+	ix, err := Load(writeModule(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for query, want := range map[string]string{
+		"shapes.Square":       "not declared",
+		"shapes.New.X":        "not a type",
+		"shapes.Circle.Perim": "no field or method",
+		"nowhere/pkg.X":       "no package",
+	} {
+		if _, err := ix.Lookup(query); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Lookup(%q) error = %v, want %q", query, err, want)
+		}
+	}
+}
+
+func TestCheckRename(t *testing.T) {
+	ix, err := Load(writeModule(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	newFn, _ := ix.Lookup("shapes.New")
+	area, _ := ix.Lookup("shapes.Circle.Area")
+	tests := []struct {
+		sym  *Symbol
+		name string
+		ok   bool
+	}{
+		{newFn, "Make", true},
+		{newFn, "Big", false},
+		{newFn, "New", false},
+		{newFn, "1x", false},
+		{area, "Surface", true},
+		{area, "R", false},
+	}
+	for _, tt := range tests {
+		err := tt.sym.CheckRename(tt.name)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s.CheckRename(%q) = %v, want ok=%v", tt.sym.Name, tt.name, err, tt.ok)
+		}
+	}
+}
+
+func TestLoad_NotModule(t *testing.T) {
+	if _, err := Load(t.TempDir()); !errors.Is(err, ErrNotModule) {
+		t.Errorf("err = %v, want ErrNotModule", err)
+	}
+}
@@ -0,0 +1,77 @@
+// Package termcap decides how much of the terminal stormtrooper can use:
+// whether the full-screen TUI will work and whether output may be
+// colored. Dumb terminals, such as CI logs and some IDE consoles, print
+// escape sequences as garbage, so anything unknown gets plain text.
+package termcap
+
+import (
+	"fmt"
+	"os"
+)
+
+// ColorMode is the value of the --color flag.
+type ColorMode string
+
+const (
+	ColorAuto   ColorMode = "auto"   // color when the terminal supports it
+	ColorAlways ColorMode = "always" // color even when it may not render
+	ColorNever  ColorMode = "never"  // no color or text styling
+)
+
+// ParseColorMode parses a --color value; empty means auto.
+func ParseColorMode(s string) (ColorMode, error) {
+	switch mode := ColorMode(s); mode {
+	case "":
+		return ColorAuto, nil
+	case ColorAuto, ColorAlways, ColorNever:
+		return mode, nil
+	}
+	return "", fmt.Errorf("--color must be auto, always, or never, got %q", s)
+}
+
+// Terminal describes where stormtrooper reads and writes.
+type Terminal struct {
+	// Interactive is true when stdin and stdout are both terminals.
+	Interactive bool
+	// OutputTTY is true when stdout is a terminal.
+	OutputTTY bool
+	// Dumb is true when TERM is unset or "dumb": the terminal cannot
+	// move the cursor or draw a full screen.
+	Dumb bool
+	// NoColor is true when the NO_COLOR convention asks for no color.
+	NoColor bool
+}
+
+// Detect describes a terminal from whether stdin and stdout are
+// terminals and from the environment.
+func Detect(stdinTTY, stdoutTTY bool, getenv func(string) string) Terminal {
+	term := getenv("TERM")
+	return Terminal{
+		Interactive: stdinTTY && stdoutTTY,
+		OutputTTY:   stdoutTTY,
+		Dumb:        term == "" || term == "dumb",
+		NoColor:     getenv("NO_COLOR") != "",
+	}
+}
+
+// IsTerminal reports whether f is a terminal.
+func IsTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// FullScreen reports whether the full-screen TUI can run.
+func (t Terminal) FullScreen() bool {
+	return t.Interactive && !t.Dumb
+}
+
+// Color reports whether output should be colored in mode.
+func (t Terminal) Color(mode ColorMode) bool {
+	switch mode {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	}
+	return t.OutputTTY && !t.Dumb && !t.NoColor
+}
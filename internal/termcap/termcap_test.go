@@ -0,0 +1,61 @@
+package termcap
+
+import (
+	"os"
+	"testing"
+)
+
+func env(vars map[string]string) func(string) string {
+	return func(key string) string { return vars[key] }
+}
+
+func TestParseColorMode(t *testing.T) {
+	for in, want := range map[string]ColorMode{"": ColorAuto, "auto": ColorAuto, "always": ColorAlways, "never": ColorNever} {
+		if got, err := ParseColorMode(in); err != nil || got != want {
+			t.Errorf("ParseColorMode(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseColorMode("sometimes"); err == nil {
+		t.Error("expected an error for an unknown mode")
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name                string
+		stdin, stdout       bool
+		env                 map[string]string
+		fullScreen, colored bool
+	}{
+		{"terminal", true, true, map[string]string{"TERM": "xterm-256color"}, true, true},
+		{"dumb terminal", true, true, map[string]string{"TERM": "dumb"}, false, false},
+		{"no TERM", true, true, nil, false, false},
+		{"CI log", false, false, map[string]string{"TERM": "xterm", "CI": "true"}, false, false},
+		{"piped input", false, true, map[string]string{"TERM": "xterm"}, false, true},
+		{"piped output", true, false, map[string]string{"TERM": "xterm"}, false, false},
+		{"NO_COLOR", true, true, map[string]string{"TERM": "xterm", "NO_COLOR": "1"}, true, false},
+	}
+	for _, tt := range tests {
+		term := Detect(tt.stdin, tt.stdout, env(tt.env))
+		if got := term.FullScreen(); got != tt.fullScreen {
+			t.Errorf("%s: FullScreen() = %v, want %v", tt.name, got, tt.fullScreen)
+		}
+		if got := term.Color(ColorAuto); got != tt.colored {
+			t.Errorf("%s: Color(auto) = %v, want %v", tt.name, got, tt.colored)
+		}
+		if !term.Color(ColorAlways) || term.Color(ColorNever) {
+			t.Errorf("%s: always and never should override detection", tt.name)
+		}
+	}
+}
+
+func TestIsTerminal(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if IsTerminal(f) {
+		t.Error("a regular file is not a terminal")
+	}
+}
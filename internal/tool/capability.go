@@ -0,0 +1,80 @@
+package tool
+
+import (
+	"slices"
+	"sort"
+)
+
+// Capability describes a kind of effect a tool can have.
+type Capability string
+
+const (
+	CapRead    Capability = "read"    // Reads files or other local state
+	CapWrite   Capability = "write"   // Changes files or other state
+	CapNetwork Capability = "network" // Talks to other machines
+	CapExec    Capability = "exec"    // Runs arbitrary commands
+)
+
+// Capable is an optional interface for tools to declare their
+// capabilities, so that a Profile can decide whether to expose them.
+type Capable interface {
+	Capabilities() []Capability
+}
+
+// CapabilitiesOf returns the capabilities t declares, or nil if it declares
+// none. Tools without capabilities are treated as able to do anything.
+func CapabilitiesOf(t Tool) []Capability {
+	if c, ok := t.(Capable); ok {
+		return c.Capabilities()
+	}
+	return nil
+}
+
+// Profile selects the tools offered to the model by capability. The zero
+// Profile exposes every tool.
+type Profile struct {
+	Name string
+	// Allow, if non-empty, exposes only tools whose capabilities are all
+	// listed.
+	Allow []Capability
+	// Deny hides tools with any of these capabilities.
+	Deny []Capability
+}
+
+// Profiles are the built-in tool profiles, by name.
+var Profiles = map[string]Profile{
+	"all":    {Name: "all"},
+	"plan":   {Name: "plan", Allow: []Capability{CapRead}},
+	"review": {Name: "review", Deny: []Capability{CapExec}},
+}
+
+// ProfileNames returns the names of the built-in profiles, sorted.
+func ProfileNames() []string {
+	names := make([]string, 0, len(Profiles))
+	for name := range Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Exposes reports whether p offers t to the model. A tool that declares no
+// capabilities is hidden by any profile that restricts them.
+func (p Profile) Exposes(t Tool) bool {
+	if len(p.Allow) == 0 && len(p.Deny) == 0 {
+		return true
+	}
+	caps := CapabilitiesOf(t)
+	if caps == nil {
+		return false
+	}
+	for _, c := range caps {
+		if len(p.Allow) > 0 && !slices.Contains(p.Allow, c) {
+			return false
+		}
+		if slices.Contains(p.Deny, c) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,49 @@
+package tool
+
+import "testing"
+
+func TestProfileExposes(t *testing.T) {
+	read := &ReadFileTool{}
+	write := &WriteFileTool{}
+	shell := &ShellExecTool{}
+	dbq := &DBQueryTool{}
+	untagged := &mockTool{name: "untagged"}
+
+	tests := []struct {
+		profile string
+		tool    Tool
+		want    bool
+	}{
+		{"all", read, true},
+		{"all", untagged, true},
+		{"plan", read, true},
+		{"plan", write, false},
+		{"plan", shell, false},
+		{"plan", dbq, false},
+		{"plan", untagged, false},
+		{"review", read, true},
+		{"review", write, true},
+		{"review", shell, false},
+		{"review", untagged, false},
+	}
+	for _, tt := range tests {
+		if got := Profiles[tt.profile].Exposes(tt.tool); got != tt.want {
+			t.Errorf("%s.Exposes(%s) = %v, want %v", tt.profile, tt.tool.Name(), got, tt.want)
+		}
+	}
+}
+
+func TestDefinitionsFor(t *testing.T) {
+	r := NewRegistry()
+	r.Register(&ReadFileTool{})
+	r.Register(&ShellExecTool{})
+	r.Register(&GrepTool{})
+
+	defs := r.DefinitionsFor(Profiles["plan"])
+	if len(defs) != 2 || defs[0].Function.Name != "read_file" || defs[1].Function.Name != "grep" {
+		t.Fatalf("unexpected plan definitions: %+v", defs)
+	}
+	if len(r.Definitions()) != 3 {
+		t.Fatal("Definitions should list every tool")
+	}
+}
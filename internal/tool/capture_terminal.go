@@ -0,0 +1,179 @@
+package tool
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Limits on a capture: tmux scrollback lines and earlier tool outputs.
+const (
+	defaultCaptureLines = 1000
+	maxCaptureLines     = 10000
+	defaultCaptureCount = 5
+)
+
+// CallOutput is a tool call the agent made earlier and its full result.
+type CallOutput struct {
+	Name   string
+	Args   string
+	Result string
+}
+
+// CaptureTerminalTool snapshots terminal output into a scratchpad entry
+// the model can page through: the scrollback of a tmux pane, such as the
+// one running a dev server, or the agent's own earlier tool outputs in
+// full, which pruning shortens in the conversation.
+type CaptureTerminalTool struct {
+	Pad *Scratchpad
+	// Outputs returns the session's tool outputs, oldest first; nil
+	// disables the "tools" source.
+	Outputs func() []CallOutput
+	// Tmux captures the last lines of a pane; nil runs tmux capture-pane.
+	Tmux func(ctx context.Context, target string, lines int) (string, error)
+}
+
+type captureTerminalParams struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Lines  int    `json:"lines"`
+	Tool   string `json:"tool"`
+	Count  int    `json:"count"`
+}
+
+func (t *CaptureTerminalTool) Name() string { return "capture_terminal" }
+func (t *CaptureTerminalTool) Description() string {
+	return "Capture terminal output into a scratchpad entry: the scrollback of a tmux pane (source \"tmux\"), or the full text of your earlier tool outputs (source \"tools\"), which older turns only show shortened. Read the entry with scratchpad_read."
+}
+func (t *CaptureTerminalTool) Permission() PermissionLevel { return PermissionPrompt }
+func (t *CaptureTerminalTool) Capabilities() []Capability  { return []Capability{CapRead} }
+
+func (t *CaptureTerminalTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+	"type": "object",
+	"properties": {
+		"source": {
+			"type": "string",
+			"enum": ["tmux", "tools"],
+			"description": "What to capture"
+		},
+		"target": {
+			"type": "string",
+			"description": "tmux: the pane, e.g. '%3' or 'dev:1.0' (default: the previously active pane, '{last}')"
+		},
+		"lines": {
+			"type": "integer",
+			"description": "tmux: how many lines of scrollback (default 1000, max 10000)"
+		},
+		"tool": {
+			"type": "string",
+			"description": "tools: only outputs of this tool, e.g. 'shell_exec'"
+		},
+		"count": {
+			"type": "integer",
+			"description": "tools: how many of the most recent outputs (default 5)"
+		}
+	},
+	"required": ["source"]
+}`)
+}
+
+// Preview returns a description for the permission prompt.
+func (t *CaptureTerminalTool) Preview(params json.RawMessage) string {
+	var p captureTerminalParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return "Capture terminal output (invalid params)"
+	}
+	if p.Source == "tmux" {
+		return fmt.Sprintf("Capture the last %d lines of tmux pane %s", captureLines(p.Lines), captureTarget(p.Target))
+	}
+	return "Capture earlier tool outputs"
+}
+
+func (t *CaptureTerminalTool) Execute(ctx context.Context, params json.RawMessage) (string, error) {
+	var p captureTerminalParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return fmt.Sprintf("Error: invalid parameters: %v", err), nil
+	}
+
+	var content, what string
+	switch p.Source {
+	case "tmux":
+		tmux := t.Tmux
+		if tmux == nil {
+			tmux = capturePane
+		}
+		lines, target := captureLines(p.Lines), captureTarget(p.Target)
+		out, err := tmux(ctx, target, lines)
+		if err != nil {
+			return fmt.Sprintf("Error: %v", err), nil
+		}
+		content = strings.TrimRight(out, "\n") + "\n"
+		what = fmt.Sprintf("%d lines of tmux pane %s", strings.Count(content, "\n"), target)
+	case "tools":
+		if t.Outputs == nil {
+			return "Error: earlier tool outputs are not available here", nil
+		}
+		count := p.Count
+		if count <= 0 {
+			count = defaultCaptureCount
+		}
+		var picked []CallOutput
+		outputs := t.Outputs()
+		for i := len(outputs) - 1; i >= 0 && len(picked) < count; i-- {
+			if p.Tool == "" || outputs[i].Name == p.Tool {
+				picked = append(picked, outputs[i])
+			}
+		}
+		if len(picked) == 0 {
+			return "No earlier tool outputs to capture.", nil
+		}
+		var b strings.Builder
+		for i := len(picked) - 1; i >= 0; i-- {
+			o := picked[i]
+			fmt.Fprintf(&b, "=== %s %s\n%s\n\n", o.Name, o.Args, strings.TrimRight(o.Result, "\n"))
+		}
+		content = b.String()
+		what = fmt.Sprintf("%d tool output(s)", len(picked))
+	default:
+		return `Error: source must be "tmux" or "tools"`, nil
+	}
+
+	name, err := t.Pad.Save("capture-"+p.Source, content)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+	return fmt.Sprintf("Captured %s (%d bytes) into scratchpad entry %s. Read it with scratchpad_read.", what, len(content), name), nil
+}
+
+func captureLines(n int) int {
+	if n <= 0 {
+		return defaultCaptureLines
+	}
+	return min(n, maxCaptureLines)
+}
+
+func captureTarget(target string) string {
+	if target == "" {
+		return "{last}"
+	}
+	return target
+}
+
+// capturePane returns the last lines of a tmux pane's scrollback, with
+// wrapped lines joined.
+func capturePane(ctx context.Context, target string, lines int) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "tmux", "capture-pane", "-p", "-J", "-S", fmt.Sprintf("-%d", lines), "-t", target)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("tmux: %s", msg)
+		}
+		return "", fmt.Errorf("tmux: %v", err)
+	}
+	return stdout.String(), nil
+}
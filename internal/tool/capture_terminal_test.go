@@ -0,0 +1,92 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// captured returns the contents of the scratchpad entry named in result.
+func captured(t *testing.T, pad *Scratchpad, result string) string {
+	t.Helper()
+	m := regexp.MustCompile(`entry (\S+)\.`).FindStringSubmatch(result)
+	if m == nil {
+		t.Fatalf("no scratchpad entry in %q", result)
+	}
+	dir, _ := pad.Dir()
+	data, err := os.ReadFile(filepath.Join(dir, m[1]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestCaptureTerminal_Tmux(t *testing.T) {
+	var _ Tool = &CaptureTerminalTool{}
+	pad := &Scratchpad{}
+	defer pad.Close()
+	var gotTarget string
+	var gotLines int
+	tool := &CaptureTerminalTool{Pad: pad, Tmux: func(_ context.Context, target string, lines int) (string, error) {
+		gotTarget, gotLines = target, lines
+		return "$ npm run dev\nError: EADDRINUSE :3000\n\n", nil
+	}}
+
+	params := json.RawMessage(`{"source":"tmux","lines":50000}`)
+	if preview := tool.Preview(params); preview != "Capture the last 10000 lines of tmux pane {last}" {
+		t.Errorf("preview = %q", preview)
+	}
+	result, _ := tool.Execute(context.Background(), params)
+	if gotTarget != "{last}" || gotLines != maxCaptureLines {
+		t.Errorf("captured %s with %d lines", gotTarget, gotLines)
+	}
+	if !strings.HasPrefix(result, "Captured 2 lines of tmux pane {last}") {
+		t.Errorf("unexpected result %q", result)
+	}
+	if got := captured(t, pad, result); got != "$ npm run dev\nError: EADDRINUSE :3000\n" {
+		t.Errorf("capture = %q", got)
+	}
+
+	tool.Tmux = func(context.Context, string, int) (string, error) { return "", errors.New("tmux: no server running") }
+	if result, _ := tool.Execute(context.Background(), params); result != "Error: tmux: no server running" {
+		t.Errorf("unexpected result %q", result)
+	}
+}
+
+func TestCaptureTerminal_Tools(t *testing.T) {
+	pad := &Scratchpad{}
+	defer pad.Close()
+	tool := &CaptureTerminalTool{Pad: pad, Outputs: func() []CallOutput {
+		return []CallOutput{
+			{Name: "shell_exec", Args: `{"command":"go build"}`, Result: "build ok"},
+			{Name: "read_file", Args: `{"file_path":"a.go"}`, Result: "package a"},
+			{Name: "shell_exec", Args: `{"command":"go test"}`, Result: "FAIL TestX\n"},
+		}
+	}}
+
+	result, _ := tool.Execute(context.Background(), json.RawMessage(`{"source":"tools","tool":"shell_exec","count":5}`))
+	if !strings.HasPrefix(result, "Captured 2 tool output(s)") {
+		t.Fatalf("unexpected result %q", result)
+	}
+	want := "=== shell_exec {\"command\":\"go build\"}\nbuild ok\n\n=== shell_exec {\"command\":\"go test\"}\nFAIL TestX\n\n"
+	if got := captured(t, pad, result); got != want {
+		t.Errorf("capture = %q", got)
+	}
+
+	result, _ = tool.Execute(context.Background(), json.RawMessage(`{"source":"tools","count":1}`))
+	if got := captured(t, pad, result); !strings.Contains(got, "go test") || strings.Contains(got, "a.go") {
+		t.Errorf("expected only the last output, got %q", got)
+	}
+
+	if result, _ := tool.Execute(context.Background(), json.RawMessage(`{"source":"tools","tool":"grep"}`)); result != "No earlier tool outputs to capture." {
+		t.Errorf("unexpected result %q", result)
+	}
+	if result, _ := tool.Execute(context.Background(), json.RawMessage(`{"source":"screen"}`)); !strings.HasPrefix(result, "Error: source") {
+		t.Errorf("unexpected result %q", result)
+	}
+}
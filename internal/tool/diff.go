@@ -0,0 +1,39 @@
+package tool
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	udiff "github.com/aymanbagabas/go-udiff"
+)
+
+// maxPreviewDiffLines caps the diff shown in a permission prompt.
+const maxPreviewDiffLines = 200
+
+// previewDiff returns the unified diff, with context, of a change to
+// path from before to after, for a permission prompt. A file created by
+// the change has existed set to false. Long diffs are cut, noting how
+// many lines were left out.
+func previewDiff(path, before, after string, existed bool) string {
+	from, to := "a/"+filepath.ToSlash(path), "b/"+filepath.ToSlash(path)
+	if !existed {
+		from = "/dev/null"
+	}
+	return cutPreviewDiff(udiff.Unified(from, to, before, after))
+}
+
+// cutPreviewDiff trims diff for a permission prompt, cutting it after
+// maxPreviewDiffLines lines and noting how many were left out.
+func cutPreviewDiff(diff string) string {
+	diff = strings.TrimRight(diff, "\n")
+	if diff == "" {
+		return "(no changes)"
+	}
+	lines := strings.Split(diff, "\n")
+	if len(lines) > maxPreviewDiffLines {
+		rest := len(lines) - maxPreviewDiffLines
+		lines = append(lines[:maxPreviewDiffLines], fmt.Sprintf("[... %d more lines of diff]", rest))
+	}
+	return strings.Join(lines, "\n")
+}
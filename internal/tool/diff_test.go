@@ -0,0 +1,19 @@
+package tool
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPreviewDiff(t *testing.T) {
+	if got := previewDiff("a.txt", "same\n", "same\n", true); got != "(no changes)" {
+		t.Errorf("unchanged = %q", got)
+	}
+
+	long := strings.Repeat("line\n", 500)
+	got := previewDiff("a.txt", "", long, false)
+	lines := strings.Split(got, "\n")
+	if len(lines) != maxPreviewDiffLines+1 || !strings.HasPrefix(lines[len(lines)-1], "[... 303 more lines") {
+		t.Errorf("long diff has %d lines, ending %q", len(lines), lines[len(lines)-1])
+	}
+}
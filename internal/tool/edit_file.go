@@ -3,13 +3,17 @@ package tool
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
 )
 
-// EditFileTool performs exact string replacement in a file.
-type EditFileTool struct{}
+// EditFileTool performs exact string replacement in a file. Files are
+// edited on the host unless FS points elsewhere.
+type EditFileTool struct {
+	FS FileSystem
+}
 
 type editFileParams struct {
 	FilePath  string `json:"file_path"`
@@ -20,6 +24,7 @@ type editFileParams struct {
 func (t *EditFileTool) Name() string        { return "edit_file" }
 func (t *EditFileTool) Description() string { return "Replace an exact string in a file with new content" }
 func (t *EditFileTool) Permission() PermissionLevel { return PermissionPrompt }
+func (t *EditFileTool) Capabilities() []Capability { return []Capability{CapWrite} }
 
 func (t *EditFileTool) Schema() json.RawMessage {
 	return json.RawMessage(`{
@@ -42,13 +47,44 @@ func (t *EditFileTool) Schema() json.RawMessage {
 }`)
 }
 
-// Preview returns a description for the permission prompt.
+// Preview returns the edit as a unified diff for the permission prompt.
+// If the edit cannot be applied, which Execute will report, it shows the
+// strings instead.
 func (t *EditFileTool) Preview(params json.RawMessage) string {
 	var p editFileParams
 	if err := json.Unmarshal(params, &p); err != nil {
 		return "Edit file (invalid params)"
 	}
-	return fmt.Sprintf("Edit %s\n--- old\n%s\n+++ new\n%s", p.FilePath, p.OldString, p.NewString)
+	path, before, after, err := t.Propose(params)
+	if err != nil {
+		return fmt.Sprintf("Edit %s (%v)\nold:\n%s\nnew:\n%s", p.FilePath, err, p.OldString, p.NewString)
+	}
+	return fmt.Sprintf("Edit %s\n%s", path, previewDiff(path, before, after, true))
+}
+
+// Propose returns the file's contents before and after the replacement.
+func (t *EditFileTool) Propose(params json.RawMessage) (path, before, after string, err error) {
+	var p editFileParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return "", "", "", err
+	}
+	if p.FilePath == "" || p.OldString == "" {
+		return "", "", "", errors.New("file_path and old_string are required")
+	}
+	data, err := fileSystem(t.FS).ReadFile(p.FilePath)
+	if err != nil {
+		return "", "", "", err
+	}
+	before = string(data)
+	if strings.Count(before, p.OldString) != 1 {
+		return "", "", "", errors.New("old_string does not match exactly once")
+	}
+	return p.FilePath, before, strings.Replace(before, p.OldString, p.NewString, 1), nil
+}
+
+// WriteProposed writes content to path.
+func (t *EditFileTool) WriteProposed(path, content string) error {
+	return fileSystem(t.FS).WriteFile(path, []byte(content), 0644)
 }
 
 func (t *EditFileTool) Execute(_ context.Context, params json.RawMessage) (string, error) {
@@ -62,8 +98,12 @@ func (t *EditFileTool) Execute(_ context.Context, params json.RawMessage) (strin
 	if p.OldString == "" {
 		return "Error: old_string is required", nil
 	}
+	if err := checkWritePath(p.FilePath); err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
 
-	data, err := os.ReadFile(p.FilePath)
+	fsys := fileSystem(t.FS)
+	data, err := fsys.ReadFile(p.FilePath)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return fmt.Sprintf("Error: file not found: %s", p.FilePath), nil
@@ -84,7 +124,7 @@ func (t *EditFileTool) Execute(_ context.Context, params json.RawMessage) (strin
 	}
 
 	newContent := strings.Replace(content, p.OldString, p.NewString, 1)
-	if err := os.WriteFile(p.FilePath, []byte(newContent), 0644); err != nil {
+	if err := fsys.WriteFile(p.FilePath, []byte(newContent), 0644); err != nil {
 		return fmt.Sprintf("Error: %v", err), nil
 	}
 	return fmt.Sprintf("File edited: %s", p.FilePath), nil
@@ -133,6 +133,22 @@ func TestEditFileEmptyOldString(t *testing.T) {
 	}
 }
 
+func TestEditFileRefusesGitDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), ".Git")
+	os.MkdirAll(dir, 0755)
+	path := filepath.Join(dir, "config")
+	os.WriteFile(path, []byte("[core]"), 0644)
+
+	params, _ := json.Marshal(editFileParams{FilePath: path, OldString: "[core]", NewString: "[core]\n\tfsmonitor = evil"})
+	result, _ := (&EditFileTool{}).Execute(context.Background(), params)
+	if !strings.Contains(result, "inside .git") {
+		t.Fatalf("expected a refusal, got %q", result)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "[core]" {
+		t.Errorf("config = %q, want it unchanged", data)
+	}
+}
+
 func TestEditFilePreview(t *testing.T) {
 	tool := &EditFileTool{}
 	params, _ := json.Marshal(editFileParams{
@@ -148,3 +164,45 @@ func TestEditFilePreview(t *testing.T) {
 		t.Fatalf("preview should show new string, got %q", preview)
 	}
 }
+
+func TestEditFilePreviewDiff(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "main.go")
+	os.WriteFile(path, []byte("package main\n\nfunc old() {}\n\nfunc main() {}\n"), 0644)
+
+	tool := &EditFileTool{}
+	params, _ := json.Marshal(editFileParams{FilePath: path, OldString: "func old() {}", NewString: "func renamed() {}"})
+	preview := tool.Preview(params)
+	for _, want := range []string{"--- a/", "+++ b/", "@@ -1,5 +1,5 @@", "-func old() {}", "+func renamed() {}", " func main() {}"} {
+		if !strings.Contains(preview, want) {
+			t.Errorf("preview missing %q:\n%s", want, preview)
+		}
+	}
+}
+
+func TestEditFilePropose(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "file.txt")
+	os.WriteFile(path, []byte("hello world"), 0644)
+
+	tool := &EditFileTool{}
+	var _ Proposer = tool
+	params, _ := json.Marshal(editFileParams{FilePath: path, OldString: "world", NewString: "go"})
+	got, before, after, err := tool.Propose(params)
+	if err != nil || got != path || before != "hello world" || after != "hello go" {
+		t.Fatalf("Propose = %q, %q, %q, %v", got, before, after, err)
+	}
+
+	// Calls that would fail are left to Execute.
+	params, _ = json.Marshal(editFileParams{FilePath: path, OldString: "absent", NewString: "x"})
+	if _, _, _, err := tool.Propose(params); err == nil {
+		t.Error("expected an error for an old_string that does not match")
+	}
+
+	if err := tool.WriteProposed(path, "hello there"); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "hello there" {
+		t.Errorf("expected 'hello there', got %q", data)
+	}
+}
@@ -0,0 +1,60 @@
+package tool
+
+import (
+	"os"
+	"path"
+	"strings"
+)
+
+// EnvFilter decides which host environment variables the commands run
+// by LocalExecutor inherit. Patterns are shell globs matched against the
+// variable name, ignoring case (e.g. "*_TOKEN", "AWS_*").
+type EnvFilter struct {
+	// Allow, when non-empty, keeps only the variables matching one of
+	// its patterns.
+	Allow []string
+	// Strip removes the variables matching any of its patterns, even if
+	// Allow matches them.
+	Strip []string
+}
+
+// Environ returns the host environment with f applied. A nil filter
+// keeps everything.
+func (f *EnvFilter) Environ() []string {
+	return f.filter(os.Environ())
+}
+
+func (f *EnvFilter) filter(environ []string) []string {
+	if f == nil {
+		return environ
+	}
+	kept := make([]string, 0, len(environ))
+	for _, kv := range environ {
+		name, _, _ := strings.Cut(kv, "=")
+		if f.Keep(name) {
+			kept = append(kept, kv)
+		}
+	}
+	return kept
+}
+
+// Keep reports whether the variable name is inherited.
+func (f *EnvFilter) Keep(name string) bool {
+	if f == nil {
+		return true
+	}
+	if len(f.Allow) > 0 && !matchAny(f.Allow, name) {
+		return false
+	}
+	return !matchAny(f.Strip, name)
+}
+
+func matchAny(patterns []string, name string) bool {
+	name = strings.ToUpper(name)
+	for _, p := range patterns {
+		if ok, _ := path.Match(strings.ToUpper(p), name); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,30 @@
+package tool
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEnvFilter(t *testing.T) {
+	environ := []string{"PATH=/bin", "HOME=/home/me", "GITHUB_TOKEN=ghp_x", "AWS_SECRET_ACCESS_KEY=y", "GOFLAGS=-mod=mod"}
+	tests := []struct {
+		name   string
+		filter *EnvFilter
+		want   []string
+	}{
+		{"nil keeps everything", nil, environ},
+		{"strip", &EnvFilter{Strip: []string{"*token*", "AWS_*"}},
+			[]string{"PATH=/bin", "HOME=/home/me", "GOFLAGS=-mod=mod"}},
+		{"allow", &EnvFilter{Allow: []string{"PATH", "GO*"}},
+			[]string{"PATH=/bin", "GOFLAGS=-mod=mod"}},
+		{"strip wins over allow", &EnvFilter{Allow: []string{"*"}, Strip: []string{"*_TOKEN"}},
+			[]string{"PATH=/bin", "HOME=/home/me", "AWS_SECRET_ACCESS_KEY=y", "GOFLAGS=-mod=mod"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.filter(environ); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package tool
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Executor builds the process that runs a shell command for shell_exec.
+// Implementations decide where the command runs: on the host, in a
+// sandbox container, or on a remote machine.
+type Executor interface {
+	Command(ctx context.Context, command string) *exec.Cmd
+}
+
+// ArgvExecutor is implemented by executors that can run a program with
+// an argument list directly, without a shell parsing it. Executors
+// without it are given the arguments quoted for sh.
+type ArgvExecutor interface {
+	CommandArgv(ctx context.Context, argv []string) *exec.Cmd
+}
+
+// LocalExecutor runs commands on the host with sh -c.
+type LocalExecutor struct {
+	// Env filters the host environment the commands inherit; nil
+	// passes it all through.
+	Env *EnvFilter
+	// NoNetwork runs the commands in a network namespace of their own
+	// with only a loopback interface, so they cannot reach the network.
+	// It needs unshare (Linux); check NetworkIsolation first.
+	NoNetwork bool
+}
+
+func (l LocalExecutor) Command(ctx context.Context, command string) *exec.Cmd {
+	return l.CommandArgv(ctx, []string{"sh", "-c", command})
+}
+
+// CommandArgv runs argv[0] on the host with the remaining arguments.
+func (l LocalExecutor) CommandArgv(ctx context.Context, argv []string) *exec.Cmd {
+	if l.NoNetwork {
+		argv = append(isolateNetwork, argv...)
+	}
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	l.setEnv(cmd)
+	return cmd
+}
+
+// isolateNetwork prefixes a command to run it in a new network namespace.
+// Mapping the user to root in it allows bringing up loopback, so the
+// command can still talk to servers it starts itself.
+var isolateNetwork = []string{"unshare", "--net", "--map-root-user", "sh", "-c", `ip link set lo up 2>/dev/null; exec "$@"`, "sh"}
+
+// NetworkIsolation reports why LocalExecutor.NoNetwork cannot work on
+// this host, or nil if it can.
+func NetworkIsolation(ctx context.Context) error {
+	out, err := exec.CommandContext(ctx, "unshare", "--net", "--map-root-user", "true").CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return fmt.Errorf("unshare: %s", msg)
+		}
+		return fmt.Errorf("unshare: %w", err)
+	}
+	return nil
+}
+
+func (l LocalExecutor) setEnv(cmd *exec.Cmd) {
+	if l.Env != nil {
+		cmd.Env = l.Env.Environ()
+	}
+}
+
+// commandArgv builds the process for argv with e, going through the
+// shell only when e cannot run an argument list directly.
+func commandArgv(ctx context.Context, e Executor, argv []string) *exec.Cmd {
+	if ae, ok := e.(ArgvExecutor); ok {
+		return ae.CommandArgv(ctx, argv)
+	}
+	return e.Command(ctx, ShellJoin(argv))
+}
+
+// ShellJoin quotes each argument for a POSIX shell and joins them with
+// spaces, so the result runs argv exactly as given.
+func ShellJoin(argv []string) string {
+	quoted := make([]string, len(argv))
+	for i, a := range argv {
+		quoted[i] = shellQuote(a)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// shellQuote leaves plain words alone and single-quotes anything else.
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_-./=:,+@%") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
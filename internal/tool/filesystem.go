@@ -0,0 +1,61 @@
+package tool
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FileSystem is the file access used by read_file, write_file,
+// write_files, and edit_file. Implementations decide where the files live:
+// on the host or on a remote machine. write_files also deletes files when
+// it rolls back, which needs a Remove method.
+type FileSystem interface {
+	Stat(name string) (fs.FileInfo, error)
+	ReadFile(name string) ([]byte, error)
+	WriteFile(name string, data []byte, perm fs.FileMode) error
+	MkdirAll(path string, perm fs.FileMode) error
+}
+
+// LocalFS accesses the host filesystem.
+type LocalFS struct{}
+
+func (LocalFS) Stat(name string) (fs.FileInfo, error) { return os.Stat(name) }
+func (LocalFS) ReadFile(name string) ([]byte, error)  { return os.ReadFile(name) }
+func (LocalFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	return os.WriteFile(name, data, perm)
+}
+func (LocalFS) MkdirAll(path string, perm fs.FileMode) error { return os.MkdirAll(path, perm) }
+func (LocalFS) Remove(name string) error                     { return os.Remove(name) }
+
+// fileSystem returns f, or the host filesystem if f is nil.
+func fileSystem(f FileSystem) FileSystem {
+	if f == nil {
+		return LocalFS{}
+	}
+	return f
+}
+
+// checkWritePath returns why write_file, write_files, and edit_file refuse
+// to write path, or nil. Files inside .git are refused because git runs
+// its hooks and reads its config from there.
+func checkWritePath(path string) error {
+	for _, part := range strings.Split(filepath.ToSlash(filepath.Clean(path)), "/") {
+		if strings.EqualFold(part, ".git") {
+			return fmt.Errorf("refusing to write inside .git: %s", path)
+		}
+	}
+	return nil
+}
+
+// removeFile deletes name from f, if f supports deleting files.
+func removeFile(f FileSystem, name string) error {
+	r, ok := f.(interface{ Remove(string) error })
+	if !ok {
+		return errors.New("cannot delete files on this file system")
+	}
+	return r.Remove(name)
+}
@@ -0,0 +1,80 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// memFS is an in-memory FileSystem backed by fstest.MapFS.
+type memFS struct {
+	files fstest.MapFS
+	dirs  []string
+}
+
+func (m *memFS) Stat(name string) (fs.FileInfo, error) { return m.files.Stat(name) }
+func (m *memFS) ReadFile(name string) ([]byte, error)  { return m.files.ReadFile(name) }
+func (m *memFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	m.files[name] = &fstest.MapFile{Data: data, Mode: perm}
+	return nil
+}
+func (m *memFS) MkdirAll(path string, perm fs.FileMode) error {
+	m.dirs = append(m.dirs, path)
+	return nil
+}
+
+func TestFileToolsUseFS(t *testing.T) {
+	mem := &memFS{files: fstest.MapFS{"src/main.go": {Data: []byte("package main\n")}}}
+	ctx := context.Background()
+
+	out, _ := (&ReadFileTool{FS: mem}).Execute(ctx, json.RawMessage(`{"file_path":"src/main.go"}`))
+	if out != "package main\n" {
+		t.Errorf("read_file = %q", out)
+	}
+
+	out, _ = (&EditFileTool{FS: mem}).Execute(ctx, json.RawMessage(`{"file_path":"src/main.go","old_string":"main","new_string":"app"}`))
+	if !strings.HasPrefix(out, "File edited") || string(mem.files["src/main.go"].Data) != "package app\n" {
+		t.Errorf("edit_file = %q, content %q", out, mem.files["src/main.go"].Data)
+	}
+
+	w := &WriteFileTool{FS: mem}
+	if got := w.Preview(json.RawMessage(`{"file_path":"src/main.go","content":"x"}`)); !strings.Contains(got, "overwrite") {
+		t.Errorf("preview should see the existing file in FS, got %q", got)
+	}
+	out, _ = w.Execute(ctx, json.RawMessage(`{"file_path":"new/file.txt","content":"hi"}`))
+	if !strings.HasPrefix(out, "File written") || string(mem.files["new/file.txt"].Data) != "hi" {
+		t.Errorf("write_file = %q", out)
+	}
+	if len(mem.dirs) != 1 || mem.dirs[0] != "new" {
+		t.Errorf("expected parent directory to be created in FS, got %v", mem.dirs)
+	}
+
+	out, _ = (&ReadFileTool{FS: mem}).Execute(ctx, json.RawMessage(`{"file_path":"missing.go"}`))
+	if !strings.HasPrefix(out, "Error: file not found") {
+		t.Errorf("expected not found from FS, got %q", out)
+	}
+}
+
+func TestLocalFS(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a", "b.txt")
+	var fsys FileSystem = LocalFS{}
+
+	if err := fsys.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := fsys.WriteFile(path, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := fsys.ReadFile(path); string(data) != "x" {
+		t.Errorf("ReadFile = %q", data)
+	}
+	if _, err := fsys.Stat(filepath.Join(dir, "nope")); !os.IsNotExist(err) {
+		t.Errorf("expected not-exist error, got %v", err)
+	}
+}
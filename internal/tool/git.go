@@ -0,0 +1,333 @@
+package tool
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Git runs git in the project for the git_status, git_diff, and
+// git_commit tools. It goes through the same executor as shell_exec, so
+// the tools work in a sandbox container or on a remote host too, and
+// their permission prompts show what is committed rather than a raw
+// command line.
+type Git struct {
+	Executor Executor
+
+	// Root is the project directory on the host; other executors start
+	// in the project already. Empty means the working directory.
+	Root string
+}
+
+// Tools returns the git_status, git_diff, and git_commit tools.
+func (g *Git) Tools() []Tool {
+	return []Tool{&GitStatusTool{Git: g}, &GitDiffTool{Git: g}, &GitCommitTool{Git: g}}
+}
+
+// run runs git with args and returns its output. When git fails, the
+// error carries its message.
+func (g *Git) run(ctx context.Context, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+	var executor Executor = LocalExecutor{}
+	if g.Executor != nil {
+		executor = g.Executor
+	}
+	argv := append([]string{"git", "--no-pager", "-c", "color.ui=false", "-c", "core.quotePath=false"}, args...)
+	cmd := commandArgv(ctx, executor, argv)
+	if _, isLocal := executor.(LocalExecutor); isLocal && g.Root != "" {
+		cmd.Dir = g.Root
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		// git commit explains "nothing to commit" on stdout.
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = strings.TrimSpace(stdout.String())
+		}
+		if msg == "" {
+			return "", fmt.Errorf("git %s: %w", args[0], err)
+		}
+		return "", fmt.Errorf("git %s: %s", args[0], msg)
+	}
+	out := stdout.String()
+	if len(out) > maxCommandOutput {
+		out = out[:maxCommandOutput] + "\n\n[truncated — output exceeds 1MB]"
+	}
+	return out, nil
+}
+
+// GitStatusTool reports the branch and the staged, unstaged, untracked,
+// and conflicted files.
+type GitStatusTool struct {
+	Git *Git
+}
+
+func (t *GitStatusTool) Name() string { return "git_status" }
+func (t *GitStatusTool) Description() string {
+	return "Show the current git branch and the staged, unstaged, untracked, and conflicted files"
+}
+func (t *GitStatusTool) Permission() PermissionLevel { return PermissionAuto }
+func (t *GitStatusTool) Capabilities() []Capability  { return []Capability{CapRead} }
+
+func (t *GitStatusTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+	"type": "object",
+	"properties": {}
+}`)
+}
+
+func (t *GitStatusTool) Execute(ctx context.Context, _ json.RawMessage) (string, error) {
+	out, err := t.Git.run(ctx, "status", "--porcelain=v1", "--branch", "-z")
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+	return formatGitStatus(out), nil
+}
+
+// gitChanges names the status codes of git status --porcelain.
+var gitChanges = map[byte]string{
+	'M': "modified",
+	'T': "type changed",
+	'A': "added",
+	'D': "deleted",
+	'R': "renamed",
+	'C': "copied",
+}
+
+// formatGitStatus turns the output of git status --porcelain=v1 --branch
+// -z into a list of files by state.
+func formatGitStatus(out string) string {
+	var branch string
+	var staged, unstaged, untracked, conflicted []string
+	entries := strings.Split(strings.TrimSuffix(out, "\x00"), "\x00")
+	for i := 0; i < len(entries); i++ {
+		e := entries[i]
+		if rest, ok := strings.CutPrefix(e, "## "); ok {
+			branch = rest
+			continue
+		}
+		if len(e) < 4 {
+			continue
+		}
+		x, y, path := e[0], e[1], e[3:]
+		// Renames and copies are followed by the original path.
+		from := path
+		if (x == 'R' || x == 'C') && i+1 < len(entries) {
+			i++
+			from = entries[i] + " -> " + path
+		}
+		switch {
+		case x == '?' && y == '?':
+			untracked = append(untracked, path)
+		case x == '!' && y == '!':
+		case x == 'U' || y == 'U' || (x == 'A' && y == 'A') || (x == 'D' && y == 'D'):
+			conflicted = append(conflicted, path)
+		default:
+			if x != ' ' {
+				staged = append(staged, gitChanges[x]+": "+from)
+			}
+			if y != ' ' {
+				unstaged = append(unstaged, gitChanges[y]+": "+path)
+			}
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("Branch: " + branch + "\n")
+	if len(staged)+len(unstaged)+len(untracked)+len(conflicted) == 0 {
+		b.WriteString("Working tree clean")
+		return b.String()
+	}
+	for _, group := range []struct {
+		title string
+		files []string
+	}{
+		{"Conflicted", conflicted},
+		{"Staged", staged},
+		{"Unstaged", unstaged},
+		{"Untracked", untracked},
+	} {
+		if len(group.files) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "%s (%d):\n", group.title, len(group.files))
+		for _, f := range group.files {
+			b.WriteString("  " + f + "\n")
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// GitDiffTool shows the unstaged or staged changes as a unified diff.
+type GitDiffTool struct {
+	Git *Git
+}
+
+type gitDiffParams struct {
+	Staged bool     `json:"staged"`
+	Paths  []string `json:"paths"`
+}
+
+func (t *GitDiffTool) Name() string { return "git_diff" }
+func (t *GitDiffTool) Description() string {
+	return "Show the unstaged changes, or the staged changes about to be committed, as a unified diff"
+}
+func (t *GitDiffTool) Permission() PermissionLevel { return PermissionAuto }
+func (t *GitDiffTool) Capabilities() []Capability  { return []Capability{CapRead} }
+
+func (t *GitDiffTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+	"type": "object",
+	"properties": {
+		"staged": {
+			"type": "boolean",
+			"description": "Show the staged changes instead of the unstaged ones (default false)"
+		},
+		"paths": {
+			"type": "array",
+			"items": {"type": "string"},
+			"description": "Only show changes to these files or directories"
+		}
+	}
+}`)
+}
+
+func (t *GitDiffTool) Execute(ctx context.Context, params json.RawMessage) (string, error) {
+	var p gitDiffParams
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &p); err != nil {
+			return fmt.Sprintf("Error: invalid parameters: %v", err), nil
+		}
+	}
+	args := []string{"diff"}
+	if p.Staged {
+		args = append(args, "--cached")
+	}
+	args = append(append(args, "--"), p.Paths...)
+	out, err := t.Git.run(ctx, args...)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+	if out == "" {
+		if p.Staged {
+			return "No staged changes", nil
+		}
+		return "No unstaged changes", nil
+	}
+	return out, nil
+}
+
+// GitCommitTool commits the staged changes, staging the given files or
+// all tracked changes first if asked to.
+type GitCommitTool struct {
+	Git *Git
+}
+
+type gitCommitParams struct {
+	Message string   `json:"message"`
+	Paths   []string `json:"paths"`
+	All     bool     `json:"all"`
+}
+
+func (t *GitCommitTool) Name() string { return "git_commit" }
+func (t *GitCommitTool) Description() string {
+	return "Commit the staged changes with a message, optionally staging the given files or all tracked changes first"
+}
+func (t *GitCommitTool) Permission() PermissionLevel { return PermissionPrompt }
+func (t *GitCommitTool) Capabilities() []Capability  { return []Capability{CapWrite} }
+
+func (t *GitCommitTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+	"type": "object",
+	"properties": {
+		"message": {
+			"type": "string",
+			"description": "The commit message: a short summary line, optionally followed by a blank line and a body"
+		},
+		"paths": {
+			"type": "array",
+			"items": {"type": "string"},
+			"description": "Files or directories to stage before committing, including new files"
+		},
+		"all": {
+			"type": "boolean",
+			"description": "Stage all changes to tracked files before committing, like git commit -a (default false)"
+		}
+	},
+	"required": ["message"]
+}`)
+}
+
+// Preview returns the message and the diff to be committed for the
+// permission prompt.
+func (t *GitCommitTool) Preview(params json.RawMessage) string {
+	var p gitCommitParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return "Commit (invalid params)"
+	}
+	var b strings.Builder
+	b.WriteString("Commit with message:\n")
+	for _, line := range strings.Split(strings.TrimSpace(p.Message), "\n") {
+		b.WriteString("  " + line + "\n")
+	}
+	if len(p.Paths) > 0 {
+		b.WriteString("Staging: " + strings.Join(p.Paths, ", ") + "\n")
+	}
+	b.WriteString("\n" + t.pendingDiff(context.Background(), p))
+	return b.String()
+}
+
+// pendingDiff returns the diff the commit would record: what is staged,
+// plus the changes p stages first. New files are listed by name.
+func (t *GitCommitTool) pendingDiff(ctx context.Context, p gitCommitParams) string {
+	diff, err := t.Git.run(ctx, "diff", "--cached")
+	if err != nil {
+		return fmt.Sprintf("(cannot show the diff: %v)", err)
+	}
+	if p.All || len(p.Paths) > 0 {
+		unstaged, err := t.Git.run(ctx, append([]string{"diff", "--"}, p.Paths...)...)
+		if err != nil {
+			return fmt.Sprintf("(cannot show the diff: %v)", err)
+		}
+		diff += unstaged
+	}
+	if len(p.Paths) > 0 {
+		if untracked, err := t.Git.run(ctx, append([]string{"ls-files", "-z", "--others", "--exclude-standard", "--"}, p.Paths...)...); err == nil {
+			for _, f := range strings.Split(strings.TrimSuffix(untracked, "\x00"), "\x00") {
+				if f == "" {
+					continue
+				}
+				diff += "new file: " + f + "\n"
+			}
+		}
+	}
+	return cutPreviewDiff(diff)
+}
+
+func (t *GitCommitTool) Execute(ctx context.Context, params json.RawMessage) (string, error) {
+	var p gitCommitParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return fmt.Sprintf("Error: invalid parameters: %v", err), nil
+	}
+	if strings.TrimSpace(p.Message) == "" {
+		return "Error: message is required", nil
+	}
+	if len(p.Paths) > 0 {
+		if _, err := t.Git.run(ctx, append([]string{"add", "--"}, p.Paths...)...); err != nil {
+			return fmt.Sprintf("Error: %v", err), nil
+		}
+	}
+	args := []string{"commit", "-m", p.Message}
+	if p.All {
+		args = append(args, "--all")
+	}
+	out, err := t.Git.run(ctx, args...)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+	return strings.TrimSpace(out), nil
+}
@@ -0,0 +1,118 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newGitRepo returns a git repository with one commit of a.txt.
+func newGitRepo(t *testing.T) *Git {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	g := &Git{Root: dir}
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"config", "user.name", "Test"},
+		{"config", "user.email", "test@example.com"},
+		{"config", "commit.gpgsign", "false"},
+	} {
+		if _, err := g.run(context.Background(), args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	os.WriteFile(filepath.Join(dir, "a.txt"), []byte("one\n"), 0644)
+	if _, err := g.run(context.Background(), "add", "a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.run(context.Background(), "commit", "-q", "-m", "first"); err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestGitStatus(t *testing.T) {
+	g := newGitRepo(t)
+	st := &GitStatusTool{Git: g}
+	if out, _ := st.Execute(context.Background(), nil); out != "Branch: main\nWorking tree clean" {
+		t.Errorf("clean status = %q", out)
+	}
+
+	os.WriteFile(filepath.Join(g.Root, "a.txt"), []byte("two\n"), 0644)
+	os.WriteFile(filepath.Join(g.Root, "b c.txt"), []byte("new\n"), 0644)
+	os.WriteFile(filepath.Join(g.Root, "d.txt"), []byte("staged\n"), 0644)
+	g.run(context.Background(), "add", "d.txt")
+	g.run(context.Background(), "mv", "a.txt", "e.txt")
+
+	out, _ := st.Execute(context.Background(), nil)
+	want := "Branch: main\nStaged (2):\n  added: d.txt\n  renamed: a.txt -> e.txt\nUnstaged (1):\n  modified: e.txt\nUntracked (1):\n  b c.txt"
+	if out != want {
+		t.Errorf("status = %q, want %q", out, want)
+	}
+}
+
+func TestGitDiff(t *testing.T) {
+	g := newGitRepo(t)
+	dt := &GitDiffTool{Git: g}
+	if out, _ := dt.Execute(context.Background(), json.RawMessage(`{}`)); out != "No unstaged changes" {
+		t.Errorf("diff of a clean tree = %q", out)
+	}
+
+	os.WriteFile(filepath.Join(g.Root, "a.txt"), []byte("two\n"), 0644)
+	out, _ := dt.Execute(context.Background(), json.RawMessage(`{"paths":["a.txt"]}`))
+	if !strings.Contains(out, "-one\n+two") {
+		t.Errorf("unstaged diff = %q", out)
+	}
+	if out, _ := dt.Execute(context.Background(), json.RawMessage(`{"staged":true}`)); out != "No staged changes" {
+		t.Errorf("staged diff = %q", out)
+	}
+}
+
+func TestGitCommit(t *testing.T) {
+	g := newGitRepo(t)
+	ct := &GitCommitTool{Git: g}
+	os.WriteFile(filepath.Join(g.Root, "a.txt"), []byte("two\n"), 0644)
+	os.WriteFile(filepath.Join(g.Root, "b.txt"), []byte("new\n"), 0644)
+
+	params := json.RawMessage(`{"message":"Update a and add b","paths":["a.txt","b.txt"]}`)
+	preview := ct.Preview(params)
+	for _, want := range []string{"Commit with message:\n  Update a and add b", "Staging: a.txt, b.txt", "-one\n+two", "new file: b.txt"} {
+		if !strings.Contains(preview, want) {
+			t.Errorf("preview missing %q:\n%s", want, preview)
+		}
+	}
+
+	out, _ := ct.Execute(context.Background(), params)
+	if !strings.Contains(out, "Update a and add b") || !strings.Contains(out, "2 files changed") {
+		t.Errorf("commit = %q", out)
+	}
+	if log, _ := g.run(context.Background(), "log", "--format=%s"); log != "Update a and add b\nfirst\n" {
+		t.Errorf("log = %q", log)
+	}
+
+	if out, _ := ct.Execute(context.Background(), json.RawMessage(`{"message":"Again"}`)); !strings.HasPrefix(out, "Error: git commit:") || !strings.Contains(out, "nothing") {
+		t.Errorf("commit with nothing staged = %q", out)
+	}
+	if out, _ := ct.Execute(context.Background(), json.RawMessage(`{"message":" "}`)); out != "Error: message is required" {
+		t.Errorf("commit without a message = %q", out)
+	}
+}
+
+func TestGitToolsCapabilities(t *testing.T) {
+	want := map[string]Capability{"git_status": CapRead, "git_diff": CapRead, "git_commit": CapWrite}
+	for _, tl := range (&Git{}).Tools() {
+		if caps := CapabilitiesOf(tl); len(caps) != 1 || caps[0] != want[tl.Name()] {
+			t.Errorf("%s capabilities = %v, want [%s]", tl.Name(), caps, want[tl.Name()])
+		}
+	}
+	if (&GitCommitTool{}).Permission() != PermissionPrompt {
+		t.Error("git_commit should prompt")
+	}
+}
@@ -22,6 +22,7 @@ type globParams struct {
 func (t *GlobTool) Name() string                     { return "glob" }
 func (t *GlobTool) Description() string              { return "Find files matching a glob pattern" }
 func (t *GlobTool) Permission() PermissionLevel      { return PermissionAuto }
+func (t *GlobTool) Capabilities() []Capability { return []Capability{CapRead} }
 
 func (t *GlobTool) Schema() json.RawMessage {
 	return json.RawMessage(`{
@@ -11,7 +11,7 @@ import (
 	"strings"
 )
 
-const maxGrepMatches = 500
+const maxGrepMatches = 5000
 
 // skipDirs contains directories to skip during grep traversal.
 var skipDirs = map[string]bool{
@@ -35,6 +35,7 @@ type grepParams struct {
 func (t *GrepTool) Name() string                { return "grep" }
 func (t *GrepTool) Description() string         { return "Search file contents using a regex pattern" }
 func (t *GrepTool) Permission() PermissionLevel { return PermissionAuto }
+func (t *GrepTool) Capabilities() []Capability  { return []Capability{CapRead} }
 
 func (t *GrepTool) Schema() json.RawMessage {
 	return json.RawMessage(`{
@@ -0,0 +1,194 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// HTTPRequestTool sends HTTP requests so the agent can exercise the APIs
+// it is building. Requests to localhost run without asking; any other
+// host needs the user's approval.
+type HTTPRequestTool struct {
+	// Client is used for requests; nil means a default client.
+	Client *http.Client
+}
+
+type httpRequestParams struct {
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers"`
+	Body    string            `json:"body"`
+	Timeout int               `json:"timeout"`
+}
+
+func (t *HTTPRequestTool) Name() string { return "http_request" }
+func (t *HTTPRequestTool) Description() string {
+	return "Send an HTTP request and return the status, headers, and body. Requests to localhost need no approval"
+}
+
+// Permission reports the stricter level; PermissionFor decides per URL.
+func (t *HTTPRequestTool) Permission() PermissionLevel { return PermissionPrompt }
+func (t *HTTPRequestTool) Capabilities() []Capability  { return []Capability{CapNetwork} }
+
+// PermissionFor lets requests to localhost run without a prompt.
+func (t *HTTPRequestTool) PermissionFor(params json.RawMessage) PermissionLevel {
+	var p httpRequestParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return PermissionPrompt
+	}
+	u, err := url.Parse(p.URL)
+	if err != nil || !isLocalHost(u.Hostname()) {
+		return PermissionPrompt
+	}
+	return PermissionAuto
+}
+
+func (t *HTTPRequestTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+	"type": "object",
+	"properties": {
+		"method": {
+			"type": "string",
+			"description": "HTTP method (default GET)"
+		},
+		"url": {
+			"type": "string",
+			"description": "Absolute http or https URL"
+		},
+		"headers": {
+			"type": "object",
+			"additionalProperties": {"type": "string"},
+			"description": "Request headers"
+		},
+		"body": {
+			"type": "string",
+			"description": "Request body"
+		},
+		"timeout": {
+			"type": "integer",
+			"description": "Timeout in seconds (default 30)"
+		}
+	},
+	"required": ["url"]
+}`)
+}
+
+// Preview returns the request line for the permission prompt.
+func (t *HTTPRequestTool) Preview(params json.RawMessage) string {
+	var p httpRequestParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return "Send HTTP request (invalid params)"
+	}
+	preview := fmt.Sprintf("Send HTTP request: %s %s", requestMethod(p.Method), p.URL)
+	if p.Body != "" {
+		body := p.Body
+		if len(body) > 200 {
+			body = body[:200] + "..."
+		}
+		preview += "\n" + body
+	}
+	return preview
+}
+
+func (t *HTTPRequestTool) Execute(ctx context.Context, params json.RawMessage) (string, error) {
+	var p httpRequestParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return fmt.Sprintf("Error: invalid parameters: %v", err), nil
+	}
+	u, err := url.Parse(p.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "Error: url must be an absolute http or https URL", nil
+	}
+
+	timeout := defaultTimeout
+	if p.Timeout > 0 {
+		timeout = min(time.Duration(p.Timeout)*time.Second, maxTimeout)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, requestMethod(p.Method), p.URL, strings.NewReader(p.Body))
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+	for k, v := range p.Headers {
+		req.Header.Set(k, v)
+	}
+
+	client := http.Client{}
+	if t.Client != nil {
+		client = *t.Client
+	}
+	// A request approved for localhost must not be redirected elsewhere
+	// without asking.
+	local := isLocalHost(u.Hostname())
+	client.CheckRedirect = func(next *http.Request, via []*http.Request) error {
+		if local && !isLocalHost(next.URL.Hostname()) {
+			return http.ErrUseLastResponse
+		}
+		if len(via) >= 10 {
+			return fmt.Errorf("stopped after 10 redirects")
+		}
+		return nil
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return fmt.Sprintf("Error: request timed out after %ds", int(timeout.Seconds())), nil
+		}
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxOutputSize+1))
+	if err != nil {
+		return fmt.Sprintf("Error: reading response: %v", err), nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s\n", resp.Proto, resp.Status)
+	keys := make([]string, 0, len(resp.Header))
+	for k := range resp.Header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s: %s\n", k, strings.Join(resp.Header[k], ", "))
+	}
+	b.WriteString("\n")
+	if len(body) > maxOutputSize {
+		b.Write(body[:maxOutputSize])
+		b.WriteString("\n\n[truncated — output exceeds 50KB]")
+	} else {
+		b.Write(body)
+	}
+	return b.String(), nil
+}
+
+func requestMethod(method string) string {
+	if method == "" {
+		return http.MethodGet
+	}
+	return strings.ToUpper(method)
+}
+
+// isLocalHost reports whether host names this machine: "localhost", a
+// *.localhost name, or a loopback address. Other names are not resolved,
+// so DNS cannot turn an external name into an unprompted request.
+func isLocalHost(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
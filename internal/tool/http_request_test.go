@@ -0,0 +1,122 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestIsLocalHost(t *testing.T) {
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{"localhost", true},
+		{"LOCALHOST.", true},
+		{"api.localhost", true},
+		{"127.0.0.1", true},
+		{"127.1.2.3", true},
+		{"::1", true},
+		{"example.com", false},
+		{"localhost.example.com", false},
+		{"10.0.0.1", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := isLocalHost(tt.host); got != tt.want {
+			t.Errorf("isLocalHost(%q) = %v, want %v", tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestHTTPRequest_PermissionFor(t *testing.T) {
+	tool := &HTTPRequestTool{}
+	tests := []struct {
+		params string
+		want   PermissionLevel
+	}{
+		{`{"url":"http://localhost:8080/api/users"}`, PermissionAuto},
+		{`{"method":"DELETE","url":"http://127.0.0.1:3000/items/1"}`, PermissionAuto},
+		{`{"url":"https://api.example.com/v1"}`, PermissionPrompt},
+		{`{"url":"::bad"}`, PermissionPrompt},
+		{`not json`, PermissionPrompt},
+	}
+	for _, tt := range tests {
+		if got := tool.PermissionFor(json.RawMessage(tt.params)); got != tt.want {
+			t.Errorf("PermissionFor(%s) = %v, want %v", tt.params, got, tt.want)
+		}
+	}
+}
+
+func TestHTTPRequest_Execute(t *testing.T) {
+	var gotMethod, gotBody, gotHeader string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod = r.Method
+		gotHeader = r.Header.Get("X-Api-Key")
+		data, _ := io.ReadAll(r.Body)
+		gotBody = string(data)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":7}`))
+	}))
+	defer srv.Close()
+
+	tool := &HTTPRequestTool{}
+	params, _ := json.Marshal(map[string]any{
+		"method":  "post",
+		"url":     srv.URL + "/users",
+		"headers": map[string]string{"X-Api-Key": "k"},
+		"body":    `{"name":"ana"}`,
+	})
+	out, _ := tool.Execute(context.Background(), params)
+
+	if gotMethod != "POST" || gotBody != `{"name":"ana"}` || gotHeader != "k" {
+		t.Errorf("server saw %s %q header %q", gotMethod, gotBody, gotHeader)
+	}
+	for _, want := range []string{"HTTP/1.1 201 Created\n", "Content-Type: application/json\n", "\n\n{\"id\":7}"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestHTTPRequest_LocalRedirectStaysLocal(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "https://external.example.com/steal", http.StatusFound)
+	}))
+	defer srv.Close()
+
+	out, _ := (&HTTPRequestTool{}).Execute(context.Background(), json.RawMessage(`{"url":"`+srv.URL+`"}`))
+	if !strings.Contains(out, "302 Found") || !strings.Contains(out, "Location: https://external.example.com/steal") {
+		t.Errorf("redirect off localhost should be returned, not followed:\n%s", out)
+	}
+}
+
+func TestHTTPRequest_Errors(t *testing.T) {
+	tool := &HTTPRequestTool{}
+	tests := []struct {
+		params string
+		want   string
+	}{
+		{`{"url":"ftp://localhost/x"}`, "absolute http or https URL"},
+		{`{"url":"/relative"}`, "absolute http or https URL"},
+		{`{"url":"http://127.0.0.1:1/"}`, "Error: "},
+	}
+	for _, tt := range tests {
+		out, _ := tool.Execute(context.Background(), json.RawMessage(tt.params))
+		if !strings.Contains(out, tt.want) {
+			t.Errorf("Execute(%s) = %q, want %q", tt.params, out, tt.want)
+		}
+	}
+}
+
+func TestHTTPRequest_Preview(t *testing.T) {
+	got := (&HTTPRequestTool{}).Preview(json.RawMessage(`{"url":"https://api.example.com/v1","body":"{}"}`))
+	if got != "Send HTTP request: GET https://api.example.com/v1\n{}" {
+		t.Errorf("Preview = %q", got)
+	}
+}
@@ -22,6 +22,7 @@ type memoryWriteParams struct {
 func (t *MemoryWriteTool) Name() string        { return "memory_write" }
 func (t *MemoryWriteTool) Description() string { return "Write content to a memory file for persistent storage across sessions" }
 func (t *MemoryWriteTool) Permission() PermissionLevel { return PermissionPrompt }
+func (t *MemoryWriteTool) Capabilities() []Capability { return []Capability{CapWrite} }
 
 func (t *MemoryWriteTool) Schema() json.RawMessage {
 	return json.RawMessage(`{
@@ -0,0 +1,28 @@
+package tool
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrOffline is the error of network access attempted in offline mode.
+var ErrOffline = errors.New("offline mode: outbound network access is blocked")
+
+// OfflineTransport refuses requests to hosts other than localhost, so a
+// tool that tries to reach the network in offline mode fails instead of
+// sending anything out.
+type OfflineTransport struct {
+	// Base carries the requests to localhost.
+	Base http.RoundTripper
+}
+
+func (t *OfflineTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !isLocalHost(req.URL.Hostname()) {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, fmt.Errorf("%w (%s)", ErrOffline, req.URL.Host)
+	}
+	return t.Base.RoundTrip(req)
+}
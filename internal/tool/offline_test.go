@@ -0,0 +1,44 @@
+package tool
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestOfflineTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("local"))
+	}))
+	defer server.Close()
+	client := &http.Client{Transport: &OfflineTransport{Base: http.DefaultTransport}}
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("localhost should be reachable: %v", err)
+	}
+	resp.Body.Close()
+
+	_, err = client.Get("https://example.com/")
+	if !errors.Is(err, ErrOffline) || !strings.Contains(err.Error(), "example.com") {
+		t.Errorf("expected ErrOffline naming the host, got %v", err)
+	}
+}
+
+func TestLocalExecutor_NoNetwork(t *testing.T) {
+	if err := NetworkIsolation(context.Background()); err != nil {
+		t.Skipf("no network isolation here: %v", err)
+	}
+	e := LocalExecutor{NoNetwork: true}
+	out, err := e.Command(context.Background(), "cat /proc/net/dev").CombinedOutput()
+	if err != nil {
+		t.Fatalf("%v\n%s", err, out)
+	}
+	// Only the loopback interface exists in the command's namespace.
+	if strings.Count(string(out), ":") != 1 || !strings.Contains(string(out), "lo:") {
+		t.Errorf("expected only loopback, got:\n%s", out)
+	}
+}
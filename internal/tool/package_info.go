@@ -0,0 +1,421 @@
+package tool
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// PackageInfoTool looks up the latest version, deprecation status, and
+// published vulnerabilities of a package, so dependency updates are based
+// on the registries rather than the model's training data.
+//
+// Versions come from the Go module proxy, npm, PyPI, and crates.io;
+// vulnerabilities come from OSV (osv.dev), which covers all four.
+type PackageInfoTool struct {
+	// Client is used for requests; nil means a default client.
+	Client *http.Client
+
+	// urls overrides the registry endpoints; replaced in tests.
+	urls *registryURLs
+}
+
+// registryURLs are the base URLs the tool queries.
+type registryURLs struct {
+	goProxy string
+	npm     string
+	pypi    string
+	crates  string
+	osv     string
+}
+
+var defaultRegistryURLs = registryURLs{
+	goProxy: "https://proxy.golang.org",
+	npm:     "https://registry.npmjs.org",
+	pypi:    "https://pypi.org",
+	crates:  "https://crates.io",
+	osv:     "https://api.osv.dev",
+}
+
+// osvEcosystems maps the tool's ecosystem names to OSV's.
+var osvEcosystems = map[string]string{
+	"go":     "Go",
+	"npm":    "npm",
+	"pypi":   "PyPI",
+	"crates": "crates.io",
+}
+
+// errPackageNotFound is returned by registry lookups for unknown packages.
+var errPackageNotFound = errors.New("package not found")
+
+type packageInfoParams struct {
+	Ecosystem string `json:"ecosystem"`
+	Name      string `json:"name"`
+	Version   string `json:"version"`
+}
+
+// packageRelease is what each registry lookup reports.
+type packageRelease struct {
+	Version    string
+	Published  time.Time
+	Deprecated string // deprecation message, empty if not deprecated
+}
+
+func (t *PackageInfoTool) Name() string { return "package_info" }
+func (t *PackageInfoTool) Description() string {
+	return "Look up a package's latest version, deprecation status, and known vulnerabilities in the Go proxy, npm, PyPI, or crates.io"
+}
+func (t *PackageInfoTool) Permission() PermissionLevel { return PermissionAuto }
+func (t *PackageInfoTool) Capabilities() []Capability  { return []Capability{CapNetwork} }
+
+func (t *PackageInfoTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+	"type": "object",
+	"properties": {
+		"ecosystem": {
+			"type": "string",
+			"enum": ["go", "npm", "pypi", "crates"],
+			"description": "Package registry to query"
+		},
+		"name": {
+			"type": "string",
+			"description": "Module path or package name"
+		},
+		"version": {
+			"type": "string",
+			"description": "Version to check for vulnerabilities (default: the latest version)"
+		}
+	},
+	"required": ["ecosystem", "name"]
+}`)
+}
+
+func (t *PackageInfoTool) Execute(ctx context.Context, params json.RawMessage) (string, error) {
+	var p packageInfoParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return fmt.Sprintf("Error: invalid parameters: %v", err), nil
+	}
+	p.Ecosystem = strings.ToLower(p.Ecosystem)
+	if _, ok := osvEcosystems[p.Ecosystem]; !ok {
+		return fmt.Sprintf("Error: unsupported ecosystem %q (use go, npm, pypi, or crates)", p.Ecosystem), nil
+	}
+	if p.Name == "" {
+		return "Error: name is required", nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	var (
+		rel packageRelease
+		err error
+	)
+	switch p.Ecosystem {
+	case "go":
+		rel, err = t.goLatest(ctx, p.Name)
+	case "npm":
+		rel, err = t.npmLatest(ctx, p.Name)
+	case "pypi":
+		rel, err = t.pypiLatest(ctx, p.Name)
+	case "crates":
+		rel, err = t.cratesLatest(ctx, p.Name)
+	}
+	if errors.Is(err, errPackageNotFound) {
+		return fmt.Sprintf("Error: %s package %q not found", p.Ecosystem, p.Name), nil
+	}
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s package %s\n", p.Ecosystem, p.Name)
+	fmt.Fprintf(&b, "Latest: %s", rel.Version)
+	if !rel.Published.IsZero() {
+		fmt.Fprintf(&b, " (published %s)", rel.Published.Format("2006-01-02"))
+	}
+	b.WriteString("\n")
+	if rel.Deprecated != "" {
+		fmt.Fprintf(&b, "Deprecated: %s\n", rel.Deprecated)
+	} else {
+		b.WriteString("Deprecated: no\n")
+	}
+
+	version := p.Version
+	if version == "" {
+		version = rel.Version
+	}
+	vulns, err := t.vulnerabilities(ctx, p.Ecosystem, p.Name, version)
+	if err != nil {
+		fmt.Fprintf(&b, "Vulnerabilities: lookup failed: %v\n", err)
+		return b.String(), nil
+	}
+	if len(vulns) == 0 {
+		fmt.Fprintf(&b, "Vulnerabilities affecting %s: none known\n", version)
+		return b.String(), nil
+	}
+	fmt.Fprintf(&b, "Vulnerabilities affecting %s: %d\n", version, len(vulns))
+	for _, v := range vulns {
+		b.WriteString("  " + v.describe() + "\n")
+	}
+	return b.String(), nil
+}
+
+func (t *PackageInfoTool) endpoints() registryURLs {
+	if t.urls != nil {
+		return *t.urls
+	}
+	return defaultRegistryURLs
+}
+
+// fetchJSON sends a request and decodes a JSON response into out. A 404
+// is reported as errPackageNotFound.
+func (t *PackageInfoTool) fetchJSON(ctx context.Context, method, endpoint string, body any, out any) error {
+	data, err := t.fetch(ctx, method, endpoint, body)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("decoding %s: %w", endpoint, err)
+	}
+	return nil
+}
+
+func (t *PackageInfoTool) fetch(ctx context.Context, method, endpoint string, body any) ([]byte, error) {
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reqBody = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reqBody)
+	if err != nil {
+		return nil, err
+	}
+	// crates.io rejects requests without a User-Agent.
+	req.Header.Set("User-Agent", "stormtrooper")
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	client := t.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 16<<20))
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return nil, errPackageNotFound
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("%s returned %s", endpoint, resp.Status)
+	}
+	return data, nil
+}
+
+// goLatest queries the module proxy's @latest endpoint and reads the
+// deprecation comment from that version's go.mod.
+func (t *PackageInfoTool) goLatest(ctx context.Context, module string) (packageRelease, error) {
+	base := t.endpoints().goProxy + "/" + escapeModulePath(module)
+	var info struct {
+		Version string
+		Time    time.Time
+	}
+	if err := t.fetchJSON(ctx, http.MethodGet, base+"/@latest", nil, &info); err != nil {
+		return packageRelease{}, err
+	}
+	rel := packageRelease{Version: info.Version, Published: info.Time}
+	if mod, err := t.fetch(ctx, http.MethodGet, base+"/@v/"+info.Version+".mod", nil); err == nil {
+		rel.Deprecated = goModDeprecation(string(mod))
+	}
+	return rel, nil
+}
+
+// escapeModulePath applies the module proxy's case encoding: each
+// upper-case letter becomes '!' followed by its lower-case form.
+func escapeModulePath(path string) string {
+	var b strings.Builder
+	for _, r := range path {
+		if r >= 'A' && r <= 'Z' {
+			b.WriteByte('!')
+			r += 'a' - 'A'
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// goModDeprecation returns the message of a "// Deprecated:" comment in
+// the comment block above the module directive or on the same line.
+func goModDeprecation(gomod string) string {
+	var comments []string
+	for _, line := range strings.Split(gomod, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "//"):
+			comments = append(comments, strings.TrimSpace(strings.TrimPrefix(line, "//")))
+			continue
+		case strings.HasPrefix(line, "module"):
+			if _, c, ok := strings.Cut(line, "//"); ok {
+				comments = append(comments, strings.TrimSpace(c))
+			}
+			for _, c := range comments {
+				if msg, ok := strings.CutPrefix(c, "Deprecated:"); ok {
+					return strings.TrimSpace(msg)
+				}
+			}
+			return ""
+		}
+		comments = nil
+	}
+	return ""
+}
+
+func (t *PackageInfoTool) npmLatest(ctx context.Context, name string) (packageRelease, error) {
+	var latest struct {
+		Version    string `json:"version"`
+		Deprecated string `json:"deprecated"`
+	}
+	// Scoped names keep their '@' but escape the '/'.
+	endpoint := t.endpoints().npm + "/" + strings.Replace(url.PathEscape(name), "%40", "@", 1) + "/latest"
+	if err := t.fetchJSON(ctx, http.MethodGet, endpoint, nil, &latest); err != nil {
+		return packageRelease{}, err
+	}
+	return packageRelease{Version: latest.Version, Deprecated: latest.Deprecated}, nil
+}
+
+func (t *PackageInfoTool) pypiLatest(ctx context.Context, name string) (packageRelease, error) {
+	var doc struct {
+		Info struct {
+			Version     string   `json:"version"`
+			Classifiers []string `json:"classifiers"`
+		} `json:"info"`
+		URLs []struct {
+			UploadTime time.Time `json:"upload_time_iso_8601"`
+		} `json:"urls"`
+	}
+	endpoint := t.endpoints().pypi + "/pypi/" + url.PathEscape(name) + "/json"
+	if err := t.fetchJSON(ctx, http.MethodGet, endpoint, nil, &doc); err != nil {
+		return packageRelease{}, err
+	}
+	rel := packageRelease{Version: doc.Info.Version}
+	if len(doc.URLs) > 0 {
+		rel.Published = doc.URLs[0].UploadTime
+	}
+	// PyPI has no deprecation flag; the Inactive status classifier is the
+	// closest thing to one.
+	for _, c := range doc.Info.Classifiers {
+		if c == "Development Status :: 7 - Inactive" {
+			rel.Deprecated = "marked inactive (Development Status :: 7 - Inactive)"
+		}
+	}
+	return rel, nil
+}
+
+func (t *PackageInfoTool) cratesLatest(ctx context.Context, name string) (packageRelease, error) {
+	var doc struct {
+		Crate struct {
+			MaxStableVersion string `json:"max_stable_version"`
+			MaxVersion       string `json:"max_version"`
+		} `json:"crate"`
+		Versions []struct {
+			Num       string    `json:"num"`
+			Yanked    bool      `json:"yanked"`
+			CreatedAt time.Time `json:"created_at"`
+		} `json:"versions"`
+	}
+	endpoint := t.endpoints().crates + "/api/v1/crates/" + url.PathEscape(name)
+	if err := t.fetchJSON(ctx, http.MethodGet, endpoint, nil, &doc); err != nil {
+		return packageRelease{}, err
+	}
+	rel := packageRelease{Version: doc.Crate.MaxStableVersion}
+	if rel.Version == "" {
+		rel.Version = doc.Crate.MaxVersion
+	}
+	for _, v := range doc.Versions {
+		if v.Num == rel.Version {
+			rel.Published = v.CreatedAt
+			if v.Yanked {
+				rel.Deprecated = "latest version is yanked"
+			}
+		}
+	}
+	return rel, nil
+}
+
+// osvVuln is the subset of an OSV record the tool reports.
+type osvVuln struct {
+	ID       string   `json:"id"`
+	Summary  string   `json:"summary"`
+	Aliases  []string `json:"aliases"`
+	Affected []struct {
+		Ranges []struct {
+			Events []struct {
+				Fixed string `json:"fixed"`
+			} `json:"events"`
+		} `json:"ranges"`
+	} `json:"affected"`
+}
+
+// describe formats the vulnerability as one line: ID, aliases, summary,
+// and the versions that fix it.
+func (v osvVuln) describe() string {
+	s := v.ID
+	if len(v.Aliases) > 0 {
+		s += " (" + strings.Join(v.Aliases, ", ") + ")"
+	}
+	if v.Summary != "" {
+		s += ": " + v.Summary
+	}
+	var fixed []string
+	for _, a := range v.Affected {
+		for _, r := range a.Ranges {
+			for _, e := range r.Events {
+				if e.Fixed != "" {
+					fixed = append(fixed, e.Fixed)
+				}
+			}
+		}
+	}
+	if len(fixed) > 0 {
+		s += " — fixed in " + strings.Join(fixed, ", ")
+	} else {
+		s += " — no fixed version"
+	}
+	return s
+}
+
+// vulnerabilities queries OSV for advisories affecting version.
+func (t *PackageInfoTool) vulnerabilities(ctx context.Context, ecosystem, name, version string) ([]osvVuln, error) {
+	if ecosystem == "go" {
+		// OSV records Go versions without the leading v.
+		version = strings.TrimPrefix(version, "v")
+	}
+	query := map[string]any{
+		"package": map[string]string{"name": name, "ecosystem": osvEcosystems[ecosystem]},
+		"version": version,
+	}
+	var resp struct {
+		Vulns []osvVuln `json:"vulns"`
+	}
+	if err := t.fetchJSON(ctx, http.MethodPost, t.endpoints().osv+"/v1/query", query, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Vulns, nil
+}
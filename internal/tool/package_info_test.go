@@ -0,0 +1,176 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newRegistryServer serves canned registry and OSV responses by path and
+// records the OSV query bodies it receives.
+func newRegistryServer(t *testing.T, responses map[string]string, osvQueries *[]string) *PackageInfoTool {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/query" {
+			body, _ := io.ReadAll(r.Body)
+			if osvQueries != nil {
+				*osvQueries = append(*osvQueries, string(body))
+			}
+		}
+		resp, ok := responses[r.URL.EscapedPath()]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		io.WriteString(w, resp)
+	}))
+	t.Cleanup(srv.Close)
+	return &PackageInfoTool{urls: &registryURLs{
+		goProxy: srv.URL,
+		npm:     srv.URL,
+		pypi:    srv.URL,
+		crates:  srv.URL,
+		osv:     srv.URL,
+	}}
+}
+
+func TestEscapeModulePath(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"github.com/gavinyap/stormtrooper", "github.com/gavinyap/stormtrooper"},
+		{"github.com/BurntSushi/toml", "github.com/!burnt!sushi/toml"},
+	}
+	for _, tt := range tests {
+		if got := escapeModulePath(tt.in); got != tt.want {
+			t.Errorf("escapeModulePath(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestGoModDeprecation(t *testing.T) {
+	tests := []struct {
+		name  string
+		gomod string
+		want  string
+	}{
+		{"none", "module example.com/m\n\ngo 1.21\n", ""},
+		{"block above", "// Deprecated: use example.com/m/v2 instead.\nmodule example.com/m\n", "use example.com/m/v2 instead."},
+		{"same line", "module example.com/m // Deprecated: unmaintained\n", "unmaintained"},
+		{"separated by blank line", "// Deprecated: stale\n\nmodule example.com/m\n", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := goModDeprecation(tt.gomod); got != tt.want {
+				t.Errorf("goModDeprecation() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPackageInfo_Go(t *testing.T) {
+	var queries []string
+	tool := newRegistryServer(t, map[string]string{
+		"/github.com/!burnt!sushi/toml/@latest":       `{"Version":"v1.4.0","Time":"2024-06-01T10:00:00Z"}`,
+		"/github.com/!burnt!sushi/toml/@v/v1.4.0.mod": "// Deprecated: moved.\nmodule github.com/BurntSushi/toml\n",
+		"/v1/query": `{"vulns":[{"id":"GO-2023-0001","aliases":["CVE-2023-1234"],"summary":"Panic on crafted input",` +
+			`"affected":[{"ranges":[{"events":[{"introduced":"0"},{"fixed":"1.3.2"}]}]}]}]}`,
+	}, &queries)
+
+	out, err := tool.Execute(context.Background(), json.RawMessage(`{"ecosystem":"go","name":"github.com/BurntSushi/toml","version":"v1.2.0"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"Latest: v1.4.0 (published 2024-06-01)",
+		"Deprecated: moved.",
+		"Vulnerabilities affecting v1.2.0: 1",
+		"GO-2023-0001 (CVE-2023-1234): Panic on crafted input — fixed in 1.3.2",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	if len(queries) != 1 || !strings.Contains(queries[0], `"ecosystem":"Go"`) || !strings.Contains(queries[0], `"version":"1.2.0"`) {
+		t.Errorf("OSV queries = %v", queries)
+	}
+}
+
+func TestPackageInfo_NPM(t *testing.T) {
+	var queries []string
+	tool := newRegistryServer(t, map[string]string{
+		"/@types%2Fnode/latest": `{"version":"20.1.0","deprecated":"use node 22 types"}`,
+		"/v1/query":             `{}`,
+	}, &queries)
+
+	out, err := tool.Execute(context.Background(), json.RawMessage(`{"ecosystem":"npm","name":"@types/node"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Latest: 20.1.0\n", "Deprecated: use node 22 types", "Vulnerabilities affecting 20.1.0: none known"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	if len(queries) != 1 || !strings.Contains(queries[0], `"version":"20.1.0"`) {
+		t.Errorf("OSV queries = %v, want the latest version checked", queries)
+	}
+}
+
+func TestPackageInfo_PyPI(t *testing.T) {
+	tool := newRegistryServer(t, map[string]string{
+		"/pypi/requests/json": `{"info":{"version":"2.32.3","classifiers":["Development Status :: 7 - Inactive"]},` +
+			`"urls":[{"upload_time_iso_8601":"2024-05-29T15:37:47.000000Z"}]}`,
+		"/v1/query": `{}`,
+	}, nil)
+
+	out, _ := tool.Execute(context.Background(), json.RawMessage(`{"ecosystem":"pypi","name":"requests"}`))
+	for _, want := range []string{"Latest: 2.32.3 (published 2024-05-29)", "Deprecated: marked inactive"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestPackageInfo_Crates(t *testing.T) {
+	tool := newRegistryServer(t, map[string]string{
+		"/api/v1/crates/serde": `{"crate":{"max_stable_version":"1.0.200","max_version":"1.0.201-rc.1"},` +
+			`"versions":[{"num":"1.0.201-rc.1"},{"num":"1.0.200","created_at":"2024-04-01T00:00:00Z"}]}`,
+		"/v1/query": `{}`,
+	}, nil)
+
+	out, _ := tool.Execute(context.Background(), json.RawMessage(`{"ecosystem":"crates","name":"serde"}`))
+	for _, want := range []string{"Latest: 1.0.200 (published 2024-04-01)", "Deprecated: no"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestPackageInfo_Errors(t *testing.T) {
+	tool := newRegistryServer(t, map[string]string{
+		"/pypi/flask/json": `{"info":{"version":"3.0.0"}}`,
+	}, nil)
+
+	tests := []struct {
+		params string
+		want   string
+	}{
+		{`{"ecosystem":"maven","name":"junit"}`, `Error: unsupported ecosystem "maven"`},
+		{`{"ecosystem":"npm"}`, "Error: name is required"},
+		{`{"ecosystem":"npm","name":"no-such-package"}`, `Error: npm package "no-such-package" not found`},
+		// A failed vulnerability lookup still reports the version.
+		{`{"ecosystem":"pypi","name":"flask"}`, "Latest: 3.0.0"},
+	}
+	for _, tt := range tests {
+		out, err := tool.Execute(context.Background(), json.RawMessage(tt.params))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(out, tt.want) {
+			t.Errorf("Execute(%s) = %q, want it to contain %q", tt.params, out, tt.want)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/atotto/clipboard"
+	"github.com/gavinyap/stormtrooper/internal/secrets"
+)
+
+// ReadClipboardTool returns the text on the system clipboard, so "fix the
+// error I just copied" works without pasting a stack trace into the
+// prompt. Credentials in it are redacted before the model sees them.
+type ReadClipboardTool struct {
+	// Read returns the clipboard text; nil means the system clipboard
+	// (pbpaste on macOS, xclip, xsel, or wl-paste on Linux).
+	Read func() (string, error)
+}
+
+func (t *ReadClipboardTool) Name() string { return "read_clipboard" }
+func (t *ReadClipboardTool) Description() string {
+	return "Read the text the user copied to the system clipboard, such as an error message or stack trace"
+}
+func (t *ReadClipboardTool) Permission() PermissionLevel { return PermissionPrompt }
+func (t *ReadClipboardTool) Capabilities() []Capability  { return []Capability{CapRead} }
+
+func (t *ReadClipboardTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+	"type": "object",
+	"properties": {}
+}`)
+}
+
+// Preview returns a description for the permission prompt.
+func (t *ReadClipboardTool) Preview(json.RawMessage) string {
+	return "Read the clipboard contents"
+}
+
+func (t *ReadClipboardTool) Execute(context.Context, json.RawMessage) (string, error) {
+	read := t.Read
+	if read == nil {
+		read = clipboard.ReadAll
+	}
+	text, err := read()
+	if err != nil {
+		return fmt.Sprintf("Error: cannot read the clipboard: %v", err), nil
+	}
+	if strings.TrimSpace(text) == "" {
+		return "The clipboard is empty.", nil
+	}
+	text, n := secrets.Redact(text)
+	if len(text) > maxOutputSize {
+		text = text[:maxOutputSize] + "\n\n[truncated — clipboard exceeds 50KB]"
+	}
+	if n > 0 {
+		text += fmt.Sprintf("\n\n[%d secret(s) redacted]", n)
+	}
+	return text, nil
+}
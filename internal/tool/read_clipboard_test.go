@@ -0,0 +1,37 @@
+package tool
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestReadClipboard(t *testing.T) {
+	var _ Tool = &ReadClipboardTool{}
+	cases := []struct {
+		name, clip string
+		err        error
+		want       string
+	}{
+		{"text", "panic: nil map\n\tmain.go:12", nil, "panic: nil map\n\tmain.go:12"},
+		{"empty", " \n", nil, "The clipboard is empty."},
+		{"unavailable", "", errors.New("no clipboard utilities available"), "Error: cannot read the clipboard: no clipboard utilities available"},
+		{"secret", "export API_KEY=abcdef123456", nil, "export API_KEY=[REDACTED]\n\n[1 secret(s) redacted]"},
+	}
+	for _, tc := range cases {
+		tool := &ReadClipboardTool{Read: func() (string, error) { return tc.clip, tc.err }}
+		got, err := tool.Execute(context.Background(), nil)
+		if err != nil || got != tc.want {
+			t.Errorf("%s: got %q, %v; want %q", tc.name, got, err, tc.want)
+		}
+	}
+}
+
+func TestReadClipboardTruncates(t *testing.T) {
+	tool := &ReadClipboardTool{Read: func() (string, error) { return strings.Repeat("x", maxOutputSize+10), nil }}
+	got, _ := tool.Execute(context.Background(), nil)
+	if !strings.HasSuffix(got, "[truncated — clipboard exceeds 50KB]") || len(got) > maxOutputSize+100 {
+		t.Errorf("expected the clipboard truncated, got %d bytes", len(got))
+	}
+}
@@ -7,10 +7,15 @@ import (
 	"os"
 )
 
-const maxReadSize = 100 * 1024 // 100KB
+// maxReadSize caps what read_file returns. The agent shows the model a
+// long file a page at a time, so this is only a backstop.
+const maxReadSize = 1024 * 1024 // 1MB
 
-// ReadFileTool reads the contents of a file.
-type ReadFileTool struct{}
+// ReadFileTool reads the contents of a file. Files are read from the host
+// unless FS points elsewhere.
+type ReadFileTool struct {
+	FS FileSystem
+}
 
 type readFileParams struct {
 	FilePath string `json:"file_path"`
@@ -19,6 +24,7 @@ type readFileParams struct {
 func (t *ReadFileTool) Name() string        { return "read_file" }
 func (t *ReadFileTool) Description() string { return "Read the contents of a file" }
 func (t *ReadFileTool) Permission() PermissionLevel { return PermissionAuto }
+func (t *ReadFileTool) Capabilities() []Capability { return []Capability{CapRead} }
 
 func (t *ReadFileTool) Schema() json.RawMessage {
 	return json.RawMessage(`{
@@ -42,7 +48,8 @@ func (t *ReadFileTool) Execute(_ context.Context, params json.RawMessage) (strin
 		return "Error: file_path is required", nil
 	}
 
-	info, err := os.Stat(p.FilePath)
+	fsys := fileSystem(t.FS)
+	info, err := fsys.Stat(p.FilePath)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return fmt.Sprintf("Error: file not found: %s", p.FilePath), nil
@@ -53,13 +60,13 @@ func (t *ReadFileTool) Execute(_ context.Context, params json.RawMessage) (strin
 		return fmt.Sprintf("Error: %s is a directory, not a file", p.FilePath), nil
 	}
 
-	data, err := os.ReadFile(p.FilePath)
+	data, err := fsys.ReadFile(p.FilePath)
 	if err != nil {
 		return fmt.Sprintf("Error: %v", err), nil
 	}
 
 	if len(data) > maxReadSize {
-		return string(data[:maxReadSize]) + "\n\n[truncated — file exceeds 100KB]", nil
+		return string(data[:maxReadSize]) + "\n\n[truncated — file exceeds 1MB]", nil
 	}
 	return string(data), nil
 }
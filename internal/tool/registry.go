@@ -1,11 +1,34 @@
 package tool
 
-import "fmt"
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
 
-// Registry holds all registered tools and dispatches calls by name.
+// Registry holds all registered tools and dispatches calls by name. It is
+// safe for concurrent use, so tools from MCP servers and plugins can come
+// and go while agents are running; agents read the registry afresh on
+// every request.
 type Registry struct {
+	mu    sync.RWMutex
 	tools map[string]Tool
 	order []string // preserves registration order
+	guard Guard
+}
+
+// Guard vets tool calls for an organization policy. Agents, and
+// /run-tool for the user's own calls, consult their registry's guard
+// before every call, whatever the tool's permission level and even when
+// every call is approved.
+type Guard interface {
+	// Check returns why the call is forbidden, or "" to allow it.
+	Check(name string, args json.RawMessage) string
+	// Record is told how each call ended: "ok", "error", "denied" (by
+	// the user), or "blocked" (by Check).
+	Record(name string, args json.RawMessage, outcome string)
 }
 
 // NewRegistry creates an empty tool registry.
@@ -18,29 +41,111 @@ func NewRegistry() *Registry {
 // Register adds a tool to the registry. Panics if a tool with the same name
 // is already registered.
 func (r *Registry) Register(t Tool) {
-	name := t.Name()
+	if err := r.add(t.Name(), t); err != nil {
+		panic(err.Error())
+	}
+}
+
+// RegisterNamespace adds a tool under "namespace.name" (e.g.
+// "mcp_github.create_issue"), so tools from different sources cannot
+// collide. It returns an error if the name is already taken.
+func (r *Registry) RegisterNamespace(namespace string, t Tool) error {
+	return r.add(namespace+"."+t.Name(), t)
+}
+
+func (r *Registry) add(name string, t Tool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	if _, exists := r.tools[name]; exists {
-		panic(fmt.Sprintf("tool already registered: %s", name))
+		return fmt.Errorf("tool already registered: %s", name)
 	}
 	r.tools[name] = t
 	r.order = append(r.order, name)
+	return nil
+}
+
+// Replace registers t under name, swapping out any tool already there
+// while keeping its place in the registration order.
+func (r *Registry) Replace(name string, t Tool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.tools[name]; !exists {
+		r.order = append(r.order, name)
+	}
+	r.tools[name] = t
+}
+
+// Unregister removes the named tool and reports whether it was registered.
+func (r *Registry) Unregister(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.tools[name]; !exists {
+		return false
+	}
+	delete(r.tools, name)
+	r.order = slices.DeleteFunc(r.order, func(n string) bool { return n == name })
+	return true
+}
+
+// UnregisterNamespace removes every tool registered under namespace and
+// returns how many there were.
+func (r *Registry) UnregisterNamespace(namespace string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	prefix := namespace + "."
+	n := len(r.order)
+	r.order = slices.DeleteFunc(r.order, func(name string) bool {
+		if !strings.HasPrefix(name, prefix) {
+			return false
+		}
+		delete(r.tools, name)
+		return true
+	})
+	return n - len(r.order)
+}
+
+// SetGuard makes agents pass every call through g before running it.
+func (r *Registry) SetGuard(g Guard) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.guard = g
+}
+
+// Guard returns the guard set with SetGuard, or nil.
+func (r *Registry) Guard() Guard {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.guard
 }
 
 // Get returns the tool with the given name, or nil if not found.
 func (r *Registry) Get(name string) Tool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	return r.tools[name]
 }
 
 // Definitions returns all registered tools in OpenAI function calling format,
-// preserving registration order.
+// preserving registration order. Namespaced tools are listed under their
+// full names.
 func (r *Registry) Definitions() []ToolDef {
+	return r.DefinitionsFor(Profile{})
+}
+
+// DefinitionsFor is like Definitions but lists only the tools p exposes.
+func (r *Registry) DefinitionsFor(p Profile) []ToolDef {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	defs := make([]ToolDef, 0, len(r.order))
 	for _, name := range r.order {
 		t := r.tools[name]
+		if !p.Exposes(t) {
+			continue
+		}
 		defs = append(defs, ToolDef{
 			Type: "function",
 			Function: FunctionDef{
-				Name:        t.Name(),
+				Name:        name,
 				Description: t.Description(),
 				Parameters:  t.Schema(),
 			},
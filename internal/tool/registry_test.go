@@ -3,6 +3,8 @@ package tool
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"sync"
 	"testing"
 )
 
@@ -139,3 +141,106 @@ func TestDefinitionsJSON(t *testing.T) {
 		t.Fatalf("expected type 'function', got %v", parsed[0]["type"])
 	}
 }
+
+func TestRegisterNamespace(t *testing.T) {
+	r := NewRegistry()
+	r.Register(&mockTool{name: "create_issue"})
+	if err := r.RegisterNamespace("mcp_github", &mockTool{name: "create_issue", desc: "GitHub"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := r.RegisterNamespace("mcp_github", &mockTool{name: "create_issue"}); err == nil {
+		t.Fatal("expected an error for a duplicate namespaced tool")
+	}
+
+	got := r.Get("mcp_github.create_issue")
+	if got == nil || got.Description() != "GitHub" {
+		t.Fatalf("Get(mcp_github.create_issue) = %v", got)
+	}
+	defs := r.Definitions()
+	if len(defs) != 2 || defs[1].Function.Name != "mcp_github.create_issue" {
+		t.Fatalf("expected the namespaced name in definitions, got %+v", defs)
+	}
+}
+
+func TestUnregister(t *testing.T) {
+	r := NewRegistry()
+	r.Register(&mockTool{name: "tool_a"})
+	r.Register(&mockTool{name: "tool_b"})
+
+	if !r.Unregister("tool_a") {
+		t.Fatal("expected tool_a to be unregistered")
+	}
+	if r.Unregister("tool_a") {
+		t.Fatal("expected a second Unregister to report false")
+	}
+	if r.Get("tool_a") != nil {
+		t.Fatal("tool_a still registered")
+	}
+	if defs := r.Definitions(); len(defs) != 1 || defs[0].Function.Name != "tool_b" {
+		t.Fatalf("unexpected definitions: %+v", defs)
+	}
+
+	// The name is free again.
+	r.Register(&mockTool{name: "tool_a"})
+}
+
+func TestUnregisterNamespace(t *testing.T) {
+	r := NewRegistry()
+	r.Register(&mockTool{name: "read"})
+	r.RegisterNamespace("mcp_fs", &mockTool{name: "read"})
+	r.RegisterNamespace("mcp_fs", &mockTool{name: "write"})
+	r.RegisterNamespace("mcp_fsx", &mockTool{name: "read"})
+
+	if n := r.UnregisterNamespace("mcp_fs"); n != 2 {
+		t.Fatalf("expected 2 tools removed, got %d", n)
+	}
+	var names []string
+	for _, d := range r.Definitions() {
+		names = append(names, d.Function.Name)
+	}
+	if len(names) != 2 || names[0] != "read" || names[1] != "mcp_fsx.read" {
+		t.Fatalf("unexpected tools left: %v", names)
+	}
+}
+
+func TestReplace(t *testing.T) {
+	r := NewRegistry()
+	r.Register(&mockTool{name: "tool_a", desc: "old"})
+	r.Register(&mockTool{name: "tool_b"})
+
+	r.Replace("tool_a", &mockTool{name: "tool_a", desc: "new"})
+	r.Replace("tool_c", &mockTool{name: "tool_c"})
+
+	defs := r.Definitions()
+	if len(defs) != 3 {
+		t.Fatalf("expected 3 definitions, got %d", len(defs))
+	}
+	if defs[0].Function.Name != "tool_a" || defs[0].Function.Description != "new" {
+		t.Fatalf("expected tool_a replaced in place, got %+v", defs[0])
+	}
+	if defs[2].Function.Name != "tool_c" {
+		t.Fatalf("expected tool_c appended, got %+v", defs[2])
+	}
+}
+
+func TestRegistryConcurrentUse(t *testing.T) {
+	r := NewRegistry()
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ns := fmt.Sprintf("ns%d", i)
+			for range 50 {
+				r.RegisterNamespace(ns, &mockTool{name: "t"})
+				r.Definitions()
+				r.Get(ns + ".t")
+				r.UnregisterNamespace(ns)
+			}
+		}()
+	}
+	wg.Wait()
+	if defs := r.Definitions(); len(defs) != 0 {
+		t.Fatalf("expected an empty registry, got %d tools", len(defs))
+	}
+}
@@ -0,0 +1,266 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Scratchpad is a temporary directory that lasts for one session, where
+// the agent can stash large intermediate artifacts (generated lists,
+// extracted data) and read them back piece by piece. Unlike memory it is
+// never loaded into the system prompt and is removed by Close.
+type Scratchpad struct {
+	mu  sync.Mutex
+	dir string
+}
+
+// Dir returns the scratchpad directory, creating it on first use.
+func (s *Scratchpad) Dir() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.dir == "" {
+		dir, err := os.MkdirTemp("", "stormtrooper-scratch-")
+		if err != nil {
+			return "", err
+		}
+		s.dir = dir
+	}
+	return s.dir, nil
+}
+
+// Close removes the scratchpad and everything in it.
+func (s *Scratchpad) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.dir == "" {
+		return nil
+	}
+	err := os.RemoveAll(s.dir)
+	s.dir = ""
+	return err
+}
+
+// Save stores content as a new entry whose name starts with prefix and
+// returns the name.
+func (s *Scratchpad) Save(prefix, content string) (string, error) {
+	dir, err := s.Dir()
+	if err != nil {
+		return "", err
+	}
+	f, err := os.CreateTemp(dir, prefix+"-*.txt")
+	if err != nil {
+		return "", err
+	}
+	_, err = f.WriteString(content)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return filepath.Base(f.Name()), nil
+}
+
+// Tools returns the scratchpad_write and scratchpad_read tools.
+func (s *Scratchpad) Tools() []Tool {
+	return []Tool{&ScratchpadWriteTool{Pad: s}, &ScratchpadReadTool{Pad: s}}
+}
+
+// path resolves a scratchpad entry name. Names are plain file names.
+func (s *Scratchpad) path(name string) (string, error) {
+	if name == "" {
+		return "", fmt.Errorf("name is required")
+	}
+	if name != filepath.Base(name) || name == "." || name == ".." {
+		return "", fmt.Errorf("name must be a plain file name, without directories")
+	}
+	dir, err := s.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name), nil
+}
+
+// ScratchpadWriteTool writes or appends to a scratchpad entry.
+type ScratchpadWriteTool struct {
+	Pad *Scratchpad
+}
+
+type scratchpadWriteParams struct {
+	Name    string `json:"name"`
+	Content string `json:"content"`
+	Append  bool   `json:"append"`
+}
+
+func (t *ScratchpadWriteTool) Name() string { return "scratchpad_write" }
+func (t *ScratchpadWriteTool) Description() string {
+	return "Save an intermediate artifact (a generated list, extracted data, a draft) to this session's scratchpad so it does not have to stay in the conversation. The scratchpad is discarded when the session ends; use memory_write for anything worth keeping."
+}
+func (t *ScratchpadWriteTool) Permission() PermissionLevel { return PermissionAuto }
+func (t *ScratchpadWriteTool) Capabilities() []Capability  { return []Capability{CapWrite} }
+
+func (t *ScratchpadWriteTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+	"type": "object",
+	"properties": {
+		"name": {
+			"type": "string",
+			"description": "Entry name, a plain file name (e.g., 'endpoints.txt')"
+		},
+		"content": {
+			"type": "string",
+			"description": "Content to write"
+		},
+		"append": {
+			"type": "boolean",
+			"description": "Append to the entry instead of replacing it"
+		}
+	},
+	"required": ["name", "content"]
+}`)
+}
+
+func (t *ScratchpadWriteTool) Execute(_ context.Context, params json.RawMessage) (string, error) {
+	var p scratchpadWriteParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return fmt.Sprintf("Error: invalid parameters: %v", err), nil
+	}
+	path, err := t.Pad.path(p.Name)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if p.Append {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	}
+	f, err := os.OpenFile(path, flags, 0600)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+	_, err = f.WriteString(p.Content)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+	return fmt.Sprintf("Scratchpad %s: %d bytes", p.Name, info.Size()), nil
+}
+
+// ScratchpadReadTool reads a scratchpad entry, or lists the entries.
+type ScratchpadReadTool struct {
+	Pad *Scratchpad
+}
+
+type scratchpadReadParams struct {
+	Name   string `json:"name"`
+	Offset int    `json:"offset"`
+	Limit  int    `json:"limit"`
+}
+
+func (t *ScratchpadReadTool) Name() string { return "scratchpad_read" }
+func (t *ScratchpadReadTool) Description() string {
+	return "Read an entry from this session's scratchpad, optionally a range of lines, or list the entries when no name is given"
+}
+func (t *ScratchpadReadTool) Permission() PermissionLevel { return PermissionAuto }
+func (t *ScratchpadReadTool) Capabilities() []Capability  { return []Capability{CapRead} }
+
+func (t *ScratchpadReadTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+	"type": "object",
+	"properties": {
+		"name": {
+			"type": "string",
+			"description": "Entry to read; omit to list the entries"
+		},
+		"offset": {
+			"type": "integer",
+			"description": "First line to return, starting at 1 (default: 1)"
+		},
+		"limit": {
+			"type": "integer",
+			"description": "Maximum number of lines to return (default: all, up to 100KB)"
+		}
+	}
+}`)
+}
+
+func (t *ScratchpadReadTool) Execute(_ context.Context, params json.RawMessage) (string, error) {
+	var p scratchpadReadParams
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &p); err != nil {
+			return fmt.Sprintf("Error: invalid parameters: %v", err), nil
+		}
+	}
+	if p.Name == "" {
+		return t.list()
+	}
+	path, err := t.Pad.path(p.Name)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Sprintf("Error: no scratchpad entry %q", p.Name), nil
+		}
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+
+	content := string(data)
+	if p.Offset > 1 || p.Limit > 0 {
+		lines := strings.SplitAfter(content, "\n")
+		if lines[len(lines)-1] == "" {
+			lines = lines[:len(lines)-1]
+		}
+		start := min(max(p.Offset, 1)-1, len(lines))
+		end := len(lines)
+		if p.Limit > 0 {
+			end = min(start+p.Limit, end)
+		}
+		content = strings.Join(lines[start:end], "")
+		if end < len(lines) {
+			content += fmt.Sprintf("\n[lines %d-%d of %d]", start+1, end, len(lines))
+		}
+	}
+	if len(content) > maxReadSize {
+		return content[:maxReadSize] + "\n\n[truncated — use offset and limit to read the rest]", nil
+	}
+	return content, nil
+}
+
+func (t *ScratchpadReadTool) list() (string, error) {
+	dir, err := t.Pad.Dir()
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+	if len(entries) == 0 {
+		return "The scratchpad is empty.", nil
+	}
+	var lines []string
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%s (%d bytes)", e.Name(), info.Size()))
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n"), nil
+}
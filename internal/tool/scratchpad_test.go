@@ -0,0 +1,116 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestScratchpadToolsInterface(t *testing.T) {
+	pad := &Scratchpad{}
+	tools := pad.Tools()
+	if len(tools) != 2 || tools[0].Name() != "scratchpad_write" || tools[1].Name() != "scratchpad_read" {
+		t.Fatalf("Tools() = %v", tools)
+	}
+	for _, tool := range tools {
+		if tool.Permission() != PermissionAuto {
+			t.Errorf("%s: expected PermissionAuto", tool.Name())
+		}
+		var schema interface{}
+		if err := json.Unmarshal(tool.Schema(), &schema); err != nil {
+			t.Errorf("%s: schema is not valid JSON: %v", tool.Name(), err)
+		}
+	}
+}
+
+func scratchCall(t *testing.T, tool Tool, params any) string {
+	t.Helper()
+	data, _ := json.Marshal(params)
+	result, err := tool.Execute(context.Background(), data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return result
+}
+
+func TestScratchpadWriteRead(t *testing.T) {
+	pad := &Scratchpad{}
+	defer pad.Close()
+	write, read := &ScratchpadWriteTool{Pad: pad}, &ScratchpadReadTool{Pad: pad}
+
+	if got := scratchCall(t, read, map[string]any{}); got != "The scratchpad is empty." {
+		t.Errorf("empty list = %q", got)
+	}
+	if got := scratchCall(t, write, scratchpadWriteParams{Name: "list.txt", Content: "a\nb\n"}); got != "Scratchpad list.txt: 4 bytes" {
+		t.Errorf("write = %q", got)
+	}
+	scratchCall(t, write, scratchpadWriteParams{Name: "list.txt", Content: "c\nd\n", Append: true})
+	if got := scratchCall(t, read, scratchpadReadParams{Name: "list.txt"}); got != "a\nb\nc\nd\n" {
+		t.Errorf("read = %q", got)
+	}
+	if got := scratchCall(t, read, scratchpadReadParams{Name: "list.txt", Offset: 2, Limit: 2}); got != "b\nc\n\n[lines 2-3 of 4]" {
+		t.Errorf("read range = %q", got)
+	}
+	if got := scratchCall(t, read, scratchpadReadParams{Name: "list.txt", Offset: 4}); got != "d\n" {
+		t.Errorf("read tail = %q", got)
+	}
+	scratchCall(t, write, scratchpadWriteParams{Name: "list.txt", Content: "replaced"})
+	if got := scratchCall(t, read, scratchpadReadParams{Name: "list.txt"}); got != "replaced" {
+		t.Errorf("read after overwrite = %q", got)
+	}
+	if got := scratchCall(t, read, map[string]any{}); got != "list.txt (8 bytes)" {
+		t.Errorf("list = %q", got)
+	}
+}
+
+func TestScratchpadSave(t *testing.T) {
+	pad := &Scratchpad{}
+	defer pad.Close()
+	read := &ScratchpadReadTool{Pad: pad}
+
+	first, err := pad.Save("shell_exec", "one\ntwo\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, _ := pad.Save("shell_exec", "three\n")
+	if first == second || !strings.HasPrefix(first, "shell_exec-") {
+		t.Errorf("expected distinct prefixed names, got %q and %q", first, second)
+	}
+	if got := scratchCall(t, read, map[string]any{"name": first, "offset": 2}); got != "two\n" {
+		t.Errorf("read saved entry = %q", got)
+	}
+}
+
+func TestScratchpadErrors(t *testing.T) {
+	pad := &Scratchpad{}
+	defer pad.Close()
+	write, read := &ScratchpadWriteTool{Pad: pad}, &ScratchpadReadTool{Pad: pad}
+	for _, name := range []string{"", "../escape", "sub/file", ".."} {
+		if got := scratchCall(t, write, scratchpadWriteParams{Name: name, Content: "x"}); !strings.HasPrefix(got, "Error:") {
+			t.Errorf("write %q = %q, want an error", name, got)
+		}
+	}
+	if got := scratchCall(t, read, scratchpadReadParams{Name: "missing"}); !strings.Contains(got, "no scratchpad entry") {
+		t.Errorf("read missing = %q", got)
+	}
+}
+
+func TestScratchpadClose(t *testing.T) {
+	pad := &Scratchpad{}
+	dir, err := pad.Dir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	scratchCall(t, &ScratchpadWriteTool{Pad: pad}, scratchpadWriteParams{Name: "a", Content: "x"})
+	if err := pad.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("scratchpad directory still exists: %v", err)
+	}
+	if err := pad.Close(); err != nil {
+		t.Errorf("second Close: %v", err)
+	}
+}
@@ -5,6 +5,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
 	"time"
 )
 
@@ -12,19 +16,95 @@ const (
 	defaultTimeout = 30 * time.Second
 	maxTimeout     = 300 * time.Second
 	maxOutputSize  = 50 * 1024 // 50KB
+
+	// maxCommandOutput caps shell_exec's output. The agent shows the
+	// model a long result a page at a time, so this is only a backstop.
+	maxCommandOutput = 1024 * 1024 // 1MB
 )
 
-// ShellExecTool runs shell commands.
-type ShellExecTool struct{}
+// ShellExecTool runs shell commands. Commands run on the host unless
+// Executor routes them elsewhere.
+type ShellExecTool struct {
+	Executor Executor
+
+	// Root is the project directory the executor starts in. A cwd
+	// parameter must stay inside it. Empty means the working directory.
+	Root string
+}
 
 type shellExecParams struct {
-	Command string `json:"command"`
-	Timeout int    `json:"timeout"`
+	Command string            `json:"command"`
+	Argv    []string          `json:"argv"`
+	Cwd     string            `json:"cwd"`
+	Env     map[string]string `json:"env"`
+	Timeout int               `json:"timeout"`
+}
+
+var envName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// check reports the problem with p, if any: exactly one of command and
+// argv must be given.
+func (p shellExecParams) check() string {
+	switch {
+	case p.Command != "" && len(p.Argv) > 0:
+		return "Error: give either command or argv, not both"
+	case len(p.Argv) > 0 && p.Argv[0] == "":
+		return "Error: argv[0] must name the program to run"
+	case p.Command == "" && len(p.Argv) == 0:
+		return "Error: command or argv is required"
+	}
+	for name := range p.Env {
+		if !envName.MatchString(name) {
+			return fmt.Sprintf("Error: invalid environment variable name %q", name)
+		}
+	}
+	return ""
+}
+
+// display is the command as shown to the user, as a shell would run it.
+func (p shellExecParams) display() string {
+	return p.script(p.Cwd)
+}
+
+// script is the shell command that runs p in dir with its env set.
+func (p shellExecParams) script(dir string) string {
+	var b strings.Builder
+	if dir != "" && dir != "." {
+		b.WriteString("cd " + shellQuote(dir) + " && ")
+	}
+	if len(p.Env) > 0 {
+		b.WriteString("export")
+		for _, kv := range p.envList() {
+			name, value, _ := strings.Cut(kv, "=")
+			b.WriteString(" " + name + "=" + shellQuote(value))
+		}
+		b.WriteString(" && ")
+	}
+	if len(p.Argv) > 0 {
+		if b.Len() > 0 {
+			b.WriteString("exec ")
+		}
+		b.WriteString(ShellJoin(p.Argv))
+	} else {
+		b.WriteString(p.Command)
+	}
+	return b.String()
+}
+
+// envList returns p.Env as sorted NAME=value pairs.
+func (p shellExecParams) envList() []string {
+	var env []string
+	for name, value := range p.Env {
+		env = append(env, name+"="+value)
+	}
+	slices.Sort(env)
+	return env
 }
 
 func (t *ShellExecTool) Name() string        { return "shell_exec" }
 func (t *ShellExecTool) Description() string { return "Execute a shell command and return its output" }
 func (t *ShellExecTool) Permission() PermissionLevel { return PermissionPrompt }
+func (t *ShellExecTool) Capabilities() []Capability { return []Capability{CapExec} }
 
 func (t *ShellExecTool) Schema() json.RawMessage {
 	return json.RawMessage(`{
@@ -34,12 +114,25 @@ func (t *ShellExecTool) Schema() json.RawMessage {
 			"type": "string",
 			"description": "The shell command to execute"
 		},
+		"argv": {
+			"type": "array",
+			"items": {"type": "string"},
+			"description": "The program and its arguments, run without a shell. Use instead of command when arguments contain quotes, spaces, or other shell metacharacters"
+		},
+		"cwd": {
+			"type": "string",
+			"description": "Directory to run in, inside the project (default: the project root)"
+		},
+		"env": {
+			"type": "object",
+			"additionalProperties": {"type": "string"},
+			"description": "Environment variables to set for this command"
+		},
 		"timeout": {
 			"type": "integer",
 			"description": "Timeout in seconds (default 30)"
 		}
-	},
-	"required": ["command"]
+	}
 }`)
 }
 
@@ -49,7 +142,17 @@ func (t *ShellExecTool) Preview(params json.RawMessage) string {
 	if err := json.Unmarshal(params, &p); err != nil {
 		return "Run command (invalid params)"
 	}
-	return fmt.Sprintf("Run command: %s", p.Command)
+	return fmt.Sprintf("Run command: %s", p.display())
+}
+
+// Command returns the call as the permission prompt shows it, with its
+// cd and exports, so a remembered approval covers only that invocation.
+func (t *ShellExecTool) Command(params json.RawMessage) string {
+	var p shellExecParams
+	if err := json.Unmarshal(params, &p); err != nil || p.check() != "" {
+		return ""
+	}
+	return p.display()
 }
 
 func (t *ShellExecTool) Execute(ctx context.Context, params json.RawMessage) (string, error) {
@@ -57,8 +160,8 @@ func (t *ShellExecTool) Execute(ctx context.Context, params json.RawMessage) (st
 	if err := json.Unmarshal(params, &p); err != nil {
 		return fmt.Sprintf("Error: invalid parameters: %v", err), nil
 	}
-	if p.Command == "" {
-		return "Error: command is required", nil
+	if msg := p.check(); msg != "" {
+		return msg, nil
 	}
 
 	timeout := defaultTimeout
@@ -72,19 +175,26 @@ func (t *ShellExecTool) Execute(ctx context.Context, params json.RawMessage) (st
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
-	cmd := exec.CommandContext(ctx, "sh", "-c", p.Command)
+	var executor Executor = LocalExecutor{}
+	if t.Executor != nil {
+		executor = t.Executor
+	}
+	cmd, err := t.command(ctx, executor, p)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
 	output, err := cmd.CombinedOutput()
 
 	// Truncate if too large
 	truncated := false
-	if len(output) > maxOutputSize {
-		output = output[:maxOutputSize]
+	if len(output) > maxCommandOutput {
+		output = output[:maxCommandOutput]
 		truncated = true
 	}
 
 	result := string(output)
 	if truncated {
-		result += "\n\n[truncated — output exceeds 50KB]"
+		result += "\n\n[truncated — output exceeds 1MB]"
 	}
 
 	if err != nil {
@@ -99,3 +209,85 @@ func (t *ShellExecTool) Execute(ctx context.Context, params json.RawMessage) (st
 
 	return result, nil
 }
+
+// command builds the process for p. On the host the directory and
+// environment are set on the process itself; other executors run a
+// shell script that sets them up first.
+func (t *ShellExecTool) command(ctx context.Context, e Executor, p shellExecParams) (*exec.Cmd, error) {
+	dir, err := t.workDir(p.Cwd, e)
+	if err != nil {
+		return nil, err
+	}
+	local, isLocal := e.(LocalExecutor)
+	if !isLocal && (dir != "." || len(p.Env) > 0) {
+		return e.Command(ctx, p.script(dir)), nil
+	}
+
+	var cmd *exec.Cmd
+	if len(p.Argv) > 0 {
+		cmd = commandArgv(ctx, e, p.Argv)
+	} else {
+		cmd = e.Command(ctx, p.Command)
+	}
+	if isLocal {
+		if dir != "." {
+			cmd.Dir = filepath.Join(t.root(), dir)
+		}
+		if len(p.Env) > 0 {
+			if cmd.Env == nil {
+				cmd.Env = local.Env.Environ()
+			}
+			cmd.Env = append(cmd.Env, p.envList()...)
+		}
+	}
+	return cmd, nil
+}
+
+func (t *ShellExecTool) root() string {
+	if t.Root == "" {
+		return "."
+	}
+	return t.Root
+}
+
+// workDir resolves cwd against the project root and returns it relative
+// to the root, or an error if it leads outside. On the host, symlinks are
+// resolved first so a link cannot be used to escape.
+func (t *ShellExecTool) workDir(cwd string, e Executor) (string, error) {
+	if cwd == "" {
+		return ".", nil
+	}
+	root, path := t.root(), cwd
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(root, path)
+	}
+	if _, isLocal := e.(LocalExecutor); isLocal {
+		var err error
+		if root, err = filepath.EvalSymlinks(root); err != nil {
+			return "", err
+		}
+		if path, err = filepath.EvalSymlinks(path); err != nil {
+			return "", fmt.Errorf("cwd: %w", err)
+		}
+	}
+	rel, ok := within(root, path)
+	if !ok {
+		return "", fmt.Errorf("cwd %s is outside the project directory", cwd)
+	}
+	return rel, nil
+}
+
+// within returns path relative to root, and whether it stays inside.
+func within(root, path string) (string, bool) {
+	abs := func(p string) string {
+		if a, err := filepath.Abs(p); err == nil {
+			return a
+		}
+		return p
+	}
+	rel, err := filepath.Rel(abs(root), abs(path))
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return rel, true
+}
@@ -3,6 +3,9 @@ package tool
 import (
 	"context"
 	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -81,6 +84,31 @@ func TestShellExecPreview(t *testing.T) {
 	}
 }
 
+func TestShellExecCommand(t *testing.T) {
+	tool := &ShellExecTool{}
+	var _ Commander = tool
+	tests := []struct {
+		params shellExecParams
+		want   string
+	}{
+		{shellExecParams{Command: "make test"}, "make test"},
+		{shellExecParams{Argv: []string{"go", "test", "./..."}}, "go test ./..."},
+		// A rule for "make test" does not cover it with an environment
+		// or directory added.
+		{shellExecParams{Command: "make test", Cwd: "sub", Env: map[string]string{"LD_PRELOAD": "/tmp/x.so"}}, "cd sub && export LD_PRELOAD=/tmp/x.so && make test"},
+		{shellExecParams{Command: "ls", Argv: []string{"ls"}}, ""},
+	}
+	for _, tt := range tests {
+		params, _ := json.Marshal(tt.params)
+		if got := CommandFor(tool, params); got != tt.want {
+			t.Errorf("CommandFor(%s) = %q, want %q", params, got, tt.want)
+		}
+	}
+	if CommandFor(&ReadFileTool{}, nil) != "" {
+		t.Error("tools that run no command have none")
+	}
+}
+
 func TestShellExecCapturesStderr(t *testing.T) {
 	tool := &ShellExecTool{}
 	params, _ := json.Marshal(shellExecParams{Command: "echo err >&2"})
@@ -92,3 +120,149 @@ func TestShellExecCapturesStderr(t *testing.T) {
 		t.Fatalf("expected stderr output, got %q", result)
 	}
 }
+
+// recordingExecutor runs commands locally and remembers what it was asked.
+type recordingExecutor struct {
+	commands []string
+}
+
+func (e *recordingExecutor) Command(ctx context.Context, command string) *exec.Cmd {
+	e.commands = append(e.commands, command)
+	return exec.CommandContext(ctx, "echo", "from executor")
+}
+
+func TestShellExecUsesExecutor(t *testing.T) {
+	ex := &recordingExecutor{}
+	tool := &ShellExecTool{Executor: ex}
+	params, _ := json.Marshal(shellExecParams{Command: "make test"})
+	result, err := tool.Execute(context.Background(), params)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.TrimSpace(result) != "from executor" {
+		t.Errorf("expected executor output, got %q", result)
+	}
+	if len(ex.commands) != 1 || ex.commands[0] != "make test" {
+		t.Errorf("executor got %v", ex.commands)
+	}
+}
+
+func TestShellExecArgv(t *testing.T) {
+	tool := &ShellExecTool{}
+	params, _ := json.Marshal(shellExecParams{Argv: []string{"printf", "%s|", "it's", "$HOME", "a b"}})
+	result, err := tool.Execute(context.Background(), params)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != "it's|$HOME|a b|" {
+		t.Errorf("arguments should pass through unchanged, got %q", result)
+	}
+	if got := tool.Preview(params); got != `Run command: printf '%s|' 'it'\''s' '$HOME' 'a b'` {
+		t.Errorf("preview = %q", got)
+	}
+}
+
+func TestShellExecArgvInvalid(t *testing.T) {
+	tool := &ShellExecTool{}
+	for _, p := range []shellExecParams{
+		{},
+		{Command: "ls", Argv: []string{"ls"}},
+		{Argv: []string{"", "x"}},
+		{Command: "ls", Env: map[string]string{"NOT-VALID": "x"}},
+	} {
+		params, _ := json.Marshal(p)
+		result, _ := tool.Execute(context.Background(), params)
+		if !strings.HasPrefix(result, "Error:") {
+			t.Errorf("%+v: expected an error, got %q", p, result)
+		}
+	}
+}
+
+func TestShellExecArgvWithoutArgvExecutor(t *testing.T) {
+	ex := &recordingExecutor{}
+	tool := &ShellExecTool{Executor: ex}
+	params, _ := json.Marshal(shellExecParams{Argv: []string{"git", "commit", "-m", "fix: don't panic"}})
+	if _, err := tool.Execute(context.Background(), params); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := `git commit -m 'fix: don'\''t panic'`
+	if len(ex.commands) != 1 || ex.commands[0] != want {
+		t.Errorf("executor got %q, want %q", ex.commands, want)
+	}
+}
+
+func TestShellJoin(t *testing.T) {
+	tests := []struct {
+		argv []string
+		want string
+	}{
+		{[]string{"go", "test", "./..."}, "go test ./..."},
+		{[]string{"echo", ""}, "echo ''"},
+		{[]string{"echo", "a;b", "$(x)"}, "echo 'a;b' '$(x)'"},
+	}
+	for _, tt := range tests {
+		if got := ShellJoin(tt.argv); got != tt.want {
+			t.Errorf("ShellJoin(%q) = %q, want %q", tt.argv, got, tt.want)
+		}
+	}
+}
+
+func TestShellExecCwdAndEnv(t *testing.T) {
+	root := t.TempDir()
+	os.Mkdir(filepath.Join(root, "sub"), 0755)
+	t.Setenv("STORMTROOPER_TEST_TOKEN", "secret")
+
+	tool := &ShellExecTool{
+		Executor: LocalExecutor{Env: &EnvFilter{Strip: []string{"*_TOKEN"}}},
+		Root:     root,
+	}
+	params, _ := json.Marshal(shellExecParams{
+		Command: `basename "$PWD"; echo "$GREETING"; echo "token=$STORMTROOPER_TEST_TOKEN"`,
+		Cwd:     "sub",
+		Env:     map[string]string{"GREETING": "it's me"},
+	})
+	result, err := tool.Execute(context.Background(), params)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != "sub\nit's me\ntoken=\n" {
+		t.Errorf("result = %q", result)
+	}
+}
+
+func TestShellExecCwdConfined(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	os.Symlink(outside, filepath.Join(root, "link"))
+
+	tool := &ShellExecTool{Root: root}
+	for _, cwd := range []string{"..", outside, "link", "missing"} {
+		params, _ := json.Marshal(shellExecParams{Command: "pwd", Cwd: cwd})
+		result, _ := tool.Execute(context.Background(), params)
+		if !strings.HasPrefix(result, "Error:") {
+			t.Errorf("cwd %q: expected an error, got %q", cwd, result)
+		}
+	}
+}
+
+func TestShellExecCwdAndEnvWithExecutor(t *testing.T) {
+	ex := &recordingExecutor{}
+	tool := &ShellExecTool{Executor: ex, Root: "/srv/proj"}
+	params, _ := json.Marshal(shellExecParams{
+		Argv: []string{"go", "test", "./..."},
+		Cwd:  "/srv/proj/api",
+		Env:  map[string]string{"GOFLAGS": "-count=1", "CGO_ENABLED": "0"},
+	})
+	if _, err := tool.Execute(context.Background(), params); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "cd api && export CGO_ENABLED=0 GOFLAGS=-count=1 && exec go test ./..."
+	if len(ex.commands) != 1 || ex.commands[0] != want {
+		t.Errorf("executor got %q, want %q", ex.commands, want)
+	}
+
+	params, _ = json.Marshal(shellExecParams{Command: "ls", Cwd: "../other"})
+	if result, _ := tool.Execute(context.Background(), params); !strings.Contains(result, "outside the project") {
+		t.Errorf("expected cwd to be confined, got %q", result)
+	}
+}
@@ -33,6 +33,51 @@ type Previewer interface {
 	Preview(params json.RawMessage) string
 }
 
+// Proposer is an optional interface for tools that change one file, so
+// that the user can accept the change hunk by hunk.
+type Proposer interface {
+	// Propose returns the file a call would change and its contents
+	// before and after. An error means the call would fail, which
+	// Execute reports.
+	Propose(params json.RawMessage) (path, before, after string, err error)
+	// WriteProposed writes content, part of a proposed change, to path.
+	WriteProposed(path, content string) error
+}
+
+// ParamPermissioner is an optional interface for tools whose permission
+// depends on the call, such as a query tool that runs reads freely but
+// asks before writes. When implemented, it takes precedence over
+// Permission.
+type ParamPermissioner interface {
+	PermissionFor(params json.RawMessage) PermissionLevel
+}
+
+// PermissionFor returns the permission level t requires for a call with
+// params.
+func PermissionFor(t Tool, params json.RawMessage) PermissionLevel {
+	if p, ok := t.(ParamPermissioner); ok {
+		return p.PermissionFor(params)
+	}
+	return t.Permission()
+}
+
+// Commander is an optional interface for tools that run commands, so
+// that the user can allow one exact invocation for good.
+type Commander interface {
+	// Command returns the whole invocation a call runs, with the working
+	// directory and environment it sets, or "" for invalid params.
+	Command(params json.RawMessage) string
+}
+
+// CommandFor returns the invocation a call of t with params runs, or ""
+// if t does not run commands.
+func CommandFor(t Tool, params json.RawMessage) string {
+	if c, ok := t.(Commander); ok {
+		return c.Command(params)
+	}
+	return ""
+}
+
 // ToolDef represents a tool definition in OpenAI function calling format.
 type ToolDef struct {
 	Type     string      `json:"type"`
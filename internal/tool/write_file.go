@@ -3,13 +3,17 @@ package tool
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 )
 
-// WriteFileTool creates or overwrites a file.
-type WriteFileTool struct{}
+// WriteFileTool creates or overwrites a file. Files are written on the host
+// unless FS points elsewhere.
+type WriteFileTool struct {
+	FS FileSystem
+}
 
 type writeFileParams struct {
 	FilePath string `json:"file_path"`
@@ -19,6 +23,7 @@ type writeFileParams struct {
 func (t *WriteFileTool) Name() string        { return "write_file" }
 func (t *WriteFileTool) Description() string { return "Create or overwrite a file with the given content" }
 func (t *WriteFileTool) Permission() PermissionLevel { return PermissionPrompt }
+func (t *WriteFileTool) Capabilities() []Capability { return []Capability{CapWrite} }
 
 func (t *WriteFileTool) Schema() json.RawMessage {
 	return json.RawMessage(`{
@@ -37,19 +42,51 @@ func (t *WriteFileTool) Schema() json.RawMessage {
 }`)
 }
 
-// Preview returns a description for the permission prompt.
+// Preview returns a description for the permission prompt, with the
+// change as a unified diff.
 func (t *WriteFileTool) Preview(params json.RawMessage) string {
 	var p writeFileParams
 	if err := json.Unmarshal(params, &p); err != nil {
 		return "Write file (invalid params)"
 	}
 	msg := fmt.Sprintf("Write %d bytes to %s", len(p.Content), p.FilePath)
-	if _, err := os.Stat(p.FilePath); err == nil {
+	_, statErr := fileSystem(t.FS).Stat(p.FilePath)
+	existed := statErr == nil
+	if existed {
 		msg += " (overwrite existing file)"
 	}
+	if _, before, after, err := t.Propose(params); err == nil {
+		msg += "\n" + previewDiff(p.FilePath, before, after, existed)
+	}
 	return msg
 }
 
+// Propose returns the file's current and new contents; a new file is
+// empty before.
+func (t *WriteFileTool) Propose(params json.RawMessage) (path, before, after string, err error) {
+	var p writeFileParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return "", "", "", err
+	}
+	if p.FilePath == "" {
+		return "", "", "", errors.New("file_path is required")
+	}
+	data, err := fileSystem(t.FS).ReadFile(p.FilePath)
+	if err != nil && !os.IsNotExist(err) {
+		return "", "", "", err
+	}
+	return p.FilePath, string(data), p.Content, nil
+}
+
+// WriteProposed writes content to path.
+func (t *WriteFileTool) WriteProposed(path, content string) error {
+	fsys := fileSystem(t.FS)
+	if err := fsys.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return fsys.WriteFile(path, []byte(content), 0644)
+}
+
 func (t *WriteFileTool) Execute(_ context.Context, params json.RawMessage) (string, error) {
 	var p writeFileParams
 	if err := json.Unmarshal(params, &p); err != nil {
@@ -58,13 +95,17 @@ func (t *WriteFileTool) Execute(_ context.Context, params json.RawMessage) (stri
 	if p.FilePath == "" {
 		return "Error: file_path is required", nil
 	}
+	if err := checkWritePath(p.FilePath); err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
 
+	fsys := fileSystem(t.FS)
 	dir := filepath.Dir(p.FilePath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
+	if err := fsys.MkdirAll(dir, 0755); err != nil {
 		return fmt.Sprintf("Error: failed to create directory %s: %v", dir, err), nil
 	}
 
-	if err := os.WriteFile(p.FilePath, []byte(p.Content), 0644); err != nil {
+	if err := fsys.WriteFile(p.FilePath, []byte(p.Content), 0644); err != nil {
 		return fmt.Sprintf("Error: %v", err), nil
 	}
 	return fmt.Sprintf("File written: %s", p.FilePath), nil
@@ -99,6 +99,18 @@ func TestWriteFileEmptyPath(t *testing.T) {
 	}
 }
 
+func TestWriteFileRefusesGitDir(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".git", "hooks", "pre-commit")
+	params, _ := json.Marshal(writeFileParams{FilePath: path, Content: "#!/bin/sh"})
+	result, _ := (&WriteFileTool{}).Execute(context.Background(), params)
+	if !strings.Contains(result, "inside .git") {
+		t.Fatalf("expected a refusal, got %q", result)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("the hook should not have been written, stat err = %v", err)
+	}
+}
+
 func TestWriteFilePreview(t *testing.T) {
 	tool := &WriteFileTool{}
 	params, _ := json.Marshal(writeFileParams{FilePath: "/some/file.txt", Content: "hello"})
@@ -123,3 +135,47 @@ func TestWriteFilePreviewOverwrite(t *testing.T) {
 		t.Fatalf("preview should mention overwrite, got %q", preview)
 	}
 }
+
+func TestWriteFilePreviewDiff(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "notes.txt")
+	os.WriteFile(path, []byte("one\ntwo\n"), 0644)
+
+	tool := &WriteFileTool{}
+	params, _ := json.Marshal(writeFileParams{FilePath: path, Content: "one\n2\n"})
+	if preview := tool.Preview(params); !strings.Contains(preview, "-two\n+2") {
+		t.Errorf("preview should show the diff, got %q", preview)
+	}
+
+	params, _ = json.Marshal(writeFileParams{FilePath: filepath.Join(dir, "new.txt"), Content: "hello\n"})
+	if preview := tool.Preview(params); !strings.Contains(preview, "--- /dev/null") || !strings.Contains(preview, "+hello") {
+		t.Errorf("preview of a new file should diff from /dev/null, got %q", preview)
+	}
+}
+
+func TestWriteFilePropose(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "existing.txt")
+	os.WriteFile(path, []byte("old"), 0644)
+
+	tool := &WriteFileTool{}
+	var _ Proposer = tool
+	params, _ := json.Marshal(writeFileParams{FilePath: path, Content: "new"})
+	got, before, after, err := tool.Propose(params)
+	if err != nil || got != path || before != "old" || after != "new" {
+		t.Fatalf("Propose = %q, %q, %q, %v", got, before, after, err)
+	}
+
+	// A new file is empty before.
+	newPath := filepath.Join(dir, "sub", "new.txt")
+	params, _ = json.Marshal(writeFileParams{FilePath: newPath, Content: "hi"})
+	if _, before, _, err := tool.Propose(params); err != nil || before != "" {
+		t.Fatalf("Propose new file = %q, %v", before, err)
+	}
+	if err := tool.WriteProposed(newPath, "part"); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(newPath); string(data) != "part" {
+		t.Errorf("expected 'part', got %q", data)
+	}
+}
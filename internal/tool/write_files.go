@@ -0,0 +1,206 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+)
+
+// WriteFilesTool creates or overwrites several files as one change: every
+// path is checked before anything is written, and if a write fails the
+// files already written are put back the way they were.
+type WriteFilesTool struct {
+	FS FileSystem
+}
+
+type writeFilesParams struct {
+	Files []writeFileParams `json:"files"`
+}
+
+func (t *WriteFilesTool) Name() string { return "write_files" }
+func (t *WriteFilesTool) Description() string {
+	return "Create or overwrite several files at once. Either every file is written or, if any write fails, none are"
+}
+func (t *WriteFilesTool) Permission() PermissionLevel { return PermissionPrompt }
+func (t *WriteFilesTool) Capabilities() []Capability  { return []Capability{CapWrite} }
+
+func (t *WriteFilesTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+	"type": "object",
+	"properties": {
+		"files": {
+			"type": "array",
+			"description": "The files to write",
+			"items": {
+				"type": "object",
+				"properties": {
+					"file_path": {
+						"type": "string",
+						"description": "Path to the file to write"
+					},
+					"content": {
+						"type": "string",
+						"description": "The content to write to the file"
+					}
+				},
+				"required": ["file_path", "content"]
+			}
+		}
+	},
+	"required": ["files"]
+}`)
+}
+
+// Preview returns a description for the permission prompt.
+func (t *WriteFilesTool) Preview(params json.RawMessage) string {
+	var p writeFilesParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return "Write files (invalid params)"
+	}
+	fsys := fileSystem(t.FS)
+	var b strings.Builder
+	fmt.Fprintf(&b, "Write %d files:", len(p.Files))
+	for _, f := range p.Files {
+		fmt.Fprintf(&b, "\n  %s (%d bytes", f.FilePath, len(f.Content))
+		if _, err := fsys.Stat(f.FilePath); err == nil {
+			b.WriteString(", overwrite")
+		}
+		b.WriteString(")")
+	}
+	return b.String()
+}
+
+// stagedFile is a file write_files will write, with what to restore if
+// the batch fails.
+type stagedFile struct {
+	path    string
+	content []byte
+	old     []byte // nil if the file does not exist yet
+}
+
+func (t *WriteFilesTool) Execute(_ context.Context, params json.RawMessage) (string, error) {
+	var p writeFilesParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return fmt.Sprintf("Error: invalid parameters: %v", err), nil
+	}
+	if len(p.Files) == 0 {
+		return "Error: files is required", nil
+	}
+
+	fsys := fileSystem(t.FS)
+	staged, err := stageFiles(fsys, p.Files)
+	if err != nil {
+		return fmt.Sprintf("Error: %v; nothing was written", err), nil
+	}
+
+	var dirs []string // created, outermost first
+	for i, f := range staged {
+		dirs = append(dirs, missingDirs(fsys, filepath.Dir(f.path))...)
+		err := fsys.MkdirAll(filepath.Dir(f.path), 0755)
+		if err == nil {
+			err = fsys.WriteFile(f.path, f.content, 0644)
+		}
+		if err != nil {
+			msg := fmt.Sprintf("Error: writing %s: %v; rolled back %d file(s)", f.path, err, i)
+			if rerr := rollback(fsys, staged[:i+1], dirs); rerr != nil {
+				msg += fmt.Sprintf(" (rollback incomplete: %v)", rerr)
+			}
+			return msg, nil
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Files written: %d", len(staged))
+	for _, f := range staged {
+		fmt.Fprintf(&b, "\n- %s", f.path)
+	}
+	return b.String(), nil
+}
+
+// stageFiles checks every path and saves the current contents of the
+// files that already exist, so a failed batch can be undone.
+func stageFiles(fsys FileSystem, files []writeFileParams) ([]stagedFile, error) {
+	seen := make(map[string]bool, len(files))
+	staged := make([]stagedFile, 0, len(files))
+	for _, f := range files {
+		if f.FilePath == "" {
+			return nil, errors.New("every file needs a file_path")
+		}
+		clean := filepath.Clean(f.FilePath)
+		if seen[clean] {
+			return nil, fmt.Errorf("%s is listed more than once", f.FilePath)
+		}
+		seen[clean] = true
+		if err := checkWritePath(f.FilePath); err != nil {
+			return nil, err
+		}
+
+		s := stagedFile{path: f.FilePath, content: []byte(f.Content)}
+		info, err := fsys.Stat(f.FilePath)
+		switch {
+		case err == nil && info.IsDir():
+			return nil, fmt.Errorf("%s is a directory", f.FilePath)
+		case err == nil:
+			if s.old, err = fsys.ReadFile(f.FilePath); err != nil {
+				return nil, fmt.Errorf("reading %s: %v", f.FilePath, err)
+			}
+			if s.old == nil {
+				s.old = []byte{}
+			}
+		case !errors.Is(err, fs.ErrNotExist):
+			return nil, fmt.Errorf("checking %s: %v", f.FilePath, err)
+		}
+		staged = append(staged, s)
+	}
+	return staged, nil
+}
+
+// missingDirs returns dir and those of its parents that do not exist yet,
+// outermost first: the directories MkdirAll(dir) would create.
+func missingDirs(fsys FileSystem, dir string) []string {
+	var missing []string
+	for {
+		if _, err := fsys.Stat(dir); !errors.Is(err, fs.ErrNotExist) {
+			break
+		}
+		missing = append([]string{dir}, missing...)
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	return missing
+}
+
+// rollback restores the staged files in reverse order, deleting the ones
+// that did not exist before, then removes the directories created for
+// them.
+func rollback(fsys FileSystem, staged []stagedFile, dirs []string) error {
+	var errs []error
+	for i := len(staged) - 1; i >= 0; i-- {
+		f := staged[i]
+		var err error
+		if f.old == nil {
+			err = removeFile(fsys, f.path)
+			if errors.Is(err, fs.ErrNotExist) {
+				err = nil
+			}
+		} else {
+			err = fsys.WriteFile(f.path, f.old, 0644)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", f.path, err))
+		}
+	}
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err := removeFile(fsys, dirs[i]); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			errs = append(errs, fmt.Errorf("%s: %w", dirs[i], err))
+		}
+	}
+	return errors.Join(errs...)
+}
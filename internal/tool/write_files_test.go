@@ -0,0 +1,104 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFilesParamsJSON(files ...writeFileParams) json.RawMessage {
+	params, _ := json.Marshal(writeFilesParams{Files: files})
+	return params
+}
+
+func TestWriteFilesSuccess(t *testing.T) {
+	var _ Tool = &WriteFilesTool{}
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "main.go")
+	os.WriteFile(existing, []byte("old"), 0644)
+	created := filepath.Join(dir, "pkg", "util.go")
+
+	tool := &WriteFilesTool{}
+	params := writeFilesParamsJSON(writeFileParams{FilePath: existing, Content: "new"}, writeFileParams{FilePath: created, Content: "util"})
+	if preview := tool.Preview(params); !strings.Contains(preview, "Write 2 files") || !strings.Contains(preview, "main.go (3 bytes, overwrite)") {
+		t.Errorf("unexpected preview:\n%s", preview)
+	}
+	result, err := tool.Execute(context.Background(), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result, "Files written: 2") {
+		t.Fatalf("expected success message, got %q", result)
+	}
+	for path, want := range map[string]string{existing: "new", created: "util"} {
+		if data, _ := os.ReadFile(path); string(data) != want {
+			t.Errorf("%s = %q, want %q", path, data, want)
+		}
+	}
+}
+
+// failingFS fails writes to one file.
+type failingFS struct {
+	LocalFS
+	fail string
+}
+
+func (f failingFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	if name == f.fail {
+		return errors.New("disk full")
+	}
+	return f.LocalFS.WriteFile(name, data, perm)
+}
+
+func TestWriteFilesRollsBack(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "main.go")
+	os.WriteFile(existing, []byte("old"), 0644)
+	created := filepath.Join(dir, "pkg", "sub", "new.go")
+	broken := filepath.Join(dir, "broken.go")
+
+	tool := &WriteFilesTool{FS: failingFS{fail: broken}}
+	result, _ := tool.Execute(context.Background(), writeFilesParamsJSON(
+		writeFileParams{FilePath: existing, Content: "new"},
+		writeFileParams{FilePath: created, Content: "x"},
+		writeFileParams{FilePath: broken, Content: "y"},
+	))
+	if !strings.Contains(result, "disk full") || !strings.Contains(result, "rolled back 2 file(s)") {
+		t.Fatalf("expected a rollback error, got %q", result)
+	}
+	if data, _ := os.ReadFile(existing); string(data) != "old" {
+		t.Errorf("main.go = %q, want it restored", data)
+	}
+	if _, err := os.Stat(created); !os.IsNotExist(err) {
+		t.Errorf("new.go should have been removed, stat err = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "pkg")); !os.IsNotExist(err) {
+		t.Errorf("the directories created for new.go should have been removed, stat err = %v", err)
+	}
+}
+
+func TestWriteFilesValidatesBeforeWriting(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "a.txt")
+	cases := map[string][]writeFileParams{
+		"files is required": nil,
+		"needs a file_path": {{FilePath: first}, {Content: "x"}},
+		"more than once":    {{FilePath: first}, {FilePath: dir + "/./a.txt"}},
+		"inside .git":       {{FilePath: first}, {FilePath: filepath.Join(dir, ".git", "config")}},
+		"is a directory":    {{FilePath: first}, {FilePath: dir}},
+	}
+	for want, files := range cases {
+		result, _ := (&WriteFilesTool{}).Execute(context.Background(), writeFilesParamsJSON(files...))
+		if !strings.Contains(result, want) {
+			t.Errorf("expected %q error, got %q", want, result)
+		}
+		if _, err := os.Stat(first); !os.IsNotExist(err) {
+			t.Fatalf("%s: nothing should be written when validation fails", want)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+package tooltest
+
+import "testing"
+
+func TestGolden(t *testing.T) {
+	Golden(t, "example", "Counted 2 lines in $WORK/a.txt\n")
+
+	r := &recorder{}
+	Golden(r, "example", "Counted 3 lines in $WORK/a.txt\n")
+	Golden(r, "missing", "")
+	if len(r.failures) != 2 {
+		t.Errorf("failures = %q, want a mismatch and a missing file", r.failures)
+	}
+}
@@ -0,0 +1,214 @@
+package tooltest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/gavinyap/stormtrooper/internal/tool"
+)
+
+// namePattern is what providers accept as a function name.
+var namePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// schemaTypes are the JSON Schema types a parameter may have.
+var schemaTypes = []string{"string", "integer", "number", "boolean", "array", "object"}
+
+// schema is the part of JSON Schema that tool parameters use.
+type schema struct {
+	Type        string             `json:"type"`
+	Description string             `json:"description"`
+	Properties  map[string]*schema `json:"properties"`
+	Required    []string           `json:"required"`
+	Items       *schema            `json:"items"`
+	Enum        []any              `json:"enum"`
+}
+
+// ValidateTool checks t's definition: a name providers accept, a
+// description, a schema that passes ValidateSchema, and a preview for
+// the permission prompt if the tool asks before it runs.
+func ValidateTool(t tool.Tool) error {
+	var errs []error
+	if !namePattern.MatchString(t.Name()) {
+		errs = append(errs, fmt.Errorf("name %q must be 1 to 64 letters, digits, underscores, or dashes", t.Name()))
+	}
+	if strings.TrimSpace(t.Description()) == "" {
+		errs = append(errs, errors.New("description is empty"))
+	}
+	if err := ValidateSchema(t.Schema()); err != nil {
+		errs = append(errs, err)
+	}
+	if _, ok := t.(tool.Previewer); t.Permission() == tool.PermissionPrompt && !ok {
+		errs = append(errs, errors.New("asks permission but does not implement tool.Previewer, so the prompt shows raw JSON"))
+	}
+	return errors.Join(errs...)
+}
+
+// ValidateSchema checks a tool's parameter schema: an object whose
+// properties each have a known type and a description, and whose
+// required parameters are all defined.
+func ValidateSchema(raw json.RawMessage) error {
+	var s schema
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return fmt.Errorf("schema is not valid JSON: %w", err)
+	}
+	if s.Type != "object" {
+		return fmt.Errorf("schema type is %q, want \"object\"", s.Type)
+	}
+	return checkSchema("", &s, true)
+}
+
+// checkSchema checks s, found at path, and the schemas inside it.
+// Top-level parameters must be described; nested ones may not be.
+func checkSchema(path string, s *schema, top bool) error {
+	var errs []error
+	if s.Type != "" && !slices.Contains(schemaTypes, s.Type) {
+		errs = append(errs, fmt.Errorf("%s: unknown type %q", where(path), s.Type))
+	}
+	for _, name := range sortedKeys(s.Properties) {
+		p := s.Properties[name]
+		if p == nil {
+			errs = append(errs, fmt.Errorf("%s: schema is null", join(path, name)))
+			continue
+		}
+		if p.Type == "" && p.Enum == nil {
+			errs = append(errs, fmt.Errorf("%s: no type", join(path, name)))
+		}
+		if top && strings.TrimSpace(p.Description) == "" {
+			errs = append(errs, fmt.Errorf("%s: no description; the model relies on it to fill the parameter in", join(path, name)))
+		}
+		errs = append(errs, checkSchema(join(path, name), p, false))
+	}
+	for _, name := range s.Required {
+		if _, ok := s.Properties[name]; !ok {
+			errs = append(errs, fmt.Errorf("%s: required parameter %q is not defined", where(path), name))
+		}
+	}
+	if s.Type == "array" {
+		if s.Items == nil {
+			errs = append(errs, fmt.Errorf("%s: array without items", where(path)))
+		} else {
+			errs = append(errs, checkSchema(path+"[]", s.Items, false))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ValidateParams checks the arguments of a call against a tool's
+// schema: required parameters are present, types and enums match, and
+// no parameter is unknown, which usually means a typo in a test.
+func ValidateParams(rawSchema, params json.RawMessage) error {
+	var s schema
+	if err := json.Unmarshal(rawSchema, &s); err != nil {
+		return fmt.Errorf("schema is not valid JSON: %w", err)
+	}
+	var v any
+	if err := json.Unmarshal(params, &v); err != nil {
+		return fmt.Errorf("params are not valid JSON: %w", err)
+	}
+	if _, ok := v.(map[string]any); !ok {
+		return fmt.Errorf("params are %s, want an object", jsonType(v))
+	}
+	return checkValue("", &s, v)
+}
+
+// checkValue checks the value v, found at path, against s.
+func checkValue(path string, s *schema, v any) error {
+	if s.Type != "" && !hasType(s.Type, v) {
+		return fmt.Errorf("%s: got %s, want %s", where(path), jsonType(v), s.Type)
+	}
+	if s.Enum != nil && !slices.ContainsFunc(s.Enum, func(e any) bool { return e == v }) {
+		return fmt.Errorf("%s: %v is not one of %v", where(path), v, s.Enum)
+	}
+	var errs []error
+	switch v := v.(type) {
+	case map[string]any:
+		for _, name := range s.Required {
+			if val, ok := v[name]; !ok || val == nil {
+				errs = append(errs, fmt.Errorf("%s: missing required parameter %q", where(path), name))
+			}
+		}
+		if s.Properties == nil {
+			break
+		}
+		for _, name := range sortedKeys(v) {
+			p, ok := s.Properties[name]
+			if !ok {
+				errs = append(errs, fmt.Errorf("%s: unknown parameter", join(path, name)))
+				continue
+			}
+			if v[name] != nil && p != nil {
+				errs = append(errs, checkValue(join(path, name), p, v[name]))
+			}
+		}
+	case []any:
+		if s.Items != nil {
+			for i, item := range v {
+				errs = append(errs, checkValue(fmt.Sprintf("%s[%d]", path, i), s.Items, item))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// hasType reports whether v, decoded from JSON, is of the schema type t.
+func hasType(t string, v any) bool {
+	switch v := v.(type) {
+	case string:
+		return t == "string"
+	case float64:
+		return t == "number" || t == "integer" && v == math.Trunc(v)
+	case bool:
+		return t == "boolean"
+	case []any:
+		return t == "array"
+	case map[string]any:
+		return t == "object"
+	}
+	return false
+}
+
+// jsonType names the JSON type of v for error messages.
+func jsonType(v any) string {
+	switch v.(type) {
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return "null"
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func where(path string) string {
+	if path == "" {
+		return "params"
+	}
+	return path
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,111 @@
+package tooltest
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/gavinyap/stormtrooper/internal/agent"
+	"github.com/gavinyap/stormtrooper/internal/tool"
+)
+
+func TestValidateTool_Builtins(t *testing.T) {
+	pad := &tool.Scratchpad{}
+	defer pad.Close()
+	spawner := &agent.SpawnAgentTool{}
+	tools := []tool.Tool{
+		&tool.ReadFileTool{}, &tool.WriteFileTool{}, &tool.WriteFilesTool{}, &tool.EditFileTool{},
+		&tool.GlobTool{}, &tool.GrepTool{}, &tool.ShellExecTool{}, &tool.HTTPRequestTool{},
+		&tool.MemoryWriteTool{}, &tool.DBQueryTool{}, &tool.PackageInfoTool{}, &tool.ReadClipboardTool{},
+		&tool.CaptureTerminalTool{}, spawner, &agent.HandoffTool{Spawner: spawner},
+	}
+	tools = append(tools, pad.Tools()...)
+	tools = append(tools, spawner.Tools()...)
+	for _, tl := range tools {
+		if err := ValidateTool(tl); err != nil {
+			t.Errorf("%s: %v", tl.Name(), err)
+		}
+	}
+}
+
+// fakeTool is a tool with a given definition.
+type fakeTool struct {
+	name, description, schema string
+	perm                      tool.PermissionLevel
+	result                    string
+}
+
+func (f *fakeTool) Name() string                     { return f.name }
+func (f *fakeTool) Description() string              { return f.description }
+func (f *fakeTool) Schema() json.RawMessage          { return json.RawMessage(f.schema) }
+func (f *fakeTool) Permission() tool.PermissionLevel { return f.perm }
+func (f *fakeTool) Execute(_ context.Context, params json.RawMessage) (string, error) {
+	return f.result + string(params), nil
+}
+
+const countSchema = `{
+	"type": "object",
+	"properties": {
+		"file_path": {"type": "string", "description": "File to count"},
+		"mode": {"type": "string", "enum": ["lines", "words"], "description": "What to count"},
+		"limit": {"type": "integer", "description": "Stop after this many"},
+		"globs": {"type": "array", "items": {"type": "string"}, "description": "Patterns"}
+	},
+	"required": ["file_path"]
+}`
+
+func TestValidateTool(t *testing.T) {
+	tests := []struct {
+		name string
+		tool *fakeTool
+		want string
+	}{
+		{"valid", &fakeTool{name: "count", description: "Count", schema: countSchema}, ""},
+		{"bad name", &fakeTool{name: "count lines", description: "Count", schema: countSchema}, "name"},
+		{"no description", &fakeTool{name: "count", schema: countSchema}, "description is empty"},
+		{"prompt without preview", &fakeTool{name: "count", description: "Count", schema: countSchema, perm: tool.PermissionPrompt}, "tool.Previewer"},
+		{"invalid JSON", &fakeTool{name: "count", description: "Count", schema: `{"type":`}, "not valid JSON"},
+		{"not an object", &fakeTool{name: "count", description: "Count", schema: `{"type":"string"}`}, `want "object"`},
+		{"undescribed parameter", &fakeTool{name: "count", description: "Count", schema: `{"type":"object","properties":{"n":{"type":"integer"}}}`}, "n: no description"},
+		{"unknown type", &fakeTool{name: "count", description: "Count", schema: `{"type":"object","properties":{"n":{"type":"int","description":"N"}}}`}, `unknown type "int"`},
+		{"undefined required", &fakeTool{name: "count", description: "Count", schema: `{"type":"object","properties":{},"required":["n"]}`}, `"n" is not defined`},
+		{"array without items", &fakeTool{name: "count", description: "Count", schema: `{"type":"object","properties":{"n":{"type":"array","description":"N"}}}`}, "array without items"},
+	}
+	for _, tt := range tests {
+		err := ValidateTool(tt.tool)
+		switch {
+		case tt.want == "" && err != nil:
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+		case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestValidateParams(t *testing.T) {
+	tests := []struct {
+		params string
+		want   string
+	}{
+		{`{"file_path": "a.txt"}`, ""},
+		{`{"file_path": "a.txt", "mode": "words", "limit": 3, "globs": ["*.go"]}`, ""},
+		{`[]`, "want an object"},
+		{`{}`, `missing required parameter "file_path"`},
+		{`{"file_path": null}`, `missing required parameter "file_path"`},
+		{`{"file_path": 3}`, "file_path: got number, want string"},
+		{`{"file_path": "a.txt", "limit": 1.5}`, "limit: got number, want integer"},
+		{`{"file_path": "a.txt", "mode": "bytes"}`, "mode: bytes is not one of"},
+		{`{"file_path": "a.txt", "globs": ["*.go", 1]}`, "globs[1]: got number, want string"},
+		{`{"filepath": "a.txt"}`, "filepath: unknown parameter"},
+	}
+	for _, tt := range tests {
+		err := ValidateParams(json.RawMessage(countSchema), json.RawMessage(tt.params))
+		switch {
+		case tt.want == "" && err != nil:
+			t.Errorf("%s: unexpected error: %v", tt.params, err)
+		case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+			t.Errorf("%s: err = %v, want %q", tt.params, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+// Package tooltest helps test implementations of tool.Tool without
+// copying the setup from other tools' tests: it checks a tool's
+// definition and schema, runs calls checked against the schema, and
+// provides a fake permission handler, temporary workspaces, and golden
+// files.
+//
+// A typical test:
+//
+//	func TestCountLines(t *testing.T) {
+//		tooltest.CheckTool(t, &CountLinesTool{})
+//		ws := tooltest.NewWorkspace(t, map[string]string{"a.txt": "one\ntwo\n"})
+//		got := tooltest.Call(t, &CountLinesTool{}, map[string]any{"file_path": ws.Path("a.txt")})
+//		tooltest.Golden(t, "count_lines", ws.Scrub(got))
+//	}
+package tooltest
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"github.com/gavinyap/stormtrooper/internal/permission"
+	"github.com/gavinyap/stormtrooper/internal/tool"
+)
+
+// CheckTool fails the test if ValidateTool finds a problem with tl's
+// definition.
+func CheckTool(t testing.TB, tl tool.Tool) {
+	t.Helper()
+	if err := ValidateTool(tl); err != nil {
+		t.Errorf("%s: %v", tl.Name(), err)
+	}
+}
+
+// Call checks params against tl's schema, runs the tool, and returns its
+// result. params may be JSON, as a json.RawMessage, []byte, or string,
+// or any value to marshal. A schema mismatch or an error from Execute
+// fails the test.
+func Call(t testing.TB, tl tool.Tool, params any) string {
+	t.Helper()
+	return CallContext(t, context.Background(), tl, params)
+}
+
+// CallContext is Call with a context, for testing timeouts and
+// cancellation.
+func CallContext(t testing.TB, ctx context.Context, tl tool.Tool, params any) string {
+	t.Helper()
+	raw, err := rawParams(params)
+	if err != nil {
+		t.Fatalf("%s: %v", tl.Name(), err)
+		return ""
+	}
+	if err := ValidateParams(tl.Schema(), raw); err != nil {
+		t.Fatalf("%s: params do not match the schema: %v", tl.Name(), err)
+		return ""
+	}
+	result, err := tl.Execute(ctx, raw)
+	if err != nil {
+		t.Fatalf("%s: Execute: %v", tl.Name(), err)
+	}
+	return result
+}
+
+func rawParams(params any) (json.RawMessage, error) {
+	switch p := params.(type) {
+	case json.RawMessage:
+		return p, nil
+	case []byte:
+		return p, nil
+	case string:
+		return json.RawMessage(p), nil
+	}
+	return json.Marshal(params)
+}
+
+// Permission is a permission.Handler that gives the same answer to
+// every prompt and records the prompts, for tools and agents that take
+// a handler.
+type Permission struct {
+	// Allow is the answer to every prompt.
+	Allow bool
+
+	mu      sync.Mutex
+	prompts []Prompt
+}
+
+var _ permission.Handler = (*Permission)(nil)
+
+// Prompt is a permission prompt that Permission answered.
+type Prompt struct {
+	Tool    string
+	Preview string
+}
+
+// Check records the prompt and answers it with p.Allow.
+func (p *Permission) Check(_ context.Context, toolName, preview string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.prompts = append(p.prompts, Prompt{Tool: toolName, Preview: preview})
+	return p.Allow
+}
+
+// Prompts returns the prompts answered so far.
+func (p *Permission) Prompts() []Prompt {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]Prompt(nil), p.prompts...)
+}
@@ -0,0 +1,64 @@
+package tooltest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/gavinyap/stormtrooper/internal/tool"
+)
+
+// recorder is a testing.TB that records failures instead of ending the
+// test, for checking that the helpers fail when they should.
+type recorder struct {
+	testing.TB
+	failures []string
+}
+
+func (r *recorder) Helper() {}
+func (r *recorder) Errorf(format string, args ...any) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+func (r *recorder) Fatalf(format string, args ...any) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func TestCall(t *testing.T) {
+	tl := &fakeTool{name: "count", description: "Count", schema: countSchema, result: "called with "}
+	if got := Call(t, tl, map[string]any{"file_path": "a.txt"}); got != `called with {"file_path":"a.txt"}` {
+		t.Errorf("Call = %q", got)
+	}
+	if got := Call(t, tl, `{"file_path": "b.txt"}`); got != `called with {"file_path": "b.txt"}` {
+		t.Errorf("Call with JSON = %q", got)
+	}
+
+	r := &recorder{}
+	if got := Call(r, tl, map[string]any{"path": "a.txt"}); got != "" || len(r.failures) != 1 {
+		t.Errorf("Call with params off the schema = %q, failures %q", got, r.failures)
+	}
+}
+
+func TestCheckTool(t *testing.T) {
+	CheckTool(t, &tool.GlobTool{})
+
+	r := &recorder{}
+	CheckTool(r, &fakeTool{name: "count", schema: countSchema})
+	if len(r.failures) != 1 {
+		t.Errorf("failures = %q, want one for the missing description", r.failures)
+	}
+}
+
+func TestPermission(t *testing.T) {
+	p := &Permission{Allow: true}
+	if !p.Check(context.Background(), "shell_exec", "go test ./...") {
+		t.Error("Check = false, want Allow")
+	}
+	p.Allow = false
+	if p.Check(context.Background(), "write_file", "Write a.txt") {
+		t.Error("Check = true, want Allow")
+	}
+	want := []Prompt{{"shell_exec", "go test ./..."}, {"write_file", "Write a.txt"}}
+	if got := p.Prompts(); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Prompts = %v, want %v", got, want)
+	}
+}
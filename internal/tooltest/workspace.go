@@ -0,0 +1,67 @@
+package tooltest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Workspace is a temporary directory of files for a tool to work on. It
+// is removed when the test ends.
+type Workspace struct {
+	Dir string
+	t   testing.TB
+}
+
+// NewWorkspace creates a workspace holding files, keyed by slash-separated
+// path relative to the workspace.
+func NewWorkspace(t testing.TB, files map[string]string) *Workspace {
+	t.Helper()
+	w := &Workspace{Dir: t.TempDir(), t: t}
+	for name, content := range files {
+		w.Write(name, content)
+	}
+	return w
+}
+
+// Path returns the absolute path of name in the workspace.
+func (w *Workspace) Path(name string) string {
+	return filepath.Join(w.Dir, filepath.FromSlash(name))
+}
+
+// Write creates or replaces name in the workspace, with its directories.
+func (w *Workspace) Write(name, content string) {
+	w.t.Helper()
+	path := w.Path(name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		w.t.Fatalf("workspace: %v", err)
+		return
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		w.t.Fatalf("workspace: %v", err)
+	}
+}
+
+// Read returns the contents of name in the workspace, failing the test
+// if it cannot be read.
+func (w *Workspace) Read(name string) string {
+	w.t.Helper()
+	data, err := os.ReadFile(w.Path(name))
+	if err != nil {
+		w.t.Fatalf("workspace: %v", err)
+	}
+	return string(data)
+}
+
+// Exists reports whether name is in the workspace.
+func (w *Workspace) Exists(name string) bool {
+	_, err := os.Stat(w.Path(name))
+	return err == nil
+}
+
+// Scrub replaces the workspace's directory in s with "$WORK", so output
+// that names files can be compared with a golden file.
+func (w *Workspace) Scrub(s string) string {
+	return strings.ReplaceAll(s, w.Dir, "$WORK")
+}
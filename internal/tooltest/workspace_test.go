@@ -0,0 +1,33 @@
+package tooltest
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestWorkspace(t *testing.T) {
+	ws := NewWorkspace(t, map[string]string{"a.txt": "one\n", "pkg/b.go": "package pkg\n"})
+	if got := ws.Read("pkg/b.go"); got != "package pkg\n" {
+		t.Errorf("Read = %q", got)
+	}
+	if ws.Path("pkg/b.go") != filepath.Join(ws.Dir, "pkg", "b.go") {
+		t.Errorf("Path = %q", ws.Path("pkg/b.go"))
+	}
+	ws.Write("a.txt", "two\n")
+	if got := ws.Read("a.txt"); got != "two\n" {
+		t.Errorf("Read after Write = %q", got)
+	}
+	if !ws.Exists("a.txt") || ws.Exists("c.txt") {
+		t.Error("Exists is wrong")
+	}
+	if got := ws.Scrub("wrote " + ws.Path("a.txt")); got != "wrote "+filepath.Join("$WORK", "a.txt") {
+		t.Errorf("Scrub = %q", got)
+	}
+
+	r := &recorder{TB: t}
+	ws = NewWorkspace(r, nil)
+	ws.Read("missing.txt")
+	if len(r.failures) != 1 {
+		t.Errorf("failures = %q, want one for the missing file", r.failures)
+	}
+}
@@ -0,0 +1,89 @@
+package tracker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// jira uses REST API v2, whose text fields are plain strings (v3 uses
+// the Atlassian Document Format). It works with Cloud and Server.
+type jira struct {
+	c *client
+}
+
+func (j *jira) Kind() string { return Jira }
+
+func (j *jira) issuePath(key string) string {
+	return "/rest/api/2/issue/" + url.PathEscape(key)
+}
+
+func (j *jira) Issue(ctx context.Context, key string) (*Issue, error) {
+	var issue struct {
+		Key    string `json:"key"`
+		Fields struct {
+			Summary     string `json:"summary"`
+			Description string `json:"description"`
+			Status      struct {
+				Name string `json:"name"`
+			} `json:"status"`
+			Comment struct {
+				Comments []struct {
+					Body   string `json:"body"`
+					Author struct {
+						DisplayName string `json:"displayName"`
+					} `json:"author"`
+				} `json:"comments"`
+			} `json:"comment"`
+		} `json:"fields"`
+	}
+	if err := j.c.do(ctx, http.MethodGet, j.issuePath(key)+"?fields=summary,description,status,comment", nil, &issue); err != nil {
+		return nil, err
+	}
+	result := &Issue{
+		Key:   issue.Key,
+		Title: issue.Fields.Summary,
+		State: issue.Fields.Status.Name,
+		Body:  issue.Fields.Description,
+		URL:   j.c.baseURL + "/browse/" + issue.Key,
+	}
+	for _, c := range issue.Fields.Comment.Comments {
+		result.Comments = append(result.Comments, Comment{Author: c.Author.DisplayName, Body: c.Body})
+	}
+	return result, nil
+}
+
+func (j *jira) Comment(ctx context.Context, key, body string) error {
+	return j.c.do(ctx, http.MethodPost, j.issuePath(key)+"/comment", map[string]string{"body": body}, nil)
+}
+
+// Transition matches state against the available transitions' names and
+// target statuses, case-insensitively.
+func (j *jira) Transition(ctx context.Context, key, state string) (string, error) {
+	var available struct {
+		Transitions []struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+			To   struct {
+				Name string `json:"name"`
+			} `json:"to"`
+		} `json:"transitions"`
+	}
+	if err := j.c.do(ctx, http.MethodGet, j.issuePath(key)+"/transitions", nil, &available); err != nil {
+		return "", err
+	}
+	var names []string
+	for _, t := range available.Transitions {
+		if strings.EqualFold(t.Name, state) || strings.EqualFold(t.To.Name, state) {
+			body := map[string]any{"transition": map[string]string{"id": t.ID}}
+			if err := j.c.do(ctx, http.MethodPost, j.issuePath(key)+"/transitions", body, nil); err != nil {
+				return "", err
+			}
+			return t.To.Name, nil
+		}
+		names = append(names, t.To.Name)
+	}
+	return "", fmt.Errorf("%s cannot move to %q; available: %s", key, state, strings.Join(names, ", "))
+}
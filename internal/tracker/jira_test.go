@@ -0,0 +1,79 @@
+package tracker
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gavinyap/stormtrooper/internal/config"
+)
+
+func TestJira(t *testing.T) {
+	responses := map[string]string{
+		"GET /rest/api/2/issue/PROJ-7?fields=summary,description,status,comment": `{"key":"PROJ-7","fields":{
+			"summary":"Export CSV","description":"AC: header row","status":{"name":"To Do"},
+			"comment":{"comments":[{"body":"Use RFC 4180","author":{"displayName":"Ana"}}]}}}`,
+		"POST /rest/api/2/issue/PROJ-7/comment":     `{}`,
+		"GET /rest/api/2/issue/PROJ-7/transitions":  `{"transitions":[{"id":"21","name":"Start","to":{"name":"In Progress"}},{"id":"31","name":"Finish","to":{"name":"Done"}}]}`,
+		"POST /rest/api/2/issue/PROJ-7/transitions": ``,
+	}
+	srv, reqs := fakeServer(t, func(r recordedRequest) (int, string) {
+		if body, ok := responses[r.Method+" "+r.Path]; ok {
+			return http.StatusOK, body
+		}
+		return http.StatusNotFound, `{"errorMessages":["Issue does not exist"]}`
+	})
+	tr, err := New(config.TrackerConfig{Type: Jira, URL: srv.URL + "/", Email: "me@acme.com", Token: "tok"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	issue, err := tr.Issue(ctx, "PROJ-7")
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	if issue.Title != "Export CSV" || issue.State != "To Do" || issue.Body != "AC: header row" || issue.URL != srv.URL+"/browse/PROJ-7" {
+		t.Errorf("unexpected issue %+v", issue)
+	}
+	if len(issue.Comments) != 1 || issue.Comments[0].Author != "Ana" {
+		t.Errorf("comments = %+v", issue.Comments)
+	}
+	if user, _, ok := (&http.Request{Header: (*reqs)[0].Header}).BasicAuth(); !ok || user != "me@acme.com" {
+		t.Error("expected basic auth with the account email")
+	}
+
+	if err := tr.Comment(ctx, "PROJ-7", "Landed in #12"); err != nil {
+		t.Fatalf("Comment: %v", err)
+	}
+	if (*reqs)[1].Body["body"] != "Landed in #12" {
+		t.Errorf("comment body = %+v", (*reqs)[1].Body)
+	}
+
+	state, err := tr.Transition(ctx, "PROJ-7", "done")
+	if err != nil || state != "Done" {
+		t.Fatalf("Transition = %q, %v", state, err)
+	}
+	if tid := (*reqs)[3].Body["transition"].(map[string]any)["id"]; tid != "31" {
+		t.Errorf("transition id = %v", tid)
+	}
+
+	_, err = tr.Transition(ctx, "PROJ-7", "Archived")
+	if err == nil || !strings.Contains(err.Error(), "In Progress, Done") {
+		t.Errorf("expected available states in error, got %v", err)
+	}
+
+	if _, err := tr.Issue(ctx, "NOPE-1"); err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Errorf("expected API error, got %v", err)
+	}
+}
+
+func TestJira_BearerWithoutEmail(t *testing.T) {
+	srv, reqs := fakeServer(t, func(r recordedRequest) (int, string) { return http.StatusOK, `{}` })
+	tr, _ := New(config.TrackerConfig{Type: Jira, URL: srv.URL, Token: "pat"})
+	tr.Comment(context.Background(), "X-1", "hi")
+	if got := (*reqs)[0].Header.Get("Authorization"); got != "Bearer pat" {
+		t.Errorf("Authorization = %q", got)
+	}
+}
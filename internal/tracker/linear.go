@@ -0,0 +1,128 @@
+package tracker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// linear talks to the Linear GraphQL API.
+type linear struct {
+	c *client
+}
+
+func (l *linear) Kind() string { return Linear }
+
+// query runs a GraphQL operation and decodes its data into out.
+func (l *linear) query(ctx context.Context, query string, vars map[string]any, out any) error {
+	var resp struct {
+		Data   any `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	resp.Data = out
+	body := map[string]any{"query": query, "variables": vars}
+	if err := l.c.do(ctx, http.MethodPost, "/graphql", body, &resp); err != nil {
+		return err
+	}
+	if len(resp.Errors) > 0 {
+		return errors.New(resp.Errors[0].Message)
+	}
+	return nil
+}
+
+type linearIssue struct {
+	ID          string `json:"id"`
+	Identifier  string `json:"identifier"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	URL         string `json:"url"`
+	State       struct {
+		Name string `json:"name"`
+	} `json:"state"`
+	Comments struct {
+		Nodes []struct {
+			Body string `json:"body"`
+			User struct {
+				Name string `json:"name"`
+			} `json:"user"`
+		} `json:"nodes"`
+	} `json:"comments"`
+	Team struct {
+		States struct {
+			Nodes []struct {
+				ID   string `json:"id"`
+				Name string `json:"name"`
+			} `json:"nodes"`
+		} `json:"states"`
+	} `json:"team"`
+}
+
+const linearIssueQuery = `query($id: String!) {
+  issue(id: $id) {
+    id identifier title description url
+    state { name }
+    comments { nodes { body user { name } } }
+    team { states { nodes { id name } } }
+  }
+}`
+
+// issue looks up an issue by identifier (e.g. "ENG-42").
+func (l *linear) issue(ctx context.Context, key string) (*linearIssue, error) {
+	var data struct {
+		Issue *linearIssue `json:"issue"`
+	}
+	if err := l.query(ctx, linearIssueQuery, map[string]any{"id": key}, &data); err != nil {
+		return nil, err
+	}
+	if data.Issue == nil {
+		return nil, fmt.Errorf("issue %s not found", key)
+	}
+	return data.Issue, nil
+}
+
+func (l *linear) Issue(ctx context.Context, key string) (*Issue, error) {
+	issue, err := l.issue(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	result := &Issue{Key: issue.Identifier, Title: issue.Title, State: issue.State.Name, Body: issue.Description, URL: issue.URL}
+	for _, c := range issue.Comments.Nodes {
+		result.Comments = append(result.Comments, Comment{Author: c.User.Name, Body: c.Body})
+	}
+	return result, nil
+}
+
+func (l *linear) Comment(ctx context.Context, key, body string) error {
+	issue, err := l.issue(ctx, key)
+	if err != nil {
+		return err
+	}
+	return l.query(ctx, `mutation($input: CommentCreateInput!) { commentCreate(input: $input) { success } }`,
+		map[string]any{"input": map[string]string{"issueId": issue.ID, "body": body}}, nil)
+}
+
+// Transition moves the issue to the team workflow state with the given
+// name, case-insensitively.
+func (l *linear) Transition(ctx context.Context, key, state string) (string, error) {
+	issue, err := l.issue(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	var names []string
+	for _, s := range issue.Team.States.Nodes {
+		if strings.EqualFold(s.Name, state) {
+			err := l.query(ctx, `mutation($id: String!, $input: IssueUpdateInput!) { issueUpdate(id: $id, input: $input) { success } }`,
+				map[string]any{"id": issue.ID, "input": map[string]string{"stateId": s.ID}}, nil)
+			if err != nil {
+				return "", err
+			}
+			return s.Name, nil
+		}
+		names = append(names, s.Name)
+	}
+	return "", fmt.Errorf("%s has no state %q; available: %s", key, state, strings.Join(names, ", "))
+}
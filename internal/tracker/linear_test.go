@@ -0,0 +1,72 @@
+package tracker
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gavinyap/stormtrooper/internal/config"
+)
+
+const linearIssueResponse = `{"data":{"issue":{
+	"id":"uuid-42","identifier":"ENG-42","title":"Rate limit","description":"Must return 429","url":"https://linear.app/acme/issue/ENG-42",
+	"state":{"name":"Todo"},
+	"comments":{"nodes":[{"body":"Per API key","user":{"name":"Kim"}}]},
+	"team":{"states":{"nodes":[{"id":"s1","name":"Todo"},{"id":"s2","name":"In Review"}]}}}}}`
+
+func TestLinear(t *testing.T) {
+	srv, reqs := fakeServer(t, func(r recordedRequest) (int, string) {
+		query, _ := r.Body["query"].(string)
+		switch {
+		case strings.Contains(query, "issue(id:"):
+			if r.Body["variables"].(map[string]any)["id"] != "ENG-42" {
+				return http.StatusOK, `{"data":{"issue":null},"errors":[{"message":"Entity not found"}]}`
+			}
+			return http.StatusOK, linearIssueResponse
+		case strings.Contains(query, "commentCreate"), strings.Contains(query, "issueUpdate"):
+			return http.StatusOK, `{"data":{"x":{"success":true}}}`
+		}
+		return http.StatusBadRequest, `{}`
+	})
+	tr, err := New(config.TrackerConfig{Type: Linear, URL: srv.URL, Token: "lin_api_key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	issue, err := tr.Issue(ctx, "ENG-42")
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	if issue.Key != "ENG-42" || issue.Body != "Must return 429" || issue.State != "Todo" || len(issue.Comments) != 1 {
+		t.Errorf("unexpected issue %+v", issue)
+	}
+	if got := (*reqs)[0].Header.Get("Authorization"); got != "lin_api_key" {
+		t.Errorf("Authorization = %q", got)
+	}
+
+	if err := tr.Comment(ctx, "ENG-42", "Done in #5"); err != nil {
+		t.Fatalf("Comment: %v", err)
+	}
+	input := (*reqs)[2].Body["variables"].(map[string]any)["input"].(map[string]any)
+	if input["issueId"] != "uuid-42" || input["body"] != "Done in #5" {
+		t.Errorf("commentCreate input = %v", input)
+	}
+
+	state, err := tr.Transition(ctx, "ENG-42", "in review")
+	if err != nil || state != "In Review" {
+		t.Fatalf("Transition = %q, %v", state, err)
+	}
+	input = (*reqs)[4].Body["variables"].(map[string]any)["input"].(map[string]any)
+	if input["stateId"] != "s2" {
+		t.Errorf("issueUpdate input = %v", input)
+	}
+
+	if _, err := tr.Transition(ctx, "ENG-42", "Shipped"); err == nil || !strings.Contains(err.Error(), "Todo, In Review") {
+		t.Errorf("expected available states in error, got %v", err)
+	}
+	if _, err := tr.Issue(ctx, "ENG-1"); err == nil || !strings.Contains(err.Error(), "Entity not found") {
+		t.Errorf("expected GraphQL error, got %v", err)
+	}
+}
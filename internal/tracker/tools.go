@@ -0,0 +1,181 @@
+package tracker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/gavinyap/stormtrooper/internal/tool"
+)
+
+// Tools returns the tracker tools for t: issue_read, issue_comment, and
+// issue_transition.
+func Tools(t Tracker) []tool.Tool {
+	return []tool.Tool{&IssueReadTool{Tracker: t}, &IssueCommentTool{Tracker: t}, &IssueTransitionTool{Tracker: t}}
+}
+
+const keySchema = `"key": {
+			"type": "string",
+			"description": "Issue key, e.g. PROJ-123"
+		}`
+
+// IssueReadTool reads a ticket, including its description and comments.
+type IssueReadTool struct {
+	Tracker Tracker
+}
+
+type issueParams struct {
+	Key   string `json:"key"`
+	Body  string `json:"body"`
+	State string `json:"state"`
+}
+
+func (t *IssueReadTool) Name() string { return "issue_read" }
+func (t *IssueReadTool) Description() string {
+	return fmt.Sprintf("Read a %s issue: title, status, description (acceptance criteria), and comments", t.Tracker.Kind())
+}
+func (t *IssueReadTool) Permission() tool.PermissionLevel { return tool.PermissionAuto }
+func (t *IssueReadTool) Capabilities() []tool.Capability {
+	return []tool.Capability{tool.CapRead, tool.CapNetwork}
+}
+
+func (t *IssueReadTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+	"type": "object",
+	"properties": {
+		` + keySchema + `
+	},
+	"required": ["key"]
+}`)
+}
+
+func (t *IssueReadTool) Execute(ctx context.Context, params json.RawMessage) (string, error) {
+	var p issueParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return fmt.Sprintf("Error: invalid parameters: %v", err), nil
+	}
+	if p.Key == "" {
+		return "Error: key is required", nil
+	}
+	issue, err := t.Tracker.Issue(ctx, p.Key)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s [%s]\n%s\n\n%s\n", issue.Key, issue.Title, issue.State, issue.URL, issue.Body)
+	for _, c := range issue.Comments {
+		fmt.Fprintf(&b, "\n--- %s:\n%s\n", c.Author, c.Body)
+	}
+	return b.String(), nil
+}
+
+// IssueCommentTool adds a comment to a ticket.
+type IssueCommentTool struct {
+	Tracker Tracker
+}
+
+func (t *IssueCommentTool) Name() string { return "issue_comment" }
+func (t *IssueCommentTool) Description() string {
+	return fmt.Sprintf("Add a comment to a %s issue", t.Tracker.Kind())
+}
+func (t *IssueCommentTool) Permission() tool.PermissionLevel { return tool.PermissionPrompt }
+func (t *IssueCommentTool) Capabilities() []tool.Capability {
+	return []tool.Capability{tool.CapWrite, tool.CapNetwork}
+}
+
+func (t *IssueCommentTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+	"type": "object",
+	"properties": {
+		` + keySchema + `,
+		"body": {
+			"type": "string",
+			"description": "Comment text"
+		}
+	},
+	"required": ["key", "body"]
+}`)
+}
+
+// Preview returns a description for the permission prompt.
+func (t *IssueCommentTool) Preview(params json.RawMessage) string {
+	var p issueParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return "Comment on issue (invalid params)"
+	}
+	return fmt.Sprintf("Comment on %s:\n%s", p.Key, p.Body)
+}
+
+func (t *IssueCommentTool) Execute(ctx context.Context, params json.RawMessage) (string, error) {
+	var p issueParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return fmt.Sprintf("Error: invalid parameters: %v", err), nil
+	}
+	if p.Key == "" {
+		return "Error: key is required", nil
+	}
+	if strings.TrimSpace(p.Body) == "" {
+		return "Error: body is required", nil
+	}
+	if err := t.Tracker.Comment(ctx, p.Key, p.Body); err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+	return fmt.Sprintf("Comment added to %s", p.Key), nil
+}
+
+// IssueTransitionTool moves a ticket to another workflow state.
+type IssueTransitionTool struct {
+	Tracker Tracker
+}
+
+func (t *IssueTransitionTool) Name() string { return "issue_transition" }
+func (t *IssueTransitionTool) Description() string {
+	return fmt.Sprintf("Move a %s issue to another status, e.g. \"In Review\" or \"Done\"", t.Tracker.Kind())
+}
+func (t *IssueTransitionTool) Permission() tool.PermissionLevel { return tool.PermissionPrompt }
+func (t *IssueTransitionTool) Capabilities() []tool.Capability {
+	return []tool.Capability{tool.CapWrite, tool.CapNetwork}
+}
+
+func (t *IssueTransitionTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+	"type": "object",
+	"properties": {
+		` + keySchema + `,
+		"state": {
+			"type": "string",
+			"description": "Target status name; on error the available statuses are listed"
+		}
+	},
+	"required": ["key", "state"]
+}`)
+}
+
+// Preview returns a description for the permission prompt.
+func (t *IssueTransitionTool) Preview(params json.RawMessage) string {
+	var p issueParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return "Change issue status (invalid params)"
+	}
+	return fmt.Sprintf("Move %s to %q", p.Key, p.State)
+}
+
+func (t *IssueTransitionTool) Execute(ctx context.Context, params json.RawMessage) (string, error) {
+	var p issueParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return fmt.Sprintf("Error: invalid parameters: %v", err), nil
+	}
+	if p.Key == "" {
+		return "Error: key is required", nil
+	}
+	if p.State == "" {
+		return "Error: state is required", nil
+	}
+	state, err := t.Tracker.Transition(ctx, p.Key, p.State)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+	return fmt.Sprintf("%s moved to %s", p.Key, state), nil
+}
@@ -0,0 +1,108 @@
+package tracker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gavinyap/stormtrooper/internal/tool"
+)
+
+var (
+	_ tool.Previewer = (*IssueCommentTool)(nil)
+	_ tool.Previewer = (*IssueTransitionTool)(nil)
+)
+
+// fakeTracker records calls and returns canned data.
+type fakeTracker struct {
+	comments    []string
+	transitions []string
+	err         error
+}
+
+func (f *fakeTracker) Kind() string { return Jira }
+
+func (f *fakeTracker) Issue(ctx context.Context, key string) (*Issue, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &Issue{Key: key, Title: "Export CSV", State: "To Do", Body: "AC: header row", URL: "https://j/browse/" + key,
+		Comments: []Comment{{Author: "Ana", Body: "Use RFC 4180"}}}, nil
+}
+
+func (f *fakeTracker) Comment(ctx context.Context, key, body string) error {
+	f.comments = append(f.comments, body)
+	return f.err
+}
+
+func (f *fakeTracker) Transition(ctx context.Context, key, state string) (string, error) {
+	f.transitions = append(f.transitions, state)
+	return "Done", f.err
+}
+
+func TestTools(t *testing.T) {
+	tools := Tools(&fakeTracker{})
+	var names []string
+	for _, tl := range tools {
+		names = append(names, tl.Name())
+		var schema map[string]any
+		if err := json.Unmarshal(tl.Schema(), &schema); err != nil {
+			t.Errorf("%s: invalid schema: %v", tl.Name(), err)
+		}
+	}
+	if strings.Join(names, ",") != "issue_read,issue_comment,issue_transition" {
+		t.Errorf("tools = %v", names)
+	}
+	if tools[0].Permission() != tool.PermissionAuto || tools[1].Permission() != tool.PermissionPrompt || tools[2].Permission() != tool.PermissionPrompt {
+		t.Error("reading should be automatic and updates should prompt")
+	}
+}
+
+func TestIssueReadTool(t *testing.T) {
+	tl := &IssueReadTool{Tracker: &fakeTracker{}}
+	out, _ := tl.Execute(context.Background(), json.RawMessage(`{"key":"PROJ-7"}`))
+	for _, want := range []string{"PROJ-7 Export CSV [To Do]", "AC: header row", "--- Ana:\nUse RFC 4180"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	if out, _ := tl.Execute(context.Background(), json.RawMessage(`{}`)); out != "Error: key is required" {
+		t.Errorf("got %q", out)
+	}
+	tl.Tracker = &fakeTracker{err: errors.New("401 Unauthorized")}
+	if out, _ := tl.Execute(context.Background(), json.RawMessage(`{"key":"PROJ-7"}`)); out != "Error: 401 Unauthorized" {
+		t.Errorf("got %q", out)
+	}
+}
+
+func TestIssueCommentTool(t *testing.T) {
+	f := &fakeTracker{}
+	tl := &IssueCommentTool{Tracker: f}
+	params := json.RawMessage(`{"key":"PROJ-7","body":"Landed"}`)
+	if got := tl.Preview(params); got != "Comment on PROJ-7:\nLanded" {
+		t.Errorf("Preview = %q", got)
+	}
+	if out, _ := tl.Execute(context.Background(), params); out != "Comment added to PROJ-7" || len(f.comments) != 1 {
+		t.Errorf("Execute = %q", out)
+	}
+	if out, _ := tl.Execute(context.Background(), json.RawMessage(`{"key":"PROJ-7"}`)); out != "Error: body is required" {
+		t.Errorf("got %q", out)
+	}
+}
+
+func TestIssueTransitionTool(t *testing.T) {
+	f := &fakeTracker{}
+	tl := &IssueTransitionTool{Tracker: f}
+	params := json.RawMessage(`{"key":"PROJ-7","state":"done"}`)
+	if got := tl.Preview(params); got != `Move PROJ-7 to "done"` {
+		t.Errorf("Preview = %q", got)
+	}
+	if out, _ := tl.Execute(context.Background(), params); out != "PROJ-7 moved to Done" {
+		t.Errorf("Execute = %q", out)
+	}
+	if out, _ := tl.Execute(context.Background(), json.RawMessage(`{"key":"PROJ-7"}`)); out != "Error: state is required" {
+		t.Errorf("got %q", out)
+	}
+}
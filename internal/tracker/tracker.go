@@ -0,0 +1,148 @@
+// Package tracker connects the agent to an issue tracker (Jira or Linear)
+// so it can read acceptance criteria from a ticket and update the ticket
+// when a change lands.
+package tracker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gavinyap/stormtrooper/internal/config"
+)
+
+// Supported tracker types.
+const (
+	Jira   = "jira"
+	Linear = "linear"
+)
+
+// Issue is a ticket with its discussion.
+type Issue struct {
+	Key      string // e.g. "PROJ-123" or "ENG-42"
+	Title    string
+	State    string
+	Body     string
+	URL      string
+	Comments []Comment
+}
+
+// Comment is one message on a ticket.
+type Comment struct {
+	Author string
+	Body   string
+}
+
+// Tracker is an issue tracker API.
+type Tracker interface {
+	// Kind returns Jira or Linear.
+	Kind() string
+	Issue(ctx context.Context, key string) (*Issue, error)
+	Comment(ctx context.Context, key, body string) error
+	// Transition moves the issue to the named state (or Jira transition)
+	// and returns the resulting state name.
+	Transition(ctx context.Context, key, state string) (string, error)
+}
+
+// New creates the Tracker described by cfg. The token defaults to
+// $JIRA_API_TOKEN or $LINEAR_API_KEY.
+func New(cfg config.TrackerConfig) (Tracker, error) {
+	c := &client{http: &http.Client{Timeout: 30 * time.Second}}
+	switch cfg.Type {
+	case Jira:
+		token := cfg.Token
+		if token == "" {
+			token = os.Getenv("JIRA_API_TOKEN")
+		}
+		if cfg.URL == "" {
+			return nil, errors.New("tracker: jira requires url")
+		}
+		if token == "" {
+			return nil, errors.New("tracker: jira requires token or JIRA_API_TOKEN")
+		}
+		c.baseURL = strings.TrimSuffix(cfg.URL, "/")
+		c.auth = func(r *http.Request) {
+			// Jira Cloud uses the account email with an API token;
+			// Server and Data Center use a bearer personal access token.
+			if cfg.Email != "" {
+				r.SetBasicAuth(cfg.Email, token)
+			} else {
+				r.Header.Set("Authorization", "Bearer "+token)
+			}
+		}
+		return &jira{c: c}, nil
+
+	case Linear:
+		token := cfg.Token
+		if token == "" {
+			token = os.Getenv("LINEAR_API_KEY")
+		}
+		if token == "" {
+			return nil, errors.New("tracker: linear requires token or LINEAR_API_KEY")
+		}
+		c.baseURL = "https://api.linear.app"
+		if cfg.URL != "" {
+			c.baseURL = strings.TrimSuffix(cfg.URL, "/")
+		}
+		c.auth = func(r *http.Request) { r.Header.Set("Authorization", token) }
+		return &linear{c: c}, nil
+	}
+	return nil, fmt.Errorf("tracker: unsupported type %q (use jira or linear)", cfg.Type)
+}
+
+// client is a small JSON-over-HTTP helper shared by the trackers.
+type client struct {
+	http    *http.Client
+	baseURL string
+	auth    func(*http.Request)
+}
+
+// do sends a request with an optional JSON body and decodes the JSON
+// response into out (when non-nil).
+func (c *client) do(ctx context.Context, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	c.auth(req)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg := strings.TrimSpace(string(data))
+		if len(msg) > 300 {
+			msg = msg[:300] + "..."
+		}
+		return fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, msg)
+	}
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
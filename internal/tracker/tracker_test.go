@@ -0,0 +1,64 @@
+package tracker
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gavinyap/stormtrooper/internal/config"
+)
+
+func TestNew_Validation(t *testing.T) {
+	t.Setenv("JIRA_API_TOKEN", "")
+	t.Setenv("LINEAR_API_KEY", "")
+	tests := []struct {
+		name string
+		cfg  config.TrackerConfig
+		want string
+	}{
+		{"unknown type", config.TrackerConfig{Type: "trello"}, "unsupported type"},
+		{"jira without url", config.TrackerConfig{Type: Jira, Token: "t"}, "requires url"},
+		{"jira without token", config.TrackerConfig{Type: Jira, URL: "https://x"}, "JIRA_API_TOKEN"},
+		{"linear without token", config.TrackerConfig{Type: Linear}, "LINEAR_API_KEY"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.cfg); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+
+	t.Setenv("LINEAR_API_KEY", "lin_api")
+	if tr, err := New(config.TrackerConfig{Type: Linear}); err != nil || tr.Kind() != Linear {
+		t.Errorf("expected Linear tracker from env token, got %v, %v", tr, err)
+	}
+}
+
+// recordedRequest is what the fake tracker server saw.
+type recordedRequest struct {
+	Method string
+	Path   string
+	Header http.Header
+	Body   map[string]any
+}
+
+// fakeServer answers with handler and records every request.
+func fakeServer(t *testing.T, handler func(r recordedRequest) (int, string)) (*httptest.Server, *[]recordedRequest) {
+	t.Helper()
+	var reqs []recordedRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := recordedRequest{Method: r.Method, Path: r.URL.RequestURI(), Header: r.Header}
+		data, _ := io.ReadAll(r.Body)
+		json.Unmarshal(data, &rec.Body)
+		reqs = append(reqs, rec)
+		status, body := handler(rec)
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &reqs
+}
@@ -0,0 +1,118 @@
+// Package trust records which workspaces the user has agreed to trust.
+// An untrusted workspace is opened without its project instructions,
+// memory, or project config, and without file-modifying tools, so that
+// a freshly cloned repository cannot inject instructions into the agent.
+package trust
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const trustFile = "trusted.json"
+
+// Decision is a remembered answer for a single workspace directory.
+type Decision struct {
+	Trusted   bool      `json:"trusted"`
+	DecidedAt time.Time `json:"decided_at"`
+}
+
+// Store holds trust decisions keyed by absolute workspace path.
+type Store struct {
+	path       string
+	Workspaces map[string]Decision `json:"workspaces"`
+}
+
+// DefaultPath returns ~/.stormtrooper/trusted.json.
+func DefaultPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".stormtrooper", trustFile), nil
+}
+
+// Load reads the trust store at path. A missing file yields an empty store.
+func Load(path string) (*Store, error) {
+	s := &Store{path: path, Workspaces: make(map[string]Decision)}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return s, nil
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("invalid trust file %s: %w", path, err)
+	}
+	if s.Workspaces == nil {
+		s.Workspaces = make(map[string]Decision)
+	}
+	return s, nil
+}
+
+// Lookup reports whether dir is trusted and whether a decision is known.
+// A trusted ancestor directory also trusts everything beneath it; an
+// explicit decision for dir itself always wins.
+func (s *Store) Lookup(dir string) (trusted bool, known bool) {
+	dir = filepath.Clean(dir)
+	if d, ok := s.Workspaces[dir]; ok {
+		return d.Trusted, true
+	}
+	for parent := filepath.Dir(dir); parent != dir; parent, dir = filepath.Dir(parent), parent {
+		if d, ok := s.Workspaces[parent]; ok && d.Trusted {
+			return true, true
+		}
+	}
+	return false, false
+}
+
+// Set records a decision for dir. Call Save to persist it.
+func (s *Store) Set(dir string, trusted bool) {
+	s.Workspaces[filepath.Clean(dir)] = Decision{
+		Trusted:   trusted,
+		DecidedAt: time.Now().UTC(),
+	}
+}
+
+// Save writes the store back to disk, creating the parent directory.
+func (s *Store) Save() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, data, 0600)
+}
+
+// Ask prompts the user whether to trust dir and returns their answer.
+// Anything other than an explicit yes is treated as no. If the input
+// closes before an answer is given, Ask returns false and io.EOF so the
+// caller can avoid remembering a decision the user never made.
+func Ask(in io.Reader, out io.Writer, dir string) (bool, error) {
+	fmt.Fprintf(out, "\nDo you trust this workspace?\n  %s\n", dir)
+	fmt.Fprintln(out, "Trusting it loads STORMTROOPER.md, project memory and project config, and enables file-modifying tools.")
+	fmt.Fprint(out, "[y/n]: ")
+
+	scanner := bufio.NewScanner(in)
+	if !scanner.Scan() {
+		fmt.Fprintln(out)
+		if err := scanner.Err(); err != nil {
+			return false, err
+		}
+		return false, io.EOF
+	}
+	line := strings.TrimSpace(scanner.Text())
+	return len(line) > 0 && (line[0] == 'y' || line[0] == 'Y'), nil
+}
@@ -0,0 +1,102 @@
+package trust
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoad_MissingFile(t *testing.T) {
+	s, err := Load(filepath.Join(t.TempDir(), "trusted.json"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, known := s.Lookup("/some/dir"); known {
+		t.Error("empty store should not know any workspace")
+	}
+}
+
+func TestLoad_InvalidJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trusted.json")
+	os.WriteFile(path, []byte("{not json"), 0644)
+
+	if _, err := Load(path); err == nil {
+		t.Fatal("expected error for invalid JSON")
+	}
+}
+
+func TestSaveAndReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "trusted.json")
+	s, _ := Load(path)
+	s.Set("/work/good", true)
+	s.Set("/work/bad", false)
+	if err := s.Save(); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	reloaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if trusted, known := reloaded.Lookup("/work/good"); !trusted || !known {
+		t.Errorf("expected /work/good trusted, got trusted=%v known=%v", trusted, known)
+	}
+	if trusted, known := reloaded.Lookup("/work/bad"); trusted || !known {
+		t.Errorf("expected /work/bad untrusted, got trusted=%v known=%v", trusted, known)
+	}
+}
+
+func TestLookup_TrustedAncestor(t *testing.T) {
+	s, _ := Load(filepath.Join(t.TempDir(), "trusted.json"))
+	s.Set("/work", true)
+
+	if trusted, known := s.Lookup("/work/project/sub"); !trusted || !known {
+		t.Errorf("expected child of trusted dir to be trusted, got trusted=%v known=%v", trusted, known)
+	}
+	if _, known := s.Lookup("/other"); known {
+		t.Error("unrelated dir should be unknown")
+	}
+}
+
+func TestLookup_ExplicitDenyBeatsAncestor(t *testing.T) {
+	s, _ := Load(filepath.Join(t.TempDir(), "trusted.json"))
+	s.Set("/work", true)
+	s.Set("/work/cloned", false)
+
+	if trusted, _ := s.Lookup("/work/cloned"); trusted {
+		t.Error("explicit untrusted decision should win over trusted ancestor")
+	}
+}
+
+func TestAsk(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    bool
+		wantEOF bool
+	}{
+		{"y\n", true, false},
+		{"yes\n", true, false},
+		{"n\n", false, false},
+		{"\n", false, false},
+		{"", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			got, err := Ask(strings.NewReader(tt.input), out, "/work/repo")
+			if got != tt.want {
+				t.Errorf("Ask(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+			if (err == io.EOF) != tt.wantEOF {
+				t.Errorf("Ask(%q) err = %v, wantEOF %v", tt.input, err, tt.wantEOF)
+			}
+			if !strings.Contains(out.String(), "/work/repo") {
+				t.Error("prompt should mention the workspace path")
+			}
+		})
+	}
+}
@@ -2,15 +2,22 @@ package tui
 
 import (
 	gocontext "context"
-	"fmt"
+	"errors"
+	"io"
 	"path/filepath"
+	"strings"
 
 	"github.com/charmbracelet/bubbles/key"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/gavinyap/stormtrooper/internal/agent"
+	"github.com/gavinyap/stormtrooper/internal/command"
 	"github.com/gavinyap/stormtrooper/internal/config"
 	projectctx "github.com/gavinyap/stormtrooper/internal/context"
+	"github.com/gavinyap/stormtrooper/internal/editor"
+	"github.com/gavinyap/stormtrooper/internal/i18n"
+	"github.com/gavinyap/stormtrooper/internal/permission"
+	"github.com/gavinyap/stormtrooper/internal/staging"
 )
 
 // FocusArea identifies which panel has keyboard focus.
@@ -36,14 +43,37 @@ type App struct {
 	height int
 	focus  FocusArea
 
+	// Config currently in effect; replaced on hot reload.
+	config *config.Config
+
 	// Agent integration
 	bridge    *Bridge
 	agent     *agent.Agent
 	agentBusy bool
+	cancel    gocontext.CancelFunc // stops the running turn; nil when none
+	commands  *command.Dispatcher
+	pins      *agent.Pins
+	editor    *editor.Editor
+
+	// Staged file changes and the screen that reviews them
+	staging   *staging.Overlay
+	review    ReviewModel
+	reviewing bool
+
+	// Argument form opened by a command such as /run-tool
+	form    FormModel
+	filling bool
+
+	// Choices offered by a command such as /resume
+	picker  PickerModel
+	picking bool
 
 	// Permission state
 	permReq *PermissionRequestMsg
 
+	// Part of an earlier answer to quote in the next message
+	reply *reply
+
 	// Sidebar visibility
 	sidebarVisible bool
 
@@ -58,6 +88,12 @@ type Options struct {
 	Config     *config.Config
 	ProjectCtx *projectctx.ProjectContext
 	Version    string
+	Restricted bool // workspace is untrusted
+	Commands   *command.Dispatcher
+	Links      *Linker          // hyperlinks file paths in tool messages; nil disables
+	Editor     *editor.Editor   // opens files from tool messages; nil disables
+	Staging    *staging.Overlay // holds file changes for review; nil writes directly
+	Pins       *agent.Pins      // files shown in the sidebar as pinned; nil hides them
 }
 
 // New creates a new App, wiring the agent to the bridge and constructing
@@ -68,8 +104,11 @@ func New(opts Options) *App {
 	bridge := NewBridge()
 
 	// Wire the agent's output and permission handler through the bridge.
-	opts.Agent.SetOutput(bridge.Stdout(), bridge.Stderr())
+	// Its events come through the bridge, so nothing is written as text.
+	opts.Agent.SetOutput(io.Discard, io.Discard)
+	opts.Agent.OnEvent(bridge.Handle)
 	opts.Agent.SetPermission(bridge.Permission())
+	opts.Agent.OnToolResult(bridge.ToolOutput)
 
 	// Derive sidebar options from project context and config.
 	projectDir := ""
@@ -79,29 +118,45 @@ func New(opts Options) *App {
 		memoryLoaded = opts.ProjectCtx.Memory != ""
 	}
 
-	modelName := ""
-	if opts.Config != nil {
-		modelName = opts.Config.Model
+	cfg := opts.Config
+	if cfg == nil {
+		cfg = &config.Config{}
 	}
+	modelName := cfg.Model
 
 	cwd := ""
 	if opts.ProjectCtx != nil {
 		cwd = opts.ProjectCtx.WorkingDir
 	}
 
+	chat := NewChatModel(&theme)
+	chat.linker = opts.Links
+	// A resumed session starts with its conversation on screen.
+	chat.SetHistory(opts.Agent.History())
+	if err := chat.SetMarkdownOptions(cfg.Markdown.Style, cfg.Markdown.WordWrap); err != nil {
+		chat.AddSystemMessage(i18n.T("chat.style_failed", err))
+	}
+
 	return &App{
-		chat:  NewChatModel(&theme),
+		chat:  chat,
 		input: NewInputModel(&theme, &keymap),
 		sidebar: NewSidebarModel(&theme, SidebarOptions{
 			ProjectDir:   projectDir,
 			MemoryLoaded: memoryLoaded,
 			ToolCount:    0,
 			ModelName:    modelName,
+			Restricted:   opts.Restricted,
 		}),
 		statusbar: NewStatusBarModel(&theme, opts.Version, modelName, cwd),
 		focus:          FocusInput,
+		config:         cfg,
 		bridge:         bridge,
 		agent:          opts.Agent,
+		commands:       opts.Commands,
+		editor:         opts.Editor,
+		staging:        opts.Staging,
+		pins:           opts.Pins,
+		review:         NewReviewModel(&theme),
 		sidebarVisible: true,
 		theme:          theme,
 		keymap:         keymap,
@@ -133,6 +188,15 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if a.permReq != nil {
 			return a.handlePermissionKey(msg)
 		}
+		if a.reviewing {
+			return a.handleReviewKey(msg)
+		}
+		if a.filling {
+			return a.handleFormKey(msg)
+		}
+		if a.picking {
+			return a.handlePickerKey(msg)
+		}
 
 		// Global keys.
 		switch {
@@ -140,14 +204,27 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return a, tea.Quit
 
 		case key.Matches(msg, a.keymap.Tab):
+			if a.focus == FocusInput && a.completeCommand() {
+				return a, nil
+			}
 			a.toggleFocus()
 			return a, nil
 
+		case key.Matches(msg, a.keymap.Review) && a.staging != nil:
+			a.openReview()
+			return a, nil
+
 		case key.Matches(msg, a.keymap.ToggleSidebar):
 			a.sidebarVisible = !a.sidebarVisible
 			a.recalcLayout()
 			return a, nil
 
+		// The input comes back with AgentDoneMsg, once the agent stops
+		// streaming or the running tool returns.
+		case key.Matches(msg, a.keymap.Stop) && a.cancel != nil:
+			a.cancel()
+			return a, nil
+
 		case key.Matches(msg, a.keymap.FocusChat):
 			if a.focus == FocusInput {
 				a.setFocus(FocusChat)
@@ -157,6 +234,50 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return a, nil
 		}
 
+		// Message selection in the chat.
+		if a.focus == FocusChat {
+			switch {
+			case key.Matches(msg, a.keymap.PrevMessage):
+				a.chat.SelectPrev()
+				return a, nil
+			case key.Matches(msg, a.keymap.NextMessage):
+				a.chat.SelectNext()
+				return a, nil
+			case key.Matches(msg, a.keymap.ToggleRaw):
+				a.chat.ToggleRaw()
+				return a, nil
+			case key.Matches(msg, a.keymap.ScrollLeft):
+				a.chat.ScrollLeft()
+				return a, nil
+			case key.Matches(msg, a.keymap.ScrollRight):
+				a.chat.ScrollRight()
+				return a, nil
+			case key.Matches(msg, a.keymap.OpenEditor):
+				return a, a.openInEditor(1)
+			case key.Matches(msg, a.keymap.OpenLink):
+				n := int(msg.String()[0] - '0')
+				if a.chat.SelectedTool() {
+					return a, a.openInEditor(n)
+				}
+				url, ok := a.chat.Link(n)
+				if !ok {
+					a.chat.AddSystemMessage(i18n.T("chat.no_link", n))
+					return a, nil
+				}
+				return a, openLink(url)
+			case key.Matches(msg, a.keymap.Reply):
+				a.cycleReply()
+				return a, nil
+			case key.Matches(msg, a.keymap.ShowRule):
+				rule, ok := a.chat.Rule()
+				if !ok {
+					rule = i18n.T("chat.no_rule")
+				}
+				a.chat.AddSystemMessage(rule)
+				return a, nil
+			}
+		}
+
 		// Forward to focused sub-model.
 		if a.focus == FocusInput {
 			var cmd tea.Cmd
@@ -170,12 +291,37 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return a, tea.Batch(cmds...)
 
 	case SendMsg:
-		a.chat.AddUserMessage(msg.Text)
+		if a.commands != nil && a.commands.Long(msg.Text) {
+			a.agentBusy = true
+			a.input.SetDisabled(true)
+			a.sidebar.SetAgentBusy(true)
+			ctx, cancel := gocontext.WithCancel(gocontext.Background())
+			a.cancel = cancel
+			return a, tea.Batch(
+				a.runCommand(ctx, msg.Text),
+				a.input.Init(),
+				a.sidebar.Init(),
+			)
+		}
+		if a.commands != nil {
+			res, handled, err := a.commands.Dispatch(gocontext.Background(), msg.Text)
+			if handled {
+				return a, a.commandResult(res, err)
+			}
+		}
+		text := msg.Text
+		if a.reply != nil {
+			text = quoteReply(a.reply.parts[a.reply.part], text)
+			a.reply = nil
+		}
+		a.chat.AddUserMessage(text)
 		a.agentBusy = true
 		a.input.SetDisabled(true)
 		a.sidebar.SetAgentBusy(true)
+		ctx, cancel := gocontext.WithCancel(gocontext.Background())
+		a.cancel = cancel
 		return a, tea.Batch(
-			a.runAgent(msg.Text),
+			a.runAgent(ctx, text),
 			a.input.Init(), // restart spinner
 			a.sidebar.Init(),
 		)
@@ -187,12 +333,26 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return a, tea.Batch(cmds...)
 
 	case ToolStartMsg:
+		// A tool call ends a model response, so its usage is in.
+		a.sidebar.SetUsage(a.agent.Usage())
 		var chatCmd, sidebarCmd tea.Cmd
 		a.chat, chatCmd = a.chat.Update(msg)
 		a.sidebar, sidebarCmd = a.sidebar.Update(msg)
 		cmds = append(cmds, chatCmd, sidebarCmd, WaitForEvent(a.bridge.Events()))
 		return a, tea.Batch(cmds...)
 
+	case AutoDecisionMsg:
+		var cmd tea.Cmd
+		a.chat, cmd = a.chat.Update(msg)
+		cmds = append(cmds, cmd, WaitForEvent(a.bridge.Events()))
+		return a, tea.Batch(cmds...)
+
+	case ToolOutputMsg:
+		var cmd tea.Cmd
+		a.chat, cmd = a.chat.Update(msg)
+		cmds = append(cmds, cmd, WaitForEvent(a.bridge.Events()))
+		return a, tea.Batch(cmds...)
+
 	case ToolResultMsg:
 		var chatCmd, sidebarCmd tea.Cmd
 		a.chat, chatCmd = a.chat.Update(msg)
@@ -201,6 +361,9 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return a, tea.Batch(cmds...)
 
 	case PermissionRequestMsg:
+		if msg.expired() {
+			return a, WaitForEvent(a.bridge.Events())
+		}
 		a.permReq = &msg
 		var cmd tea.Cmd
 		a.chat, cmd = a.chat.Update(msg)
@@ -211,10 +374,20 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		a.agentBusy = false
 		a.input.SetDisabled(false)
 		a.sidebar.SetAgentBusy(false)
+		a.sidebar.SetUsage(a.agent.Usage())
 		a.setFocus(FocusInput)
+		if a.cancel != nil {
+			a.cancel()
+			a.cancel = nil
+		}
 
-		if msg.Error != nil {
-			a.chat.AddSystemMessage(fmt.Sprintf("Error: %v", msg.Error))
+		if errors.Is(msg.Error, gocontext.Canceled) {
+			a.chat.AddSystemMessage(i18n.T("cancelled"))
+		} else if msg.Error != nil {
+			a.chat.AddSystemMessage(i18n.T("error", msg.Error))
+		}
+		if a.staging != nil && a.staging.Len() > 0 {
+			a.chat.AddSystemMessage(i18n.T("review.pending", a.staging.Len()))
 		}
 
 		var chatCmd, sidebarCmd tea.Cmd
@@ -232,6 +405,90 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case SubAgentDoneMsg:
 		cmds = append(cmds, WaitForEvent(a.bridge.Events()))
 		return a, tea.Batch(cmds...)
+
+	case SubAgentProgressMsg:
+		var cmd tea.Cmd
+		a.sidebar, cmd = a.sidebar.Update(msg)
+		cmds = append(cmds, cmd, WaitForEvent(a.bridge.Events()))
+		return a, tea.Batch(cmds...)
+
+	case WarningMsg:
+		a.chat.AddSystemMessage(i18n.T("warning", msg.Text))
+		cmds = append(cmds, WaitForEvent(a.bridge.Events()))
+		return a, tea.Batch(cmds...)
+
+	case CompactMsg:
+		a.chat.AddSystemMessage(msg.Text)
+		cmds = append(cmds, WaitForEvent(a.bridge.Events()))
+		return a, tea.Batch(cmds...)
+
+	case ModelMsg:
+		var cmd tea.Cmd
+		a.chat, cmd = a.chat.Update(msg)
+		cmds = append(cmds, cmd, WaitForEvent(a.bridge.Events()))
+		return a, tea.Batch(cmds...)
+
+	case CommandDoneMsg:
+		a.agentBusy = false
+		a.input.SetDisabled(false)
+		a.sidebar.SetAgentBusy(false)
+		if a.cancel != nil {
+			a.cancel()
+			a.cancel = nil
+		}
+		if errors.Is(msg.Err, gocontext.Canceled) {
+			a.chat.AddSystemMessage(i18n.T("cancelled"))
+			return a, nil
+		}
+		return a, a.commandResult(msg.Result, msg.Err)
+
+	case FormDoneMsg:
+		a.agentBusy = false
+		a.input.SetDisabled(false)
+		if msg.Err != nil {
+			a.chat.AddSystemMessage(i18n.T("error", msg.Err))
+		} else {
+			a.chat.AddSystemMessage(msg.Output)
+		}
+		return a, nil
+
+	case PickerDoneMsg:
+		a.agentBusy = false
+		a.input.SetDisabled(false)
+		if msg.Err != nil {
+			a.chat.AddSystemMessage(i18n.T("error", msg.Err))
+			return a, nil
+		}
+		if msg.Reload {
+			a.chat.SetHistory(a.agent.History())
+		}
+		if msg.Output != "" {
+			a.chat.AddSystemMessage(msg.Output)
+		}
+		// The choice may have switched the model.
+		a.statusbar.SetModel(a.agent.Model())
+		a.sidebar.SetModelName(a.agent.Model())
+		return a, nil
+
+	case ExecDoneMsg:
+		if msg.Err != nil {
+			a.chat.AddSystemMessage(i18n.T("chat.editor_failed", msg.Err))
+		}
+		return a, nil
+
+	case LinkOpenedMsg:
+		if msg.Err != nil {
+			a.chat.AddSystemMessage(i18n.T("chat.link_failed", msg.URL, msg.Err))
+		}
+		return a, nil
+
+	case ConfigReloadMsg:
+		a.applyConfig(msg)
+		return a, nil
+
+	case NoticeMsg:
+		a.chat.AddSystemMessage(msg.Text)
+		return a, nil
 	}
 
 	// Forward spinner ticks and other messages to sub-models that need them.
@@ -247,7 +504,13 @@ func (a *App) View() string {
 	statusBar := a.statusbar.View()
 	chatView := a.chat.View()
 	var mainArea string
-	if a.sidebarVisible {
+	if a.reviewing {
+		mainArea = a.review.View()
+	} else if a.filling {
+		mainArea = a.form.View()
+	} else if a.picking {
+		mainArea = a.picker.View()
+	} else if a.sidebarVisible {
 		sidebarView := a.sidebar.View()
 		mainArea = lipgloss.JoinHorizontal(lipgloss.Top, chatView, sidebarView)
 	} else {
@@ -264,30 +527,206 @@ func (a *App) Bridge() *Bridge {
 }
 
 // handlePermissionKey processes y/n keys during a permission prompt.
+// Stopping the agent denies the pending call.
 func (a *App) handlePermissionKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch {
 	case key.Matches(msg, a.keymap.PermAllow):
-		a.permReq.Response <- true
-		a.permReq = nil
-		var cmd tea.Cmd
-		a.chat, cmd = a.chat.Update(PermissionResponseMsg{Allowed: true})
-		return a, cmd
+		return a.answerPermission(permission.AllowOnce)
 
 	case key.Matches(msg, a.keymap.PermDeny):
-		a.permReq.Response <- false
-		a.permReq = nil
+		return a.answerPermission(permission.Deny)
+
+	case key.Matches(msg, a.keymap.PermAlways) && a.permReq.Decision != nil:
+		return a.answerPermission(permission.AllowTool)
+
+	case key.Matches(msg, a.keymap.PermCommand) && a.permReq.Decision != nil && a.permReq.Command != "":
+		return a.answerPermission(permission.AllowCommand)
+
+	case key.Matches(msg, a.keymap.Stop) && a.cancel != nil:
+		a.cancel()
+		return a.answerPermission(permission.Deny)
+
+	case key.Matches(msg, a.keymap.Quit):
+		return a, tea.Quit
+	}
+
+	// Ignore all other keys during permission prompt.
+	return a, nil
+}
+
+// answerPermission answers the pending permission prompt with d.
+func (a *App) answerPermission(d permission.Decision) (tea.Model, tea.Cmd) {
+	req := a.permReq
+	req.respond(d)
+	a.permReq = nil
+	var cmd tea.Cmd
+	a.chat, cmd = a.chat.Update(PermissionResponseMsg{Allowed: d != permission.Deny, Decision: d, ToolName: req.ToolName})
+	return a, cmd
+}
+
+// openReview shows the review screen, or says there is nothing to review.
+func (a *App) openReview() {
+	changes := a.staging.Changes()
+	if len(changes) == 0 {
+		a.chat.AddSystemMessage(i18n.T("review.empty"))
+		return
+	}
+	a.review.SetChanges(changes)
+	a.reviewing = true
+	a.recalcLayout()
+}
+
+// handleReviewKey processes keys on the review screen. Applying or
+// discarding acts on every staged file at once and closes the screen.
+func (a *App) handleReviewKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, a.keymap.Quit):
+		return a, tea.Quit
+	case key.Matches(msg, a.keymap.FocusChat):
+		a.reviewing = false
+	case key.Matches(msg, a.keymap.PrevMessage):
+		a.review.PrevFile()
+	case key.Matches(msg, a.keymap.NextMessage):
+		a.review.NextFile()
+	case key.Matches(msg, a.keymap.Apply):
+		a.reviewing = false
+		files, err := a.staging.Apply()
+		if err != nil {
+			a.chat.AddSystemMessage(i18n.T("error", err))
+		} else {
+			a.chat.AddSystemMessage(i18n.T("review.applied", len(files), strings.Join(files, ", ")))
+		}
+	case key.Matches(msg, a.keymap.Discard):
+		a.reviewing = false
+		files := a.staging.Discard()
+		a.chat.AddSystemMessage(i18n.T("review.discarded", len(files), strings.Join(files, ", ")))
+	default:
 		var cmd tea.Cmd
-		a.chat, cmd = a.chat.Update(PermissionResponseMsg{Allowed: false})
+		a.review, cmd = a.review.Update(msg)
 		return a, cmd
+	}
+	return a, nil
+}
+
+// openForm shows the argument form f, or reports why it cannot.
+func (a *App) openForm(f *command.Form) {
+	form, err := NewFormModel(&a.theme, f)
+	if err != nil {
+		a.chat.AddSystemMessage(i18n.T("error", err))
+		return
+	}
+	a.form = form
+	a.filling = true
+	a.recalcLayout()
+}
 
+// handleFormKey processes keys on an argument form. Submitting closes the
+// form and runs it in the background with the input disabled, since the
+// result is added to the conversation.
+func (a *App) handleFormKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
 	case key.Matches(msg, a.keymap.Quit):
 		return a, tea.Quit
+	case key.Matches(msg, a.keymap.FocusChat):
+		a.filling = false
+	case key.Matches(msg, a.keymap.Tab):
+		a.form.NextField()
+	case key.Matches(msg, a.keymap.PrevField):
+		a.form.PrevField()
+	case key.Matches(msg, a.keymap.Send):
+		cmd := a.form.Submit()
+		if cmd == nil {
+			return a, nil // invalid input; the form shows why
+		}
+		a.filling = false
+		a.agentBusy = true
+		a.input.SetDisabled(true)
+		a.chat.AddSystemMessage(i18n.T("form.running", a.form.form.Title))
+		return a, tea.Batch(cmd, a.input.Init())
+	default:
+		var cmd tea.Cmd
+		a.form, cmd = a.form.Update(msg)
+		return a, cmd
 	}
+	return a, nil
+}
 
-	// Ignore all other keys during permission prompt.
+// handlePickerKey processes keys on a picker. Choosing closes it and runs
+// the choice in the background with the input disabled, since it may
+// replace the conversation.
+func (a *App) handlePickerKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, a.keymap.Quit):
+		return a, tea.Quit
+	case key.Matches(msg, a.keymap.FocusChat):
+		a.picking = false
+	// While filtering, letters go to the filter, so only the arrow keys
+	// move the highlight.
+	case a.picker.Filtering() && msg.Type == tea.KeyUp:
+		a.picker.Prev()
+	case a.picker.Filtering() && msg.Type == tea.KeyDown:
+		a.picker.Next()
+	case a.picker.Filtering() && msg.Type == tea.KeyBackspace:
+		a.picker.Backspace()
+	case a.picker.Filtering() && (msg.Type == tea.KeyRunes || msg.Type == tea.KeySpace):
+		a.picker.Type(string(msg.Runes))
+	case key.Matches(msg, a.keymap.ScrollUp):
+		a.picker.Prev()
+	case key.Matches(msg, a.keymap.ScrollDown):
+		a.picker.Next()
+	case key.Matches(msg, a.keymap.Send):
+		choose := a.picker.Choose()
+		if choose == nil {
+			return a, nil
+		}
+		a.picking = false
+		a.agentBusy = true
+		a.input.SetDisabled(true)
+		return a, tea.Batch(choose, a.input.Init())
+	}
 	return a, nil
 }
 
+// applyConfig applies the safe subset of a reloaded config and reports
+// what changed in the chat. The model takes effect on the next request.
+func (a *App) applyConfig(msg ConfigReloadMsg) {
+	if msg.Err != nil {
+		a.chat.AddSystemMessage(i18n.T("config.reload_failed", msg.Err))
+		return
+	}
+
+	changes := config.Changes(a.config, msg.Config)
+	if len(changes) == 0 {
+		return
+	}
+	// Only a changed config model replaces the one picked with /model.
+	if msg.Config.Model != a.config.Model {
+		a.agent.SetModel(msg.Config.Model)
+		a.statusbar.SetModel(msg.Config.Model)
+		a.sidebar.SetModelName(msg.Config.Model)
+	}
+	a.config = msg.Config
+
+	a.agent.SetMaxTokens(msg.Config.ResponseMaxTokens())
+	a.agent.SetPrices(agent.ConfigPrices(msg.Config))
+	a.agent.SetSampling(msg.Config.Temperature, msg.Config.TopP, agent.ConfigSampling(msg.Config))
+	if err := a.chat.SetMarkdownOptions(msg.Config.Markdown.Style, msg.Config.Markdown.WordWrap); err != nil {
+		changes = append(changes, i18n.T("chat.style_failed", err))
+	}
+
+	a.chat.AddSystemMessage(i18n.T("config.reloaded") + "\n" + strings.Join(changes, "\n"))
+}
+
+// openInEditor opens file n of the selected tool message in the editor.
+func (a *App) openInEditor(n int) tea.Cmd {
+	ref, ok := a.chat.SelectedPath(n)
+	if !ok || a.editor == nil {
+		a.chat.AddSystemMessage(i18n.T("chat.no_path", n))
+		return nil
+	}
+	return runForeground(a.editor.Command(ref.Path, ref.Line))
+}
+
 // toggleFocus switches between FocusInput and FocusChat.
 func (a *App) toggleFocus() {
 	if a.focus == FocusInput {
@@ -331,15 +770,123 @@ func (a *App) recalcLayout() {
 
 	a.statusbar.SetWidth(a.width)
 	a.chat.SetSize(chatWidth, chatHeight)
+	a.review.SetSize(a.width, chatHeight)
+	a.form.SetSize(a.width, chatHeight)
+	a.picker.SetSize(a.width, chatHeight)
 	a.sidebar.SetHeight(chatHeight)
 	a.input.SetWidth(a.width)
 }
 
-// runAgent starts the agent in a goroutine and returns AgentDoneMsg when complete.
-func (a *App) runAgent(userMessage string) tea.Cmd {
+// completeCommand completes the slash command being typed in the input,
+// as far as the matching commands agree, and lists them if they differ.
+// It reports false if the input is not the start of a command.
+func (a *App) completeCommand() bool {
+	if a.commands == nil {
+		return false
+	}
+	typed := a.input.Value()
+	matches := a.commands.Complete(typed)
+	if matches == nil {
+		return strings.HasPrefix(typed, "/") && !strings.ContainsAny(typed, " \t\n")
+	}
+	if len(matches) == 1 {
+		a.input.SetValue(matches[0] + " ")
+		return true
+	}
+	prefix := matches[0]
+	for _, m := range matches[1:] {
+		for !strings.HasPrefix(m, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	if prefix == typed {
+		a.chat.AddSystemMessage(i18n.T("input.completions", strings.Join(matches, "  ")))
+	}
+	a.input.SetValue(prefix)
+	return true
+}
+
+// cycleReply quotes the selected answer in the next message, or moves
+// on to its next code block, or after the last one cancels the reply.
+func (a *App) cycleReply() {
+	i, parts, ok := a.chat.Quotable()
+	if !ok {
+		a.chat.AddSystemMessage(i18n.T("chat.no_reply"))
+		return
+	}
+	if a.reply == nil || a.reply.msg != i {
+		a.reply = &reply{msg: i, parts: parts}
+	} else if a.reply.part++; a.reply.part == len(a.reply.parts) {
+		a.reply = nil
+		a.chat.AddSystemMessage(i18n.T("chat.reply_cancelled"))
+		return
+	}
+	switch {
+	case a.reply.part > 0:
+		a.chat.AddSystemMessage(i18n.T("chat.reply_block", a.reply.part, len(parts)-1))
+	case len(parts) > 1:
+		a.chat.AddSystemMessage(i18n.T("chat.reply_message"))
+	default:
+		a.chat.AddSystemMessage(i18n.T("chat.reply_only"))
+	}
+}
+
+// runAgent starts the agent in a goroutine and returns AgentDoneMsg when
+// complete. Cancelling ctx stops the turn.
+func (a *App) runAgent(ctx gocontext.Context, userMessage string) tea.Cmd {
 	ag := a.agent
 	return func() tea.Msg {
-		err := ag.Send(gocontext.Background(), userMessage)
+		err := ag.Send(ctx, userMessage)
 		return AgentDoneMsg{Error: err}
 	}
 }
+
+// CommandDoneMsg carries the outcome of a Long slash command.
+type CommandDoneMsg struct {
+	Result command.Result
+	Err    error
+}
+
+// runCommand returns a tea.Cmd that runs a Long slash command off the
+// UI thread.
+func (a *App) runCommand(ctx gocontext.Context, input string) tea.Cmd {
+	commands := a.commands
+	return func() tea.Msg {
+		res, _, err := commands.Dispatch(ctx, input)
+		return CommandDoneMsg{Result: res, Err: err}
+	}
+}
+
+// commandResult shows the outcome of a slash command and returns what
+// it leaves to run, such as an editor.
+func (a *App) commandResult(res command.Result, err error) tea.Cmd {
+	var cmd tea.Cmd
+	if err != nil {
+		a.chat.AddSystemMessage(i18n.T("error", err))
+	} else if res.Exit {
+		return tea.Quit
+	} else if res.Exec != nil {
+		cmd = runForeground(res.Exec)
+	} else if res.Form != nil {
+		a.openForm(res.Form)
+	} else if res.Picker != nil {
+		a.picker = NewPickerModel(&a.theme, res.Picker, res.Reload)
+		a.picking = true
+		a.recalcLayout()
+	} else {
+		if res.Reload {
+			a.chat.SetHistory(a.agent.History())
+			a.reply = nil
+		}
+		if res.Output != "" {
+			a.chat.AddSystemMessage(res.Output)
+		}
+	}
+	if a.pins != nil {
+		a.sidebar.SetPinned(a.pins.List())
+	}
+	// /model may have switched the model.
+	a.statusbar.SetModel(a.agent.Model())
+	a.sidebar.SetModelName(a.agent.Model())
+	return cmd
+}
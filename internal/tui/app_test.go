@@ -1,15 +1,23 @@
 package tui
 
 import (
+	"context"
 	"errors"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/gavinyap/stormtrooper/internal/agent"
+	"github.com/gavinyap/stormtrooper/internal/command"
 	"github.com/gavinyap/stormtrooper/internal/config"
 	projectctx "github.com/gavinyap/stormtrooper/internal/context"
+	"github.com/gavinyap/stormtrooper/internal/editor"
+	"github.com/gavinyap/stormtrooper/internal/llm"
 	"github.com/gavinyap/stormtrooper/internal/permission"
+	"github.com/gavinyap/stormtrooper/internal/session"
+	"github.com/gavinyap/stormtrooper/internal/staging"
 	"github.com/gavinyap/stormtrooper/internal/tool"
 )
 
@@ -134,6 +142,438 @@ func TestApp_SendMessage(t *testing.T) {
 	}
 }
 
+func TestApp_SlashCommand(t *testing.T) {
+	app := newTestApp()
+	app.commands = command.NewDispatcher()
+	app.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+
+	model, _ := app.Update(SendMsg{Text: "/help"})
+	a := model.(*App)
+	if a.agentBusy {
+		t.Fatal("a slash command should not start the agent")
+	}
+	last := a.chat.messages[len(a.chat.messages)-1]
+	if last.Role != RoleSystem || !strings.Contains(last.Content, "/help") {
+		t.Errorf("expected help in chat, got %+v", last)
+	}
+
+	model, _ = app.Update(SendMsg{Text: "/unknown"})
+	if !model.(*App).agentBusy {
+		t.Error("unknown commands should go to the agent")
+	}
+}
+
+func TestApp_CompleteCommand(t *testing.T) {
+	app := newTestApp()
+	app.commands = command.NewDispatcher()
+	app.commands.Register(command.Command{Name: "compact", Usage: "/compact"})
+	app.commands.Register(command.Command{Name: "context", Usage: "/context"})
+	app.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	tab := tea.KeyMsg{Type: tea.KeyTab}
+
+	app.input.SetValue("/he")
+	app.Update(tab)
+	if got := app.input.Value(); got != "/help " {
+		t.Errorf("tab completed %q, want %q", got, "/help ")
+	}
+	if app.focus != FocusInput {
+		t.Error("tab on a command should not move the focus")
+	}
+
+	app.input.SetValue("/c")
+	app.Update(tab)
+	if got := app.input.Value(); got != "/co" {
+		t.Errorf("tab completed %q, want the common prefix /co", got)
+	}
+	app.Update(tab)
+	if last := app.chat.messages[len(app.chat.messages)-1]; !strings.Contains(last.Content, "/compact  /context") {
+		t.Errorf("a second tab should list the matches, got %+v", last)
+	}
+
+	app.input.SetValue("hello")
+	app.Update(tab)
+	if app.focus != FocusChat {
+		t.Error("tab outside a command should toggle the focus")
+	}
+}
+
+func TestApp_ExitCommand(t *testing.T) {
+	app := newTestApp()
+	app.commands = command.NewDispatcher()
+	app.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+
+	_, cmd := app.Update(SendMsg{Text: "/exit"})
+	if cmd == nil {
+		t.Fatal("/exit should quit")
+	}
+	if _, ok := cmd().(tea.QuitMsg); !ok {
+		t.Error("/exit should return tea.Quit")
+	}
+}
+
+func TestApp_PinCommandUpdatesSidebar(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.md")
+	os.WriteFile(path, []byte("notes"), 0644)
+
+	app := newTestApp()
+	app.pins = agent.NewPins(nil)
+	app.commands = command.NewDispatcher()
+	app.commands.Register(command.Pin(app.pins))
+	app.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+
+	app.Update(SendMsg{Text: "/pin " + path})
+	if len(app.sidebar.pinned) != 1 || app.sidebar.pinned[0] != path {
+		t.Errorf("sidebar pinned = %q", app.sidebar.pinned)
+	}
+}
+
+func TestApp_ChatSelectionKeys(t *testing.T) {
+	app := newTestApp()
+	app.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	app.chat.messages = append(app.chat.messages, ChatMessage{Role: RoleAssistant, Content: "```\n" + strings.Repeat("y", 300) + "\n```"})
+	app.chat.renderAll()
+
+	app.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	app.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("[")})
+	if app.chat.selected != 0 {
+		t.Fatalf("[ in chat focus should select the message, selected = %d", app.chat.selected)
+	}
+	app.Update(tea.KeyMsg{Type: tea.KeyRight})
+	if app.chat.xOffset != horizontalStep {
+		t.Errorf("right should scroll the selected message, xOffset = %d", app.chat.xOffset)
+	}
+	app.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("r")})
+	if !app.chat.messages[0].Raw {
+		t.Error("r should toggle the markdown source")
+	}
+}
+
+func TestApp_OpenInEditor(t *testing.T) {
+	app := newTestApp()
+	app.editor = editor.New("nano", "/work", func(string) string { return "" })
+	app.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	app.chat.messages = append(app.chat.messages,
+		ChatMessage{Role: RoleTool, Content: "grep", Paths: []fileRef{{Path: "a.go", Line: 3}}},
+		ChatMessage{Role: RoleAssistant, Content: "Found it."},
+	)
+	app.chat.renderAll()
+
+	app.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	app.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("[")})
+	app.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("[")})
+	if !app.chat.SelectedTool() {
+		t.Fatalf("[ should reach the tool message, selected = %d", app.chat.selected)
+	}
+	if _, cmd := app.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("e")}); cmd == nil {
+		t.Error("e on a tool message with files should run the editor")
+	}
+
+	n := len(app.chat.messages)
+	if _, cmd := app.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("2")}); cmd != nil {
+		t.Error("a missing file should not run the editor")
+	}
+	if last := app.chat.messages[len(app.chat.messages)-1]; len(app.chat.messages) != n+1 || !strings.Contains(last.Content, "No file [2]") {
+		t.Errorf("expected a notice for the missing file, got %+v", last)
+	}
+
+	app.Update(ExecDoneMsg{Err: errors.New("exit status 1")})
+	if last := app.chat.messages[len(app.chat.messages)-1]; !strings.Contains(last.Content, "exit status 1") {
+		t.Errorf("expected the editor error in chat, got %+v", last)
+	}
+}
+
+func TestApp_Reply(t *testing.T) {
+	app := newTestApp()
+	app.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	app.chat.messages = append(app.chat.messages, ChatMessage{Role: RoleAssistant, Content: "Try this:\n\n```go\nfunc a() {}\n```"})
+	app.chat.renderAll()
+	reply := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(">")}
+
+	app.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	app.Update(reply)
+	app.Update(reply)
+	if last := app.chat.messages[len(app.chat.messages)-1]; !strings.Contains(last.Content, "code block 1 of 1") {
+		t.Fatalf("second > should quote the code block, got %+v", last)
+	}
+
+	app.Update(SendMsg{Text: "rename it"})
+	sent := app.chat.messages[len(app.chat.messages)-1]
+	want := replyMarker + "\n\n> ```go\n> func a() {}\n> ```\n\nrename it"
+	if sent.Role != RoleUser || sent.Content != want {
+		t.Errorf("sent %q, want %q", sent.Content, want)
+	}
+	if app.reply != nil {
+		t.Error("the reply should be cleared once sent")
+	}
+}
+
+func TestApp_ReplyCycle(t *testing.T) {
+	app := newTestApp()
+	app.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	reply := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(">")}
+
+	app.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	app.Update(reply)
+	if last := app.chat.messages[len(app.chat.messages)-1]; !strings.Contains(last.Content, "No answer") {
+		t.Errorf("expected a notice without an answer, got %+v", last)
+	}
+
+	app.chat.messages = append(app.chat.messages, ChatMessage{Role: RoleAssistant, Content: "Done."})
+	app.chat.renderAll()
+	app.Update(reply)
+	if app.reply == nil || app.reply.part != 0 {
+		t.Fatalf("> should quote the whole answer, reply = %+v", app.reply)
+	}
+	app.Update(reply)
+	if app.reply != nil {
+		t.Errorf("> past the last part should cancel the reply, reply = %+v", app.reply)
+	}
+}
+
+func TestApp_AutoDecision(t *testing.T) {
+	app := newTestApp()
+	app.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	app.Update(AutoDecisionMsg{Tool: "write_file", Approved: true, Rule: "write_file"})
+	app.Update(AutoDecisionMsg{Tool: "shell_exec", Approved: true, Rule: "go test ./..."})
+
+	line := app.chat.messages[len(app.chat.messages)-1]
+	if line.Role != RoleTool || !strings.Contains(line.Content, "shell_exec auto-approved by rule `go test ./...`") {
+		t.Fatalf("expected a line for the decision, got %+v", line)
+	}
+
+	// w explains the latest decision, or the selected one.
+	app.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	why := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("w")}
+	app.Update(why)
+	if last := app.chat.messages[len(app.chat.messages)-1]; last.Role != RoleSystem || !strings.Contains(last.Content, "commands:\n  shell_exec:\n    - \"go test ./...\"") {
+		t.Errorf("expected the command rule, got %+v", last)
+	}
+	app.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("[")})
+	app.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("[")})
+	app.Update(why)
+	if last := app.chat.messages[len(app.chat.messages)-1]; !strings.Contains(last.Content, "tools:\n  - write_file") {
+		t.Errorf("expected the tool rule, got %+v", last)
+	}
+}
+
+func TestApp_AutoDecisionPolicy(t *testing.T) {
+	app := newTestApp()
+	app.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	app.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	why := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("w")}
+	app.Update(why)
+	if last := app.chat.messages[len(app.chat.messages)-1]; !strings.Contains(last.Content, "No automatic") {
+		t.Errorf("expected a notice without a decision, got %+v", last)
+	}
+
+	app.Update(AutoDecisionMsg{Tool: "shell_exec", Reason: "no curl"})
+	app.Update(why)
+	if last := app.chat.messages[len(app.chat.messages)-1]; !strings.Contains(last.Content, "organization policy: no curl") {
+		t.Errorf("expected the policy reason, got %+v", last)
+	}
+}
+
+func TestApp_ReviewStagedChanges(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")
+	os.WriteFile(a, []byte("old\n"), 0644)
+
+	app := newTestApp()
+	app.staging = staging.New(nil)
+	app.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+
+	app.Update(tea.KeyMsg{Type: tea.KeyCtrlR})
+	if app.reviewing {
+		t.Fatal("the review screen should not open with nothing staged")
+	}
+
+	app.staging.WriteFile(a, []byte("new\n"), 0644)
+	app.staging.WriteFile(b, []byte("created\n"), 0644)
+	app.Update(AgentDoneMsg{})
+	if last := app.chat.messages[len(app.chat.messages)-1]; !strings.Contains(last.Content, "2 file(s) staged") {
+		t.Errorf("expected a staged-changes notice, got %+v", last)
+	}
+
+	app.Update(tea.KeyMsg{Type: tea.KeyCtrlR})
+	if !app.reviewing {
+		t.Fatal("Ctrl+R should open the review screen")
+	}
+	view := stripANSI(app.View())
+	if !strings.Contains(view, "Staged changes (2 file(s))") || !strings.Contains(view, "+new") {
+		t.Errorf("expected the first file's diff:\n%s", view)
+	}
+	app.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("]")})
+	if view := stripANSI(app.View()); !strings.Contains(view, "+created") {
+		t.Errorf("] should show the next file:\n%s", view)
+	}
+
+	app.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("a")})
+	if app.reviewing || app.staging.Len() != 0 {
+		t.Fatal("a should apply everything and close the screen")
+	}
+	if data, _ := os.ReadFile(b); string(data) != "created\n" {
+		t.Errorf("b.txt = %q", data)
+	}
+}
+
+func TestApp_RunToolForm(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.md")
+	os.WriteFile(path, []byte("remember the milk"), 0644)
+
+	app := newTestApp()
+	reg := tool.NewRegistry()
+	reg.Register(&tool.ReadFileTool{})
+	app.commands = command.NewDispatcher()
+	app.commands.Register(command.RunTool(app.agent, reg))
+	app.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+
+	app.Update(SendMsg{Text: "/run-tool read_file"})
+	if !app.filling {
+		t.Fatal("/run-tool should open the argument form")
+	}
+	if view := stripANSI(app.View()); !strings.Contains(view, "Run read_file") || !strings.Contains(view, "file_path *") {
+		t.Errorf("expected the form in the view:\n%s", view)
+	}
+
+	app.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(path)})
+	_, cmd := app.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if app.filling || !app.agentBusy {
+		t.Fatal("Enter should close the form and run the tool")
+	}
+	var done FormDoneMsg
+	for _, msg := range cmd().(tea.BatchMsg) {
+		if msg == nil {
+			continue
+		}
+		if d, ok := msg().(FormDoneMsg); ok {
+			done = d
+		}
+	}
+	app.Update(done)
+	if app.agentBusy {
+		t.Error("the input should be enabled once the tool is done")
+	}
+	if last := app.chat.messages[len(app.chat.messages)-1]; !strings.Contains(last.Content, "remember the milk") {
+		t.Errorf("expected the tool result in the chat, got %+v", last)
+	}
+	if history := app.agent.History(); len(history) != 2 || history[1].Role != "tool" {
+		t.Errorf("expected the result in the conversation, got %+v", history)
+	}
+
+	app.Update(SendMsg{Text: "/run-tool read_file"})
+	app.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if app.filling {
+		t.Error("Esc should close the form")
+	}
+}
+
+func TestApp_ResumePicker(t *testing.T) {
+	dir := t.TempDir()
+	saved := &session.Session{ID: "earlier", Messages: []llm.Message{
+		{Role: "system", Content: "sys"},
+		{Role: "user", Content: "fix the build"},
+		{Role: "assistant", Content: "Fixed the import."},
+	}}
+	saved.Save(dir)
+
+	app := newTestApp()
+	app.commands = command.NewDispatcher()
+	app.commands.Register(command.Resume(dir, func(s *session.Session) (string, error) {
+		app.agent.Restore(s.Messages)
+		return "Resumed " + s.ID + ".", nil
+	}))
+	app.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+
+	app.Update(SendMsg{Text: "/resume"})
+	if !app.picking {
+		t.Fatal("/resume should open the picker")
+	}
+	if view := stripANSI(app.View()); !strings.Contains(view, "Resume a session") || !strings.Contains(view, "fix the build") {
+		t.Errorf("expected the sessions in the view:\n%s", view)
+	}
+
+	_, cmd := app.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if app.picking || !app.agentBusy {
+		t.Fatal("Enter should close the picker and resume the session")
+	}
+	var done PickerDoneMsg
+	for _, msg := range cmd().(tea.BatchMsg) {
+		if msg == nil {
+			continue
+		}
+		if d, ok := msg().(PickerDoneMsg); ok {
+			done = d
+		}
+	}
+	app.Update(done)
+	if app.agentBusy {
+		t.Error("the input should be enabled once the session is resumed")
+	}
+	var contents []string
+	for _, m := range app.chat.messages {
+		contents = append(contents, m.Content)
+	}
+	if want := []string{"fix the build", "Fixed the import.", "Resumed earlier."}; strings.Join(contents, "|") != strings.Join(want, "|") {
+		t.Errorf("chat = %q, want the resumed conversation %q", contents, want)
+	}
+
+	app.Update(SendMsg{Text: "/resume"})
+	app.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if app.picking {
+		t.Error("Esc should close the picker")
+	}
+}
+
+func TestApp_ModelPicker(t *testing.T) {
+	app := newTestApp()
+	app.commands = command.NewDispatcher()
+	app.commands.Register(command.Model(app.agent, nil, func(context.Context) ([]llm.Model, error) {
+		return []llm.Model{{ID: "anthropic/claude"}, {ID: "openai/gpt-4o"}, {ID: "openai/gpt-oss"}}, nil
+	}))
+	app.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	app.agent.Restore([]llm.Message{{Role: "system", Content: "sys"}, {Role: "user", Content: "keep me"}})
+
+	// The list loads in the background, and the picker opens with it.
+	_, cmd := app.Update(SendMsg{Text: "/model"})
+	if app.picking || !app.agentBusy {
+		t.Fatal("/model should load the models before opening the picker")
+	}
+	for _, c := range cmd().(tea.BatchMsg) {
+		if c == nil {
+			continue
+		}
+		if d, ok := c().(CommandDoneMsg); ok {
+			app.Update(d)
+		}
+	}
+	if !app.picking || app.agentBusy {
+		t.Fatal("/model should open the picker")
+	}
+	// Letters, including j and k, go to the filter.
+	for _, r := range "gpt-oss" {
+		app.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+	if view := stripANSI(app.View()); !strings.Contains(view, "Filter: gpt-oss") || strings.Contains(view, "gpt-4o") {
+		t.Errorf("expected the filtered models in the view:\n%s", view)
+	}
+
+	_, cmd = app.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	for _, msg := range cmd().(tea.BatchMsg) {
+		if msg == nil {
+			continue
+		}
+		if d, ok := msg().(PickerDoneMsg); ok {
+			app.Update(d)
+		}
+	}
+	if app.agent.Model() != "openai/gpt-oss" || !strings.Contains(stripANSI(app.statusbar.View()), "openai/gpt-oss") {
+		t.Errorf("model = %s, status bar %q", app.agent.Model(), stripANSI(app.statusbar.View()))
+	}
+	if h := app.agent.History(); h[len(h)-1].Content != "keep me" {
+		t.Errorf("switching models should keep the conversation, got %v", h)
+	}
+}
+
 func TestApp_PermissionFlow(t *testing.T) {
 	app := newTestApp()
 
@@ -176,6 +616,62 @@ func TestApp_PermissionFlow(t *testing.T) {
 	}
 }
 
+func TestApp_PermissionExpired(t *testing.T) {
+	app := newTestApp()
+	done := make(chan struct{})
+	close(done)
+	app.Update(PermissionRequestMsg{ID: "r", ToolName: "shell_exec", Preview: "Run: make", Response: make(chan bool, 1), Done: done})
+	if app.permReq != nil {
+		t.Error("a request nobody waits for should not be shown")
+	}
+}
+
+func TestApp_PermissionRemember(t *testing.T) {
+	tests := []struct {
+		key     rune
+		command string
+		want    permission.Decision
+		pending bool // the key is ignored and the prompt stays open
+	}{
+		{'a', "go test ./...", permission.AllowTool, false},
+		{'c', "go test ./...", permission.AllowCommand, false},
+		{'c', "", 0, true},
+		{'y', "", permission.AllowOnce, false},
+	}
+	for _, tt := range tests {
+		app := newTestApp()
+		decision := make(chan permission.Decision, 1)
+		model, _ := app.Update(PermissionRequestMsg{ID: "r", ToolName: "shell_exec", Preview: "Run: go test", Decision: decision, Command: tt.command})
+		model, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{tt.key}})
+		a := model.(*App)
+
+		if tt.pending {
+			if a.permReq == nil {
+				t.Errorf("%c with command %q: prompt closed", tt.key, tt.command)
+			}
+			continue
+		}
+		select {
+		case got := <-decision:
+			if got != tt.want {
+				t.Errorf("%c: decision = %v, want %v", tt.key, got, tt.want)
+			}
+		default:
+			t.Errorf("%c: no decision sent", tt.key)
+		}
+	}
+}
+
+func TestApp_PermissionAlwaysNeedsDecider(t *testing.T) {
+	app := newTestApp()
+	respCh := make(chan bool, 1)
+	model, _ := app.Update(PermissionRequestMsg{ID: "r", ToolName: "write_file", Preview: "Write a.go", Response: respCh})
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'a'}})
+	if model.(*App).permReq == nil {
+		t.Fatal("'a' should be ignored by a prompt that cannot remember the answer")
+	}
+}
+
 func TestApp_PermissionDeny(t *testing.T) {
 	app := newTestApp()
 
@@ -261,6 +757,113 @@ func TestApp_AgentDoneWithError(t *testing.T) {
 	}
 }
 
+func TestApp_StopAgent(t *testing.T) {
+	app := newTestApp()
+	app.Update(tea.WindowSizeMsg{Width: 100, Height: 30})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	app.agentBusy = true
+	app.cancel = cancel
+	app.input.SetDisabled(true)
+
+	// Esc still moves the focus, so the chat can be scrolled while the
+	// agent runs.
+	app.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if ctx.Err() != nil || app.focus != FocusChat {
+		t.Fatal("Esc should focus the chat without stopping the agent")
+	}
+	app.Update(tea.KeyMsg{Type: tea.KeyCtrlX})
+	if ctx.Err() == nil {
+		t.Fatal("Ctrl+X should cancel the running agent")
+	}
+
+	model, _ := app.Update(AgentDoneMsg{Error: errors.Join(errors.New("agent cancelled"), context.Canceled)})
+	a := model.(*App)
+	if a.agentBusy || a.input.disabled || a.cancel != nil {
+		t.Fatal("expected the input back once the agent stops")
+	}
+	last := a.chat.messages[len(a.chat.messages)-1]
+	if last.Role != RoleSystem || last.Content != "Cancelled" {
+		t.Errorf("expected a Cancelled message, got %+v", last)
+	}
+
+	if a.focus != FocusInput {
+		t.Error("the input should have the focus once the agent stops")
+	}
+}
+
+func TestApp_LongCommand(t *testing.T) {
+	app := newTestApp()
+	app.commands = command.NewDispatcher()
+	app.commands.Register(command.Command{Name: "slow", Long: true, Run: func(ctx context.Context, args []string) (string, error) {
+		if len(args) > 0 {
+			return "done " + args[0], nil
+		}
+		<-ctx.Done()
+		return "", ctx.Err()
+	}})
+	app.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+
+	// run returns the CommandDoneMsg among the messages of cmd.
+	run := func(cmd tea.Cmd) {
+		for _, c := range cmd().(tea.BatchMsg) {
+			if c == nil {
+				continue
+			}
+			if d, ok := c().(CommandDoneMsg); ok {
+				app.Update(d)
+				return
+			}
+		}
+		t.Fatal("no CommandDoneMsg")
+	}
+
+	_, cmd := app.Update(SendMsg{Text: "/slow now"})
+	if !app.agentBusy || !app.input.disabled || app.cancel == nil {
+		t.Fatal("a long command should show the busy state")
+	}
+	run(cmd)
+	if app.agentBusy || app.cancel != nil {
+		t.Error("expected the input back once the command finishes")
+	}
+	if last := app.chat.messages[len(app.chat.messages)-1]; last.Content != "done now" {
+		t.Errorf("expected the output, got %+v", last)
+	}
+
+	_, cmd = app.Update(SendMsg{Text: "/slow"})
+	app.Update(tea.KeyMsg{Type: tea.KeyCtrlX})
+	run(cmd)
+	if last := app.chat.messages[len(app.chat.messages)-1]; last.Content != "Cancelled" {
+		t.Errorf("Stop should cancel the command, got %+v", last)
+	}
+}
+
+func TestApp_StopAgentDeniesPrompt(t *testing.T) {
+	app := newTestApp()
+	ctx, cancel := context.WithCancel(context.Background())
+	app.agentBusy = true
+	app.cancel = cancel
+
+	respCh := make(chan bool, 1)
+	app.Update(PermissionRequestMsg{ID: "r", ToolName: "shell_exec", Preview: "Run: make", Response: respCh})
+	app.Update(tea.KeyMsg{Type: tea.KeyCtrlX})
+
+	if ctx.Err() == nil {
+		t.Error("Ctrl+X should cancel the running agent")
+	}
+	if app.permReq != nil {
+		t.Error("the prompt should close")
+	}
+	select {
+	case allowed := <-respCh:
+		if allowed {
+			t.Error("the pending call should be denied")
+		}
+	default:
+		t.Error("no answer sent")
+	}
+}
+
 func TestApp_View(t *testing.T) {
 	app := newTestApp()
 
@@ -408,3 +1011,62 @@ func TestApp_ToolStartForwarded(t *testing.T) {
 
 	_ = a
 }
+
+func TestApp_ConfigReload(t *testing.T) {
+	app := newTestApp()
+	app.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+
+	model, _ := app.Update(ConfigReloadMsg{Config: &config.Config{Model: "reloaded-model"}})
+	a := model.(*App)
+
+	if a.agent.Model() != "reloaded-model" {
+		t.Errorf("expected agent model 'reloaded-model', got %q", a.agent.Model())
+	}
+	if a.statusbar.model != "reloaded-model" {
+		t.Errorf("expected status bar model 'reloaded-model', got %q", a.statusbar.model)
+	}
+	last := a.chat.messages[len(a.chat.messages)-1]
+	if last.Role != RoleSystem || !strings.Contains(last.Content, "test-model -> reloaded-model") {
+		t.Errorf("expected reload system message, got %+v", last)
+	}
+}
+
+func TestApp_ConfigReloadNoChangeIsSilent(t *testing.T) {
+	app := newTestApp()
+
+	app.Update(ConfigReloadMsg{Config: &config.Config{Model: "test-model"}})
+	if len(app.chat.messages) != 0 {
+		t.Errorf("expected no system message for unchanged config, got %d", len(app.chat.messages))
+	}
+}
+
+func TestApp_ConfigReloadKeepsPickedModel(t *testing.T) {
+	app := newTestApp()
+	app.agent.SetModel("picked-model")
+
+	app.Update(ConfigReloadMsg{Config: &config.Config{Model: "test-model", MaxTokens: 2048}})
+	if app.agent.Model() != "picked-model" {
+		t.Errorf("a reload without a model change replaced the /model pick, got %q", app.agent.Model())
+	}
+}
+
+func TestApp_ConfigReloadError(t *testing.T) {
+	app := newTestApp()
+
+	app.Update(ConfigReloadMsg{Err: errors.New("invalid YAML")})
+	if len(app.chat.messages) != 1 || !strings.Contains(app.chat.messages[0].Content, "invalid YAML") {
+		t.Errorf("expected reload error in chat, got %+v", app.chat.messages)
+	}
+	if app.agent.Model() != "test-model" {
+		t.Errorf("model should be unchanged after failed reload, got %q", app.agent.Model())
+	}
+}
+
+func TestApp_Notice(t *testing.T) {
+	app := newTestApp()
+
+	app.Update(NoticeMsg{Text: "Received a message from vim."})
+	if len(app.chat.messages) != 1 || app.chat.messages[0].Role != RoleSystem || app.chat.messages[0].Content != "Received a message from vim." {
+		t.Errorf("expected the notice in chat, got %+v", app.chat.messages)
+	}
+}
@@ -1,22 +1,23 @@
 package tui
 
 import (
-	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
-	"io"
 	"strings"
-	"sync"
 	"sync/atomic"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/gavinyap/stormtrooper/internal/agent"
+	"github.com/gavinyap/stormtrooper/internal/i18n"
 	"github.com/gavinyap/stormtrooper/internal/permission"
 )
 
 // Ensure interfaces are satisfied at compile time.
 var (
-	_ io.Writer        = (*EventWriter)(nil)
-	_ io.Writer        = (*ToolEventWriter)(nil)
 	_ permission.Handler = (*PermissionInterceptor)(nil)
+	_ permission.HunkReviewer = (*PermissionInterceptor)(nil)
+	_ permission.Decider      = (*PermissionInterceptor)(nil)
 )
 
 // idCounter is used to generate unique IDs for permission requests.
@@ -26,78 +27,39 @@ func generateID() string {
 	return fmt.Sprintf("perm-%d", idCounter.Add(1))
 }
 
-// EventWriter implements io.Writer. Each Write sends a TokenMsg
-// on the events channel. Used as the agent's stdout.
-type EventWriter struct {
-	events chan<- AgentEvent
-}
-
-func (w *EventWriter) Write(p []byte) (int, error) {
-	w.events <- TokenMsg{Content: string(p)}
-	return len(p), nil
-}
-
-// ToolEventWriter implements io.Writer. It parses stderr output from the
-// agent and converts recognized patterns into structured events.
-type ToolEventWriter struct {
-	events chan<- AgentEvent
-	mu     sync.Mutex
-	buf    []byte
-}
-
-func (w *ToolEventWriter) Write(p []byte) (int, error) {
-	w.mu.Lock()
-	defer w.mu.Unlock()
-
-	w.buf = append(w.buf, p...)
-
-	for {
-		idx := bytes.IndexByte(w.buf, '\n')
-		if idx < 0 {
-			break
-		}
-		line := string(w.buf[:idx])
-		w.buf = w.buf[idx+1:]
-		w.parseLine(line)
-	}
-
-	return len(p), nil
-}
-
-func (w *ToolEventWriter) parseLine(line string) {
-	line = strings.TrimSpace(line)
-	if line == "" {
-		return
-	}
-
-	switch {
-	case strings.HasPrefix(line, "[tool:done] "):
-		name := strings.TrimPrefix(line, "[tool:done] ")
-		w.events <- ToolResultMsg{Name: name}
-
-	case strings.HasPrefix(line, "[tool:error] "):
-		name := strings.TrimPrefix(line, "[tool:error] ")
-		w.events <- ToolResultMsg{Name: name, Error: "error"}
-
-	case strings.HasPrefix(line, "[tool] "):
-		rest := strings.TrimPrefix(line, "[tool] ")
-		// Skip "permission denied" lines — handled by the permission flow.
-		if strings.Contains(rest, ": permission denied") {
-			return
-		}
-		// Skip "Unknown tool:" lines.
-		if strings.HasPrefix(rest, "Unknown tool:") {
-			return
+// eventMsg turns an agent event into the message the TUI shows it with,
+// or nil for events it does not show. The end of a turn is not among
+// them: runAgent reports it with AgentDoneMsg.
+func eventMsg(e agent.Event) AgentEvent {
+	switch e := e.(type) {
+	case agent.TokenDelta:
+		return TokenMsg{Content: e.Content}
+	case agent.ToolStart:
+		return ToolStartMsg{Name: e.Name}
+	case agent.ToolResult:
+		return ToolResultMsg{Name: e.Name, Error: e.Error}
+	case agent.SubAgentSpawn:
+		return SubAgentSpawnMsg{Task: e.Task}
+	case agent.SubAgentDone:
+		return SubAgentDoneMsg{}
+	case agent.SubAgentEvent:
+		// A lone sub-agent's tool calls show in the chat like our own;
+		// those of sub-agents in parallel go to their sidebar entries.
+		msg := eventMsg(e.Event)
+		if e.Agent == 0 || msg == nil {
+			return msg
 		}
-		w.events <- ToolStartMsg{Name: rest}
-
-	case strings.HasPrefix(line, "[agent] Spawning sub-agent: "):
-		task := strings.TrimPrefix(line, "[agent] Spawning sub-agent: ")
-		w.events <- SubAgentSpawnMsg{Task: task}
-
-	case line == "[agent] Sub-agent completed":
-		w.events <- SubAgentDoneMsg{}
+		return SubAgentProgressMsg{Agent: e.Agent, Msg: msg}
+	case agent.AutoDecision:
+		return AutoDecisionMsg{Tool: e.Tool, Approved: e.Approved, Rule: e.Rule, Reason: e.Reason}
+	case agent.Warning:
+		return WarningMsg{Text: e.Text}
+	case agent.Compacted:
+		return CompactMsg{Text: e.Text}
+	case agent.ModelUsed:
+		return ModelMsg{Model: e.Model}
 	}
+	return nil
 }
 
 // PermissionInterceptor implements permission.Handler for TUI mode.
@@ -112,23 +74,71 @@ func NewPermissionInterceptor(events chan<- AgentEvent) *PermissionInterceptor {
 	return &PermissionInterceptor{events: events}
 }
 
-// Check sends a permission request to the TUI and blocks until the user responds.
-func (p *PermissionInterceptor) Check(toolName string, preview string) bool {
+// Check sends a permission request to the TUI and blocks until the user
+// responds, denying if ctx is done first.
+func (p *PermissionInterceptor) Check(ctx context.Context, toolName string, preview string) bool {
 	respCh := make(chan bool, 1)
-	p.events <- PermissionRequestMsg{
+	msg := PermissionRequestMsg{
 		ID:       generateID(),
 		ToolName: toolName,
 		Preview:  preview,
 		Response: respCh,
+		Done:     ctx.Done(),
+	}
+	allowed, _ := ask(ctx, p.events, msg, respCh)
+	return allowed
+}
+
+// Decide sends a permission request that offers to remember the answer
+// and blocks until the user responds, denying if ctx is done first.
+func (p *PermissionInterceptor) Decide(ctx context.Context, toolName, preview, command string) permission.Decision {
+	respCh := make(chan permission.Decision, 1)
+	msg := PermissionRequestMsg{
+		ID:       generateID(),
+		ToolName: toolName,
+		Preview:  preview,
+		Decision: respCh,
+		Command:  command,
+		Done:     ctx.Done(),
+	}
+	if d, ok := ask(ctx, p.events, msg, respCh); ok {
+		return d
+	}
+	return permission.Deny
+}
+
+// ReviewHunks asks about each hunk in turn with the usual y/n prompt.
+// Once ctx is done, the remaining hunks are rejected.
+func (p *PermissionInterceptor) ReviewHunks(ctx context.Context, toolName, path string, hunks []string) []bool {
+	accepted := make([]bool, len(hunks))
+	for i, h := range hunks {
+		accepted[i] = p.Check(ctx, toolName, i18n.T("permission.hunk", path, i+1, len(hunks), strings.TrimRight(h, "\n")))
+		if ctx.Err() != nil {
+			return make([]bool, len(hunks))
+		}
+	}
+	return accepted
+}
+
+// ask sends msg to the TUI and waits for the answer on resp. ok is false
+// if ctx was done first.
+func ask[T any](ctx context.Context, events chan<- AgentEvent, msg AgentEvent, resp <-chan T) (answer T, ok bool) {
+	select {
+	case events <- msg:
+	case <-ctx.Done():
+		return answer, false
+	}
+	select {
+	case answer = <-resp:
+		return answer, true
+	case <-ctx.Done():
+		return answer, false
 	}
-	return <-respCh
 }
 
 // Bridge connects an agent.Agent to the Bubble Tea event loop.
 type Bridge struct {
 	events chan AgentEvent
-	stdout *EventWriter
-	stderr *ToolEventWriter
 	perm   *PermissionInterceptor
 }
 
@@ -137,8 +147,6 @@ func NewBridge() *Bridge {
 	events := make(chan AgentEvent, 256)
 	return &Bridge{
 		events: events,
-		stdout: &EventWriter{events: events},
-		stderr: &ToolEventWriter{events: events},
 		perm:   NewPermissionInterceptor(events),
 	}
 }
@@ -148,11 +156,19 @@ func (b *Bridge) Events() <-chan AgentEvent {
 	return b.events
 }
 
-// Stdout returns the io.Writer to set as the agent's stdout.
-func (b *Bridge) Stdout() io.Writer { return b.stdout }
+// Handle forwards an agent event to the TUI. It is registered with the
+// agent's OnEvent.
+func (b *Bridge) Handle(e agent.Event) {
+	if msg := eventMsg(e); msg != nil {
+		b.events <- msg
+	}
+}
 
-// Stderr returns the io.Writer to set as the agent's stderr.
-func (b *Bridge) Stderr() io.Writer { return b.stderr }
+// ToolOutput forwards a finished tool call to the TUI. It is registered
+// with the agent's OnToolResult.
+func (b *Bridge) ToolOutput(name string, args json.RawMessage, result string) {
+	b.events <- ToolOutputMsg{Name: name, Args: args, Result: result}
+}
 
 // Permission returns the permission handler for TUI mode.
 func (b *Bridge) Permission() permission.Handler { return b.perm }
@@ -1,169 +1,61 @@
 package tui
 
 import (
+	"context"
+	"fmt"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/gavinyap/stormtrooper/internal/agent"
 	"github.com/gavinyap/stormtrooper/internal/permission"
 )
 
-func TestEventWriter(t *testing.T) {
-	ch := make(chan AgentEvent, 10)
-	w := &EventWriter{events: ch}
-
-	n, err := w.Write([]byte("hello world"))
-	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
-	}
-	if n != 11 {
-		t.Fatalf("expected 11 bytes written, got %d", n)
+func TestEventMsg(t *testing.T) {
+	tests := []struct {
+		event agent.Event
+		want  AgentEvent
+	}{
+		{agent.TokenDelta{Content: "hello world"}, TokenMsg{Content: "hello world"}},
+		{agent.ToolStart{Name: "read_file", Args: []byte(`{}`)}, ToolStartMsg{Name: "read_file"}},
+		{agent.ToolResult{Name: "read_file", Result: "package a"}, ToolResultMsg{Name: "read_file"}},
+		{agent.ToolResult{Name: "read_file", Error: "panic: runtime error: index out of range"}, ToolResultMsg{Name: "read_file", Error: "panic: runtime error: index out of range"}},
+		{agent.SubAgentSpawn{Task: "Fix the login bug"}, SubAgentSpawnMsg{Task: "Fix the login bug"}},
+		{agent.SubAgentDone{}, SubAgentDoneMsg{}},
+		// A lone sub-agent's tools show like our own.
+		{agent.SubAgentEvent{Event: agent.ToolStart{Name: "grep"}}, ToolStartMsg{Name: "grep"}},
+		{agent.SubAgentEvent{Agent: 2, Event: agent.ToolStart{Name: "grep"}}, SubAgentProgressMsg{Agent: 2, Msg: ToolStartMsg{Name: "grep"}}},
+		{agent.SubAgentEvent{Agent: 2, Event: agent.TurnDone{}}, nil},
+		{agent.AutoDecision{Tool: "shell_exec", Approved: true, Rule: "go test ./..."}, AutoDecisionMsg{Tool: "shell_exec", Approved: true, Rule: "go test ./..."}},
+		{agent.AutoDecision{Tool: "shell_exec", Reason: "no curl"}, AutoDecisionMsg{Tool: "shell_exec", Reason: "no curl"}},
+		{agent.Warning{Text: "Response stopped by the provider's content filter"}, WarningMsg{Text: "Response stopped by the provider's content filter"}},
+		{agent.Compacted{Text: "Summarized 12 earlier messages"}, CompactMsg{Text: "Summarized 12 earlier messages"}},
+		{agent.ModelUsed{Model: "openai/gpt-4o-mini"}, ModelMsg{Model: "openai/gpt-4o-mini"}},
+		// runAgent reports the end of the turn.
+		{agent.TurnDone{}, nil},
 	}
-
-	select {
-	case ev := <-ch:
-		tok, ok := ev.(TokenMsg)
-		if !ok {
-			t.Fatalf("expected TokenMsg, got %T", ev)
-		}
-		if tok.Content != "hello world" {
-			t.Fatalf("expected 'hello world', got %q", tok.Content)
-		}
-	case <-time.After(time.Second):
-		t.Fatal("timed out waiting for event")
-	}
-}
-
-func TestToolEventWriter_ToolStart(t *testing.T) {
-	ch := make(chan AgentEvent, 10)
-	w := &ToolEventWriter{events: ch}
-
-	w.Write([]byte("[tool] read_file\n"))
-
-	select {
-	case ev := <-ch:
-		msg, ok := ev.(ToolStartMsg)
-		if !ok {
-			t.Fatalf("expected ToolStartMsg, got %T", ev)
-		}
-		if msg.Name != "read_file" {
-			t.Fatalf("expected 'read_file', got %q", msg.Name)
+	for _, tt := range tests {
+		if got := eventMsg(tt.event); got != tt.want {
+			t.Errorf("eventMsg(%#v) = %#v, want %#v", tt.event, got, tt.want)
 		}
-	case <-time.After(time.Second):
-		t.Fatal("timed out waiting for event")
 	}
 }
 
-func TestToolEventWriter_PartialLines(t *testing.T) {
-	ch := make(chan AgentEvent, 10)
-	w := &ToolEventWriter{events: ch}
-
-	// Write partial line
-	w.Write([]byte("[tool] edi"))
-	// No event should be emitted yet
-	select {
-	case ev := <-ch:
-		t.Fatalf("unexpected event from partial line: %T", ev)
-	default:
-	}
-
-	// Complete the line
-	w.Write([]byte("t_file\n"))
-
-	select {
-	case ev := <-ch:
-		msg, ok := ev.(ToolStartMsg)
-		if !ok {
-			t.Fatalf("expected ToolStartMsg, got %T", ev)
-		}
-		if msg.Name != "edit_file" {
-			t.Fatalf("expected 'edit_file', got %q", msg.Name)
-		}
-	case <-time.After(time.Second):
-		t.Fatal("timed out waiting for event")
-	}
-}
-
-func TestToolEventWriter_PermissionDeniedIgnored(t *testing.T) {
-	ch := make(chan AgentEvent, 10)
-	w := &ToolEventWriter{events: ch}
-
-	w.Write([]byte("[tool] shell_exec: permission denied\n"))
-
-	select {
-	case ev := <-ch:
-		t.Fatalf("expected no event for permission denied, got %T", ev)
-	default:
-		// expected: no event
-	}
-}
-
-func TestToolEventWriter_SubAgentSpawn(t *testing.T) {
-	ch := make(chan AgentEvent, 10)
-	w := &ToolEventWriter{events: ch}
-
-	w.Write([]byte("[agent] Spawning sub-agent: Fix the login bug\n"))
-
-	select {
-	case ev := <-ch:
-		msg, ok := ev.(SubAgentSpawnMsg)
-		if !ok {
-			t.Fatalf("expected SubAgentSpawnMsg, got %T", ev)
-		}
-		if msg.Task != "Fix the login bug" {
-			t.Fatalf("expected 'Fix the login bug', got %q", msg.Task)
-		}
-	case <-time.After(time.Second):
-		t.Fatal("timed out waiting for event")
-	}
-}
-
-func TestToolEventWriter_SubAgentDone(t *testing.T) {
-	ch := make(chan AgentEvent, 10)
-	w := &ToolEventWriter{events: ch}
-
-	w.Write([]byte("[agent] Sub-agent completed\n"))
+func TestBridge_Handle(t *testing.T) {
+	b := NewBridge()
+	b.Handle(agent.TurnDone{})
+	b.Handle(agent.ToolStart{Name: "read_file"})
 
 	select {
-	case ev := <-ch:
-		_, ok := ev.(SubAgentDoneMsg)
-		if !ok {
-			t.Fatalf("expected SubAgentDoneMsg, got %T", ev)
+	case ev := <-b.Events():
+		if msg, ok := ev.(ToolStartMsg); !ok || msg.Name != "read_file" {
+			t.Fatalf("expected ToolStartMsg for read_file, got %#v", ev)
 		}
 	case <-time.After(time.Second):
 		t.Fatal("timed out waiting for event")
 	}
-}
-
-func TestToolEventWriter_MultipleLines(t *testing.T) {
-	ch := make(chan AgentEvent, 10)
-	w := &ToolEventWriter{events: ch}
-
-	w.Write([]byte("[tool] read_file\n[tool] edit_file\n"))
-
-	events := make([]AgentEvent, 0, 2)
-	for i := 0; i < 2; i++ {
-		select {
-		case ev := <-ch:
-			events = append(events, ev)
-		case <-time.After(time.Second):
-			t.Fatalf("timed out waiting for event %d", i)
-		}
-	}
-
-	msg1, ok := events[0].(ToolStartMsg)
-	if !ok {
-		t.Fatalf("expected ToolStartMsg, got %T", events[0])
-	}
-	if msg1.Name != "read_file" {
-		t.Fatalf("expected 'read_file', got %q", msg1.Name)
-	}
-
-	msg2, ok := events[1].(ToolStartMsg)
-	if !ok {
-		t.Fatalf("expected ToolStartMsg, got %T", events[1])
-	}
-	if msg2.Name != "edit_file" {
-		t.Fatalf("expected 'edit_file', got %q", msg2.Name)
+	if len(b.Events()) != 0 {
+		t.Errorf("events not shown should not be sent, got %#v", <-b.Events())
 	}
 }
 
@@ -176,7 +68,7 @@ func TestPermissionInterceptor_Allow(t *testing.T) {
 
 	done := make(chan bool, 1)
 	go func() {
-		result := interceptor.Check("shell_exec", "Run: ls -la")
+		result := interceptor.Check(context.Background(), "shell_exec", "Run: ls -la")
 		done <- result
 	}()
 
@@ -218,7 +110,7 @@ func TestPermissionInterceptor_Deny(t *testing.T) {
 
 	done := make(chan bool, 1)
 	go func() {
-		result := interceptor.Check("write_file", "Write to /etc/passwd")
+		result := interceptor.Check(context.Background(), "write_file", "Write to /etc/passwd")
 		done <- result
 	}()
 
@@ -243,39 +135,12 @@ func TestPermissionInterceptor_Deny(t *testing.T) {
 func TestBridge(t *testing.T) {
 	b := NewBridge()
 
-	if b.Stdout() == nil {
-		t.Fatal("Stdout() should not be nil")
-	}
-	if b.Stderr() == nil {
-		t.Fatal("Stderr() should not be nil")
-	}
 	if b.Permission() == nil {
 		t.Fatal("Permission() should not be nil")
 	}
 	if b.Events() == nil {
 		t.Fatal("Events() should not be nil")
 	}
-
-	// Verify all components write to the same channel.
-	b.Stdout().Write([]byte("token"))
-	select {
-	case ev := <-b.Events():
-		if _, ok := ev.(TokenMsg); !ok {
-			t.Fatalf("expected TokenMsg from Stdout, got %T", ev)
-		}
-	case <-time.After(time.Second):
-		t.Fatal("timed out")
-	}
-
-	b.Stderr().Write([]byte("[tool] grep\n"))
-	select {
-	case ev := <-b.Events():
-		if _, ok := ev.(ToolStartMsg); !ok {
-			t.Fatalf("expected ToolStartMsg from Stderr, got %T", ev)
-		}
-	case <-time.After(time.Second):
-		t.Fatal("timed out")
-	}
 }
 
 func TestWaitForEvent(t *testing.T) {
@@ -312,3 +177,107 @@ func TestGenerateID(t *testing.T) {
 		t.Fatalf("expected unique IDs, got %q and %q", id1, id2)
 	}
 }
+
+// BenchmarkBridge_TokenThroughput measures tokens moving from the agent's
+// events to a consumer draining the events channel.
+func BenchmarkBridge_TokenThroughput(b *testing.B) {
+	bridge := NewBridge()
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < b.N; i++ {
+			<-bridge.Events()
+		}
+		close(done)
+	}()
+
+	token := agent.TokenDelta{Content: "word "}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bridge.Handle(token)
+	}
+	<-done
+}
+
+func TestBridge_ToolOutput(t *testing.T) {
+	b := NewBridge()
+	b.ToolOutput("read_file", []byte(`{"path":"a.go"}`), "package a")
+
+	select {
+	case ev := <-b.Events():
+		out, ok := ev.(ToolOutputMsg)
+		if !ok {
+			t.Fatalf("expected ToolOutputMsg, got %T", ev)
+		}
+		if out.Name != "read_file" || string(out.Args) != `{"path":"a.go"}` || out.Result != "package a" {
+			t.Fatalf("unexpected event %+v", out)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for event")
+	}
+}
+
+func TestPermissionInterceptor_ReviewHunks(t *testing.T) {
+	ch := make(chan AgentEvent, 1)
+	interceptor := NewPermissionInterceptor(ch)
+
+	done := make(chan []bool, 1)
+	go func() {
+		done <- interceptor.ReviewHunks(context.Background(), "edit_file", "main.go", []string{"@@ -1 +1 @@\n-a\n+b\n", "@@ -9 +9 @@\n-c\n+d\n"})
+	}()
+
+	for i, allow := range []bool{false, true} {
+		select {
+		case ev := <-ch:
+			msg := ev.(PermissionRequestMsg)
+			if msg.ToolName != "edit_file" || !strings.HasPrefix(msg.Preview, fmt.Sprintf("main.go, hunk %d of 2\n", i+1)) {
+				t.Fatalf("unexpected request %q: %q", msg.ToolName, msg.Preview)
+			}
+			msg.Response <- allow
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for permission request")
+		}
+	}
+
+	select {
+	case got := <-done:
+		if len(got) != 2 || got[0] || !got[1] {
+			t.Fatalf("expected [false true], got %v", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for ReviewHunks result")
+	}
+}
+
+func TestPermissionInterceptor_Cancelled(t *testing.T) {
+	ch := make(chan AgentEvent, 1)
+	interceptor := NewPermissionInterceptor(ch)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// The first hunk is accepted, then the agent is stopped while the
+	// second is asked about.
+	done := make(chan []bool, 1)
+	go func() {
+		done <- interceptor.ReviewHunks(ctx, "edit_file", "main.go", []string{"@@ -1 +1 @@\n-a\n+b\n", "@@ -9 +9 @@\n-c\n+d\n"})
+	}()
+	(<-ch).(PermissionRequestMsg).Response <- true
+	msg := (<-ch).(PermissionRequestMsg)
+	cancel()
+	select {
+	case got := <-done:
+		if len(got) != 2 || got[0] || got[1] {
+			t.Fatalf("expected every hunk rejected, got %v", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("ReviewHunks kept waiting after the context was cancelled")
+	}
+	if !msg.expired() {
+		t.Error("the request should report that nobody waits for it")
+	}
+
+	if interceptor.Check(ctx, "shell_exec", "Run: make") {
+		t.Error("Check should deny once the context is cancelled")
+	}
+	if d := interceptor.Decide(ctx, "shell_exec", "Run: make", "make"); d != permission.Deny {
+		t.Errorf("Decide = %v, want Deny", d)
+	}
+}
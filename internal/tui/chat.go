@@ -2,12 +2,18 @@ package tui
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/glamour"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/gavinyap/stormtrooper/internal/i18n"
+	"github.com/gavinyap/stormtrooper/internal/permission"
+	"github.com/gavinyap/stormtrooper/internal/llm"
 )
 
 // MessageRole identifies who authored a chat message.
@@ -25,29 +31,50 @@ type ChatMessage struct {
 	Role    MessageRole
 	Content string
 	Time    time.Time
+	// Raw shows an assistant message's markdown source instead of
+	// rendering it.
+	Raw bool
+	// Paths are the files a tool message touched or found.
+	Paths []fileRef
+	// Model names the model or models that wrote an assistant message,
+	// shown when the agent routes turns between models.
+	Model string
+	// Rule explains the automatic permission decision a tool message
+	// reports, shown on request.
+	Rule string
 }
 
+// defaultMarkdownStyle is the glamour style used when none is configured.
+const defaultMarkdownStyle = "dark"
+
 // ChatModel is the Bubble Tea model for the scrollable chat viewport.
 type ChatModel struct {
 	viewport   viewport.Model
 	messages   []ChatMessage
 	streaming  *strings.Builder // accumulates current assistant response tokens
+	models     []string         // models that wrote the current response, in order
 	theme      *Theme
 	width      int
 	height     int
 	autoScroll bool
 	renderer   *glamour.TermRenderer
+	style      string // glamour style name or JSON style file
+	wordWrap   int    // 0 fits the viewport
+	selected   int    // index of the selected message, or -1
+	xOffset    int    // horizontal scroll of the selected message
+	offsets    []int  // first viewport line of each message
+	linker     *Linker
 }
 
+// horizontalStep is how many columns ScrollLeft and ScrollRight move.
+const horizontalStep = 8
+
 // NewChatModel creates a ChatModel with the given theme.
 func NewChatModel(theme *Theme) ChatModel {
 	vp := viewport.New(0, 0)
 	vp.SetContent("")
 
-	r, _ := glamour.NewTermRenderer(
-		glamour.WithStylePath("dark"),
-		glamour.WithWordWrap(80),
-	)
+	r, _ := newRenderer(defaultMarkdownStyle, 80)
 
 	return ChatModel{
 		viewport:   vp,
@@ -55,7 +82,47 @@ func NewChatModel(theme *Theme) ChatModel {
 		streaming:  &strings.Builder{},
 		autoScroll: true,
 		renderer:   r,
+		style:      defaultMarkdownStyle,
+		selected:   -1,
+	}
+}
+
+// newRenderer creates a glamour renderer. style is a standard glamour
+// style name or the path of a JSON style file. Colors follow lipgloss's
+// profile, so --color=never reaches markdown too.
+func newRenderer(style string, wordWrap int) (*glamour.TermRenderer, error) {
+	return glamour.NewTermRenderer(
+		glamour.WithStylePath(style),
+		glamour.WithWordWrap(wordWrap),
+		glamour.WithColorProfile(lipgloss.ColorProfile()),
+	)
+}
+
+// SetMarkdownOptions changes the markdown style and word-wrap width. An
+// empty style means the default, and a width of 0 fits the viewport. If
+// the style cannot be loaded the current renderer is kept.
+func (m *ChatModel) SetMarkdownOptions(style string, wordWrap int) error {
+	if style == "" {
+		style = defaultMarkdownStyle
+	}
+	r, err := newRenderer(style, m.wrapWidth(wordWrap))
+	if err != nil {
+		return err
+	}
+	m.style, m.wordWrap, m.renderer = style, wordWrap, r
+	m.renderAll()
+	return nil
+}
+
+// wrapWidth returns the word-wrap width for markdown.
+func (m *ChatModel) wrapWidth(wordWrap int) int {
+	if wordWrap > 0 {
+		return wordWrap
+	}
+	if m.viewport.Width > 4 {
+		return m.viewport.Width - 4 // leave a small margin
 	}
+	return 80
 }
 
 // AddUserMessage appends a user message and re-renders the viewport.
@@ -78,6 +145,31 @@ func (m *ChatModel) AddSystemMessage(content string) {
 	m.renderAll()
 }
 
+// SetHistory replaces the chat with a conversation restored from a saved
+// session: the user's prompts, the assistant's replies, and a line for
+// each tool call. The system prompt and tool results are left out.
+func (m *ChatModel) SetHistory(history []llm.Message) {
+	m.messages = nil
+	m.streaming.Reset()
+	m.selected = -1
+	for _, msg := range history {
+		switch msg.Role {
+		case "user":
+			m.messages = append(m.messages, ChatMessage{Role: RoleUser, Content: msg.Content})
+		case "assistant":
+			if msg.Content != "" {
+				m.messages = append(m.messages, ChatMessage{Role: RoleAssistant, Content: msg.Content, Model: msg.Model})
+			}
+			for _, tc := range msg.ToolCalls {
+				m.messages = append(m.messages, ChatMessage{Role: RoleTool, Content: "> " + tc.Function.Name})
+			}
+		}
+	}
+	m.renderAll()
+	m.viewport.GotoBottom()
+	m.autoScroll = true
+}
+
 // SetSize updates the viewport dimensions and recreates the glamour renderer
 // with the new width. The viewport dimensions are reduced to account for the
 // border that wraps the chat panel (2 rows for top+bottom, 2 cols for
@@ -98,16 +190,166 @@ func (m *ChatModel) SetSize(w, h int) {
 	m.viewport.Width = innerW
 	m.viewport.Height = innerH
 
-	if innerW > 0 {
-		r, err := glamour.NewTermRenderer(
-			glamour.WithStylePath("dark"),
-			glamour.WithWordWrap(innerW-4), // leave a small margin
-		)
-		if err == nil {
-			m.renderer = r
+	if r, err := newRenderer(m.style, m.wrapWidth(m.wordWrap)); err == nil {
+		m.renderer = r
+	}
+	m.renderAll()
+}
+
+// selectable reports whether message i can be selected: assistant
+// messages, and tool messages that list files.
+func (m *ChatModel) selectable(i int) bool {
+	msg := m.messages[i]
+	return msg.Role == RoleAssistant || (msg.Role == RoleTool && (len(msg.Paths) > 0 || msg.Rule != ""))
+}
+
+// SelectPrev selects the message before the selected one, or the last
+// one if none is selected, and scrolls to it.
+func (m *ChatModel) SelectPrev() {
+	start := m.selected - 1
+	if m.selected < 0 {
+		start = len(m.messages) - 1
+	}
+	for i := start; i >= 0; i-- {
+		if m.selectable(i) {
+			m.selected, m.xOffset = i, 0
+			m.renderAll()
+			m.viewport.SetYOffset(m.offsets[i])
+			m.autoScroll = false
+			return
+		}
+	}
+}
+
+// SelectNext selects the message after the selected one.
+// Moving past the last one clears the selection and returns to the
+// bottom of the chat.
+func (m *ChatModel) SelectNext() {
+	if m.selected < 0 {
+		return
+	}
+	for i := m.selected + 1; i < len(m.messages); i++ {
+		if m.selectable(i) {
+			m.selected, m.xOffset = i, 0
+			m.renderAll()
+			m.viewport.SetYOffset(m.offsets[i])
+			return
 		}
 	}
+	m.selected, m.xOffset = -1, 0
 	m.renderAll()
+	m.viewport.GotoBottom()
+	m.autoScroll = true
+}
+
+// ScrollLeft scrolls the selected message's wide lines to the left.
+func (m *ChatModel) ScrollLeft() {
+	m.scrollSelected(-horizontalStep)
+}
+
+// ScrollRight scrolls the selected message's wide lines to the right.
+func (m *ChatModel) ScrollRight() {
+	m.scrollSelected(horizontalStep)
+}
+
+func (m *ChatModel) scrollSelected(n int) {
+	if m.selected < 0 {
+		return
+	}
+	y := m.viewport.YOffset
+	m.xOffset = max(m.xOffset+n, 0)
+	m.renderAll() // clamps xOffset to the message's width
+	m.viewport.SetYOffset(y)
+}
+
+// SelectedPath returns file n (counting from 1) of the selected tool
+// message.
+func (m *ChatModel) SelectedPath(n int) (fileRef, bool) {
+	if m.selected < 0 || m.messages[m.selected].Role != RoleTool {
+		return fileRef{}, false
+	}
+	paths := m.messages[m.selected].Paths
+	if n < 1 || n > len(paths) {
+		return fileRef{}, false
+	}
+	return paths[n-1], true
+}
+
+// SelectedTool reports whether a tool message is selected.
+func (m *ChatModel) SelectedTool() bool {
+	return m.selected >= 0 && m.messages[m.selected].Role == RoleTool
+}
+
+// Link returns reference n (counting from 1) of the selected message, or
+// of the last assistant message if none is selected.
+func (m *ChatModel) Link(n int) (string, bool) {
+	i := m.selected
+	if i < 0 {
+		for i = len(m.messages) - 1; i >= 0 && m.messages[i].Role != RoleAssistant; i-- {
+		}
+	}
+	if i < 0 {
+		return "", false
+	}
+	links := extractLinks(m.messages[i].Content)
+	if n < 1 || n > len(links) {
+		return "", false
+	}
+	return links[n-1], true
+}
+
+// Quotable returns the index of the selected message, or of the last
+// assistant message if none is selected, and the parts of it that can be
+// quoted in a reply. It reports false if that is not an answer.
+func (m *ChatModel) Quotable() (int, []string, bool) {
+	i := m.selected
+	if i < 0 {
+		for i = len(m.messages) - 1; i >= 0 && m.messages[i].Role != RoleAssistant; i-- {
+		}
+	}
+	if i < 0 || m.messages[i].Role != RoleAssistant {
+		return 0, nil, false
+	}
+	return i, quoteParts(m.messages[i].Content), true
+}
+
+// Rule returns the explanation of the selected automatic permission
+// decision, or of the latest one if none is selected.
+func (m *ChatModel) Rule() (string, bool) {
+	if m.selected >= 0 {
+		rule := m.messages[m.selected].Rule
+		return rule, rule != ""
+	}
+	for i := len(m.messages) - 1; i >= 0; i-- {
+		if m.messages[i].Rule != "" {
+			return m.messages[i].Rule, true
+		}
+	}
+	return "", false
+}
+
+// explainDecision describes the rule or policy behind an automatic
+// permission decision.
+func explainDecision(msg AutoDecisionMsg) string {
+	switch {
+	case !msg.Approved:
+		return i18n.T("chat.rule_policy", msg.Reason)
+	case msg.Rule == msg.Tool:
+		return i18n.T("chat.rule_tool", permission.RulesFile, msg.Tool)
+	default:
+		return i18n.T("chat.rule_command", permission.RulesFile, msg.Tool, msg.Rule)
+	}
+}
+
+// ToggleRaw switches the selected message between rendered markdown and
+// its source.
+func (m *ChatModel) ToggleRaw() {
+	if m.selected < 0 {
+		return
+	}
+	m.messages[m.selected].Raw = !m.messages[m.selected].Raw
+	m.renderAll()
+	m.viewport.SetYOffset(m.offsets[m.selected])
 }
 
 // Init returns nil; no initial commands are needed.
@@ -130,6 +372,12 @@ func (m ChatModel) Update(msg tea.Msg) (ChatModel, tea.Cmd) {
 			m.viewport.GotoBottom()
 		}
 
+	case ModelMsg:
+		if !slices.Contains(m.models, msg.Model) {
+			m.models = append(m.models, msg.Model)
+			m.renderAll()
+		}
+
 	case AgentDoneMsg:
 		// Finalize the streaming message.
 		if m.streaming.Len() > 0 {
@@ -137,9 +385,11 @@ func (m ChatModel) Update(msg tea.Msg) (ChatModel, tea.Cmd) {
 				Role:    RoleAssistant,
 				Content: m.streaming.String(),
 				Time:    time.Now(),
+				Model:   strings.Join(m.models, ", "),
 			})
 			m.streaming.Reset()
 		}
+		m.models = nil
 		m.renderAll()
 		if m.autoScroll {
 			m.viewport.GotoBottom()
@@ -156,12 +406,28 @@ func (m ChatModel) Update(msg tea.Msg) (ChatModel, tea.Cmd) {
 			m.viewport.GotoBottom()
 		}
 
+	case AutoDecisionMsg:
+		content := i18n.T("chat.auto_approved", msg.Tool, msg.Rule)
+		if !msg.Approved {
+			content = i18n.T("chat.auto_denied", msg.Tool, msg.Reason)
+		}
+		m.messages = append(m.messages, ChatMessage{
+			Role:    RoleTool,
+			Content: content,
+			Time:    time.Now(),
+			Rule:    explainDecision(msg),
+		})
+		m.renderAll()
+		if m.autoScroll {
+			m.viewport.GotoBottom()
+		}
+
 	case ToolResultMsg:
 		// Update the most recent tool message with the same name.
 		for i := len(m.messages) - 1; i >= 0; i-- {
 			if m.messages[i].Role == RoleTool && strings.HasPrefix(m.messages[i].Content, "> "+msg.Name) {
 				if msg.Error != "" {
-					m.messages[i].Content = fmt.Sprintf("> %s \u2717", msg.Name)
+					m.messages[i].Content = fmt.Sprintf("> %s \u2717 %s", msg.Name, msg.Error)
 				} else {
 					m.messages[i].Content = fmt.Sprintf("> %s \u2713", msg.Name)
 				}
@@ -173,8 +439,27 @@ func (m ChatModel) Update(msg tea.Msg) (ChatModel, tea.Cmd) {
 			m.viewport.GotoBottom()
 		}
 
+	case ToolOutputMsg:
+		for i := len(m.messages) - 1; i >= 0; i-- {
+			if m.messages[i].Role == RoleTool && strings.HasPrefix(m.messages[i].Content, "> "+msg.Name) {
+				m.messages[i].Paths = toolPaths(msg.Name, msg.Args, msg.Result)
+				break
+			}
+		}
+		m.renderAll()
+		if m.autoScroll {
+			m.viewport.GotoBottom()
+		}
+
 	case PermissionRequestMsg:
-		prompt := fmt.Sprintf("[PERMISSION] %s\n%s\n[y] allow  [n] deny", msg.ToolName, msg.Preview)
+		keys := i18n.T("permission.tui_keys")
+		switch {
+		case msg.Decision != nil && msg.Command != "":
+			keys = i18n.T("permission.tui_keys_command", msg.ToolName)
+		case msg.Decision != nil:
+			keys = i18n.T("permission.tui_keys_always", msg.ToolName)
+		}
+		prompt := fmt.Sprintf("%s %s\n%s\n%s", i18n.T("permission.tui_title"), msg.ToolName, msg.Preview, keys)
 		m.messages = append(m.messages, ChatMessage{
 			Role:    RoleSystem,
 			Content: prompt,
@@ -188,11 +473,16 @@ func (m ChatModel) Update(msg tea.Msg) (ChatModel, tea.Cmd) {
 	case PermissionResponseMsg:
 		// Update the last permission prompt to show the result.
 		for i := len(m.messages) - 1; i >= 0; i-- {
-			if m.messages[i].Role == RoleSystem && strings.HasPrefix(m.messages[i].Content, "[PERMISSION]") {
-				if msg.Allowed {
-					m.messages[i].Content += "\n-> Allowed"
-				} else {
-					m.messages[i].Content += "\n-> Denied"
+			if m.messages[i].Role == RoleSystem && strings.HasPrefix(m.messages[i].Content, i18n.T("permission.tui_title")) {
+				switch {
+				case msg.Decision == permission.AllowTool:
+					m.messages[i].Content += "\n" + i18n.T("permission.allowed_tool", msg.ToolName)
+				case msg.Decision == permission.AllowCommand:
+					m.messages[i].Content += "\n" + i18n.T("permission.allowed_command")
+				case msg.Allowed:
+					m.messages[i].Content += "\n" + i18n.T("permission.allowed")
+				default:
+					m.messages[i].Content += "\n" + i18n.T("permission.denied")
 				}
 				break
 			}
@@ -237,43 +527,115 @@ func (m ChatModel) View() string {
 // current streaming buffer.
 func (m *ChatModel) renderAll() {
 	var sections []string
-
-	for _, msg := range m.messages {
-		sections = append(sections, m.renderMessage(msg))
+	m.offsets = m.offsets[:0]
+	line := 0
+
+	for i, msg := range m.messages {
+		section := m.fit(m.renderMessage(msg, i == m.selected), i == m.selected)
+		sections = append(sections, section)
+		m.offsets = append(m.offsets, line)
+		line += strings.Count(section, "\n") + 2 // sections are separated by a blank line
 	}
 
 	// If we're currently streaming, render the partial assistant response.
 	if m.streaming.Len() > 0 {
-		prefix := m.theme.AssistantPrefix.Render("Assistant:")
-		content := m.renderMarkdown(m.streaming.String())
-		sections = append(sections, prefix+"\n"+content)
+		prefix := m.theme.AssistantPrefix.Render(i18n.T("chat.assistant"))
+		if len(m.models) > 0 {
+			prefix += " " + m.theme.ToolInline.Render(strings.Join(m.models, ", "))
+		}
+		content := m.renderMarkdown(closePartial(m.streaming.String()))
+		sections = append(sections, m.fit(prefix+"\n"+content, false))
 	}
 
 	full := strings.Join(sections, "\n\n")
 	m.viewport.SetContent(full)
 }
 
-// renderMessage renders a single ChatMessage according to its role.
-func (m *ChatModel) renderMessage(msg ChatMessage) string {
+// fit keeps a rendered message within the viewport width. Lines of the
+// selected message are shifted by the horizontal scroll offset, keeping
+// its first (prefix) line in place; other messages are truncated. Either
+// way a note below the message says how to see the rest.
+func (m *ChatModel) fit(section string, selected bool) string {
+	width := m.viewport.Width
+	lines := strings.Split(section, "\n")
+	widest := 0
+	for _, line := range lines {
+		widest = max(widest, ansi.StringWidth(line))
+	}
+	if width <= 1 || widest <= width {
+		if selected {
+			m.xOffset = 0
+		}
+		return section
+	}
+
+	var note string
+	if selected {
+		m.xOffset = min(m.xOffset, widest-width)
+		for i := 1; i < len(lines); i++ {
+			lines[i] = ansi.Cut(lines[i], m.xOffset, m.xOffset+width)
+		}
+		note = i18n.T("chat.wide_scroll", m.xOffset+1, min(m.xOffset+width, widest), widest)
+	} else {
+		for i, line := range lines {
+			lines[i] = ansi.Truncate(line, width, "…")
+		}
+		note = i18n.T("chat.wide")
+	}
+	return strings.Join(lines, "\n") + "\n" + m.theme.ToolInline.Render(ansi.Truncate(note, width, "…"))
+}
+
+// renderMessage renders a single ChatMessage according to its role,
+// marking it if it is selected.
+func (m *ChatModel) renderMessage(msg ChatMessage, selected bool) string {
 	switch msg.Role {
 	case RoleUser:
-		prefix := m.theme.UserPrefix.Render("You:")
-		content := m.theme.UserMessage.Render(msg.Content)
+		prefix := m.theme.UserPrefix.Render(i18n.T("chat.you"))
+		content := m.theme.UserMessage.Width(m.wrapWidth(0)).Render(msg.Content)
 		return prefix + "\n" + content
 
 	case RoleAssistant:
-		prefix := m.theme.AssistantPrefix.Render("Assistant:")
+		prefix := m.theme.AssistantPrefix.Render(i18n.T("chat.assistant"))
+		if selected {
+			prefix = m.theme.SelectedMarker.Render("▶ ") + prefix
+		}
+		if msg.Model != "" {
+			prefix += " " + m.theme.ToolInline.Render(msg.Model)
+		}
+		if msg.Raw {
+			prefix += " " + m.theme.ToolInline.Render(i18n.T("chat.raw"))
+			return prefix + "\n" + lipgloss.NewStyle().Width(m.wrapWidth(m.wordWrap)).Render(msg.Content)
+		}
 		content := m.renderMarkdown(msg.Content)
+		if links := extractLinks(msg.Content); len(links) > 0 {
+			content += "\n\n" + m.theme.ToolInline.Render(renderReferences(links))
+		}
 		return prefix + "\n" + content
 
 	case RoleTool:
-		return m.theme.ToolInline.Render("  " + msg.Content)
+		out := m.theme.ToolInline.Render("  " + msg.Content)
+		if selected {
+			out = m.theme.SelectedMarker.Render("▶ ") + m.theme.ToolInline.Render(msg.Content)
+		}
+		for i, ref := range msg.Paths {
+			if i == maxToolPaths {
+				out += "\n    " + m.theme.ToolInline.Render(i18n.T("chat.more_paths", len(msg.Paths)-maxToolPaths))
+				break
+			}
+			label := ref.String()
+			if selected {
+				label = fmt.Sprintf("[%d] %s", i+1, label)
+			}
+			out += "\n    " + m.linker.Link(m.theme.ToolInline.Render(label), ref)
+		}
+		return out
 
 	case RoleSystem:
-		// Permission prompts get the amber/yellow bordered box.
+		// Permission prompts get the amber/yellow bordered box, with
+		// any diff in them colored.
 		box := m.theme.PermissionBorder.
 			Width(m.width - 4).
-			Render(m.theme.PermissionText.Render(msg.Content))
+			Render(colorDiff(m.theme, msg.Content, m.theme.PermissionText.Render))
 		return box
 
 	default:
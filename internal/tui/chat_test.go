@@ -1,11 +1,16 @@
 package tui
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
 	"regexp"
 	"strings"
 	"testing"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/gavinyap/stormtrooper/internal/llm"
 )
 
 var ansiRe = regexp.MustCompile(`\x1b\[[0-9;]*m`)
@@ -40,6 +45,27 @@ func TestChatModel_AddUserMessage(t *testing.T) {
 	}
 }
 
+func TestChatModel_SetHistory(t *testing.T) {
+	m := newTestChatModel()
+	m.AddSystemMessage("left over")
+	m.SetHistory([]llm.Message{
+		{Role: "system", Content: "sys"},
+		{Role: "user", Content: "read the readme"},
+		{Role: "assistant", ToolCalls: []llm.ToolCall{{ID: "1", Function: llm.FunctionCall{Name: "read_file"}}}},
+		{Role: "tool", ToolCallID: "1", Content: "# Project"},
+		{Role: "assistant", Content: "It describes the project."},
+	})
+
+	var got []string
+	for _, msg := range m.messages {
+		got = append(got, fmt.Sprintf("%d:%s", msg.Role, msg.Content))
+	}
+	want := []string{"0:read the readme", "2:> read_file", "1:It describes the project."}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("messages = %q, want %q", got, want)
+	}
+}
+
 func TestChatModel_StreamTokens(t *testing.T) {
 	m := newTestChatModel()
 
@@ -89,6 +115,30 @@ func TestChatModel_AgentDone(t *testing.T) {
 	}
 }
 
+func TestChatModel_RoutedModels(t *testing.T) {
+	m := newTestChatModel()
+	m, _ = m.Update(ModelMsg{Model: "cheap"})
+	m, _ = m.Update(TokenMsg{Content: "Let me check."})
+	m, _ = m.Update(ModelMsg{Model: "main"})
+	m, _ = m.Update(ModelMsg{Model: "main"})
+	if view := stripANSI(m.View()); !strings.Contains(view, "cheap, main") {
+		t.Errorf("expected the models beside the streaming response, got:\n%s", view)
+	}
+
+	m, _ = m.Update(AgentDoneMsg{})
+	if m.messages[0].Model != "cheap, main" {
+		t.Errorf("Model = %q, want %q", m.messages[0].Model, "cheap, main")
+	}
+	if len(m.models) != 0 {
+		t.Errorf("models not reset after the turn: %v", m.models)
+	}
+
+	m.SetHistory([]llm.Message{{Role: "user", Content: "hi"}, {Role: "assistant", Content: "hello", Model: "cheap"}})
+	if m.messages[1].Model != "cheap" {
+		t.Errorf("restored Model = %q, want cheap", m.messages[1].Model)
+	}
+}
+
 func TestChatModel_AgentDone_EmptyStream(t *testing.T) {
 	m := newTestChatModel()
 
@@ -129,8 +179,8 @@ func TestChatModel_ToolError(t *testing.T) {
 	m, _ = m.Update(ToolStartMsg{ID: "1", Name: "shell_exec", Args: "ls"})
 	m, _ = m.Update(ToolResultMsg{ID: "1", Name: "shell_exec", Error: "exit 1"})
 
-	if !strings.Contains(m.messages[0].Content, "\u2717") {
-		t.Errorf("expected cross mark for errored tool, got %q", m.messages[0].Content)
+	if !strings.Contains(m.messages[0].Content, "\u2717 exit 1") {
+		t.Errorf("expected cross mark and error for errored tool, got %q", m.messages[0].Content)
 	}
 }
 
@@ -259,3 +309,177 @@ func TestChatModel_ViewBorderWrapped(t *testing.T) {
 		t.Error("expected chat view to be wrapped in a border")
 	}
 }
+
+// benchChatModel returns a ChatModel holding a realistic conversation of
+// n exchanges.
+func benchChatModel(n int) ChatModel {
+	m := newTestChatModel()
+	for i := 0; i < n; i++ {
+		m.messages = append(m.messages,
+			ChatMessage{Role: RoleUser, Content: "Explain the agent loop"},
+			ChatMessage{Role: RoleTool, Content: "> read_file ✓"},
+			ChatMessage{Role: RoleAssistant, Content: stressText(120)},
+		)
+	}
+	return m
+}
+
+func BenchmarkChatModel_RenderAll(b *testing.B) {
+	for _, n := range []int{1, 10, 50} {
+		b.Run(fmt.Sprintf("exchanges=%d", n), func(b *testing.B) {
+			m := benchChatModel(n)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				m.renderAll()
+			}
+		})
+	}
+}
+
+func BenchmarkChatModel_RenderMarkdown(b *testing.B) {
+	m := newTestChatModel()
+	text := stressText(500)
+	b.SetBytes(int64(len(text)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.renderMarkdown(text)
+	}
+}
+
+// BenchmarkChatModel_StreamToken measures one streamed token landing in a
+// chat that already holds a long partial response.
+func BenchmarkChatModel_StreamToken(b *testing.B) {
+	m := benchChatModel(10)
+	m.streaming.WriteString(stressText(300))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m, _ = m.Update(TokenMsg{Content: "word "})
+	}
+}
+
+func TestChatModel_MarkdownOptions(t *testing.T) {
+	m := newTestChatModel()
+	if err := m.SetMarkdownOptions("no-such-style", 0); err == nil {
+		t.Fatal("expected an error for an unknown style")
+	}
+	if m.style != defaultMarkdownStyle || m.renderer == nil {
+		t.Errorf("a failed style change should keep the renderer, style = %q", m.style)
+	}
+
+	style := filepath.Join(t.TempDir(), "style.json")
+	os.WriteFile(style, []byte(`{"document": {"margin": 0}}`), 0644)
+	if err := m.SetMarkdownOptions(style, 20); err != nil {
+		t.Fatalf("custom style: %v", err)
+	}
+	m.messages = append(m.messages, ChatMessage{Role: RoleAssistant, Content: strings.Repeat("word ", 10)})
+	m.renderAll()
+	for _, line := range strings.Split(stripANSI(m.renderMarkdown(m.messages[0].Content)), "\n") {
+		if len(strings.TrimRight(line, " ")) > 20 {
+			t.Errorf("line %q is wider than the configured wrap", line)
+		}
+	}
+}
+
+func TestChatModel_SelectAndToggleRaw(t *testing.T) {
+	m := newTestChatModel()
+	m.AddUserMessage("question")
+	for _, text := range []string{"first **answer**", "second **answer**"} {
+		m, _ = m.Update(TokenMsg{Content: text})
+		m, _ = m.Update(AgentDoneMsg{})
+	}
+
+	m.SelectPrev()
+	if m.selected != 2 {
+		t.Fatalf("SelectPrev with no selection should pick the last answer, got %d", m.selected)
+	}
+	m.SelectPrev()
+	m.SelectPrev() // no earlier assistant message; the selection stays
+	if m.selected != 1 {
+		t.Fatalf("selected = %d, want 1", m.selected)
+	}
+
+	m.ToggleRaw()
+	view := stripANSI(m.viewport.View())
+	if !strings.Contains(view, "first **answer**") || !strings.Contains(view, "(markdown source)") {
+		t.Errorf("expected the markdown source of the selected message, got:\n%s", view)
+	}
+	if m.messages[2].Raw {
+		t.Error("only the selected message should switch to raw")
+	}
+
+	m.SelectNext()
+	m.SelectNext()
+	if m.selected != -1 || !m.autoScroll {
+		t.Errorf("moving past the last answer should clear the selection, selected = %d", m.selected)
+	}
+}
+
+func TestChatModel_SelectToolPaths(t *testing.T) {
+	m := newTestChatModel()
+	m.messages = []ChatMessage{
+		{Role: RoleTool, Content: "grep", Paths: []fileRef{{Path: "a.go", Line: 3}, {Path: "b.go"}}},
+		{Role: RoleTool, Content: "shell_exec"},
+	}
+	m.renderAll()
+
+	m.SelectPrev()
+	if m.selected != 0 {
+		t.Fatalf("tool messages without files should be skipped, selected = %d", m.selected)
+	}
+	if ref, ok := m.SelectedPath(2); !ok || ref.Path != "b.go" {
+		t.Errorf("SelectedPath(2) = %+v, %v", ref, ok)
+	}
+	if _, ok := m.SelectedPath(3); ok {
+		t.Error("SelectedPath past the last file should fail")
+	}
+	if view := stripANSI(m.viewport.View()); !strings.Contains(view, "[1] a.go:3") {
+		t.Errorf("expected numbered files on the selected tool message, got:\n%s", view)
+	}
+}
+
+func TestChatModel_WideContent(t *testing.T) {
+	theme := DefaultTheme()
+	m := NewChatModel(&theme)
+	m.SetSize(60, 40) // inner width 58
+	wide := strings.Repeat("y", 150) + "END"
+	m.AddUserMessage(strings.Repeat("x", 200))
+	m.messages = append(m.messages, ChatMessage{Role: RoleAssistant, Content: "```\n" + wide + "\n```"})
+	m.renderAll()
+
+	checkWidth := func() string {
+		t.Helper()
+		view := m.viewport.View()
+		for _, line := range strings.Split(view, "\n") {
+			if w := ansi.StringWidth(line); w > m.viewport.Width {
+				t.Fatalf("line is %d columns wide, viewport is %d: %q", w, m.viewport.Width, stripANSI(line))
+			}
+		}
+		return stripANSI(view)
+	}
+	view := checkWidth()
+	if !strings.Contains(view, "wider than the chat") || strings.Contains(view, "END") {
+		t.Errorf("expected the wide message to be truncated with a note:\n%s", view)
+	}
+	if strings.Count(view, "x") != 200 {
+		t.Errorf("the long user message should wrap, not be cut")
+	}
+
+	m.SelectPrev()
+	for i := 0; i < 20; i++ {
+		m.ScrollRight()
+	}
+	view = checkWidth()
+	if !strings.Contains(view, "END") || !strings.Contains(view, "Assistant:") {
+		t.Errorf("scrolling right should reveal the end and keep the prefix:\n%s", view)
+	}
+	if !strings.Contains(view, "columns ") || !strings.Contains(view, "scroll with left/right") {
+		t.Errorf("expected a column position note:\n%s", view)
+	}
+
+	for i := 0; i < 20; i++ {
+		m.ScrollLeft()
+	}
+	if m.xOffset != 0 {
+		t.Errorf("xOffset = %d after scrolling back", m.xOffset)
+	}
+}
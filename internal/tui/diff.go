@@ -0,0 +1,34 @@
+package tui
+
+import "strings"
+
+// colorDiff styles the lines of text: those of a unified diff, from its
+// "---" and "+++" header on, by kind, and the others with plain, such as
+// a style's Render method.
+func colorDiff(theme *Theme, text string, plain func(...string) string) string {
+	lines := strings.Split(text, "\n")
+	inDiff := false
+	for i, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ "):
+			inDiff = true
+			lines[i] = plain(line)
+		case !inDiff, strings.HasPrefix(line, "+++ "):
+			lines[i] = plain(line)
+		case strings.HasPrefix(line, "+"):
+			lines[i] = theme.DiffAdd.Render(line)
+		case strings.HasPrefix(line, "-"):
+			lines[i] = theme.DiffDelete.Render(line)
+		case strings.HasPrefix(line, "@@"):
+			lines[i] = theme.DiffHunk.Render(line)
+		default:
+			lines[i] = plain(line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// unstyled is a plain style for colorDiff that leaves lines as they are.
+func unstyled(strs ...string) string {
+	return strings.Join(strs, " ")
+}
@@ -0,0 +1,35 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+func TestColorDiff(t *testing.T) {
+	mark := func(tag string) lipgloss.Style {
+		return lipgloss.NewStyle().Transform(func(s string) string { return tag + s })
+	}
+	theme := DefaultTheme()
+	theme.DiffAdd, theme.DiffDelete, theme.DiffHunk = mark("add:"), mark("del:"), mark("hunk:")
+	plain := mark("plain:")
+
+	text := "Edit main.go\n- not a diff line\n--- a/main.go\n+++ b/main.go\n@@ -1,2 +1,2 @@\n-old\n+new\n same"
+	want := []string{
+		"plain:Edit main.go",
+		"plain:- not a diff line",
+		"plain:--- a/main.go",
+		"plain:+++ b/main.go",
+		"hunk:@@ -1,2 +1,2 @@",
+		"del:-old",
+		"add:+new",
+		"plain: same",
+	}
+	if got := colorDiff(&theme, text, plain.Render); got != strings.Join(want, "\n") {
+		t.Errorf("colorDiff =\n%s\nwant\n%s", got, strings.Join(want, "\n"))
+	}
+	if got := colorDiff(&theme, "- a\n+ b", unstyled); got != "- a\n+ b" {
+		t.Errorf("text without a diff = %q, want it unchanged", got)
+	}
+}
@@ -0,0 +1,20 @@
+package tui
+
+import (
+	"os/exec"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ExecDoneMsg reports that a foreground program such as an editor exited.
+type ExecDoneMsg struct {
+	Err error
+}
+
+// runForeground suspends the TUI, runs cmd with the terminal, and restores
+// the TUI when it exits.
+func runForeground(cmd *exec.Cmd) tea.Cmd {
+	return tea.ExecProcess(cmd, func(err error) tea.Msg {
+		return ExecDoneMsg{Err: err}
+	})
+}
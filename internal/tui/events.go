@@ -1,5 +1,12 @@
 package tui
 
+import (
+	"encoding/json"
+
+	"github.com/gavinyap/stormtrooper/internal/config"
+	"github.com/gavinyap/stormtrooper/internal/permission"
+)
+
 // AgentEvent is the interface for all events sent from the agent bridge
 // to the Bubble Tea event loop. Each event type implements this with a
 // marker method.
@@ -24,7 +31,15 @@ type ToolResultMsg struct {
 	ID     string
 	Name   string
 	Result string // truncated for display
-	Error  string // non-empty if the tool errored
+	Error  string // if the tool errored, its error class and message
+}
+
+// ToolOutputMsg carries a finished tool call's arguments and result, so
+// the chat can show the files it touched.
+type ToolOutputMsg struct {
+	Name   string
+	Args   json.RawMessage
+	Result string
 }
 
 // PermissionRequestMsg asks the user to approve/deny a tool execution.
@@ -34,11 +49,41 @@ type PermissionRequestMsg struct {
 	ToolName string
 	Preview  string
 	Response chan<- bool // send true=allow, false=deny
+	// Decision, if set, is answered instead of Response, and the user
+	// may allow the tool, or Command if it is not empty, for good.
+	Decision chan<- permission.Decision
+	Command  string
+	// Done, if set, is closed once the call stops waiting for an answer,
+	// as when the agent is stopped; the prompt is not shown after that.
+	Done <-chan struct{}
+}
+
+// expired reports whether the call stopped waiting for an answer.
+func (m *PermissionRequestMsg) expired() bool {
+	select {
+	case <-m.Done:
+		return true
+	default:
+		return false
+	}
+}
+
+// respond answers the request with d, or with whether d allows the call
+// if the request cannot remember it.
+func (m *PermissionRequestMsg) respond(d permission.Decision) {
+	if m.Decision != nil {
+		m.Decision <- d
+		return
+	}
+	m.Response <- d != permission.Deny
 }
 
 // PermissionResponseMsg is sent by the TUI after the user responds to a permission prompt.
 type PermissionResponseMsg struct {
 	Allowed bool
+	// Decision says whether the approval is remembered, and what for.
+	Decision permission.Decision
+	ToolName string
 }
 
 // AgentDoneMsg signals that the agent has finished processing the user's message.
@@ -54,12 +99,67 @@ type SubAgentSpawnMsg struct {
 // SubAgentDoneMsg signals that a sub-agent has completed.
 type SubAgentDoneMsg struct{}
 
+// SubAgentProgressMsg is progress from one of several sub-agents
+// running in parallel, numbered from 1.
+type SubAgentProgressMsg struct {
+	Agent int
+	Msg   AgentEvent // such as ToolStartMsg for a tool the sub-agent runs
+}
+
+// AutoDecisionMsg reports a permission decision made without asking: a
+// call allowed by a remembered rule, or forbidden by the organization
+// policy.
+type AutoDecisionMsg struct {
+	Tool     string
+	Approved bool
+	Rule     string // the remembered approval that allowed the call
+	Reason   string // why the policy forbade the call
+}
+
+// WarningMsg carries a warning from the agent, such as a response cut off
+// by the length limit.
+type WarningMsg struct {
+	Text string
+}
+
+// CompactMsg reports that the agent summarized older turns to keep the
+// conversation inside the context window.
+type CompactMsg struct {
+	Text string
+}
+
+// ModelMsg reports the model that writes the next part of the response,
+// when the agent routes turns between models.
+type ModelMsg struct {
+	Model string
+}
+
+// ConfigReloadMsg is sent into the program when the config files change
+// on disk. It is not an AgentEvent: it comes from the config watcher, not
+// the agent bridge.
+type ConfigReloadMsg struct {
+	Config *config.Config
+	Err    error
+}
+
+// NoticeMsg is sent into the program to show a system message that does
+// not come from the agent, such as a message injected by another program.
+type NoticeMsg struct {
+	Text string
+}
+
 // agentEvent marker implementations.
 func (TokenMsg) agentEvent()              {}
 func (ToolStartMsg) agentEvent()          {}
 func (ToolResultMsg) agentEvent()         {}
+func (ToolOutputMsg) agentEvent()         {}
 func (PermissionRequestMsg) agentEvent()  {}
 func (PermissionResponseMsg) agentEvent() {}
 func (AgentDoneMsg) agentEvent()          {}
 func (SubAgentSpawnMsg) agentEvent()      {}
 func (SubAgentDoneMsg) agentEvent()       {}
+func (SubAgentProgressMsg) agentEvent()   {}
+func (AutoDecisionMsg) agentEvent()       {}
+func (WarningMsg) agentEvent()            {}
+func (CompactMsg) agentEvent()            {}
+func (ModelMsg) agentEvent()              {}
@@ -0,0 +1,244 @@
+package tui
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/gavinyap/stormtrooper/internal/command"
+	"github.com/gavinyap/stormtrooper/internal/i18n"
+)
+
+// formField is one property of a form's schema.
+type formField struct {
+	name     string
+	typ      string // JSON schema type; empty is treated as string
+	desc     string
+	required bool
+	input    textinput.Model
+}
+
+// FormModel collects a command's arguments with one text field per
+// property of its JSON schema, such as a tool's parameters for /run-tool.
+type FormModel struct {
+	theme  *Theme
+	form   *command.Form
+	fields []formField
+	cur    int
+	err    string
+	width  int
+	height int
+}
+
+// FormDoneMsg carries the output of a submitted form.
+type FormDoneMsg struct {
+	Output string
+	Err    error
+}
+
+// NewFormModel builds a form from f's schema. Fields follow the order of
+// the schema's properties.
+func NewFormModel(theme *Theme, f *command.Form) (FormModel, error) {
+	var schema struct {
+		Properties json.RawMessage `json:"properties"`
+		Required   []string        `json:"required"`
+	}
+	if err := json.Unmarshal(f.Schema, &schema); err != nil {
+		return FormModel{}, fmt.Errorf("invalid schema for %s: %w", f.Title, err)
+	}
+	names, props, err := orderedProperties(schema.Properties)
+	if err != nil {
+		return FormModel{}, fmt.Errorf("invalid schema for %s: %w", f.Title, err)
+	}
+
+	m := FormModel{theme: theme, form: f}
+	for _, name := range names {
+		var prop struct {
+			Type        string `json:"type"`
+			Description string `json:"description"`
+		}
+		json.Unmarshal(props[name], &prop)
+		in := textinput.New()
+		in.Prompt = ""
+		in.Placeholder = prop.Type
+		field := formField{name: name, typ: prop.Type, desc: prop.Description, input: in}
+		for _, r := range schema.Required {
+			field.required = field.required || r == name
+		}
+		m.fields = append(m.fields, field)
+	}
+	m.focus(0)
+	return m, nil
+}
+
+// orderedProperties decodes a JSON object, keeping the order of its keys.
+func orderedProperties(raw json.RawMessage) ([]string, map[string]json.RawMessage, error) {
+	props := map[string]json.RawMessage{}
+	if len(raw) == 0 {
+		return nil, props, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, nil, fmt.Errorf("properties must be an object")
+	}
+	var names []string
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, nil, err
+		}
+		name, _ := tok.(string)
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, nil, err
+		}
+		names = append(names, name)
+		props[name] = value
+	}
+	return names, props, nil
+}
+
+// SetSize sets the dimensions, including the border.
+func (m *FormModel) SetSize(w, h int) {
+	m.width, m.height = w, h
+	for i := range m.fields {
+		m.fields[i].input.Width = max(w-6, 10)
+	}
+}
+
+// NextField moves to the next field, wrapping around.
+func (m *FormModel) NextField() {
+	if len(m.fields) > 0 {
+		m.focus((m.cur + 1) % len(m.fields))
+	}
+}
+
+// PrevField moves to the previous field, wrapping around.
+func (m *FormModel) PrevField() {
+	if len(m.fields) > 0 {
+		m.focus((m.cur + len(m.fields) - 1) % len(m.fields))
+	}
+}
+
+func (m *FormModel) focus(i int) {
+	for j := range m.fields {
+		m.fields[j].input.Blur()
+	}
+	m.cur = i
+	if i < len(m.fields) {
+		m.fields[i].input.Focus()
+	}
+}
+
+// Args converts the fields to a JSON object, parsing each by its schema
+// type. Empty fields are left out.
+func (m *FormModel) Args() (json.RawMessage, error) {
+	args := map[string]any{}
+	for _, f := range m.fields {
+		text := strings.TrimSpace(f.input.Value())
+		if text == "" {
+			if f.required {
+				return nil, fmt.Errorf("%s is required", f.name)
+			}
+			continue
+		}
+		v, err := parseField(f.typ, text)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.name, err)
+		}
+		args[f.name] = v
+	}
+	return json.Marshal(args)
+}
+
+// parseField converts text to a value of the JSON schema type typ. Arrays
+// may be JSON or comma-separated strings.
+func parseField(typ, text string) (any, error) {
+	switch typ {
+	case "integer":
+		return strconv.Atoi(text)
+	case "number":
+		return strconv.ParseFloat(text, 64)
+	case "boolean":
+		return strconv.ParseBool(text)
+	case "array":
+		if !strings.HasPrefix(text, "[") {
+			parts := strings.Split(text, ",")
+			for i := range parts {
+				parts[i] = strings.TrimSpace(parts[i])
+			}
+			return parts, nil
+		}
+		fallthrough
+	case "object":
+		var v any
+		if err := json.Unmarshal([]byte(text), &v); err != nil {
+			return nil, fmt.Errorf("not valid JSON: %w", err)
+		}
+		return v, nil
+	}
+	return text, nil
+}
+
+// Submit collects the arguments and returns a command that calls the
+// form's Submit with them. On invalid input it shows the error and
+// returns nil, leaving the form open.
+func (m *FormModel) Submit() tea.Cmd {
+	args, err := m.Args()
+	if err != nil {
+		m.err = err.Error()
+		return nil
+	}
+	submit := m.form.Submit
+	return func() tea.Msg {
+		out, err := submit(context.Background(), args)
+		return FormDoneMsg{Output: out, Err: err}
+	}
+}
+
+// Update forwards keys to the current field.
+func (m FormModel) Update(msg tea.Msg) (FormModel, tea.Cmd) {
+	if m.cur >= len(m.fields) {
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.fields[m.cur].input, cmd = m.fields[m.cur].input.Update(msg)
+	return m, cmd
+}
+
+// View renders the fields with their descriptions and the key help.
+func (m FormModel) View() string {
+	var b strings.Builder
+	b.WriteString(m.theme.SidebarHeading.Render(i18n.T("form.title", m.form.Title)) + "\n\n")
+	if len(m.fields) == 0 {
+		b.WriteString(m.theme.ToolInline.Render(i18n.T("form.no_fields")) + "\n")
+	}
+	for i, f := range m.fields {
+		prefix := "  "
+		if i == m.cur {
+			prefix = m.theme.SelectedMarker.Render("▶ ")
+		}
+		label := f.name
+		if f.required {
+			label += " *"
+		}
+		b.WriteString(prefix + label + "\n")
+		if f.desc != "" {
+			b.WriteString("  " + m.theme.ToolInline.Render(f.desc) + "\n")
+		}
+		b.WriteString("  " + f.input.View() + "\n\n")
+	}
+	if m.err != "" {
+		b.WriteString(m.theme.DiffDelete.Render(m.err) + "\n")
+	}
+	b.WriteString(m.theme.ToolInline.Render(i18n.T("form.help")))
+	return m.theme.ChatBorder.
+		Width(m.width).
+		Height(m.height).
+		Render(b.String())
+}
@@ -0,0 +1,94 @@
+package tui
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/gavinyap/stormtrooper/internal/command"
+)
+
+const formSchema = `{
+	"type": "object",
+	"properties": {
+		"pattern": {"type": "string", "description": "Regex to search for"},
+		"max_results": {"type": "integer"},
+		"paths": {"type": "array"},
+		"ignore_case": {"type": "boolean"}
+	},
+	"required": ["pattern"]
+}`
+
+func typeInto(m *FormModel, text string) {
+	for _, r := range text {
+		*m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+}
+
+func TestFormModel_Args(t *testing.T) {
+	theme := DefaultTheme()
+	var submitted json.RawMessage
+	f := &command.Form{Title: "grep", Schema: json.RawMessage(formSchema), Submit: func(_ context.Context, args json.RawMessage) (string, error) {
+		submitted = args
+		return "ok", nil
+	}}
+	m, err := NewFormModel(&theme, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, field := range m.fields {
+		names = append(names, field.name)
+	}
+	if strings.Join(names, ",") != "pattern,max_results,paths,ignore_case" {
+		t.Fatalf("fields = %v, want schema order", names)
+	}
+
+	if m.Submit() != nil || !strings.Contains(m.err, "pattern is required") {
+		t.Fatalf("expected a required-field error, got %q", m.err)
+	}
+
+	typeInto(&m, "TODO")
+	m.NextField()
+	typeInto(&m, "x")
+	if m.Submit() != nil || !strings.Contains(m.err, "max_results") {
+		t.Fatalf("expected an integer error, got %q", m.err)
+	}
+	m.fields[1].input.SetValue("5")
+	m.NextField()
+	typeInto(&m, "cmd, internal")
+	m.PrevField()
+	m.PrevField()
+	if m.cur != 0 {
+		t.Fatalf("cur = %d after moving back, want 0", m.cur)
+	}
+
+	cmd := m.Submit()
+	if cmd == nil {
+		t.Fatalf("Submit failed: %s", m.err)
+	}
+	if done := cmd().(FormDoneMsg); done.Output != "ok" || done.Err != nil {
+		t.Errorf("FormDoneMsg = %+v", done)
+	}
+	var got map[string]any
+	json.Unmarshal(submitted, &got)
+	if got["pattern"] != "TODO" || got["max_results"] != float64(5) || len(got["paths"].([]any)) != 2 {
+		t.Errorf("submitted %s", submitted)
+	}
+	if _, ok := got["ignore_case"]; ok {
+		t.Error("empty optional fields should be left out")
+	}
+	if !strings.Contains(m.View(), "Run grep") {
+		t.Error("expected the title in the view")
+	}
+}
+
+func TestNewFormModel_InvalidSchema(t *testing.T) {
+	theme := DefaultTheme()
+	if _, err := NewFormModel(&theme, &command.Form{Title: "bad", Schema: json.RawMessage(`{"properties": []}`)}); err == nil {
+		t.Error("expected an error for non-object properties")
+	}
+}
@@ -0,0 +1,142 @@
+package tui
+
+import (
+	"encoding/json"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/x/ansi"
+	"github.com/gavinyap/stormtrooper/internal/config"
+)
+
+// defaultLinkURL opens files with the system's file handler.
+const defaultLinkURL = "file://{path}"
+
+// maxToolPaths caps the file paths listed under a tool message.
+const maxToolPaths = 10
+
+// fileRef is a file path, and optionally a line, mentioned by a tool.
+type fileRef struct {
+	Path string
+	Line int // 0 if unknown
+}
+
+// String formats the reference as path or path:line.
+func (r fileRef) String() string {
+	if r.Line > 0 {
+		return r.Path + ":" + strconv.Itoa(r.Line)
+	}
+	return r.Path
+}
+
+// Linker turns file paths into OSC 8 hyperlinks, so terminals that
+// support them can open the file (or jump to it in an editor) on click.
+// A nil or disabled Linker leaves text unchanged.
+type Linker struct {
+	enabled  bool
+	template string
+	dir      string
+}
+
+// NewLinker creates a Linker from the hyperlinks config. Relative paths
+// resolve against dir. With enabled "auto", links are only emitted when
+// the environment identifies a terminal known to support them.
+func NewLinker(cfg config.HyperlinkConfig, dir string, getenv func(string) string) *Linker {
+	enabled := false
+	switch cfg.Enabled {
+	case "always":
+		enabled = true
+	case "", "auto":
+		enabled = supportsHyperlinks(getenv)
+	}
+	template := cfg.URL
+	if template == "" {
+		template = defaultLinkURL
+	}
+	return &Linker{enabled: enabled, template: template, dir: dir}
+}
+
+// supportsHyperlinks reports whether the terminal is known to render OSC 8
+// hyperlinks. Unknown terminals are assumed not to, since some print the
+// escape sequence as garbage.
+func supportsHyperlinks(getenv func(string) string) bool {
+	switch getenv("TERM_PROGRAM") {
+	case "iTerm.app", "WezTerm", "vscode", "ghostty", "Hyper":
+		return true
+	}
+	for _, v := range []string{"KITTY_WINDOW_ID", "WT_SESSION", "KONSOLE_VERSION"} {
+		if getenv(v) != "" {
+			return true
+		}
+	}
+	if vte, err := strconv.Atoi(getenv("VTE_VERSION")); err == nil && vte >= 5000 {
+		return true
+	}
+	term := getenv("TERM")
+	return strings.Contains(term, "kitty") || strings.Contains(term, "alacritty") || strings.HasPrefix(term, "foot")
+}
+
+// URL returns the link target for a file reference.
+func (l *Linker) URL(ref fileRef) string {
+	path := ref.Path
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(l.dir, path)
+	}
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path // Windows drive letters
+	}
+	path = (&url.URL{Path: path}).EscapedPath()
+	return strings.NewReplacer("{path}", path, "{line}", strconv.Itoa(max(ref.Line, 1))).Replace(l.template)
+}
+
+// Link wraps text in a hyperlink to ref.
+func (l *Linker) Link(text string, ref fileRef) string {
+	if l == nil || !l.enabled {
+		return text
+	}
+	return ansi.SetHyperlink(l.URL(ref)) + text + ansi.ResetHyperlink()
+}
+
+// toolPaths extracts the files a tool call touched or found: the path
+// argument of file tools, the matches of grep, and the results of glob.
+func toolPaths(name string, args json.RawMessage, result string) []fileRef {
+	if strings.HasPrefix(result, "Error:") {
+		return nil
+	}
+	var refs []fileRef
+	switch name {
+	case "grep":
+		for _, line := range strings.Split(result, "\n") {
+			parts := strings.SplitN(line, ":", 3)
+			if len(parts) < 3 {
+				break // "No matches" or the truncation note
+			}
+			n, err := strconv.Atoi(parts[1])
+			if err != nil {
+				break
+			}
+			refs = append(refs, fileRef{Path: parts[0], Line: n})
+		}
+	case "glob":
+		if strings.HasPrefix(result, "No files matched") {
+			return nil
+		}
+		for _, line := range strings.Split(result, "\n") {
+			if line == "" {
+				break // the truncation note follows a blank line
+			}
+			refs = append(refs, fileRef{Path: line})
+		}
+	default:
+		var p struct {
+			Path string `json:"path"`
+		}
+		if json.Unmarshal(args, &p) == nil && p.Path != "" {
+			refs = append(refs, fileRef{Path: p.Path})
+		}
+	}
+	return refs
+}
@@ -0,0 +1,112 @@
+package tui
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gavinyap/stormtrooper/internal/config"
+)
+
+func envOf(vars map[string]string) func(string) string {
+	return func(k string) string { return vars[k] }
+}
+
+func TestNewLinker(t *testing.T) {
+	tests := []struct {
+		enabled string
+		env     map[string]string
+		want    bool
+	}{
+		{"", map[string]string{"TERM_PROGRAM": "iTerm.app"}, true},
+		{"auto", map[string]string{"VTE_VERSION": "6003"}, true},
+		{"auto", map[string]string{"VTE_VERSION": "4200"}, false},
+		{"auto", map[string]string{"TERM": "xterm-kitty"}, true},
+		{"auto", map[string]string{"TERM": "xterm-256color"}, false},
+		{"always", nil, true},
+		{"never", map[string]string{"WT_SESSION": "1"}, false},
+	}
+	for _, tt := range tests {
+		l := NewLinker(config.HyperlinkConfig{Enabled: tt.enabled}, "/work", envOf(tt.env))
+		if l.enabled != tt.want {
+			t.Errorf("enabled=%q env=%v: got %v, want %v", tt.enabled, tt.env, l.enabled, tt.want)
+		}
+	}
+}
+
+func TestLinkerURL(t *testing.T) {
+	l := NewLinker(config.HyperlinkConfig{Enabled: "always"}, "/work/my project", envOf(nil))
+	if got := l.URL(fileRef{Path: "src/main.go", Line: 12}); got != "file:///work/my%20project/src/main.go" {
+		t.Errorf("file URL = %q", got)
+	}
+
+	l = NewLinker(config.HyperlinkConfig{Enabled: "always", URL: "vscode://file/{path}:{line}"}, "/work", envOf(nil))
+	if got := l.URL(fileRef{Path: "/etc/hosts"}); got != "vscode://file//etc/hosts:1" {
+		t.Errorf("editor URL = %q", got)
+	}
+	if got := l.URL(fileRef{Path: "a.go", Line: 7}); got != "vscode://file//work/a.go:7" {
+		t.Errorf("editor URL with line = %q", got)
+	}
+
+	linked := l.Link("a.go", fileRef{Path: "a.go"})
+	if !strings.HasPrefix(linked, "\x1b]8;;vscode://file//work/a.go:1") || !strings.Contains(linked, "a.go\x1b]8;;") {
+		t.Errorf("Link = %q", linked)
+	}
+	var disabled *Linker
+	if got := disabled.Link("a.go", fileRef{Path: "a.go"}); got != "a.go" {
+		t.Errorf("a nil linker should leave text alone, got %q", got)
+	}
+}
+
+func TestToolPaths(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   string
+		result string
+		want   []fileRef
+	}{
+		{"read_file", `{"path": "main.go"}`, "package main", []fileRef{{Path: "main.go"}}},
+		{"read_file", `{"path": "gone.go"}`, "Error: no such file", nil},
+		{"shell_exec", `{"command": "ls"}`, "main.go", nil},
+		{"grep", `{"pattern": "x"}`, "a.go:3:x := 1\nb/c.go:10:y := x\n\n[truncated — showing first 100 matches]", []fileRef{{Path: "a.go", Line: 3}, {Path: "b/c.go", Line: 10}}},
+		{"grep", `{"pattern": "x"}`, "No matches found for pattern: x", nil},
+		{"glob", `{"pattern": "*.go"}`, "a.go\nb.go", []fileRef{{Path: "a.go"}, {Path: "b.go"}}},
+		{"glob", `{"pattern": "*.rs"}`, "No files matched the pattern: *.rs", nil},
+	}
+	for _, tt := range tests {
+		if got := toolPaths(tt.name, json.RawMessage(tt.args), tt.result); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("toolPaths(%s, %s) = %+v, want %+v", tt.name, tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestChatModel_ToolPathsLinked(t *testing.T) {
+	m := newTestChatModel()
+	m.linker = NewLinker(config.HyperlinkConfig{Enabled: "always"}, "/work", envOf(nil))
+	m, _ = m.Update(ToolStartMsg{Name: "grep"})
+	m, _ = m.Update(ToolResultMsg{Name: "grep"})
+	m, _ = m.Update(ToolOutputMsg{Name: "grep", Result: "a.go:3:x"})
+
+	view := m.viewport.View()
+	if !strings.Contains(view, "\x1b]8;;file:///work/a.go") {
+		t.Errorf("expected an OSC 8 link in the tool message:\n%q", view)
+	}
+	if !strings.Contains(stripOSC(stripANSI(view)), "a.go:3") {
+		t.Errorf("expected the grep hit under the tool message:\n%s", stripANSI(view))
+	}
+}
+
+func stripOSC(s string) string {
+	for {
+		i := strings.Index(s, "\x1b]8;;")
+		if i < 0 {
+			return s
+		}
+		j := strings.Index(s[i:], "\x1b\\")
+		if j < 0 {
+			return s
+		}
+		s = s[:i] + s[i+j+2:]
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/textarea"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/gavinyap/stormtrooper/internal/i18n"
 )
 
 // SendMsg is emitted when the user presses Enter with non-empty input.
@@ -28,7 +29,7 @@ type InputModel struct {
 // NewInputModel creates an InputModel with configured textarea defaults.
 func NewInputModel(theme *Theme, keymap *KeyMap) InputModel {
 	ta := textarea.New()
-	ta.Placeholder = "Type a message... (Enter to send, Ctrl+J for newline)"
+	ta.Placeholder = i18n.T("input.placeholder")
 	ta.ShowLineNumbers = false
 	ta.CharLimit = 10000
 	ta.SetHeight(3)
@@ -103,7 +104,7 @@ func (m InputModel) View() string {
 	if m.disabled {
 		return m.theme.InputBorder.
 			Width(m.width).
-			Render(m.spinner.View() + " Thinking...")
+			Render(m.spinner.View() + " " + i18n.T("input.thinking"))
 	}
 	return m.textarea.View()
 }
@@ -123,6 +124,16 @@ func (m *InputModel) Blur() {
 	m.textarea.Blur()
 }
 
+// Value returns the text typed so far.
+func (m *InputModel) Value() string {
+	return m.textarea.Value()
+}
+
+// SetValue replaces the text typed so far, leaving the cursor at its end.
+func (m *InputModel) SetValue(s string) {
+	m.textarea.SetValue(s)
+}
+
 // SetWidth updates the input width.
 func (m *InputModel) SetWidth(w int) {
 	m.width = w
@@ -176,7 +176,7 @@ func TestIntegration_UserMessageAppears(t *testing.T) {
 
 // newStreamingIntegrationApp creates an App backed by a mock SSE server that
 // streams the given tokens as individual SSE data lines. This exercises the
-// full path: agent -> LLM client -> SSE parser -> EventWriter -> bridge ->
+// full path: agent -> LLM client -> SSE parser -> agent events -> bridge ->
 // TUI Update loop.
 func newStreamingIntegrationApp(t *testing.T, tokens []string) *App {
 	t.Helper()
@@ -13,8 +13,24 @@ type KeyMap struct {
 	Quit       key.Binding // Ctrl+C
 	PermAllow  key.Binding // y -- allow permission
 	PermDeny   key.Binding // n -- deny permission
+	PermAlways    key.Binding // a -- always allow the tool
+	PermCommand   key.Binding // c -- always allow the exact command
 	Tab           key.Binding // Tab -- toggle focus
 	ToggleSidebar key.Binding // Ctrl+B -- toggle sidebar
+	PrevMessage   key.Binding // [ -- select previous message in chat focus
+	NextMessage   key.Binding // ] -- select next message in chat focus
+	ToggleRaw     key.Binding // r -- show the selected message's markdown source
+	ScrollLeft    key.Binding // Left/h -- scroll the selected message left
+	ScrollRight   key.Binding // Right/l -- scroll the selected message right
+	OpenLink      key.Binding // 1-9 -- open reference N of the selected message
+	OpenEditor    key.Binding // e -- open the selected tool message's file in the editor
+	Review        key.Binding // Ctrl+R -- review staged file changes
+	Apply         key.Binding // a -- apply staged changes on the review screen
+	Discard       key.Binding // d -- discard staged changes on the review screen
+	PrevField     key.Binding // Shift+Tab -- previous field in an argument form
+	Reply         key.Binding // > -- quote the selected answer, then each code block, in the next message
+	ShowRule      key.Binding // w -- show the rule behind an automatic permission decision
+	Stop          key.Binding // Ctrl+X -- stop the running agent
 }
 
 // DefaultKeyMap returns the default key bindings.
@@ -56,6 +72,14 @@ func DefaultKeyMap() KeyMap {
 			key.WithKeys("n"),
 			key.WithHelp("n", "deny"),
 		),
+		PermAlways: key.NewBinding(
+			key.WithKeys("a"),
+			key.WithHelp("a", "always allow the tool"),
+		),
+		PermCommand: key.NewBinding(
+			key.WithKeys("c"),
+			key.WithHelp("c", "always allow the command"),
+		),
 		Tab: key.NewBinding(
 			key.WithKeys("tab"),
 			key.WithHelp("tab", "toggle focus"),
@@ -64,5 +88,61 @@ func DefaultKeyMap() KeyMap {
 			key.WithKeys("ctrl+b"),
 			key.WithHelp("ctrl+b", "toggle sidebar"),
 		),
+		PrevMessage: key.NewBinding(
+			key.WithKeys("["),
+			key.WithHelp("[", "previous message"),
+		),
+		NextMessage: key.NewBinding(
+			key.WithKeys("]"),
+			key.WithHelp("]", "next message"),
+		),
+		ToggleRaw: key.NewBinding(
+			key.WithKeys("r"),
+			key.WithHelp("r", "toggle markdown source"),
+		),
+		ScrollLeft: key.NewBinding(
+			key.WithKeys("left", "h"),
+			key.WithHelp("left/h", "scroll message left"),
+		),
+		ScrollRight: key.NewBinding(
+			key.WithKeys("right", "l"),
+			key.WithHelp("right/l", "scroll message right"),
+		),
+		OpenLink: key.NewBinding(
+			key.WithKeys("1", "2", "3", "4", "5", "6", "7", "8", "9"),
+			key.WithHelp("1-9", "open reference"),
+		),
+		OpenEditor: key.NewBinding(
+			key.WithKeys("e"),
+			key.WithHelp("e", "open in editor"),
+		),
+		Review: key.NewBinding(
+			key.WithKeys("ctrl+r"),
+			key.WithHelp("ctrl+r", "review staged changes"),
+		),
+		Apply: key.NewBinding(
+			key.WithKeys("a"),
+			key.WithHelp("a", "apply all"),
+		),
+		Discard: key.NewBinding(
+			key.WithKeys("d"),
+			key.WithHelp("d", "discard all"),
+		),
+		PrevField: key.NewBinding(
+			key.WithKeys("shift+tab"),
+			key.WithHelp("shift+tab", "previous field"),
+		),
+		Reply: key.NewBinding(
+			key.WithKeys(">"),
+			key.WithHelp(">", "reply to message"),
+		),
+		ShowRule: key.NewBinding(
+			key.WithKeys("w"),
+			key.WithHelp("w", "why was this allowed"),
+		),
+		Stop: key.NewBinding(
+			key.WithKeys("ctrl+x"),
+			key.WithHelp("ctrl+x", "stop the agent"),
+		),
 	}
 }
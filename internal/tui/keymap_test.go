@@ -2,6 +2,8 @@ package tui
 
 import (
 	"testing"
+
+	"github.com/charmbracelet/bubbles/key"
 )
 
 func TestDefaultKeyMap(t *testing.T) {
@@ -21,7 +23,23 @@ func TestDefaultKeyMap(t *testing.T) {
 		{"Quit", []string{"ctrl+c"}, func() []string { return km.Quit.Keys() }},
 		{"PermAllow", []string{"y"}, func() []string { return km.PermAllow.Keys() }},
 		{"PermDeny", []string{"n"}, func() []string { return km.PermDeny.Keys() }},
+		{"PermAlways", []string{"a"}, func() []string { return km.PermAlways.Keys() }},
+		{"PermCommand", []string{"c"}, func() []string { return km.PermCommand.Keys() }},
 		{"Tab", []string{"tab"}, func() []string { return km.Tab.Keys() }},
+		{"PrevMessage", []string{"["}, func() []string { return km.PrevMessage.Keys() }},
+		{"NextMessage", []string{"]"}, func() []string { return km.NextMessage.Keys() }},
+		{"ToggleRaw", []string{"r"}, func() []string { return km.ToggleRaw.Keys() }},
+		{"ScrollLeft", []string{"left", "h"}, func() []string { return km.ScrollLeft.Keys() }},
+		{"ScrollRight", []string{"right", "l"}, func() []string { return km.ScrollRight.Keys() }},
+		{"OpenLink", []string{"1"}, func() []string { return km.OpenLink.Keys() }},
+		{"OpenEditor", []string{"e"}, func() []string { return km.OpenEditor.Keys() }},
+		{"Review", []string{"ctrl+r"}, func() []string { return km.Review.Keys() }},
+		{"Apply", []string{"a"}, func() []string { return km.Apply.Keys() }},
+		{"Discard", []string{"d"}, func() []string { return km.Discard.Keys() }},
+		{"PrevField", []string{"shift+tab"}, func() []string { return km.PrevField.Keys() }},
+		{"Reply", []string{">"}, func() []string { return km.Reply.Keys() }},
+		{"ShowRule", []string{"w"}, func() []string { return km.ShowRule.Keys() }},
+		{"Stop", []string{"ctrl+x"}, func() []string { return km.Stop.Keys() }},
 	}
 
 	for _, tt := range tests {
@@ -44,3 +62,26 @@ func TestDefaultKeyMap(t *testing.T) {
 		})
 	}
 }
+
+// TestDefaultKeyMap_GlobalKeysDistinct checks that the keys handled in
+// every focus and state do not shadow one another.
+func TestDefaultKeyMap_GlobalKeysDistinct(t *testing.T) {
+	km := DefaultKeyMap()
+	global := map[string]key.Binding{
+		"Quit":          km.Quit,
+		"Tab":           km.Tab,
+		"Review":        km.Review,
+		"ToggleSidebar": km.ToggleSidebar,
+		"Stop":          km.Stop,
+		"FocusChat":     km.FocusChat,
+	}
+	owner := map[string]string{}
+	for name, b := range global {
+		for _, k := range b.Keys() {
+			if other, ok := owner[k]; ok {
+				t.Errorf("%q is bound to both %s and %s", k, other, name)
+			}
+			owner[k] = name
+		}
+	}
+}
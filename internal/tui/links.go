@@ -0,0 +1,80 @@
+package tui
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// urlRe matches http and https URLs in message text, including the
+// targets of markdown links.
+var urlRe = regexp.MustCompile(`https?://[^\s<>"'\x60\]]+`)
+
+// extractLinks returns the distinct URLs in text, in order of first
+// appearance. Trailing punctuation that ends a sentence, and a closing
+// parenthesis without a matching opening one, are not part of the URL.
+func extractLinks(text string) []string {
+	var links []string
+	seen := map[string]bool{}
+	for _, u := range urlRe.FindAllString(text, -1) {
+		for {
+			trimmed := strings.TrimRight(u, ".,;:!?*_")
+			if strings.HasSuffix(trimmed, ")") && strings.Count(trimmed, "(") < strings.Count(trimmed, ")") {
+				trimmed = trimmed[:len(trimmed)-1]
+			}
+			if trimmed == u {
+				break
+			}
+			u = trimmed
+		}
+		if !seen[u] {
+			seen[u] = true
+			links = append(links, u)
+		}
+	}
+	return links
+}
+
+// renderReferences lists links as numbered references.
+func renderReferences(links []string) string {
+	lines := make([]string, len(links))
+	for i, link := range links {
+		lines[i] = fmt.Sprintf("[%d] %s", i+1, link)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// LinkOpenedMsg reports the result of opening a reference in the browser.
+type LinkOpenedMsg struct {
+	URL string
+	Err error
+}
+
+// openBrowser opens url with the system's default handler. Tests replace
+// it.
+var openBrowser = func(url string) error {
+	return openCommand(runtime.GOOS, url).Start()
+}
+
+// openCommand returns the command that opens url on the given OS.
+func openCommand(goos, url string) *exec.Cmd {
+	switch goos {
+	case "darwin":
+		return exec.Command("open", url)
+	case "windows":
+		return exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		return exec.Command("xdg-open", url)
+	}
+}
+
+// openLink returns a command that opens url and reports the outcome.
+func openLink(url string) tea.Cmd {
+	return func() tea.Msg {
+		return LinkOpenedMsg{URL: url, Err: openBrowser(url)}
+	}
+}
@@ -0,0 +1,95 @@
+package tui
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestExtractLinks(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"no links here", nil},
+		{"See https://go.dev/doc. Then http://example.com/a?b=c, done!", []string{"https://go.dev/doc", "http://example.com/a?b=c"}},
+		{"[docs](https://pkg.go.dev/net/http) and again https://pkg.go.dev/net/http", []string{"https://pkg.go.dev/net/http"}},
+		{"(see https://en.wikipedia.org/wiki/Go_(programming_language))", []string{"https://en.wikipedia.org/wiki/Go_(programming_language)"}},
+		{"`https://example.com/code` and <https://example.com/angle>", []string{"https://example.com/code", "https://example.com/angle"}},
+		{"**https://example.com/bold**", []string{"https://example.com/bold"}},
+	}
+	for _, tt := range tests {
+		if got := extractLinks(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("extractLinks(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestOpenCommand(t *testing.T) {
+	for goos, want := range map[string]string{
+		"darwin":  "open https://x",
+		"windows": "rundll32 url.dll,FileProtocolHandler https://x",
+		"linux":   "xdg-open https://x",
+	} {
+		if got := strings.Join(openCommand(goos, "https://x").Args, " "); got != want {
+			t.Errorf("%s: %q, want %q", goos, got, want)
+		}
+	}
+}
+
+func TestChatModel_References(t *testing.T) {
+	m := newTestChatModel()
+	m.messages = append(m.messages,
+		ChatMessage{Role: RoleAssistant, Content: "Read https://go.dev/doc and https://go.dev/blog."},
+		ChatMessage{Role: RoleUser, Content: "thanks"},
+	)
+	m.renderAll()
+
+	view := stripANSI(m.viewport.View())
+	if !strings.Contains(view, "[1] https://go.dev/doc") || !strings.Contains(view, "[2] https://go.dev/blog") {
+		t.Errorf("expected numbered references:\n%s", view)
+	}
+	if url, ok := m.Link(2); !ok || url != "https://go.dev/blog" {
+		t.Errorf("Link(2) = %q, %v", url, ok)
+	}
+	if _, ok := m.Link(3); ok {
+		t.Error("Link(3) should not exist")
+	}
+}
+
+func TestApp_OpenLink(t *testing.T) {
+	var opened []string
+	orig := openBrowser
+	defer func() { openBrowser = orig }()
+	openBrowser = func(url string) error {
+		opened = append(opened, url)
+		return errors.New("no browser")
+	}
+
+	app := newTestApp()
+	app.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	app.chat.messages = append(app.chat.messages, ChatMessage{Role: RoleAssistant, Content: "See https://go.dev/doc"})
+	app.Update(tea.KeyMsg{Type: tea.KeyEsc})
+
+	_, cmd := app.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("1")})
+	if cmd == nil {
+		t.Fatal("expected a command to open the link")
+	}
+	app.Update(cmd())
+	if !reflect.DeepEqual(opened, []string{"https://go.dev/doc"}) {
+		t.Errorf("opened = %q", opened)
+	}
+	last := app.chat.messages[len(app.chat.messages)-1]
+	if !strings.Contains(last.Content, "Could not open https://go.dev/doc: no browser") {
+		t.Errorf("expected the open failure in chat, got %+v", last)
+	}
+
+	app.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("2")})
+	last = app.chat.messages[len(app.chat.messages)-1]
+	if last.Content != "No reference [2] in this message" {
+		t.Errorf("missing reference: %+v", last)
+	}
+}
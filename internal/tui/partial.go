@@ -0,0 +1,94 @@
+package tui
+
+import (
+	"regexp"
+	"strings"
+)
+
+// fencePattern matches the opening or closing line of a code fence.
+var fencePattern = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})")
+
+// delimiterRowPattern matches the row under a table's header.
+var delimiterRowPattern = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?\s*$`)
+
+// closePartial makes markdown that is still streaming render the way it
+// will once complete, so the layout does not jump from frame to frame:
+// an open code fence is closed, a table is held back until its delimiter
+// row arrives and its last row is whole, and an unclosed code span, bold
+// span, or link on the last line is closed or held back. It is for
+// display only; the message is kept as streamed.
+func closePartial(text string) string {
+	lines := strings.Split(text, "\n")
+	if fence := openFence(lines); fence != "" {
+		return text + "\n" + fence
+	}
+
+	// The block being written: the lines after the last blank one. The
+	// last line is incomplete unless text ends in a newline.
+	start := len(lines) - 1
+	for start > 0 && strings.TrimSpace(lines[start-1]) != "" {
+		start--
+	}
+	block := lines[start:]
+	if isTableRow(block[0]) {
+		if len(block) < 3 || !delimiterRowPattern.MatchString(block[1]) {
+			return strings.Join(lines[:start], "\n")
+		}
+		return strings.Join(lines[:len(lines)-1], "\n")
+	}
+
+	head := strings.Join(block[:len(block)-1], "\n")
+	last := closeInline(head, block[len(block)-1])
+	return strings.Join(append(lines[:len(lines)-1], last), "\n")
+}
+
+// openFence returns the marker that closes the code fence left open by
+// lines, or "" if every fence is closed.
+func openFence(lines []string) string {
+	open := ""
+	for _, line := range lines {
+		m := fencePattern.FindStringSubmatch(line)
+		switch {
+		case m == nil:
+		case open == "":
+			open = m[1]
+		case m[1][0] == open[0] && len(m[1]) >= len(open) && strings.TrimSpace(line[len(m[0]):]) == "":
+			open = ""
+		}
+	}
+	return open
+}
+
+// isTableRow reports whether line looks like a row of a pipe table.
+func isTableRow(line string) bool {
+	return strings.HasPrefix(strings.TrimSpace(line), "|")
+}
+
+// closeInline closes a code span or bold span left open in the
+// paragraph being written, whose last line is last and earlier lines
+// head, by appending to last, and cuts an unfinished link off last.
+func closeInline(head, last string) string {
+	if strings.Count(head+last, "`")%2 == 1 {
+		return last + "`"
+	}
+	if i := strings.LastIndex(last, "["); i >= 0 && !strings.Contains(last[i:], ")") {
+		if j := strings.Index(last[i:], "]"); j < 0 || strings.HasPrefix(last[i+j+1:], "(") {
+			last = strings.TrimRight(last[:i], " ")
+		}
+	}
+	if strings.Count(withoutCode(head+"\n"+last), "**")%2 == 1 {
+		// A lone trailing asterisk is the first half of the closing pair.
+		last = strings.TrimSuffix(last, "*") + "**"
+	}
+	return last
+}
+
+// withoutCode removes code spans from s, whose asterisks are literal.
+func withoutCode(s string) string {
+	parts := strings.Split(s, "`")
+	var b strings.Builder
+	for i := 0; i < len(parts); i += 2 {
+		b.WriteString(parts[i])
+	}
+	return b.String()
+}
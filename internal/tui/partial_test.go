@@ -0,0 +1,48 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestClosePartial(t *testing.T) {
+	tests := []struct {
+		name, text, want string
+	}{
+		{"plain", "Hello wor", "Hello wor"},
+		{"open fence", "Try this:\n```go\nfunc main() {", "Try this:\n```go\nfunc main() {\n```"},
+		{"closed fence", "```go\nx := 1\n```\nDone", "```go\nx := 1\n```\nDone"},
+		{"tilde fence", "~~~~\ncode", "~~~~\ncode\n~~~~"},
+		{"backticks inside fence", "```\na ` b", "```\na ` b\n```"},
+		{"table header only", "Results:\n\n| Name | Age |", "Results:\n"},
+		{"table delimiter in progress", "Results:\n\n| Name | Age |\n|---|-", "Results:\n"},
+		{"table partial row", "| Name | Age |\n|---|---|\n| Ann | 3", "| Name | Age |\n|---|---|"},
+		{"table whole rows", "| Name | Age |\n|---|---|\n| Ann | 3 |\n", "| Name | Age |\n|---|---|\n| Ann | 3 |"},
+		{"open code span", "Run `go te", "Run `go te`"},
+		{"code span across lines", "Run `go\ntest", "Run `go\ntest`"},
+		{"open bold", "This is **impor", "This is **impor**"},
+		{"half-closed bold", "This is **important*", "This is **important**"},
+		{"asterisks in code", "Use `**` for bold", "Use `**` for bold"},
+		{"unfinished link text", "See [the do", "See"},
+		{"unfinished link target", "See [the docs](https://exa", "See"},
+		{"finished link", "See [the docs](https://example.com) and", "See [the docs](https://example.com) and"},
+		{"checkbox", "- [x] done", "- [x] done"},
+	}
+	for _, tt := range tests {
+		if got := closePartial(tt.text); got != tt.want {
+			t.Errorf("%s: closePartial(%q) = %q, want %q", tt.name, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestChatModel_StreamingClosesFence(t *testing.T) {
+	m := newTestChatModel()
+	m, _ = m.Update(TokenMsg{Content: "Here:\n```go\nfunc main() {}\n"})
+	view := stripANSI(m.viewport.View())
+	if strings.Contains(view, "```") {
+		t.Errorf("open fence rendered literally:\n%s", view)
+	}
+	if !strings.Contains(view, "func main()") {
+		t.Errorf("code missing from the streaming view:\n%s", view)
+	}
+}
@@ -0,0 +1,136 @@
+package tui
+
+import (
+	"context"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/gavinyap/stormtrooper/internal/command"
+	"github.com/gavinyap/stormtrooper/internal/i18n"
+)
+
+// PickerModel lists a command's choices, such as the sessions /resume
+// can continue, with one highlighted. If the picker has Filter set,
+// typing narrows the list to the items containing the typed text.
+type PickerModel struct {
+	theme  *Theme
+	picker *command.Picker
+	reload bool
+	filter string
+	shown  []int // indexes of the items matching filter
+	cur    int   // index into shown
+	width  int
+	height int
+}
+
+// PickerDoneMsg carries the output of a chosen item. Reload is true when
+// the choice replaced the conversation.
+type PickerDoneMsg struct {
+	Output string
+	Err    error
+	Reload bool
+}
+
+// NewPickerModel shows p's items with the first one highlighted. reload
+// is passed on in PickerDoneMsg.
+func NewPickerModel(theme *Theme, p *command.Picker, reload bool) PickerModel {
+	m := PickerModel{theme: theme, picker: p, reload: reload}
+	m.applyFilter()
+	return m
+}
+
+// SetSize sets the dimensions, including the border.
+func (m *PickerModel) SetSize(w, h int) {
+	m.width, m.height = w, h
+}
+
+// Filtering reports whether typing filters the items.
+func (m *PickerModel) Filtering() bool {
+	return m.picker.Filter
+}
+
+// Type adds s to the filter.
+func (m *PickerModel) Type(s string) {
+	m.filter += s
+	m.applyFilter()
+}
+
+// Backspace removes the last character of the filter.
+func (m *PickerModel) Backspace() {
+	if r := []rune(m.filter); len(r) > 0 {
+		m.filter = string(r[:len(r)-1])
+		m.applyFilter()
+	}
+}
+
+// applyFilter shows the items containing the filter, ignoring case, and
+// highlights the first.
+func (m *PickerModel) applyFilter() {
+	m.shown, m.cur = m.shown[:0], 0
+	filter := strings.ToLower(m.filter)
+	for i, item := range m.picker.Items {
+		if strings.Contains(strings.ToLower(item), filter) {
+			m.shown = append(m.shown, i)
+		}
+	}
+}
+
+// Next highlights the next item, stopping at the last.
+func (m *PickerModel) Next() {
+	if m.cur < len(m.shown)-1 {
+		m.cur++
+	}
+}
+
+// Prev highlights the previous item, stopping at the first.
+func (m *PickerModel) Prev() {
+	if m.cur > 0 {
+		m.cur--
+	}
+}
+
+// Choose returns a command that calls the picker's Choose with the
+// highlighted item, or nil if no item matches the filter.
+func (m *PickerModel) Choose() tea.Cmd {
+	if len(m.shown) == 0 {
+		return nil
+	}
+	choose, i, reload := m.picker.Choose, m.shown[m.cur], m.reload
+	return func() tea.Msg {
+		out, err := choose(context.Background(), i)
+		return PickerDoneMsg{Output: out, Err: err, Reload: reload}
+	}
+}
+
+// View renders the items that fit, keeping the highlighted one in view.
+func (m PickerModel) View() string {
+	var b strings.Builder
+	b.WriteString(m.theme.SidebarHeading.Render(m.picker.Title) + "\n\n")
+	// Border, title, blank line, and help.
+	rows := max(m.height-5, 1)
+	help := i18n.T("picker.help")
+	if m.picker.Filter {
+		b.WriteString(i18n.T("picker.filter", m.filter) + "\n")
+		rows = max(rows-1, 1)
+		help = i18n.T("picker.filter_help")
+	}
+	if len(m.shown) == 0 {
+		b.WriteString(m.theme.ToolInline.Render(i18n.T("picker.no_matches")) + "\n")
+	}
+	start := 0
+	if m.cur >= rows {
+		start = m.cur - rows + 1
+	}
+	for i := start; i < len(m.shown) && i < start+rows; i++ {
+		prefix := "  "
+		if i == m.cur {
+			prefix = m.theme.SelectedMarker.Render("▶ ")
+		}
+		b.WriteString(prefix + m.picker.Items[m.shown[i]] + "\n")
+	}
+	b.WriteString("\n" + m.theme.ToolInline.Render(help))
+	return m.theme.ChatBorder.
+		Width(m.width).
+		Height(m.height).
+		Render(b.String())
+}
@@ -0,0 +1,82 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gavinyap/stormtrooper/internal/command"
+)
+
+func TestPickerModel(t *testing.T) {
+	theme := DefaultTheme()
+	var items []string
+	for i := range 10 {
+		items = append(items, fmt.Sprintf("item %d", i))
+	}
+	m := NewPickerModel(&theme, &command.Picker{
+		Title: "Pick one",
+		Items: items,
+		Choose: func(_ context.Context, i int) (string, error) {
+			return items[i], nil
+		},
+	}, true)
+	m.SetSize(60, 9)
+
+	m.Prev()
+	if m.cur != 0 {
+		t.Errorf("Prev at the top moved to %d", m.cur)
+	}
+	for range 20 {
+		m.Next()
+	}
+	if m.cur != 9 {
+		t.Errorf("Next past the end moved to %d", m.cur)
+	}
+
+	// Only four rows fit, so the list scrolls to keep the last item in view.
+	view := stripANSI(m.View())
+	if !strings.Contains(view, "Pick one") || !strings.Contains(view, "▶ item 9") || strings.Contains(view, "item 5") {
+		t.Errorf("unexpected view:\n%s", view)
+	}
+
+	if done := m.Choose()().(PickerDoneMsg); done.Output != "item 9" || done.Err != nil || !done.Reload {
+		t.Errorf("Choose = %+v", done)
+	}
+}
+
+func TestPickerModel_Filter(t *testing.T) {
+	theme := DefaultTheme()
+	items := []string{"openai/gpt-4o", "anthropic/claude", "openai/gpt-oss"}
+	m := NewPickerModel(&theme, &command.Picker{
+		Title:  "Switch model",
+		Items:  items,
+		Filter: true,
+		Choose: func(_ context.Context, i int) (string, error) {
+			return items[i], nil
+		},
+	}, false)
+	m.SetSize(60, 12)
+
+	m.Type("GPT")
+	m.Next()
+	if done := m.Choose()().(PickerDoneMsg); done.Output != "openai/gpt-oss" {
+		t.Errorf("Choose after filtering = %+v", done)
+	}
+	view := stripANSI(m.View())
+	if !strings.Contains(view, "Filter: GPT") || strings.Contains(view, "anthropic") {
+		t.Errorf("unexpected view:\n%s", view)
+	}
+
+	m.Type("x")
+	if m.Choose() != nil || !strings.Contains(stripANSI(m.View()), "No matches") {
+		t.Error("expected no matches")
+	}
+	for range 4 {
+		m.Backspace()
+	}
+	if len(m.shown) != 3 {
+		t.Errorf("clearing the filter shows %d items, want 3", len(m.shown))
+	}
+}
@@ -0,0 +1,57 @@
+package tui
+
+import (
+	"strings"
+)
+
+// replyMarker introduces the quoted part of an earlier answer in a user
+// message, so the model knows what the follow-up refers to.
+const replyMarker = "Replying to this part of your earlier answer:"
+
+// reply is the part of an earlier answer quoted into the next message.
+type reply struct {
+	msg   int      // index of the answer in the chat
+	parts []string // the whole answer, then each of its code blocks
+	part  int      // index into parts of the quoted part
+}
+
+// quoteParts returns what can be quoted from an answer: all of it, then
+// each of its fenced code blocks, fences included.
+func quoteParts(content string) []string {
+	parts := []string{strings.TrimSpace(content)}
+	var block []string
+	open := ""
+	for _, line := range strings.Split(content, "\n") {
+		m := fencePattern.FindStringSubmatch(line)
+		switch {
+		case open == "" && m != nil:
+			open = m[1]
+			block = []string{line}
+		case open == "":
+		case m != nil && m[1][0] == open[0] && len(m[1]) >= len(open) && strings.TrimSpace(line[len(m[0]):]) == "":
+			parts = append(parts, strings.Join(append(block, line), "\n"))
+			open = ""
+		default:
+			block = append(block, line)
+		}
+	}
+	if open != "" {
+		parts = append(parts, strings.Join(block, "\n"))
+	}
+	return parts
+}
+
+// quoteReply returns text preceded by quoted, marked as a quote.
+func quoteReply(quoted, text string) string {
+	var b strings.Builder
+	b.WriteString(replyMarker + "\n\n")
+	for _, line := range strings.Split(quoted, "\n") {
+		if line == "" {
+			b.WriteString(">\n")
+			continue
+		}
+		b.WriteString("> " + line + "\n")
+	}
+	b.WriteString("\n" + text)
+	return b.String()
+}
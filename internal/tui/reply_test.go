@@ -0,0 +1,38 @@
+package tui
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestQuoteParts(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{"no code", "Just text.\n", []string{"Just text."}},
+		{
+			"two blocks",
+			"First:\n```go\na()\n```\nThen:\n~~~\nb\n~~~\n",
+			[]string{"First:\n```go\na()\n```\nThen:\n~~~\nb\n~~~", "```go\na()\n```", "~~~\nb\n~~~"},
+		},
+		{"longer fence", "````\n```\nx\n````", []string{"````\n```\nx\n````", "````\n```\nx\n````"}},
+		{"unclosed", "```\nx", []string{"```\nx", "```\nx"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := quoteParts(tt.content); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("quoteParts(%q) = %q, want %q", tt.content, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuoteReply(t *testing.T) {
+	got := quoteReply("one\n\ntwo", "why?")
+	want := replyMarker + "\n\n> one\n>\n> two\n\nwhy?"
+	if got != want {
+		t.Errorf("quoteReply = %q, want %q", got, want)
+	}
+}
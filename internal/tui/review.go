@@ -0,0 +1,117 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/gavinyap/stormtrooper/internal/i18n"
+	"github.com/gavinyap/stormtrooper/internal/staging"
+)
+
+// ReviewModel shows the staged file changes one file at a time, so they
+// can be read before they are applied or discarded.
+type ReviewModel struct {
+	theme    *Theme
+	viewport viewport.Model
+	changes  []staging.Change
+	file     int
+	width    int
+	height   int
+}
+
+// NewReviewModel creates an empty ReviewModel.
+func NewReviewModel(theme *Theme) ReviewModel {
+	return ReviewModel{theme: theme, viewport: viewport.New(80, 20)}
+}
+
+// SetChanges replaces the changes under review and shows the first file.
+func (m *ReviewModel) SetChanges(changes []staging.Change) {
+	m.changes = changes
+	m.file = 0
+	m.render()
+}
+
+// SetSize sets the dimensions, including the border.
+func (m *ReviewModel) SetSize(w, h int) {
+	m.width, m.height = w, h
+	// Border, plus the file list and help lines.
+	m.viewport.Width = max(w-2, 1)
+	m.viewport.Height = max(h-2-len(m.changes)-2, 1)
+	m.render()
+}
+
+// NextFile shows the next file, wrapping around.
+func (m *ReviewModel) NextFile() {
+	if len(m.changes) > 0 {
+		m.file = (m.file + 1) % len(m.changes)
+		m.render()
+	}
+}
+
+// PrevFile shows the previous file, wrapping around.
+func (m *ReviewModel) PrevFile() {
+	if len(m.changes) > 0 {
+		m.file = (m.file + len(m.changes) - 1) % len(m.changes)
+		m.render()
+	}
+}
+
+// render puts the current file's diff in the viewport.
+func (m *ReviewModel) render() {
+	if len(m.changes) == 0 {
+		m.viewport.SetContent("")
+		return
+	}
+	diff := strings.TrimRight(m.changes[m.file].Diff(), "\n")
+	m.viewport.SetContent(colorDiff(m.theme, diff, unstyled))
+	m.viewport.GotoTop()
+}
+
+// Update scrolls the diff.
+func (m ReviewModel) Update(msg tea.Msg) (ReviewModel, tea.Cmd) {
+	var cmd tea.Cmd
+	m.viewport, cmd = m.viewport.Update(msg)
+	return m, cmd
+}
+
+// View renders the file list, the current diff, and the key help.
+func (m ReviewModel) View() string {
+	var b strings.Builder
+	b.WriteString(m.theme.SidebarHeading.Render(i18n.T("review.title", len(m.changes))) + "\n")
+	for i, c := range m.changes {
+		prefix := "  "
+		if i == m.file {
+			prefix = m.theme.SelectedMarker.Render("▶ ")
+		}
+		b.WriteString(prefix + c.Path + m.theme.ToolInline.Render(" "+changeSummary(c)) + "\n")
+	}
+	b.WriteString(m.viewport.View() + "\n")
+	b.WriteString(m.theme.ToolInline.Render(i18n.T("review.help")))
+	return m.theme.ChatBorder.
+		Width(m.width).
+		Height(m.height).
+		Render(b.String())
+}
+
+// changeSummary counts the lines a change adds and removes.
+func changeSummary(c staging.Change) string {
+	switch {
+	case c.Old == nil:
+		return i18n.T("review.created")
+	case c.New == nil:
+		return i18n.T("review.deleted")
+	}
+	var added, removed int
+	for _, line := range strings.Split(c.Diff(), "\n") {
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+		case strings.HasPrefix(line, "+"):
+			added++
+		case strings.HasPrefix(line, "-"):
+			removed++
+		}
+	}
+	return fmt.Sprintf("+%d -%d", added, removed)
+}
@@ -0,0 +1,35 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gavinyap/stormtrooper/internal/staging"
+)
+
+func TestReviewModel_Files(t *testing.T) {
+	theme := DefaultTheme()
+	m := NewReviewModel(&theme)
+	m.SetChanges([]staging.Change{
+		{Path: "a.go", Old: []byte("one\ntwo\n"), New: []byte("one\nthree\nfour\n")},
+		{Path: "b.go", New: []byte("new\n")},
+		{Path: "c.go", Old: []byte("gone\n")},
+	})
+	m.SetSize(80, 30)
+
+	view := stripANSI(m.View())
+	for _, want := range []string{"a.go +2 -1", "b.go new file", "c.go deleted", "+three"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("view missing %q:\n%s", want, view)
+		}
+	}
+
+	m.PrevFile()
+	if m.file != 2 || !strings.Contains(stripANSI(m.View()), "-gone") {
+		t.Errorf("PrevFile from the first file should wrap to the last, file = %d", m.file)
+	}
+	m.NextFile()
+	if m.file != 0 {
+		t.Errorf("NextFile from the last file should wrap to the first, file = %d", m.file)
+	}
+}
@@ -7,6 +7,9 @@ import (
 	"github.com/charmbracelet/bubbles/spinner"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/gavinyap/stormtrooper/internal/agent"
+	"github.com/gavinyap/stormtrooper/internal/i18n"
 )
 
 // ToolCallEntry represents a tool call displayed in the sidebar.
@@ -16,12 +19,20 @@ type ToolCallEntry struct {
 	Error   bool
 }
 
+// SubAgentEntry is a sub-agent running in parallel, shown in the sidebar.
+type SubAgentEntry struct {
+	Task string
+	Tool string // the tool it is running, if any
+	Done bool
+}
+
 // SidebarOptions holds static project info for the sidebar.
 type SidebarOptions struct {
 	ProjectDir   string
 	MemoryLoaded bool
 	ToolCount    int
 	ModelName    string
+	Restricted   bool // workspace is untrusted; project files were not loaded
 }
 
 // SidebarModel is the Bubble Tea model for the right sidebar.
@@ -37,12 +48,20 @@ type SidebarModel struct {
 	// Agent Status
 	agentBusy bool
 	spinner   spinner.Model
+	subAgents []SubAgentEntry // parallel and background sub-agents, by number from 1
+
+	// Token usage
+	usage agent.Usage
+
+	// Pinned files
+	pinned []string
 
 	// Project Info
 	projectDir   string
 	memoryLoaded bool
 	toolCount    int
 	modelName    string
+	restricted   bool
 }
 
 // NewSidebarModel creates a SidebarModel with the given options.
@@ -59,6 +78,7 @@ func NewSidebarModel(theme *Theme, opts SidebarOptions) SidebarModel {
 		memoryLoaded: opts.MemoryLoaded,
 		toolCount:    opts.ToolCount,
 		modelName:    opts.ModelName,
+		restricted:   opts.Restricted,
 	}
 }
 
@@ -89,8 +109,18 @@ func (m SidebarModel) Update(msg tea.Msg) (SidebarModel, tea.Cmd) {
 		}
 		return m, nil
 
+	case SubAgentProgressMsg:
+		m.subAgentProgress(msg)
+		return m, nil
+
 	case AgentDoneMsg:
 		m.agentBusy = false
+		// Background sub-agents outlive the turn; the rest are done.
+		for i := range m.subAgents {
+			if m.subAgents[i].Done {
+				m.subAgents[i] = SubAgentEntry{}
+			}
+		}
 		return m, nil
 
 	case spinner.TickMsg:
@@ -117,8 +147,14 @@ func (m SidebarModel) View() string {
 	sections := []string{
 		m.renderToolActivity(innerWidth),
 		m.renderAgentStatus(innerWidth),
-		m.renderProjectInfo(innerWidth),
 	}
+	if m.usage.Requests > 0 {
+		sections = append(sections, m.renderUsage(innerWidth))
+	}
+	if len(m.pinned) > 0 {
+		sections = append(sections, m.renderPinned(innerWidth))
+	}
+	sections = append(sections, m.renderProjectInfo(innerWidth))
 
 	content := strings.Join(sections, "\n\n")
 
@@ -133,20 +169,35 @@ func (m *SidebarModel) SetAgentBusy(busy bool) {
 	m.agentBusy = busy
 }
 
+// SetModelName updates the model shown in the project info section.
+func (m *SidebarModel) SetModelName(name string) {
+	m.modelName = name
+}
+
+// SetUsage updates the token usage section.
+func (m *SidebarModel) SetUsage(u agent.Usage) {
+	m.usage = u
+}
+
+// SetPinned updates the pinned files section.
+func (m *SidebarModel) SetPinned(paths []string) {
+	m.pinned = paths
+}
+
 // SetHeight updates the sidebar height.
 func (m *SidebarModel) SetHeight(h int) {
 	m.height = h
 }
 
 func (m SidebarModel) renderToolActivity(width int) string {
-	heading := m.theme.SidebarHeading.Render("Tool Activity")
+	heading := m.theme.SidebarHeading.Render(i18n.T("sidebar.tool_activity"))
 	separator := m.theme.SidebarItem.Render(strings.Repeat("\u2500", min(width, 15)))
 
 	var lines []string
 	lines = append(lines, heading, separator)
 
 	if len(m.toolCalls) == 0 {
-		lines = append(lines, m.theme.SidebarItem.Render("No activity"))
+		lines = append(lines, m.theme.SidebarItem.Render(i18n.T("sidebar.no_activity")))
 	} else {
 		for _, tc := range m.toolCalls {
 			lines = append(lines, m.renderToolEntry(tc))
@@ -167,35 +218,111 @@ func (m SidebarModel) renderToolEntry(tc ToolCallEntry) string {
 }
 
 func (m SidebarModel) renderAgentStatus(width int) string {
-	heading := m.theme.SidebarHeading.Render("Agent Status")
+	heading := m.theme.SidebarHeading.Render(i18n.T("sidebar.agent_status"))
 	separator := m.theme.SidebarItem.Render(strings.Repeat("\u2500", min(width, 15)))
 
 	var status string
 	if m.agentBusy {
-		status = m.theme.ToolRunning.Render(m.spinner.View() + " Thinking...")
+		status = m.theme.ToolRunning.Render(m.spinner.View() + " " + i18n.T("input.thinking"))
 	} else {
-		status = m.theme.SidebarItem.Render("Idle")
+		status = m.theme.SidebarItem.Render(i18n.T("sidebar.idle"))
 	}
 
-	return fmt.Sprintf("%s\n%s\n%s", heading, separator, status)
+	lines := []string{heading, separator, status}
+	for i, sa := range m.subAgents {
+		if sa.Task == "" {
+			continue
+		}
+		var line string
+		switch {
+		case sa.Done:
+			line = m.theme.ToolDone.Render(ansi.Truncate(fmt.Sprintf("\u2713 %d %s", i+1, sa.Task), width, "…"))
+		case sa.Tool != "":
+			line = m.theme.ToolRunning.Render(ansi.Truncate(fmt.Sprintf("%s %d %s: %s", m.spinner.View(), i+1, sa.Tool, sa.Task), width, "…"))
+		default:
+			line = m.theme.ToolRunning.Render(ansi.Truncate(fmt.Sprintf("%s %d %s", m.spinner.View(), i+1, sa.Task), width, "…"))
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// subAgentProgress updates a parallel sub-agent's entry from its
+// progress.
+func (m *SidebarModel) subAgentProgress(msg SubAgentProgressMsg) {
+	if msg.Agent < 1 {
+		return
+	}
+	for len(m.subAgents) < msg.Agent {
+		m.subAgents = append(m.subAgents, SubAgentEntry{})
+	}
+	sa := &m.subAgents[msg.Agent-1]
+	switch m := msg.Msg.(type) {
+	case SubAgentSpawnMsg:
+		*sa = SubAgentEntry{Task: m.Task}
+	case SubAgentDoneMsg:
+		sa.Done, sa.Tool = true, ""
+	case ToolStartMsg:
+		sa.Tool = m.Name
+	case ToolResultMsg:
+		sa.Tool = ""
+	}
+}
+
+func (m SidebarModel) renderUsage(width int) string {
+	heading := m.theme.SidebarHeading.Render(i18n.T("sidebar.usage"))
+	separator := m.theme.SidebarItem.Render(strings.Repeat("\u2500", min(width, 15)))
+
+	return strings.Join([]string{
+		heading,
+		separator,
+		m.theme.SidebarItem.Render(i18n.T("sidebar.tokens", formatTokens(m.usage.PromptTokens), formatTokens(m.usage.CompletionTokens))),
+		m.theme.SidebarItem.Render(i18n.T("sidebar.cost", m.usage.FormatCost())),
+	}, "\n")
+}
+
+// formatTokens abbreviates a token count, e.g. 12345 as "12.3k".
+func formatTokens(n int) string {
+	switch {
+	case n >= 1_000_000:
+		return fmt.Sprintf("%.1fM", float64(n)/1e6)
+	case n >= 1000:
+		return fmt.Sprintf("%.1fk", float64(n)/1e3)
+	default:
+		return fmt.Sprint(n)
+	}
+}
+
+func (m SidebarModel) renderPinned(width int) string {
+	heading := m.theme.SidebarHeading.Render(i18n.T("sidebar.pinned"))
+	separator := m.theme.SidebarItem.Render(strings.Repeat("\u2500", min(width, 15)))
+
+	lines := []string{heading, separator}
+	for _, path := range m.pinned {
+		lines = append(lines, m.theme.SidebarItem.Render(ansi.Truncate(path, width, "…")))
+	}
+	return strings.Join(lines, "\n")
 }
 
 func (m SidebarModel) renderProjectInfo(width int) string {
-	heading := m.theme.SidebarHeading.Render("Project Info")
+	heading := m.theme.SidebarHeading.Render(i18n.T("sidebar.project_info"))
 	separator := m.theme.SidebarItem.Render(strings.Repeat("\u2500", min(width, 15)))
 
-	memStatus := "not loaded"
+	memStatus := i18n.T("sidebar.memory_missing")
 	if m.memoryLoaded {
-		memStatus = "loaded"
+		memStatus = i18n.T("sidebar.memory_loaded")
 	}
 
 	lines := []string{
 		heading,
 		separator,
-		m.theme.SidebarItem.Render(fmt.Sprintf("Dir: %s", m.projectDir)),
-		m.theme.SidebarItem.Render(fmt.Sprintf("Memory: %s", memStatus)),
-		m.theme.SidebarItem.Render(fmt.Sprintf("Tools: %d", m.toolCount)),
-		m.theme.SidebarItem.Render(fmt.Sprintf("Model: %s", m.modelName)),
+		m.theme.SidebarItem.Render(i18n.T("sidebar.dir", m.projectDir)),
+		m.theme.SidebarItem.Render(i18n.T("sidebar.memory", memStatus)),
+		m.theme.SidebarItem.Render(i18n.T("sidebar.tools", m.toolCount)),
+		m.theme.SidebarItem.Render(i18n.T("sidebar.model", m.modelName)),
+	}
+	if m.restricted {
+		lines = append(lines, m.theme.ToolRunning.Render(i18n.T("sidebar.restricted")))
 	}
 
 	return strings.Join(lines, "\n")
@@ -3,6 +3,8 @@ package tui
 import (
 	"strings"
 	"testing"
+
+	"github.com/gavinyap/stormtrooper/internal/agent"
 )
 
 func newTestSidebarModel() SidebarModel {
@@ -95,6 +97,39 @@ func TestSidebar_AgentStatus(t *testing.T) {
 	}
 }
 
+func TestSidebar_SubAgents(t *testing.T) {
+	m := newTestSidebarModel()
+	for _, msg := range []SubAgentProgressMsg{
+		{Agent: 1, Msg: SubAgentSpawnMsg{Task: "lint the code"}},
+		{Agent: 2, Msg: SubAgentSpawnMsg{Task: "run the tests"}},
+		{Agent: 2, Msg: ToolStartMsg{Name: "shell_exec"}},
+		{Agent: 1, Msg: SubAgentDoneMsg{}},
+	} {
+		m, _ = m.Update(msg)
+	}
+
+	view := m.View()
+	for _, want := range []string{"\u2713 1 lint the code", "2 shell_exec: run the"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("expected %q in view:\n%s", want, view)
+		}
+	}
+
+	m, _ = m.Update(SubAgentProgressMsg{Agent: 2, Msg: ToolResultMsg{Name: "shell_exec"}})
+	if m.subAgents[1].Tool != "" {
+		t.Errorf("tool should be cleared when it finishes, got %q", m.subAgents[1].Tool)
+	}
+
+	m, _ = m.Update(AgentDoneMsg{})
+	if strings.Contains(m.View(), "lint the code") {
+		t.Error("finished sub-agents should be cleared when the agent is done")
+	}
+	// Agent 2 is still running, as a background sub-agent would be.
+	if !strings.Contains(m.View(), "run the tests") {
+		t.Error("running sub-agents should stay listed after the turn")
+	}
+}
+
 func TestSidebar_ProjectInfo(t *testing.T) {
 	m := newTestSidebarModel()
 	view := m.View()
@@ -140,3 +175,54 @@ func TestSidebar_MostRecentAtTop(t *testing.T) {
 		t.Errorf("expected older tool second, got %q", m.toolCalls[1].Name)
 	}
 }
+
+func TestSidebar_RestrictedWorkspace(t *testing.T) {
+	m := newTestSidebarModel()
+	m.SetHeight(30)
+	if strings.Contains(m.View(), "Trust: restricted") {
+		t.Error("trusted workspace should not show restricted marker")
+	}
+
+	theme := DefaultTheme()
+	m = NewSidebarModel(&theme, SidebarOptions{ProjectDir: "cloned", Restricted: true})
+	m.SetHeight(30)
+	if !strings.Contains(m.View(), "Trust: restricted") {
+		t.Error("untrusted workspace should show restricted marker")
+	}
+}
+
+func TestSidebar_PinnedFiles(t *testing.T) {
+	m := newTestSidebarModel()
+	m.SetHeight(30)
+	if strings.Contains(m.View(), "Pinned Files") {
+		t.Error("the pinned section should be hidden when nothing is pinned")
+	}
+
+	m.SetPinned([]string{"docs/design.md", "internal/agent/" + strings.Repeat("x", 40) + ".go"})
+	view := m.View()
+	if !strings.Contains(view, "Pinned Files") || !strings.Contains(view, "docs/design.md") {
+		t.Errorf("expected the pinned files listed:\n%s", view)
+	}
+	if strings.Contains(view, strings.Repeat("x", 40)) {
+		t.Error("long paths should be truncated to the sidebar width")
+	}
+}
+
+func TestSidebar_Usage(t *testing.T) {
+	m := newTestSidebarModel()
+	m.SetHeight(30)
+	if strings.Contains(m.View(), "Usage") {
+		t.Error("the usage section should be hidden before any usage is reported")
+	}
+
+	m.SetUsage(agent.Usage{Requests: 2, PromptTokens: 12345, CompletionTokens: 678, Cost: 0.0421})
+	view := m.View()
+	for _, want := range []string{"Usage", "Tokens: 12.3k in, 678 out", "Cost: $0.04"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("expected %q in:\n%s", want, view)
+		}
+	}
+	if got := formatTokens(2_500_000); got != "2.5M" {
+		t.Errorf("formatTokens(2500000) = %q", got)
+	}
+}
@@ -71,6 +71,11 @@ func (m *StatusBarModel) SetWidth(w int) {
 	m.width = w
 }
 
+// SetModel updates the model name shown in the status bar.
+func (m *StatusBarModel) SetModel(model string) {
+	m.model = model
+}
+
 // truncateCWD shortens a CWD from the left if it exceeds available space.
 // For example, "/home/user/projects/myapp/src" becomes "...ojects/myapp/src".
 func (m StatusBarModel) truncateCWD(cwd string) string {
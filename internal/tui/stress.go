@@ -0,0 +1,106 @@
+package tui
+
+import (
+	gocontext "context"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/gavinyap/stormtrooper/internal/agent"
+	"github.com/gavinyap/stormtrooper/internal/llm"
+	"github.com/gavinyap/stormtrooper/internal/llm/mock"
+	"github.com/gavinyap/stormtrooper/internal/tool"
+)
+
+// StressResult reports how fast the TUI displayed a synthetic token stream.
+type StressResult struct {
+	Tokens  int
+	Elapsed time.Duration
+}
+
+// TokensPerSecond is the end-to-end display throughput.
+func (r StressResult) TokensPerSecond() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.Tokens) / r.Elapsed.Seconds()
+}
+
+func (r StressResult) String() string {
+	perToken := time.Duration(0)
+	if r.Tokens > 0 {
+		perToken = r.Elapsed / time.Duration(r.Tokens)
+	}
+	return fmt.Sprintf("Displayed %d tokens in %s (%.0f tokens/sec, %s per token)",
+		r.Tokens, r.Elapsed.Round(time.Millisecond), r.TokensPerSecond(), perToken)
+}
+
+// Stress streams about n synthetic markdown tokens from the mock provider
+// through a real agent and bridge into a headless App of the given size,
+// calling Update and View for every event as the Bubble Tea loop does.
+// No terminal or network is needed, so results are comparable between
+// runs and machines.
+func Stress(n, width, height int) StressResult {
+	client := llm.NewClient("")
+	client.SetTransport((&mock.Script{Default: stressText(n)}).Transport())
+	ag := agent.New(agent.Options{
+		Client:   client,
+		Registry: tool.NewRegistry(),
+		Model:    "stress",
+	})
+
+	app := New(Options{Agent: ag, Version: "stress"})
+	app.Update(tea.WindowSizeMsg{Width: width, Height: height})
+	app.View()
+
+	events := app.bridge.Events()
+	done := make(chan error, 1)
+	tokens := 0
+	handle := func(ev AgentEvent) {
+		if _, ok := ev.(TokenMsg); ok {
+			tokens++
+		}
+		app.Update(ev)
+		app.View()
+	}
+
+	start := time.Now()
+	go func() {
+		done <- ag.Send(gocontext.Background(), "stress")
+	}()
+	for running := true; running; {
+		select {
+		case ev := <-events:
+			handle(ev)
+		case err := <-done:
+			// Send has returned, so every event is already buffered.
+			for len(events) > 0 {
+				handle(<-events)
+			}
+			app.Update(AgentDoneMsg{Error: err})
+			app.View()
+			running = false
+		}
+	}
+
+	return StressResult{Tokens: tokens, Elapsed: time.Since(start)}
+}
+
+// stressText builds an n-word reply mixing prose, emphasis, lists, and
+// code blocks so markdown rendering cost is representative.
+func stressText(n int) string {
+	words := []string{"lorem", "**ipsum**", "dolor", "`sit`", "amet", "consectetur", "adipiscing", "elit"}
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		switch {
+		case i > 0 && i%200 == 0:
+			b.WriteString("\n\n```go\nfunc main() {}\n```\n\n")
+		case i > 0 && i%50 == 0:
+			b.WriteString("\n\n- ")
+		}
+		b.WriteString(words[i%len(words)])
+		b.WriteString(" ")
+	}
+	return b.String()
+}
@@ -0,0 +1,25 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStress(t *testing.T) {
+	result := Stress(100, 100, 30)
+	if result.Tokens < 100 {
+		t.Errorf("expected at least 100 tokens displayed, got %d", result.Tokens)
+	}
+	if result.Elapsed <= 0 || result.TokensPerSecond() <= 0 {
+		t.Errorf("expected positive timing, got %+v", result)
+	}
+	if !strings.Contains(result.String(), "tokens/sec") {
+		t.Errorf("unexpected summary %q", result.String())
+	}
+}
+
+func TestStressResult_ZeroElapsed(t *testing.T) {
+	if got := (StressResult{Tokens: 5}).TokensPerSecond(); got != 0 {
+		t.Errorf("expected 0 tokens/sec for zero elapsed, got %v", got)
+	}
+}
@@ -15,6 +15,7 @@ type Theme struct {
 	AssistantPrefix lipgloss.Style // "Assistant:" label
 	UserMessage     lipgloss.Style
 	ToolInline      lipgloss.Style // Inline tool activity in chat
+	SelectedMarker  lipgloss.Style // marks the selected message
 
 	// Sidebar section styles
 	SidebarHeading lipgloss.Style
@@ -28,6 +29,11 @@ type Theme struct {
 
 	// Input
 	InputPlaceholder lipgloss.Style
+
+	// Review screen
+	DiffAdd    lipgloss.Style // added lines
+	DiffDelete lipgloss.Style // removed lines
+	DiffHunk   lipgloss.Style // @@ hunk headers
 }
 
 // DefaultTheme returns a Theme with sensible defaults for light and dark terminals.
@@ -37,6 +43,7 @@ func DefaultTheme() Theme {
 	gray := lipgloss.Color("245")
 	amber := lipgloss.Color("214")
 	green := lipgloss.Color("2")
+	red := lipgloss.Color("1")
 	statusBg := lipgloss.Color("236")
 	statusFg := lipgloss.Color("252")
 
@@ -67,6 +74,9 @@ func DefaultTheme() Theme {
 		ToolInline: lipgloss.NewStyle().
 			Foreground(gray).
 			Italic(true),
+		SelectedMarker: lipgloss.NewStyle().
+			Foreground(amber).
+			Bold(true),
 
 		SidebarHeading: lipgloss.NewStyle().
 			Foreground(purple).
@@ -87,5 +97,12 @@ func DefaultTheme() Theme {
 		InputPlaceholder: lipgloss.NewStyle().
 			Foreground(gray).
 			Italic(true),
+
+		DiffAdd: lipgloss.NewStyle().
+			Foreground(green),
+		DiffDelete: lipgloss.NewStyle().
+			Foreground(red),
+		DiffHunk: lipgloss.NewStyle().
+			Foreground(cyan),
 	}
 }
@@ -0,0 +1,50 @@
+package undo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/gavinyap/stormtrooper/internal/tool"
+)
+
+// LastEditTool reverts the most recent logged change to a file, so the
+// agent can back out of an edit that went wrong.
+type LastEditTool struct {
+	Log *Log
+}
+
+func (t *LastEditTool) Name() string { return "undo_last_edit" }
+func (t *LastEditTool) Description() string {
+	return "Undo the most recent change made to a file with write_file, write_files, or edit_file, restoring its earlier contents (or deleting it if the change created it)"
+}
+func (t *LastEditTool) Permission() tool.PermissionLevel { return tool.PermissionPrompt }
+func (t *LastEditTool) Capabilities() []tool.Capability  { return []tool.Capability{tool.CapWrite} }
+
+func (t *LastEditTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+	"type": "object",
+	"properties": {}
+}`)
+}
+
+// Preview returns a description for the permission prompt.
+func (t *LastEditTool) Preview(json.RawMessage) string {
+	op, ok := t.Log.Last()
+	switch {
+	case !ok:
+		return "Undo the last edit (there is none)"
+	case op.Existed:
+		return fmt.Sprintf("Undo %s\nThe file gets back its earlier contents.", op)
+	default:
+		return fmt.Sprintf("Undo %s\nThe file is deleted.", op)
+	}
+}
+
+func (t *LastEditTool) Execute(context.Context, json.RawMessage) (string, error) {
+	op, err := t.Log.UndoLast()
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+	return "Undid " + op.Reverted() + ".", nil
+}
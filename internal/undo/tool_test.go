@@ -0,0 +1,39 @@
+package undo
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLastEditTool(t *testing.T) {
+	dir := t.TempDir()
+	log, _ := Open(Dir(dir), nil)
+	tl := &LastEditTool{Log: log}
+
+	if got := tl.Preview(nil); got != "Undo the last edit (there is none)" {
+		t.Errorf("empty preview = %q", got)
+	}
+	if got, _ := tl.Execute(context.Background(), nil); got != "Error: no changes to undo" {
+		t.Errorf("empty execute = %q", got)
+	}
+
+	path := filepath.Join(dir, "a.txt")
+	os.WriteFile(path, []byte("old"), 0644)
+	log.FS().WriteFile(path, []byte("new"), 0644)
+
+	if got, want := tl.Preview(nil), "Undo #1: changed "+path+"\nThe file gets back its earlier contents."; got != want {
+		t.Errorf("preview = %q, want %q", got, want)
+	}
+	got, err := tl.Execute(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Undid #1: restored " + path + "."; got != want {
+		t.Errorf("execute = %q, want %q", got, want)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "old" {
+		t.Errorf("a.txt = %q", data)
+	}
+}
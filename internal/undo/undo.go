@@ -0,0 +1,254 @@
+// Package undo copies each file the agent is about to change to
+// .stormtrooper/undo/ and logs the change, so a single edit can be
+// reverted with /undo or the undo_last_edit tool, even in a later
+// session.
+package undo
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gavinyap/stormtrooper/internal/tool"
+)
+
+const undoDir = ".stormtrooper/undo"
+
+// maxOps is how many changes are kept; the oldest are forgotten first.
+const maxOps = 100
+
+// Dir returns the undo directory for the given project directory.
+func Dir(projectDir string) string {
+	return filepath.Join(projectDir, undoDir)
+}
+
+// Op is one logged change to a file.
+type Op struct {
+	ID   int       `json:"id"`
+	Time time.Time `json:"time"`
+	Path string    `json:"path"`
+	// Existed is false when the change created the file, which undoing
+	// it deletes.
+	Existed bool `json:"existed"`
+}
+
+// String describes the change, e.g. "#3: changed main.go".
+func (op Op) String() string {
+	verb := "changed"
+	if !op.Existed {
+		verb = "created"
+	}
+	return fmt.Sprintf("#%d: %s %s", op.ID, verb, op.Path)
+}
+
+// Reverted describes what undoing the change did.
+func (op Op) Reverted() string {
+	if op.Existed {
+		return fmt.Sprintf("#%d: restored %s", op.ID, op.Path)
+	}
+	return fmt.Sprintf("#%d: deleted %s, which it created", op.ID, op.Path)
+}
+
+// Log records changes to files written through the FileSystem returned
+// by FS. Snapshots and the log are kept on the host in dir, wherever the
+// files themselves are. A Log is safe for concurrent use.
+type Log struct {
+	mu  sync.Mutex
+	dir string
+	fs  tool.FileSystem
+	ops []Op // oldest first
+}
+
+// Open loads the log in dir, for files in fsys; nil means the host.
+func Open(dir string, fsys tool.FileSystem) (*Log, error) {
+	if fsys == nil {
+		fsys = tool.LocalFS{}
+	}
+	l := &Log{dir: dir, fs: fsys}
+	data, err := os.ReadFile(l.logPath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var op Op
+		if err := json.Unmarshal(scanner.Bytes(), &op); err != nil {
+			return nil, fmt.Errorf("%s: %w", l.logPath(), err)
+		}
+		l.ops = append(l.ops, op)
+	}
+	return l, scanner.Err()
+}
+
+// FS returns a FileSystem that snapshots a file before each write or
+// removal.
+func (l *Log) FS() tool.FileSystem {
+	return loggingFS{l}
+}
+
+// Ops returns the changes that can be undone, oldest first.
+func (l *Log) Ops() []Op {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]Op(nil), l.ops...)
+}
+
+// Last returns the most recent change, if any.
+func (l *Log) Last() (Op, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.ops) == 0 {
+		return Op{}, false
+	}
+	return l.ops[len(l.ops)-1], true
+}
+
+// UndoLast reverts the most recent change.
+func (l *Log) UndoLast() (Op, error) {
+	op, ok := l.Last()
+	if !ok {
+		return Op{}, fmt.Errorf("no changes to undo")
+	}
+	return l.Undo(op.ID)
+}
+
+// Undo reverts the change with the given ID: the file gets back its
+// contents from before it, or is deleted if the change created it. A
+// change to a file changed again later cannot be undone until the later
+// change is.
+func (l *Log) Undo(id int) (Op, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	i := -1
+	for j, op := range l.ops {
+		if op.ID == id {
+			i = j
+		}
+	}
+	if i < 0 {
+		return Op{}, fmt.Errorf("no change #%d to undo", id)
+	}
+	op := l.ops[i]
+	for _, later := range l.ops[i+1:] {
+		if later.Path == op.Path {
+			return Op{}, fmt.Errorf("%s was changed again by #%d; undo that first", op.Path, later.ID)
+		}
+	}
+
+	if op.Existed {
+		data, err := os.ReadFile(l.snapshotPath(op.ID))
+		if err != nil {
+			return Op{}, fmt.Errorf("reading snapshot of %s: %w", op.Path, err)
+		}
+		if err := l.fs.WriteFile(op.Path, data, 0644); err != nil {
+			return Op{}, fmt.Errorf("restoring %s: %w", op.Path, err)
+		}
+	} else if err := l.remove(op.Path); err != nil {
+		return Op{}, fmt.Errorf("deleting %s: %w", op.Path, err)
+	}
+
+	l.ops = append(l.ops[:i:i], l.ops[i+1:]...)
+	os.Remove(l.snapshotPath(op.ID))
+	return op, l.save()
+}
+
+// record snapshots path's current contents and logs the change about to
+// be made to it.
+func (l *Log) record(path string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	op := Op{ID: 1, Time: time.Now(), Path: path}
+	if len(l.ops) > 0 {
+		op.ID = l.ops[len(l.ops)-1].ID + 1
+	}
+	if err := os.MkdirAll(l.dir, 0755); err != nil {
+		return err
+	}
+	if data, err := l.fs.ReadFile(path); err == nil {
+		op.Existed = true
+		if err := os.WriteFile(l.snapshotPath(op.ID), data, 0644); err != nil {
+			return err
+		}
+	}
+	l.ops = append(l.ops, op)
+	for len(l.ops) > maxOps {
+		os.Remove(l.snapshotPath(l.ops[0].ID))
+		l.ops = l.ops[1:]
+	}
+	return l.save()
+}
+
+// save rewrites the log file.
+func (l *Log) save() error {
+	var b bytes.Buffer
+	for _, op := range l.ops {
+		data, err := json.Marshal(op)
+		if err != nil {
+			return err
+		}
+		b.Write(data)
+		b.WriteByte('\n')
+	}
+	if err := os.MkdirAll(l.dir, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(l.logPath(), b.Bytes(), 0644)
+}
+
+// remove deletes a file a change created. Only the host filesystem and
+// file systems with a Remove method support this.
+func (l *Log) remove(path string) error {
+	var err error
+	switch fsys := l.fs.(type) {
+	case interface{ Remove(string) error }:
+		err = fsys.Remove(path)
+	case tool.LocalFS:
+		err = os.Remove(path)
+	default:
+		return fmt.Errorf("cannot delete files on this file system")
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (l *Log) logPath() string { return filepath.Join(l.dir, "log.jsonl") }
+
+func (l *Log) snapshotPath(id int) string {
+	return filepath.Join(l.dir, strconv.Itoa(id))
+}
+
+// loggingFS snapshots files before delegating writes. A change is not
+// made if its snapshot cannot be taken.
+type loggingFS struct{ l *Log }
+
+func (f loggingFS) Stat(name string) (fs.FileInfo, error) { return f.l.fs.Stat(name) }
+func (f loggingFS) ReadFile(name string) ([]byte, error)  { return f.l.fs.ReadFile(name) }
+func (f loggingFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	if err := f.l.record(name); err != nil {
+		return fmt.Errorf("saving undo snapshot: %w", err)
+	}
+	return f.l.fs.WriteFile(name, data, perm)
+}
+func (f loggingFS) MkdirAll(path string, perm fs.FileMode) error { return f.l.fs.MkdirAll(path, perm) }
+func (f loggingFS) Remove(name string) error {
+	if err := f.l.record(name); err != nil {
+		return fmt.Errorf("saving undo snapshot: %w", err)
+	}
+	return f.l.remove(name)
+}
@@ -0,0 +1,104 @@
+package undo
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUndo(t *testing.T) {
+	dir := t.TempDir()
+	log, err := Open(Dir(dir), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys := log.FS()
+	path := filepath.Join(dir, "main.go")
+	os.WriteFile(path, []byte("v1"), 0644)
+	fsys.WriteFile(path, []byte("v2"), 0644)
+	fsys.WriteFile(path, []byte("v3"), 0644)
+
+	if _, err := log.Undo(1); err == nil || !strings.Contains(err.Error(), "changed again by #2") {
+		t.Errorf("undoing a change overwritten later: %v", err)
+	}
+	op, err := log.UndoLast()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if op.ID != 2 || !op.Existed {
+		t.Errorf("undid %+v", op)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "v2" {
+		t.Errorf("after undoing #2: %q", data)
+	}
+	if _, err := log.Undo(1); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "v1" {
+		t.Errorf("after undoing #1: %q", data)
+	}
+	if _, err := log.UndoLast(); err == nil {
+		t.Error("undo with an empty log should fail")
+	}
+	if entries, _ := os.ReadDir(Dir(dir)); len(entries) != 1 {
+		t.Errorf("undo directory holds %d entries, want only the log", len(entries))
+	}
+}
+
+func TestUndoCreatedFile(t *testing.T) {
+	dir := t.TempDir()
+	log, _ := Open(Dir(dir), nil)
+	path := filepath.Join(dir, "new.txt")
+	log.FS().WriteFile(path, []byte("hello"), 0644)
+
+	op, err := log.UndoLast()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if op.Existed {
+		t.Error("a created file should be logged as not existing")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("undoing a creation should delete the file")
+	}
+}
+
+func TestUndoPersists(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "notes.txt")
+	os.WriteFile(path, []byte("before"), 0644)
+	log, _ := Open(Dir(dir), nil)
+	log.FS().WriteFile(path, []byte("after"), 0644)
+
+	reopened, err := Open(Dir(dir), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ops := reopened.Ops()
+	if len(ops) != 1 || ops[0].Path != path || ops[0].String() != "#1: changed "+path {
+		t.Fatalf("reopened log = %+v", ops)
+	}
+	if _, err := reopened.UndoLast(); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "before" {
+		t.Errorf("restored %q", data)
+	}
+}
+
+func TestUndoPrunes(t *testing.T) {
+	dir := t.TempDir()
+	log, _ := Open(Dir(dir), nil)
+	path := filepath.Join(dir, "f.txt")
+	for i := 0; i < maxOps+5; i++ {
+		log.FS().WriteFile(path, []byte{byte(i)}, 0644)
+	}
+	ops := log.Ops()
+	if len(ops) != maxOps || ops[0].ID != 6 {
+		t.Errorf("kept %d changes starting at #%d", len(ops), ops[0].ID)
+	}
+	if _, err := os.Stat(filepath.Join(Dir(dir), "1")); !os.IsNotExist(err) {
+		t.Error("snapshot of a forgotten change was kept")
+	}
+}
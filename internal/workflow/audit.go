@@ -0,0 +1,198 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Audit is one ecosystem's vulnerability scanner.
+type Audit struct {
+	Ecosystem string
+	Command   string
+	// Install tells the user how to get the scanner when it is missing.
+	Install string
+}
+
+// ErrNoManifests is returned when a project has no dependency manifest
+// that a supported scanner understands.
+var ErrNoManifests = errors.New("no go.mod, package.json, requirements.txt, or pyproject.toml found")
+
+// DetectAudits returns the scanners that apply to the project in dir.
+func DetectAudits(dir string) []Audit {
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(dir, name))
+		return err == nil
+	}
+	var audits []Audit
+	if exists("go.mod") {
+		audits = append(audits, Audit{
+			Ecosystem: "go",
+			Command:   "govulncheck ./...",
+			Install:   "go install golang.org/x/vuln/cmd/govulncheck@latest",
+		})
+	}
+	if exists("package.json") {
+		audits = append(audits, Audit{
+			Ecosystem: "npm",
+			Command:   "npm audit",
+			Install:   "install Node.js and npm",
+		})
+	}
+	switch {
+	case exists("requirements.txt"):
+		audits = append(audits, Audit{
+			Ecosystem: "pypi",
+			Command:   "pip-audit -r requirements.txt",
+			Install:   "pip install pip-audit",
+		})
+	case exists("pyproject.toml"):
+		audits = append(audits, Audit{
+			Ecosystem: "pypi",
+			Command:   "pip-audit .",
+			Install:   "pip install pip-audit",
+		})
+	}
+	return audits
+}
+
+// AuditReport is the result of a dependency audit.
+type AuditReport struct {
+	Summary  string    `json:"summary"`
+	Findings []Finding `json:"findings"`
+	// Patch is a unified diff of the proposed manifest changes. It is
+	// proposed only; the audit does not modify files.
+	Patch string `json:"patch,omitempty"`
+	// Scanners lists each scanner that ran and its outcome.
+	Scanners []ScannerResult `json:"scanners"`
+}
+
+// Finding is one vulnerability as triaged by the agent.
+type Finding struct {
+	ID        string `json:"id"`
+	Package   string `json:"package"`
+	Ecosystem string `json:"ecosystem"`
+	Installed string `json:"installed"`
+	FixedIn   string `json:"fixed_in"`
+	Severity  string `json:"severity"`
+	// Action is "upgrade", "investigate", or "ignore".
+	Action string `json:"action"`
+	Reason string `json:"reason"`
+}
+
+// ScannerResult records how one scanner run went.
+type ScannerResult struct {
+	Ecosystem string `json:"ecosystem"`
+	Command   string `json:"command"`
+	// Status is "clean", "findings", or "skipped".
+	Status string `json:"status"`
+	Note   string `json:"note,omitempty"`
+}
+
+// Actionable reports whether any finding needs an upgrade or a closer
+// look.
+func (r *AuditReport) Actionable() bool {
+	for _, f := range r.Findings {
+		if f.Action != "ignore" {
+			return true
+		}
+	}
+	return false
+}
+
+// Markdown renders the report for people.
+func (r *AuditReport) Markdown() string {
+	var b strings.Builder
+	b.WriteString("# Dependency audit\n\n")
+	for _, s := range r.Scanners {
+		fmt.Fprintf(&b, "- `%s` (%s): %s", s.Command, s.Ecosystem, s.Status)
+		if s.Note != "" {
+			b.WriteString(" — " + s.Note)
+		}
+		b.WriteString("\n")
+	}
+	if r.Summary != "" {
+		b.WriteString("\n" + r.Summary + "\n")
+	}
+	if len(r.Findings) > 0 {
+		b.WriteString("\n## Findings\n\n")
+		b.WriteString("| ID | Package | Installed | Fixed in | Severity | Action | Reason |\n")
+		b.WriteString("|---|---|---|---|---|---|---|\n")
+		for _, f := range r.Findings {
+			fmt.Fprintf(&b, "| %s | %s | %s | %s | %s | %s | %s |\n",
+				f.ID, f.Package, f.Installed, f.FixedIn, f.Severity, f.Action, strings.ReplaceAll(f.Reason, "|", `\|`))
+		}
+	}
+	if r.Patch != "" {
+		b.WriteString("\n## Proposed patch\n\n```diff\n" + strings.TrimRight(r.Patch, "\n") + "\n```\n")
+	}
+	return b.String()
+}
+
+// maxScanOutput caps how much of each scanner's output the agent sees.
+const maxScanOutput = 30 * 1024
+
+const auditPrompt = `I ran dependency vulnerability scanners on this project. Their output follows.
+
+%s
+Triage every reported vulnerability:
+- Check whether the project actually uses the affected package or code path (read the code, use grep).
+- Use package_info to confirm the fixed versions.
+- Choose an action: "upgrade" (a fixed version exists and the vulnerability applies), "investigate" (no fix yet, or impact unclear), or "ignore" (not reachable or not applicable; say why).
+- For upgrades, pick the smallest version bump that fixes the issue.
+
+Do not modify any files. Propose the upgrades as a unified diff of the dependency manifests instead.
+
+End your reply with a JSON code block in exactly this shape:
+` + "```json" + `
+{"summary": "one paragraph", "findings": [{"id": "", "package": "", "ecosystem": "", "installed": "", "fixed_in": "", "severity": "", "action": "", "reason": ""}], "patch": "unified diff, or empty"}
+` + "```"
+
+// AuditDeps runs the given scanners, has the agent triage what they
+// report, and returns the structured report. The agent is not consulted
+// when every scanner comes back clean.
+func AuditDeps(ctx context.Context, env Env, audits []Audit) (*AuditReport, error) {
+	if len(audits) == 0 {
+		return nil, ErrNoManifests
+	}
+
+	report := &AuditReport{}
+	var scans strings.Builder
+	for _, a := range audits {
+		env.logf("[audit] %s", a.Command)
+		res, err := runCommand(ctx, env.Executor, env.Dir, a.Command)
+		if err != nil {
+			return nil, err
+		}
+		sr := ScannerResult{Ecosystem: a.Ecosystem, Command: a.Command}
+		switch {
+		case res.ExitCode == exitNotFound:
+			sr.Status = "skipped"
+			sr.Note = "scanner not installed (" + a.Install + ")"
+		case res.ExitCode == 0:
+			sr.Status = "clean"
+		default:
+			// govulncheck exits 3 and npm audit and pip-audit exit 1 when
+			// they find something.
+			sr.Status = "findings"
+			fmt.Fprintf(&scans, "### %s (%s, exit status %d)\n\n```\n%s\n```\n\n",
+				a.Command, a.Ecosystem, res.ExitCode, truncate(strings.TrimSpace(res.Output), maxScanOutput))
+		}
+		report.Scanners = append(report.Scanners, sr)
+	}
+	if scans.Len() == 0 {
+		report.Summary = "No known vulnerabilities were reported."
+		return report, nil
+	}
+
+	env.logf("[audit] triaging findings")
+	triaged, err := askJSON[AuditReport](ctx, env.Agent, fmt.Sprintf(auditPrompt, scans.String()))
+	if err != nil {
+		return nil, err
+	}
+	triaged.Scanners = report.Scanners
+	return triaged, nil
+}
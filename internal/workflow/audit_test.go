@@ -0,0 +1,104 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDetectAudits(t *testing.T) {
+	dir := t.TempDir()
+	if got := DetectAudits(dir); len(got) != 0 {
+		t.Fatalf("DetectAudits(empty) = %v", got)
+	}
+	for _, name := range []string{"go.mod", "package.json", "pyproject.toml"} {
+		os.WriteFile(filepath.Join(dir, name), nil, 0644)
+	}
+	var commands []string
+	for _, a := range DetectAudits(dir) {
+		commands = append(commands, a.Command)
+	}
+	want := "govulncheck ./...|npm audit|pip-audit ."
+	if got := strings.Join(commands, "|"); got != want {
+		t.Errorf("commands = %q, want %q", got, want)
+	}
+
+	os.WriteFile(filepath.Join(dir, "requirements.txt"), nil, 0644)
+	audits := DetectAudits(dir)
+	if got := audits[len(audits)-1].Command; got != "pip-audit -r requirements.txt" {
+		t.Errorf("with requirements.txt, python command = %q", got)
+	}
+}
+
+func TestAuditDeps_NoManifests(t *testing.T) {
+	_, err := AuditDeps(context.Background(), Env{}, nil)
+	if !errors.Is(err, ErrNoManifests) {
+		t.Errorf("err = %v, want ErrNoManifests", err)
+	}
+}
+
+func TestAuditDeps_CleanSkipsAgent(t *testing.T) {
+	a := &fakeAgent{}
+	env := Env{Agent: a, Executor: scriptExecutor{"govulncheck ./...": "echo No vulnerabilities found."}}
+	audits := []Audit{
+		{Ecosystem: "go", Command: "govulncheck ./..."},
+		{Ecosystem: "npm", Command: "npm audit", Install: "install npm"},
+	}
+	r, err := AuditDeps(context.Background(), env, audits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(a.prompts) != 0 {
+		t.Errorf("agent was asked %d times, want 0", len(a.prompts))
+	}
+	if r.Scanners[0].Status != "clean" || r.Scanners[1].Status != "skipped" {
+		t.Errorf("scanners = %+v", r.Scanners)
+	}
+	if !strings.Contains(r.Scanners[1].Note, "install npm") {
+		t.Errorf("skipped note = %q, want install hint", r.Scanners[1].Note)
+	}
+	if r.Actionable() {
+		t.Error("clean report should not be actionable")
+	}
+}
+
+func TestAuditDeps_Triage(t *testing.T) {
+	a := &fakeAgent{replies: []string{"Only one applies.\n```json\n" + `{
+		"summary": "One reachable vulnerability.",
+		"findings": [
+			{"id": "GO-2024-0001", "package": "golang.org/x/net", "installed": "v0.17.0", "fixed_in": "v0.23.0", "action": "upgrade", "reason": "HTTP/2 server in use"},
+			{"id": "GO-2024-0002", "package": "golang.org/x/text", "installed": "v0.3.0", "action": "ignore", "reason": "language | tag parsing unused"}
+		],
+		"patch": "--- a/go.mod\n+++ b/go.mod\n"
+	}` + "\n```"}}
+	env := Env{Agent: a, Executor: scriptExecutor{"govulncheck ./...": "echo 'Vulnerability #1: GO-2024-0001'; exit 3"}}
+
+	r, err := AuditDeps(context.Background(), env, []Audit{{Ecosystem: "go", Command: "govulncheck ./..."}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(a.prompts) != 1 || !strings.Contains(a.prompts[0], "Vulnerability #1: GO-2024-0001") {
+		t.Fatalf("prompt did not include scanner output: %q", a.prompts)
+	}
+	if len(r.Findings) != 2 || !r.Actionable() {
+		t.Fatalf("findings = %+v", r.Findings)
+	}
+	if len(r.Scanners) != 1 || r.Scanners[0].Status != "findings" {
+		t.Errorf("scanners = %+v", r.Scanners)
+	}
+
+	md := r.Markdown()
+	for _, want := range []string{
+		"- `govulncheck ./...` (go): findings",
+		"| GO-2024-0001 | golang.org/x/net | v0.17.0 | v0.23.0 |  | upgrade | HTTP/2 server in use |",
+		`language \| tag parsing unused`,
+		"```diff\n--- a/go.mod\n+++ b/go.mod\n```",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("markdown missing %q:\n%s", want, md)
+		}
+	}
+}
@@ -0,0 +1,310 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ConflictHunk is one region between git conflict markers.
+type ConflictHunk struct {
+	// Start and End are the 1-based lines of the <<<<<<< and >>>>>>>
+	// markers.
+	Start, End int
+	// OursLabel and TheirsLabel are the names on the markers, usually
+	// the branch or commit on each side.
+	OursLabel, TheirsLabel string
+	Ours, Theirs           string
+	// Base is the common ancestor's text, present with
+	// merge.conflictStyle diff3 or zdiff3.
+	Base string
+}
+
+// ParseConflicts finds the conflict hunks in src. Markers must start a
+// line and be exactly seven characters, as git writes them.
+func ParseConflicts(src string) []ConflictHunk {
+	const (
+		outside = iota
+		inOurs
+		inBase
+		inTheirs
+	)
+	var (
+		hunks []ConflictHunk
+		cur   ConflictHunk
+		part  []string
+		state = outside
+	)
+	for i, line := range strings.Split(src, "\n") {
+		n := i + 1
+		switch {
+		case state == outside && isMarker(line, "<<<<<<<"):
+			cur = ConflictHunk{Start: n, OursLabel: markerLabel(line)}
+			part, state = nil, inOurs
+		case state == inOurs && isMarker(line, "|||||||"):
+			cur.Ours, part, state = joinLines(part), nil, inBase
+		case (state == inOurs || state == inBase) && isMarker(line, "======="):
+			if state == inOurs {
+				cur.Ours = joinLines(part)
+			} else {
+				cur.Base = joinLines(part)
+			}
+			part, state = nil, inTheirs
+		case state == inTheirs && isMarker(line, ">>>>>>>"):
+			cur.Theirs, cur.End, cur.TheirsLabel = joinLines(part), n, markerLabel(line)
+			hunks = append(hunks, cur)
+			state = outside
+		case state != outside:
+			part = append(part, line)
+		}
+	}
+	return hunks
+}
+
+func isMarker(line, marker string) bool {
+	rest, ok := strings.CutPrefix(line, marker)
+	return ok && (rest == "" || rest[0] == ' ')
+}
+
+func markerLabel(line string) string {
+	return strings.TrimSpace(line[7:])
+}
+
+func joinLines(lines []string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// resolveHunks replaces each hunk in src with its resolution. hunks must
+// be those ParseConflicts returned for src.
+func resolveHunks(src string, hunks []ConflictHunk, resolutions []string) string {
+	lines := strings.Split(src, "\n")
+	var out []string
+	next := 1
+	for i, h := range hunks {
+		out = append(out, lines[next-1:h.Start-1]...)
+		if r := resolutions[i]; r != "" {
+			out = append(out, strings.Split(strings.TrimSuffix(r, "\n"), "\n")...)
+		}
+		next = h.End + 1
+	}
+	out = append(out, lines[next-1:]...)
+	return strings.Join(out, "\n")
+}
+
+// ConflictFile is the outcome for one conflicted file.
+type ConflictFile struct {
+	File  string
+	Hunks int
+	// Notes are the agent's reasons, one per hunk.
+	Notes []string
+	// Resolved reports whether the resolution was approved, written, and
+	// staged.
+	Resolved bool
+	// Error explains why the file was left alone.
+	Error string `json:",omitempty"`
+}
+
+// ConflictResult is the outcome of ResolveConflicts.
+type ConflictResult struct {
+	Files []ConflictFile
+}
+
+// Resolved reports whether every conflicted file was resolved.
+func (r *ConflictResult) Resolved() bool {
+	for _, f := range r.Files {
+		if !f.Resolved {
+			return false
+		}
+	}
+	return true
+}
+
+// Summary renders the result for people.
+func (r *ConflictResult) Summary() string {
+	var b strings.Builder
+	if len(r.Files) == 0 {
+		b.WriteString("No conflicted files.\n")
+		return b.String()
+	}
+	for _, f := range r.Files {
+		status := "resolved"
+		switch {
+		case f.Error != "":
+			status = "not resolved: " + f.Error
+		case !f.Resolved:
+			status = "not applied"
+		}
+		fmt.Fprintf(&b, "- %s (%d hunk(s), %s)\n", f.File, f.Hunks, status)
+		for i, n := range f.Notes {
+			fmt.Fprintf(&b, "  %d. %s\n", i+1, n)
+		}
+	}
+	return b.String()
+}
+
+// conflictContext is how many lines around each hunk the prompt shows.
+const conflictContext = 10
+
+const resolvePrompt = `%s has %d git merge conflict(s) between %s and %s. Resolve each one.
+
+%s
+Combine both sides' intent where they are compatible; where they truly conflict, keep what the surrounding code and the rest of the project need, and say so. Read other files or run git log/git show with shell_exec if you need history. Do not modify any files: I will apply your resolutions after review.
+
+End your reply with a JSON code block giving, for each hunk in order, the exact text that replaces it (markers and all) and a one-line reason:
+` + "```json" + `
+{"resolutions": [{"hunk": 1, "text": "...", "reason": "..."}]}
+` + "```"
+
+type hunkResolution struct {
+	Hunk   int    `json:"hunk"`
+	Text   string `json:"text"`
+	Reason string `json:"reason"`
+}
+
+type conflictResolutions struct {
+	Resolutions []hunkResolution `json:"resolutions"`
+}
+
+// ResolveConflicts finds the files git reports as unmerged, asks the
+// agent to resolve each one's conflict hunks, and shows the resolution
+// as a diff. Approved files are written and staged with git add. paths,
+// if given, limits it to those files.
+func ResolveConflicts(ctx context.Context, env Env, paths []string) (*ConflictResult, error) {
+	res, err := runCommand(ctx, env.Executor, env.Dir, "git diff --name-only --diff-filter=U")
+	if err != nil {
+		return nil, err
+	}
+	if res.ExitCode != 0 {
+		return nil, fmt.Errorf("git diff: %s", strings.TrimSpace(res.Output))
+	}
+	files := strings.Fields(res.Output)
+	if len(paths) > 0 {
+		want := map[string]bool{}
+		for _, p := range paths {
+			want[filepath.ToSlash(filepath.Clean(p))] = true
+		}
+		var kept []string
+		for _, f := range files {
+			if want[f] {
+				kept = append(kept, f)
+			}
+		}
+		files = kept
+	}
+
+	branch := currentBranch(ctx, env)
+	result := &ConflictResult{}
+	for _, file := range files {
+		cf, err := resolveFile(ctx, env, file, branch)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			cf.Error = err.Error()
+		}
+		result.Files = append(result.Files, cf)
+	}
+	return result, nil
+}
+
+// currentBranch names HEAD for the prompt, or returns "" when detached,
+// as it is during a rebase.
+func currentBranch(ctx context.Context, env Env) string {
+	res, err := runCommand(ctx, env.Executor, env.Dir, "git rev-parse --abbrev-ref HEAD")
+	if err != nil || res.ExitCode != 0 {
+		return ""
+	}
+	if b := strings.TrimSpace(res.Output); b != "HEAD" {
+		return b
+	}
+	return ""
+}
+
+func resolveFile(ctx context.Context, env Env, file, branch string) (ConflictFile, error) {
+	cf := ConflictFile{File: file}
+	data, err := os.ReadFile(filepath.Join(env.Dir, filepath.FromSlash(file)))
+	if err != nil {
+		return cf, err
+	}
+	src := string(data)
+	hunks := ParseConflicts(src)
+	cf.Hunks = len(hunks)
+	if len(hunks) == 0 {
+		return cf, fmt.Errorf("no conflict markers found (binary or deleted on one side?)")
+	}
+
+	ours, theirs := hunks[0].OursLabel, hunks[0].TheirsLabel
+	if ours == "HEAD" && branch != "" {
+		ours = branch + " (HEAD)"
+	}
+	env.logf("[resolve-conflicts] %s: %d hunk(s)", file, len(hunks))
+	reply, err := askJSON[conflictResolutions](ctx, env.Agent,
+		fmt.Sprintf(resolvePrompt, file, len(hunks), ours, theirs, describeHunks(src, hunks)))
+	if err != nil {
+		return cf, err
+	}
+
+	texts := make([]string, len(hunks))
+	notes := make([]string, len(hunks))
+	seen := make([]bool, len(hunks))
+	for _, r := range reply.Resolutions {
+		if r.Hunk < 1 || r.Hunk > len(hunks) {
+			return cf, fmt.Errorf("resolution for hunk %d, but there are %d", r.Hunk, len(hunks))
+		}
+		if hasMarkers(r.Text) {
+			return cf, fmt.Errorf("the resolution of hunk %d still has conflict markers", r.Hunk)
+		}
+		texts[r.Hunk-1], notes[r.Hunk-1], seen[r.Hunk-1] = r.Text, r.Reason, true
+	}
+	for i, ok := range seen {
+		if !ok {
+			return cf, fmt.Errorf("no resolution for hunk %d", i+1)
+		}
+	}
+	cf.Notes = notes
+
+	applied, err := applyEdit(ctx, env, "resolve-conflicts", file, src, resolveHunks(src, hunks, texts))
+	if err != nil || !applied {
+		return cf, err
+	}
+	add, err := runCommand(ctx, env.Executor, env.Dir, "git add -- "+shellQuote(file))
+	if err != nil {
+		return cf, err
+	}
+	if add.ExitCode != 0 {
+		return cf, fmt.Errorf("git add: %s", strings.TrimSpace(add.Output))
+	}
+	cf.Resolved = true
+	return cf, nil
+}
+
+func hasMarkers(text string) bool {
+	for _, line := range strings.Split(text, "\n") {
+		if isMarker(line, "<<<<<<<") || isMarker(line, ">>>>>>>") {
+			return true
+		}
+	}
+	return false
+}
+
+// describeHunks renders each hunk, numbered, with conflictContext lines
+// on either side.
+func describeHunks(src string, hunks []ConflictHunk) string {
+	lines := strings.Split(src, "\n")
+	var b strings.Builder
+	for i, h := range hunks {
+		start := max(h.Start-conflictContext, 1)
+		end := min(h.End+conflictContext, len(lines))
+		fmt.Fprintf(&b, "Hunk %d (lines %d-%d):\n```\n", i+1, h.Start, h.End)
+		for n := start; n <= end; n++ {
+			b.WriteString(lines[n-1] + "\n")
+		}
+		b.WriteString("```\n\n")
+	}
+	return b.String()
+}
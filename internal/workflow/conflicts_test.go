@@ -0,0 +1,127 @@
+package workflow
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const conflicted = `package a
+
+<<<<<<< HEAD
+const Name = "ours"
+=======
+const Name = "theirs"
+>>>>>>> feature
+
+func F() {
+<<<<<<< HEAD
+	one()
+||||||| base
+	zero()
+=======
+	two()
+>>>>>>> feature
+}
+`
+
+func TestParseConflicts(t *testing.T) {
+	hunks := ParseConflicts(conflicted)
+	if len(hunks) != 2 {
+		t.Fatalf("hunks = %+v", hunks)
+	}
+	want := []ConflictHunk{
+		{Start: 3, End: 7, OursLabel: "HEAD", TheirsLabel: "feature", Ours: "const Name = \"ours\"\n", Theirs: "const Name = \"theirs\"\n"},
+		{Start: 10, End: 16, OursLabel: "HEAD", TheirsLabel: "feature", Ours: "\tone()\n", Base: "\tzero()\n", Theirs: "\ttwo()\n"},
+	}
+	for i := range want {
+		if hunks[i] != want[i] {
+			t.Errorf("hunk %d = %+v, want %+v", i, hunks[i], want[i])
+		}
+	}
+}
+
+func TestParseConflicts_IgnoresLookalikes(t *testing.T) {
+	src := "<<<<<<<< not a marker\n=======\n"
+	if hunks := ParseConflicts(src); len(hunks) != 0 {
+		t.Errorf("hunks = %+v", hunks)
+	}
+}
+
+func TestResolveHunks(t *testing.T) {
+	got := resolveHunks(conflicted, ParseConflicts(conflicted), []string{"const Name = \"both\"\n", ""})
+	want := "package a\n\nconst Name = \"both\"\n\nfunc F() {\n}\n"
+	if got != want {
+		t.Errorf("resolveHunks() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestResolveConflicts(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "a.go"), []byte(conflicted), 0644)
+	os.WriteFile(filepath.Join(dir, "b.go"), []byte(conflicted), 0644)
+	staged := filepath.Join(dir, "staged")
+	exec := scriptExecutor{
+		"git diff --name-only --diff-filter=U": "echo a.go; echo b.go",
+		"git rev-parse --abbrev-ref HEAD":      "echo main",
+		"git add -- 'a.go'":                    "echo a.go >> " + staged,
+	}
+	a := &fakeAgent{replies: []string{
+		"```json\n" + `{"resolutions": [{"hunk": 1, "text": "const Name = \"both\"\n", "reason": "keep both"}, {"hunk": 2, "text": "\tone()\n\ttwo()\n", "reason": "call both"}]}` + "\n```",
+		"```json\n" + `{"resolutions": [{"hunk": 1, "text": "x\n", "reason": "partial"}]}` + "\n```",
+	}}
+	perm := &recordingPermission{allow: true}
+	r, err := ResolveConflicts(context.Background(), Env{Agent: a, Executor: exec, Dir: dir, Permission: perm}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Files) != 2 || !r.Files[0].Resolved || r.Files[1].Resolved || r.Resolved() {
+		t.Fatalf("result = %+v", r)
+	}
+	if !strings.Contains(r.Files[1].Error, "no resolution for hunk 2") {
+		t.Errorf("b.go error = %q", r.Files[1].Error)
+	}
+	if !strings.Contains(a.prompts[0], "between main (HEAD) and feature") || !strings.Contains(a.prompts[0], "Hunk 2 (lines 10-16)") {
+		t.Errorf("prompt =\n%s", a.prompts[0])
+	}
+	if len(perm.previews) != 1 || !strings.Contains(perm.previews[0], "+\ttwo()") {
+		t.Errorf("previews = %q", perm.previews)
+	}
+	got, _ := os.ReadFile(filepath.Join(dir, "a.go"))
+	if want := "package a\n\nconst Name = \"both\"\n\nfunc F() {\n\tone()\n\ttwo()\n}\n"; string(got) != want {
+		t.Errorf("a.go =\n%s", got)
+	}
+	if data, _ := os.ReadFile(staged); string(data) != "a.go\n" {
+		t.Errorf("staged = %q, want a.go", data)
+	}
+	if s := r.Summary(); !strings.Contains(s, "- a.go (2 hunk(s), resolved)\n  1. keep both\n  2. call both\n") {
+		t.Errorf("Summary() =\n%s", s)
+	}
+}
+
+func TestResolveConflicts_Declined(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "a.go"), []byte(conflicted), 0644)
+	exec := scriptExecutor{
+		"git diff --name-only --diff-filter=U": "echo a.go; echo other.go",
+		"git rev-parse --abbrev-ref HEAD":      "echo HEAD",
+	}
+	a := &fakeAgent{replies: []string{
+		"```json\n" + `{"resolutions": [{"hunk": 1, "text": "", "reason": "drop"}, {"hunk": 2, "text": "", "reason": "drop"}]}` + "\n```",
+	}}
+	r, err := ResolveConflicts(context.Background(), Env{Agent: a, Executor: exec, Dir: dir, Permission: &recordingPermission{}}, []string{"./a.go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Files) != 1 || r.Files[0].Resolved || r.Files[0].Error != "" {
+		t.Fatalf("result = %+v", r)
+	}
+	if !strings.Contains(a.prompts[0], "between HEAD and feature") {
+		t.Errorf("prompt should use the marker label when detached:\n%s", a.prompts[0])
+	}
+	if got, _ := os.ReadFile(filepath.Join(dir, "a.go")); string(got) != conflicted {
+		t.Error("a.go changed although the resolution was declined")
+	}
+}
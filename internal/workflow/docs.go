@@ -0,0 +1,434 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// UndocumentedSymbol is an exported declaration without a doc comment.
+type UndocumentedSymbol struct {
+	Name string // "Func", "Type", "Type.Method", or a const or var name
+	Kind string // func, method, type, const, or var
+	// Line is where the comment goes: the line of the declaration, or of
+	// the spec within a grouped declaration.
+	Line   int
+	Indent string
+}
+
+// PackageDocs lists what a package is missing.
+type PackageDocs struct {
+	Name string // package name
+	Dir  string // relative to the project
+	// Files maps each source file (relative to the project) to its
+	// undocumented symbols, in source order.
+	Files map[string][]UndocumentedSymbol
+	// HasPackageDoc reports whether any file has a package comment.
+	HasPackageDoc bool
+}
+
+// FindUndocumented parses the non-test Go files in the package directory
+// rel and returns the exported symbols that lack doc comments.
+func FindUndocumented(dir, rel string) (*PackageDocs, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, rel, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	docs := &PackageDocs{Dir: filepath.ToSlash(rel), Files: map[string][]UndocumentedSymbol{}}
+	for _, path := range matches {
+		if strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		fset := token.NewFileSet()
+		file, err := parser.ParseFile(fset, path, src, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		if docs.Name == "" {
+			docs.Name = file.Name.Name
+		}
+		if file.Doc != nil {
+			docs.HasPackageDoc = true
+		}
+		relPath, _ := filepath.Rel(dir, path)
+		if symbols := undocumented(fset, file, strings.Split(string(src), "\n")); len(symbols) > 0 {
+			docs.Files[filepath.ToSlash(relPath)] = symbols
+		}
+	}
+	if docs.Name == "" {
+		return nil, fmt.Errorf("no Go source files in %s", rel)
+	}
+	return docs, nil
+}
+
+// undocumented returns file's exported declarations without doc
+// comments. Specs in a grouped declaration count as documented when the
+// group has a comment or the spec has a trailing one.
+func undocumented(fset *token.FileSet, file *ast.File, lines []string) []UndocumentedSymbol {
+	var symbols []UndocumentedSymbol
+	add := func(name, kind string, pos token.Pos) {
+		line := fset.Position(pos).Line
+		indent := ""
+		if line-1 < len(lines) {
+			text := lines[line-1]
+			indent = text[:len(text)-len(strings.TrimLeft(text, " \t"))]
+		}
+		symbols = append(symbols, UndocumentedSymbol{Name: name, Kind: kind, Line: line, Indent: indent})
+	}
+
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Doc != nil || !d.Name.IsExported() {
+				continue
+			}
+			name := funcName(d)
+			if d.Recv != nil {
+				recv, _, _ := strings.Cut(name, ".")
+				if !ast.IsExported(recv) {
+					continue
+				}
+				add(name, "method", d.Pos())
+			} else {
+				add(name, "func", d.Pos())
+			}
+		case *ast.GenDecl:
+			if d.Tok == token.IMPORT {
+				continue
+			}
+			grouped := d.Lparen.IsValid()
+			if d.Doc != nil && (!grouped || d.Tok != token.TYPE) {
+				continue
+			}
+			for _, spec := range d.Specs {
+				var name string
+				var doc, comment *ast.CommentGroup
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					name, doc, comment = s.Name.Name, s.Doc, s.Comment
+				case *ast.ValueSpec:
+					name, doc, comment = s.Names[0].Name, s.Doc, s.Comment
+				}
+				if !ast.IsExported(name) || doc != nil || (grouped && comment != nil) {
+					continue
+				}
+				pos := spec.Pos()
+				if !grouped {
+					pos = d.Pos()
+				}
+				add(name, d.Tok.String(), pos)
+			}
+		}
+	}
+	return symbols
+}
+
+// insertComments adds each symbol's doc comment above its declaration.
+// docs maps symbol names to comment text without the // markers; symbols
+// without an entry are left alone.
+func insertComments(src string, symbols []UndocumentedSymbol, docs map[string]string) string {
+	lines := strings.Split(src, "\n")
+	sorted := append([]UndocumentedSymbol(nil), symbols...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Line > sorted[j].Line })
+	for _, s := range sorted {
+		text := strings.TrimSpace(docs[s.Name])
+		if text == "" || s.Line < 1 || s.Line > len(lines) {
+			continue
+		}
+		comment := commentLines(text, s.Indent)
+		at := s.Line - 1
+		lines = append(lines[:at], append(comment, lines[at:]...)...)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// commentLines formats text as // comment lines with the given indent.
+func commentLines(text, indent string) []string {
+	var out []string
+	for _, l := range strings.Split(text, "\n") {
+		l = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(l), "//"))
+		if l == "" {
+			out = append(out, indent+"//")
+		} else {
+			out = append(out, indent+"// "+l)
+		}
+	}
+	return out
+}
+
+// insertPackageDoc adds a package comment above the package clause.
+func insertPackageDoc(src, text string) (string, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.PackageClauseOnly)
+	if err != nil {
+		return "", err
+	}
+	lines := strings.Split(src, "\n")
+	at := fset.Position(file.Package).Line - 1
+	comment := commentLines(text, "")
+	if at > 0 && strings.TrimSpace(lines[at-1]) != "" {
+		// Keep build constraints and license headers separate.
+		comment = append([]string{""}, comment...)
+	}
+	lines = append(lines[:at], append(comment, lines[at:]...)...)
+	return strings.Join(lines, "\n"), nil
+}
+
+// mergeReadmeSection replaces the section of readme with the same "## "
+// heading as section, or appends section if there is none.
+func mergeReadmeSection(readme, section, title string) string {
+	section = strings.TrimSpace(section) + "\n"
+	heading, _, _ := strings.Cut(section, "\n")
+	if strings.TrimSpace(readme) == "" {
+		return "# " + title + "\n\n" + section
+	}
+	lines := strings.Split(readme, "\n")
+	for i, l := range lines {
+		if strings.TrimSpace(l) != strings.TrimSpace(heading) {
+			continue
+		}
+		end := len(lines)
+		for j := i + 1; j < len(lines); j++ {
+			if strings.HasPrefix(lines[j], "## ") || strings.HasPrefix(lines[j], "# ") {
+				end = j
+				break
+			}
+		}
+		rest := strings.Join(lines[end:], "\n")
+		if rest != "" {
+			rest = "\n" + rest
+		}
+		before := strings.Join(lines[:i], "\n")
+		if before != "" {
+			before += "\n"
+		}
+		return before + section + rest
+	}
+	return strings.TrimRight(readme, "\n") + "\n\n" + section
+}
+
+// GenDocsOptions configures GenDocs.
+type GenDocsOptions struct {
+	// Package is the package directory, relative to the project.
+	Package string
+	// Readme asks for an overview section in the package's README.md.
+	Readme bool
+}
+
+// DocEdit is one change GenDocs proposed.
+type DocEdit struct {
+	File    string
+	Symbols []string
+	Applied bool
+}
+
+// GenDocsResult is the outcome of GenDocs.
+type GenDocsResult struct {
+	Package string
+	Edits   []DocEdit
+}
+
+// Summary renders the result for people.
+func (r *GenDocsResult) Summary() string {
+	var b strings.Builder
+	if len(r.Edits) == 0 {
+		fmt.Fprintf(&b, "%s: every exported symbol is documented.\n", r.Package)
+		return b.String()
+	}
+	for _, e := range r.Edits {
+		status := "applied"
+		if !e.Applied {
+			status = "not applied"
+		}
+		fmt.Fprintf(&b, "- %s (%s): %s\n", e.File, status, strings.Join(e.Symbols, ", "))
+	}
+	return b.String()
+}
+
+// maxDocSource caps how much of a file is included in a prompt.
+const maxDocSource = 60 * 1024
+
+const genDocsPrompt = `Write Go doc comments for the exported symbols in %s that have none:
+%s
+The file:
+
+` + "```go\n%s\n```" + `
+
+Read whatever else you need to describe each symbol accurately. Follow Go conventions: start with the symbol's name, say what it does and anything a caller must know, and keep it short. Do not edit files; I will insert the comments.
+
+Reply with a JSON code block mapping each symbol to its comment text, without the // markers:
+` + "```json" + `
+{"comments": {"Symbol": "Symbol does ..."}}
+` + "```"
+
+const genDocsPackagePrompt = `Now write package-level documentation for package %s (%s).%s
+
+Reply with a JSON code block:
+` + "```json" + `
+{"package_doc": "Package %s ...", "readme": "## ...\n..."}
+` + "```"
+
+// GenDocs finds undocumented exported symbols in a package and, one file
+// at a time, asks the agent for doc comments, inserts them, and shows the
+// diff for approval before writing. It then does the same for a package
+// comment and, if asked, a README section.
+func GenDocs(ctx context.Context, env Env, opts GenDocsOptions) (*GenDocsResult, error) {
+	rel, err := packageDir(env.Dir, opts.Package)
+	if err != nil {
+		return nil, err
+	}
+	pkg, err := FindUndocumented(env.Dir, rel)
+	if err != nil {
+		return nil, err
+	}
+	result := &GenDocsResult{Package: packagePattern(rel)}
+
+	files := make([]string, 0, len(pkg.Files))
+	for f := range pkg.Files {
+		files = append(files, f)
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		symbols := pkg.Files[file]
+		path := filepath.Join(env.Dir, filepath.FromSlash(file))
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		src := string(data)
+
+		var list strings.Builder
+		for _, s := range symbols {
+			fmt.Fprintf(&list, "- %s %s (line %d)\n", s.Kind, s.Name, s.Line)
+		}
+		env.logf("[gen-docs] %s: %d symbol(s)", file, len(symbols))
+		reply, err := askJSON[struct {
+			Comments map[string]string `json:"comments"`
+		}](ctx, env.Agent, fmt.Sprintf(genDocsPrompt, file, list.String(), truncate(src, maxDocSource)))
+		if err != nil {
+			return nil, err
+		}
+		var names []string
+		for _, s := range symbols {
+			if strings.TrimSpace(reply.Comments[s.Name]) != "" {
+				names = append(names, s.Name)
+			}
+		}
+		if len(names) == 0 {
+			continue
+		}
+		edit, err := proposeEdit(ctx, env, file, src, insertComments(src, symbols, reply.Comments), true)
+		if err != nil {
+			return nil, err
+		}
+		edit.Symbols = names
+		result.Edits = append(result.Edits, edit)
+	}
+
+	if pkg.HasPackageDoc && !opts.Readme {
+		return result, nil
+	}
+	var asks []string
+	if !pkg.HasPackageDoc {
+		asks = append(asks, ` "package_doc" is the package comment: start with "Package `+pkg.Name+`", say what the package is for and how it fits into the project.`)
+	}
+	if opts.Readme {
+		asks = append(asks, ` "readme" is a Markdown section for the package's README.md: a "## " heading, an overview, and a short usage example; leave it empty if a README section adds nothing.`)
+	}
+	env.logf("[gen-docs] package documentation")
+	reply, err := askJSON[struct {
+		PackageDoc string `json:"package_doc"`
+		Readme     string `json:"readme"`
+	}](ctx, env.Agent, fmt.Sprintf(genDocsPackagePrompt, pkg.Name, result.Package, strings.Join(asks, ""), pkg.Name))
+	if err != nil {
+		return nil, err
+	}
+
+	if !pkg.HasPackageDoc && strings.TrimSpace(reply.PackageDoc) != "" {
+		file, err := packageDocFile(env.Dir, pkg)
+		if err != nil {
+			return nil, err
+		}
+		path := filepath.Join(env.Dir, filepath.FromSlash(file))
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		updated, err := insertPackageDoc(string(data), reply.PackageDoc)
+		if err != nil {
+			return nil, err
+		}
+		edit, err := proposeEdit(ctx, env, file, string(data), updated, true)
+		if err != nil {
+			return nil, err
+		}
+		edit.Symbols = []string{"package " + pkg.Name}
+		result.Edits = append(result.Edits, edit)
+	}
+	if opts.Readme && strings.TrimSpace(reply.Readme) != "" {
+		file := filepath.ToSlash(filepath.Join(pkg.Dir, "README.md"))
+		path := filepath.Join(env.Dir, filepath.FromSlash(file))
+		data, err := os.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		edit, err := proposeEdit(ctx, env, file, string(data), mergeReadmeSection(string(data), reply.Readme, pkg.Name), false)
+		if err != nil {
+			return nil, err
+		}
+		edit.Symbols = []string{"README section"}
+		result.Edits = append(result.Edits, edit)
+	}
+	return result, nil
+}
+
+// packageDocFile picks the file for a new package comment: doc.go or the
+// file named after the package if either exists, else the first source
+// file.
+func packageDocFile(dir string, pkg *PackageDocs) (string, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, pkg.Dir, "*.go"))
+	if err != nil {
+		return "", err
+	}
+	var files []string
+	for _, m := range matches {
+		if !strings.HasSuffix(m, "_test.go") {
+			files = append(files, filepath.Base(m))
+		}
+	}
+	if len(files) == 0 {
+		return "", fmt.Errorf("no Go source files in %s", pkg.Dir)
+	}
+	choice := files[0]
+	for _, f := range files {
+		if f == "doc.go" || (f == pkg.Name+".go" && choice != "doc.go") {
+			choice = f
+		}
+	}
+	return filepath.ToSlash(filepath.Join(pkg.Dir, choice)), nil
+}
+
+// proposeEdit offers the edit from old to updated for approval. Go
+// sources must still parse after the edit.
+func proposeEdit(ctx context.Context, env Env, file, old, updated string, goSource bool) (DocEdit, error) {
+	edit := DocEdit{File: file}
+	if goSource {
+		if _, err := parser.ParseFile(token.NewFileSet(), file, updated, parser.ParseComments); err != nil {
+			env.logf("[gen-docs] %s: skipped, the edit does not parse: %v", file, err)
+			return edit, nil
+		}
+	}
+	applied, err := applyEdit(ctx, env, "gen-docs", file, old, updated)
+	edit.Applied = applied
+	return edit, err
+}
@@ -0,0 +1,187 @@
+package workflow
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const shapesSource = `//go:build !js
+
+package shapes
+
+import "math"
+
+// Circle is documented.
+type Circle struct{ R float64 }
+
+func (c Circle) Area() float64 {
+	return math.Pi * c.R * c.R
+}
+
+type square struct{}
+
+func (square) Area() float64 { return 0 }
+
+func New(r float64) Circle { return Circle{r} }
+
+const (
+	Small = 1 // trailing comments count
+	Large = 10
+	hidden = 0
+)
+
+var Default = New(1)
+`
+
+func writeShapes(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	os.Mkdir(filepath.Join(dir, "shapes"), 0755)
+	os.WriteFile(filepath.Join(dir, "shapes", "shapes.go"), []byte(shapesSource), 0644)
+	os.WriteFile(filepath.Join(dir, "shapes", "shapes_test.go"), []byte("package shapes\n\nfunc Helper() {}\n"), 0644)
+	return dir
+}
+
+func TestFindUndocumented(t *testing.T) {
+	dir := writeShapes(t)
+	pkg, err := FindUndocumented(dir, "shapes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkg.Name != "shapes" || pkg.HasPackageDoc {
+		t.Errorf("pkg = %+v", pkg)
+	}
+	if len(pkg.Files) != 1 {
+		t.Fatalf("files = %v, want only shapes.go", pkg.Files)
+	}
+	var got []string
+	for _, s := range pkg.Files["shapes/shapes.go"] {
+		got = append(got, s.Kind+" "+s.Name)
+	}
+	want := "method Circle.Area|func New|const Large|var Default"
+	if strings.Join(got, "|") != want {
+		t.Errorf("symbols = %q, want %q", strings.Join(got, "|"), want)
+	}
+}
+
+func TestInsertComments(t *testing.T) {
+	dir := writeShapes(t)
+	pkg, _ := FindUndocumented(dir, "shapes")
+	out := insertComments(shapesSource, pkg.Files["shapes/shapes.go"], map[string]string{
+		"Circle.Area": "Area returns the circle's area.",
+		"Large":       "Large is a big size.",
+		"Default":     "Default is a unit circle.\n\nIt is shared.",
+	})
+	for _, want := range []string{
+		"// Area returns the circle's area.\nfunc (c Circle) Area()",
+		"\tSmall = 1 // trailing comments count\n\t// Large is a big size.\n\tLarge = 10",
+		"// Default is a unit circle.\n//\n// It is shared.\nvar Default",
+		"\nfunc New(r float64)",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "// New") {
+		t.Error("symbols without a comment should be left alone")
+	}
+}
+
+func TestInsertPackageDoc(t *testing.T) {
+	out, err := insertPackageDoc(shapesSource, "Package shapes computes areas.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out, "//go:build !js\n\n// Package shapes computes areas.\npackage shapes\n") {
+		t.Errorf("output:\n%s", out)
+	}
+	out, _ = insertPackageDoc("package x\n", "Package x does things.")
+	if out != "// Package x does things.\npackage x\n" {
+		t.Errorf("output = %q", out)
+	}
+}
+
+func TestMergeReadmeSection(t *testing.T) {
+	tests := []struct {
+		name, readme, want string
+	}{
+		{"new file", "", "# shapes\n\n## Overview\nNew.\n"},
+		{"append", "# shapes\n\nIntro.\n", "# shapes\n\nIntro.\n\n## Overview\nNew.\n"},
+		{"replace", "# shapes\n\n## Overview\nOld.\n\n## License\nMIT\n", "# shapes\n\n## Overview\nNew.\n\n## License\nMIT\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mergeReadmeSection(tt.readme, "## Overview\nNew.", "shapes"); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// recordingPermission approves or declines everything and records the
+// previews it was shown.
+type recordingPermission struct {
+	allow    bool
+	previews []string
+}
+
+func (p *recordingPermission) Check(_ context.Context, toolName, preview string) bool {
+	p.previews = append(p.previews, preview)
+	return p.allow
+}
+
+func TestGenDocs(t *testing.T) {
+	dir := writeShapes(t)
+	a := &fakeAgent{replies: []string{
+		"```json\n" + `{"comments": {"Circle.Area": "Area returns the area.", "New": "New returns a circle of radius r."}}` + "\n```",
+		"```json\n" + `{"package_doc": "Package shapes computes areas.", "readme": "## Usage\nCall New."}` + "\n```",
+	}}
+	perm := &recordingPermission{allow: true}
+	r, err := GenDocs(context.Background(), Env{Agent: a, Dir: dir, Permission: perm}, GenDocsOptions{Package: "shapes", Readme: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Edits) != 3 {
+		t.Fatalf("edits = %+v", r.Edits)
+	}
+	if !strings.Contains(perm.previews[0], "+// Area returns the area.") {
+		t.Errorf("preview should be a diff:\n%s", perm.previews[0])
+	}
+	src, _ := os.ReadFile(filepath.Join(dir, "shapes", "shapes.go"))
+	for _, want := range []string{"// Package shapes computes areas.\npackage shapes", "// New returns a circle of radius r.\nfunc New"} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("shapes.go missing %q:\n%s", want, src)
+		}
+	}
+	readme, _ := os.ReadFile(filepath.Join(dir, "shapes", "README.md"))
+	if string(readme) != "# shapes\n\n## Usage\nCall New.\n" {
+		t.Errorf("README.md = %q", readme)
+	}
+	if !strings.Contains(a.prompts[0], "method Circle.Area") || !strings.Contains(a.prompts[0], "func (c Circle) Area()") {
+		t.Errorf("prompt should list symbols and include the file:\n%s", a.prompts[0])
+	}
+}
+
+func TestGenDocs_Declined(t *testing.T) {
+	dir := writeShapes(t)
+	a := &fakeAgent{replies: []string{
+		"```json\n" + `{"comments": {"New": "New returns a circle."}}` + "\n```",
+		"```json\n" + `{"package_doc": ""}` + "\n```",
+	}}
+	r, err := GenDocs(context.Background(), Env{Agent: a, Dir: dir, Permission: &recordingPermission{}}, GenDocsOptions{Package: "shapes"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Edits) != 1 || r.Edits[0].Applied {
+		t.Fatalf("edits = %+v", r.Edits)
+	}
+	if src, _ := os.ReadFile(filepath.Join(dir, "shapes", "shapes.go")); string(src) != shapesSource {
+		t.Error("declined edit was written")
+	}
+	if !strings.Contains(r.Summary(), "shapes/shapes.go (not applied): New") {
+		t.Errorf("Summary() = %q", r.Summary())
+	}
+}
@@ -0,0 +1,451 @@
+package workflow
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	udiff "github.com/aymanbagabas/go-udiff"
+)
+
+// DefaultGenTestsTargets is how many functions GenTests asks for tests
+// for by default, least covered first.
+const DefaultGenTestsTargets = 10
+
+// GenTestsOptions configures GenTests.
+type GenTestsOptions struct {
+	// Package is the package directory, relative to the project.
+	Package string
+	// MaxTargets caps how many functions the agent is asked to cover.
+	// Zero means DefaultGenTestsTargets.
+	MaxTargets int
+	// MaxAttempts is how many times the agent may fix its tests when they
+	// fail to compile or pass. Zero means DefaultTestAttempts.
+	MaxAttempts int
+}
+
+// UncoveredFunc is a function with statements no test executes.
+type UncoveredFunc struct {
+	File      string // relative to the project
+	Name      string // "Func" or "Type.Method"
+	Line      int
+	Covered   int // statements
+	Total     int
+	Uncovered []LineRange
+}
+
+// LineRange is an inclusive range of source lines.
+type LineRange struct{ Start, End int }
+
+func (r LineRange) String() string {
+	if r.Start == r.End {
+		return strconv.Itoa(r.Start)
+	}
+	return fmt.Sprintf("%d-%d", r.Start, r.End)
+}
+
+// GenTestsResult is the outcome of GenTests.
+type GenTestsResult struct {
+	Package string
+	Before  float64 // statement coverage, percent
+	After   float64
+	Targets []UncoveredFunc
+	// Passed reports whether the package's tests pass with the new tests.
+	Passed bool
+	// Diff is a unified diff of the test files the agent wrote or changed.
+	Diff string
+}
+
+// Summary renders the result for people.
+func (r *GenTestsResult) Summary() string {
+	var b strings.Builder
+	if len(r.Targets) == 0 {
+		fmt.Fprintf(&b, "%s: every function is fully covered (%.1f%%).\n", r.Package, r.Before)
+		return b.String()
+	}
+	if r.Passed {
+		fmt.Fprintf(&b, "%s: coverage %.1f%% -> %.1f%%\n", r.Package, r.Before, r.After)
+	} else {
+		fmt.Fprintf(&b, "%s: coverage %.1f%% before; not re-measured because the tests fail\n", r.Package, r.Before)
+	}
+	b.WriteString("Targeted functions:\n")
+	for _, f := range r.Targets {
+		fmt.Fprintf(&b, "- %s (%s:%d): %d/%d statements covered\n", f.Name, f.File, f.Line, f.Covered, f.Total)
+	}
+	if !r.Passed {
+		b.WriteString("\nThe generated tests still fail; review the diff before keeping it.\n")
+	}
+	if r.Diff != "" {
+		b.WriteString("\n" + r.Diff)
+	} else {
+		b.WriteString("\nNo test files were changed.\n")
+	}
+	return b.String()
+}
+
+const genTestsPrompt = `Write Go tests for package %s to cover the code that no test executes. Uncovered functions, with the uncovered line ranges:
+
+%s
+Rules:
+- Read each function and the package's existing tests first, and follow their style.
+- Write table-driven tests (a slice of cases run in a loop, with t.Run where cases have names) in _test.go files in the package directory.
+- Exercise the uncovered lines, including error paths, and assert on results; do not write tests that only call functions.
+- Do not change non-test files.
+- Run ` + "`go test %s`" + ` with shell_exec and make sure the tests compile and pass before you finish.`
+
+const genTestsFixPrompt = `The tests you wrote do not pass: ` + "`%s`" + ` fails.
+
+%s
+%s
+Fix the tests. Do not change non-test files; if a test found a real bug, remove that case and mention the bug in your reply.`
+
+// GenTests measures the package's coverage, asks the agent to write
+// table-driven tests for the least covered functions, checks that they
+// compile and pass (giving the agent the failures to fix if not), and
+// reports the coverage change and the diff of the test files.
+//
+// The coverage profile is read from the project directory, so this works
+// with local and sandboxed execution but not on a remote host.
+func GenTests(ctx context.Context, env Env, opts GenTestsOptions) (*GenTestsResult, error) {
+	rel, err := packageDir(env.Dir, opts.Package)
+	if err != nil {
+		return nil, err
+	}
+	pkg := packagePattern(rel)
+	maxTargets := opts.MaxTargets
+	if maxTargets <= 0 {
+		maxTargets = DefaultGenTestsTargets
+	}
+	attempts := opts.MaxAttempts
+	if attempts <= 0 {
+		attempts = DefaultTestAttempts
+	}
+
+	result := &GenTestsResult{Package: pkg}
+	env.logf("[gen-tests] measuring coverage of %s", pkg)
+	funcs, err := measureCoverage(ctx, env, pkg)
+	if err != nil {
+		return nil, err
+	}
+	result.Before = totalCoverage(funcs)
+	for _, f := range funcs {
+		if f.Covered < f.Total {
+			result.Targets = append(result.Targets, f)
+		}
+	}
+	sort.SliceStable(result.Targets, func(i, j int) bool {
+		a, b := result.Targets[i], result.Targets[j]
+		return a.Total-a.Covered > b.Total-b.Covered
+	})
+	if len(result.Targets) > maxTargets {
+		result.Targets = result.Targets[:maxTargets]
+	}
+	if len(result.Targets) == 0 {
+		result.After, result.Passed = result.Before, true
+		return result, nil
+	}
+
+	before := readTestFiles(filepath.Join(env.Dir, rel))
+	var list strings.Builder
+	for _, f := range result.Targets {
+		var ranges []string
+		for _, r := range f.Uncovered {
+			ranges = append(ranges, r.String())
+		}
+		fmt.Fprintf(&list, "- %s (%s:%d), %d of %d statements covered; uncovered lines %s\n",
+			f.Name, f.File, f.Line, f.Covered, f.Total, strings.Join(ranges, ", "))
+	}
+	env.logf("[gen-tests] writing tests for %d function(s)", len(result.Targets))
+	if err := env.Agent.Send(ctx, fmt.Sprintf(genTestsPrompt, pkg, list.String(), pkg)); err != nil {
+		return nil, err
+	}
+
+	// Verify: the package's tests must compile and pass.
+	command := "go test -json " + pkg
+	for attempt := 1; ; attempt++ {
+		env.logf("[gen-tests] %s", command)
+		res, err := runCommand(ctx, env.Executor, env.Dir, command)
+		if err != nil {
+			return nil, err
+		}
+		if res.ExitCode == 0 {
+			result.Passed = true
+			break
+		}
+		if attempt > attempts {
+			break
+		}
+		report, source := describeFailures(env.Dir, parseFailures(command, res.Output))
+		if err := env.Agent.Send(ctx, fmt.Sprintf(genTestsFixPrompt, command, report, source)); err != nil {
+			return nil, err
+		}
+	}
+
+	if result.Passed {
+		after, err := measureCoverage(ctx, env, pkg)
+		if err != nil {
+			return nil, err
+		}
+		result.After = totalCoverage(after)
+	}
+	result.Diff = diffFiles(env.Dir, before, readTestFiles(filepath.Join(env.Dir, rel)))
+	return result, nil
+}
+
+// packageDir checks that path names a directory inside dir and returns it
+// relative to dir.
+func packageDir(dir, path string) (string, error) {
+	if path == "" {
+		path = "."
+	}
+	abs := path
+	if !filepath.IsAbs(abs) {
+		abs = filepath.Join(dir, path)
+	}
+	rel, err := filepath.Rel(dir, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is outside the project", path)
+	}
+	if info, err := os.Stat(abs); err != nil || !info.IsDir() {
+		return "", fmt.Errorf("%s is not a package directory", path)
+	}
+	return rel, nil
+}
+
+// packagePattern turns a directory relative to the project into a
+// package pattern for the go command.
+func packagePattern(rel string) string {
+	if rel == "." {
+		return "."
+	}
+	return "./" + filepath.ToSlash(rel)
+}
+
+// coverProfile is where the coverage profile is written, inside the
+// project so a sandbox container writes it where we can read it.
+const coverProfile = ".stormtrooper/gen-tests.cover"
+
+// measureCoverage runs the package's tests with coverage and returns
+// per-function statement counts.
+func measureCoverage(ctx context.Context, env Env, pkg string) ([]UncoveredFunc, error) {
+	profile := filepath.Join(env.Dir, coverProfile)
+	if err := os.MkdirAll(filepath.Dir(profile), 0755); err != nil {
+		return nil, err
+	}
+	defer os.Remove(profile)
+
+	res, err := runCommand(ctx, env.Executor, env.Dir, "go test -coverprofile="+coverProfile+" "+pkg)
+	if err != nil {
+		return nil, err
+	}
+	if res.ExitCode != 0 {
+		return nil, fmt.Errorf("go test %s fails; fix the existing tests first (stormtrooper test):\n%s",
+			pkg, truncate(strings.TrimSpace(res.Output), maxFailureOutput))
+	}
+	blocks, err := parseCoverProfile(profile)
+	if err != nil {
+		return nil, err
+	}
+	return functionCoverage(env.Dir, modulePath(env.Dir), blocks)
+}
+
+// coverBlock is one basic block from a coverage profile.
+type coverBlock struct {
+	StartLine, StartCol, EndLine, EndCol int
+	NumStmt                              int
+	Count                                int
+}
+
+// parseCoverProfile reads a go test -coverprofile file, keyed by the
+// file's import path. Blocks listed more than once are merged.
+func parseCoverProfile(path string) (map[string][]coverBlock, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading coverage profile: %w", err)
+	}
+	defer f.Close()
+
+	type key struct {
+		file string
+		pos  [4]int
+	}
+	seen := map[key]int{} // index into files[file]
+	files := map[string][]coverBlock{}
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := sc.Text()
+		if strings.HasPrefix(line, "mode:") || line == "" {
+			continue
+		}
+		// name.go:startLine.startCol,endLine.endCol numStmt count
+		colon := strings.LastIndex(line, ":")
+		if colon < 0 {
+			return nil, fmt.Errorf("bad coverage line %q", line)
+		}
+		var b coverBlock
+		if _, err := fmt.Sscanf(line[colon+1:], "%d.%d,%d.%d %d %d",
+			&b.StartLine, &b.StartCol, &b.EndLine, &b.EndCol, &b.NumStmt, &b.Count); err != nil {
+			return nil, fmt.Errorf("bad coverage line %q: %w", line, err)
+		}
+		name := line[:colon]
+		k := key{name, [4]int{b.StartLine, b.StartCol, b.EndLine, b.EndCol}}
+		if i, ok := seen[k]; ok {
+			files[name][i].Count = max(files[name][i].Count, b.Count)
+			continue
+		}
+		seen[k] = len(files[name])
+		files[name] = append(files[name], b)
+	}
+	return files, sc.Err()
+}
+
+// functionCoverage assigns profile blocks to the functions that contain
+// them.
+func functionCoverage(dir, module string, profile map[string][]coverBlock) ([]UncoveredFunc, error) {
+	names := make([]string, 0, len(profile))
+	for name := range profile {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var funcs []UncoveredFunc
+	for _, name := range names {
+		rel := strings.TrimPrefix(strings.TrimPrefix(name, module), "/")
+		path := filepath.Join(dir, filepath.FromSlash(rel))
+		fset := token.NewFileSet()
+		file, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", rel, err)
+		}
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Body == nil {
+				continue
+			}
+			start, end := fset.Position(fn.Pos()).Line, fset.Position(fn.End()).Line
+			uf := UncoveredFunc{File: filepath.ToSlash(rel), Name: funcName(fn), Line: start}
+			for _, b := range profile[name] {
+				if b.StartLine < start || b.EndLine > end {
+					continue
+				}
+				uf.Total += b.NumStmt
+				if b.Count > 0 {
+					uf.Covered += b.NumStmt
+				} else if b.NumStmt > 0 {
+					uf.Uncovered = addRange(uf.Uncovered, LineRange{b.StartLine, b.EndLine})
+				}
+			}
+			if uf.Total > 0 {
+				funcs = append(funcs, uf)
+			}
+		}
+	}
+	return funcs, nil
+}
+
+// addRange appends r, merging it with the last range when they touch.
+// Profile blocks come in source order.
+func addRange(ranges []LineRange, r LineRange) []LineRange {
+	if n := len(ranges); n > 0 && r.Start <= ranges[n-1].End+1 {
+		ranges[n-1].End = max(ranges[n-1].End, r.End)
+		return ranges
+	}
+	return append(ranges, r)
+}
+
+// funcName returns "Name" for functions and "Type.Name" for methods.
+func funcName(fn *ast.FuncDecl) string {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return fn.Name.Name
+	}
+	t := fn.Recv.List[0].Type
+	for {
+		switch x := t.(type) {
+		case *ast.StarExpr:
+			t = x.X
+			continue
+		case *ast.IndexExpr:
+			t = x.X
+			continue
+		case *ast.IndexListExpr:
+			t = x.X
+			continue
+		case *ast.Ident:
+			return x.Name + "." + fn.Name.Name
+		}
+		return fn.Name.Name
+	}
+}
+
+// totalCoverage returns the percentage of statements covered.
+func totalCoverage(funcs []UncoveredFunc) float64 {
+	var covered, total int
+	for _, f := range funcs {
+		covered += f.Covered
+		total += f.Total
+	}
+	if total == 0 {
+		return 100
+	}
+	return 100 * float64(covered) / float64(total)
+}
+
+// readTestFiles returns the contents of the _test.go files in dir.
+func readTestFiles(dir string) map[string]string {
+	files := map[string]string{}
+	matches, _ := filepath.Glob(filepath.Join(dir, "*_test.go"))
+	for _, m := range matches {
+		if data, err := os.ReadFile(m); err == nil {
+			files[m] = string(data)
+		}
+	}
+	return files
+}
+
+// diffFiles returns a unified diff from before to after, with paths
+// relative to dir.
+func diffFiles(dir string, before, after map[string]string) string {
+	paths := map[string]bool{}
+	for p := range before {
+		paths[p] = true
+	}
+	for p := range after {
+		paths[p] = true
+	}
+	sorted := make([]string, 0, len(paths))
+	for p := range paths {
+		sorted = append(sorted, p)
+	}
+	sort.Strings(sorted)
+
+	var b strings.Builder
+	for _, p := range sorted {
+		old, hadOld := before[p]
+		cur, hasCur := after[p]
+		if old == cur && hadOld == hasCur {
+			continue
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			rel = p
+		}
+		rel = filepath.ToSlash(rel)
+		from, to := "a/"+rel, "b/"+rel
+		if !hadOld {
+			from = "/dev/null"
+		}
+		if !hasCur {
+			to = "/dev/null"
+		}
+		b.WriteString(udiff.Unified(from, to, old, cur))
+	}
+	return b.String()
+}
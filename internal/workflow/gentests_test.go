@@ -0,0 +1,182 @@
+package workflow
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const calcSource = `package calc
+
+func Add(a, b int) int {
+	return a + b
+}
+
+func Abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+type Acc struct{ n int }
+
+func (a *Acc) Add(n int) {
+	a.n += n
+}
+`
+
+// newCalcModule writes a small module with one tested function.
+func newCalcModule(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/m\n\ngo 1.21\n"), 0644)
+	os.Mkdir(filepath.Join(dir, "calc"), 0755)
+	os.WriteFile(filepath.Join(dir, "calc", "calc.go"), []byte(calcSource), 0644)
+	os.WriteFile(filepath.Join(dir, "calc", "calc_test.go"), []byte(`package calc
+
+import "testing"
+
+func TestAdd(t *testing.T) {
+	if Add(1, 2) != 3 {
+		t.Fatal("Add")
+	}
+}
+`), 0644)
+	return dir
+}
+
+func TestParseCoverProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cover.out")
+	os.WriteFile(path, []byte(`mode: set
+example.com/m/calc/calc.go:3.24,5.2 1 1
+example.com/m/calc/calc.go:7.21,8.11 1 0
+example.com/m/calc/calc.go:7.21,8.11 1 1
+example.com/m/calc/calc.go:8.11,10.3 1 0
+`), 0644)
+	blocks, err := parseCoverProfile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := blocks["example.com/m/calc/calc.go"]
+	if len(got) != 3 {
+		t.Fatalf("blocks = %+v, want 3 (duplicate merged)", got)
+	}
+	if got[1].Count != 1 {
+		t.Errorf("merged block count = %d, want 1", got[1].Count)
+	}
+	if got[2] != (coverBlock{StartLine: 8, StartCol: 11, EndLine: 10, EndCol: 3, NumStmt: 1}) {
+		t.Errorf("block = %+v", got[2])
+	}
+}
+
+func TestFunctionCoverage(t *testing.T) {
+	dir := newCalcModule(t)
+	profile := map[string][]coverBlock{"example.com/m/calc/calc.go": {
+		{StartLine: 3, EndLine: 5, NumStmt: 1, Count: 1},
+		{StartLine: 7, EndLine: 8, NumStmt: 1, Count: 1},
+		{StartLine: 8, EndLine: 10, NumStmt: 1},
+		{StartLine: 11, EndLine: 11, NumStmt: 1},
+		{StartLine: 16, EndLine: 18, NumStmt: 1},
+	}}
+	funcs, err := functionCoverage(dir, "example.com/m", profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(funcs) != 3 {
+		t.Fatalf("funcs = %+v", funcs)
+	}
+	abs := funcs[1]
+	if abs.Name != "Abs" || abs.File != "calc/calc.go" || abs.Line != 7 || abs.Covered != 1 || abs.Total != 3 {
+		t.Errorf("Abs = %+v", abs)
+	}
+	if len(abs.Uncovered) != 1 || abs.Uncovered[0].String() != "8-11" {
+		t.Errorf("Abs uncovered = %v, want merged 8-11", abs.Uncovered)
+	}
+	if funcs[2].Name != "Acc.Add" {
+		t.Errorf("method name = %q, want Acc.Add", funcs[2].Name)
+	}
+	if got := totalCoverage(funcs); got != 40 {
+		t.Errorf("totalCoverage = %v, want 40", got)
+	}
+}
+
+func TestPackageDir(t *testing.T) {
+	dir := newCalcModule(t)
+	tests := []struct {
+		path, want string
+		ok         bool
+	}{
+		{"./calc", "calc", true},
+		{"calc", "calc", true},
+		{"", ".", true},
+		{"../elsewhere", "", false},
+		{"calc/calc.go", "", false},
+	}
+	for _, tt := range tests {
+		got, err := packageDir(dir, tt.path)
+		if got != tt.want || (err == nil) != tt.ok {
+			t.Errorf("packageDir(%q) = %q, %v", tt.path, got, err)
+		}
+	}
+}
+
+func TestDiffFiles(t *testing.T) {
+	dir := "/p"
+	got := diffFiles(dir,
+		map[string]string{"/p/a_test.go": "one\n", "/p/same_test.go": "x\n"},
+		map[string]string{"/p/a_test.go": "one\ntwo\n", "/p/same_test.go": "x\n", "/p/new_test.go": "new\n"})
+	for _, want := range []string{"--- a/a_test.go\n+++ b/a_test.go", "+two", "--- /dev/null\n+++ b/new_test.go", "+new"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("diff missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "same_test.go") {
+		t.Errorf("unchanged file in diff:\n%s", got)
+	}
+}
+
+func TestGenTests(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not installed")
+	}
+	dir := newCalcModule(t)
+	a := &fixingAgent{fakeAgent: fakeAgent{replies: []string{"Added TestAbs."}}, fix: func() {
+		os.WriteFile(filepath.Join(dir, "calc", "abs_test.go"), []byte(`package calc
+
+import "testing"
+
+func TestAbs(t *testing.T) {
+	tests := []struct{ in, want int }{{-2, 2}, {3, 3}}
+	for _, tt := range tests {
+		if got := Abs(tt.in); got != tt.want {
+			t.Errorf("Abs(%d) = %d", tt.in, got)
+		}
+	}
+}
+`), 0644)
+	}}
+
+	r, err := GenTests(context.Background(), Env{Agent: a, Dir: dir}, GenTestsOptions{Package: "./calc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Passed || len(a.prompts) != 1 {
+		t.Fatalf("Passed = %v after %d prompts", r.Passed, len(a.prompts))
+	}
+	if r.Targets[0].Name != "Abs" || r.After <= r.Before {
+		t.Errorf("targets = %+v, coverage %.1f -> %.1f", r.Targets, r.Before, r.After)
+	}
+	if !strings.Contains(a.prompts[0], "Abs (calc/calc.go:7)") {
+		t.Errorf("prompt = %s", a.prompts[0])
+	}
+	if !strings.Contains(r.Diff, "+++ b/calc/abs_test.go") {
+		t.Errorf("diff = %s", r.Diff)
+	}
+	if _, err := os.Stat(filepath.Join(dir, coverProfile)); !os.IsNotExist(err) {
+		t.Error("coverage profile was left behind")
+	}
+}
@@ -0,0 +1,315 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gavinyap/stormtrooper/internal/symbol"
+)
+
+// RefactorOptions configures Refactor. Exactly one of Rename and Change
+// is set.
+type RefactorOptions struct {
+	// Symbol names what to refactor, as accepted by symbol.Index.Lookup.
+	Symbol string
+	// Rename is the new name. Renames are done mechanically at every
+	// reference the index finds; the agent is not involved.
+	Rename string
+	// Change describes any other change, such as a new signature, which
+	// the agent carries out file by file.
+	Change string
+	// DryRun stops after the impact analysis.
+	DryRun bool
+}
+
+// RefactorResult is the outcome of Refactor.
+type RefactorResult struct {
+	Symbol *symbol.Symbol
+	// Files are the files that reference the symbol.
+	Files []symbol.FileRefs
+	// Changed are the files the refactor would rewrite.
+	Changed []string
+	// Failed maps files the agent could not produce valid edits for to
+	// the reason.
+	Failed map[string]string
+	// Applied reports whether the edits were approved and written.
+	Applied bool
+	// Check is the result of go vet after the edits were written.
+	Check *commandResult
+}
+
+// CheckFailed reports whether go vet fails after the edits were written.
+func (r *RefactorResult) CheckFailed() bool {
+	return r.Check != nil && r.Check.ExitCode != 0
+}
+
+// Impact renders the symbol's references by file.
+func (r *RefactorResult) Impact() string {
+	s := r.Symbol
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s.%s (%s): %d reference(s) in %d file(s)\n", s.Kind, s.Package, s.Name, s.Decl, len(s.Refs), len(r.Files))
+	for _, f := range r.Files {
+		note := ""
+		if f.File == s.Decl.File {
+			note = " (declaration)"
+		}
+		fmt.Fprintf(&b, "  %4d  %s%s\n", f.Count, f.File, note)
+	}
+	return b.String()
+}
+
+// Summary renders the result for people.
+func (r *RefactorResult) Summary() string {
+	var b strings.Builder
+	b.WriteString(r.Impact())
+	if len(r.Failed) > 0 {
+		files := make([]string, 0, len(r.Failed))
+		for f := range r.Failed {
+			files = append(files, f)
+		}
+		sort.Strings(files)
+		b.WriteString("\nNot edited:\n")
+		for _, f := range files {
+			fmt.Fprintf(&b, "- %s: %s\n", f, r.Failed[f])
+		}
+	}
+	switch {
+	case len(r.Changed) == 0:
+		b.WriteString("\nNo files changed.\n")
+	case !r.Applied:
+		fmt.Fprintf(&b, "\n%d file(s) not changed: the edits were not applied.\n", len(r.Changed))
+	default:
+		fmt.Fprintf(&b, "\nUpdated %d file(s).\n", len(r.Changed))
+	}
+	if r.Check != nil {
+		if r.Check.ExitCode == 0 {
+			fmt.Fprintf(&b, "`%s` passes.\n", r.Check.Command)
+		} else {
+			fmt.Fprintf(&b, "`%s` fails:\n%s\n", r.Check.Command, truncate(strings.TrimSpace(r.Check.Output), maxFailureOutput))
+		}
+	}
+	return b.String()
+}
+
+// refactorCheck is run after the edits are written. Unlike go build it
+// also compiles the tests.
+const refactorCheck = "go vet ./..."
+
+// Refactor finds every reference to a Go symbol with the symbol index,
+// logs the impact, works out the edits for each affected file, and asks
+// for approval once for all of them before writing anything.
+func Refactor(ctx context.Context, env Env, opts RefactorOptions) (*RefactorResult, error) {
+	if (opts.Rename == "") == (opts.Change == "") {
+		return nil, errors.New("give either a new name or a change to make")
+	}
+	ix, err := symbol.Load(env.Dir)
+	if err != nil {
+		return nil, err
+	}
+	sym, err := ix.Lookup(opts.Symbol)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Rename != "" {
+		if err := sym.CheckRename(opts.Rename); err != nil {
+			return nil, err
+		}
+	}
+	result := &RefactorResult{Symbol: sym, Files: sym.Files(), Failed: map[string]string{}}
+	env.logf("[refactor] %s", strings.TrimRight(result.Impact(), "\n"))
+	if opts.DryRun {
+		return result, nil
+	}
+
+	var edits []fileEdit
+	if opts.Rename != "" {
+		edits, err = renameEdits(env.Dir, sym, opts.Rename)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		for _, f := range result.Files {
+			env.logf("[refactor] editing %s", f.File)
+			e, err := changeEdit(ctx, env, sym, f.File, opts.Change)
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil, err
+				}
+				result.Failed[f.File] = err.Error()
+				continue
+			}
+			if e.New != e.Old {
+				edits = append(edits, e)
+			}
+		}
+	}
+	for _, e := range edits {
+		result.Changed = append(result.Changed, e.File)
+	}
+	if len(edits) == 0 {
+		return result, nil
+	}
+
+	title := fmt.Sprintf("Refactor %s: update %d file(s)", sym.Name, len(edits))
+	if opts.Rename != "" {
+		title = fmt.Sprintf("Rename %s to %s in %d file(s)", sym.Name, opts.Rename, len(edits))
+	}
+	result.Applied, err = applyEdits(ctx, env, "refactor", title, edits)
+	if err != nil || !result.Applied {
+		return result, err
+	}
+	env.logf("[refactor] %s", refactorCheck)
+	check, err := runCommand(ctx, env.Executor, env.Dir, refactorCheck)
+	if err != nil {
+		return result, err
+	}
+	result.Check = &check
+	return result, nil
+}
+
+// renameEdits replaces the symbol's name at its declaration and every
+// reference.
+func renameEdits(dir string, sym *symbol.Symbol, name string) ([]fileEdit, error) {
+	offsets := map[string][]int{sym.Decl.File: {sym.Decl.Offset}}
+	for _, r := range sym.Refs {
+		offsets[r.File] = append(offsets[r.File], r.Offset)
+	}
+	var edits []fileEdit
+	for _, f := range sym.Files() {
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(f.File)))
+		if err != nil {
+			return nil, err
+		}
+		src := string(data)
+		updated := src
+		offs := offsets[f.File]
+		sort.Sort(sort.Reverse(sort.IntSlice(offs)))
+		for _, off := range offs {
+			if !strings.HasPrefix(updated[off:], sym.Name) {
+				return nil, fmt.Errorf("%s changed since it was indexed", f.File)
+			}
+			updated = updated[:off] + name + updated[off+len(sym.Name):]
+		}
+		edits = append(edits, fileEdit{File: f.File, Old: src, New: updated})
+	}
+	return edits, nil
+}
+
+// refactorEdit is one replacement the agent proposes.
+type refactorEdit struct {
+	Old string `json:"old_string"`
+	New string `json:"new_string"`
+}
+
+type refactorEdits struct {
+	Edits []refactorEdit `json:"edits"`
+}
+
+const refactorPrompt = `Refactor: %s
+
+This applies to the %s %s, declared at %s. It is referenced in %d file(s); I will ask about each in turn. Now update %s: the declaration if it is in this file, and each reference marked > below.
+
+%s
+Read whatever else you need, but do not modify any files: I apply the edits to all files together after review.
+
+End your reply with a JSON code block of edits to this file. Each old_string must appear exactly once in the file as it is now:
+` + "```json" + `
+{"edits": [{"old_string": "...", "new_string": "..."}]}
+` + "```" + `
+If nothing in this file needs to change, reply with {"edits": []}.`
+
+const refactorRetryPrompt = `Those edits cannot be applied to %s: %v. Reply with the full list of edits for the file again, as a JSON code block.`
+
+// changeEdit asks the agent for the edits one file needs, giving it one
+// more chance if they do not apply cleanly.
+func changeEdit(ctx context.Context, env Env, sym *symbol.Symbol, file, change string) (fileEdit, error) {
+	data, err := os.ReadFile(filepath.Join(env.Dir, filepath.FromSlash(file)))
+	if err != nil {
+		return fileEdit{}, err
+	}
+	src := string(data)
+	var lines []int
+	if file == sym.Decl.File {
+		lines = append(lines, sym.Decl.Line)
+	}
+	for _, r := range sym.Refs {
+		if r.File == file {
+			lines = append(lines, r.Line)
+		}
+	}
+	prompt := fmt.Sprintf(refactorPrompt, change, sym.Kind, sym.Name, sym.Decl, len(sym.Files()), file, refExcerpts(file, src, lines))
+	reply, err := askJSON[refactorEdits](ctx, env.Agent, prompt)
+	if err != nil {
+		return fileEdit{}, err
+	}
+	updated, err := replaceAll(src, reply.Edits)
+	if err != nil {
+		if reply, err = askJSON[refactorEdits](ctx, env.Agent, fmt.Sprintf(refactorRetryPrompt, file, err)); err != nil {
+			return fileEdit{}, err
+		}
+		if updated, err = replaceAll(src, reply.Edits); err != nil {
+			return fileEdit{}, err
+		}
+	}
+	return fileEdit{File: file, Old: src, New: updated}, nil
+}
+
+// replaceAll applies edits in order, each to a snippet that must occur
+// exactly once.
+func replaceAll(src string, edits []refactorEdit) (string, error) {
+	for i, e := range edits {
+		if e.Old == "" {
+			return "", fmt.Errorf("edit %d has an empty old_string", i+1)
+		}
+		switch n := strings.Count(src, e.Old); n {
+		case 1:
+			src = strings.Replace(src, e.Old, e.New, 1)
+		case 0:
+			return "", fmt.Errorf("edit %d: old_string not found", i+1)
+		default:
+			return "", fmt.Errorf("edit %d: old_string appears %d times", i+1, n)
+		}
+	}
+	return src, nil
+}
+
+// refRadius is how many lines around each reference the prompt shows.
+const refRadius = 3
+
+// refExcerpts renders the given lines of src with their surroundings,
+// numbered and marked, merging excerpts that overlap.
+func refExcerpts(file, src string, lines []int) string {
+	all := strings.Split(src, "\n")
+	marked := map[int]bool{}
+	for _, l := range lines {
+		marked[l] = true
+	}
+	sort.Ints(lines)
+	var b strings.Builder
+	b.WriteString(file + ":\n")
+	last := 0
+	for _, l := range lines {
+		start := max(l-refRadius, last+1, 1)
+		end := min(l+refRadius, len(all))
+		if start > end {
+			continue
+		}
+		if last > 0 && start > last+1 {
+			b.WriteString("  ...\n")
+		}
+		for n := start; n <= end; n++ {
+			marker := " "
+			if marked[n] {
+				marker = ">"
+			}
+			fmt.Fprintf(&b, "%s%5d  %s\n", marker, n, all[n-1])
+		}
+		last = end
+	}
+	return b.String()
+}
@@ -0,0 +1,137 @@
+package workflow
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeGreeter lays out a module with a function used from two files.
+func writeGreeter(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":          "module example.com/greet\n\ngo 1.21\n",
+		"greet/greet.go":  "package greet\n\nfunc Hello(name string) string { return \"hello \" + name }\n",
+		"greet/loud.go":   "package greet\n\nfunc Loud(name string) string { return Hello(name) + \"!\" }\n",
+		"cmd/hi/main.go":  "package main\n\nimport \"example.com/greet/greet\"\n\nfunc main() { println(greet.Hello(\"you\")) }\n",
+		"greet/unused.go": "package greet\n\n// Hello is mentioned here but not referenced.\nvar _ = \"Hello\"\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte(content), 0644)
+	}
+	return dir
+}
+
+func TestRefactor_Rename(t *testing.T) {
+	dir := writeGreeter(t)
+	perm := &recordingPermission{allow: true}
+	exec := scriptExecutor{refactorCheck: "true"}
+	env := Env{Agent: &fakeAgent{}, Executor: exec, Dir: dir, Permission: perm}
+	r, err := Refactor(context.Background(), env, RefactorOptions{Symbol: "greet.Hello", Rename: "Greet"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Applied || len(r.Changed) != 3 {
+		t.Fatalf("result = %+v", r)
+	}
+	if len(perm.previews) != 1 {
+		t.Fatalf("asked %d times, want once for all files", len(perm.previews))
+	}
+	if !strings.HasPrefix(perm.previews[0], "Rename Hello to Greet in 3 file(s)\n") || !strings.Contains(perm.previews[0], "+++ b/cmd/hi/main.go") {
+		t.Errorf("preview =\n%s", perm.previews[0])
+	}
+	main, _ := os.ReadFile(filepath.Join(dir, "cmd", "hi", "main.go"))
+	if !strings.Contains(string(main), "greet.Greet(\"you\")") {
+		t.Errorf("main.go =\n%s", main)
+	}
+	unused, _ := os.ReadFile(filepath.Join(dir, "greet", "unused.go"))
+	if strings.Contains(string(unused), "Greet") {
+		t.Errorf("unused.go should not change:\n%s", unused)
+	}
+	if r.Check == nil || r.Check.ExitCode != 0 {
+		t.Errorf("Check = %+v", r.Check)
+	}
+}
+
+func TestRefactor_RenameConflict(t *testing.T) {
+	env := Env{Agent: &fakeAgent{}, Dir: writeGreeter(t)}
+	if _, err := Refactor(context.Background(), env, RefactorOptions{Symbol: "greet.Hello", Rename: "Loud"}); err == nil {
+		t.Fatal("expected a conflict error")
+	}
+}
+
+func TestRefactor_DryRun(t *testing.T) {
+	dir := writeGreeter(t)
+	perm := &recordingPermission{allow: true}
+	r, err := Refactor(context.Background(), Env{Agent: &fakeAgent{}, Dir: dir, Permission: perm}, RefactorOptions{Symbol: "greet.Hello", Rename: "Greet", DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(perm.previews) != 0 || len(r.Changed) != 0 {
+		t.Errorf("dry run changed files: %+v", r)
+	}
+	want := "func example.com/greet/greet.Hello (greet/greet.go:3:6): 2 reference(s) in 3 file(s)\n" +
+		"     0  greet/greet.go (declaration)\n" +
+		"     1  cmd/hi/main.go\n" +
+		"     1  greet/loud.go\n"
+	if got := r.Impact(); got != want {
+		t.Errorf("Impact() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestRefactor_Change(t *testing.T) {
+	dir := writeGreeter(t)
+	reply := func(edits string) string { return "```json\n{\"edits\": " + edits + "}\n```" }
+	a := &fakeAgent{replies: []string{
+		// greet/greet.go
+		reply(`[{"old_string": "func Hello(name string) string", "new_string": "func Hello(name string, loud bool) string"}]`),
+		// cmd/hi/main.go: a bad edit, then a good one
+		reply(`[{"old_string": "Hello(you)", "new_string": "x"}]`),
+		reply(`[{"old_string": "greet.Hello(\"you\")", "new_string": "greet.Hello(\"you\", false)"}]`),
+		// greet/loud.go: nothing usable twice
+		reply(`[{"old_string": "name", "new_string": "n"}]`),
+		reply(`[{"old_string": "name", "new_string": "n"}]`),
+	}}
+	perm := &recordingPermission{allow: true}
+	env := Env{Agent: a, Executor: scriptExecutor{refactorCheck: "echo broken; exit 1"}, Dir: dir, Permission: perm}
+	r, err := Refactor(context.Background(), env, RefactorOptions{Symbol: "greet.Hello", Change: "add a loud bool parameter"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(a.prompts[0], "Refactor: add a loud bool parameter") || !strings.Contains(a.prompts[0], ">    3  func Hello") {
+		t.Errorf("first prompt =\n%s", a.prompts[0])
+	}
+	if !strings.Contains(a.prompts[2], "old_string not found") {
+		t.Errorf("retry prompt = %q", a.prompts[2])
+	}
+	if len(r.Changed) != 2 || r.Failed["greet/loud.go"] == "" {
+		t.Errorf("result = %+v", r)
+	}
+	if len(perm.previews) != 1 {
+		t.Errorf("asked %d times, want once", len(perm.previews))
+	}
+	src, _ := os.ReadFile(filepath.Join(dir, "greet", "greet.go"))
+	if !strings.Contains(string(src), "loud bool") {
+		t.Errorf("greet.go =\n%s", src)
+	}
+	if s := r.Summary(); !strings.Contains(s, "greet/loud.go: edit 1: old_string appears") || !strings.Contains(s, "fails:\nbroken") {
+		t.Errorf("Summary() =\n%s", s)
+	}
+}
+
+func TestRefExcerpts(t *testing.T) {
+	src := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15"
+	got := refExcerpts("f.go", src, []int{5, 4, 14})
+	want := "f.go:\n" +
+		"     1  1\n     2  2\n     3  3\n>    4  4\n>    5  5\n     6  6\n     7  7\n     8  8\n" +
+		"  ...\n" +
+		"    11  11\n    12  12\n    13  13\n>   14  14\n    15  15\n"
+	if got != want {
+		t.Errorf("refExcerpts() =\n%s\nwant\n%s", got, want)
+	}
+}
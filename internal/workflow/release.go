@@ -0,0 +1,235 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Commit is one commit in a release range.
+type Commit struct {
+	Hash    string
+	Subject string
+	Body    string
+	// Title is the merged pull request's title when the commit is a PR
+	// merge or squash, otherwise the subject.
+	Title string
+	// PR is the pull or merge request number, if known.
+	PR string
+}
+
+var (
+	// GitHub merge commit: "Merge pull request #12 from owner/branch",
+	// with the PR title as the first body line.
+	githubMergeRE = regexp.MustCompile(`^Merge pull request #(\d+) from `)
+	// GitLab merge commit body: "See merge request group/project!12".
+	gitlabMergeRE = regexp.MustCompile(`See merge request \S+!(\d+)`)
+	// Squash merge subject: "Title (#12)".
+	squashRE = regexp.MustCompile(`^(.*) \(#(\d+)\)$`)
+)
+
+// ParseCommits reads the output of git log --format=%H%x1f%s%x1f%b%x1e.
+// Merges that are not pull request merges (such as "Merge branch 'main'
+// into feature") are dropped as noise.
+func ParseCommits(log string) []Commit {
+	var commits []Commit
+	for _, rec := range strings.Split(log, "\x1e") {
+		fields := strings.SplitN(strings.TrimLeft(rec, "\n"), "\x1f", 3)
+		if len(fields) < 2 || fields[0] == "" {
+			continue
+		}
+		c := Commit{Hash: fields[0], Subject: fields[1], Title: fields[1]}
+		if len(fields) == 3 {
+			c.Body = strings.TrimSpace(fields[2])
+		}
+		firstBodyLine, _, _ := strings.Cut(c.Body, "\n")
+		switch {
+		case githubMergeRE.MatchString(c.Subject):
+			c.PR = githubMergeRE.FindStringSubmatch(c.Subject)[1]
+			if firstBodyLine != "" {
+				c.Title = firstBodyLine
+			}
+		case gitlabMergeRE.MatchString(c.Body):
+			c.PR = gitlabMergeRE.FindStringSubmatch(c.Body)[1]
+			if firstBodyLine != "" && !gitlabMergeRE.MatchString(firstBodyLine) {
+				c.Title = firstBodyLine
+			}
+		case squashRE.MatchString(c.Subject):
+			m := squashRE.FindStringSubmatch(c.Subject)
+			c.Title, c.PR = m[1], m[2]
+		case strings.HasPrefix(c.Subject, "Merge "):
+			continue
+		}
+		commits = append(commits, c)
+	}
+	return commits
+}
+
+// changelogSections are the Keep a Changelog section names, in order.
+var changelogSections = []string{"Added", "Changed", "Deprecated", "Removed", "Fixed", "Security"}
+
+// ReleaseNotes is a drafted changelog entry.
+type ReleaseNotes struct {
+	Version string `json:"-"`
+	Date    string `json:"-"`
+	// Sections maps Keep a Changelog section names to entries.
+	Sections map[string][]string `json:"sections"`
+	// Written reports whether the entry was written to the changelog.
+	Written bool `json:"-"`
+}
+
+// Markdown renders the entry in Keep a Changelog format.
+func (n *ReleaseNotes) Markdown() string {
+	var b strings.Builder
+	if n.Version == "" || strings.EqualFold(n.Version, "unreleased") {
+		b.WriteString("## [Unreleased]\n")
+	} else {
+		fmt.Fprintf(&b, "## [%s] - %s\n", strings.TrimPrefix(n.Version, "v"), n.Date)
+	}
+	for _, name := range changelogSections {
+		entries := n.Sections[name]
+		if len(entries) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n### %s\n", name)
+		for _, e := range entries {
+			b.WriteString("- " + strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(e), "- ")) + "\n")
+		}
+	}
+	return b.String()
+}
+
+// mergeChangelog puts section into changelog: in place of an entry with
+// the same heading, or above the newest entry.
+func mergeChangelog(changelog, section string) string {
+	if strings.TrimSpace(changelog) == "" {
+		return "# Changelog\n\nAll notable changes to this project will be documented in this file.\n\n" + section
+	}
+	heading, _, _ := strings.Cut(section, "\n")
+	lines := strings.Split(changelog, "\n")
+	for i, l := range lines {
+		if strings.TrimSpace(l) == heading {
+			end := len(lines)
+			for j := i + 1; j < len(lines); j++ {
+				if strings.HasPrefix(lines[j], "## ") {
+					end = j
+					break
+				}
+			}
+			rest := ""
+			if end < len(lines) {
+				rest = "\n" + strings.Join(lines[end:], "\n")
+			}
+			return strings.Join(append(lines[:i:i], section), "\n") + rest
+		}
+	}
+	for i, l := range lines {
+		if strings.HasPrefix(l, "## ") {
+			return strings.Join(lines[:i], "\n") + "\n" + section + "\n" + strings.Join(lines[i:], "\n")
+		}
+	}
+	return strings.TrimRight(changelog, "\n") + "\n\n" + section
+}
+
+// ReleaseOptions configures DraftReleaseNotes.
+type ReleaseOptions struct {
+	// Range is a git revision range, "<from>..<to>"; a bare revision
+	// means "<from>..HEAD".
+	Range string
+	// Version heads the entry; empty means Unreleased.
+	Version string
+	// Date is shown next to a version.
+	Date string
+	// Changelog, if set, is the file (relative to the project) the entry
+	// is written into after approval.
+	Changelog string
+}
+
+const releaseNotesPrompt = `Draft release notes for %s from these commits (short hash, title, pull request, and the start of the message):
+
+%s
+Group the changes into Keep a Changelog sections: Added, Changed, Deprecated, Removed, Fixed, Security.
+- Write for users of the project, not its developers: say what changed for them, one line per change.
+- Merge commits that belong to the same change; leave out refactors, tests, CI, and other changes users cannot see.
+- Mention the pull request as (#N) where there is one.
+- If a title is too vague to classify, read the change with shell_exec (git show --stat <hash>, then git show <hash>) before deciding.
+Do not modify any files.
+
+End your reply with a JSON code block:
+` + "```json" + `
+{"sections": {"Added": ["..."], "Fixed": ["..."]}}
+` + "```"
+
+// maxCommitBody caps how much of each commit message the prompt shows.
+const maxCommitBody = 300
+
+// DraftReleaseNotes reads the commits in the range, asks the agent to
+// group them into a Keep a Changelog entry, and, if opts.Changelog is
+// set, offers to write the entry into that file.
+func DraftReleaseNotes(ctx context.Context, env Env, opts ReleaseOptions) (*ReleaseNotes, error) {
+	rng := opts.Range
+	if rng == "" {
+		return nil, fmt.Errorf("a revision range is required")
+	}
+	if !strings.Contains(rng, "..") {
+		rng += "..HEAD"
+	}
+	env.logf("[release-notes] git log %s", rng)
+	res, err := runCommand(ctx, env.Executor, env.Dir, "git log --format=%H%x1f%s%x1f%b%x1e "+shellQuote(rng))
+	if err != nil {
+		return nil, err
+	}
+	if res.ExitCode != 0 {
+		return nil, fmt.Errorf("git log %s: %s", rng, strings.TrimSpace(res.Output))
+	}
+	commits := ParseCommits(res.Output)
+	if len(commits) == 0 {
+		return nil, fmt.Errorf("no commits in %s", rng)
+	}
+
+	var list strings.Builder
+	for _, c := range commits {
+		fmt.Fprintf(&list, "- %.10s %s", c.Hash, c.Title)
+		if c.PR != "" {
+			fmt.Fprintf(&list, " (#%s)", c.PR)
+		}
+		body := c.Body
+		if c.Title != c.Subject {
+			// The title came from the body; don't repeat it.
+			_, body, _ = strings.Cut(body, "\n")
+		}
+		if body = strings.Join(strings.Fields(body), " "); body != "" {
+			if len(body) > maxCommitBody {
+				body = body[:maxCommitBody] + "..."
+			}
+			list.WriteString(" — " + body)
+		}
+		list.WriteString("\n")
+	}
+	env.logf("[release-notes] grouping %d commit(s)", len(commits))
+	notes, err := askJSON[ReleaseNotes](ctx, env.Agent, fmt.Sprintf(releaseNotesPrompt, rng, list.String()))
+	if err != nil {
+		return nil, err
+	}
+	notes.Version, notes.Date = opts.Version, opts.Date
+
+	if opts.Changelog != "" {
+		old, err := os.ReadFile(filepath.Join(env.Dir, filepath.FromSlash(opts.Changelog)))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		notes.Written, err = applyEdit(ctx, env, "release-notes", opts.Changelog, string(old), mergeChangelog(string(old), notes.Markdown()))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return notes, nil
+}
+
+// shellQuote single-quotes s for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
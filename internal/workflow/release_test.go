@@ -0,0 +1,148 @@
+package workflow
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// gitLog formats commits like git log --format=%H%x1f%s%x1f%b%x1e.
+func gitLog(commits ...[3]string) string {
+	var b strings.Builder
+	for _, c := range commits {
+		b.WriteString(c[0] + "\x1f" + c[1] + "\x1f" + c[2] + "\x1e\n")
+	}
+	return b.String()
+}
+
+func TestParseCommits(t *testing.T) {
+	log := gitLog(
+		[3]string{"aaa", "Merge pull request #12 from someone/feature", "Add dark mode\n\nLong description."},
+		[3]string{"bbb", "Merge branch 'fix' into 'main'", "Fix crash on empty input\n\nSee merge request group/project!7"},
+		[3]string{"ccc", "Speed up startup (#15)", ""},
+		[3]string{"ddd", "Merge branch 'main' into feature", ""},
+		[3]string{"eee", "Fix typo", "In the help text.\n"},
+	)
+	got := ParseCommits(log)
+	want := []Commit{
+		{Hash: "aaa", Title: "Add dark mode", PR: "12"},
+		{Hash: "bbb", Title: "Fix crash on empty input", PR: "7"},
+		{Hash: "ccc", Title: "Speed up startup", PR: "15"},
+		{Hash: "eee", Title: "Fix typo", Body: "In the help text."},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("ParseCommits() = %+v", got)
+	}
+	for i, w := range want {
+		g := got[i]
+		if g.Hash != w.Hash || g.Title != w.Title || g.PR != w.PR {
+			t.Errorf("commit %d = %+v, want %+v", i, g, w)
+		}
+		if w.Body != "" && g.Body != w.Body {
+			t.Errorf("commit %d body = %q, want %q", i, g.Body, w.Body)
+		}
+	}
+}
+
+func TestReleaseNotesMarkdown(t *testing.T) {
+	n := &ReleaseNotes{
+		Version: "v1.2.0",
+		Date:    "2026-01-02",
+		Sections: map[string][]string{
+			"Fixed": {"- Fix crash (#7)"},
+			"Added": {"Dark mode (#12)"},
+			"Other": {"ignored"},
+		},
+	}
+	want := "## [1.2.0] - 2026-01-02\n\n### Added\n- Dark mode (#12)\n\n### Fixed\n- Fix crash (#7)\n"
+	if got := n.Markdown(); got != want {
+		t.Errorf("Markdown() =\n%s\nwant\n%s", got, want)
+	}
+	n.Version = ""
+	if got := n.Markdown(); !strings.HasPrefix(got, "## [Unreleased]\n") {
+		t.Errorf("Markdown() = %q, want an Unreleased heading", got)
+	}
+}
+
+func TestMergeChangelog(t *testing.T) {
+	existing := "# Changelog\n\nIntro.\n\n## [Unreleased]\n\n### Added\n- Old\n\n## [1.0.0] - 2025-01-01\n\n### Added\n- First\n"
+	tests := []struct {
+		name, changelog, section, want string
+	}{
+		{
+			"replaces same heading",
+			existing,
+			"## [Unreleased]\n\n### Fixed\n- New\n",
+			"# Changelog\n\nIntro.\n\n## [Unreleased]\n\n### Fixed\n- New\n\n## [1.0.0] - 2025-01-01\n\n### Added\n- First\n",
+		},
+		{
+			"inserts above newest",
+			existing,
+			"## [1.1.0] - 2025-02-01\n\n### Fixed\n- New\n",
+			"# Changelog\n\nIntro.\n\n## [1.1.0] - 2025-02-01\n\n### Fixed\n- New\n\n## [Unreleased]\n\n### Added\n- Old\n\n## [1.0.0] - 2025-01-01\n\n### Added\n- First\n",
+		},
+		{
+			"creates file",
+			"",
+			"## [Unreleased]\n",
+			"# Changelog\n\nAll notable changes to this project will be documented in this file.\n\n## [Unreleased]\n",
+		},
+		{
+			"appends when no entries",
+			"# Changelog\n",
+			"## [Unreleased]\n",
+			"# Changelog\n\n## [Unreleased]\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mergeChangelog(tt.changelog, tt.section); got != tt.want {
+				t.Errorf("mergeChangelog() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDraftReleaseNotes(t *testing.T) {
+	dir := t.TempDir()
+	logFile := filepath.Join(dir, "log")
+	os.WriteFile(logFile, []byte(gitLog(
+		[3]string{"aaaaaaaaaaaa", "Add dark mode (#12)", ""},
+		[3]string{"bbbbbbbbbbbb", "Tweak things", "Reworked the parser."},
+	)), 0644)
+	os.WriteFile(filepath.Join(dir, "CHANGELOG.md"), []byte("# Changelog\n\n## [1.0.0] - 2025-01-01\n"), 0644)
+	exec := scriptExecutor{"git log --format=%H%x1f%s%x1f%b%x1e 'v1.0.0..HEAD'": "cat " + logFile}
+	a := &fakeAgent{replies: []string{"```json\n" + `{"sections": {"Added": ["Dark mode (#12)"], "Changed": ["Faster parser"]}}` + "\n```"}}
+	perm := &recordingPermission{allow: true}
+
+	env := Env{Agent: a, Executor: exec, Dir: dir, Permission: perm}
+	n, err := DraftReleaseNotes(context.Background(), env, ReleaseOptions{Range: "v1.0.0", Version: "1.1.0", Date: "2025-02-01", Changelog: "CHANGELOG.md"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !n.Written {
+		t.Error("Written = false, want true")
+	}
+	for _, want := range []string{"aaaaaaaaaa Add dark mode (#12)", "bbbbbbbbbb Tweak things — Reworked the parser."} {
+		if !strings.Contains(a.prompts[0], want) {
+			t.Errorf("prompt missing %q:\n%s", want, a.prompts[0])
+		}
+	}
+	if len(perm.previews) != 1 || !strings.Contains(perm.previews[0], "+## [1.1.0] - 2025-02-01") {
+		t.Errorf("previews = %q", perm.previews)
+	}
+	got, _ := os.ReadFile(filepath.Join(dir, "CHANGELOG.md"))
+	if want := "# Changelog\n\n## [1.1.0] - 2025-02-01\n\n### Added\n- Dark mode (#12)\n\n### Changed\n- Faster parser\n\n## [1.0.0] - 2025-01-01\n"; string(got) != want {
+		t.Errorf("CHANGELOG.md =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestDraftReleaseNotes_EmptyRange(t *testing.T) {
+	exec := scriptExecutor{"git log --format=%H%x1f%s%x1f%b%x1e 'v1..v2'": "true"}
+	_, err := DraftReleaseNotes(context.Background(), Env{Agent: &fakeAgent{}, Executor: exec}, ReleaseOptions{Range: "v1..v2"})
+	if err == nil || !strings.Contains(err.Error(), "no commits") {
+		t.Errorf("err = %v, want no commits", err)
+	}
+}
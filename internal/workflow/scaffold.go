@@ -0,0 +1,271 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Template describes a kind of project Scaffold can generate.
+type Template struct {
+	Name        string
+	Description string
+	Language    string
+	Framework   string // default when the options name none
+	// Verify builds and tests the generated project, run in its
+	// directory.
+	Verify string
+	// Conventions are extra instructions for this kind of project.
+	Conventions string
+}
+
+// Templates are the project templates, by name.
+var Templates = map[string]Template{
+	"go-cli": {
+		Name:        "go-cli",
+		Description: "Go command-line tool",
+		Language:    "Go",
+		Framework:   "the standard library (flag)",
+		Verify:      "go mod tidy && go vet ./... && go test ./...",
+		Conventions: "Use a go.mod with the module path github.com/example/<name> unless the spec says otherwise, cmd/<name>/main.go for the entry point, and internal/ for packages.",
+	},
+	"go-service": {
+		Name:        "go-service",
+		Description: "Go HTTP service",
+		Language:    "Go",
+		Framework:   "net/http",
+		Verify:      "go mod tidy && go vet ./... && go test ./...",
+		Conventions: "Include a /healthz endpoint, graceful shutdown on SIGTERM, configuration from environment variables, and a Dockerfile.",
+	},
+	"python-package": {
+		Name:        "python-package",
+		Description: "Python package",
+		Language:    "Python",
+		Framework:   "pyproject.toml with setuptools and pytest",
+		Verify:      "python3 -m compileall -q src tests && python3 -m pytest -q",
+		Conventions: "Use the src/ layout, type hints, and a tests/ directory.",
+	},
+	"node-api": {
+		Name:        "node-api",
+		Description: "TypeScript HTTP API",
+		Language:    "TypeScript",
+		Framework:   "Express",
+		Verify:      "npm install && npm run build && npm test",
+		Conventions: `Include "build" and "test" scripts in package.json, strict tsconfig settings, and a /healthz route.`,
+	},
+	"rust-cli": {
+		Name:        "rust-cli",
+		Description: "Rust command-line tool",
+		Language:    "Rust",
+		Framework:   "clap",
+		Verify:      "cargo build && cargo test",
+		Conventions: "Put the argument parsing in main.rs and the logic in lib.rs so it can be tested.",
+	},
+}
+
+// TemplateNames returns the template names, sorted.
+func TemplateNames() []string {
+	names := make([]string, 0, len(Templates))
+	for name := range Templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DefaultScaffoldAttempts is how many rounds Scaffold gives the agent to
+// make a failing verification build pass.
+const DefaultScaffoldAttempts = 2
+
+// ScaffoldOptions configures Scaffold.
+type ScaffoldOptions struct {
+	Template string
+	// Name is the project name; Dir defaults to it.
+	Name string
+	// Dir is where the project goes, relative to the working directory.
+	// It must not exist or be empty.
+	Dir       string
+	Framework string // default: the template's
+	// CI is "github", "gitlab", or "none". Empty means github.
+	CI string
+	// Spec describes what the project should do.
+	Spec string
+	// MaxAttempts is how many fix rounds a failing verification gets.
+	// Zero means DefaultScaffoldAttempts.
+	MaxAttempts int
+}
+
+// ScaffoldResult is the outcome of Scaffold.
+type ScaffoldResult struct {
+	Name     string
+	Dir      string
+	Template string
+	Files    []string // relative to Dir
+	Verify   string
+	Verified bool
+	// VerifyOutput is the output of the last verification run when it
+	// failed.
+	VerifyOutput string
+	Summary      string
+}
+
+// Report renders the result for people.
+func (r *ScaffoldResult) Report() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s (%s)\n\n", r.Name, r.Template)
+	if r.Summary != "" {
+		fmt.Fprintf(&b, "%s\n\n", strings.TrimSpace(r.Summary))
+	}
+	fmt.Fprintf(&b, "## Files in %s\n\n", r.Dir)
+	for _, f := range r.Files {
+		fmt.Fprintf(&b, "- %s\n", f)
+	}
+	if r.Verified {
+		fmt.Fprintf(&b, "\nVerified: `%s` passes.\n", r.Verify)
+	} else {
+		fmt.Fprintf(&b, "\nVerification failed: `%s`\n\n```\n%s\n```\n", r.Verify, truncate(strings.TrimSpace(r.VerifyOutput), maxFailureOutput))
+	}
+	return b.String()
+}
+
+const scaffoldPrompt = `Create a new %s project named %q in the directory %s (relative to the working directory), which is empty.
+
+- Kind: %s
+- Framework: %s
+- CI: %s
+%s
+What it should do:
+%s
+
+Write every file with write_file, using paths under %s. Include a README.md with build and usage instructions, a .gitignore, and at least one meaningful test. Keep it small: a working skeleton for the spec, not every feature. Do not run commands; I will build and test the project with ` + "`%s`" + ` when you are done.`
+
+const scaffoldFixPrompt = `The verification build ` + "`%s`" + ` fails in %s:
+
+` + "```\n%s\n```" + `
+
+Fix the project files so it passes.`
+
+const scaffoldSummaryPrompt = `Summarize the project you created in a few sentences for its README reader: the layout, how to run it, and any next steps the spec leaves open. Reply with only the summary.`
+
+// Scaffold has the agent generate a project from a template and a spec
+// in a fresh directory, then runs the template's verification build,
+// giving the agent a chance to fix failures, and asks for a summary.
+func Scaffold(ctx context.Context, env Env, opts ScaffoldOptions) (*ScaffoldResult, error) {
+	tmpl, ok := Templates[opts.Template]
+	if !ok {
+		return nil, fmt.Errorf("unknown template %q (use %s)", opts.Template, strings.Join(TemplateNames(), ", "))
+	}
+	if opts.Name == "" {
+		return nil, errors.New("a project name is required")
+	}
+	if strings.TrimSpace(opts.Spec) == "" {
+		return nil, errors.New("describe what the project should do")
+	}
+	if opts.Dir == "" {
+		opts.Dir = opts.Name
+	}
+	if filepath.IsAbs(opts.Dir) || strings.HasPrefix(filepath.Clean(opts.Dir), "..") {
+		return nil, fmt.Errorf("project directory must be inside the working directory: %s", opts.Dir)
+	}
+	framework := opts.Framework
+	if framework == "" {
+		framework = tmpl.Framework
+	}
+	ci, err := ciDescription(opts.CI, tmpl.Verify)
+	if err != nil {
+		return nil, err
+	}
+	attempts := opts.MaxAttempts
+	if attempts <= 0 {
+		attempts = DefaultScaffoldAttempts
+	}
+
+	dir := filepath.Join(env.Dir, opts.Dir)
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) > 0 {
+		return nil, fmt.Errorf("%s is not empty", opts.Dir)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	rel := filepath.ToSlash(filepath.Clean(opts.Dir))
+	result := &ScaffoldResult{Name: opts.Name, Dir: rel, Template: tmpl.Name, Verify: tmpl.Verify}
+
+	conventions := ""
+	if tmpl.Conventions != "" {
+		conventions = "- Conventions: " + strings.ReplaceAll(tmpl.Conventions, "<name>", opts.Name) + "\n"
+	}
+	env.logf("[new] generating %s project in %s", tmpl.Name, rel)
+	prompt := fmt.Sprintf(scaffoldPrompt, tmpl.Language, opts.Name, rel, tmpl.Description, framework, ci, conventions, strings.TrimSpace(opts.Spec), rel, tmpl.Verify)
+	if err := env.Agent.Send(ctx, prompt); err != nil {
+		return nil, err
+	}
+
+	for attempt := 1; ; attempt++ {
+		env.logf("[new] %s", tmpl.Verify)
+		res, err := runCommand(ctx, env.Executor, dir, tmpl.Verify)
+		if err != nil {
+			return nil, err
+		}
+		if res.ExitCode == 0 {
+			result.Verified, result.VerifyOutput = true, ""
+			break
+		}
+		result.VerifyOutput = res.Output
+		if attempt > attempts || res.ExitCode == exitNotFound {
+			break
+		}
+		env.logf("[new] verification failed; fix attempt %d of %d", attempt, attempts)
+		if err := env.Agent.Send(ctx, fmt.Sprintf(scaffoldFixPrompt, tmpl.Verify, rel, truncate(strings.TrimSpace(res.Output), maxFailureOutput))); err != nil {
+			return nil, err
+		}
+	}
+
+	if result.Files, err = listFiles(dir); err != nil {
+		return nil, err
+	}
+	if err := env.Agent.Send(ctx, scaffoldSummaryPrompt); err != nil {
+		return nil, err
+	}
+	result.Summary = lastReply(env.Agent)
+	return result, nil
+}
+
+// ciDescription tells the agent which CI configuration to write.
+func ciDescription(ci, verify string) (string, error) {
+	switch ci {
+	case "", "github":
+		return "a GitHub Actions workflow in .github/workflows/ci.yml that runs `" + verify + "`", nil
+	case "gitlab":
+		return "a .gitlab-ci.yml that runs `" + verify + "`", nil
+	case "none":
+		return "none", nil
+	}
+	return "", fmt.Errorf("unsupported CI %q (use github, gitlab, or none)", ci)
+}
+
+// listFiles returns the files under dir, relative to it, skipping
+// dependency and build directories the verification created.
+func listFiles(dir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			switch d.Name() {
+			case "node_modules", "target", "dist", "__pycache__", ".pytest_cache", ".git":
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, _ := filepath.Rel(dir, path)
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	return files, err
+}
@@ -0,0 +1,113 @@
+package workflow
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writingAgent is a fakeAgent that also writes files on each Send, like
+// the real agent would with write_file.
+type writingAgent struct {
+	fakeAgent
+	dir    string
+	writes []map[string]string
+}
+
+func (a *writingAgent) Send(ctx context.Context, prompt string) error {
+	if len(a.writes) > 0 {
+		for name, content := range a.writes[0] {
+			path := filepath.Join(a.dir, name)
+			os.MkdirAll(filepath.Dir(path), 0755)
+			os.WriteFile(path, []byte(content), 0644)
+		}
+		a.writes = a.writes[1:]
+	}
+	return a.fakeAgent.Send(ctx, prompt)
+}
+
+func TestScaffold_GeneratesAndVerifies(t *testing.T) {
+	dir := t.TempDir()
+	verify := Templates["go-cli"].Verify
+	ag := &writingAgent{
+		dir: dir,
+		writes: []map[string]string{
+			{"todo/go.mod": "module todo\n", "todo/main.go": "package main\n", "todo/.github/workflows/ci.yml": "on: push\n"},
+			{"todo/fixed": ""},
+		},
+		fakeAgent: fakeAgent{replies: []string{"Created the project.", "Fixed the import.", "A todo CLI. Run it with go run ./cmd/todo."}},
+	}
+	env := Env{
+		Agent:    ag,
+		Executor: scriptExecutor{verify: `test -f fixed || { echo "main.go:1: undefined: flag"; exit 1; }`},
+		Dir:      dir,
+	}
+
+	result, err := Scaffold(context.Background(), env, ScaffoldOptions{Template: "go-cli", Name: "todo", Spec: "a todo list CLI"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Verified {
+		t.Errorf("expected the project to verify after one fix, got %q", result.VerifyOutput)
+	}
+	if len(ag.prompts) != 3 {
+		t.Fatalf("expected generate, fix, and summary prompts, got %d", len(ag.prompts))
+	}
+	for _, want := range []string{`"todo"`, "directory todo", "a todo list CLI", ".github/workflows/ci.yml", "github.com/example/todo"} {
+		if !strings.Contains(ag.prompts[0], want) {
+			t.Errorf("generate prompt missing %q:\n%s", want, ag.prompts[0])
+		}
+	}
+	if !strings.Contains(ag.prompts[1], "undefined: flag") {
+		t.Errorf("fix prompt should include the build output: %s", ag.prompts[1])
+	}
+	if got := strings.Join(result.Files, ","); got != ".github/workflows/ci.yml,fixed,go.mod,main.go" {
+		t.Errorf("Files = %s", got)
+	}
+	report := result.Report()
+	if !strings.Contains(report, "A todo CLI") || !strings.Contains(report, "passes") {
+		t.Errorf("unexpected report:\n%s", report)
+	}
+}
+
+func TestScaffold_ReportsFailedVerification(t *testing.T) {
+	dir := t.TempDir()
+	verify := Templates["rust-cli"].Verify
+	ag := &writingAgent{dir: dir, writes: []map[string]string{{"tool/Cargo.toml": "[package]\n"}}}
+	env := Env{Agent: ag, Executor: scriptExecutor{verify: "echo error[E0425]; exit 101"}, Dir: dir}
+
+	result, err := Scaffold(context.Background(), env, ScaffoldOptions{Template: "rust-cli", Name: "tool", CI: "none", Spec: "x", MaxAttempts: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Verified || !strings.Contains(result.VerifyOutput, "E0425") {
+		t.Errorf("expected a failed verification, got %+v", result)
+	}
+	if len(ag.prompts) != 3 {
+		t.Errorf("expected one fix attempt, got %d prompts", len(ag.prompts))
+	}
+	if !strings.Contains(result.Report(), "Verification failed") {
+		t.Errorf("unexpected report:\n%s", result.Report())
+	}
+}
+
+func TestScaffold_Validates(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "taken"), 0755)
+	os.WriteFile(filepath.Join(dir, "taken", "x"), nil, 0644)
+	env := Env{Agent: &fakeAgent{}, Dir: dir}
+	cases := map[string]ScaffoldOptions{
+		"unknown template": {Template: "cobol", Name: "x", Spec: "x"},
+		"name is required": {Template: "go-cli", Spec: "x"},
+		"not empty":        {Template: "go-cli", Name: "taken", Spec: "x"},
+		"inside":           {Template: "go-cli", Name: "x", Dir: "../x", Spec: "x"},
+		"unsupported CI":   {Template: "go-cli", Name: "x", CI: "jenkins", Spec: "x"},
+	}
+	for want, opts := range cases {
+		if _, err := Scaffold(context.Background(), env, opts); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q error, got %v", want, err)
+		}
+	}
+}
@@ -0,0 +1,455 @@
+package workflow
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultTestAttempts is how many fix rounds FixTests allows by default.
+const DefaultTestAttempts = 3
+
+// TestOptions configures FixTests.
+type TestOptions struct {
+	// Command runs the tests. Go test JSON output (go test -json) is
+	// parsed into individual failures; other commands are treated as a
+	// single failure with their output.
+	Command string
+	// MaxAttempts is how many times the agent may try a fix before
+	// FixTests gives up. Zero means DefaultTestAttempts.
+	MaxAttempts int
+}
+
+// TestFailure is one failing test, or a package that failed to build.
+type TestFailure struct {
+	Package string
+	Test    string // empty for build and package-level failures
+	Output  string
+}
+
+// Name identifies the failure in summaries.
+func (f TestFailure) Name() string {
+	switch {
+	case f.Test != "":
+		return f.Package + "." + f.Test
+	case f.Package != "":
+		return f.Package
+	}
+	return "test command"
+}
+
+// TestFix is one round in which the agent changed code.
+type TestFix struct {
+	Attempt  int
+	Failures []string
+	Summary  string
+}
+
+// TestResult is the outcome of FixTests.
+type TestResult struct {
+	Command string
+	Passed  bool
+	Fixes   []TestFix
+	// Remaining lists the failures left when the attempts ran out.
+	Remaining []TestFailure
+}
+
+// Summary renders the result for people.
+func (r *TestResult) Summary() string {
+	var b strings.Builder
+	switch {
+	case r.Passed && len(r.Fixes) == 0:
+		fmt.Fprintf(&b, "Tests pass (%s).\n", r.Command)
+	case r.Passed:
+		fmt.Fprintf(&b, "Tests pass after %d fix(es):\n", len(r.Fixes))
+	default:
+		fmt.Fprintf(&b, "Tests still failing after %d fix attempt(s):\n", len(r.Fixes))
+	}
+	for _, f := range r.Fixes {
+		fmt.Fprintf(&b, "%d. %s\n   Fixed: %s\n", f.Attempt, f.Summary, strings.Join(f.Failures, ", "))
+	}
+	if len(r.Remaining) > 0 {
+		b.WriteString("Remaining failures:\n")
+		for _, f := range r.Remaining {
+			fmt.Fprintf(&b, "- %s\n", f.Name())
+		}
+	}
+	return b.String()
+}
+
+// DetectTestCommand returns the usual test command for the project in
+// dir, or "" if it cannot tell.
+func DetectTestCommand(dir string) string {
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(dir, name))
+		return err == nil
+	}
+	switch {
+	case exists("go.mod"):
+		return "go test -json ./..."
+	case exists("package.json"):
+		return "npm test"
+	case exists("Cargo.toml"):
+		return "cargo test"
+	case exists("pyproject.toml"), exists("pytest.ini"), exists("setup.py"):
+		return "pytest"
+	}
+	return ""
+}
+
+// maxFailureOutput caps each failure's output in the prompt.
+const maxFailureOutput = 8 * 1024
+
+const testFixPrompt = `The test command ` + "`%s`" + ` fails. Failures:
+
+%s
+%s
+Find the root cause and fix it with the smallest change that makes these tests pass. Fix the code under test rather than weakening the tests, unless a test is clearly wrong. You may run the tests with shell_exec to check your fix.
+
+End your reply with one line starting with "Summary:" describing what you changed and why.`
+
+// FixTests runs the test command and, while it fails, hands the failures
+// and the source they point at to the agent to fix, until the tests pass
+// or MaxAttempts fixes have been tried.
+func FixTests(ctx context.Context, env Env, opts TestOptions) (*TestResult, error) {
+	if opts.Command == "" {
+		return nil, errors.New("no test command: pass one explicitly")
+	}
+	attempts := opts.MaxAttempts
+	if attempts <= 0 {
+		attempts = DefaultTestAttempts
+	}
+
+	result := &TestResult{Command: opts.Command}
+	for attempt := 1; ; attempt++ {
+		env.logf("[test] %s", opts.Command)
+		res, err := runCommand(ctx, env.Executor, env.Dir, opts.Command)
+		if err != nil {
+			return nil, err
+		}
+		if res.ExitCode == 0 {
+			result.Passed = true
+			return result, nil
+		}
+		if res.ExitCode == exitNotFound {
+			return nil, fmt.Errorf("test command not found: %s", strings.TrimSpace(res.Output))
+		}
+		failures := parseFailures(opts.Command, res.Output)
+		if attempt > attempts {
+			result.Remaining = failures
+			return result, nil
+		}
+
+		env.logf("[test] %d failure(s); fix attempt %d of %d", len(failures), attempt, attempts)
+		report, source := describeFailures(env.Dir, failures)
+		if err := env.Agent.Send(ctx, fmt.Sprintf(testFixPrompt, opts.Command, report, source)); err != nil {
+			return nil, err
+		}
+		var names []string
+		for _, f := range failures {
+			names = append(names, f.Name())
+		}
+		result.Fixes = append(result.Fixes, TestFix{Attempt: attempt, Failures: names, Summary: fixSummary(lastReply(env.Agent))})
+	}
+}
+
+// describeFailures formats failures for a prompt: each failure's output,
+// and separately the source excerpts they reference (empty if none).
+func describeFailures(dir string, failures []TestFailure) (report, source string) {
+	var b strings.Builder
+	for _, f := range failures {
+		fmt.Fprintf(&b, "### %s\n\n```\n%s\n```\n\n", f.Name(), truncate(strings.TrimSpace(f.Output), maxFailureOutput))
+	}
+	if source = sourceContext(dir, failures); source != "" {
+		source = "Source referenced by the failures:\n\n" + source + "\n"
+	}
+	return b.String(), source
+}
+
+// parseFailures splits test output into failures. Without a parser for
+// the command's format, the whole output is one failure.
+func parseFailures(command, output string) []TestFailure {
+	if strings.Contains(command, "go test") && strings.Contains(command, "-json") {
+		if failures := ParseGoTestJSON(output); len(failures) > 0 {
+			return failures
+		}
+	}
+	return []TestFailure{{Output: output}}
+}
+
+// goTestEvent is one line of go test -json output.
+type goTestEvent struct {
+	Action     string
+	Package    string
+	Test       string
+	Output     string
+	ImportPath string // build-output and build-fail events
+}
+
+// ParseGoTestJSON extracts failing tests and build failures from the
+// output of go test -json. Plain-text lines (such as build errors from
+// older Go versions) are attached to the package-level failures. A
+// parent test is omitted when one of its subtests failed.
+func ParseGoTestJSON(output string) []TestFailure {
+	var (
+		failures []TestFailure
+		outputs  = map[string]*strings.Builder{} // by package + "\x00" + test
+		build    = map[string]*strings.Builder{} // by import path
+		plain    strings.Builder
+		failed   = map[string]bool{} // packages with a recorded failure
+	)
+	appendTo := func(m map[string]*strings.Builder, key, s string) {
+		if m[key] == nil {
+			m[key] = &strings.Builder{}
+		}
+		m[key].WriteString(s)
+	}
+	text := func(m map[string]*strings.Builder, key string) string {
+		if b := m[key]; b != nil {
+			return b.String()
+		}
+		return ""
+	}
+
+	sc := bufio.NewScanner(strings.NewReader(output))
+	sc.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for sc.Scan() {
+		line := sc.Text()
+		var ev goTestEvent
+		if !strings.HasPrefix(line, "{") || json.Unmarshal([]byte(line), &ev) != nil {
+			plain.WriteString(line + "\n")
+			continue
+		}
+		// Build events name the package as "pkg" or "pkg [pkg.test]".
+		importPath, _, _ := strings.Cut(ev.ImportPath, " ")
+		switch ev.Action {
+		case "output":
+			appendTo(outputs, ev.Package+"\x00"+ev.Test, ev.Output)
+		case "build-output":
+			appendTo(build, importPath, ev.Output)
+		case "build-fail":
+			failures = append(failures, TestFailure{Package: importPath, Output: text(build, importPath)})
+			failed[importPath] = true
+		case "fail":
+			if ev.Test != "" {
+				failures = append(failures, TestFailure{Package: ev.Package, Test: ev.Test, Output: text(outputs, ev.Package+"\x00"+ev.Test)})
+				failed[ev.Package] = true
+			} else if !failed[ev.Package] {
+				// A package failing without a failed test: a panic in
+				// TestMain, a timeout, or a build error reported as text.
+				failures = append(failures, TestFailure{Package: ev.Package, Output: plain.String() + text(outputs, ev.Package+"\x00")})
+				failed[ev.Package] = true
+			}
+		}
+	}
+
+	// Drop parents whose subtests failed; the subtest output is the
+	// useful part.
+	kept := failures[:0]
+	for _, f := range failures {
+		if !hasFailedSubtest(failures, f) {
+			kept = append(kept, f)
+		}
+	}
+	return kept
+}
+
+func hasFailedSubtest(failures []TestFailure, parent TestFailure) bool {
+	if parent.Test == "" {
+		return false
+	}
+	for _, f := range failures {
+		if f.Package == parent.Package && strings.HasPrefix(f.Test, parent.Test+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// fileLineRE matches file:line references such as "foo_test.go:42".
+var fileLineRE = regexp.MustCompile(`([\w./-]+\.\w+):(\d+)`)
+
+const (
+	contextLines   = 15 // lines shown either side of a reference
+	maxSnippets    = 8
+	maxContextSize = 24 * 1024
+)
+
+// sourceContext returns numbered source excerpts around the file:line
+// references in the failures' output, so the agent starts with the
+// relevant code instead of searching for it.
+func sourceContext(dir string, failures []TestFailure) string {
+	module := modulePath(dir)
+	seen := map[string]bool{}
+	var b strings.Builder
+	snippets := 0
+	for _, f := range failures {
+		for _, m := range fileLineRE.FindAllStringSubmatch(f.Output, -1) {
+			if snippets == maxSnippets || b.Len() > maxContextSize {
+				return b.String()
+			}
+			path := resolveSource(dir, module, f.Package, m[1])
+			line, _ := strconv.Atoi(m[2])
+			if path == "" || seen[path+":"+m[2]] {
+				continue
+			}
+			seen[path+":"+m[2]] = true
+			if text := excerpt(path, line, contextLines); text != "" {
+				rel, err := filepath.Rel(dir, path)
+				if err != nil {
+					rel = path
+				}
+				fmt.Fprintf(&b, "%s:%d\n```\n%s```\n\n", rel, line, text)
+				snippets++
+			}
+		}
+	}
+	return b.String()
+}
+
+// resolveSource finds the file a reference names: absolute, relative to
+// the failing package's directory (as go test prints them), or relative
+// to the project root.
+func resolveSource(dir, module, pkg, name string) string {
+	var candidates []string
+	if filepath.IsAbs(name) {
+		candidates = append(candidates, name)
+	} else {
+		if module != "" && (pkg == module || strings.HasPrefix(pkg, module+"/")) {
+			rel := strings.TrimPrefix(strings.TrimPrefix(pkg, module), "/")
+			candidates = append(candidates, filepath.Join(dir, rel, name))
+		}
+		candidates = append(candidates, filepath.Join(dir, name))
+	}
+	for _, c := range candidates {
+		if info, err := os.Stat(c); err == nil && info.Mode().IsRegular() {
+			return c
+		}
+	}
+	return ""
+}
+
+// modulePath reads the module path from dir/go.mod, or returns "".
+func modulePath(dir string) string {
+	data, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if rest, ok := strings.CutPrefix(strings.TrimSpace(line), "module"); ok {
+			return strings.Trim(strings.TrimSpace(rest), `"`)
+		}
+	}
+	return ""
+}
+
+// excerpt returns the lines around line in path, numbered, with the
+// referenced line marked.
+func excerpt(path string, line, radius int) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	lines := strings.Split(string(data), "\n")
+	if line < 1 || line > len(lines) {
+		return ""
+	}
+	start := max(line-radius, 1)
+	end := min(line+radius, len(lines))
+	var b strings.Builder
+	for n := start; n <= end; n++ {
+		marker := " "
+		if n == line {
+			marker = ">"
+		}
+		fmt.Fprintf(&b, "%s%5d  %s\n", marker, n, lines[n-1])
+	}
+	return b.String()
+}
+
+// fixSummary returns the agent's "Summary:" line, or its last paragraph
+// when it did not write one.
+func fixSummary(reply string) string {
+	lines := strings.Split(strings.TrimSpace(reply), "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		if s, ok := strings.CutPrefix(strings.TrimSpace(lines[i]), "Summary:"); ok {
+			return strings.TrimSpace(s)
+		}
+	}
+	paragraphs := strings.Split(strings.TrimSpace(reply), "\n\n")
+	last := strings.TrimSpace(paragraphs[len(paragraphs)-1])
+	if len(last) > 300 {
+		last = last[:300] + "..."
+	}
+	return last
+}
+
+// DefaultWatchInterval is how often WaitForChange checks the tree.
+const DefaultWatchInterval = time.Second
+
+// WaitForChange blocks until a file under dir is created, modified, or
+// removed, polling every interval, or until ctx is cancelled. Hidden
+// directories, node_modules, and vendor are ignored.
+func WaitForChange(ctx context.Context, dir string, interval time.Duration) error {
+	before := snapshot(dir)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if !sameSnapshot(before, snapshot(dir)) {
+				return nil
+			}
+		}
+	}
+}
+
+// fileVersion identifies one version of a file on disk.
+type fileVersion struct {
+	modTime time.Time
+	size    int64
+}
+
+func snapshot(dir string) map[string]fileVersion {
+	files := map[string]fileVersion{}
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			name := d.Name()
+			if path != dir && (strings.HasPrefix(name, ".") || name == "node_modules" || name == "vendor") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info, err := d.Info(); err == nil && info.Mode().IsRegular() {
+			files[path] = fileVersion{modTime: info.ModTime(), size: info.Size()}
+		}
+		return nil
+	})
+	return files
+}
+
+func sameSnapshot(a, b map[string]fileVersion) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for path, v := range a {
+		if b[path] != v {
+			return false
+		}
+	}
+	return true
+}
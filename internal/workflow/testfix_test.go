@@ -0,0 +1,197 @@
+package workflow
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const goTestOutput = `{"Action":"start","Package":"example.com/m/calc"}
+{"Action":"run","Package":"example.com/m/calc","Test":"TestAdd"}
+{"Action":"output","Package":"example.com/m/calc","Test":"TestAdd","Output":"=== RUN   TestAdd\n"}
+{"Action":"output","Package":"example.com/m/calc","Test":"TestAdd/negative","Output":"    calc_test.go:6: Add(-1, -1) = 0, want -2\n"}
+{"Action":"fail","Package":"example.com/m/calc","Test":"TestAdd/negative","Elapsed":0}
+{"Action":"fail","Package":"example.com/m/calc","Test":"TestAdd","Elapsed":0}
+{"Action":"pass","Package":"example.com/m/calc","Test":"TestSub","Elapsed":0}
+{"Action":"fail","Package":"example.com/m/calc","Elapsed":0.01}
+{"ImportPath":"example.com/m/broken [example.com/m/broken.test]","Action":"build-output","Output":"broken/broken.go:3:1: syntax error\n"}
+{"ImportPath":"example.com/m/broken [example.com/m/broken.test]","Action":"build-fail"}
+{"Action":"fail","Package":"example.com/m/broken","Elapsed":0}
+{"Action":"pass","Package":"example.com/m/ok","Elapsed":0}
+`
+
+func TestParseGoTestJSON(t *testing.T) {
+	failures := ParseGoTestJSON(goTestOutput)
+	var names []string
+	for _, f := range failures {
+		names = append(names, f.Name())
+	}
+	want := "example.com/m/calc.TestAdd/negative|example.com/m/broken"
+	if got := strings.Join(names, "|"); got != want {
+		t.Fatalf("failures = %q, want %q", got, want)
+	}
+	if !strings.Contains(failures[0].Output, "want -2") {
+		t.Errorf("test output = %q", failures[0].Output)
+	}
+	if !strings.Contains(failures[1].Output, "syntax error") {
+		t.Errorf("build output = %q", failures[1].Output)
+	}
+}
+
+func TestParseGoTestJSON_PlainTextPackageFailure(t *testing.T) {
+	out := "# example.com/m/calc\ncalc.go:3:1: undefined: x\n" +
+		`{"Action":"output","Package":"example.com/m/calc","Output":"FAIL\texample.com/m/calc [build failed]\n"}` + "\n" +
+		`{"Action":"fail","Package":"example.com/m/calc","Elapsed":0}` + "\n"
+	failures := ParseGoTestJSON(out)
+	if len(failures) != 1 || failures[0].Test != "" || !strings.Contains(failures[0].Output, "undefined: x") {
+		t.Fatalf("failures = %+v", failures)
+	}
+}
+
+func TestSourceContext(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/m\n\ngo 1.21\n"), 0644)
+	os.Mkdir(filepath.Join(dir, "calc"), 0755)
+	var src strings.Builder
+	for i := 1; i <= 40; i++ {
+		src.WriteString("line" + strings.Repeat("x", i%3) + "\n")
+	}
+	os.WriteFile(filepath.Join(dir, "calc", "calc_test.go"), []byte(src.String()), 0644)
+
+	got := sourceContext(dir, []TestFailure{{
+		Package: "example.com/m/calc",
+		Test:    "TestAdd",
+		Output:  "calc_test.go:20: bad\ncalc_test.go:20: again\nmissing.go:1: gone\n",
+	}})
+	if strings.Count(got, "calc/calc_test.go:20") != 1 {
+		t.Errorf("want one excerpt for the repeated reference:\n%s", got)
+	}
+	if !strings.Contains(got, ">   20  ") || !strings.Contains(got, "    5  ") || strings.Contains(got, "    4  ") {
+		t.Errorf("excerpt should cover lines 5-35 with 20 marked:\n%s", got)
+	}
+	if strings.Contains(got, "missing.go") {
+		t.Errorf("missing files should be skipped:\n%s", got)
+	}
+}
+
+func TestFixSummary(t *testing.T) {
+	tests := []struct{ reply, want string }{
+		{"I changed the loop.\n\nSummary: Fixed off-by-one in Add.", "Fixed off-by-one in Add."},
+		{"First part.\n\nChanged the comparison to <=.", "Changed the comparison to <=."},
+	}
+	for _, tt := range tests {
+		if got := fixSummary(tt.reply); got != tt.want {
+			t.Errorf("fixSummary(%q) = %q, want %q", tt.reply, got, tt.want)
+		}
+	}
+}
+
+func TestDetectTestCommand(t *testing.T) {
+	dir := t.TempDir()
+	if got := DetectTestCommand(dir); got != "" {
+		t.Errorf("empty dir: %q", got)
+	}
+	os.WriteFile(filepath.Join(dir, "package.json"), []byte("{}"), 0644)
+	if got := DetectTestCommand(dir); got != "npm test" {
+		t.Errorf("package.json: %q", got)
+	}
+	os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module m\n"), 0644)
+	if got := DetectTestCommand(dir); got != "go test -json ./..." {
+		t.Errorf("go.mod: %q", got)
+	}
+}
+
+func TestFixTests_FixesUntilGreen(t *testing.T) {
+	dir := t.TempDir()
+	marker := filepath.Join(dir, "fixed")
+	output := filepath.Join(dir, "output.json")
+	os.WriteFile(output, []byte(goTestOutput), 0644)
+	// The tests fail until the "fix" creates the marker file.
+	exec := scriptExecutor{"go test -json ./...": "[ -e " + marker + " ] && exit 0; cat " + output + "; exit 1"}
+	a := &fixingAgent{fakeAgent: fakeAgent{replies: []string{"Summary: Fixed Add for negatives."}}, fix: func() {
+		os.WriteFile(marker, nil, 0644)
+	}}
+
+	r, err := FixTests(context.Background(), Env{Agent: a, Executor: exec, Dir: dir}, TestOptions{Command: "go test -json ./..."})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Passed || len(r.Fixes) != 1 {
+		t.Fatalf("result = %+v", r)
+	}
+	if r.Fixes[0].Summary != "Fixed Add for negatives." || len(r.Fixes[0].Failures) != 2 {
+		t.Errorf("fix = %+v", r.Fixes[0])
+	}
+	if !strings.Contains(a.prompts[0], "Add(-1, -1) = 0, want -2") {
+		t.Errorf("prompt missing failure output:\n%s", a.prompts[0])
+	}
+	if s := r.Summary(); !strings.Contains(s, "Tests pass after 1 fix(es)") || !strings.Contains(s, "Fixed Add for negatives.") {
+		t.Errorf("Summary() = %q", s)
+	}
+}
+
+func TestFixTests_GivesUp(t *testing.T) {
+	a := &fakeAgent{}
+	exec := scriptExecutor{"make test": "echo boom; exit 2"}
+	r, err := FixTests(context.Background(), Env{Agent: a, Executor: exec}, TestOptions{Command: "make test", MaxAttempts: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Passed || len(r.Fixes) != 2 || len(a.prompts) != 2 {
+		t.Fatalf("result = %+v, prompts = %d", r, len(a.prompts))
+	}
+	if len(r.Remaining) != 1 || !strings.Contains(r.Remaining[0].Output, "boom") {
+		t.Errorf("remaining = %+v", r.Remaining)
+	}
+	if !strings.Contains(r.Summary(), "Tests still failing after 2 fix attempt(s)") {
+		t.Errorf("Summary() = %q", r.Summary())
+	}
+}
+
+func TestFixTests_CommandNotFound(t *testing.T) {
+	_, err := FixTests(context.Background(), Env{Agent: &fakeAgent{}, Executor: scriptExecutor{}}, TestOptions{Command: "nosuchtest"})
+	if err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+// fixingAgent runs fix when it is sent a prompt, standing in for the
+// agent editing files.
+type fixingAgent struct {
+	fakeAgent
+	fix func()
+}
+
+func (a *fixingAgent) Send(ctx context.Context, prompt string) error {
+	a.fix()
+	return a.fakeAgent.Send(ctx, prompt)
+}
+
+func TestWaitForChange(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "a.go"), []byte("package a"), 0644)
+	os.Mkdir(filepath.Join(dir, ".git"), 0755)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	go os.WriteFile(filepath.Join(dir, ".git", "index"), []byte("x"), 0644)
+	if err := WaitForChange(ctx, dir, 10*time.Millisecond); err == nil {
+		t.Fatal("changes under .git should be ignored")
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- WaitForChange(context.Background(), dir, 10*time.Millisecond) }()
+	time.Sleep(30 * time.Millisecond)
+	os.WriteFile(filepath.Join(dir, "b.go"), []byte("package a"), 0644)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("WaitForChange did not notice the new file")
+	}
+}
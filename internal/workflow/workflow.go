@@ -0,0 +1,185 @@
+// Package workflow implements the scripted modes (stormtrooper audit-deps
+// and friends) that run project commands themselves, hand the results to
+// the agent, and turn its answer into a report.
+//
+// Workflows drive the same agent and tools as the interactive modes; they
+// only decide what to ask and when to stop.
+package workflow
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	udiff "github.com/aymanbagabas/go-udiff"
+	"github.com/gavinyap/stormtrooper/internal/llm"
+	"github.com/gavinyap/stormtrooper/internal/permission"
+	"github.com/gavinyap/stormtrooper/internal/tool"
+)
+
+// Agent is the part of agent.Agent a workflow drives.
+type Agent interface {
+	Send(ctx context.Context, prompt string) error
+	History() []llm.Message
+}
+
+// Env is what a workflow runs against.
+type Env struct {
+	Agent Agent
+	// Executor runs project commands, so workflows honour the sandbox
+	// and remote settings like shell_exec does.
+	Executor tool.Executor
+	// Dir is the project directory.
+	Dir string
+	// Log receives progress lines.
+	Log io.Writer
+	// Permission approves edits a workflow makes itself rather than
+	// through the agent's tools. Nil approves everything.
+	Permission permission.Handler
+}
+
+func (e Env) logf(format string, args ...any) {
+	if e.Log != nil {
+		fmt.Fprintf(e.Log, format+"\n", args...)
+	}
+}
+
+// exitNotFound is the shell's exit status for an unknown command.
+const exitNotFound = 127
+
+// commandResult is the outcome of one project command.
+type commandResult struct {
+	Command  string
+	Output   string
+	ExitCode int
+}
+
+// runCommand runs command in dir through the executor and returns its
+// combined output. A non-zero exit is not an error; only failing to
+// start is.
+func runCommand(ctx context.Context, e tool.Executor, dir, command string) (commandResult, error) {
+	if e == nil {
+		e = tool.LocalExecutor{}
+	}
+	cmd := e.Command(ctx, command)
+	if cmd.Dir == "" {
+		cmd.Dir = dir
+	}
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	err := cmd.Run()
+	res := commandResult{Command: command, Output: out.String()}
+	var exitErr *exec.ExitError
+	switch {
+	case errors.As(err, &exitErr):
+		res.ExitCode = exitErr.ExitCode()
+	case err != nil:
+		return res, fmt.Errorf("running %q: %w", command, err)
+	}
+	return res, nil
+}
+
+// lastReply returns the content of the agent's most recent answer.
+func lastReply(a Agent) string {
+	history := a.History()
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].Role == "assistant" && history[i].Content != "" {
+			return history[i].Content
+		}
+	}
+	return ""
+}
+
+// extractJSON returns the last ```json fenced block in reply, or the
+// reply itself when it is a bare JSON object.
+func extractJSON(reply string) (string, bool) {
+	const fence = "```json"
+	if i := strings.LastIndex(reply, fence); i >= 0 {
+		body := reply[i+len(fence):]
+		if end := strings.Index(body, "```"); end >= 0 {
+			return strings.TrimSpace(body[:end]), true
+		}
+	}
+	if s := strings.TrimSpace(reply); strings.HasPrefix(s, "{") && strings.HasSuffix(s, "}") {
+		return s, true
+	}
+	return "", false
+}
+
+const jsonRetryPrompt = "Your reply did not end with the JSON code block I asked for. Reply with only that JSON code block."
+
+// askJSON sends prompt and decodes the JSON block that ends the agent's
+// reply into a T, asking once more if it is missing or malformed.
+func askJSON[T any](ctx context.Context, a Agent, prompt string) (*T, error) {
+	var lastErr error
+	for _, p := range []string{prompt, jsonRetryPrompt} {
+		if err := a.Send(ctx, p); err != nil {
+			return nil, err
+		}
+		raw, ok := extractJSON(lastReply(a))
+		if !ok {
+			lastErr = errors.New("the agent did not return the JSON report")
+			continue
+		}
+		var v T
+		if err := json.Unmarshal([]byte(raw), &v); err != nil {
+			lastErr = fmt.Errorf("the agent returned an invalid report: %w", err)
+			continue
+		}
+		return &v, nil
+	}
+	return nil, lastErr
+}
+
+// applyEdit shows the diff from old to updated to env.Permission and
+// writes file (relative to the project) if it is approved. name is the
+// workflow asking.
+func applyEdit(ctx context.Context, env Env, name, file, old, updated string) (bool, error) {
+	return applyEdits(ctx, env, name, "Update "+file, []fileEdit{{File: file, Old: old, New: updated}})
+}
+
+// fileEdit is new content for one project file.
+type fileEdit struct {
+	File     string
+	Old, New string
+}
+
+// applyEdits asks env.Permission once, with title and the diffs of all
+// edits, and writes every file if it is approved.
+func applyEdits(ctx context.Context, env Env, name, title string, edits []fileEdit) (bool, error) {
+	var preview strings.Builder
+	preview.WriteString(title + "\n")
+	for _, e := range edits {
+		from := "a/" + e.File
+		if e.Old == "" {
+			from = "/dev/null"
+		}
+		preview.WriteString(udiff.Unified(from, "b/"+e.File, e.Old, e.New))
+	}
+	if env.Permission != nil && !env.Permission.Check(ctx, name, preview.String()) {
+		return false, nil
+	}
+	for _, e := range edits {
+		path := filepath.Join(env.Dir, filepath.FromSlash(e.File))
+		if err := os.WriteFile(path, []byte(e.New), 0644); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// truncate keeps the last max bytes of s, where failures usually are.
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return "[... earlier output truncated]\n" + s[len(s)-max:]
+}
@@ -0,0 +1,109 @@
+package workflow
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/gavinyap/stormtrooper/internal/llm"
+)
+
+// fakeAgent answers each Send with the next canned reply.
+type fakeAgent struct {
+	replies []string
+	prompts []string
+	history []llm.Message
+}
+
+func (a *fakeAgent) Send(ctx context.Context, prompt string) error {
+	a.prompts = append(a.prompts, prompt)
+	reply := ""
+	if len(a.replies) > 0 {
+		reply, a.replies = a.replies[0], a.replies[1:]
+	}
+	a.history = append(a.history,
+		llm.Message{Role: "user", Content: prompt},
+		llm.Message{Role: "assistant", Content: reply})
+	return nil
+}
+
+func (a *fakeAgent) History() []llm.Message { return a.history }
+
+// scriptExecutor runs a stand-in shell script for each known command, and
+// exits 127 like sh does for anything else.
+type scriptExecutor map[string]string
+
+func (s scriptExecutor) Command(ctx context.Context, command string) *exec.Cmd {
+	script, ok := s[command]
+	if !ok {
+		script = "echo " + command + ": not found >&2; exit 127"
+	}
+	return exec.CommandContext(ctx, "sh", "-c", script)
+}
+
+func TestRunCommand(t *testing.T) {
+	exec := scriptExecutor{"check": "echo out; echo err >&2; exit 3"}
+	res, err := runCommand(context.Background(), exec, "", "check")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.ExitCode != 3 {
+		t.Errorf("ExitCode = %d, want 3", res.ExitCode)
+	}
+	if !strings.Contains(res.Output, "out") || !strings.Contains(res.Output, "err") {
+		t.Errorf("Output = %q, want stdout and stderr", res.Output)
+	}
+}
+
+func TestExtractJSON(t *testing.T) {
+	tests := []struct {
+		name, reply, want string
+		ok                bool
+	}{
+		{"fenced", "Done.\n```json\n{\"a\": 1}\n```\n", `{"a": 1}`, true},
+		{"last block wins", "```json\n{\"a\": 1}\n```\nthen\n```json\n{\"a\": 2}\n```", `{"a": 2}`, true},
+		{"bare object", "  {\"a\": 1}\n", `{"a": 1}`, true},
+		{"unterminated", "```json\n{\"a\": 1}", "", false},
+		{"prose", "All good.", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := extractJSON(tt.reply)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("extractJSON() = %q, %v; want %q, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestAskJSON_RetriesOnce(t *testing.T) {
+	a := &fakeAgent{replies: []string{"I looked into it.", "```json\n{\"summary\": \"ok\"}\n```"}}
+	r, err := askJSON[AuditReport](context.Background(), a, "audit please")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Summary != "ok" {
+		t.Errorf("Summary = %q, want ok", r.Summary)
+	}
+	if len(a.prompts) != 2 || a.prompts[1] != jsonRetryPrompt {
+		t.Errorf("prompts = %q, want the retry prompt second", a.prompts)
+	}
+}
+
+func TestAskJSON_GivesUp(t *testing.T) {
+	a := &fakeAgent{replies: []string{"no", "```json\n{not json}\n```"}}
+	if _, err := askJSON[AuditReport](context.Background(), a, "audit please"); err == nil {
+		t.Fatal("expected an error after two bad replies")
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	if got := truncate("short", 10); got != "short" {
+		t.Errorf("truncate() = %q", got)
+	}
+	got := truncate("0123456789", 4)
+	if !strings.HasSuffix(got, "6789") || !strings.Contains(got, "truncated") {
+		t.Errorf("truncate() = %q, want the tail with a marker", got)
+	}
+}
@@ -0,0 +1,188 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// WorktreeOptions configures Worktree.
+type WorktreeOptions struct {
+	Task string
+	// Branch is the new branch; default stormtrooper/<slug of the task>.
+	Branch string
+	// Base is the revision the branch starts from; default HEAD.
+	Base string
+	// Dir is where the worktree goes; default a sibling of the
+	// repository named <repo>-<slug>.
+	Dir string
+	// Remove deletes the worktree afterwards, keeping the branch.
+	Remove bool
+	// Run runs an agent session on task with dir as its working
+	// directory, so its files and commands stay inside the worktree.
+	Run func(ctx context.Context, dir, task string) error
+}
+
+// WorktreeResult is the outcome of Worktree.
+type WorktreeResult struct {
+	Task    string
+	Branch  string
+	Base    string // abbreviated commit
+	Dir     string
+	Removed bool
+	// Commit is the abbreviated commit holding the agent's changes;
+	// empty if it changed nothing.
+	Commit string
+	Stat   string // git diff --stat of the commit
+	// RunErr is why the agent session failed; its changes are left
+	// uncommitted in the worktree.
+	RunErr error
+}
+
+// Report renders the result for people.
+func (r *WorktreeResult) Report() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", r.Task)
+	fmt.Fprintf(&b, "- Branch: %s (from %s)\n", r.Branch, r.Base)
+	if r.Removed {
+		fmt.Fprintf(&b, "- Worktree: %s (removed)\n", r.Dir)
+	} else {
+		fmt.Fprintf(&b, "- Worktree: %s\n", r.Dir)
+	}
+	switch {
+	case r.RunErr != nil:
+		fmt.Fprintf(&b, "\nThe agent session failed: %v\nIts changes are left uncommitted in the worktree.\n", r.RunErr)
+		return b.String()
+	case r.Commit == "":
+		b.WriteString("\nThe agent made no changes.\n")
+		return b.String()
+	}
+	fmt.Fprintf(&b, "- Commit: %s\n\n```\n%s\n```\n\n", r.Commit, strings.TrimRight(r.Stat, "\n"))
+	fmt.Fprintf(&b, "Review it with `git diff %s...%s`, then push it for a pull request with `git push -u origin %s`.\n", r.Base, r.Branch, r.Branch)
+	if !r.Removed {
+		fmt.Fprintf(&b, "Remove the worktree when you are done with `git worktree remove %s`.\n", shellQuote(r.Dir))
+	}
+	return b.String()
+}
+
+// Worktree creates a branch and a git worktree for it, runs an agent
+// session confined to the worktree, and commits what the agent changed,
+// so several agents can work on one repository at once without touching
+// each other's files.
+func Worktree(ctx context.Context, env Env, opts WorktreeOptions) (*WorktreeResult, error) {
+	if strings.TrimSpace(opts.Task) == "" {
+		return nil, errors.New("describe the task")
+	}
+	git := func(dir, args string) (string, error) {
+		res, err := runCommand(ctx, env.Executor, dir, "git "+args)
+		if err != nil {
+			return "", err
+		}
+		if res.ExitCode != 0 {
+			return "", fmt.Errorf("git %s: %s", strings.Fields(args)[0], strings.TrimSpace(res.Output))
+		}
+		return strings.TrimRight(res.Output, "\n"), nil
+	}
+
+	root, err := git(env.Dir, "rev-parse --show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	slug := taskSlug(opts.Task)
+	result := &WorktreeResult{Task: opts.Task, Branch: opts.Branch, Dir: opts.Dir}
+	if result.Branch == "" {
+		result.Branch = "stormtrooper/" + slug
+	}
+	if result.Dir == "" {
+		result.Dir = filepath.Join(filepath.Dir(root), filepath.Base(root)+"-"+slug)
+	} else if !filepath.IsAbs(result.Dir) {
+		result.Dir = filepath.Join(env.Dir, result.Dir)
+	}
+	base := opts.Base
+	if base == "" {
+		base = "HEAD"
+	}
+	if result.Base, err = git(env.Dir, "rev-parse --short --verify "+shellQuote(base+"^{commit}")); err != nil {
+		return nil, err
+	}
+
+	env.logf("[worktree] creating %s in %s", result.Branch, result.Dir)
+	if _, err := git(env.Dir, fmt.Sprintf("worktree add -b %s %s %s", shellQuote(result.Branch), shellQuote(result.Dir), result.Base)); err != nil {
+		return nil, err
+	}
+
+	env.logf("[worktree] running the agent in %s", result.Dir)
+	if result.RunErr = opts.Run(ctx, result.Dir, opts.Task); result.RunErr != nil {
+		return result, nil
+	}
+
+	// The session's own files under .stormtrooper are not part of the
+	// change.
+	if _, err := git(result.Dir, "add -A -- . ':!.stormtrooper'"); err != nil {
+		return nil, err
+	}
+	res, err := runCommand(ctx, env.Executor, result.Dir, "git diff --cached --quiet")
+	if err != nil {
+		return nil, err
+	}
+	if res.ExitCode != 0 {
+		if _, err := git(result.Dir, "commit -q -m "+shellQuote(commitMessage(opts.Task))); err != nil {
+			return nil, err
+		}
+		if result.Commit, err = git(result.Dir, "rev-parse --short HEAD"); err != nil {
+			return nil, err
+		}
+		if result.Stat, err = git(result.Dir, "diff --stat "+result.Base+" HEAD"); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.Remove {
+		env.logf("[worktree] removing %s", result.Dir)
+		if _, err := git(env.Dir, "worktree remove --force "+shellQuote(result.Dir)); err != nil {
+			return nil, err
+		}
+		result.Removed = true
+	}
+	return result, nil
+}
+
+// taskSlug names a branch and directory after the first words of task.
+func taskSlug(task string) string {
+	var words []string
+	for _, w := range strings.Fields(strings.ToLower(task)) {
+		w = strings.Map(func(r rune) rune {
+			if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+				return r
+			}
+			return -1
+		}, w)
+		if w != "" {
+			words = append(words, w)
+		}
+		if len(words) == 4 {
+			break
+		}
+	}
+	if len(words) == 0 {
+		return "task"
+	}
+	return strings.Join(words, "-")
+}
+
+// commitMessage uses the task's first line as the subject and keeps the
+// rest as the body.
+func commitMessage(task string) string {
+	task = strings.TrimSpace(task)
+	subject, body, _ := strings.Cut(task, "\n")
+	if len(subject) > 72 {
+		subject = subject[:69] + "..."
+		body = task
+	}
+	if body = strings.TrimSpace(body); body != "" {
+		return subject + "\n\n" + body
+	}
+	return subject
+}
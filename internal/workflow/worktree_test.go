@@ -0,0 +1,129 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// gitRepo creates a repository with one commit, isolated from the
+// user's git configuration.
+func gitRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_AUTHOR_NAME", "Test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "Test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+	dir := filepath.Join(t.TempDir(), "app")
+	os.MkdirAll(dir, 0755)
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0644)
+	for _, args := range [][]string{{"init", "-q", "-b", "main"}, {"add", "."}, {"commit", "-q", "-m", "initial"}} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	return dir
+}
+
+func gitOutput(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func TestWorktree_CommitsTheAgentsChanges(t *testing.T) {
+	repo := gitRepo(t)
+	var ranIn string
+	run := func(_ context.Context, dir, task string) error {
+		ranIn = dir
+		os.MkdirAll(filepath.Join(dir, ".stormtrooper", "sessions"), 0755)
+		os.WriteFile(filepath.Join(dir, ".stormtrooper", "sessions", "s.json"), []byte("{}"), 0644)
+		return os.WriteFile(filepath.Join(dir, "util.go"), []byte("package main // util\n"), 0644)
+	}
+
+	result, err := Worktree(context.Background(), Env{Dir: repo}, WorktreeOptions{Task: "Add a util file\n\nKeep it small.", Run: run})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantDir := filepath.Join(filepath.Dir(repo), "app-add-a-util-file")
+	if result.Dir != wantDir || ranIn != wantDir {
+		t.Errorf("worktree in %s, agent ran in %s; want %s", result.Dir, ranIn, wantDir)
+	}
+	if result.Branch != "stormtrooper/add-a-util-file" || result.Commit == "" {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	if _, err := os.Stat(filepath.Join(repo, "util.go")); !os.IsNotExist(err) {
+		t.Error("the agent's file should not appear in the main checkout")
+	}
+	if got := gitOutput(t, repo, "log", "-1", "--format=%s%n%b", result.Branch); got != "Add a util file\nKeep it small." {
+		t.Errorf("commit message = %q", got)
+	}
+	if got := gitOutput(t, repo, "show", "--name-only", "--format=", result.Branch); got != "util.go" {
+		t.Errorf("committed files = %q, want only util.go", got)
+	}
+	if got := gitOutput(t, repo, "rev-parse", "--abbrev-ref", "HEAD"); got != "main" {
+		t.Errorf("main checkout switched to %s", got)
+	}
+	report := result.Report()
+	for _, want := range []string{"util.go", "git push -u origin stormtrooper/add-a-util-file", "git worktree remove"} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report missing %q:\n%s", want, report)
+		}
+	}
+
+	// The same branch cannot be created twice.
+	if _, err := Worktree(context.Background(), Env{Dir: repo}, WorktreeOptions{Task: "add a util file", Run: run}); err == nil || !strings.Contains(err.Error(), "git worktree") {
+		t.Errorf("expected a git worktree error, got %v", err)
+	}
+}
+
+func TestWorktree_NoChangesAndRemove(t *testing.T) {
+	repo := gitRepo(t)
+	result, err := Worktree(context.Background(), Env{Dir: repo}, WorktreeOptions{
+		Task:   "look around",
+		Branch: "explore",
+		Dir:    "../explore",
+		Remove: true,
+		Run:    func(context.Context, string, string) error { return nil },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Commit != "" || !result.Removed || !strings.Contains(result.Report(), "no changes") {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(repo), "explore")); !os.IsNotExist(err) {
+		t.Error("expected the worktree removed")
+	}
+	if got := gitOutput(t, repo, "branch", "--list", "explore"); !strings.Contains(got, "explore") {
+		t.Error("the branch should be kept")
+	}
+}
+
+func TestWorktree_ReportsAFailedRun(t *testing.T) {
+	repo := gitRepo(t)
+	result, err := Worktree(context.Background(), Env{Dir: repo}, WorktreeOptions{
+		Task: "break things",
+		Run:  func(context.Context, string, string) error { return errors.New("exit status 1") },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Commit != "" || !strings.Contains(result.Report(), "failed: exit status 1") {
+		t.Errorf("unexpected result %+v\n%s", result, result.Report())
+	}
+}